@@ -0,0 +1,149 @@
+// Command fakeworker runs thousands of simulated workers against a control
+// plane and reports registration and heartbeat latency percentiles. With
+// -tasks the workers also pull tasks and report task RPC latency.
+//
+//	fakeworker -addrs localhost:50051,localhost:50052 -workers 2000 -ramp 30s \
+//	    -duration 5m -churn crash-storm -churn-every 1m -churn-fraction 0.2
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/fakeworker"
+)
+
+func main() {
+	var (
+		addrs         = flag.String("addrs", "localhost:50051", "comma-separated control-plane gRPC addresses")
+		workers       = flag.Int("workers", 500, "number of simulated workers")
+		prefix        = flag.String("id-prefix", "fake", "worker ID prefix")
+		heartbeat     = flag.Duration("heartbeat", 5*time.Second, "heartbeat interval")
+		ramp          = flag.Duration("ramp", 10*time.Second, "window over which workers first register")
+		duration      = flag.Duration("duration", time.Minute, "how long to run; 0 runs until interrupted")
+		rpcTimeout    = flag.Duration("rpc-timeout", 5*time.Second, "per-RPC deadline")
+		redirects     = flag.String("redirect-map", "", "comma-separated from=to rewrites for leader redirects, e.g. cp-aws-1:50051=localhost:50051")
+		churn         = flag.String("churn", "", "churn pattern: crash-storm, rolling or mass-reconnect")
+		churnEvery    = flag.Duration("churn-every", time.Minute, "churn period")
+		churnFraction = flag.Float64("churn-fraction", 0.1, "share of workers affected per churn period")
+		churnDowntime = flag.Duration("churn-downtime", 30*time.Second, "how long crashed workers stay down")
+		tasks         = flag.Bool("tasks", false, "also pull and run tasks from TaskService")
+		taskDuration  = flag.Duration("task-duration", time.Second, "simulated task run time")
+		taskFailures  = flag.Float64("task-failure-rate", 0, "share of tasks reported as failed")
+		taskRenew     = flag.Duration("task-renew-every", 10*time.Second, "task lease renewal period")
+		reportEvery   = flag.Duration("report-every", 10*time.Second, "progress report interval; 0 disables")
+		seed          = flag.Uint64("seed", uint64(time.Now().UnixNano()), "seed for jitter and churn choices")
+		verbose       = flag.Bool("v", false, "debug logging")
+	)
+	flag.Parse()
+
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	redirectMap, err := parseRedirects(*redirects)
+	if err != nil {
+		fatal(err)
+	}
+	fleet, err := fakeworker.NewFleet(fakeworker.Config{
+		Seeds:      splitCSV(*addrs),
+		Workers:    *workers,
+		IDPrefix:   *prefix,
+		Heartbeat:  *heartbeat,
+		Ramp:       *ramp,
+		RPCTimeout: *rpcTimeout,
+		JoinToken:  os.Getenv("WORKER_JOIN_TOKEN"),
+		Redirects:  redirectMap,
+		Churn: fakeworker.Churn{
+			Pattern:  fakeworker.ChurnPattern(*churn),
+			Every:    *churnEvery,
+			Fraction: *churnFraction,
+			Downtime: *churnDowntime,
+		},
+		Tasks: fakeworker.Tasks{
+			Enabled:     *tasks,
+			Duration:    *taskDuration,
+			FailureRate: *taskFailures,
+			RenewEvery:  *taskRenew,
+		},
+		Seed: *seed,
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	slog.Info("fakeworker starting", "workers", *workers, "addrs", *addrs, "churn", *churn, "seed", *seed)
+	if *reportEvery > 0 {
+		go func() {
+			ticker := time.NewTicker(*reportEvery)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					progress(fleet)
+				}
+			}
+		}()
+	}
+	if err := fleet.Run(ctx); err != nil {
+		fatal(err)
+	}
+	if err := fleet.Recorder().WriteReport(os.Stdout); err != nil {
+		fatal(err)
+	}
+}
+
+// progress logs a one-line snapshot of the run so far.
+func progress(fleet *fakeworker.Fleet) {
+	attrs := []any{"registered", fleet.Registered()}
+	for _, s := range fleet.Recorder().Summaries() {
+		attrs = append(attrs, s.Op+"_p99", s.P99, s.Op+"_errors", s.Errors)
+	}
+	slog.Info("progress", attrs...)
+}
+
+func parseRedirects(s string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, pair := range splitCSV(s) {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid -redirect-map entry %q, want from=to", pair)
+		}
+		m[from] = to
+	}
+	return m, nil
+}
+
+func splitCSV(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func fatal(err error) {
+	slog.Error("fakeworker", "error", err)
+	os.Exit(1)
+}
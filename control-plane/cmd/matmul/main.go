@@ -0,0 +1,217 @@
+// Command matmul plans a blocked matrix multiplication C = A×B, writes its
+// manifest (block layout, map and reduce tasks, checksum plan) and, with
+// -submit, submits it to the control plane as a MapReduce job.
+//
+//	matmul -m 65536 -k 65536 -n 65536 -block 4096 -prefix s3://data/matmul/run-1/ \
+//	    -submit localhost:50051,localhost:50052
+//
+// The manifest goes to <prefix>manifest.json unless -out says otherwise;
+// s3:// destinations use the MINIO_* variables. Submission authenticates with
+// OPERATOR_TOKEN when it is set.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/matmul"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/storage"
+)
+
+// maxRedirects bounds how many leader redirects a submission follows.
+const maxRedirects = 3
+
+var failurePolicies = map[string]taskpb.JobFailurePolicy{
+	"fail-fast": taskpb.JobFailurePolicy_JOB_FAILURE_POLICY_FAIL_FAST,
+	"continue":  taskpb.JobFailurePolicy_JOB_FAILURE_POLICY_CONTINUE,
+}
+
+func main() {
+	var (
+		m             = flag.Int("m", 0, "rows of A and C")
+		k             = flag.Int("k", 0, "columns of A, rows of B")
+		n             = flag.Int("n", 0, "columns of B and C")
+		block         = flag.Int("block", 4096, "block edge length in elements")
+		dtype         = flag.String("dtype", "float64", "element type: float32 or float64")
+		prefix        = flag.String("prefix", "", "storage prefix holding the A and B blocks, e.g. s3://bucket/matmul/run-1/")
+		jobID         = flag.String("job", "", "job ID; generated when empty")
+		probes        = flag.Int("probes", 2, "checksum probe vectors per block of C")
+		seed          = flag.Uint64("seed", uint64(time.Now().UnixNano()), "seed for the checksum probe vectors")
+		out           = flag.String("out", "", "manifest destination: a file path, s3://bucket/key or - for stdout; default <prefix>manifest.json")
+		submit        = flag.String("submit", "", "comma-separated control-plane gRPC addresses; empty only writes the manifest")
+		redirects     = flag.String("redirect-map", "", "comma-separated from=to rewrites for leader redirects, e.g. cp-aws-1:50051=localhost:50051")
+		policy        = flag.String("failure-policy", "fail-fast", "fail-fast or continue")
+		placement     = flag.String("placement", "", "placement policy for every task")
+		cloudAffinity = flag.String("cloud-affinity", "", "preferred cloud for every task")
+		cpuMillis     = flag.Int64("cpu-millis", 0, "declared CPU per task")
+		memoryMB      = flag.Int64("memory-mb", 0, "declared memory per task")
+		timeout       = flag.Duration("timeout", time.Minute, "deadline for writing the manifest and submitting")
+	)
+	flag.Parse()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	if *jobID == "" {
+		*jobID = fmt.Sprintf("matmul-%d", time.Now().Unix())
+	}
+	manifest, err := matmul.Plan(matmul.Spec{
+		JobID: *jobID, M: *m, K: *k, N: *n, BlockSize: *block, DType: *dtype,
+		Prefix: *prefix, Probes: *probes, Seed: *seed,
+	})
+	if err != nil {
+		fatal(err)
+	}
+	fp, ok := failurePolicies[*policy]
+	if !ok {
+		fatal(fmt.Errorf("unknown -failure-policy %q (want fail-fast or continue)", *policy))
+	}
+	redirectMap, err := parseRedirects(*redirects)
+	if err != nil {
+		fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		fatal(err)
+	}
+	if *out == "" {
+		*out = manifest.URI()
+	}
+	if err := writeManifest(ctx, *out, append(data, '\n')); err != nil {
+		fatal(err)
+	}
+	slog.Info("manifest written", "dest", *out, "job_id", *jobID, "grid", fmt.Sprintf("%dx%dx%d",
+		manifest.Grid.RowBlocks, manifest.Grid.InnerBlocks, manifest.Grid.ColBlocks),
+		"maps", len(manifest.Maps), "reduces", len(manifest.Reduces), "input_bytes", manifest.InputBytes())
+
+	if *submit == "" {
+		return
+	}
+	req := manifest.Request()
+	req.FailurePolicy = fp
+	req.Placement, req.CloudAffinity = *placement, *cloudAffinity
+	req.CpuMillis, req.MemoryMb = *cpuMillis, *memoryMB
+	resp, err := submitJob(ctx, splitCSV(*submit), redirectMap, req)
+	if err != nil {
+		fatal(err)
+	}
+	slog.Info("job submitted", "job_id", resp.Job.JobId, "state", resp.Job.State)
+}
+
+// writeManifest stores data at dest: stdout, an s3:// URL or a file path.
+func writeManifest(ctx context.Context, dest string, data []byte) error {
+	if dest == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	bucket, key, isS3, err := storage.ParseURL(dest)
+	switch {
+	case err != nil:
+		return err
+	case isS3:
+		client, err := storage.New(storage.Config{
+			Endpoint:  envOr("MINIO_ENDPOINT", "http://localhost:9000"),
+			AccessKey: os.Getenv("MINIO_ROOT_USER"),
+			SecretKey: os.Getenv("MINIO_ROOT_PASSWORD"),
+			Region:    os.Getenv("MINIO_REGION"),
+		})
+		if err != nil {
+			return err
+		}
+		return client.Put(ctx, bucket, key, bytes.NewReader(data), int64(len(data)))
+	default:
+		return os.WriteFile(dest, data, 0o644)
+	}
+}
+
+// submitJob tries each address in turn, following leader redirects, until
+// one accepts the job.
+func submitJob(ctx context.Context, addrs []string, redirects map[string]string,
+	req *taskpb.SubmitMapReduceRequest) (*taskpb.SubmitJobResponse, error) {
+	var errs []error
+	for _, addr := range addrs {
+		for range maxRedirects + 1 {
+			resp, err := submitTo(ctx, addr, req)
+			switch {
+			case err != nil:
+				errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+			case resp.Ok:
+				return resp, nil
+			case resp.Error != "":
+				// The leader refused the job itself; another node would too.
+				return nil, fmt.Errorf("%s: %s", addr, resp.Error)
+			case resp.LeaderAddr != "":
+				if to, ok := redirects[resp.LeaderAddr]; ok {
+					addr = to
+				} else {
+					addr = resp.LeaderAddr
+				}
+				continue
+			default:
+				errs = append(errs, fmt.Errorf("%s: not the leader and no leader known", addr))
+			}
+			break
+		}
+	}
+	return nil, fmt.Errorf("no control plane accepted the job: %w", errors.Join(errs...))
+}
+
+func submitTo(ctx context.Context, addr string, req *taskpb.SubmitMapReduceRequest) (*taskpb.SubmitJobResponse, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if token := os.Getenv("OPERATOR_TOKEN"); token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+	return taskpb.NewTaskServiceClient(conn).SubmitMapReduce(ctx, req)
+}
+
+func parseRedirects(s string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, pair := range splitCSV(s) {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid -redirect-map entry %q, want from=to", pair)
+		}
+		m[from] = to
+	}
+	return m, nil
+}
+
+func splitCSV(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func fatal(err error) {
+	slog.Error("matmul", "error", err)
+	os.Exit(1)
+}
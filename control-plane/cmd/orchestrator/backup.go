@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/storage"
+)
+
+// backupTimeout bounds a whole backup or restore transfer.
+const backupTimeout = 5 * time.Minute
+
+// registerBackupHandlers exposes
+//
+//	GET /raft/snapshot    take a snapshot on the leader and stream it as a backup file
+//
+// The snapshot holds credential hashes and sealed CA keys, so the endpoint
+// requires "Authorization: Bearer <BACKUP_TOKEN>".
+func registerBackupHandlers(mux *http.ServeMux, raftNode *internalraft.RaftNode, token string) {
+	mux.HandleFunc("/raft/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "invalid backup token", http.StatusUnauthorized)
+			return
+		}
+		// Backup buffers the snapshot before writing, so failures can still
+		// be reported as HTTP errors.
+		var buf bytes.Buffer
+		hdr, err := raftNode.Backup(&buf)
+		switch {
+		case errors.Is(err, hashiraft.ErrNotLeader):
+			http.Error(w, "not leader; retry against "+raftNode.LeaderID(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		slog.Info("backup served", "index", hdr.Index, "term", hdr.Term, "bytes", hdr.Size)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf(`attachment; filename="raft-%d-%d.snap"`, hdr.Term, hdr.Index))
+		_, _ = w.Write(buf.Bytes())
+	})
+}
+
+// runBackup implements "orchestrator backup": fetch a backup from the leader
+// and store it in a local file or an S3-compatible bucket.
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	from := fs.String("from", "http://localhost:8080", "comma-separated control-plane HTTP addresses; the leader is found among them")
+	out := fs.String("out", "", "destination: a file path or s3://bucket/key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("-out is required")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	data, err := fetchBackup(ctx, splitCSV(*from), os.Getenv("BACKUP_TOKEN"))
+	if err != nil {
+		return err
+	}
+	// Verify before storing, so a bad transfer never replaces a good backup.
+	hdr, _, err := internalraft.ReadBackup(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("downloaded backup is invalid: %w", err)
+	}
+
+	bucket, key, isS3, err := storage.ParseURL(*out)
+	switch {
+	case err != nil:
+		return err
+	case isS3:
+		client, err := storageClient()
+		if err != nil {
+			return err
+		}
+		err = client.Put(ctx, bucket, key, bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return err
+		}
+	default:
+		if err := writeFileAtomic(*out, data); err != nil {
+			return err
+		}
+	}
+	slog.Info("backup written", "dest", *out, "index", hdr.Index, "term", hdr.Term,
+		"node_id", hdr.NodeID, "sha256", hdr.SHA256)
+	return nil
+}
+
+// fetchBackup asks each address in turn until the leader answers.
+func fetchBackup(ctx context.Context, addrs []string, token string) ([]byte, error) {
+	var errs []error
+	for _, addr := range addrs {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/raft/snapshot", nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+		case resp.StatusCode != http.StatusOK:
+			errs = append(errs, fmt.Errorf("%s: %s: %s", addr, resp.Status, strings.TrimSpace(string(body))))
+		default:
+			return body, nil
+		}
+	}
+	return nil, fmt.Errorf("no leader returned a backup: %w", errors.Join(errs...))
+}
+
+// runRestore implements "orchestrator restore": seed this node's empty
+// RAFT_DATA_DIR from a backup. The new cluster's membership comes from
+// NODE_ID, RAFT_ADDR and RAFT_PEERS, exactly as when the node starts.
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	in := fs.String("in", "", "backup source: a file path or s3://bucket/key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return errors.New("-in is required")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	var src io.ReadCloser
+	bucket, key, isS3, err := storage.ParseURL(*in)
+	switch {
+	case err != nil:
+		return err
+	case isS3:
+		client, err := storageClient()
+		if err != nil {
+			return err
+		}
+		if src, err = client.Get(ctx, bucket, key); err != nil {
+			return err
+		}
+	default:
+		if src, err = os.Open(*in); err != nil {
+			return err
+		}
+	}
+	defer src.Close()
+
+	cfg := internalraft.Config{
+		NodeID:   envOr("NODE_ID", "cp-unknown"),
+		RaftAddr: envOr("RAFT_ADDR", ":7000"),
+		DataDir:  envOr("RAFT_DATA_DIR", "/data/raft"),
+		Peers:    splitCSV(os.Getenv("RAFT_PEERS")),
+	}
+	hdr, err := internalraft.RestoreBackup(cfg, src)
+	if err != nil {
+		return err
+	}
+	slog.Info("restore complete — start the node to serve the restored state",
+		"data_dir", cfg.DataDir, "index", hdr.Index, "term", hdr.Term,
+		"source_node", hdr.NodeID, "taken_at", hdr.CreatedAt, "peers", cfg.Peers)
+	return nil
+}
+
+// storageClient builds an object-store client from the MINIO_* variables the
+// rest of the deployment already uses.
+func storageClient() (*storage.Client, error) {
+	return storage.New(storage.Config{
+		Endpoint:  envOr("MINIO_ENDPOINT", "http://localhost:9000"),
+		AccessKey: os.Getenv("MINIO_ROOT_USER"),
+		SecretKey: os.Getenv("MINIO_ROOT_PASSWORD"),
+		Region:    os.Getenv("MINIO_REGION"),
+	})
+}
+
+// writeFileAtomic writes data next to path and renames it into place.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
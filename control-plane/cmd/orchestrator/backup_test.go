@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetchBackupFindsLeader(t *testing.T) {
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not leader; retry against cp-2", http.StatusConflict)
+	}))
+	defer follower.Close()
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/raft/snapshot" || r.Header.Get("Authorization") != "Bearer s3cret" {
+			http.Error(w, "bad request", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("backup bytes"))
+	}))
+	defer leader.Close()
+
+	got, err := fetchBackup(t.Context(), []string{follower.URL, leader.URL + "/"}, "s3cret")
+	if err != nil || string(got) != "backup bytes" {
+		t.Fatalf("fetchBackup = %q, %v", got, err)
+	}
+
+	_, err = fetchBackup(t.Context(), []string{follower.URL}, "s3cret")
+	if err == nil || !strings.Contains(err.Error(), "retry against cp-2") {
+		t.Errorf("expected the follower's redirect hint in the error, got %v", err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluster.snap")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("new")); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("file contains %q", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temp file left behind: %v", entries)
+	}
+}
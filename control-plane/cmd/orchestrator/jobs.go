@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/scheduler"
+)
+
+// registerJobHandlers serves job status from the local FSM, in the same JSON
+// shape as the TaskService responses:
+//
+//	GET /jobs[?state=running]                 every job, optionally by state
+//	GET /jobs?job_id=<id>[&tasks=true]        one job with per-stage counts
+//	GET /tasks/dead-letters[?job_id=<id>]     tasks that failed for good
+//
+// Jobs are submitted over gRPC (TaskService.SubmitJob).
+func registerJobHandlers(mux *http.ServeMux, svc *scheduler.Service) {
+	mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		var (
+			resp proto.Message
+			err  error
+		)
+		if id := q.Get("job_id"); id != "" {
+			var got *taskpb.GetJobResponse
+			got, err = svc.GetJob(r.Context(), &taskpb.GetJobRequest{JobId: id, IncludeTasks: q.Get("tasks") == "true"})
+			if err == nil && !got.Ok {
+				http.Error(w, got.Error, http.StatusNotFound)
+				return
+			}
+			resp = got
+		} else {
+			req := &taskpb.ListJobsRequest{}
+			if st := q.Get("state"); st != "" {
+				v, ok := taskpb.JobState_value["JOB_STATE_"+strings.ToUpper(st)]
+				if !ok {
+					http.Error(w, "unknown job state "+st, http.StatusBadRequest)
+					return
+				}
+				req.State = taskpb.JobState(v)
+			}
+			resp, err = svc.ListJobs(r.Context(), req)
+		}
+		writeProto(w, resp, err)
+	})
+	mux.HandleFunc("/tasks/dead-letters", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		resp, err := svc.ListDeadLetters(r.Context(), &taskpb.ListDeadLettersRequest{JobId: r.URL.Query().Get("job_id")})
+		writeProto(w, resp, err)
+	})
+}
+
+// writeProto writes resp as JSON, or err as a server error.
+func writeProto(w http.ResponseWriter, resp proto.Message, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/scheduler"
+)
+
+// localityFromEnv reads the data-locality and egress settings for the task
+// scheduler. probes maps each cloud to an address the RTT prober dials there.
+func localityFromEnv() (loc *scheduler.Locality, rtt *scheduler.RTTTable, probes map[string]string, err error) {
+	var cfg scheduler.LocalityConfig
+	if cfg.Locations, err = scheduler.ParseDataLocations(os.Getenv("TASK_DATA_LOCATIONS")); err != nil {
+		return nil, nil, nil, fmt.Errorf("TASK_DATA_LOCATIONS: %w", err)
+	}
+	if cfg.EgressPerGB, err = scheduler.ParseCloudPairs(os.Getenv("TASK_EGRESS_COST_PER_GB"), scheduler.ParseCost); err != nil {
+		return nil, nil, nil, fmt.Errorf("TASK_EGRESS_COST_PER_GB: %w", err)
+	}
+	cfg.DefaultEgressPerGB = floatEnv("TASK_DEFAULT_EGRESS_COST_PER_GB", 0)
+	cfg.Wait = durationEnv("TASK_LOCALITY_WAIT", 30*time.Second)
+
+	configured, err := scheduler.ParseCloudPairs(os.Getenv("TASK_CLOUD_RTT"), time.ParseDuration)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("TASK_CLOUD_RTT: %w", err)
+	}
+	rtt = scheduler.NewRTTTable(configured)
+
+	probes = make(map[string]string)
+	for _, entry := range splitCSV(os.Getenv("TASK_RTT_PROBES")) {
+		cloud, addr, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || cloud == "" || addr == "" {
+			return nil, nil, nil, fmt.Errorf("TASK_RTT_PROBES: invalid entry %q, want cloud=host:port", entry)
+		}
+		probes[cloud] = addr
+	}
+	return scheduler.NewLocality(cfg, rtt), rtt, probes, nil
+}
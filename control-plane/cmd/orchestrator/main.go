@@ -21,9 +21,12 @@ import (
 	"google.golang.org/grpc/reflection"
 
 	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/agent"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/clock"
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
 	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
-	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/pki"
 	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/scheduler"
 )
 
 func main() {
@@ -32,6 +35,26 @@ func main() {
 	}))
 	slog.SetDefault(logger)
 
+	// Offline subcommands; with no arguments the binary runs a control-plane node.
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "backup":
+			err = runBackup(os.Args[2:])
+		case "restore":
+			err = runRestore(os.Args[2:])
+		case "recover":
+			err = runRecover(os.Args[2:])
+		default:
+			err = fmt.Errorf("unknown command %q (want backup, restore or recover)", os.Args[1])
+		}
+		if err != nil {
+			slog.Error(os.Args[1]+" failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	nodeID := envOr("NODE_ID", "cp-unknown")
 	grpcAddr := envOr("GRPC_ADDR", ":50051")
 	httpAddr := envOr("HTTP_ADDR", ":8080")
@@ -39,6 +62,15 @@ func main() {
 	raftDataDir := envOr("RAFT_DATA_DIR", "/data/raft")
 	raftBootstrap := boolEnv("RAFT_BOOTSTRAP")
 	raftPeers := splitCSV(os.Getenv("RAFT_PEERS"))
+	// RAFT_TRANSPORT=grpc carries Raft on the gRPC server instead of its own
+	// TCP listener; RAFT_ADDR and RAFT_PEERS then name gRPC host:ports.
+	raftTransport := envOr("RAFT_TRANSPORT", "tcp")
+	raftTLS := internalraft.TLSConfig{
+		CertFile:       os.Getenv("RAFT_TLS_CERT_FILE"),
+		KeyFile:        os.Getenv("RAFT_TLS_KEY_FILE"),
+		CAFile:         os.Getenv("RAFT_TLS_CA_FILE"),
+		ReloadInterval: durationEnv("RAFT_TLS_RELOAD_INTERVAL", 30*time.Second),
+	}
 
 	slog.Info("control plane starting",
 		"node_id", nodeID,
@@ -48,17 +80,49 @@ func main() {
 		"raft_data_dir", raftDataDir,
 		"raft_bootstrap", raftBootstrap,
 		"raft_peers", raftPeers,
+		"raft_transport", raftTransport,
+		"raft_tls", raftTLS.Enabled(),
 	)
 
 	// ── Raft node ────────────────────────────────────────────────
+	warnRecoveryHistory(raftDataDir)
 	fsm := internalraft.NewPipelineFSM()
-	raftNode, err := internalraft.NewRaftNode(internalraft.Config{
+	raftCfg := internalraft.Config{
 		NodeID:    nodeID,
 		RaftAddr:  raftAddr,
 		DataDir:   raftDataDir,
 		Bootstrap: raftBootstrap,
 		Peers:     raftPeers,
-	}, fsm)
+		TLS:       raftTLS,
+	}
+	var (
+		raftNode *internalraft.RaftNode
+		grpcRaft *internalraft.GRPCTransport
+		grpcTLS  *internalraft.GRPCTLS
+		err      error
+	)
+	switch raftTransport {
+	case "tcp":
+		if !raftTLS.Enabled() {
+			slog.Warn("RAFT_TLS_CERT_FILE not set — Raft traffic is plaintext")
+		}
+		raftNode, err = internalraft.NewRaftNode(raftCfg, fsm)
+	case "grpc":
+		// Raft shares the worker-facing gRPC server, so it is only safe when
+		// that server can tell nodes from everyone else by certificate.
+		if !raftTLS.Enabled() {
+			slog.Error("RAFT_TRANSPORT=grpc requires RAFT_TLS_CERT_FILE, RAFT_TLS_KEY_FILE and RAFT_TLS_CA_FILE")
+			os.Exit(1)
+		}
+		grpcTLS, err = internalraft.NewGRPCTLS(raftTLS, nodeID, raftAddr, raftPeers, fsm)
+		if err != nil {
+			break
+		}
+		grpcRaft = internalraft.NewGRPCTransportTLS(raftAddr, grpcTLS)
+		raftNode, err = internalraft.NewRaftNodeWithTransport(raftCfg, fsm, grpcRaft)
+	default:
+		err = fmt.Errorf("unknown RAFT_TRANSPORT %q (want tcp or grpc)", raftTransport)
+	}
 	if err != nil {
 		slog.Error("failed to start raft node", "error", err)
 		os.Exit(1)
@@ -70,35 +134,112 @@ func main() {
 	if err != nil {
 		grpcPort = "50051"
 	}
-	registry := agent.NewAgentRegistry(raftNode, grpcPort)
+	agentCfg := agent.DefaultConfig()
+	agentCfg.Detector.SuspectThreshold = floatEnv("PHI_SUSPECT_THRESHOLD", agentCfg.Detector.SuspectThreshold)
+	agentCfg.Detector.OfflineThreshold = floatEnv("PHI_OFFLINE_THRESHOLD", agentCfg.Detector.OfflineThreshold)
+	agentCfg.Detector.MinStdDev = durationEnv("PHI_MIN_STDDEV", agentCfg.Detector.MinStdDev)
+	agentCfg.Detector.AcceptablePause = durationEnv("PHI_ACCEPTABLE_PAUSE", agentCfg.Detector.AcceptablePause)
+	agentCfg.Detector.FirstHeartbeat = time.Duration(
+		intEnv("WORKER_HEARTBEAT_INTERVAL_S", int(agentCfg.Detector.FirstHeartbeat/time.Second))) * time.Second
+	agentCfg.Flap.Window = durationEnv("FLAP_WINDOW", agentCfg.Flap.Window)
+	agentCfg.Flap.MaxTransitions = intEnv("FLAP_MAX_TRANSITIONS", agentCfg.Flap.MaxTransitions)
+	agentCfg.Flap.BaseBackoff = durationEnv("FLAP_BASE_BACKOFF", agentCfg.Flap.BaseBackoff)
+	agentCfg.Flap.MaxBackoff = durationEnv("FLAP_MAX_BACKOFF", agentCfg.Flap.MaxBackoff)
+	agentCfg.Reaper.Retention = durationEnv("WORKER_RETENTION", agentCfg.Reaper.Retention)
+	agentCfg.Reaper.Interval = durationEnv("REAPER_INTERVAL", agentCfg.Reaper.Interval)
+	agentCfg.Auth.JoinToken = os.Getenv("WORKER_JOIN_TOKEN")
+	if agentCfg.Auth.JoinToken == "" {
+		slog.Warn("WORKER_JOIN_TOKEN not set — worker authentication disabled")
+	}
+	agentCfg.Auth.OperatorToken = os.Getenv("OPERATOR_TOKEN")
+	if agentCfg.Auth.JoinToken != "" && agentCfg.Auth.OperatorToken == "" {
+		slog.Warn("OPERATOR_TOKEN not set — TaskService operator calls will be refused")
+	}
+	registry := agent.NewAgentRegistryWithConfig(raftNode, fsm, grpcPort, agentCfg)
+	registry.SetKillSource(fsm)
 	registryCtx, registryCancel := context.WithCancel(context.Background())
 	registry.Start(registryCtx)
 
-	// ── Prometheus stats polling (every 5s) ──────────────────────
-	statsCtx, statsCancel := context.WithCancel(context.Background())
-	go func() {
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
-		var lastTerm uint64
-		for {
-			select {
-			case <-statsCtx.Done():
-				return
-			case <-ticker.C:
-				stats := raftNode.Stats()
-				metrics.RaftState.Set(raftNode.StateFloat())
-				if termStr, ok := stats["term"]; ok {
-					if term, err := strconv.ParseUint(termStr, 10, 64); err == nil {
-						metrics.RaftTerm.Set(float64(term))
-						if term > lastTerm && lastTerm > 0 {
-							metrics.RaftElectionsTotal.Inc()
-						}
-						lastTerm = term
-					}
-				}
+	// ── Task service (submission, pull and push assignment) ──────
+	taskCfg := scheduler.DefaultConfig()
+	taskCfg.LeaseDuration = durationEnv("TASK_LEASE_DURATION", taskCfg.LeaseDuration)
+	taskCfg.MaxPollWait = durationEnv("TASK_MAX_POLL_WAIT", taskCfg.MaxPollWait)
+	taskCfg.ScheduleInterval = durationEnv("TASK_SCHEDULE_INTERVAL", taskCfg.ScheduleInterval)
+	taskCfg.Retry.MaxAttempts = intEnv("TASK_MAX_ATTEMPTS", taskCfg.Retry.MaxAttempts)
+	taskCfg.Retry.InitialBackoff = durationEnv("TASK_RETRY_INITIAL_BACKOFF", taskCfg.Retry.InitialBackoff)
+	taskCfg.Retry.MaxBackoff = durationEnv("TASK_RETRY_MAX_BACKOFF", taskCfg.Retry.MaxBackoff)
+	taskCfg.Retry.Multiplier = floatEnv("TASK_RETRY_MULTIPLIER", taskCfg.Retry.Multiplier)
+	taskCfg.Retry.Jitter = floatEnv("TASK_RETRY_JITTER", taskCfg.Retry.Jitter)
+	if err := taskCfg.Retry.Validate(); err != nil {
+		slog.Error("invalid task retry config", "error", err)
+		os.Exit(1)
+	}
+	taskCfg.SpeculationPercentile = floatEnv("TASK_SPECULATION_PERCENTILE", taskCfg.SpeculationPercentile)
+	taskCfg.SpeculationMinSamples = intEnv("TASK_SPECULATION_MIN_SAMPLES", taskCfg.SpeculationMinSamples)
+	if p := taskCfg.SpeculationPercentile; p < 0 || p > 100 {
+		slog.Error("invalid task speculation config: percentile must be between 0 and 100", "percentile", p)
+		os.Exit(1)
+	}
+	taskCfg.TaskTimeout = durationEnv("TASK_TIMEOUT", taskCfg.TaskTimeout)
+	taskCfg.KillGrace = durationEnv("TASK_KILL_GRACE", taskCfg.KillGrace)
+	if taskCfg.TaskTimeout < 0 || taskCfg.KillGrace < 0 {
+		slog.Error("invalid task deadline config: TASK_TIMEOUT and TASK_KILL_GRACE must not be negative")
+		os.Exit(1)
+	}
+	taskSvc := scheduler.NewServiceWithConfig(raftNode, fsm, registry.LeaderGRPCAddr, taskCfg)
+	taskSvc.SetEpochValidator(registry)
+	locality, cloudRTT, rttProbes, err := localityFromEnv()
+	if err != nil {
+		slog.Error("invalid task locality config", "error", err)
+		os.Exit(1)
+	}
+	taskSvc.SetLocality(locality)
+	if nodeCloud := os.Getenv("CLOUD_TAG"); nodeCloud != "" && len(rttProbes) > 0 {
+		scheduler.ProbeRTT(registryCtx, clock.Real{}, cloudRTT, nodeCloud, rttProbes,
+			durationEnv("TASK_RTT_PROBE_INTERVAL", 10*time.Second))
+	}
+	taskSvc.Start(registryCtx)
+
+	// ── Built-in certificate authority ───────────────────────────
+	var ca *pki.Authority
+	pkiNodeToken := os.Getenv("PKI_NODE_TOKEN")
+	if sealKey := os.Getenv("PKI_SEAL_KEY"); sealKey != "" {
+		key, err := pki.ParseKey(sealKey)
+		if err != nil {
+			slog.Error("invalid PKI_SEAL_KEY", "error", err)
+			os.Exit(1)
+		}
+		sealer, err := pki.NewSealer(key)
+		if err != nil {
+			slog.Error("failed to create PKI sealer", "error", err)
+			os.Exit(1)
+		}
+		pkiCfg := pki.DefaultConfig()
+		pkiCfg.RootValidity = durationEnv("PKI_ROOT_VALIDITY", pkiCfg.RootValidity)
+		pkiCfg.NodeCertTTL = durationEnv("PKI_NODE_CERT_TTL", pkiCfg.NodeCertTTL)
+		pkiCfg.WorkerCertTTL = durationEnv("PKI_WORKER_CERT_TTL", pkiCfg.WorkerCertTTL)
+		ca = pki.NewAuthority(raftNode, fsm, sealer, pkiCfg)
+		registry.SetCertIssuer(ca)
+		go ensureCARootLoop(registryCtx, ca, raftNode)
+		if raftTLS.Enabled() {
+			nodeTLS, err := internalraft.NewNodeTLS(raftTLS, nodeID, raftAddr, raftPeers, fsm)
+			if err != nil {
+				slog.Error("failed to load node TLS for PKI renewal", "error", err)
+				os.Exit(1)
+			}
+			renewAddr := envOr("PKI_RENEW_ADDR", ":8443")
+			go servePKIRenewal(registryCtx, renewAddr, nodeTLS, ca, raftNode)
+			if boolEnv("PKI_AUTO_RENEW") {
+				go nodeCertRenewer(ca, raftNode, raftTLS, nodeTLS, nodeID, renewAddr).Run(registryCtx)
 			}
 		}
-	}()
+	} else {
+		slog.Warn("PKI_SEAL_KEY not set — built-in certificate authority disabled")
+	}
+
+	// ── Prometheus stats polling (every 5s) ──────────────────────
+	statsCtx, statsCancel := context.WithCancel(context.Background())
+	internalraft.PollStats(statsCtx, raftNode, clock.Real{}, 5*time.Second)
 
 	// ── gRPC server ──────────────────────────────────────────────
 	lis, err := net.Listen("tcp", grpcAddr)
@@ -106,11 +247,23 @@ func main() {
 		slog.Error("failed to listen on grpc addr", "addr", grpcAddr, "error", err)
 		os.Exit(1)
 	}
-	grpcServer := grpc.NewServer()
+	grpcOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(registry.UnaryAuthInterceptor())}
+	if grpcTLS != nil {
+		// Workers must then connect over TLS too; Raft calls need a node cert.
+		grpcOpts = append(grpcOpts,
+			grpc.Creds(grpcTLS.ServerCredentials()),
+			grpc.ChainUnaryInterceptor(grpcTLS.UnaryInterceptor()),
+			grpc.ChainStreamInterceptor(grpcTLS.StreamInterceptor()))
+	}
+	grpcServer := grpc.NewServer(grpcOpts...)
 	healthSvc := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthSvc)
 	healthSvc.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
 	workerpb.RegisterWorkerServiceServer(grpcServer, registry)
+	taskpb.RegisterTaskServiceServer(grpcServer, taskSvc)
+	if grpcRaft != nil {
+		grpcRaft.Register(grpcServer)
+	}
 	reflection.Register(grpcServer)
 
 	// ── HTTP debug server ────────────────────────────────────────
@@ -136,22 +289,46 @@ func main() {
 	mux.HandleFunc("/cluster-state", func(w http.ResponseWriter, r *http.Request) {
 		workers := fsm.Workers()
 		list := make([]*internalraft.WorkerInfo, 0, len(workers))
+		quarantined := make([]*internalraft.WorkerInfo, 0)
 		for _, info := range workers {
 			list = append(list, info)
+			if info.Status == internalraft.WorkerQuarantined {
+				quarantined = append(quarantined, info)
+			}
 		}
 		resp := struct {
-			NodeID  string                     `json:"node_id"`
-			State   string                     `json:"state"`
-			Workers []*internalraft.WorkerInfo `json:"workers"`
+			NodeID      string                          `json:"node_id"`
+			State       string                          `json:"state"`
+			Workers     []*internalraft.WorkerInfo      `json:"workers"`
+			Quarantined []*internalraft.WorkerInfo      `json:"quarantined"`
+			Tombstones  []internalraft.WorkerTombstone  `json:"tombstones"`
+			Revocations []internalraft.WorkerRevocation `json:"revocations"`
 		}{
-			NodeID:  nodeID,
-			State:   raftNode.State().String(),
-			Workers: list,
+			NodeID:      nodeID,
+			State:       raftNode.State().String(),
+			Workers:     list,
+			Quarantined: quarantined,
+			Tombstones:  fsm.Tombstones(),
+			Revocations: fsm.Revocations(),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(resp)
 	})
 
+	registerWorkerHandlers(mux, registry, raftNode.LeaderID, agentCfg.Auth.OperatorToken)
+
+	registerJobHandlers(mux, taskSvc)
+
+	if ca != nil {
+		registerPKIHandlers(mux, ca, fsm, raftNode, pkiNodeToken)
+	}
+
+	if backupToken := os.Getenv("BACKUP_TOKEN"); backupToken != "" {
+		registerBackupHandlers(mux, raftNode, backupToken)
+	} else {
+		slog.Warn("BACKUP_TOKEN not set — snapshot backup endpoint disabled")
+	}
+
 	mux.Handle("/metrics", promhttp.Handler())
 
 	httpServer := &http.Server{Addr: httpAddr, Handler: mux}
@@ -204,6 +381,33 @@ func boolEnv(key string) bool {
 	return os.Getenv(key) == "true"
 }
 
+// intEnv parses key as an integer, returning fallback if unset or invalid.
+func intEnv(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+// floatEnv parses key as a float64, returning fallback if unset or invalid.
+func floatEnv(key string, fallback float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+// durationEnv parses key as a Go duration (e.g. "500ms"), returning fallback if unset or invalid.
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
 // splitCSV splits a comma-separated string, returning nil for empty input.
 func splitCSV(s string) []string {
 	if s == "" {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 )
 
 func TestEnvOr(t *testing.T) {
@@ -50,3 +51,31 @@ func TestSplitCSV(t *testing.T) {
 		t.Errorf("unexpected result: %v", got)
 	}
 }
+
+func TestNumericEnv(t *testing.T) {
+	t.Setenv("N", "42")
+	if got := intEnv("N", 7); got != 42 {
+		t.Errorf("intEnv: expected 42, got %d", got)
+	}
+	t.Setenv("N", "nope")
+	if got := intEnv("N", 7); got != 7 {
+		t.Errorf("intEnv: expected fallback 7 for invalid value, got %d", got)
+	}
+
+	t.Setenv("F", "2.5")
+	if got := floatEnv("F", 1); got != 2.5 {
+		t.Errorf("floatEnv: expected 2.5, got %v", got)
+	}
+	if got := floatEnv("MISSING_KEY", 1); got != 1 {
+		t.Errorf("floatEnv: expected fallback 1, got %v", got)
+	}
+
+	t.Setenv("D", "750ms")
+	if got := durationEnv("D", time.Second); got != 750*time.Millisecond {
+		t.Errorf("durationEnv: expected 750ms, got %s", got)
+	}
+	t.Setenv("D", "750")
+	if got := durationEnv("D", time.Second); got != time.Second {
+		t.Errorf("durationEnv: expected fallback for unitless value, got %s", got)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/pki"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// maxCSRBytes bounds the body accepted by POST /pki/sign.
+const maxCSRBytes = 64 << 10
+
+// caRootView is a CARoot without its sealed key, for the audit endpoint.
+type caRootView struct {
+	ID        string    `json:"id"`
+	CertPEM   string    `json:"cert_pem"`
+	NotAfter  time.Time `json:"not_after"`
+	AddedAt   time.Time `json:"added_at"`
+	Active    bool      `json:"active"`
+	RetiredAt time.Time `json:"retired_at,omitzero"`
+}
+
+// registerPKIHandlers exposes the built-in CA on the HTTP server:
+//
+//	GET  /pki/ca                                   trust bundle (PEM)
+//	GET  /pki/certs                                roots and issued/revoked serials
+//	POST /pki/sign?kind=node&subject=<node id>     CSR in body → signed certificate for a Raft server
+//	POST /pki/rotate                               new active root; old roots stay trusted
+//	POST /pki/retire?root_id=<id>[&force=true]     drop a root from the trust bundle
+//	POST /pki/revoke?serial=<hex>&reason=<text>    revoke an issued certificate
+//
+// POST endpoints require the X-Node-Token header and must reach the leader.
+// /pki/sign only signs for server IDs in the Raft configuration; running nodes
+// renew over mutual TLS instead (servePKIRenewal). Workers obtain certificates
+// over gRPC (IssueCertificate).
+func registerPKIHandlers(mux *http.ServeMux, ca *pki.Authority, fsm *internalraft.PipelineFSM,
+	raftNode *internalraft.RaftNode, nodeToken string) {
+
+	mux.HandleFunc("/pki/ca", func(w http.ResponseWriter, r *http.Request) {
+		bundle := ca.TrustBundle()
+		if len(bundle) == 0 {
+			http.Error(w, pki.ErrNoActiveRoot.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		_, _ = w.Write(bundle)
+	})
+
+	mux.HandleFunc("/pki/certs", func(w http.ResponseWriter, r *http.Request) {
+		roots := fsm.CARoots()
+		views := make([]caRootView, len(roots))
+		for i, root := range roots {
+			views[i] = caRootView{ID: root.ID, CertPEM: root.CertPEM, NotAfter: root.NotAfter,
+				AddedAt: root.AddedAt, Active: root.Active, RetiredAt: root.RetiredAt}
+		}
+		resp := struct {
+			Roots []caRootView              `json:"roots"`
+			Certs []internalraft.IssuedCert `json:"certs"`
+		}{Roots: views, Certs: fsm.IssuedCerts()}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	// adminPost wraps the mutating endpoints with method, token and error handling.
+	adminPost := func(path string, h func(r *http.Request) (interface{}, error)) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if nodeToken == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(pki.NodeTokenHeader)), []byte(nodeToken)) != 1 {
+				http.Error(w, "invalid node token", http.StatusUnauthorized)
+				return
+			}
+			resp, err := h(r)
+			switch {
+			case errors.Is(err, pki.ErrNotLeader):
+				http.Error(w, "not leader; retry against "+raftNode.LeaderID(), http.StatusConflict)
+			case errors.Is(err, pki.ErrInvalidCSR), errors.Is(err, pki.ErrRootInUse):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case err != nil:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			default:
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(resp)
+			}
+		})
+	}
+
+	adminPost("/pki/sign", func(r *http.Request) (interface{}, error) {
+		if kind := r.URL.Query().Get("kind"); kind != pki.KindNode {
+			return nil, fmt.Errorf("%w: only kind=node is issued over HTTP", pki.ErrInvalidCSR)
+		}
+		subject := r.URL.Query().Get("subject")
+		if subject == "" {
+			return nil, fmt.Errorf("%w: subject is required", pki.ErrInvalidCSR)
+		}
+		if !raftNode.IsServer(subject) {
+			return nil, fmt.Errorf("%w: %q is not a raft server", pki.ErrInvalidCSR, subject)
+		}
+		csr, err := io.ReadAll(io.LimitReader(r.Body, maxCSRBytes))
+		if err != nil {
+			return nil, err
+		}
+		return ca.Sign(pki.KindNode, subject, csr)
+	})
+
+	adminPost("/pki/rotate", func(r *http.Request) (interface{}, error) {
+		id, err := ca.Rotate()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"root_id": id}, nil
+	})
+
+	adminPost("/pki/retire", func(r *http.Request) (interface{}, error) {
+		id := r.URL.Query().Get("root_id")
+		if err := ca.Retire(id, r.URL.Query().Get("force") == "true"); err != nil {
+			return nil, err
+		}
+		return map[string]string{"retired": id}, nil
+	})
+
+	adminPost("/pki/revoke", func(r *http.Request) (interface{}, error) {
+		serial := r.URL.Query().Get("serial")
+		if err := ca.Revoke(serial, r.URL.Query().Get("reason")); err != nil {
+			return nil, err
+		}
+		return map[string]string{"revoked": serial}, nil
+	})
+}
+
+// ensureCARootLoop creates the first CA root once this node leads a cluster
+// that has none.
+func ensureCARootLoop(ctx context.Context, ca *pki.Authority, raftNode *internalraft.RaftNode) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if raftNode.State() != hashiraft.Leader {
+				continue
+			}
+			if err := ca.EnsureRoot(); err != nil {
+				slog.Warn("CA root initialisation failed", "error", err)
+			}
+		}
+	}
+}
+
+// nodeSigner signs certificates; *pki.Authority implements it.
+type nodeSigner interface {
+	Sign(kind, subject string, csrPEM []byte) (*pki.Issued, error)
+}
+
+// servePKIRenewal serves
+//
+//	POST /pki/renew    CSR in body → node certificate for the caller
+//
+// on addr over mutual TLS until ctx is cancelled. Callers must present a node
+// certificate naming a Raft server, and the new certificate is issued to that
+// server ID. Only the leader signs; followers answer 409.
+func servePKIRenewal(ctx context.Context, addr string, nodeTLS *internalraft.NodeTLS,
+	ca nodeSigner, raftNode *internalraft.RaftNode) {
+
+	mux := http.NewServeMux()
+	mux.Handle("/pki/renew", renewHandler(ca, nodeTLS.PeerID, raftNode.LeaderID))
+	srv := &http.Server{Addr: addr, Handler: mux, TLSConfig: nodeTLS.ServerConfig(),
+		ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	slog.Info("PKI renewal server starting", "addr", addr)
+	if err := srv.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("PKI renewal server error", "error", err)
+	}
+}
+
+// renewHandler signs the CSR in the body for the node peerID finds in the
+// client certificate — never for a subject the caller names.
+func renewHandler(ca nodeSigner, peerID func(*tls.ConnectionState) (string, error),
+	leaderID func() string) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		subject, err := peerID(r.TLS)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		csr, err := io.ReadAll(io.LimitReader(r.Body, maxCSRBytes))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		issued, err := ca.Sign(pki.KindNode, subject, csr)
+		switch {
+		case errors.Is(err, pki.ErrNotLeader):
+			http.Error(w, "not leader; retry against "+leaderID(), http.StatusConflict)
+		case errors.Is(err, pki.ErrInvalidCSR):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			slog.Info("node certificate renewed", "subject", subject)
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(issued)
+		}
+	})
+}
+
+// nodeCertRenewer keeps this node's Raft TLS certificate fresh. The leader
+// signs locally; followers send the CSR to the leader's /pki/renew over mutual
+// TLS, authenticated by the certificate being renewed. The trust bundle is
+// read from the local FSM replica.
+func nodeCertRenewer(ca *pki.Authority, raftNode *internalraft.RaftNode, tlsCfg internalraft.TLSConfig,
+	nodeTLS *internalraft.NodeTLS, nodeID, renewAddr string) *pki.Renewer {
+
+	_, renewPort, err := net.SplitHostPort(renewAddr)
+	if err != nil {
+		renewPort = "8443"
+	}
+	return &pki.Renewer{
+		CertFile:      tlsCfg.CertFile,
+		KeyFile:       tlsCfg.KeyFile,
+		CAFile:        tlsCfg.CAFile,
+		Subject:       nodeID,
+		RenewBefore:   1.0 / 3,
+		CheckInterval: time.Minute,
+		Issue: func(ctx context.Context, csrPEM []byte) (*pki.Issued, error) {
+			if raftNode.State() == hashiraft.Leader {
+				return ca.Sign(pki.KindNode, nodeID, csrPEM)
+			}
+			leader := raftNode.LeaderID()
+			if leader == "" {
+				return nil, errors.New("no raft leader to sign node certificate")
+			}
+			client := &pki.Client{HTTP: &http.Client{
+				Timeout:   10 * time.Second,
+				Transport: &http.Transport{TLSClientConfig: nodeTLS.ClientConfig(leader)},
+			}}
+			return client.Renew(ctx, "https://"+net.JoinHostPort(leader, renewPort), csrPEM)
+		},
+		Bundle: func(context.Context) ([]byte, error) {
+			bundle := ca.TrustBundle()
+			if len(bundle) == 0 {
+				return nil, pki.ErrNoActiveRoot
+			}
+			return bundle, nil
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/pki"
+)
+
+type stubSigner struct{ subjects []string }
+
+func (s *stubSigner) Sign(kind, subject string, _ []byte) (*pki.Issued, error) {
+	s.subjects = append(s.subjects, kind+"/"+subject)
+	return &pki.Issued{}, nil
+}
+
+func TestRenewSignsForVerifiedPeerOnly(t *testing.T) {
+	peerID := func(cs *tls.ConnectionState) (string, error) {
+		if cs == nil {
+			return "", errors.New("no client certificate")
+		}
+		return "cp-2", nil
+	}
+	signer := &stubSigner{}
+	h := renewHandler(signer, peerID, func() string { return "cp-1" })
+
+	req := httptest.NewRequest(http.MethodPost, "/pki/renew?subject=cp-1", strings.NewReader("csr"))
+	req.TLS = &tls.ConnectionState{}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if len(signer.subjects) != 1 || signer.subjects[0] != "node/cp-2" {
+		t.Errorf("signed %v, want [node/cp-2] from the peer certificate", signer.subjects)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/pki/renew", strings.NewReader("csr"))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without TLS: status %d, want 403", rec.Code)
+	}
+	if len(signer.subjects) != 1 {
+		t.Errorf("signed without a verified peer: %v", signer.subjects)
+	}
+}
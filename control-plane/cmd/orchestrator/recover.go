@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// runRecover implements "orchestrator recover": rewrite this stopped node's
+// Raft membership after permanent quorum loss. See docs/disaster_recovery.md.
+func runRecover(args []string) error {
+	fs := flag.NewFlagSet("recover", flag.ContinueOnError)
+	peers := fs.String("peers", "", "new cluster membership in RAFT_PEERS syntax; must include NODE_ID")
+	reason := fs.String("reason", "", "why quorum was lost; recorded in the audit log")
+	confirm := fs.Bool("confirm", false, "acknowledge that entries only the lost nodes had are discarded")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case !*confirm:
+		return errors.New("refusing to recover without -confirm; read docs/disaster_recovery.md first")
+	case *peers == "":
+		return errors.New("-peers is required")
+	case *reason == "":
+		return errors.New("-reason is required")
+	}
+
+	cfg := internalraft.Config{
+		NodeID:   envOr("NODE_ID", "cp-unknown"),
+		RaftAddr: envOr("RAFT_ADDR", ":7000"),
+		DataDir:  envOr("RAFT_DATA_DIR", "/data/raft"),
+	}
+	operator := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		operator = u.Username
+	}
+
+	slog.Warn("MANUAL RAFT RECOVERY — rewriting cluster membership from local state",
+		"node_id", cfg.NodeID, "data_dir", cfg.DataDir, "new_peers", *peers, "operator", operator)
+	rec, err := internalraft.RecoverCluster(cfg, splitCSV(*peers), operator, *reason)
+	if err != nil {
+		return err
+	}
+	slog.Warn("MANUAL RAFT RECOVERY COMPLETE — start this node (and every other recovered peer) now",
+		"node_id", rec.NodeID, "index", rec.Index, "term", rec.Term,
+		"previous_servers", fmt.Sprint(rec.Previous), "new_servers", fmt.Sprint(rec.New),
+		"reason", rec.Reason)
+	return nil
+}
+
+// warnRecoveryHistory repeats the latest recovery at every startup, so a
+// recovered cluster never looks like an untouched one in the logs.
+func warnRecoveryHistory(dataDir string) {
+	history, err := internalraft.RecoveryHistory(dataDir)
+	if err != nil {
+		slog.Error("cannot read recovery audit log", "data_dir", dataDir, "error", err)
+		return
+	}
+	if len(history) == 0 {
+		return
+	}
+	last := history[len(history)-1]
+	slog.Warn("this node's Raft state was manually recovered",
+		"recoveries", len(history), "last_at", last.At, "last_operator", last.Operator,
+		"last_reason", last.Reason, "new_servers", fmt.Sprint(last.New))
+}
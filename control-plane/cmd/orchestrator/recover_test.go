@@ -0,0 +1,23 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunRecoverRequiresConfirmation(t *testing.T) {
+	t.Setenv("RAFT_DATA_DIR", t.TempDir())
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-peers", "n1", "-reason", "lost two"}, "-confirm"},
+		{[]string{"-confirm", "-reason", "lost two"}, "-peers"},
+		{[]string{"-confirm", "-peers", "n1"}, "-reason"},
+	} {
+		err := runRecover(tc.args)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("runRecover(%q) = %v, want an error mentioning %s", tc.args, err, tc.want)
+		}
+	}
+}
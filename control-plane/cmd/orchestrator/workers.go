@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/agent"
+)
+
+// workerRevoker is the part of the agent registry the worker endpoints use.
+type workerRevoker interface {
+	RevokeWorker(workerID, reason string) error
+}
+
+// registerWorkerHandlers exposes
+//
+//	POST /workers/revoke?worker_id=<id>&reason=<text>   revoke a worker's credential (leader only)
+//
+// Revocation deletes the worker's credential and requeues its tasks, so it
+// requires "Authorization: Bearer <OPERATOR_TOKEN>"; without an operator
+// token every request is refused.
+func registerWorkerHandlers(mux *http.ServeMux, registry workerRevoker, leaderID func() string, operatorToken string) {
+	mux.HandleFunc("/workers/revoke", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || operatorToken == "" || subtle.ConstantTimeCompare([]byte(got), []byte(operatorToken)) != 1 {
+			http.Error(w, "invalid operator token", http.StatusUnauthorized)
+			return
+		}
+		workerID := r.URL.Query().Get("worker_id")
+		if workerID == "" {
+			http.Error(w, "worker_id is required", http.StatusBadRequest)
+			return
+		}
+		err := registry.RevokeWorker(workerID, r.URL.Query().Get("reason"))
+		switch {
+		case errors.Is(err, agent.ErrNotLeader):
+			http.Error(w, "not leader; retry against "+leaderID(), http.StatusConflict)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"revoked":%q}`, workerID)
+		}
+	})
+}
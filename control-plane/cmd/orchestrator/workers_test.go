@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubRevoker struct{ revoked []string }
+
+func (s *stubRevoker) RevokeWorker(workerID, _ string) error {
+	s.revoked = append(s.revoked, workerID)
+	return nil
+}
+
+func TestRevokeRequiresOperatorToken(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		operatorToken string
+		authorization string
+		want          int
+	}{
+		{"no credential", "op-secret", "", http.StatusUnauthorized},
+		{"wrong token", "op-secret", "Bearer nope", http.StatusUnauthorized},
+		{"token without bearer", "op-secret", "op-secret", http.StatusUnauthorized},
+		{"no operator token configured", "", "Bearer ", http.StatusUnauthorized},
+		{"operator token", "op-secret", "Bearer op-secret", http.StatusOK},
+	} {
+		revoker := &stubRevoker{}
+		mux := http.NewServeMux()
+		registerWorkerHandlers(mux, revoker, func() string { return "cp-1" }, tc.operatorToken)
+
+		req := httptest.NewRequest(http.MethodPost, "/workers/revoke?worker_id=w-1", nil)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.want)
+		}
+		if revoked := len(revoker.revoked) > 0; revoked != (tc.want == http.StatusOK) {
+			t.Errorf("%s: revoked = %v", tc.name, revoker.revoked)
+		}
+	}
+}
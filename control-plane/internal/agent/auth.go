@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	hashiraft "github.com/hashicorp/raft"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// Metadata keys used by workers to authenticate.
+const (
+	joinTokenMetadataKey = "x-join-token"
+	authMetadataKey      = "authorization"
+	bearerPrefix         = "Bearer "
+)
+
+// AuthConfig controls worker and operator authentication. An empty JoinToken
+// disables both.
+type AuthConfig struct {
+	// JoinToken is the shared bootstrap secret a worker presents once, on
+	// RegisterWorker, in exchange for a per-worker credential.
+	JoinToken string
+	// OperatorToken is the bearer credential required on TaskService calls
+	// that do not act for a worker (submitting, cancelling and inspecting
+	// tasks and jobs). Empty with auth enabled refuses those calls.
+	OperatorToken string
+}
+
+// ErrNotLeader is returned by leader-only administrative calls on a follower.
+var ErrNotLeader = errors.New("not the raft leader")
+
+// workerIDRequest is satisfied by every WorkerService request message and by
+// the worker-facing TaskService requests.
+type workerIDRequest interface {
+	GetWorkerId() string
+}
+
+type workerIdentityKey struct{}
+
+// WorkerIdentity returns the worker ID verified by the auth interceptor, if any.
+func WorkerIdentity(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(workerIdentityKey{}).(string)
+	return id, ok
+}
+
+// AuthEnabled reports whether worker authentication is enforced.
+func (r *AgentRegistry) AuthEnabled() bool {
+	return r.cfg.Auth.JoinToken != ""
+}
+
+// UnaryAuthInterceptor authenticates WorkerService calls, the TaskService
+// calls workers make (those whose request carries a worker_id) and the
+// TaskService calls operators make (every other TaskService call).
+//
+// RegisterWorker must carry the bootstrap join token, and also the current
+// credential if the worker_id is registered and not offline, so the join token
+// alone cannot take over a live worker's identity. Operator calls must carry
+// the operator token as a bearer credential. Every other call must carry the
+// credential issued to the request's worker_id, so a worker can only act as
+// itself. Revoked workers are refused outright. Followers skip the
+// credential check on non-register calls — they only return a redirect, and
+// their copy of the FSM may not have the newest credential yet.
+func (r *AgentRegistry) UnaryAuthInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		if !r.AuthEnabled() || !isWorkerFacing(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		wr, ok := req.(workerIDRequest)
+		if !ok {
+			if !strings.HasPrefix(info.FullMethod, "/task.TaskService/") {
+				return handler(ctx, req)
+			}
+			if err := r.verifyOperator(firstMD(md, authMetadataKey)); err != nil {
+				metrics.WorkerAuthFailuresTotal.WithLabelValues("operator").Inc()
+				slog.Warn("operator authentication failed", "method", info.FullMethod, "error", err)
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+			return handler(ctx, req)
+		}
+		workerID := wr.GetWorkerId()
+
+		if err := r.checkRevoked(workerID); err != nil {
+			return nil, err
+		}
+
+		if info.FullMethod == workerpb.WorkerService_RegisterWorker_FullMethodName {
+			if !constantTimeEqual(firstMD(md, joinTokenMetadataKey), r.cfg.Auth.JoinToken) {
+				metrics.WorkerAuthFailuresTotal.WithLabelValues("join_token").Inc()
+				slog.Warn("RegisterWorker: invalid join token", "worker_id", workerID)
+				return nil, status.Error(codes.Unauthenticated, "invalid join token")
+			}
+			if r.raft.State() == hashiraft.Leader {
+				if err := r.checkTakeover(workerID, firstMD(md, authMetadataKey)); err != nil {
+					metrics.WorkerAuthFailuresTotal.WithLabelValues("takeover").Inc()
+					slog.Warn("RegisterWorker: refusing to replace a live registration",
+						"worker_id", workerID, "error", err)
+					return nil, status.Error(codes.PermissionDenied, err.Error())
+				}
+			}
+			return handler(ctx, req)
+		}
+
+		if r.raft.State() != hashiraft.Leader {
+			return handler(ctx, req)
+		}
+		if err := r.verifyCredential(workerID, firstMD(md, authMetadataKey)); err != nil {
+			metrics.WorkerAuthFailuresTotal.WithLabelValues("credential").Inc()
+			slog.Warn("worker authentication failed", "worker_id", workerID,
+				"method", info.FullMethod, "error", err)
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(context.WithValue(ctx, workerIdentityKey{}, workerID), req)
+	}
+}
+
+// isWorkerFacing reports whether method belongs to a service workers call.
+func isWorkerFacing(method string) bool {
+	return strings.HasPrefix(method, "/worker.WorkerService/") ||
+		strings.HasPrefix(method, "/task.TaskService/")
+}
+
+// RevokeWorker replicates a credential revocation through Raft. Leader only.
+// Once committed, every node refuses the worker's credential and join-token
+// re-registration under the same ID.
+func (r *AgentRegistry) RevokeWorker(workerID, reason string) error {
+	if r.raft.State() != hashiraft.Leader {
+		return ErrNotLeader
+	}
+	cmd, err := internalraft.MarshalCommand(internalraft.CmdRevokeWorker,
+		internalraft.RevokeWorkerPayload{ID: workerID, Reason: reason, RevokedAt: r.clock.Now()})
+	if err != nil {
+		return fmt.Errorf("marshal revoke command: %w", err)
+	}
+	if err := r.raft.Apply(cmd, raftApplyTimeout); err != nil {
+		return fmt.Errorf("raft apply revoke: %w", err)
+	}
+
+	r.mu.Lock()
+	delete(r.trackers, workerID)
+	r.mu.Unlock()
+	metrics.WorkerPhi.DeleteLabelValues(workerID)
+	slog.Warn("worker credential revoked", "worker_id", workerID, "reason", reason)
+	return nil
+}
+
+// issueCredential returns a fresh random credential and its hex SHA-256, or
+// empty strings when authentication is disabled.
+func (r *AgentRegistry) issueCredential() (credential, hash string, err error) {
+	if !r.AuthEnabled() {
+		return "", "", nil
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("generate credential: %w", err)
+	}
+	credential = hex.EncodeToString(buf)
+	return credential, hashCredential(credential), nil
+}
+
+// checkRevoked returns PermissionDenied if the worker's credential was revoked.
+func (r *AgentRegistry) checkRevoked(workerID string) error {
+	if r.workers == nil {
+		return nil
+	}
+	if rev := r.workers.Revocation(workerID); rev != nil {
+		metrics.WorkerAuthFailuresTotal.WithLabelValues("revoked").Inc()
+		return status.Errorf(codes.PermissionDenied, "worker %q revoked: %s", workerID, rev.Reason)
+	}
+	return nil
+}
+
+// verifyCredential checks a bearer authorization value against the hash stored
+// in the FSM for workerID.
+func (r *AgentRegistry) verifyCredential(workerID, authorization string) error {
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return errors.New("missing bearer credential")
+	}
+	if r.workers == nil {
+		return errors.New("no credential store")
+	}
+	want := r.workers.CredentialHash(workerID)
+	if want == "" {
+		return fmt.Errorf("no credential issued to worker %q", workerID)
+	}
+	got := hashCredential(strings.TrimPrefix(authorization, bearerPrefix))
+	if !constantTimeEqual(got, want) {
+		return fmt.Errorf("credential does not belong to worker %q", workerID)
+	}
+	return nil
+}
+
+// checkTakeover allows a registration under workerID only if no live worker
+// holds that ID or the caller presents the holder's credential. Offline
+// workers may be re-registered with the join token alone, which is how a
+// worker that lost its credential (e.g. a restarted process) gets back in.
+func (r *AgentRegistry) checkTakeover(workerID, authorization string) error {
+	if r.workers == nil {
+		return nil
+	}
+	w := r.workers.GetWorker(workerID)
+	if w == nil || w.Status == internalraft.WorkerOffline || r.workers.CredentialHash(workerID) == "" {
+		return nil
+	}
+	if err := r.verifyCredential(workerID, authorization); err != nil {
+		return fmt.Errorf("worker %q is %s; re-registering it needs its current credential: %w",
+			workerID, w.Status, err)
+	}
+	return nil
+}
+
+// verifyOperator checks a bearer authorization value against the operator token.
+func (r *AgentRegistry) verifyOperator(authorization string) error {
+	if r.cfg.Auth.OperatorToken == "" {
+		return errors.New("operator calls are disabled: no operator token configured")
+	}
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return errors.New("missing bearer credential")
+	}
+	if !constantTimeEqual(strings.TrimPrefix(authorization, bearerPrefix), r.cfg.Auth.OperatorToken) {
+		return errors.New("invalid operator token")
+	}
+	return nil
+}
+
+func hashCredential(credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return hex.EncodeToString(sum[:])
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func firstMD(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
@@ -0,0 +1,300 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/pki"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+func newAuthRegistry(t *testing.T) (*AgentRegistry, *mockRaft) {
+	t.Helper()
+	fsm := internalraft.NewPipelineFSM()
+	mr := &mockRaft{isLeader: true, fsm: fsm}
+	cfg := DefaultConfig()
+	cfg.Auth.JoinToken = "join-secret"
+	return NewAgentRegistryWithConfig(mr, fsm, "50051", cfg), mr
+}
+
+// callRegister runs RegisterWorker through the auth interceptor.
+func callRegister(reg *AgentRegistry, joinToken, workerID string) (*workerpb.RegisterWorkerResponse, error) {
+	return callReRegister(reg, joinToken, "", workerID)
+}
+
+// callReRegister runs RegisterWorker through the auth interceptor, also
+// presenting credential if it is not empty.
+func callReRegister(reg *AgentRegistry, joinToken, credential, workerID string) (*workerpb.RegisterWorkerResponse, error) {
+	md := metadata.Pairs(joinTokenMetadataKey, joinToken)
+	if credential != "" {
+		md.Append(authMetadataKey, bearerPrefix+credential)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	info := &grpc.UnaryServerInfo{FullMethod: workerpb.WorkerService_RegisterWorker_FullMethodName}
+	resp, err := reg.UnaryAuthInterceptor()(ctx, &workerpb.RegisterWorkerRequest{WorkerId: workerID}, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return reg.RegisterWorker(ctx, req.(*workerpb.RegisterWorkerRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*workerpb.RegisterWorkerResponse), nil
+}
+
+// callHeartbeat runs Heartbeat through the auth interceptor and reports the
+// identity the handler saw.
+func callHeartbeat(reg *AgentRegistry, credential string, req *workerpb.HeartbeatRequest) (string, error) {
+	md := metadata.MD{}
+	if credential != "" {
+		md = metadata.Pairs(authMetadataKey, bearerPrefix+credential)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	info := &grpc.UnaryServerInfo{FullMethod: workerpb.WorkerService_Heartbeat_FullMethodName}
+	var identity string
+	_, err := reg.UnaryAuthInterceptor()(ctx, req, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			identity, _ = WorkerIdentity(ctx)
+			return reg.Heartbeat(ctx, req.(*workerpb.HeartbeatRequest))
+		})
+	return identity, err
+}
+
+func TestAuth_RegisterRequiresJoinToken(t *testing.T) {
+	reg, mr := newAuthRegistry(t)
+
+	_, err := callRegister(reg, "wrong", "w-1")
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+	if len(mr.appliedCmds) != 0 {
+		t.Error("rejected registration must not reach Raft")
+	}
+
+	resp, err := callRegister(reg, "join-secret", "w-1")
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if !resp.Ok || resp.Credential == "" {
+		t.Fatalf("expected credential to be issued, got %+v", resp)
+	}
+	if got := reg.workers.CredentialHash("w-1"); got != hashCredential(resp.Credential) {
+		t.Error("FSM must store the hash of the issued credential")
+	}
+}
+
+func TestAuth_CredentialBoundToWorkerID(t *testing.T) {
+	reg, _ := newAuthRegistry(t)
+	w1, err := callRegister(reg, "join-secret", "w-1")
+	if err != nil {
+		t.Fatalf("register w-1: %v", err)
+	}
+	w2, err := callRegister(reg, "join-secret", "w-2")
+	if err != nil {
+		t.Fatalf("register w-2: %v", err)
+	}
+
+	id, err := callHeartbeat(reg, w1.Credential, &workerpb.HeartbeatRequest{WorkerId: "w-1", Epoch: w1.Epoch})
+	if err != nil {
+		t.Fatalf("heartbeat with own credential: %v", err)
+	}
+	if id != "w-1" {
+		t.Errorf("expected verified identity w-1, got %q", id)
+	}
+
+	if _, err := callHeartbeat(reg, "", &workerpb.HeartbeatRequest{WorkerId: "w-1", Epoch: w1.Epoch}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("missing credential: expected Unauthenticated, got %v", err)
+	}
+	if _, err := callHeartbeat(reg, w2.Credential, &workerpb.HeartbeatRequest{WorkerId: "w-1", Epoch: w1.Epoch}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("other worker's credential: expected Unauthenticated, got %v", err)
+	}
+}
+
+func TestAuth_ReRegisterLiveWorkerNeedsCredential(t *testing.T) {
+	reg, mr := newAuthRegistry(t)
+	w1, err := callRegister(reg, "join-secret", "w-1")
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	w2, err := callRegister(reg, "join-secret", "w-2")
+	if err != nil {
+		t.Fatalf("register w-2: %v", err)
+	}
+
+	applied := len(mr.appliedCmds)
+	if _, err := callRegister(reg, "join-secret", "w-1"); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("join token alone on a live worker: expected PermissionDenied, got %v", err)
+	}
+	if _, err := callReRegister(reg, "join-secret", w2.Credential, "w-1"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("another worker's credential: expected PermissionDenied, got %v", err)
+	}
+	if len(mr.appliedCmds) != applied {
+		t.Error("refused re-registration must not reach Raft")
+	}
+
+	again, err := callReRegister(reg, "join-secret", w1.Credential, "w-1")
+	if err != nil || !again.Ok || again.Epoch <= w1.Epoch {
+		t.Fatalf("re-register with the current credential: %+v, %v", again, err)
+	}
+
+	// Once the worker is offline, the join token is enough again.
+	cmd, _ := internalraft.MarshalCommand(internalraft.CmdUpdateWorkerStatus,
+		internalraft.UpdateWorkerStatusPayload{ID: "w-1", Status: internalraft.WorkerOffline})
+	if err := mr.Apply(cmd, 0); err != nil {
+		t.Fatalf("mark offline: %v", err)
+	}
+	if resp, err := callRegister(reg, "join-secret", "w-1"); err != nil || !resp.Ok {
+		t.Errorf("re-register an offline worker: %+v, %v", resp, err)
+	}
+}
+
+func TestAuth_RevocationIsEnforced(t *testing.T) {
+	reg, _ := newAuthRegistry(t)
+	w1, err := callRegister(reg, "join-secret", "w-1")
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	if err := reg.RevokeWorker("w-1", "compromised"); err != nil {
+		t.Fatalf("RevokeWorker: %v", err)
+	}
+
+	if _, err := callHeartbeat(reg, w1.Credential, &workerpb.HeartbeatRequest{WorkerId: "w-1", Epoch: w1.Epoch}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("revoked heartbeat: expected PermissionDenied, got %v", err)
+	}
+	if _, err := callRegister(reg, "join-secret", "w-1"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("revoked re-registration: expected PermissionDenied, got %v", err)
+	}
+	if w := reg.workers.GetWorker("w-1"); w.Status != internalraft.WorkerRevoked {
+		t.Errorf("expected worker status revoked, got %s", w.Status)
+	}
+}
+
+func TestAuth_RevokeOnFollower(t *testing.T) {
+	reg, _ := newFollowerRegistry()
+	if err := reg.RevokeWorker("w-1", ""); err != ErrNotLeader {
+		t.Errorf("expected ErrNotLeader, got %v", err)
+	}
+}
+
+func TestAuth_FollowerRedirectsWithoutCredential(t *testing.T) {
+	fsm := internalraft.NewPipelineFSM()
+	mr := &mockRaft{isLeader: false, leaderAddr: "cp-aws-1:7000"}
+	cfg := DefaultConfig()
+	cfg.Auth.JoinToken = "join-secret"
+	reg := NewAgentRegistryWithConfig(mr, fsm, "50051", cfg)
+
+	if _, err := callHeartbeat(reg, "", &workerpb.HeartbeatRequest{WorkerId: "w-1"}); err != nil {
+		t.Errorf("follower should redirect without checking credentials, got %v", err)
+	}
+}
+
+func TestAuth_TaskServiceWorkerCalls(t *testing.T) {
+	reg, _ := newAuthRegistry(t)
+	resp, err := callRegister(reg, "join-secret", "w-1")
+	if err != nil || !resp.Ok {
+		t.Fatalf("register: %+v, %v", resp, err)
+	}
+	call := func(method, credential string, req interface{}) (string, error) {
+		md := metadata.MD{}
+		if credential != "" {
+			md = metadata.Pairs(authMetadataKey, bearerPrefix+credential)
+		}
+		var identity string
+		_, err := reg.UnaryAuthInterceptor()(metadata.NewIncomingContext(context.Background(), md), req,
+			&grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				identity, _ = WorkerIdentity(ctx)
+				return nil, nil
+			})
+		return identity, err
+	}
+
+	acquire := &taskpb.AcquireTaskRequest{WorkerId: "w-1", Epoch: resp.Epoch}
+	if _, err := call(taskpb.TaskService_AcquireTask_FullMethodName, "", acquire); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("AcquireTask without a credential: expected Unauthenticated, got %v", err)
+	}
+	if id, err := call(taskpb.TaskService_AcquireTask_FullMethodName, resp.Credential, acquire); err != nil || id != "w-1" {
+		t.Errorf("AcquireTask with the credential: identity %q, %v", id, err)
+	}
+	// Operator calls carry no worker_id; a worker credential does not stand in
+	// for the operator token.
+	if _, err := call(taskpb.TaskService_SubmitTask_FullMethodName, "", &taskpb.SubmitTaskRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("SubmitTask without a credential: expected Unauthenticated, got %v", err)
+	}
+	if _, err := call(taskpb.TaskService_CancelJob_FullMethodName, resp.Credential, &taskpb.CancelJobRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("CancelJob with a worker credential: expected Unauthenticated, got %v", err)
+	}
+}
+
+func TestAuth_OperatorCalls(t *testing.T) {
+	reg, _ := newAuthRegistry(t)
+	call := func(authorization string) error {
+		md := metadata.MD{}
+		if authorization != "" {
+			md = metadata.Pairs(authMetadataKey, authorization)
+		}
+		_, err := reg.UnaryAuthInterceptor()(metadata.NewIncomingContext(context.Background(), md),
+			&taskpb.SubmitJobRequest{}, &grpc.UnaryServerInfo{FullMethod: taskpb.TaskService_SubmitJob_FullMethodName},
+			func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+		return err
+	}
+
+	// Auth on without an operator token refuses operator calls outright.
+	if err := call(bearerPrefix + "anything"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("no operator token configured: expected Unauthenticated, got %v", err)
+	}
+
+	reg.cfg.Auth.OperatorToken = "op-secret"
+	if err := call(bearerPrefix + "op-secret"); err != nil {
+		t.Errorf("operator token: %v", err)
+	}
+	if err := call(bearerPrefix + "wrong"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("wrong operator token: expected Unauthenticated, got %v", err)
+	}
+	if err := call("op-secret"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("token without the bearer prefix: expected Unauthenticated, got %v", err)
+	}
+}
+
+type stubIssuer struct {
+	kind, subject string
+}
+
+func (s *stubIssuer) Sign(kind, subject string, _ []byte) (*pki.Issued, error) {
+	s.kind, s.subject = kind, subject
+	return &pki.Issued{CertificatePEM: "cert", TrustBundlePEM: "bundle"}, nil
+}
+
+func TestIssueCertificate(t *testing.T) {
+	reg, _ := newAuthRegistry(t)
+	w1, err := callRegister(reg, "join-secret", "w-1")
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	resp, _ := reg.IssueCertificate(context.Background(), &workerpb.IssueCertificateRequest{WorkerId: "w-1", Epoch: w1.Epoch})
+	if resp.Ok {
+		t.Fatal("expected failure without a configured CA")
+	}
+
+	issuer := &stubIssuer{}
+	reg.SetCertIssuer(issuer)
+	resp, _ = reg.IssueCertificate(context.Background(), &workerpb.IssueCertificateRequest{WorkerId: "w-1", Epoch: w1.Epoch + 1})
+	if resp.Ok {
+		t.Error("expected an unknown epoch to be refused")
+	}
+	resp, _ = reg.IssueCertificate(context.Background(), &workerpb.IssueCertificateRequest{WorkerId: "w-1", Epoch: w1.Epoch})
+	if !resp.Ok || resp.CertificatePem != "cert" || resp.CaBundlePem != "bundle" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if issuer.kind != pki.KindWorker || issuer.subject != "w-1" {
+		t.Errorf("expected a worker cert for w-1, got %s for %s", issuer.kind, issuer.subject)
+	}
+}
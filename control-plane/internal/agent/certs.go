@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/pki"
+)
+
+// CertIssuer signs certificates from CSRs; *pki.Authority implements it.
+type CertIssuer interface {
+	Sign(kind, subject string, csrPEM []byte) (*pki.Issued, error)
+}
+
+// SetCertIssuer enables the IssueCertificate RPC. Call before serving.
+func (r *AgentRegistry) SetCertIssuer(ci CertIssuer) {
+	r.certs = ci
+}
+
+// IssueCertificate signs a worker certificate for the calling worker. The auth
+// interceptor has already bound the caller to req.WorkerId, and the epoch check
+// keeps a fenced-out duplicate process from obtaining one.
+func (r *AgentRegistry) IssueCertificate(
+	ctx context.Context,
+	req *workerpb.IssueCertificateRequest,
+) (*workerpb.IssueCertificateResponse, error) {
+
+	if r.raft.State() != hashiraft.Leader {
+		return &workerpb.IssueCertificateResponse{
+			Ok:         false,
+			LeaderAddr: r.raftAddrToGRPC(r.raft.Leader()),
+		}, nil
+	}
+	if r.certs == nil {
+		return &workerpb.IssueCertificateResponse{Ok: false, Error: "certificate authority not configured"}, nil
+	}
+	if err := r.ValidateEpoch(req.WorkerId, req.Epoch); err != nil {
+		return &workerpb.IssueCertificateResponse{Ok: false, Error: err.Error()}, nil
+	}
+
+	issued, err := r.certs.Sign(pki.KindWorker, req.WorkerId, []byte(req.CsrPem))
+	if err != nil {
+		slog.Warn("IssueCertificate failed", "worker_id", req.WorkerId, "error", err)
+		return &workerpb.IssueCertificateResponse{Ok: false, Error: err.Error()}, nil
+	}
+	return &workerpb.IssueCertificateResponse{
+		Ok:             true,
+		CertificatePem: issued.CertificatePEM,
+		CaBundlePem:    issued.TrustBundlePEM,
+		NotAfterUnix:   issued.NotAfter.Unix(),
+	}, nil
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"math"
+	"time"
+)
+
+// DetectorConfig tunes the phi-accrual failure detector used for every worker.
+//
+// Phi expresses how unlikely it is that the worker is still alive given the
+// heartbeat inter-arrival times observed so far: phi=1 means a 10% chance the
+// silence is normal, phi=2 1%, phi=3 0.1% and so on. Workers on a jittery
+// cross-cloud link build up a wide distribution and are given more slack;
+// workers on a local link are detected quickly.
+type DetectorConfig struct {
+	SuspectThreshold float64       // phi at which a worker is marked suspect
+	OfflineThreshold float64       // phi at which a worker is marked offline
+	WindowSize       int           // number of inter-arrival samples kept per worker
+	MinStdDev        time.Duration // floor on the std-dev so a perfectly regular worker isn't flagged on tiny jitter
+	AcceptablePause  time.Duration // extra silence tolerated on top of the mean (GC pauses, retries)
+	FirstHeartbeat   time.Duration // assumed interval until real samples exist — the worker's heartbeat period
+	CheckInterval    time.Duration // how often the monitor evaluates phi
+}
+
+// DefaultDetectorConfig returns thresholds tuned for the 5 s worker heartbeat.
+// A perfectly regular worker goes suspect after ~7.5 s and offline after ~9 s
+// of silence — a little sooner once real samples tighten the distribution;
+// workers with jittery links get proportionally longer.
+func DefaultDetectorConfig() DetectorConfig {
+	return DetectorConfig{
+		SuspectThreshold: 3,
+		OfflineThreshold: 8,
+		WindowSize:       100,
+		MinStdDev:        500 * time.Millisecond,
+		AcceptablePause:  1 * time.Second,
+		FirstHeartbeat:   5 * time.Second,
+		CheckInterval:    1 * time.Second,
+	}
+}
+
+// maxPhi caps phi once the probability underflows, so it stays usable as a
+// Prometheus gauge value instead of +Inf.
+const maxPhi = 100
+
+// PhiAccrualDetector estimates worker liveness from heartbeat inter-arrival times.
+// It is not safe for concurrent use; AgentRegistry guards it with its own mutex.
+type PhiAccrualDetector struct {
+	cfg       DetectorConfig
+	intervals []float64 // ring buffer of inter-arrival times in milliseconds
+	next      int
+	sum       float64
+	sumSq     float64
+	last      time.Time
+}
+
+// NewPhiAccrualDetector returns a detector seeded with cfg.FirstHeartbeat so it
+// can produce a sensible phi before any real intervals have been observed.
+func NewPhiAccrualDetector(cfg DetectorConfig, now time.Time) *PhiAccrualDetector {
+	d := &PhiAccrualDetector{cfg: cfg, last: now}
+	if d.cfg.WindowSize < 2 {
+		d.cfg.WindowSize = 2
+	}
+	// Seed with two samples at mean ± std so the initial std-dev is a quarter
+	// of the expected interval — the same bootstrap Akka uses.
+	mean := float64(cfg.FirstHeartbeat.Milliseconds())
+	std := mean / 4
+	d.add(mean - std)
+	d.add(mean + std)
+	return d
+}
+
+// Heartbeat records an arrival at now.
+func (d *PhiAccrualDetector) Heartbeat(now time.Time) {
+	if interval := now.Sub(d.last); interval > 0 {
+		d.add(float64(interval.Milliseconds()))
+	}
+	d.last = now
+}
+
+// LastHeartbeat returns the time of the most recent arrival.
+func (d *PhiAccrualDetector) LastHeartbeat() time.Time {
+	return d.last
+}
+
+// Phi returns the suspicion level at now.
+func (d *PhiAccrualDetector) Phi(now time.Time) float64 {
+	n := float64(len(d.intervals))
+	mean := d.sum / n
+	variance := d.sumSq/n - mean*mean
+	std := math.Sqrt(math.Max(variance, 0))
+	if minStd := float64(d.cfg.MinStdDev.Milliseconds()); std < minStd {
+		std = minStd
+	}
+	mean += float64(d.cfg.AcceptablePause.Milliseconds())
+
+	elapsed := float64(now.Sub(d.last).Milliseconds())
+	return phi(elapsed, mean, std)
+}
+
+// add pushes one interval into the ring buffer, evicting the oldest once full.
+func (d *PhiAccrualDetector) add(ms float64) {
+	if len(d.intervals) < d.cfg.WindowSize {
+		d.intervals = append(d.intervals, ms)
+	} else {
+		old := d.intervals[d.next]
+		d.sum -= old
+		d.sumSq -= old * old
+		d.intervals[d.next] = ms
+		d.next = (d.next + 1) % d.cfg.WindowSize
+	}
+	d.sum += ms
+	d.sumSq += ms * ms
+}
+
+// phi computes -log10(P(interval > elapsed)) using the logistic approximation
+// of the normal CDF from the Akka implementation (error < 0.02%).
+func phi(elapsed, mean, std float64) float64 {
+	y := (elapsed - mean) / std
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	var p float64
+	if elapsed > mean {
+		p = e / (1 + e)
+	} else {
+		p = 1 - 1/(1+e)
+	}
+	if p <= 0 {
+		return maxPhi
+	}
+	return math.Min(-math.Log10(p), maxPhi)
+}
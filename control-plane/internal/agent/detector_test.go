@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPhi_GrowsWithSilence(t *testing.T) {
+	start := time.Now()
+	d := NewPhiAccrualDetector(DefaultDetectorConfig(), start)
+
+	prev := -1.0
+	for _, after := range []time.Duration{0, 5 * time.Second, 8 * time.Second, 12 * time.Second, 30 * time.Second} {
+		p := d.Phi(start.Add(after))
+		if p < prev {
+			t.Errorf("phi decreased from %.2f to %.2f at +%s", prev, p, after)
+		}
+		prev = p
+	}
+	if prev != maxPhi {
+		t.Errorf("expected phi capped at %d after 30 s of silence, got %.2f", maxPhi, prev)
+	}
+}
+
+func TestPhi_RegularWorkerThresholds(t *testing.T) {
+	cfg := DefaultDetectorConfig()
+	now := time.Now()
+	d := NewPhiAccrualDetector(cfg, now)
+	for i := 0; i < 50; i++ {
+		now = now.Add(5 * time.Second)
+		d.Heartbeat(now)
+	}
+
+	if p := d.Phi(now.Add(5 * time.Second)); p >= cfg.SuspectThreshold {
+		t.Errorf("on-time heartbeat gap should not be suspect, phi=%.2f", p)
+	}
+	if p := d.Phi(now.Add(8 * time.Second)); p < cfg.SuspectThreshold || p >= cfg.OfflineThreshold {
+		t.Errorf("8 s gap should be suspect but not offline, phi=%.2f", p)
+	}
+	if p := d.Phi(now.Add(10 * time.Second)); p < cfg.OfflineThreshold {
+		t.Errorf("10 s gap should be offline, phi=%.2f", p)
+	}
+}
+
+func TestPhi_JitteryWorkerGetsMoreSlack(t *testing.T) {
+	cfg := DefaultDetectorConfig()
+	now := time.Now()
+	steady := NewPhiAccrualDetector(cfg, now)
+	jittery := NewPhiAccrualDetector(cfg, now)
+
+	ts, tj := now, now
+	for i := 0; i < 50; i++ {
+		ts = ts.Add(5 * time.Second)
+		steady.Heartbeat(ts)
+		// alternate 3 s / 7 s gaps — same mean, large variance
+		gap := 3 * time.Second
+		if i%2 == 1 {
+			gap = 7 * time.Second
+		}
+		tj = tj.Add(gap)
+		jittery.Heartbeat(tj)
+	}
+
+	silence := 9 * time.Second
+	ps, pj := steady.Phi(ts.Add(silence)), jittery.Phi(tj.Add(silence))
+	if pj >= ps {
+		t.Errorf("jittery worker should be less suspicious after %s: steady=%.2f jittery=%.2f",
+			silence, ps, pj)
+	}
+	if pj >= cfg.OfflineThreshold {
+		t.Errorf("jittery worker should not be offline after %s, phi=%.2f", silence, pj)
+	}
+}
+
+func TestPhi_WindowEvictsOldSamples(t *testing.T) {
+	cfg := DefaultDetectorConfig()
+	cfg.WindowSize = 10
+	now := time.Now()
+	d := NewPhiAccrualDetector(cfg, now)
+
+	// Long intervals first, then a run of short ones that must push them out.
+	for i := 0; i < 10; i++ {
+		now = now.Add(20 * time.Second)
+		d.Heartbeat(now)
+	}
+	for i := 0; i < 10; i++ {
+		now = now.Add(1 * time.Second)
+		d.Heartbeat(now)
+	}
+	if len(d.intervals) != cfg.WindowSize {
+		t.Fatalf("expected %d samples, got %d", cfg.WindowSize, len(d.intervals))
+	}
+	if p := d.Phi(now.Add(5 * time.Second)); p < cfg.OfflineThreshold {
+		t.Errorf("5 s gap after 1 s cadence should be offline once old samples are evicted, phi=%.2f", p)
+	}
+}
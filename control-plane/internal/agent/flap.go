@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+)
+
+// FlapConfig controls flapping detection and quarantine.
+//
+// Every online↔offline transition the registry commits is recorded per worker.
+// A worker that exceeds MaxTransitions within Window is quarantined: it keeps
+// heartbeating, but no status changes are written to Raft for it and it is not
+// eligible for work until its backoff expires. Each successive quarantine of the
+// same worker doubles the backoff, up to MaxBackoff.
+type FlapConfig struct {
+	Window         time.Duration
+	MaxTransitions int
+	BaseBackoff    time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultFlapConfig quarantines a worker after three down/up bounces in ten minutes.
+func DefaultFlapConfig() FlapConfig {
+	return FlapConfig{
+		Window:         10 * time.Minute,
+		MaxTransitions: 6,
+		BaseBackoff:    1 * time.Minute,
+		MaxBackoff:     30 * time.Minute,
+	}
+}
+
+// quarantineReasonFlapping is the reason code used in metrics labels.
+const quarantineReasonFlapping = "flapping"
+
+// recordTransition appends now to the tracker's transition history, drops
+// entries older than the window, and reports whether the worker is flapping.
+// Caller must hold the registry lock.
+func (c FlapConfig) recordTransition(t *HeartbeatTracker, now time.Time) bool {
+	cutoff := now.Add(-c.Window)
+	kept := t.Transitions[:0]
+	for _, ts := range t.Transitions {
+		if ts.After(cutoff) {
+			kept = append(kept, ts)
+		}
+	}
+	t.Transitions = append(kept, now)
+	return c.MaxTransitions > 0 && len(t.Transitions) >= c.MaxTransitions
+}
+
+// backoff returns the quarantine length for the n-th quarantine (1-based).
+func (c FlapConfig) backoff(n int) time.Duration {
+	d := c.BaseBackoff
+	for i := 1; i < n && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	return d
+}
+
+// quarantineReason describes why a worker was quarantined, for the cluster state.
+func (c FlapConfig) quarantineReason(transitions int) string {
+	return fmt.Sprintf("%s: %d status transitions within %s", quarantineReasonFlapping,
+		transitions, c.Window)
+}
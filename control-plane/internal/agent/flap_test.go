@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlapConfig_RecordTransitionSlidingWindow(t *testing.T) {
+	cfg := FlapConfig{Window: time.Minute, MaxTransitions: 3}
+	tr := &HeartbeatTracker{}
+	now := time.Now()
+
+	if cfg.recordTransition(tr, now) || cfg.recordTransition(tr, now.Add(10*time.Second)) {
+		t.Fatal("two transitions must not count as flapping")
+	}
+	// The first two fall out of the window before the third arrives.
+	if cfg.recordTransition(tr, now.Add(2*time.Minute)) {
+		t.Error("transitions outside the window must not count")
+	}
+	if len(tr.Transitions) != 1 {
+		t.Errorf("expected old transitions pruned, have %d", len(tr.Transitions))
+	}
+	if cfg.recordTransition(tr, now.Add(2*time.Minute+time.Second)) {
+		t.Fatal("two in-window transitions must not count as flapping")
+	}
+	if !cfg.recordTransition(tr, now.Add(2*time.Minute+2*time.Second)) {
+		t.Error("third in-window transition should report flapping")
+	}
+}
+
+func TestFlapConfig_Backoff(t *testing.T) {
+	cfg := FlapConfig{BaseBackoff: time.Minute, MaxBackoff: 5 * time.Minute}
+	cases := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 5 * time.Minute},
+		{20, 5 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := cfg.backoff(c.n); got != c.want {
+			t.Errorf("backoff(%d) = %s, want %s", c.n, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package agent
+
+import (
+	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// KillSource lists the kill orders addressed to a worker; PipelineFSM
+// implements it.
+type KillSource interface {
+	KillOrders(workerID string) []internalraft.KillOrder
+}
+
+// SetKillSource makes heartbeat responses carry the worker's kill orders.
+// Call before serving.
+func (r *AgentRegistry) SetKillSource(ks KillSource) {
+	r.kills = ks
+}
+
+// killsFor returns the kill orders for workerID as sent in a heartbeat
+// response. Orders are repeated on every heartbeat until the worker reports
+// the attempt or the leader revokes its lease.
+func (r *AgentRegistry) killsFor(workerID string) []*workerpb.TaskKill {
+	if r.kills == nil {
+		return nil
+	}
+	var out []*workerpb.TaskKill
+	for _, k := range r.kills.KillOrders(workerID) {
+		out = append(out, &workerpb.TaskKill{
+			TaskId:       k.TaskID,
+			Attempt:      uint32(k.Attempt),
+			Reason:       k.Reason,
+			GraceUntilMs: k.GraceUntil.UnixMilli(),
+		})
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// ReaperConfig controls garbage collection of long-dead workers.
+type ReaperConfig struct {
+	Retention time.Duration // how long a worker stays offline before it is removed; 0 disables reaping
+	Interval  time.Duration // how often the leader scans for expired workers
+}
+
+// DefaultReaperConfig keeps offline workers for a day.
+func DefaultReaperConfig() ReaperConfig {
+	return ReaperConfig{
+		Retention: 24 * time.Hour,
+		Interval:  1 * time.Minute,
+	}
+}
+
+// reapDeadWorkers proposes a remove_worker command for every worker that has
+// been offline (or revoked) for longer than the retention period. Only runs on
+// the leader. Revocation records are kept, so a reaped revoked ID stays banned.
+// The FSM re-checks the status when the entry commits, so a worker that comes
+// back between the scan and the commit is not removed; its leader-local state
+// is then kept too.
+func (r *AgentRegistry) reapDeadWorkers() {
+	if r.raft.State() != hashiraft.Leader || r.workers == nil {
+		return
+	}
+
+	now := r.clock.Now()
+	workers := r.workers.Workers()
+	ids := make([]string, 0, len(workers))
+	for id := range workers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		w := workers[id]
+		dead := w.Status == internalraft.WorkerOffline || w.Status == internalraft.WorkerRevoked
+		if !dead || now.Sub(w.LastSeen) < r.cfg.Reaper.Retention {
+			continue
+		}
+
+		slog.Info("reaping dead worker", "worker_id", id, "last_seen", w.LastSeen)
+		cmd, err := internalraft.MarshalCommand(internalraft.CmdRemoveWorker,
+			internalraft.RemoveWorkerPayload{
+				ID:        id,
+				Reason:    "offline longer than " + r.cfg.Reaper.Retention.String(),
+				RemovedAt: now,
+			})
+		if err != nil {
+			slog.Error("marshal remove command", "worker_id", id, "error", err)
+			continue
+		}
+		if _, err := r.raft.ApplyCommand(cmd, raftApplyTimeout); err != nil {
+			slog.Warn("reap refused", "worker_id", id, "error", err)
+			continue
+		}
+
+		// Drop leader-local state so a re-registration under the same ID starts clean.
+		r.mu.Lock()
+		delete(r.trackers, id)
+		r.mu.Unlock()
+		metrics.WorkerPhi.DeleteLabelValues(id)
+		metrics.WorkersReapedTotal.Inc()
+	}
+}
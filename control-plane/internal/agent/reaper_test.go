@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+func TestReapDeadWorkers(t *testing.T) {
+	mr := &mockRaft{isLeader: true}
+	old := time.Now().Add(-48 * time.Hour)
+	fsm := stubWorkers{
+		"w-dead":     {ID: "w-dead", Status: internalraft.WorkerOffline, LastSeen: old},
+		"w-recent":   {ID: "w-recent", Status: internalraft.WorkerOffline, LastSeen: time.Now()},
+		"w-online":   {ID: "w-online", Status: internalraft.WorkerOnline, LastSeen: old},
+		"w-isolated": {ID: "w-isolated", Status: internalraft.WorkerQuarantined, LastSeen: old},
+	}
+	reg := NewAgentRegistryWithConfig(mr, fsm, "50051", DefaultConfig())
+	reg.mu.Lock()
+	reg.trackers["w-dead"] = &HeartbeatTracker{LastSeen: old, MarkedOffline: true}
+	reg.mu.Unlock()
+
+	reg.reapDeadWorkers()
+
+	if len(mr.appliedCmds) != 1 {
+		t.Fatalf("expected 1 Apply call, got %d", len(mr.appliedCmds))
+	}
+	cmd := lastAppliedCommand(t, mr)
+	if cmd.Type != internalraft.CmdRemoveWorker {
+		t.Fatalf("expected CmdRemoveWorker, got %s", cmd.Type)
+	}
+	var p internalraft.RemoveWorkerPayload
+	if err := json.Unmarshal(cmd.Payload, &p); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if p.ID != "w-dead" || p.Reason == "" || p.RemovedAt.IsZero() {
+		t.Errorf("unexpected payload: %+v", p)
+	}
+
+	reg.mu.Lock()
+	_, tracked := reg.trackers["w-dead"]
+	reg.mu.Unlock()
+	if tracked {
+		t.Error("tracker for reaped worker should be dropped")
+	}
+}
+
+func TestReapRefusedByFSMKeepsTracker(t *testing.T) {
+	// The scan sees w-back offline, but it re-registered before the remove
+	// entry committed.
+	fsm := internalraft.NewPipelineFSM()
+	mr := &mockRaft{isLeader: true, fsm: fsm}
+	cmd, _ := internalraft.MarshalCommand(internalraft.CmdRegisterWorker, internalraft.RegisterWorkerPayload{ID: "w-back"})
+	if _, err := mr.ApplyCommand(cmd, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	reg := NewAgentRegistryWithConfig(mr, stubWorkers{
+		"w-back": {ID: "w-back", Status: internalraft.WorkerOffline, LastSeen: old},
+	}, "50051", DefaultConfig())
+	reg.mu.Lock()
+	reg.trackers["w-back"] = &HeartbeatTracker{LastSeen: time.Now()}
+	reg.mu.Unlock()
+
+	reg.reapDeadWorkers()
+
+	if fsm.GetWorker("w-back") == nil {
+		t.Fatal("FSM removed an online worker")
+	}
+	reg.mu.Lock()
+	_, tracked := reg.trackers["w-back"]
+	reg.mu.Unlock()
+	if !tracked {
+		t.Error("tracker must survive a refused removal")
+	}
+}
+
+func TestReapDeadWorkers_NotLeader(t *testing.T) {
+	mr := &mockRaft{isLeader: false}
+	fsm := stubWorkers{"w-dead": {
+		ID: "w-dead", Status: internalraft.WorkerOffline, LastSeen: time.Now().Add(-48 * time.Hour),
+	}}
+	reg := NewAgentRegistryWithConfig(mr, fsm, "50051", DefaultConfig())
+
+	reg.reapDeadWorkers()
+
+	if len(mr.appliedCmds) != 0 {
+		t.Error("follower must not reap workers")
+	}
+}
+
+func TestReapedWorkerReregistersCleanly(t *testing.T) {
+	mr := &mockRaft{isLeader: true}
+	fsm := stubWorkers{"w-dead": {
+		ID: "w-dead", Status: internalraft.WorkerOffline, LastSeen: time.Now().Add(-48 * time.Hour),
+	}}
+	reg := NewAgentRegistryWithConfig(mr, fsm, "50051", DefaultConfig())
+	reg.mu.Lock()
+	reg.trackers["w-dead"] = &HeartbeatTracker{
+		MarkedOffline:   true,
+		Transitions:     []time.Time{time.Now()},
+		QuarantineCount: 3,
+	}
+	reg.mu.Unlock()
+
+	reg.reapDeadWorkers()
+	delete(fsm, "w-dead") // what the FSM does when the remove_worker entry commits
+
+	if _, err := reg.RegisterWorker(context.Background(),
+		&workerpb.RegisterWorkerRequest{WorkerId: "w-dead"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	reg.mu.Lock()
+	tr := reg.trackers["w-dead"]
+	reg.mu.Unlock()
+	if tr.MarkedOffline || len(tr.Transitions) != 0 || tr.QuarantineCount != 0 {
+		t.Errorf("re-registered worker should start clean, got %+v", tr)
+	}
+}
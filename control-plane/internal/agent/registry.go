@@ -3,8 +3,11 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,60 +15,132 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/clock"
 	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
 	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
 )
 
-const (
-	heartbeatTimeout = 15 * time.Second // 3 missed beats at 5 s interval
-	monitorInterval  = 5 * time.Second
-	raftApplyTimeout = 2 * time.Second
+const raftApplyTimeout = 2 * time.Second
+
+// Epoch validation errors. A stale epoch means a newer process registered under
+// the same worker ID; the caller must stop rather than re-register.
+var (
+	ErrNotRegistered = errors.New("worker not registered")
+	ErrStaleEpoch    = errors.New("stale registration epoch")
+	ErrUnknownEpoch  = errors.New("unknown registration epoch")
 )
 
+// Config holds the tunables for an AgentRegistry.
+type Config struct {
+	Detector DetectorConfig
+	Flap     FlapConfig
+	Reaper   ReaperConfig
+	Auth     AuthConfig
+}
+
+// DefaultConfig returns the configuration used by NewAgentRegistry.
+func DefaultConfig() Config {
+	return Config{
+		Detector: DefaultDetectorConfig(),
+		Flap:     DefaultFlapConfig(),
+		Reaper:   DefaultReaperConfig(),
+	}
+}
+
 // RaftApplier is the subset of RaftNode that AgentRegistry needs.
 // The narrow interface keeps the registry testable without a real Raft cluster.
 type RaftApplier interface {
 	Apply(cmd []byte, timeout time.Duration) error
+	ApplyCommand(cmd []byte, timeout time.Duration) (interface{}, error)
 	Leader() string
 	LeaderID() string
 	State() hashiraft.RaftState
 }
 
+// WorkerReader is the read side of PipelineFSM that AgentRegistry needs to
+// rebuild leader-local state (e.g. quarantine) after a leader failover, find
+// long-dead workers to reap, and authenticate workers.
+type WorkerReader interface {
+	GetWorker(id string) *internalraft.WorkerInfo
+	Workers() map[string]*internalraft.WorkerInfo
+	CredentialHash(id string) string
+	Revocation(id string) *internalraft.WorkerRevocation
+}
+
 // HeartbeatTracker holds ephemeral (non-Raft) liveness state for one worker.
 // It lives only in memory on the current leader — the authoritative worker status
 // is always the PipelineFSM replicated via Raft.
 type HeartbeatTracker struct {
 	LastSeen      time.Time
+	Suspect       bool // phi crossed the suspect threshold; cleared by the next heartbeat
 	MarkedOffline bool // prevents duplicate Raft Apply calls for the same offline event
+
+	// Detector is created lazily by the monitor when nil, seeded from LastSeen.
+	Detector *PhiAccrualDetector
+
+	Transitions      []time.Time // committed online↔offline transitions within the flap window
+	QuarantinedUntil time.Time   // non-zero while quarantined
+	QuarantineCount  int         // drives exponential backoff; seeded from the FSM
+}
+
+// quarantined reports whether the tracker is in an unexpired quarantine.
+func (t *HeartbeatTracker) quarantined(now time.Time) bool {
+	return !t.QuarantinedUntil.IsZero() && now.Before(t.QuarantinedUntil)
 }
 
 // AgentRegistry implements workerpb.WorkerServiceServer.
 // It handles RegisterWorker and Heartbeat RPCs, enforces leader-only writes,
-// and runs a background goroutine to mark workers offline after missed heartbeats.
+// and runs a background goroutine that moves workers to suspect and then offline
+// as their phi-accrual suspicion level rises.
 type AgentRegistry struct {
 	workerpb.UnimplementedWorkerServiceServer
 
 	mu       sync.Mutex
 	trackers map[string]*HeartbeatTracker
 
+	cfg      Config
 	raft     RaftApplier
-	grpcPort string // e.g. "50051" — used to build the gRPC redirect addr from a Raft addr
+	workers  WorkerReader // may be nil — leader-local state then starts empty after failover
+	certs    CertIssuer   // may be nil — IssueCertificate then reports the CA as unconfigured
+	kills    KillSource   // may be nil — heartbeat responses then carry no kill orders
+	clock    clock.Clock  // wall clock unless a simulation injects a virtual one
+	grpcPort string       // e.g. "50051" — used to build the gRPC redirect addr from a Raft addr
 }
 
-// NewAgentRegistry creates an AgentRegistry. Call Start to activate the monitor.
-// grpcPort is the port the gRPC server listens on (e.g. "50051").
+// NewAgentRegistry creates an AgentRegistry with DefaultConfig. Call Start to
+// activate the monitor. grpcPort is the port the gRPC server listens on (e.g. "50051").
 func NewAgentRegistry(raft RaftApplier, grpcPort string) *AgentRegistry {
+	return NewAgentRegistryWithConfig(raft, nil, grpcPort, DefaultConfig())
+}
+
+// NewAgentRegistryWithConfig creates an AgentRegistry with explicit tunables.
+// workers is normally the node's PipelineFSM.
+func NewAgentRegistryWithConfig(raft RaftApplier, workers WorkerReader, grpcPort string,
+	cfg Config) *AgentRegistry {
 	return &AgentRegistry{
 		trackers: make(map[string]*HeartbeatTracker),
+		cfg:      cfg,
 		raft:     raft,
+		workers:  workers,
+		clock:    clock.Real{},
 		grpcPort: grpcPort,
 	}
 }
 
-// Start launches the background heartbeat-monitor goroutine.
+// SetClock replaces the wall clock, e.g. with a clock.Sim for deterministic
+// simulation. Call before Start.
+func (r *AgentRegistry) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// Start schedules the heartbeat monitor and reaper on the registry's clock.
 // ctx should be cancelled on graceful shutdown.
 func (r *AgentRegistry) Start(ctx context.Context) {
-	go r.monitorLoop(ctx)
+	r.clock.Every(ctx, r.cfg.Detector.CheckInterval, r.checkHeartbeats)
+	if r.cfg.Reaper.Retention > 0 && r.workers != nil {
+		r.clock.Every(ctx, r.cfg.Reaper.Interval, r.reapDeadWorkers)
+	}
 }
 
 // RegisterWorker handles a worker's initial registration RPC.
@@ -85,32 +160,62 @@ func (r *AgentRegistry) RegisterWorker(
 		}, nil
 	}
 
+	now := r.clock.Now()
+	r.mu.Lock()
+	prev := r.trackerLocked(req.WorkerId, now)
+	r.mu.Unlock()
+	if prev.quarantined(now) {
+		slog.Warn("RegisterWorker: worker quarantined, rejecting",
+			"worker_id", req.WorkerId, "until", prev.QuarantinedUntil)
+		return &workerpb.RegisterWorkerResponse{
+			Ok:    false,
+			Error: fmt.Sprintf("worker quarantined until %s", prev.QuarantinedUntil.Format(time.RFC3339)),
+		}, nil
+	}
+
+	credential, credentialHash, err := r.issueCredential()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
 	cmd, err := internalraft.MarshalCommand(internalraft.CmdRegisterWorker,
 		internalraft.RegisterWorkerPayload{
-			ID:       req.WorkerId,
-			Address:  req.Address,
-			CloudTag: req.CloudTag,
+			ID:             req.WorkerId,
+			Address:        req.Address,
+			CloudTag:       req.CloudTag,
+			CredentialHash: credentialHash,
+			Capacity:       internalraft.Resources{CPUMillis: req.CpuMillis, MemoryMB: req.MemoryMb},
 		})
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "marshal command: %v", err)
 	}
 
-	if err := r.raft.Apply(cmd, raftApplyTimeout); err != nil {
+	resp, err := r.raft.ApplyCommand(cmd, raftApplyTimeout)
+	if err != nil {
 		return nil, status.Errorf(codes.Internal, "raft apply: %v", err)
 	}
+	epoch, _ := resp.(uint64)
 
 	r.mu.Lock()
 	r.trackers[req.WorkerId] = &HeartbeatTracker{
-		LastSeen:      time.Now().UTC(),
-		MarkedOffline: false,
+		LastSeen:        now,
+		MarkedOffline:   false,
+		Detector:        NewPhiAccrualDetector(r.cfg.Detector, now),
+		Transitions:     prev.Transitions,
+		QuarantineCount: prev.QuarantineCount,
 	}
 	r.mu.Unlock()
+	if !prev.QuarantinedUntil.IsZero() {
+		// Re-registration after an expired quarantine re-admits the worker.
+		metrics.WorkerQuarantined.DeleteLabelValues(req.WorkerId, quarantineReasonFlapping)
+	}
 
 	slog.Info("worker registered",
 		"worker_id", req.WorkerId,
 		"cloud", req.CloudTag,
-		"address", req.Address)
-	return &workerpb.RegisterWorkerResponse{Ok: true}, nil
+		"address", req.Address,
+		"epoch", epoch)
+	return &workerpb.RegisterWorkerResponse{Ok: true, Epoch: epoch, Credential: credential}, nil
 }
 
 // Heartbeat handles a periodic liveness ping from a registered worker.
@@ -128,71 +233,245 @@ func (r *AgentRegistry) Heartbeat(
 		}, nil
 	}
 
+	if err := r.ValidateEpoch(req.WorkerId, req.Epoch); err != nil {
+		slog.Warn("heartbeat rejected", "worker_id", req.WorkerId, "epoch", req.Epoch, "error", err)
+		fenced := errors.Is(err, ErrStaleEpoch)
+		if fenced {
+			metrics.WorkerFencedTotal.Inc()
+		}
+		return &workerpb.HeartbeatResponse{Ok: false, Fenced: fenced, Error: err.Error()}, nil
+	}
+
+	now := r.clock.Now()
 	r.mu.Lock()
 	t, exists := r.trackers[req.WorkerId]
 	if !exists {
 		// Worker heartbeating without having registered on this leader
 		// (can happen after a leader failover). Create a tracker so the
 		// monitor doesn't incorrectly flag it as stale.
-		t = &HeartbeatTracker{}
+		t = r.trackerLocked(req.WorkerId, now)
 		r.trackers[req.WorkerId] = t
+	} else if t.Detector != nil {
+		t.Detector.Heartbeat(now)
 	}
-	t.LastSeen = time.Now().UTC()
+	recovered := t.Suspect || t.MarkedOffline
+	wasOffline := t.MarkedOffline
+	t.LastSeen = now
+	t.Suspect = false
 	t.MarkedOffline = false // reset on any successful heartbeat
+
+	// A quarantined worker's status is frozen until the monitor re-admits it.
+	var flapping bool
+	var until time.Time
+	var reason string
+	if t.quarantined(now) {
+		recovered = false
+	} else if wasOffline && r.cfg.Flap.recordTransition(t, now) {
+		until, reason = r.quarantineLocked(t, now)
+		flapping = true
+	}
 	r.mu.Unlock()
 
+	switch {
+	case flapping:
+		r.applyQuarantine(req.WorkerId, reason, until)
+	case recovered:
+		slog.Info("worker heartbeat resumed — marking online", "worker_id", req.WorkerId)
+		r.applyStatus(req.WorkerId, internalraft.WorkerOnline)
+	}
+
 	slog.Debug("heartbeat received", "worker_id", req.WorkerId)
-	return &workerpb.HeartbeatResponse{Ok: true}, nil
-}
-
-// monitorLoop ticks every monitorInterval and checks for stale workers.
-func (r *AgentRegistry) monitorLoop(ctx context.Context) {
-	ticker := time.NewTicker(monitorInterval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			r.checkHeartbeats()
-		}
+	return &workerpb.HeartbeatResponse{Ok: true, Kills: r.killsFor(req.WorkerId)}, nil
+}
+
+// ValidateEpoch checks that epoch is the current registration epoch for the
+// worker in the FSM. It returns ErrNotRegistered, ErrStaleEpoch (a newer
+// registration exists) or ErrUnknownEpoch (the caller's epoch is ahead of the
+// FSM — it must re-register). Without a WorkerReader every epoch is accepted.
+func (r *AgentRegistry) ValidateEpoch(workerID string, epoch uint64) error {
+	if r.workers == nil {
+		return nil
 	}
+	w := r.workers.GetWorker(workerID)
+	switch {
+	case w == nil:
+		return ErrNotRegistered
+	case epoch < w.Epoch:
+		return fmt.Errorf("%w: got %d, current %d", ErrStaleEpoch, epoch, w.Epoch)
+	case epoch > w.Epoch:
+		return fmt.Errorf("%w: got %d, current %d", ErrUnknownEpoch, epoch, w.Epoch)
+	}
+	return nil
 }
 
-// checkHeartbeats marks workers offline via Raft when their heartbeat has been
-// absent for longer than heartbeatTimeout. Only runs on the leader.
+// checkHeartbeats evaluates every worker's phi and moves it to suspect or
+// offline via Raft once the configured thresholds are crossed. Only runs on the
+// leader; phi is reported for live workers only, so offline and quarantined
+// workers and every worker on a former leader drop out of WorkerPhi.
 func (r *AgentRegistry) checkHeartbeats() {
 	if r.raft.State() != hashiraft.Leader {
+		metrics.WorkerPhi.Reset()
 		return
 	}
 
-	now := time.Now().UTC()
+	now := r.clock.Now()
+
+	type transition struct {
+		id     string
+		status string
+		phi    float64
+		reason string    // quarantine only
+		until  time.Time // quarantine only
+	}
 
 	r.mu.Lock()
-	var stale []string
-	for id, t := range r.trackers {
-		if !t.MarkedOffline && now.Sub(t.LastSeen) > heartbeatTimeout {
-			t.MarkedOffline = true // set inside lock — prevents double-queueing
-			stale = append(stale, id)
+	var changes []transition
+	// Visit workers in ID order so the commands a scan produces are replicated
+	// in the same order every time.
+	ids := make([]string, 0, len(r.trackers))
+	for id := range r.trackers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	phis := make(map[string]float64, len(ids))
+	for _, id := range ids {
+		t := r.trackers[id]
+		if t.Detector == nil {
+			t.Detector = NewPhiAccrualDetector(r.cfg.Detector, t.LastSeen)
+		}
+		phi := t.Detector.Phi(now)
+		phis[id] = phi
+
+		// Quarantine expired — re-admit with whatever state the detector sees now.
+		// Re-admission is not counted as a flap transition.
+		if !t.QuarantinedUntil.IsZero() {
+			if t.quarantined(now) {
+				continue
+			}
+			t.QuarantinedUntil = time.Time{}
+			metrics.WorkerQuarantined.DeleteLabelValues(id, quarantineReasonFlapping)
+			status := internalraft.WorkerOnline
+			if phi >= r.cfg.Detector.SuspectThreshold {
+				status = internalraft.WorkerOffline
+				t.MarkedOffline = true
+			}
+			changes = append(changes, transition{id: id, status: status, phi: phi})
+			continue
+		}
+
+		if t.MarkedOffline {
+			continue
+		}
+
+		// Flags are set inside the lock — prevents double-queueing.
+		switch {
+		case phi >= r.cfg.Detector.OfflineThreshold:
+			if r.cfg.Flap.recordTransition(t, now) {
+				until, reason := r.quarantineLocked(t, now)
+				changes = append(changes, transition{id, internalraft.WorkerQuarantined, phi, reason, until})
+				continue
+			}
+			t.MarkedOffline = true
+			changes = append(changes, transition{id: id, status: internalraft.WorkerOffline, phi: phi})
+		case phi >= r.cfg.Detector.SuspectThreshold && !t.Suspect:
+			t.Suspect = true
+			changes = append(changes, transition{id: id, status: internalraft.WorkerSuspect, phi: phi})
+		}
+	}
+	for _, id := range ids {
+		if t := r.trackers[id]; t.MarkedOffline || t.quarantined(now) {
+			metrics.WorkerPhi.DeleteLabelValues(id)
+		} else {
+			metrics.WorkerPhi.WithLabelValues(id).Set(phis[id])
 		}
 	}
 	r.mu.Unlock()
 
-	// Apply offline commands outside the lock — Raft Apply can be slow.
-	for _, id := range stale {
-		slog.Warn("worker heartbeat timeout — marking offline", "worker_id", id)
-		cmd, err := internalraft.MarshalCommand(internalraft.CmdUpdateWorkerStatus,
-			internalraft.UpdateWorkerStatusPayload{ID: id, Status: "offline"})
-		if err != nil {
-			slog.Error("marshal offline command", "worker_id", id, "error", err)
+	// Apply status commands outside the lock — Raft Apply can be slow.
+	// On failure the flags stay set — avoids spamming a struggling cluster.
+	// The worker's next heartbeat or re-registration will reset them.
+	for _, c := range changes {
+		if c.status == internalraft.WorkerQuarantined {
+			r.applyQuarantine(c.id, c.reason, c.until)
 			continue
 		}
-		if err := r.raft.Apply(cmd, raftApplyTimeout); err != nil {
-			slog.Error("raft apply offline", "worker_id", id, "error", err)
-			// Keep MarkedOffline=true — avoids spamming a struggling cluster.
-			// The worker's re-registration will reset it.
+		slog.Warn("worker heartbeat monitor — marking "+c.status,
+			"worker_id", c.id, "phi", c.phi)
+		r.applyStatus(c.id, c.status)
+	}
+}
+
+// trackerLocked returns the tracker for id, or a fresh one seeded from the FSM
+// when this leader has not seen the worker yet. The fresh tracker is not stored.
+// Caller must hold r.mu.
+func (r *AgentRegistry) trackerLocked(id string, now time.Time) *HeartbeatTracker {
+	if t, ok := r.trackers[id]; ok {
+		return t
+	}
+	t := &HeartbeatTracker{LastSeen: now, Detector: NewPhiAccrualDetector(r.cfg.Detector, now)}
+	if r.workers != nil {
+		if w := r.workers.GetWorker(id); w != nil {
+			// A new leader inherits the old one's verdict, so the next
+			// heartbeat from a suspect or offline worker marks it online.
+			t.Suspect = w.Status == internalraft.WorkerSuspect
+			t.MarkedOffline = w.Status == internalraft.WorkerOffline
+			t.QuarantineCount = w.QuarantineCount
+			if w.Status == internalraft.WorkerQuarantined {
+				t.QuarantinedUntil = w.QuarantinedUntil
+			}
 		}
 	}
+	return t
+}
+
+// quarantineLocked puts the tracker into quarantine and returns the deadline
+// and reason to replicate. Caller must hold r.mu.
+func (r *AgentRegistry) quarantineLocked(t *HeartbeatTracker, now time.Time) (time.Time, string) {
+	reason := r.cfg.Flap.quarantineReason(len(t.Transitions))
+	t.QuarantineCount++
+	t.QuarantinedUntil = now.Add(r.cfg.Flap.backoff(t.QuarantineCount))
+	t.Transitions = nil
+	t.Suspect = false
+	t.MarkedOffline = false
+	return t.QuarantinedUntil, reason
+}
+
+// applyQuarantine replicates a quarantine decision through Raft, logging failures.
+func (r *AgentRegistry) applyQuarantine(id, reason string, until time.Time) {
+	slog.Warn("worker flapping — quarantining", "worker_id", id, "reason", reason, "until", until)
+	cmd, err := internalraft.MarshalCommand(internalraft.CmdQuarantineWorker,
+		internalraft.QuarantineWorkerPayload{ID: id, Reason: reason, Until: until})
+	if err != nil {
+		slog.Error("marshal quarantine command", "worker_id", id, "error", err)
+		return
+	}
+	if err := r.raft.Apply(cmd, raftApplyTimeout); err != nil {
+		slog.Error("raft apply quarantine", "worker_id", id, "error", err)
+		return
+	}
+	metrics.WorkerQuarantinesTotal.Inc()
+	metrics.WorkerQuarantined.WithLabelValues(id, quarantineReasonFlapping).Set(1)
+}
+
+// applyStatus replicates a worker status change through Raft, logging failures.
+func (r *AgentRegistry) applyStatus(id, status string) {
+	cmd, err := internalraft.MarshalCommand(internalraft.CmdUpdateWorkerStatus,
+		internalraft.UpdateWorkerStatusPayload{ID: id, Status: status})
+	if err != nil {
+		slog.Error("marshal status command", "worker_id", id, "status", status, "error", err)
+		return
+	}
+	if err := r.raft.Apply(cmd, raftApplyTimeout); err != nil {
+		slog.Error("raft apply status", "worker_id", id, "status", status, "error", err)
+		return
+	}
+	metrics.WorkerStatusTransitionsTotal.WithLabelValues(status).Inc()
+}
+
+// LeaderGRPCAddr returns the gRPC address of the current Raft leader, or ""
+// if none is known. Other gRPC services use it for follower redirects.
+func (r *AgentRegistry) LeaderGRPCAddr() string {
+	return r.raftAddrToGRPC(r.raft.Leader())
 }
 
 // raftAddrToGRPC converts a Raft peer address (e.g. "cp-aws-1:7000") into
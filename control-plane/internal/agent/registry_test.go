@@ -3,12 +3,14 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
 	hashiraft "github.com/hashicorp/raft"
 
 	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
 	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
 )
 
@@ -20,15 +22,35 @@ type mockRaft struct {
 	leaderID    string // server ID (hostname) of the leader
 	appliedCmds [][]byte
 	applyErr    error
+	fsm         *internalraft.PipelineFSM // optional — applied commands are fed to it
 }
 
-func (m *mockRaft) Apply(cmd []byte, _ time.Duration) error {
+// Apply, like RaftNode.Apply, reports only whether the entry committed, not
+// whether the FSM accepted it.
+func (m *mockRaft) Apply(cmd []byte, timeout time.Duration) error {
 	if m.applyErr != nil {
 		return m.applyErr
 	}
-	m.appliedCmds = append(m.appliedCmds, cmd)
+	_, _ = m.ApplyCommand(cmd, timeout)
 	return nil
 }
+
+func (m *mockRaft) ApplyCommand(cmd []byte, _ time.Duration) (interface{}, error) {
+	if m.applyErr != nil {
+		return nil, m.applyErr
+	}
+	m.appliedCmds = append(m.appliedCmds, cmd)
+	if m.fsm == nil {
+		return nil, nil
+	}
+	resp := m.fsm.Apply(&hashiraft.Log{
+		Index: uint64(len(m.appliedCmds)), Type: hashiraft.LogCommand, Data: cmd,
+	})
+	if err, ok := resp.(error); ok {
+		return nil, err
+	}
+	return resp, nil
+}
 func (m *mockRaft) Leader() string   { return m.leaderAddr }
 func (m *mockRaft) LeaderID() string { return m.leaderID }
 func (m *mockRaft) State() hashiraft.RaftState {
@@ -148,6 +170,38 @@ func TestHeartbeat_OnLeader(t *testing.T) {
 	}
 }
 
+type stubKills []internalraft.KillOrder
+
+func (s stubKills) KillOrders(workerID string) []internalraft.KillOrder {
+	var out []internalraft.KillOrder
+	for _, k := range s {
+		if k.Worker == workerID {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func TestHeartbeat_CarriesKillOrders(t *testing.T) {
+	reg, _ := newLeaderRegistry()
+	grace := time.UnixMilli(1_700_000_030_000)
+	reg.SetKillSource(stubKills{
+		{TaskID: "t-1", Attempt: 2, Worker: "w-1", Reason: "deadline exceeded", GraceUntil: grace},
+		{TaskID: "t-2", Attempt: 1, Worker: "w-2", Reason: "cancelled"},
+	})
+	resp, err := reg.Heartbeat(context.Background(), &workerpb.HeartbeatRequest{WorkerId: "w-1"})
+	if err != nil || !resp.Ok {
+		t.Fatalf("heartbeat: %+v, %v", resp, err)
+	}
+	if len(resp.Kills) != 1 {
+		t.Fatalf("expected one kill for w-1, got %+v", resp.Kills)
+	}
+	if k := resp.Kills[0]; k.TaskId != "t-1" || k.Attempt != 2 || k.Reason != "deadline exceeded" ||
+		k.GraceUntilMs != grace.UnixMilli() {
+		t.Errorf("kill = %+v", k)
+	}
+}
+
 func TestHeartbeat_OnFollower(t *testing.T) {
 	reg, mr := newFollowerRegistry()
 	resp, err := reg.Heartbeat(context.Background(), &workerpb.HeartbeatRequest{WorkerId: "w-1"})
@@ -194,7 +248,7 @@ func TestCheckHeartbeats_MarksOffline(t *testing.T) {
 	reg, mr := newLeaderRegistry()
 	reg.mu.Lock()
 	reg.trackers["w-stale"] = &HeartbeatTracker{
-		LastSeen:      time.Now().Add(-20 * time.Second), // well past the 15 s timeout
+		LastSeen:      time.Now().Add(-20 * time.Second), // well past the offline threshold
 		MarkedOffline: false,
 	}
 	reg.mu.Unlock()
@@ -234,6 +288,40 @@ func TestCheckHeartbeats_NoSpam(t *testing.T) {
 	}
 }
 
+// hasPhi reports whether WorkerPhi has a series for id. It removes the
+// series, so call it once per check.
+func hasPhi(id string) bool {
+	return metrics.WorkerPhi.DeleteLabelValues(id)
+}
+
+func TestCheckHeartbeats_PhiOnlyForLiveWorkers(t *testing.T) {
+	reg, mr := newLeaderRegistry()
+	reg.mu.Lock()
+	reg.trackers["w-live"] = &HeartbeatTracker{LastSeen: time.Now()}
+	reg.trackers["w-stale"] = &HeartbeatTracker{LastSeen: time.Now().Add(-20 * time.Second)}
+	reg.trackers["w-quarantined"] = &HeartbeatTracker{LastSeen: time.Now(), QuarantinedUntil: time.Now().Add(time.Hour)}
+	reg.mu.Unlock()
+
+	reg.checkHeartbeats()
+	if !hasPhi("w-live") {
+		t.Error("a live worker should report phi")
+	}
+	if hasPhi("w-stale") {
+		t.Error("a worker marked offline must drop out of worker_phi")
+	}
+	if hasPhi("w-quarantined") {
+		t.Error("a quarantined worker must drop out of worker_phi")
+	}
+
+	// A former leader stops reporting altogether.
+	reg.checkHeartbeats()
+	mr.isLeader = false
+	reg.checkHeartbeats()
+	if hasPhi("w-live") {
+		t.Error("a node that lost leadership must not keep reporting phi")
+	}
+}
+
 func TestCheckHeartbeats_NotLeader(t *testing.T) {
 	reg, mr := newFollowerRegistry()
 	reg.mu.Lock()
@@ -266,6 +354,315 @@ func TestCheckHeartbeats_FreshWorkerNotMarked(t *testing.T) {
 	}
 }
 
+func TestCheckHeartbeats_SuspectBeforeOffline(t *testing.T) {
+	reg, mr := newLeaderRegistry()
+	reg.mu.Lock()
+	reg.trackers["w-slow"] = &HeartbeatTracker{
+		// Past the suspect threshold for a fresh detector, short of offline.
+		LastSeen: time.Now().Add(-11 * time.Second),
+	}
+	reg.mu.Unlock()
+
+	reg.checkHeartbeats()
+	reg.checkHeartbeats() // still suspect — must not apply again
+
+	if len(mr.appliedCmds) != 1 {
+		t.Fatalf("expected 1 Apply call, got %d", len(mr.appliedCmds))
+	}
+	var p internalraft.UpdateWorkerStatusPayload
+	if err := json.Unmarshal(lastAppliedCommand(t, mr).Payload, &p); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if p.Status != internalraft.WorkerSuspect {
+		t.Errorf("expected status suspect, got %s", p.Status)
+	}
+
+	// Silence continues past the offline threshold.
+	reg.mu.Lock()
+	reg.trackers["w-slow"].Detector = NewPhiAccrualDetector(reg.cfg.Detector, time.Now().Add(-30*time.Second))
+	reg.mu.Unlock()
+	reg.checkHeartbeats()
+
+	if len(mr.appliedCmds) != 2 {
+		t.Fatalf("expected 2 Apply calls, got %d", len(mr.appliedCmds))
+	}
+	if err := json.Unmarshal(lastAppliedCommand(t, mr).Payload, &p); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if p.Status != internalraft.WorkerOffline {
+		t.Errorf("expected status offline, got %s", p.Status)
+	}
+}
+
+func TestHeartbeat_RecoversSuspectWorker(t *testing.T) {
+	reg, mr := newLeaderRegistry()
+	reg.mu.Lock()
+	reg.trackers["w-1"] = &HeartbeatTracker{
+		LastSeen: time.Now().Add(-11 * time.Second),
+		Suspect:  true,
+	}
+	reg.mu.Unlock()
+
+	if _, err := reg.Heartbeat(context.Background(), &workerpb.HeartbeatRequest{WorkerId: "w-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(mr.appliedCmds) != 1 {
+		t.Fatalf("expected 1 Apply call, got %d", len(mr.appliedCmds))
+	}
+	var p internalraft.UpdateWorkerStatusPayload
+	if err := json.Unmarshal(lastAppliedCommand(t, mr).Payload, &p); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if p.ID != "w-1" || p.Status != internalraft.WorkerOnline {
+		t.Errorf("unexpected payload: %+v", p)
+	}
+	reg.mu.Lock()
+	suspect := reg.trackers["w-1"].Suspect
+	reg.mu.Unlock()
+	if suspect {
+		t.Error("Suspect should be reset after a heartbeat")
+	}
+}
+
+// ── flapping / quarantine tests ──────────────────────────────────────────────
+
+// goSilent makes a worker look like it stopped heartbeating 30 s ago.
+func goSilent(reg *AgentRegistry, id string) {
+	reg.mu.Lock()
+	reg.trackers[id].Detector = NewPhiAccrualDetector(reg.cfg.Detector, time.Now().Add(-30*time.Second))
+	reg.mu.Unlock()
+}
+
+// appliedTypes returns the command types of every applied Raft command.
+func appliedTypes(t *testing.T, mr *mockRaft) []internalraft.CommandType {
+	t.Helper()
+	out := make([]internalraft.CommandType, 0, len(mr.appliedCmds))
+	for _, raw := range mr.appliedCmds {
+		var cmd internalraft.Command
+		if err := json.Unmarshal(raw, &cmd); err != nil {
+			t.Fatalf("unmarshal command: %v", err)
+		}
+		out = append(out, cmd.Type)
+	}
+	return out
+}
+
+func TestFlappingWorkerIsQuarantined(t *testing.T) {
+	reg, mr := newLeaderRegistry()
+	reg.cfg.Flap = FlapConfig{Window: time.Minute, MaxTransitions: 4, BaseBackoff: time.Minute, MaxBackoff: time.Hour}
+	ctx := context.Background()
+	if _, err := reg.RegisterWorker(ctx, &workerpb.RegisterWorkerRequest{WorkerId: "w-flap"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	// offline → online → offline → online: the 4th transition trips the threshold.
+	for i := 0; i < 2; i++ {
+		goSilent(reg, "w-flap")
+		reg.checkHeartbeats()
+		if _, err := reg.Heartbeat(ctx, &workerpb.HeartbeatRequest{WorkerId: "w-flap"}); err != nil {
+			t.Fatalf("heartbeat: %v", err)
+		}
+	}
+
+	types := appliedTypes(t, mr)
+	want := []internalraft.CommandType{
+		internalraft.CmdRegisterWorker,
+		internalraft.CmdUpdateWorkerStatus, // offline
+		internalraft.CmdUpdateWorkerStatus, // online
+		internalraft.CmdUpdateWorkerStatus, // offline
+		internalraft.CmdQuarantineWorker,   // instead of online
+	}
+	if len(types) != len(want) {
+		t.Fatalf("applied %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("applied %v, want %v", types, want)
+		}
+	}
+	var p internalraft.QuarantineWorkerPayload
+	if err := json.Unmarshal(lastAppliedCommand(t, mr).Payload, &p); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if p.ID != "w-flap" || p.Reason == "" || time.Until(p.Until) < 50*time.Second {
+		t.Errorf("unexpected quarantine payload: %+v", p)
+	}
+
+	// While quarantined, further bounces write nothing to Raft.
+	goSilent(reg, "w-flap")
+	reg.checkHeartbeats()
+	if _, err := reg.Heartbeat(ctx, &workerpb.HeartbeatRequest{WorkerId: "w-flap"}); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if len(mr.appliedCmds) != len(want) {
+		t.Errorf("quarantined worker must not generate Raft writes, got %d extra",
+			len(mr.appliedCmds)-len(want))
+	}
+
+	// Re-registration is refused until the backoff expires.
+	resp, err := reg.RegisterWorker(ctx, &workerpb.RegisterWorkerRequest{WorkerId: "w-flap"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if resp.Ok || resp.Error == "" {
+		t.Errorf("expected quarantined registration to be rejected, got %+v", resp)
+	}
+}
+
+func TestQuarantineExpiryReadmitsWorker(t *testing.T) {
+	reg, mr := newLeaderRegistry()
+	reg.mu.Lock()
+	reg.trackers["w-q"] = &HeartbeatTracker{
+		LastSeen:         time.Now(),
+		Detector:         NewPhiAccrualDetector(reg.cfg.Detector, time.Now()),
+		QuarantinedUntil: time.Now().Add(-time.Second),
+		QuarantineCount:  1,
+	}
+	reg.mu.Unlock()
+
+	reg.checkHeartbeats()
+
+	if len(mr.appliedCmds) != 1 {
+		t.Fatalf("expected 1 Apply call, got %d", len(mr.appliedCmds))
+	}
+	var p internalraft.UpdateWorkerStatusPayload
+	if err := json.Unmarshal(lastAppliedCommand(t, mr).Payload, &p); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if p.Status != internalraft.WorkerOnline {
+		t.Errorf("expected healthy worker re-admitted online, got %s", p.Status)
+	}
+	reg.mu.Lock()
+	tr := reg.trackers["w-q"]
+	reg.mu.Unlock()
+	if !tr.QuarantinedUntil.IsZero() || tr.QuarantineCount != 1 {
+		t.Errorf("expected quarantine cleared but count kept, got until=%s count=%d",
+			tr.QuarantinedUntil, tr.QuarantineCount)
+	}
+}
+
+type stubWorkers map[string]*internalraft.WorkerInfo
+
+func (s stubWorkers) GetWorker(id string) *internalraft.WorkerInfo { return s[id] }
+func (s stubWorkers) Workers() map[string]*internalraft.WorkerInfo { return s }
+func (s stubWorkers) CredentialHash(string) string                 { return "" }
+func (s stubWorkers) Revocation(string) *internalraft.WorkerRevocation {
+	return nil
+}
+
+func TestQuarantineSurvivesLeaderFailover(t *testing.T) {
+	mr := &mockRaft{isLeader: true}
+	fsm := stubWorkers{"w-q": {
+		ID:               "w-q",
+		Status:           internalraft.WorkerQuarantined,
+		QuarantinedUntil: time.Now().Add(time.Minute),
+		QuarantineCount:  2,
+	}}
+	reg := NewAgentRegistryWithConfig(mr, fsm, "50051", DefaultConfig())
+
+	resp, err := reg.RegisterWorker(context.Background(), &workerpb.RegisterWorkerRequest{WorkerId: "w-q"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if resp.Ok {
+		t.Error("new leader must honour a quarantine recorded in the FSM")
+	}
+	if len(mr.appliedCmds) != 0 {
+		t.Errorf("expected no Raft writes, got %d", len(mr.appliedCmds))
+	}
+}
+
+func TestHeartbeatAfterFailoverMarksWorkerOnline(t *testing.T) {
+	for _, status := range []string{internalraft.WorkerSuspect, internalraft.WorkerOffline} {
+		fsm := internalraft.NewPipelineFSM()
+		old := &mockRaft{isLeader: true, fsm: fsm}
+		resp, err := NewAgentRegistryWithConfig(old, fsm, "50051", DefaultConfig()).
+			RegisterWorker(context.Background(), &workerpb.RegisterWorkerRequest{WorkerId: "w-1"})
+		if err != nil || !resp.Ok {
+			t.Fatalf("register: %v %+v", err, resp)
+		}
+		// The old leader's last word on w-1 before it lost leadership.
+		cmd, _ := internalraft.MarshalCommand(internalraft.CmdUpdateWorkerStatus,
+			internalraft.UpdateWorkerStatusPayload{ID: "w-1", Status: status})
+		if err := old.Apply(cmd, time.Second); err != nil {
+			t.Fatalf("apply status: %v", err)
+		}
+
+		mr := &mockRaft{isLeader: true, fsm: fsm}
+		reg := NewAgentRegistryWithConfig(mr, fsm, "50051", DefaultConfig())
+		hb, err := reg.Heartbeat(context.Background(),
+			&workerpb.HeartbeatRequest{WorkerId: "w-1", Epoch: resp.Epoch})
+		if err != nil || !hb.Ok {
+			t.Fatalf("%s: heartbeat: %v %+v", status, err, hb)
+		}
+		if got := fsm.GetWorker("w-1").Status; got != internalraft.WorkerOnline {
+			t.Errorf("%s worker after failover heartbeat: status %s, want online", status, got)
+		}
+	}
+}
+
+// ── epoch fencing tests ──────────────────────────────────────────────────────
+
+func TestRegistrationEpochFencesZombie(t *testing.T) {
+	fsm := internalraft.NewPipelineFSM()
+	mr := &mockRaft{isLeader: true, fsm: fsm}
+	reg := NewAgentRegistryWithConfig(mr, fsm, "50051", DefaultConfig())
+	ctx := context.Background()
+
+	first, err := reg.RegisterWorker(ctx, &workerpb.RegisterWorkerRequest{WorkerId: "w-1"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	second, err := reg.RegisterWorker(ctx, &workerpb.RegisterWorkerRequest{WorkerId: "w-1"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if first.Epoch == 0 || second.Epoch <= first.Epoch {
+		t.Fatalf("expected increasing epochs, got %d then %d", first.Epoch, second.Epoch)
+	}
+
+	// The restarted process keeps heartbeating normally…
+	resp, err := reg.Heartbeat(ctx, &workerpb.HeartbeatRequest{WorkerId: "w-1", Epoch: second.Epoch})
+	if err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if !resp.Ok || resp.Fenced {
+		t.Errorf("current epoch heartbeat rejected: %+v", resp)
+	}
+
+	// …while the zombie is fenced.
+	resp, err = reg.Heartbeat(ctx, &workerpb.HeartbeatRequest{WorkerId: "w-1", Epoch: first.Epoch})
+	if err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if resp.Ok || !resp.Fenced {
+		t.Errorf("stale epoch heartbeat should be fenced: %+v", resp)
+	}
+}
+
+func TestValidateEpoch(t *testing.T) {
+	fsm := stubWorkers{"w-1": {ID: "w-1", Epoch: 5}}
+	reg := NewAgentRegistryWithConfig(&mockRaft{isLeader: true}, fsm, "50051", DefaultConfig())
+	cases := []struct {
+		id    string
+		epoch uint64
+		want  error
+	}{
+		{"w-1", 5, nil},
+		{"w-1", 4, ErrStaleEpoch},
+		{"w-1", 0, ErrStaleEpoch},
+		{"w-1", 6, ErrUnknownEpoch},
+		{"w-2", 5, ErrNotRegistered},
+	}
+	for _, c := range cases {
+		err := reg.ValidateEpoch(c.id, c.epoch)
+		if !errors.Is(err, c.want) || (c.want == nil && err != nil) {
+			t.Errorf("ValidateEpoch(%s, %d) = %v, want %v", c.id, c.epoch, err, c.want)
+		}
+	}
+}
+
 // ── raftAddrToGRPC tests ─────────────────────────────────────────────────────
 
 func TestRaftAddrToGRPC(t *testing.T) {
@@ -0,0 +1,38 @@
+// Package clock abstracts time and periodic work so control-plane components
+// can run either on the wall clock or under a deterministic virtual clock.
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Clock supplies the current time and runs periodic work.
+type Clock interface {
+	Now() time.Time
+	// Every calls fn each interval until ctx is done. It does not block; the
+	// real clock runs fn on its own goroutine, a Sim from its event loop.
+	Every(ctx context.Context, interval time.Duration, fn func())
+}
+
+// Real is the wall clock.
+type Real struct{}
+
+// Now returns time.Now in UTC.
+func (Real) Now() time.Time { return time.Now().UTC() }
+
+// Every starts a ticker goroutine.
+func (Real) Every(ctx context.Context, interval time.Duration, fn func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
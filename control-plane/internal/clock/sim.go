@@ -0,0 +1,108 @@
+package clock
+
+import (
+	"container/heap"
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Sim is a virtual clock and single-threaded scheduler. Nothing happens until
+// Run advances it; events then execute one at a time in (time, schedule
+// order), so a simulation whose callbacks only use Sim's clock and Rand
+// replays identically for the same seed.
+type Sim struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    uint64
+	events eventHeap
+	rng    *rand.Rand
+}
+
+// NewSim returns a Sim starting at start, with randomness derived from seed.
+func NewSim(seed uint64, start time.Time) *Sim {
+	return &Sim{now: start.UTC(), rng: rand.New(rand.NewPCG(seed, seed^0x5851f42d4c957f2d))}
+}
+
+// Now returns the virtual time.
+func (s *Sim) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// Rand returns the simulation's seeded source. Use it only from callbacks so
+// draws happen in a deterministic order.
+func (s *Sim) Rand() *rand.Rand { return s.rng }
+
+// After schedules fn to run d from now.
+func (s *Sim) After(d time.Duration, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pushLocked(s.now.Add(d), fn)
+}
+
+// Every schedules fn at each interval until ctx is done.
+func (s *Sim) Every(ctx context.Context, interval time.Duration, fn func()) {
+	var tick func()
+	tick = func() {
+		if ctx.Err() != nil {
+			return
+		}
+		fn()
+		s.After(interval, tick)
+	}
+	s.After(interval, tick)
+}
+
+// Run executes events in order until none remain before until, then sets the
+// clock to until.
+func (s *Sim) Run(until time.Time) {
+	for {
+		s.mu.Lock()
+		if len(s.events) == 0 || s.events[0].at.After(until) {
+			s.now = until
+			s.mu.Unlock()
+			return
+		}
+		ev := heap.Pop(&s.events).(*event)
+		s.now = ev.at
+		s.mu.Unlock()
+		ev.fn()
+	}
+}
+
+// RunFor runs the simulation for d of virtual time.
+func (s *Sim) RunFor(d time.Duration) {
+	s.Run(s.Now().Add(d))
+}
+
+func (s *Sim) pushLocked(at time.Time, fn func()) {
+	s.seq++
+	heap.Push(&s.events, &event{at: at, seq: s.seq, fn: fn})
+}
+
+type event struct {
+	at  time.Time
+	seq uint64
+	fn  func()
+}
+
+type eventHeap []*event
+
+func (h eventHeap) Len() int { return len(h) }
+func (h eventHeap) Less(i, j int) bool {
+	if !h[i].at.Equal(h[j].at) {
+		return h[i].at.Before(h[j].at)
+	}
+	return h[i].seq < h[j].seq
+}
+func (h eventHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *eventHeap) Push(x any)   { *h = append(*h, x.(*event)) }
+func (h *eventHeap) Pop() any {
+	old := *h
+	ev := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return ev
+}
@@ -0,0 +1,45 @@
+package clock
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSimRunsEventsInOrder(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewSim(1, start)
+	var got []string
+	s.After(2*time.Second, func() { got = append(got, "b") })
+	s.After(time.Second, func() {
+		got = append(got, "a")
+		// Scheduled from a callback for the same instant as "b": runs after it.
+		s.After(time.Second, func() { got = append(got, "c") })
+	})
+	s.After(5*time.Second, func() { got = append(got, "late") })
+
+	s.RunFor(3 * time.Second)
+	if want := []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if !s.Now().Equal(start.Add(3 * time.Second)) {
+		t.Errorf("clock at %v after RunFor", s.Now())
+	}
+}
+
+func TestSimEveryStopsOnCancel(t *testing.T) {
+	s := NewSim(1, time.Unix(0, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := 0
+	s.Every(ctx, time.Second, func() {
+		ticks++
+		if ticks == 3 {
+			cancel()
+		}
+	})
+	s.RunFor(time.Minute)
+	if ticks != 3 {
+		t.Fatalf("got %d ticks, want 3", ticks)
+	}
+}
@@ -0,0 +1,107 @@
+package fakeworker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ChurnPattern names a way of disturbing the fleet while it runs.
+type ChurnPattern string
+
+const (
+	// ChurnNone leaves every worker running.
+	ChurnNone ChurnPattern = ""
+	// ChurnCrashStorm crashes Fraction of the fleet at once every Every and
+	// restarts them together after Downtime — a rack or AZ going dark.
+	ChurnCrashStorm ChurnPattern = "crash-storm"
+	// ChurnRolling crashes one worker at a time, spaced so Fraction of the
+	// fleet cycles through each Every, each coming back after Downtime.
+	ChurnRolling ChurnPattern = "rolling"
+	// ChurnReconnect makes Fraction of the fleet drop its leader address and
+	// reconnect simultaneously every Every — the thundering herd that follows
+	// a leader failover.
+	ChurnReconnect ChurnPattern = "mass-reconnect"
+)
+
+// Churn configures the churn pattern.
+type Churn struct {
+	Pattern  ChurnPattern
+	Every    time.Duration // period of the pattern
+	Fraction float64       // share of workers affected per period, 0–1
+	Downtime time.Duration // how long crashed workers stay down
+}
+
+func (c Churn) validate() error {
+	switch c.Pattern {
+	case ChurnNone:
+		return nil
+	case ChurnCrashStorm, ChurnRolling, ChurnReconnect:
+	default:
+		return fmt.Errorf("fakeworker: unknown churn pattern %q", c.Pattern)
+	}
+	if c.Every <= 0 {
+		return fmt.Errorf("fakeworker: churn %s needs a positive period", c.Pattern)
+	}
+	if c.Fraction <= 0 || c.Fraction > 1 {
+		return fmt.Errorf("fakeworker: churn fraction must be in (0, 1], got %g", c.Fraction)
+	}
+	return nil
+}
+
+// churn drives the configured pattern until ctx is done.
+func (f *Fleet) churn(ctx context.Context) {
+	c := f.cfg.Churn
+	if c.Pattern == ChurnNone {
+		return
+	}
+	n := max(1, int(c.Fraction*float64(len(f.workers))))
+	interval := c.Every
+	if c.Pattern == ChurnRolling {
+		interval = c.Every / time.Duration(n)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		switch c.Pattern {
+		case ChurnCrashStorm:
+			victims := f.pick(n)
+			slog.Info("churn: crash storm", "workers", len(victims), "downtime", c.Downtime)
+			for _, i := range victims {
+				f.Crash(i)
+			}
+			f.restartAfter(ctx, c.Downtime, victims)
+		case ChurnRolling:
+			victims := f.pick(1)
+			f.Crash(victims[0])
+			f.restartAfter(ctx, c.Downtime, victims)
+		case ChurnReconnect:
+			victims := f.pick(n)
+			slog.Info("churn: mass reconnect", "workers", len(victims))
+			for _, i := range victims {
+				f.Reconnect(i)
+			}
+		}
+	}
+}
+
+// pick returns n distinct random worker indexes.
+func (f *Fleet) pick(n int) []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rng.Perm(len(f.workers))[:n]
+}
+
+func (f *Fleet) restartAfter(ctx context.Context, d time.Duration, idx []int) {
+	time.AfterFunc(d, func() {
+		for _, i := range idx {
+			f.Restart(ctx, i)
+		}
+	})
+}
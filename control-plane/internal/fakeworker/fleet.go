@@ -0,0 +1,226 @@
+// Package fakeworker simulates large numbers of workers speaking the
+// WorkerService protocol against a real control plane, for load and scale
+// testing. Each simulated worker registers, heartbeats, follows leader
+// redirects and re-registers exactly as the Python worker does, but costs a
+// goroutine instead of a container. Optionally it also pulls tasks from
+// TaskService and pretends to run them.
+package fakeworker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+)
+
+// Config controls a fleet of simulated workers.
+type Config struct {
+	Seeds      []string      // control-plane gRPC addresses; workers start at a random one
+	Workers    int           // number of simulated workers
+	IDPrefix   string        // worker IDs are "<prefix>-00042"; default "fake"
+	Heartbeat  time.Duration // heartbeat interval, jittered ±10%; default 5s
+	Ramp       time.Duration // initial registrations are spread evenly over this window
+	RetryDelay time.Duration // backoff after a failed RPC; default 1s
+	RPCTimeout time.Duration // per-RPC deadline; default 5s
+	JoinToken  string        // sent as x-join-token on registration when set
+	// Redirects rewrites leader addresses returned by the control plane, e.g.
+	// Docker-internal "cp-aws-1:50051" to a host-published "localhost:50051".
+	Redirects map[string]string
+	Churn     Churn
+	Tasks     Tasks
+	Seed      uint64 // seeds jitter, churn and task failure choices
+}
+
+func (c *Config) setDefaults() {
+	if c.IDPrefix == "" {
+		c.IDPrefix = "fake"
+	}
+	if c.Heartbeat == 0 {
+		c.Heartbeat = 5 * time.Second
+	}
+	if c.RetryDelay == 0 {
+		c.RetryDelay = time.Second
+	}
+	if c.RPCTimeout == 0 {
+		c.RPCTimeout = 5 * time.Second
+	}
+	c.Tasks.setDefaults()
+}
+
+// Fleet runs and churns a set of simulated workers.
+type Fleet struct {
+	cfg Config
+	rec *Recorder
+
+	mu      sync.Mutex
+	conns   map[string]*grpc.ClientConn
+	workers []*worker
+	rng     *rand.Rand
+	wg      sync.WaitGroup
+}
+
+// NewFleet validates cfg and builds an idle fleet; call Run to start it.
+func NewFleet(cfg Config) (*Fleet, error) {
+	cfg.setDefaults()
+	if len(cfg.Seeds) == 0 {
+		return nil, fmt.Errorf("fakeworker: at least one control-plane address is required")
+	}
+	if cfg.Workers <= 0 {
+		return nil, fmt.Errorf("fakeworker: worker count must be positive, got %d", cfg.Workers)
+	}
+	if err := cfg.Churn.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Tasks.validate(); err != nil {
+		return nil, err
+	}
+	f := &Fleet{
+		cfg:   cfg,
+		rec:   NewRecorder(),
+		conns: make(map[string]*grpc.ClientConn),
+		rng:   rand.New(rand.NewPCG(cfg.Seed, 0x6f72636865737472)),
+	}
+	clouds := []string{"aws", "gcp", "azure"}
+	for i := 0; i < cfg.Workers; i++ {
+		f.workers = append(f.workers, &worker{
+			id:    fmt.Sprintf("%s-%05d", cfg.IDPrefix, i),
+			cloud: clouds[i%len(clouds)],
+			f:     f,
+			rng:   rand.New(rand.NewPCG(cfg.Seed, uint64(i))),
+			kick:  make(chan struct{}, 1),
+		})
+	}
+	return f, nil
+}
+
+// Recorder returns the fleet's latency recorder.
+func (f *Fleet) Recorder() *Recorder { return f.rec }
+
+// Run starts every worker over the ramp window, applies the churn pattern,
+// and blocks until ctx is done and all workers have stopped.
+func (f *Fleet) Run(ctx context.Context) error {
+	defer f.closeConns()
+	step := time.Duration(0)
+	if len(f.workers) > 1 {
+		step = f.cfg.Ramp / time.Duration(len(f.workers)-1)
+	}
+	go f.churn(ctx)
+	for i := range f.workers {
+		if i > 0 && step > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(step):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		f.Restart(ctx, i)
+	}
+	<-ctx.Done()
+	f.wg.Wait()
+	return nil
+}
+
+// Registered returns how many workers currently hold a registration.
+func (f *Fleet) Registered() int {
+	n := 0
+	for _, w := range f.workers {
+		if w.registered() {
+			n++
+		}
+	}
+	return n
+}
+
+// Crash stops worker i as if its process died: no deregistration, it simply
+// goes silent until Restart.
+func (f *Fleet) Crash(i int) {
+	f.mu.Lock()
+	w := f.workers[i]
+	cancel := w.cancel
+	w.cancel = nil
+	f.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		f.rec.Inc("crash")
+	}
+}
+
+// Restart starts worker i as a fresh process, which registers anew. It is a
+// no-op if the worker is running.
+func (f *Fleet) Restart(ctx context.Context, i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.workers[i]
+	if w.cancel != nil || ctx.Err() != nil {
+		return
+	}
+	wctx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+	addr := f.cfg.Seeds[f.rng.IntN(len(f.cfg.Seeds))]
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		w.run(wctx, addr)
+	}()
+}
+
+// Reconnect makes worker i drop its leader address and heartbeat immediately
+// via a random seed, as clients do when their connection breaks.
+func (f *Fleet) Reconnect(i int) {
+	select {
+	case f.workers[i].kick <- struct{}{}:
+	default:
+	}
+}
+
+// client returns a WorkerService client for addr.
+func (f *Fleet) client(addr string) (workerpb.WorkerServiceClient, error) {
+	conn, err := f.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+	return workerpb.NewWorkerServiceClient(conn), nil
+}
+
+// conn returns the connection to addr, sharing one per address across all
+// workers and services.
+func (f *Fleet) conn(addr string) (*grpc.ClientConn, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	conn, ok := f.conns[addr]
+	if !ok {
+		var err error
+		conn, err = grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return nil, err
+		}
+		f.conns[addr] = conn
+	}
+	return conn, nil
+}
+
+func (f *Fleet) randomSeed() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cfg.Seeds[f.rng.IntN(len(f.cfg.Seeds))]
+}
+
+func (f *Fleet) closeConns() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for addr, conn := range f.conns {
+		if err := conn.Close(); err != nil {
+			slog.Debug("close connection", "addr", addr, "error", err)
+		}
+	}
+	f.conns = make(map[string]*grpc.ClientConn)
+}
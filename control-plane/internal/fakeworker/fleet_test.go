@@ -0,0 +1,222 @@
+package fakeworker
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+)
+
+// fakeService is a WorkerService that either leads (accepting everything) or
+// redirects to leaderAddr.
+type fakeService struct {
+	workerpb.UnimplementedWorkerServiceServer
+	leaderAddr string // empty: this node leads
+
+	mu         sync.Mutex
+	epochs     map[string]uint64
+	next       uint64
+	heartbeats map[string]int
+	fence      map[string]bool
+
+	tasks *fakeTaskService // also served when set
+}
+
+func (s *fakeService) RegisterWorker(_ context.Context, req *workerpb.RegisterWorkerRequest) (*workerpb.RegisterWorkerResponse, error) {
+	if s.leaderAddr != "" {
+		return &workerpb.RegisterWorkerResponse{LeaderAddr: s.leaderAddr}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	s.epochs[req.WorkerId] = s.next
+	return &workerpb.RegisterWorkerResponse{Ok: true, Epoch: s.next}, nil
+}
+
+func (s *fakeService) Heartbeat(_ context.Context, req *workerpb.HeartbeatRequest) (*workerpb.HeartbeatResponse, error) {
+	if s.leaderAddr != "" {
+		return &workerpb.HeartbeatResponse{LeaderAddr: s.leaderAddr}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fence[req.WorkerId] {
+		return &workerpb.HeartbeatResponse{Fenced: true, Error: "stale registration epoch"}, nil
+	}
+	if s.epochs[req.WorkerId] != req.Epoch {
+		return &workerpb.HeartbeatResponse{Error: "unknown registration epoch"}, nil
+	}
+	s.heartbeats[req.WorkerId]++
+	return &workerpb.HeartbeatResponse{Ok: true}, nil
+}
+
+func (s *fakeService) registrations() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next
+}
+
+func (s *fakeService) heartbeatsFrom(id string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.heartbeats[id]
+}
+
+func serve(t *testing.T, svc *fakeService) string {
+	t.Helper()
+	if svc.epochs == nil {
+		svc.epochs = make(map[string]uint64)
+		svc.heartbeats = make(map[string]int)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	workerpb.RegisterWorkerServiceServer(srv, svc)
+	if svc.tasks != nil {
+		taskpb.RegisterTaskServiceServer(srv, svc.tasks)
+	}
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// leaderAndFollower starts a leader and a follower that redirects to it.
+func leaderAndFollower(t *testing.T) (leader *fakeService, addrs []string) {
+	leader = &fakeService{}
+	leaderAddr := serve(t, leader)
+	followerAddr := serve(t, &fakeService{leaderAddr: leaderAddr})
+	return leader, []string{followerAddr, leaderAddr}
+}
+
+func runFleet(t *testing.T, cfg Config, d time.Duration) *Fleet {
+	t.Helper()
+	f, err := NewFleet(cfg)
+	if err != nil {
+		t.Fatalf("NewFleet: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		f.Run(ctx)
+		close(done)
+	}()
+	<-ctx.Done()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fleet did not stop")
+	}
+	return f
+}
+
+func TestFleetRegistersAndHeartbeatsViaRedirects(t *testing.T) {
+	leader, addrs := leaderAndFollower(t)
+	f := runFleet(t, Config{
+		Seeds:     addrs,
+		Workers:   50,
+		Heartbeat: 20 * time.Millisecond,
+		Ramp:      50 * time.Millisecond,
+		Seed:      1,
+	}, 500*time.Millisecond)
+
+	if got := leader.registrations(); got != 50 {
+		t.Errorf("expected 50 registrations, got %d", got)
+	}
+	if f.Recorder().Counter("redirect") == 0 {
+		t.Error("expected workers seeded at the follower to be redirected")
+	}
+	for _, s := range f.Recorder().Summaries() {
+		if s.Op == "heartbeat" && s.Count < 50*5 {
+			t.Errorf("expected steady heartbeats, got %d", s.Count)
+		}
+		if s.Errors != 0 {
+			t.Errorf("%s: %d errors", s.Op, s.Errors)
+		}
+	}
+}
+
+func TestFleetCrashStormReregisters(t *testing.T) {
+	leader, addrs := leaderAndFollower(t)
+	f := runFleet(t, Config{
+		Seeds:     addrs,
+		Workers:   20,
+		Heartbeat: 20 * time.Millisecond,
+		Churn:     Churn{Pattern: ChurnCrashStorm, Every: 100 * time.Millisecond, Fraction: 0.5, Downtime: 30 * time.Millisecond},
+		Seed:      2,
+	}, 550*time.Millisecond)
+
+	if crashes := f.Recorder().Counter("crash"); crashes < 40 {
+		t.Errorf("expected several storms of 10 crashes, got %d", crashes)
+	}
+	// Every restart is a new process and must register again.
+	if got := leader.registrations(); got < 20+30 {
+		t.Errorf("expected crashed workers to re-register, got %d registrations", got)
+	}
+}
+
+func TestFleetStopsFencedWorker(t *testing.T) {
+	leader := &fakeService{fence: map[string]bool{"fake-00000": true}}
+	addr := serve(t, leader)
+	f := runFleet(t, Config{Seeds: []string{addr}, Workers: 2, Heartbeat: 10 * time.Millisecond, Seed: 3},
+		200*time.Millisecond)
+
+	if got := f.Recorder().Counter("fenced"); got != 1 {
+		t.Errorf("expected the fenced worker to stop after one rejection, got %d", got)
+	}
+	if leader.heartbeatsFrom("fake-00001") < 5 {
+		t.Error("unfenced worker should keep heartbeating")
+	}
+}
+
+func TestNewFleetValidates(t *testing.T) {
+	for _, cfg := range []Config{
+		{Workers: 1},
+		{Seeds: []string{"x:1"}},
+		{Seeds: []string{"x:1"}, Workers: 1, Churn: Churn{Pattern: "meteor"}},
+		{Seeds: []string{"x:1"}, Workers: 1, Churn: Churn{Pattern: ChurnRolling, Every: time.Second, Fraction: 2}},
+		{Seeds: []string{"x:1"}, Workers: 1, Tasks: Tasks{Enabled: true, FailureRate: -0.5}},
+	} {
+		if _, err := NewFleet(cfg); err == nil {
+			t.Errorf("expected %+v to be rejected", cfg)
+		}
+	}
+}
+
+func TestRecorderPercentiles(t *testing.T) {
+	r := NewRecorder()
+	for i := 1; i <= 100; i++ {
+		r.Observe("heartbeat", time.Duration(i)*time.Millisecond)
+	}
+	r.Error("register")
+	r.Inc("redirect")
+
+	sums := r.Summaries()
+	if len(sums) != 2 || sums[0].Op != "heartbeat" || sums[1].Op != "register" {
+		t.Fatalf("unexpected summaries %+v", sums)
+	}
+	hb := sums[0]
+	if hb.P50 != 50*time.Millisecond || hb.P90 != 90*time.Millisecond ||
+		hb.P99 != 99*time.Millisecond || hb.Max != 100*time.Millisecond {
+		t.Errorf("wrong percentiles %+v", hb)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"p99", "99ms", "redirect=1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package fakeworker
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Recorder collects per-operation RPC latencies and outcome counters. It
+// keeps every sample: a thousand workers heartbeating every 5s for an hour is
+// under a million durations, which is cheaper than getting percentiles wrong.
+type Recorder struct {
+	mu       sync.Mutex
+	samples  map[string][]time.Duration
+	errors   map[string]int
+	counters map[string]int
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		samples:  make(map[string][]time.Duration),
+		errors:   make(map[string]int),
+		counters: make(map[string]int),
+	}
+}
+
+// Observe records one completed RPC.
+func (r *Recorder) Observe(op string, d time.Duration) {
+	r.mu.Lock()
+	r.samples[op] = append(r.samples[op], d)
+	r.mu.Unlock()
+}
+
+// Error counts one failed RPC (transport error or timeout).
+func (r *Recorder) Error(op string) {
+	r.mu.Lock()
+	r.errors[op]++
+	r.mu.Unlock()
+}
+
+// Inc bumps a named event counter, e.g. "redirect" or "fenced".
+func (r *Recorder) Inc(name string) {
+	r.mu.Lock()
+	r.counters[name]++
+	r.mu.Unlock()
+}
+
+// Summary is the latency distribution of one operation.
+type Summary struct {
+	Op                 string
+	Count, Errors      int
+	P50, P90, P99, Max time.Duration
+}
+
+// Summaries returns one Summary per operation seen, sorted by name.
+func (r *Recorder) Summaries() []Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ops := make([]string, 0, len(r.samples))
+	for op := range r.samples {
+		ops = append(ops, op)
+	}
+	for op := range r.errors {
+		if _, ok := r.samples[op]; !ok {
+			ops = append(ops, op)
+		}
+	}
+	slices.Sort(ops)
+
+	out := make([]Summary, 0, len(ops))
+	for _, op := range ops {
+		sorted := slices.Clone(r.samples[op])
+		slices.Sort(sorted)
+		s := Summary{Op: op, Count: len(sorted), Errors: r.errors[op]}
+		if len(sorted) > 0 {
+			s.P50 = percentile(sorted, 0.50)
+			s.P90 = percentile(sorted, 0.90)
+			s.P99 = percentile(sorted, 0.99)
+			s.Max = sorted[len(sorted)-1]
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// Counter returns the current value of a named counter.
+func (r *Recorder) Counter(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counters[name]
+}
+
+// percentile uses the nearest-rank method on an ascending slice.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted))+0.999999) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+// WriteReport prints the latency table followed by the event counters.
+func (r *Recorder) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\tcount\terrors\tp50\tp90\tp99\tmax\t")
+	for _, s := range r.Summaries() {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n", s.Op, s.Count, s.Errors,
+			round(s.P50), round(s.P90), round(s.P99), round(s.Max))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	names := make([]string, 0, len(r.counters))
+	for name := range r.counters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	counts := make([]int, len(names))
+	for i, name := range names {
+		counts[i] = r.counters[name]
+	}
+	r.mu.Unlock()
+	for i, name := range names {
+		if _, err := fmt.Fprintf(w, "%s=%d\n", name, counts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func round(d time.Duration) time.Duration {
+	if d >= time.Millisecond {
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
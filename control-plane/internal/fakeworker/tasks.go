@@ -0,0 +1,252 @@
+package fakeworker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+)
+
+// Tasks makes simulated workers pull and "run" tasks: each one long-polls
+// AcquireTask, holds the task for Duration (jittered ±10%) while renewing its
+// lease, then reports success, or failure with probability FailureRate. A
+// MapReduce map task succeeds with a made-up output per partition, and a task
+// with an output URI asks for the output commit before reporting success. A
+// kill order, from a lease renewal or a heartbeat, stops the task at once and
+// reports it failed.
+type Tasks struct {
+	Enabled     bool
+	Duration    time.Duration // simulated run time; default 1s
+	FailureRate float64       // share of tasks reported as failed, 0–1
+	PollWait    time.Duration // AcquireTask long-poll; default 10s
+	RenewEvery  time.Duration // lease renewal period; default 10s
+}
+
+func (t *Tasks) setDefaults() {
+	if t.Duration == 0 {
+		t.Duration = time.Second
+	}
+	if t.PollWait == 0 {
+		t.PollWait = 10 * time.Second
+	}
+	if t.RenewEvery == 0 {
+		t.RenewEvery = 10 * time.Second
+	}
+}
+
+func (t Tasks) validate() error {
+	if t.FailureRate < 0 || t.FailureRate > 1 {
+		return fmt.Errorf("fakeworker: task failure rate must be in [0, 1], got %g", t.FailureRate)
+	}
+	return nil
+}
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// runTasks pulls and runs tasks for session s until ctx is done or the worker
+// is fenced. It tracks the leader on its own, independent of the heartbeat loop.
+func (w *worker) runTasks(ctx context.Context, s *session) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-s.ready:
+	}
+	addr := w.f.randomSeed()
+	for ctx.Err() == nil {
+		var resp *taskpb.AcquireTaskResponse
+		err := w.taskRPC(ctx, s, &addr, "acquire", w.f.cfg.Tasks.PollWait+w.f.cfg.RPCTimeout,
+			func(ctx context.Context, c taskpb.TaskServiceClient, epoch uint64) (string, error) {
+				var err error
+				resp, err = c.AcquireTask(ctx, &taskpb.AcquireTaskRequest{
+					WorkerId: w.id, Epoch: epoch, WaitMs: uint32(w.f.cfg.Tasks.PollWait.Milliseconds()),
+				})
+				return resp.GetLeaderAddr(), err
+			})
+		switch {
+		case err != nil:
+			w.sleep(ctx, w.f.cfg.RetryDelay)
+		case resp.Fenced:
+			return
+		case !resp.Ok:
+			w.f.rec.Inc("acquire_rejected")
+			slog.Debug("acquire rejected", "worker_id", w.id, "error", resp.Error)
+			w.sleep(ctx, w.f.cfg.RetryDelay)
+		case resp.Task != nil:
+			w.runTask(ctx, s, &addr, resp.Task)
+		}
+	}
+}
+
+// runTask holds task for the configured duration, renewing its lease, and
+// reports the outcome. A crash (ctx done) abandons the task without a report;
+// a kill order stops it with one.
+func (w *worker) runTask(ctx context.Context, s *session, addr *string, task *taskpb.Task) {
+	w.f.rec.Inc("task_started")
+	done := time.NewTimer(w.jitter(w.f.cfg.Tasks.Duration))
+	defer done.Stop()
+	renew := time.NewTicker(w.f.cfg.Tasks.RenewEvery)
+	defer renew.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-renew.C:
+			var resp *taskpb.RenewTaskLeaseResponse
+			err := w.taskRPC(ctx, s, addr, "renew", w.f.cfg.RPCTimeout,
+				func(ctx context.Context, c taskpb.TaskServiceClient, epoch uint64) (string, error) {
+					var err error
+					resp, err = c.RenewTaskLease(ctx, &taskpb.RenewTaskLeaseRequest{
+						WorkerId: w.id, Epoch: epoch, TaskId: task.TaskId, Attempt: task.Attempt,
+					})
+					return resp.GetLeaderAddr(), err
+				})
+			if err == nil && (resp.LeaseLost || resp.Fenced) {
+				w.f.rec.Inc("lease_lost")
+				return
+			}
+			if k := resp.GetKill(); err == nil && k != nil {
+				w.stopTask(ctx, s, addr, task, k.Reason)
+				return
+			}
+		case k := <-s.kills:
+			if k.TaskId == task.TaskId && k.Attempt == task.Attempt {
+				w.stopTask(ctx, s, addr, task, k.Reason)
+				return
+			}
+		case <-done.C:
+			w.completeTask(ctx, s, addr, task)
+			return
+		}
+	}
+}
+
+func (w *worker) completeTask(ctx context.Context, s *session, addr *string, task *taskpb.Task) {
+	w.rngMu.Lock()
+	failed := w.rng.Float64() < w.f.cfg.Tasks.FailureRate
+	w.rngMu.Unlock()
+	req := &taskpb.CompleteTaskRequest{WorkerId: w.id, TaskId: task.TaskId, Attempt: task.Attempt, Succeeded: !failed}
+	outcome := "task_succeeded"
+	if failed {
+		req.Error = "simulated failure"
+		outcome = "task_failed"
+	} else {
+		req.Outputs = mapOutputs(w.id, task)
+		if task.OutputUri != "" && !w.commitOutput(ctx, s, addr, task) {
+			return
+		}
+	}
+	w.report(ctx, s, addr, req, outcome)
+}
+
+// stopTask reports task, stopped on a kill order, as failed.
+func (w *worker) stopTask(ctx context.Context, s *session, addr *string, task *taskpb.Task, reason string) {
+	slog.Debug("task killed", "worker_id", w.id, "task_id", task.TaskId, "attempt", task.Attempt, "reason", reason)
+	w.report(ctx, s, addr, &taskpb.CompleteTaskRequest{
+		WorkerId: w.id, TaskId: task.TaskId, Attempt: task.Attempt, Error: "stopped: " + reason,
+	}, "task_killed")
+}
+
+// report sends req, recording outcome once the leader accepts it.
+func (w *worker) report(ctx context.Context, s *session, addr *string, req *taskpb.CompleteTaskRequest, outcome string) {
+	var resp *taskpb.CompleteTaskResponse
+	err := w.taskRPC(ctx, s, addr, "complete", w.f.cfg.RPCTimeout,
+		func(ctx context.Context, c taskpb.TaskServiceClient, epoch uint64) (string, error) {
+			req.Epoch = epoch
+			var err error
+			resp, err = c.CompleteTask(ctx, req)
+			return resp.GetLeaderAddr(), err
+		})
+	switch {
+	case err != nil:
+	case resp.Ok:
+		w.f.rec.Inc(outcome)
+	case resp.LeaseLost:
+		w.f.rec.Inc("lease_lost")
+	default:
+		w.f.rec.Inc("complete_rejected")
+		slog.Debug("complete rejected", "worker_id", w.id, "task_id", req.TaskId, "error", resp.Error)
+	}
+}
+
+// commitOutput asks for task's output commit, as a worker does before writing
+// the output. It reports whether the commit was granted.
+func (w *worker) commitOutput(ctx context.Context, s *session, addr *string, task *taskpb.Task) bool {
+	var resp *taskpb.CommitTaskOutputResponse
+	err := w.taskRPC(ctx, s, addr, "commit", w.f.cfg.RPCTimeout,
+		func(ctx context.Context, c taskpb.TaskServiceClient, epoch uint64) (string, error) {
+			var err error
+			resp, err = c.CommitTaskOutput(ctx, &taskpb.CommitTaskOutputRequest{
+				WorkerId: w.id, Epoch: epoch, TaskId: task.TaskId, Attempt: task.Attempt,
+			})
+			return resp.GetLeaderAddr(), err
+		})
+	switch {
+	case err != nil:
+		return false
+	case resp.Ok:
+		return true
+	case resp.LeaseLost:
+		w.f.rec.Inc("lease_lost")
+	default:
+		w.f.rec.Inc("commit_rejected")
+		slog.Debug("commit rejected", "worker_id", w.id, "task_id", task.TaskId, "error", resp.Error)
+	}
+	return false
+}
+
+// mapOutputs fakes the per-partition outputs of a MapReduce map task, held
+// on the worker; other tasks report none.
+func mapOutputs(workerID string, task *taskpb.Task) []*taskpb.IntermediateOutput {
+	out := make([]*taskpb.IntermediateOutput, task.Partitions)
+	for r := range out {
+		out[r] = &taskpb.IntermediateOutput{
+			Partition: uint32(r),
+			Uri:       fmt.Sprintf("fake://%s/%s/%d/part-%05d", workerID, task.TaskId, task.Attempt, r),
+			Bytes:     1 << 20,
+		}
+	}
+	return out
+}
+
+// taskRPC runs call against *addr with the session's credential, following up
+// to maxRedirects leader redirects; call returns the response's leader_addr.
+// Latency is recorded under op once a leader answers — for acquire that
+// includes the long-poll wait.
+func (w *worker) taskRPC(ctx context.Context, s *session, addr *string, op string, timeout time.Duration,
+	call func(ctx context.Context, c taskpb.TaskServiceClient, epoch uint64) (string, error)) error {
+	epoch, credential := s.identity()
+	if credential != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+credential)
+	}
+	for range maxRedirects {
+		conn, err := w.f.conn(*addr)
+		if err != nil {
+			w.f.rec.Error(op)
+			return err
+		}
+		rctx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		redirect, err := call(rctx, taskpb.NewTaskServiceClient(conn), epoch)
+		cancel()
+		if err != nil {
+			if !stopping(ctx) {
+				w.f.rec.Error(op)
+				slog.Debug(op+" failed", "worker_id", w.id, "addr", *addr, "error", err)
+				*addr = w.f.randomSeed()
+			}
+			return err
+		}
+		if redirect == "" {
+			w.f.rec.Observe(op, time.Since(start))
+			return nil
+		}
+		w.f.rec.Inc("redirect")
+		*addr = w.f.rewrite(redirect)
+	}
+	return errTooManyRedirects
+}
@@ -0,0 +1,125 @@
+package fakeworker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+)
+
+// fakeTaskService hands out a fixed number of tasks and records results. Like
+// fakeService it either leads or redirects to leaderAddr.
+type fakeTaskService struct {
+	taskpb.UnimplementedTaskServiceServer
+	leaderAddr string
+
+	mu        sync.Mutex
+	pending   int
+	next      int
+	renewals  int
+	succeeded int
+	failed    int
+}
+
+func (s *fakeTaskService) AcquireTask(ctx context.Context, req *taskpb.AcquireTaskRequest) (*taskpb.AcquireTaskResponse, error) {
+	if s.leaderAddr != "" {
+		return &taskpb.AcquireTaskResponse{LeaderAddr: s.leaderAddr}, nil
+	}
+	s.mu.Lock()
+	if s.pending > 0 {
+		s.pending--
+		s.next++
+		id := fmt.Sprintf("t-%d", s.next)
+		s.mu.Unlock()
+		return &taskpb.AcquireTaskResponse{Ok: true, Task: &taskpb.Task{TaskId: id, Attempt: 1}}, nil
+	}
+	s.mu.Unlock()
+	// Nothing to do: hold the poll like the real service.
+	select {
+	case <-time.After(time.Duration(req.WaitMs) * time.Millisecond):
+	case <-ctx.Done():
+	}
+	return &taskpb.AcquireTaskResponse{Ok: true}, nil
+}
+
+func (s *fakeTaskService) RenewTaskLease(_ context.Context, req *taskpb.RenewTaskLeaseRequest) (*taskpb.RenewTaskLeaseResponse, error) {
+	if s.leaderAddr != "" {
+		return &taskpb.RenewTaskLeaseResponse{LeaderAddr: s.leaderAddr}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renewals++
+	return &taskpb.RenewTaskLeaseResponse{Ok: true}, nil
+}
+
+func (s *fakeTaskService) CompleteTask(_ context.Context, req *taskpb.CompleteTaskRequest) (*taskpb.CompleteTaskResponse, error) {
+	if s.leaderAddr != "" {
+		return &taskpb.CompleteTaskResponse{LeaderAddr: s.leaderAddr}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Succeeded {
+		s.succeeded++
+	} else {
+		s.failed++
+	}
+	return &taskpb.CompleteTaskResponse{Ok: true}, nil
+}
+
+func (s *fakeTaskService) results() (succeeded, failed, renewals int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.succeeded, s.failed, s.renewals
+}
+
+func TestFleetRunsTasks(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		failureRate float64
+	}{
+		{"all succeed", 0},
+		{"all fail", 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tasks := &fakeTaskService{pending: 40}
+			leaderAddr := serve(t, &fakeService{tasks: tasks})
+			followerAddr := serve(t, &fakeService{leaderAddr: leaderAddr,
+				tasks: &fakeTaskService{leaderAddr: leaderAddr}})
+
+			f := runFleet(t, Config{
+				Seeds:     []string{followerAddr, leaderAddr},
+				Workers:   10,
+				Heartbeat: 20 * time.Millisecond,
+				Ramp:      20 * time.Millisecond,
+				Tasks: Tasks{
+					Enabled:     true,
+					Duration:    30 * time.Millisecond,
+					FailureRate: tc.failureRate,
+					PollWait:    20 * time.Millisecond,
+					RenewEvery:  10 * time.Millisecond,
+				},
+				Seed: 4,
+			}, 600*time.Millisecond)
+
+			succeeded, failed, renewals := tasks.results()
+			want := [2]int{40, 0}
+			if tc.failureRate == 1 {
+				want = [2]int{0, 40}
+			}
+			if got := [2]int{succeeded, failed}; got != want {
+				t.Errorf("succeeded/failed = %v, want %v", got, want)
+			}
+			if renewals == 0 {
+				t.Error("expected leases to be renewed while tasks ran")
+			}
+			rec := f.Recorder()
+			if rec.Counter("task_started") != 40 || rec.Counter("task_succeeded")+rec.Counter("task_failed") != 40 {
+				t.Errorf("recorder counts: started=%d succeeded=%d failed=%d", rec.Counter("task_started"),
+					rec.Counter("task_succeeded"), rec.Counter("task_failed"))
+			}
+		})
+	}
+}
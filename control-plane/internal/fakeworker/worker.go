@@ -0,0 +1,227 @@
+package fakeworker
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+)
+
+// maxRedirects bounds how many redirects a single attempt follows before
+// backing off, so a cluster mid-election can't spin a worker in a loop.
+const maxRedirects = 5
+
+// worker is one simulated worker. Each Restart runs a new session — the
+// equivalent of a new process — so a crashed session that is still unwinding
+// never shares state with its successor.
+type worker struct {
+	id    string
+	cloud string
+	f     *Fleet
+	kick  chan struct{}
+
+	cancel  context.CancelFunc // guarded by f.mu; nil while crashed
+	current atomic.Pointer[session]
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+}
+
+// session is the state of one worker process.
+type session struct {
+	addr       string // heartbeat loop only
+	registered atomic.Bool
+	ready      chan struct{} // closed on first registration
+	readyOnce  sync.Once
+	kills      chan *workerpb.TaskKill // heartbeat → task loop; dropped when full
+
+	mu         sync.Mutex // epoch and credential are read by the task loop too
+	epoch      uint64
+	credential string
+}
+
+func (s *session) identity() (uint64, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.epoch, s.credential
+}
+
+func (w *worker) registered() bool {
+	s := w.current.Load()
+	return s != nil && s.registered.Load()
+}
+
+// run registers and then heartbeats until ctx is done or the worker is fenced.
+// With tasks enabled, a second loop pulls and runs tasks alongside.
+func (w *worker) run(ctx context.Context, addr string) {
+	s := &session{addr: addr, ready: make(chan struct{}), kills: make(chan *workerpb.TaskKill, 8)}
+	w.current.Store(s)
+	defer s.registered.Store(false)
+
+	if w.f.cfg.Tasks.Enabled {
+		tctx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			w.runTasks(tctx, s)
+		}()
+		defer func() {
+			stop()
+			<-done
+		}()
+	}
+
+	for ctx.Err() == nil {
+		if !s.registered.Load() && !w.register(ctx, s) {
+			w.sleep(ctx, w.f.cfg.RetryDelay)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.kick:
+			s.addr = w.f.randomSeed()
+			w.f.rec.Inc("reconnect")
+		case <-time.After(w.jitter(w.f.cfg.Heartbeat)):
+		}
+		if !w.heartbeat(ctx, s) {
+			return
+		}
+	}
+}
+
+// register attempts registration, following redirects. It reports success.
+func (w *worker) register(ctx context.Context, s *session) bool {
+	req := &workerpb.RegisterWorkerRequest{WorkerId: w.id, Address: w.id + ":8080", CloudTag: w.cloud}
+	if w.f.cfg.JoinToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-join-token", w.f.cfg.JoinToken)
+	}
+	// A live registration is only replaced by the worker holding it.
+	if _, credential := s.identity(); credential != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+credential)
+	}
+	for range maxRedirects {
+		client, err := w.f.client(s.addr)
+		if err != nil {
+			w.f.rec.Error("register")
+			return false
+		}
+		rctx, cancel := context.WithTimeout(ctx, w.f.cfg.RPCTimeout)
+		start := time.Now()
+		resp, err := client.RegisterWorker(rctx, req)
+		cancel()
+		if err != nil {
+			if !stopping(ctx) {
+				w.f.rec.Error("register")
+				slog.Debug("register failed", "worker_id", w.id, "addr", s.addr, "error", err)
+				s.addr = w.f.randomSeed()
+			}
+			return false
+		}
+		w.f.rec.Observe("register", time.Since(start))
+		switch {
+		case resp.Ok:
+			s.mu.Lock()
+			s.epoch, s.credential = resp.Epoch, resp.Credential
+			s.mu.Unlock()
+			s.registered.Store(true)
+			s.readyOnce.Do(func() { close(s.ready) })
+			return true
+		case resp.LeaderAddr != "":
+			w.f.rec.Inc("redirect")
+			s.addr = w.f.rewrite(resp.LeaderAddr)
+		default:
+			w.f.rec.Inc("register_rejected")
+			slog.Debug("register rejected", "worker_id", w.id, "error", resp.Error)
+			return false
+		}
+	}
+	return false
+}
+
+// heartbeat sends one heartbeat. It returns false once the worker is fenced
+// and must stop.
+func (w *worker) heartbeat(ctx context.Context, s *session) bool {
+	client, err := w.f.client(s.addr)
+	if err != nil {
+		w.f.rec.Error("heartbeat")
+		return true
+	}
+	epoch, credential := s.identity()
+	if credential != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+credential)
+	}
+	rctx, cancel := context.WithTimeout(ctx, w.f.cfg.RPCTimeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := client.Heartbeat(rctx, &workerpb.HeartbeatRequest{WorkerId: w.id, Epoch: epoch})
+	if err != nil {
+		if !stopping(ctx) {
+			// The node may be down; try another and keep the registration.
+			w.f.rec.Error("heartbeat")
+			slog.Debug("heartbeat failed", "worker_id", w.id, "addr", s.addr, "error", err)
+			s.addr = w.f.randomSeed()
+		}
+		return true
+	}
+	w.f.rec.Observe("heartbeat", time.Since(start))
+	switch {
+	case resp.Ok:
+		for _, k := range resp.Kills {
+			select {
+			case s.kills <- k:
+			default: // the order is repeated on the next heartbeat
+			}
+		}
+	case resp.LeaderAddr != "":
+		w.f.rec.Inc("redirect")
+		s.addr = w.f.rewrite(resp.LeaderAddr)
+	case resp.Fenced:
+		w.f.rec.Inc("fenced")
+		slog.Warn("worker fenced — stopping", "worker_id", w.id, "error", resp.Error)
+		return false
+	default:
+		w.f.rec.Inc("reregister")
+		s.registered.Store(false)
+	}
+	return true
+}
+
+// jitter spreads d by ±10% so workers started together drift apart.
+func (w *worker) jitter(d time.Duration) time.Duration {
+	w.rngMu.Lock()
+	defer w.rngMu.Unlock()
+	return d - d/10 + time.Duration(w.rng.Int64N(int64(d/5)+1))
+}
+
+func (w *worker) sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(w.jitter(d)):
+	}
+}
+
+// stopping reports whether ctx is done or past its deadline. gRPC arms its own
+// deadline timer, so an RPC can fail with DeadlineExceeded a moment before
+// ctx.Err is set; that is the run ending, not an RPC failure.
+func stopping(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
+
+// rewrite applies the configured redirect mapping.
+func (f *Fleet) rewrite(addr string) string {
+	if to, ok := f.cfg.Redirects[addr]; ok {
+		return to
+	}
+	return addr
+}
@@ -0,0 +1,211 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.5
+// source: raft.proto
+
+package raftpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// RaftMessage is one encoded request or response.
+type RaftMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payload       []byte                 `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"` // responses only: the handler's error, if any
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftMessage) Reset() {
+	*x = RaftMessage{}
+	mi := &file_raft_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftMessage) ProtoMessage() {}
+
+func (x *RaftMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_raft_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftMessage.ProtoReflect.Descriptor instead.
+func (*RaftMessage) Descriptor() ([]byte, []int) {
+	return file_raft_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RaftMessage) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *RaftMessage) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// SnapshotChunk streams an InstallSnapshot call. The first chunk carries the
+// encoded request; every chunk may carry snapshot data.
+type SnapshotChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Request       []byte                 `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotChunk) Reset() {
+	*x = SnapshotChunk{}
+	mi := &file_raft_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotChunk) ProtoMessage() {}
+
+func (x *SnapshotChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_raft_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotChunk.ProtoReflect.Descriptor instead.
+func (*SnapshotChunk) Descriptor() ([]byte, []int) {
+	return file_raft_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SnapshotChunk) GetRequest() []byte {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *SnapshotChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+var File_raft_proto protoreflect.FileDescriptor
+
+const file_raft_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"raft.proto\x12\x04raft\"=\n" +
+	"\vRaftMessage\x12\x18\n" +
+	"\apayload\x18\x01 \x01(\fR\apayload\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"=\n" +
+	"\rSnapshotChunk\x12\x18\n" +
+	"\arequest\x18\x01 \x01(\fR\arequest\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data2\xe7\x02\n" +
+	"\rRaftTransport\x125\n" +
+	"\rAppendEntries\x12\x11.raft.RaftMessage\x1a\x11.raft.RaftMessage\x12A\n" +
+	"\x15AppendEntriesPipeline\x12\x11.raft.RaftMessage\x1a\x11.raft.RaftMessage(\x010\x01\x123\n" +
+	"\vRequestVote\x12\x11.raft.RaftMessage\x1a\x11.raft.RaftMessage\x126\n" +
+	"\x0eRequestPreVote\x12\x11.raft.RaftMessage\x1a\x11.raft.RaftMessage\x122\n" +
+	"\n" +
+	"TimeoutNow\x12\x11.raft.RaftMessage\x1a\x11.raft.RaftMessage\x12;\n" +
+	"\x0fInstallSnapshot\x12\x13.raft.SnapshotChunk\x1a\x11.raft.RaftMessage(\x01BTZRgithub.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/raft;raftpbb\x06proto3"
+
+var (
+	file_raft_proto_rawDescOnce sync.Once
+	file_raft_proto_rawDescData []byte
+)
+
+func file_raft_proto_rawDescGZIP() []byte {
+	file_raft_proto_rawDescOnce.Do(func() {
+		file_raft_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_raft_proto_rawDesc), len(file_raft_proto_rawDesc)))
+	})
+	return file_raft_proto_rawDescData
+}
+
+var file_raft_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_raft_proto_goTypes = []any{
+	(*RaftMessage)(nil),   // 0: raft.RaftMessage
+	(*SnapshotChunk)(nil), // 1: raft.SnapshotChunk
+}
+var file_raft_proto_depIdxs = []int32{
+	0, // 0: raft.RaftTransport.AppendEntries:input_type -> raft.RaftMessage
+	0, // 1: raft.RaftTransport.AppendEntriesPipeline:input_type -> raft.RaftMessage
+	0, // 2: raft.RaftTransport.RequestVote:input_type -> raft.RaftMessage
+	0, // 3: raft.RaftTransport.RequestPreVote:input_type -> raft.RaftMessage
+	0, // 4: raft.RaftTransport.TimeoutNow:input_type -> raft.RaftMessage
+	1, // 5: raft.RaftTransport.InstallSnapshot:input_type -> raft.SnapshotChunk
+	0, // 6: raft.RaftTransport.AppendEntries:output_type -> raft.RaftMessage
+	0, // 7: raft.RaftTransport.AppendEntriesPipeline:output_type -> raft.RaftMessage
+	0, // 8: raft.RaftTransport.RequestVote:output_type -> raft.RaftMessage
+	0, // 9: raft.RaftTransport.RequestPreVote:output_type -> raft.RaftMessage
+	0, // 10: raft.RaftTransport.TimeoutNow:output_type -> raft.RaftMessage
+	0, // 11: raft.RaftTransport.InstallSnapshot:output_type -> raft.RaftMessage
+	6, // [6:12] is the sub-list for method output_type
+	0, // [0:6] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_raft_proto_init() }
+func file_raft_proto_init() {
+	if File_raft_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_raft_proto_rawDesc), len(file_raft_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_raft_proto_goTypes,
+		DependencyIndexes: file_raft_proto_depIdxs,
+		MessageInfos:      file_raft_proto_msgTypes,
+	}.Build()
+	File_raft_proto = out.File
+	file_raft_proto_goTypes = nil
+	file_raft_proto_depIdxs = nil
+}
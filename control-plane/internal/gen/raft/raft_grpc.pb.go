@@ -0,0 +1,299 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             v6.33.5
+// source: raft.proto
+
+package raftpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RaftTransport_AppendEntries_FullMethodName         = "/raft.RaftTransport/AppendEntries"
+	RaftTransport_AppendEntriesPipeline_FullMethodName = "/raft.RaftTransport/AppendEntriesPipeline"
+	RaftTransport_RequestVote_FullMethodName           = "/raft.RaftTransport/RequestVote"
+	RaftTransport_RequestPreVote_FullMethodName        = "/raft.RaftTransport/RequestPreVote"
+	RaftTransport_TimeoutNow_FullMethodName            = "/raft.RaftTransport/TimeoutNow"
+	RaftTransport_InstallSnapshot_FullMethodName       = "/raft.RaftTransport/InstallSnapshot"
+)
+
+// RaftTransportClient is the client API for RaftTransport service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RaftTransportClient interface {
+	AppendEntries(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*RaftMessage, error)
+	AppendEntriesPipeline(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RaftMessage, RaftMessage], error)
+	RequestVote(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*RaftMessage, error)
+	RequestPreVote(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*RaftMessage, error)
+	TimeoutNow(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*RaftMessage, error)
+	InstallSnapshot(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SnapshotChunk, RaftMessage], error)
+}
+
+type raftTransportClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRaftTransportClient(cc grpc.ClientConnInterface) RaftTransportClient {
+	return &raftTransportClient{cc}
+}
+
+func (c *raftTransportClient) AppendEntries(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*RaftMessage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RaftMessage)
+	err := c.cc.Invoke(ctx, RaftTransport_AppendEntries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftTransportClient) AppendEntriesPipeline(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RaftMessage, RaftMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RaftTransport_ServiceDesc.Streams[0], RaftTransport_AppendEntriesPipeline_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RaftMessage, RaftMessage]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RaftTransport_AppendEntriesPipelineClient = grpc.BidiStreamingClient[RaftMessage, RaftMessage]
+
+func (c *raftTransportClient) RequestVote(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*RaftMessage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RaftMessage)
+	err := c.cc.Invoke(ctx, RaftTransport_RequestVote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftTransportClient) RequestPreVote(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*RaftMessage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RaftMessage)
+	err := c.cc.Invoke(ctx, RaftTransport_RequestPreVote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftTransportClient) TimeoutNow(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*RaftMessage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RaftMessage)
+	err := c.cc.Invoke(ctx, RaftTransport_TimeoutNow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftTransportClient) InstallSnapshot(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SnapshotChunk, RaftMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RaftTransport_ServiceDesc.Streams[1], RaftTransport_InstallSnapshot_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SnapshotChunk, RaftMessage]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RaftTransport_InstallSnapshotClient = grpc.ClientStreamingClient[SnapshotChunk, RaftMessage]
+
+// RaftTransportServer is the server API for RaftTransport service.
+// All implementations must embed UnimplementedRaftTransportServer
+// for forward compatibility.
+type RaftTransportServer interface {
+	AppendEntries(context.Context, *RaftMessage) (*RaftMessage, error)
+	AppendEntriesPipeline(grpc.BidiStreamingServer[RaftMessage, RaftMessage]) error
+	RequestVote(context.Context, *RaftMessage) (*RaftMessage, error)
+	RequestPreVote(context.Context, *RaftMessage) (*RaftMessage, error)
+	TimeoutNow(context.Context, *RaftMessage) (*RaftMessage, error)
+	InstallSnapshot(grpc.ClientStreamingServer[SnapshotChunk, RaftMessage]) error
+	mustEmbedUnimplementedRaftTransportServer()
+}
+
+// UnimplementedRaftTransportServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRaftTransportServer struct{}
+
+func (UnimplementedRaftTransportServer) AppendEntries(context.Context, *RaftMessage) (*RaftMessage, error) {
+	return nil, status.Error(codes.Unimplemented, "method AppendEntries not implemented")
+}
+func (UnimplementedRaftTransportServer) AppendEntriesPipeline(grpc.BidiStreamingServer[RaftMessage, RaftMessage]) error {
+	return status.Error(codes.Unimplemented, "method AppendEntriesPipeline not implemented")
+}
+func (UnimplementedRaftTransportServer) RequestVote(context.Context, *RaftMessage) (*RaftMessage, error) {
+	return nil, status.Error(codes.Unimplemented, "method RequestVote not implemented")
+}
+func (UnimplementedRaftTransportServer) RequestPreVote(context.Context, *RaftMessage) (*RaftMessage, error) {
+	return nil, status.Error(codes.Unimplemented, "method RequestPreVote not implemented")
+}
+func (UnimplementedRaftTransportServer) TimeoutNow(context.Context, *RaftMessage) (*RaftMessage, error) {
+	return nil, status.Error(codes.Unimplemented, "method TimeoutNow not implemented")
+}
+func (UnimplementedRaftTransportServer) InstallSnapshot(grpc.ClientStreamingServer[SnapshotChunk, RaftMessage]) error {
+	return status.Error(codes.Unimplemented, "method InstallSnapshot not implemented")
+}
+func (UnimplementedRaftTransportServer) mustEmbedUnimplementedRaftTransportServer() {}
+func (UnimplementedRaftTransportServer) testEmbeddedByValue()                       {}
+
+// UnsafeRaftTransportServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RaftTransportServer will
+// result in compilation errors.
+type UnsafeRaftTransportServer interface {
+	mustEmbedUnimplementedRaftTransportServer()
+}
+
+func RegisterRaftTransportServer(s grpc.ServiceRegistrar, srv RaftTransportServer) {
+	// If the following call panics, it indicates UnimplementedRaftTransportServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RaftTransport_ServiceDesc, srv)
+}
+
+func _RaftTransport_AppendEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RaftMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftTransportServer).AppendEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RaftTransport_AppendEntries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftTransportServer).AppendEntries(ctx, req.(*RaftMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RaftTransport_AppendEntriesPipeline_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RaftTransportServer).AppendEntriesPipeline(&grpc.GenericServerStream[RaftMessage, RaftMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RaftTransport_AppendEntriesPipelineServer = grpc.BidiStreamingServer[RaftMessage, RaftMessage]
+
+func _RaftTransport_RequestVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RaftMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftTransportServer).RequestVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RaftTransport_RequestVote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftTransportServer).RequestVote(ctx, req.(*RaftMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RaftTransport_RequestPreVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RaftMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftTransportServer).RequestPreVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RaftTransport_RequestPreVote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftTransportServer).RequestPreVote(ctx, req.(*RaftMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RaftTransport_TimeoutNow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RaftMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftTransportServer).TimeoutNow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RaftTransport_TimeoutNow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftTransportServer).TimeoutNow(ctx, req.(*RaftMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RaftTransport_InstallSnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RaftTransportServer).InstallSnapshot(&grpc.GenericServerStream[SnapshotChunk, RaftMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RaftTransport_InstallSnapshotServer = grpc.ClientStreamingServer[SnapshotChunk, RaftMessage]
+
+// RaftTransport_ServiceDesc is the grpc.ServiceDesc for RaftTransport service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RaftTransport_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "raft.RaftTransport",
+	HandlerType: (*RaftTransportServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AppendEntries",
+			Handler:    _RaftTransport_AppendEntries_Handler,
+		},
+		{
+			MethodName: "RequestVote",
+			Handler:    _RaftTransport_RequestVote_Handler,
+		},
+		{
+			MethodName: "RequestPreVote",
+			Handler:    _RaftTransport_RequestPreVote_Handler,
+		},
+		{
+			MethodName: "TimeoutNow",
+			Handler:    _RaftTransport_TimeoutNow_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "AppendEntriesPipeline",
+			Handler:       _RaftTransport_AppendEntriesPipeline_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "InstallSnapshot",
+			Handler:       _RaftTransport_InstallSnapshot_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "raft.proto",
+}
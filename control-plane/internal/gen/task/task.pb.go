@@ -0,0 +1,4305 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.5
+// source: task.proto
+
+package taskpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TaskType int32
+
+const (
+	TaskType_TASK_TYPE_UNSPECIFIED TaskType = 0
+	TaskType_TASK_TYPE_MAP         TaskType = 1
+	TaskType_TASK_TYPE_REDUCE      TaskType = 2
+	TaskType_TASK_TYPE_GENERIC     TaskType = 3
+)
+
+// Enum value maps for TaskType.
+var (
+	TaskType_name = map[int32]string{
+		0: "TASK_TYPE_UNSPECIFIED",
+		1: "TASK_TYPE_MAP",
+		2: "TASK_TYPE_REDUCE",
+		3: "TASK_TYPE_GENERIC",
+	}
+	TaskType_value = map[string]int32{
+		"TASK_TYPE_UNSPECIFIED": 0,
+		"TASK_TYPE_MAP":         1,
+		"TASK_TYPE_REDUCE":      2,
+		"TASK_TYPE_GENERIC":     3,
+	}
+)
+
+func (x TaskType) Enum() *TaskType {
+	p := new(TaskType)
+	*p = x
+	return p
+}
+
+func (x TaskType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TaskType) Descriptor() protoreflect.EnumDescriptor {
+	return file_task_proto_enumTypes[0].Descriptor()
+}
+
+func (TaskType) Type() protoreflect.EnumType {
+	return &file_task_proto_enumTypes[0]
+}
+
+func (x TaskType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TaskType.Descriptor instead.
+func (TaskType) EnumDescriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{0}
+}
+
+type TaskState int32
+
+const (
+	TaskState_TASK_STATE_UNSPECIFIED TaskState = 0
+	TaskState_TASK_STATE_PENDING     TaskState = 1 // waiting for a worker
+	TaskState_TASK_STATE_RUNNING     TaskState = 2 // assigned to assigned_worker
+	TaskState_TASK_STATE_SUCCEEDED   TaskState = 3
+	TaskState_TASK_STATE_FAILED      TaskState = 4
+	TaskState_TASK_STATE_CANCELLED   TaskState = 5
+	TaskState_TASK_STATE_BLOCKED     TaskState = 6 // job task waiting for its dependencies
+)
+
+// Enum value maps for TaskState.
+var (
+	TaskState_name = map[int32]string{
+		0: "TASK_STATE_UNSPECIFIED",
+		1: "TASK_STATE_PENDING",
+		2: "TASK_STATE_RUNNING",
+		3: "TASK_STATE_SUCCEEDED",
+		4: "TASK_STATE_FAILED",
+		5: "TASK_STATE_CANCELLED",
+		6: "TASK_STATE_BLOCKED",
+	}
+	TaskState_value = map[string]int32{
+		"TASK_STATE_UNSPECIFIED": 0,
+		"TASK_STATE_PENDING":     1,
+		"TASK_STATE_RUNNING":     2,
+		"TASK_STATE_SUCCEEDED":   3,
+		"TASK_STATE_FAILED":      4,
+		"TASK_STATE_CANCELLED":   5,
+		"TASK_STATE_BLOCKED":     6,
+	}
+)
+
+func (x TaskState) Enum() *TaskState {
+	p := new(TaskState)
+	*p = x
+	return p
+}
+
+func (x TaskState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TaskState) Descriptor() protoreflect.EnumDescriptor {
+	return file_task_proto_enumTypes[1].Descriptor()
+}
+
+func (TaskState) Type() protoreflect.EnumType {
+	return &file_task_proto_enumTypes[1]
+}
+
+func (x TaskState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TaskState.Descriptor instead.
+func (TaskState) EnumDescriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{1}
+}
+
+// ErrorClass is how a worker classifies a failed attempt.
+type ErrorClass int32
+
+const (
+	ErrorClass_ERROR_CLASS_UNSPECIFIED ErrorClass = 0 // retryable
+	ErrorClass_ERROR_CLASS_RETRYABLE   ErrorClass = 1 // e.g. a transient storage error
+	ErrorClass_ERROR_CLASS_FATAL       ErrorClass = 2 // retrying cannot help, e.g. malformed input
+)
+
+// Enum value maps for ErrorClass.
+var (
+	ErrorClass_name = map[int32]string{
+		0: "ERROR_CLASS_UNSPECIFIED",
+		1: "ERROR_CLASS_RETRYABLE",
+		2: "ERROR_CLASS_FATAL",
+	}
+	ErrorClass_value = map[string]int32{
+		"ERROR_CLASS_UNSPECIFIED": 0,
+		"ERROR_CLASS_RETRYABLE":   1,
+		"ERROR_CLASS_FATAL":       2,
+	}
+)
+
+func (x ErrorClass) Enum() *ErrorClass {
+	p := new(ErrorClass)
+	*p = x
+	return p
+}
+
+func (x ErrorClass) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorClass) Descriptor() protoreflect.EnumDescriptor {
+	return file_task_proto_enumTypes[2].Descriptor()
+}
+
+func (ErrorClass) Type() protoreflect.EnumType {
+	return &file_task_proto_enumTypes[2]
+}
+
+func (x ErrorClass) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorClass.Descriptor instead.
+func (ErrorClass) EnumDescriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{2}
+}
+
+// AttemptOutcome is how one attempt at a task ended.
+type AttemptOutcome int32
+
+const (
+	AttemptOutcome_ATTEMPT_OUTCOME_UNSPECIFIED   AttemptOutcome = 0
+	AttemptOutcome_ATTEMPT_OUTCOME_SUCCEEDED     AttemptOutcome = 1
+	AttemptOutcome_ATTEMPT_OUTCOME_FAILED        AttemptOutcome = 2 // reported by the worker
+	AttemptOutcome_ATTEMPT_OUTCOME_LEASE_EXPIRED AttemptOutcome = 3
+	AttemptOutcome_ATTEMPT_OUTCOME_WORKER_LOST   AttemptOutcome = 4 // the worker went offline or was revoked
+	AttemptOutcome_ATTEMPT_OUTCOME_INTERRUPTED   AttemptOutcome = 5 // stopped by the control plane, e.g. a reduce whose input was lost
+	AttemptOutcome_ATTEMPT_OUTCOME_CANCELLED     AttemptOutcome = 6
+	AttemptOutcome_ATTEMPT_OUTCOME_SUPERSEDED    AttemptOutcome = 7 // another attempt of the task won
+	AttemptOutcome_ATTEMPT_OUTCOME_TIMED_OUT     AttemptOutcome = 8 // ran past the task's deadline and was killed
+)
+
+// Enum value maps for AttemptOutcome.
+var (
+	AttemptOutcome_name = map[int32]string{
+		0: "ATTEMPT_OUTCOME_UNSPECIFIED",
+		1: "ATTEMPT_OUTCOME_SUCCEEDED",
+		2: "ATTEMPT_OUTCOME_FAILED",
+		3: "ATTEMPT_OUTCOME_LEASE_EXPIRED",
+		4: "ATTEMPT_OUTCOME_WORKER_LOST",
+		5: "ATTEMPT_OUTCOME_INTERRUPTED",
+		6: "ATTEMPT_OUTCOME_CANCELLED",
+		7: "ATTEMPT_OUTCOME_SUPERSEDED",
+		8: "ATTEMPT_OUTCOME_TIMED_OUT",
+	}
+	AttemptOutcome_value = map[string]int32{
+		"ATTEMPT_OUTCOME_UNSPECIFIED":   0,
+		"ATTEMPT_OUTCOME_SUCCEEDED":     1,
+		"ATTEMPT_OUTCOME_FAILED":        2,
+		"ATTEMPT_OUTCOME_LEASE_EXPIRED": 3,
+		"ATTEMPT_OUTCOME_WORKER_LOST":   4,
+		"ATTEMPT_OUTCOME_INTERRUPTED":   5,
+		"ATTEMPT_OUTCOME_CANCELLED":     6,
+		"ATTEMPT_OUTCOME_SUPERSEDED":    7,
+		"ATTEMPT_OUTCOME_TIMED_OUT":     8,
+	}
+)
+
+func (x AttemptOutcome) Enum() *AttemptOutcome {
+	p := new(AttemptOutcome)
+	*p = x
+	return p
+}
+
+func (x AttemptOutcome) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AttemptOutcome) Descriptor() protoreflect.EnumDescriptor {
+	return file_task_proto_enumTypes[3].Descriptor()
+}
+
+func (AttemptOutcome) Type() protoreflect.EnumType {
+	return &file_task_proto_enumTypes[3]
+}
+
+func (x AttemptOutcome) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AttemptOutcome.Descriptor instead.
+func (AttemptOutcome) EnumDescriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{3}
+}
+
+type JobState int32
+
+const (
+	JobState_JOB_STATE_UNSPECIFIED JobState = 0
+	JobState_JOB_STATE_BLOCKED     JobState = 1 // stages only: every unfinished task is blocked
+	JobState_JOB_STATE_RUNNING     JobState = 2
+	JobState_JOB_STATE_SUCCEEDED   JobState = 3
+	JobState_JOB_STATE_FAILED      JobState = 4 // at least one task failed or was cancelled
+	JobState_JOB_STATE_CANCELLED   JobState = 5 // cancelled with CancelJob
+)
+
+// Enum value maps for JobState.
+var (
+	JobState_name = map[int32]string{
+		0: "JOB_STATE_UNSPECIFIED",
+		1: "JOB_STATE_BLOCKED",
+		2: "JOB_STATE_RUNNING",
+		3: "JOB_STATE_SUCCEEDED",
+		4: "JOB_STATE_FAILED",
+		5: "JOB_STATE_CANCELLED",
+	}
+	JobState_value = map[string]int32{
+		"JOB_STATE_UNSPECIFIED": 0,
+		"JOB_STATE_BLOCKED":     1,
+		"JOB_STATE_RUNNING":     2,
+		"JOB_STATE_SUCCEEDED":   3,
+		"JOB_STATE_FAILED":      4,
+		"JOB_STATE_CANCELLED":   5,
+	}
+)
+
+func (x JobState) Enum() *JobState {
+	p := new(JobState)
+	*p = x
+	return p
+}
+
+func (x JobState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JobState) Descriptor() protoreflect.EnumDescriptor {
+	return file_task_proto_enumTypes[4].Descriptor()
+}
+
+func (JobState) Type() protoreflect.EnumType {
+	return &file_task_proto_enumTypes[4]
+}
+
+func (x JobState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JobState.Descriptor instead.
+func (JobState) EnumDescriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{4}
+}
+
+type JobType int32
+
+const (
+	JobType_JOB_TYPE_UNSPECIFIED JobType = 0
+	JobType_JOB_TYPE_DAG         JobType = 1
+	JobType_JOB_TYPE_MAPREDUCE   JobType = 2
+)
+
+// Enum value maps for JobType.
+var (
+	JobType_name = map[int32]string{
+		0: "JOB_TYPE_UNSPECIFIED",
+		1: "JOB_TYPE_DAG",
+		2: "JOB_TYPE_MAPREDUCE",
+	}
+	JobType_value = map[string]int32{
+		"JOB_TYPE_UNSPECIFIED": 0,
+		"JOB_TYPE_DAG":         1,
+		"JOB_TYPE_MAPREDUCE":   2,
+	}
+)
+
+func (x JobType) Enum() *JobType {
+	p := new(JobType)
+	*p = x
+	return p
+}
+
+func (x JobType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JobType) Descriptor() protoreflect.EnumDescriptor {
+	return file_task_proto_enumTypes[5].Descriptor()
+}
+
+func (JobType) Type() protoreflect.EnumType {
+	return &file_task_proto_enumTypes[5]
+}
+
+func (x JobType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JobType.Descriptor instead.
+func (JobType) EnumDescriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{5}
+}
+
+type JobFailurePolicy int32
+
+const (
+	JobFailurePolicy_JOB_FAILURE_POLICY_UNSPECIFIED JobFailurePolicy = 0 // fail-fast
+	// A failed or cancelled task cancels every unfinished task of the job.
+	JobFailurePolicy_JOB_FAILURE_POLICY_FAIL_FAST JobFailurePolicy = 1
+	// A failed or cancelled task cancels only the tasks downstream of it;
+	// independent branches run to completion. The job still ends failed.
+	JobFailurePolicy_JOB_FAILURE_POLICY_CONTINUE JobFailurePolicy = 2
+)
+
+// Enum value maps for JobFailurePolicy.
+var (
+	JobFailurePolicy_name = map[int32]string{
+		0: "JOB_FAILURE_POLICY_UNSPECIFIED",
+		1: "JOB_FAILURE_POLICY_FAIL_FAST",
+		2: "JOB_FAILURE_POLICY_CONTINUE",
+	}
+	JobFailurePolicy_value = map[string]int32{
+		"JOB_FAILURE_POLICY_UNSPECIFIED": 0,
+		"JOB_FAILURE_POLICY_FAIL_FAST":   1,
+		"JOB_FAILURE_POLICY_CONTINUE":    2,
+	}
+)
+
+func (x JobFailurePolicy) Enum() *JobFailurePolicy {
+	p := new(JobFailurePolicy)
+	*p = x
+	return p
+}
+
+func (x JobFailurePolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JobFailurePolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_task_proto_enumTypes[6].Descriptor()
+}
+
+func (JobFailurePolicy) Type() protoreflect.EnumType {
+	return &file_task_proto_enumTypes[6]
+}
+
+func (x JobFailurePolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JobFailurePolicy.Descriptor instead.
+func (JobFailurePolicy) EnumDescriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{6}
+}
+
+// Task is one unit of work. Timestamps are Unix milliseconds; 0 means unset.
+type Task struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	TaskId           string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	JobId            string                 `protobuf:"bytes,2,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Type             TaskType               `protobuf:"varint,3,opt,name=type,proto3,enum=task.TaskType" json:"type,omitempty"`
+	InputUris        []string               `protobuf:"bytes,4,rep,name=input_uris,json=inputUris,proto3" json:"input_uris,omitempty"` // e.g. "s3://bucket/key"
+	OutputUri        string                 `protobuf:"bytes,5,opt,name=output_uri,json=outputUri,proto3" json:"output_uri,omitempty"`
+	Attempt          uint32                 `protobuf:"varint,6,opt,name=attempt,proto3" json:"attempt,omitempty"` // 1 for the first run
+	State            TaskState              `protobuf:"varint,7,opt,name=state,proto3,enum=task.TaskState" json:"state,omitempty"`
+	AssignedWorker   string                 `protobuf:"bytes,8,opt,name=assigned_worker,json=assignedWorker,proto3" json:"assigned_worker,omitempty"`
+	Error            string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"` // why the task failed or was cancelled
+	CreatedAtMs      int64                  `protobuf:"varint,10,opt,name=created_at_ms,json=createdAtMs,proto3" json:"created_at_ms,omitempty"`
+	StartedAtMs      int64                  `protobuf:"varint,11,opt,name=started_at_ms,json=startedAtMs,proto3" json:"started_at_ms,omitempty"`
+	FinishedAtMs     int64                  `protobuf:"varint,12,opt,name=finished_at_ms,json=finishedAtMs,proto3" json:"finished_at_ms,omitempty"`
+	LeaseExpiresAtMs int64                  `protobuf:"varint,13,opt,name=lease_expires_at_ms,json=leaseExpiresAtMs,proto3" json:"lease_expires_at_ms,omitempty"` // running tasks only
+	Placement        string                 `protobuf:"bytes,14,opt,name=placement,proto3" json:"placement,omitempty"`                                            // placement policy; empty: first worker to poll
+	CloudAffinity    string                 `protobuf:"bytes,15,opt,name=cloud_affinity,json=cloudAffinity,proto3" json:"cloud_affinity,omitempty"`               // preferred worker cloud_tag
+	CpuMillis        int64                  `protobuf:"varint,16,opt,name=cpu_millis,json=cpuMillis,proto3" json:"cpu_millis,omitempty"`                          // declared requirements
+	MemoryMb         int64                  `protobuf:"varint,17,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"`
+	PlacementReason  string                 `protobuf:"bytes,18,opt,name=placement_reason,json=placementReason,proto3" json:"placement_reason,omitempty"` // why the scheduler chose assigned_worker
+	InputBytes       []int64                `protobuf:"varint,19,rep,packed,name=input_bytes,json=inputBytes,proto3" json:"input_bytes,omitempty"`        // declared size of each input_uris entry
+	EgressBytes      int64                  `protobuf:"varint,20,opt,name=egress_bytes,json=egressBytes,proto3" json:"egress_bytes,omitempty"`            // estimated cross-cloud input bytes, summed over attempts
+	EgressCost       float64                `protobuf:"fixed64,21,opt,name=egress_cost,json=egressCost,proto3" json:"egress_cost,omitempty"`              // estimated cost of egress_bytes
+	Stage            string                 `protobuf:"bytes,22,opt,name=stage,proto3" json:"stage,omitempty"`                                            // job tasks only
+	DependsOn        []string               `protobuf:"bytes,23,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`                   // task IDs that must succeed first
+	Partitions       uint32                 `protobuf:"varint,24,opt,name=partitions,proto3" json:"partitions,omitempty"`                                 // MapReduce map tasks: partitions to write
+	Partition        uint32                 `protobuf:"varint,25,opt,name=partition,proto3" json:"partition,omitempty"`                                   // MapReduce reduce tasks: partition to read
+	Retry            *RetryPolicy           `protobuf:"bytes,26,opt,name=retry,proto3" json:"retry,omitempty"`
+	Attempts         []*TaskAttempt         `protobuf:"bytes,27,rep,name=attempts,proto3" json:"attempts,omitempty"`                                 // finished attempts, oldest first
+	Failures         uint32                 `protobuf:"varint,28,opt,name=failures,proto3" json:"failures,omitempty"`                                // attempts that counted against retry.max_attempts
+	NotBeforeMs      int64                  `protobuf:"varint,29,opt,name=not_before_ms,json=notBeforeMs,proto3" json:"not_before_ms,omitempty"`     // pending tasks: backing off until then
+	Speculative      *SpeculativeAttempt    `protobuf:"bytes,30,opt,name=speculative,proto3" json:"speculative,omitempty"`                           // running duplicate of a straggling attempt
+	CommitAttempt    uint32                 `protobuf:"varint,31,opt,name=commit_attempt,json=commitAttempt,proto3" json:"commit_attempt,omitempty"` // attempt granted the output commit; 0: none yet
+	TimeoutMs        uint32                 `protobuf:"varint,32,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`             // execution deadline of each attempt; 0: none
+	KillGraceMs      uint32                 `protobuf:"varint,33,opt,name=kill_grace_ms,json=killGraceMs,proto3" json:"kill_grace_ms,omitempty"`     // how long a killed attempt has to stop
+	Kills            []*KillOrder           `protobuf:"bytes,34,rep,name=kills,proto3" json:"kills,omitempty"`                                       // attempts told to stop whose grace has not run out
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Task) Reset() {
+	*x = Task{}
+	mi := &file_task_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Task) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Task) ProtoMessage() {}
+
+func (x *Task) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Task.ProtoReflect.Descriptor instead.
+func (*Task) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Task) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *Task) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *Task) GetType() TaskType {
+	if x != nil {
+		return x.Type
+	}
+	return TaskType_TASK_TYPE_UNSPECIFIED
+}
+
+func (x *Task) GetInputUris() []string {
+	if x != nil {
+		return x.InputUris
+	}
+	return nil
+}
+
+func (x *Task) GetOutputUri() string {
+	if x != nil {
+		return x.OutputUri
+	}
+	return ""
+}
+
+func (x *Task) GetAttempt() uint32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *Task) GetState() TaskState {
+	if x != nil {
+		return x.State
+	}
+	return TaskState_TASK_STATE_UNSPECIFIED
+}
+
+func (x *Task) GetAssignedWorker() string {
+	if x != nil {
+		return x.AssignedWorker
+	}
+	return ""
+}
+
+func (x *Task) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Task) GetCreatedAtMs() int64 {
+	if x != nil {
+		return x.CreatedAtMs
+	}
+	return 0
+}
+
+func (x *Task) GetStartedAtMs() int64 {
+	if x != nil {
+		return x.StartedAtMs
+	}
+	return 0
+}
+
+func (x *Task) GetFinishedAtMs() int64 {
+	if x != nil {
+		return x.FinishedAtMs
+	}
+	return 0
+}
+
+func (x *Task) GetLeaseExpiresAtMs() int64 {
+	if x != nil {
+		return x.LeaseExpiresAtMs
+	}
+	return 0
+}
+
+func (x *Task) GetPlacement() string {
+	if x != nil {
+		return x.Placement
+	}
+	return ""
+}
+
+func (x *Task) GetCloudAffinity() string {
+	if x != nil {
+		return x.CloudAffinity
+	}
+	return ""
+}
+
+func (x *Task) GetCpuMillis() int64 {
+	if x != nil {
+		return x.CpuMillis
+	}
+	return 0
+}
+
+func (x *Task) GetMemoryMb() int64 {
+	if x != nil {
+		return x.MemoryMb
+	}
+	return 0
+}
+
+func (x *Task) GetPlacementReason() string {
+	if x != nil {
+		return x.PlacementReason
+	}
+	return ""
+}
+
+func (x *Task) GetInputBytes() []int64 {
+	if x != nil {
+		return x.InputBytes
+	}
+	return nil
+}
+
+func (x *Task) GetEgressBytes() int64 {
+	if x != nil {
+		return x.EgressBytes
+	}
+	return 0
+}
+
+func (x *Task) GetEgressCost() float64 {
+	if x != nil {
+		return x.EgressCost
+	}
+	return 0
+}
+
+func (x *Task) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *Task) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *Task) GetPartitions() uint32 {
+	if x != nil {
+		return x.Partitions
+	}
+	return 0
+}
+
+func (x *Task) GetPartition() uint32 {
+	if x != nil {
+		return x.Partition
+	}
+	return 0
+}
+
+func (x *Task) GetRetry() *RetryPolicy {
+	if x != nil {
+		return x.Retry
+	}
+	return nil
+}
+
+func (x *Task) GetAttempts() []*TaskAttempt {
+	if x != nil {
+		return x.Attempts
+	}
+	return nil
+}
+
+func (x *Task) GetFailures() uint32 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
+}
+
+func (x *Task) GetNotBeforeMs() int64 {
+	if x != nil {
+		return x.NotBeforeMs
+	}
+	return 0
+}
+
+func (x *Task) GetSpeculative() *SpeculativeAttempt {
+	if x != nil {
+		return x.Speculative
+	}
+	return nil
+}
+
+func (x *Task) GetCommitAttempt() uint32 {
+	if x != nil {
+		return x.CommitAttempt
+	}
+	return 0
+}
+
+func (x *Task) GetTimeoutMs() uint32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+func (x *Task) GetKillGraceMs() uint32 {
+	if x != nil {
+		return x.KillGraceMs
+	}
+	return 0
+}
+
+func (x *Task) GetKills() []*KillOrder {
+	if x != nil {
+		return x.Kills
+	}
+	return nil
+}
+
+// KillOrder tells the worker running one attempt of a task to stop it before
+// grace_until_ms, after which the leader revokes its lease.
+type KillOrder struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Attempt       uint32                 `protobuf:"varint,2,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	Worker        string                 `protobuf:"bytes,3,opt,name=worker,proto3" json:"worker,omitempty"`
+	Reason        string                 `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	RequestedAtMs int64                  `protobuf:"varint,5,opt,name=requested_at_ms,json=requestedAtMs,proto3" json:"requested_at_ms,omitempty"`
+	GraceUntilMs  int64                  `protobuf:"varint,6,opt,name=grace_until_ms,json=graceUntilMs,proto3" json:"grace_until_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KillOrder) Reset() {
+	*x = KillOrder{}
+	mi := &file_task_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KillOrder) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KillOrder) ProtoMessage() {}
+
+func (x *KillOrder) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KillOrder.ProtoReflect.Descriptor instead.
+func (*KillOrder) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *KillOrder) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *KillOrder) GetAttempt() uint32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *KillOrder) GetWorker() string {
+	if x != nil {
+		return x.Worker
+	}
+	return ""
+}
+
+func (x *KillOrder) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *KillOrder) GetRequestedAtMs() int64 {
+	if x != nil {
+		return x.RequestedAtMs
+	}
+	return 0
+}
+
+func (x *KillOrder) GetGraceUntilMs() int64 {
+	if x != nil {
+		return x.GraceUntilMs
+	}
+	return 0
+}
+
+// SpeculativeAttempt is a second attempt running alongside a task's attempt
+// on another worker. A worker receives it as a Task whose attempt and
+// assigned_worker are the duplicate's.
+type SpeculativeAttempt struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Attempt          uint32                 `protobuf:"varint,1,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	Worker           string                 `protobuf:"bytes,2,opt,name=worker,proto3" json:"worker,omitempty"`
+	StartedAtMs      int64                  `protobuf:"varint,3,opt,name=started_at_ms,json=startedAtMs,proto3" json:"started_at_ms,omitempty"`
+	LeaseExpiresAtMs int64                  `protobuf:"varint,4,opt,name=lease_expires_at_ms,json=leaseExpiresAtMs,proto3" json:"lease_expires_at_ms,omitempty"`
+	Reason           string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"` // why the duplicate was launched
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *SpeculativeAttempt) Reset() {
+	*x = SpeculativeAttempt{}
+	mi := &file_task_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SpeculativeAttempt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SpeculativeAttempt) ProtoMessage() {}
+
+func (x *SpeculativeAttempt) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SpeculativeAttempt.ProtoReflect.Descriptor instead.
+func (*SpeculativeAttempt) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SpeculativeAttempt) GetAttempt() uint32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *SpeculativeAttempt) GetWorker() string {
+	if x != nil {
+		return x.Worker
+	}
+	return ""
+}
+
+func (x *SpeculativeAttempt) GetStartedAtMs() int64 {
+	if x != nil {
+		return x.StartedAtMs
+	}
+	return 0
+}
+
+func (x *SpeculativeAttempt) GetLeaseExpiresAtMs() int64 {
+	if x != nil {
+		return x.LeaseExpiresAtMs
+	}
+	return 0
+}
+
+func (x *SpeculativeAttempt) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// RetryPolicy bounds how often a task is retried and how long it waits in
+// between: the n-th retry waits initial_backoff_ms × multiplier^(n-1), capped
+// at max_backoff_ms, of which a jitter share is randomised. In a submission,
+// zero fields take the cluster default.
+type RetryPolicy struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	MaxAttempts      uint32                 `protobuf:"varint,1,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"` // attempts in total; 1 disables retries
+	InitialBackoffMs uint32                 `protobuf:"varint,2,opt,name=initial_backoff_ms,json=initialBackoffMs,proto3" json:"initial_backoff_ms,omitempty"`
+	MaxBackoffMs     uint32                 `protobuf:"varint,3,opt,name=max_backoff_ms,json=maxBackoffMs,proto3" json:"max_backoff_ms,omitempty"`
+	Multiplier       float64                `protobuf:"fixed64,4,opt,name=multiplier,proto3" json:"multiplier,omitempty"`
+	Jitter           float64                `protobuf:"fixed64,5,opt,name=jitter,proto3" json:"jitter,omitempty"` // 0–1
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *RetryPolicy) Reset() {
+	*x = RetryPolicy{}
+	mi := &file_task_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetryPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetryPolicy) ProtoMessage() {}
+
+func (x *RetryPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetryPolicy.ProtoReflect.Descriptor instead.
+func (*RetryPolicy) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RetryPolicy) GetMaxAttempts() uint32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetInitialBackoffMs() uint32 {
+	if x != nil {
+		return x.InitialBackoffMs
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetMaxBackoffMs() uint32 {
+	if x != nil {
+		return x.MaxBackoffMs
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetMultiplier() float64 {
+	if x != nil {
+		return x.Multiplier
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetJitter() float64 {
+	if x != nil {
+		return x.Jitter
+	}
+	return 0
+}
+
+// TaskAttempt is one finished run of a task.
+type TaskAttempt struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Attempt       uint32                 `protobuf:"varint,1,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	Worker        string                 `protobuf:"bytes,2,opt,name=worker,proto3" json:"worker,omitempty"`
+	StartedAtMs   int64                  `protobuf:"varint,3,opt,name=started_at_ms,json=startedAtMs,proto3" json:"started_at_ms,omitempty"`
+	FinishedAtMs  int64                  `protobuf:"varint,4,opt,name=finished_at_ms,json=finishedAtMs,proto3" json:"finished_at_ms,omitempty"`
+	Outcome       AttemptOutcome         `protobuf:"varint,5,opt,name=outcome,proto3,enum=task.AttemptOutcome" json:"outcome,omitempty"`
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	ErrorClass    ErrorClass             `protobuf:"varint,7,opt,name=error_class,json=errorClass,proto3,enum=task.ErrorClass" json:"error_class,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskAttempt) Reset() {
+	*x = TaskAttempt{}
+	mi := &file_task_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskAttempt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskAttempt) ProtoMessage() {}
+
+func (x *TaskAttempt) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskAttempt.ProtoReflect.Descriptor instead.
+func (*TaskAttempt) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TaskAttempt) GetAttempt() uint32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *TaskAttempt) GetWorker() string {
+	if x != nil {
+		return x.Worker
+	}
+	return ""
+}
+
+func (x *TaskAttempt) GetStartedAtMs() int64 {
+	if x != nil {
+		return x.StartedAtMs
+	}
+	return 0
+}
+
+func (x *TaskAttempt) GetFinishedAtMs() int64 {
+	if x != nil {
+		return x.FinishedAtMs
+	}
+	return 0
+}
+
+func (x *TaskAttempt) GetOutcome() AttemptOutcome {
+	if x != nil {
+		return x.Outcome
+	}
+	return AttemptOutcome_ATTEMPT_OUTCOME_UNSPECIFIED
+}
+
+func (x *TaskAttempt) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *TaskAttempt) GetErrorClass() ErrorClass {
+	if x != nil {
+		return x.ErrorClass
+	}
+	return ErrorClass_ERROR_CLASS_UNSPECIFIED
+}
+
+// IntermediateOutput is a MapReduce map task's output for one partition.
+type IntermediateOutput struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Partition uint32                 `protobuf:"varint,1,opt,name=partition,proto3" json:"partition,omitempty"`
+	Uri       string                 `protobuf:"bytes,2,opt,name=uri,proto3" json:"uri,omitempty"` // empty: no records for this partition
+	Bytes     int64                  `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	// durable marks output stored off the worker (e.g. object storage), which
+	// survives it. Other outputs are lost if the worker goes offline.
+	Durable       bool `protobuf:"varint,4,opt,name=durable,proto3" json:"durable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IntermediateOutput) Reset() {
+	*x = IntermediateOutput{}
+	mi := &file_task_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IntermediateOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IntermediateOutput) ProtoMessage() {}
+
+func (x *IntermediateOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IntermediateOutput.ProtoReflect.Descriptor instead.
+func (*IntermediateOutput) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *IntermediateOutput) GetPartition() uint32 {
+	if x != nil {
+		return x.Partition
+	}
+	return 0
+}
+
+func (x *IntermediateOutput) GetUri() string {
+	if x != nil {
+		return x.Uri
+	}
+	return ""
+}
+
+func (x *IntermediateOutput) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *IntermediateOutput) GetDurable() bool {
+	if x != nil {
+		return x.Durable
+	}
+	return false
+}
+
+// SubmitTaskRequest creates a pending task. task_id is generated when empty.
+type SubmitTaskRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	TaskId    string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	JobId     string                 `protobuf:"bytes,2,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Type      TaskType               `protobuf:"varint,3,opt,name=type,proto3,enum=task.TaskType" json:"type,omitempty"`
+	InputUris []string               `protobuf:"bytes,4,rep,name=input_uris,json=inputUris,proto3" json:"input_uris,omitempty"`
+	OutputUri string                 `protobuf:"bytes,5,opt,name=output_uri,json=outputUri,proto3" json:"output_uri,omitempty"`
+	// placement selects how the leader pushes the task to a worker:
+	// "round-robin", "least-loaded", "bin-pack", "cloud-affinity" or
+	// "locality". Empty leaves it for whichever worker polls first.
+	Placement     string `protobuf:"bytes,6,opt,name=placement,proto3" json:"placement,omitempty"`
+	CloudAffinity string `protobuf:"bytes,7,opt,name=cloud_affinity,json=cloudAffinity,proto3" json:"cloud_affinity,omitempty"`
+	CpuMillis     int64  `protobuf:"varint,8,opt,name=cpu_millis,json=cpuMillis,proto3" json:"cpu_millis,omitempty"`
+	MemoryMb      int64  `protobuf:"varint,9,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"`
+	// input_bytes, if set, gives the size of each input_uris entry; the
+	// "locality" placement policy and egress estimates use it.
+	InputBytes []int64 `protobuf:"varint,10,rep,packed,name=input_bytes,json=inputBytes,proto3" json:"input_bytes,omitempty"`
+	// depends_on lists task IDs in the same job that must succeed before this
+	// task runs. Only valid inside SubmitJobRequest, where task_id is required
+	// for any task another one names.
+	DependsOn []string     `protobuf:"bytes,11,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
+	Retry     *RetryPolicy `protobuf:"bytes,12,opt,name=retry,proto3" json:"retry,omitempty"`
+	// timeout_ms bounds how long each attempt may run; kill_grace_ms is how
+	// long a killed attempt has to stop. Zero takes the cluster default.
+	TimeoutMs     uint32 `protobuf:"varint,13,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	KillGraceMs   uint32 `protobuf:"varint,14,opt,name=kill_grace_ms,json=killGraceMs,proto3" json:"kill_grace_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitTaskRequest) Reset() {
+	*x = SubmitTaskRequest{}
+	mi := &file_task_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTaskRequest) ProtoMessage() {}
+
+func (x *SubmitTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTaskRequest.ProtoReflect.Descriptor instead.
+func (*SubmitTaskRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SubmitTaskRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *SubmitTaskRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *SubmitTaskRequest) GetType() TaskType {
+	if x != nil {
+		return x.Type
+	}
+	return TaskType_TASK_TYPE_UNSPECIFIED
+}
+
+func (x *SubmitTaskRequest) GetInputUris() []string {
+	if x != nil {
+		return x.InputUris
+	}
+	return nil
+}
+
+func (x *SubmitTaskRequest) GetOutputUri() string {
+	if x != nil {
+		return x.OutputUri
+	}
+	return ""
+}
+
+func (x *SubmitTaskRequest) GetPlacement() string {
+	if x != nil {
+		return x.Placement
+	}
+	return ""
+}
+
+func (x *SubmitTaskRequest) GetCloudAffinity() string {
+	if x != nil {
+		return x.CloudAffinity
+	}
+	return ""
+}
+
+func (x *SubmitTaskRequest) GetCpuMillis() int64 {
+	if x != nil {
+		return x.CpuMillis
+	}
+	return 0
+}
+
+func (x *SubmitTaskRequest) GetMemoryMb() int64 {
+	if x != nil {
+		return x.MemoryMb
+	}
+	return 0
+}
+
+func (x *SubmitTaskRequest) GetInputBytes() []int64 {
+	if x != nil {
+		return x.InputBytes
+	}
+	return nil
+}
+
+func (x *SubmitTaskRequest) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *SubmitTaskRequest) GetRetry() *RetryPolicy {
+	if x != nil {
+		return x.Retry
+	}
+	return nil
+}
+
+func (x *SubmitTaskRequest) GetTimeoutMs() uint32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+func (x *SubmitTaskRequest) GetKillGraceMs() uint32 {
+	if x != nil {
+		return x.KillGraceMs
+	}
+	return 0
+}
+
+type SubmitTaskResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	LeaderAddr    string                 `protobuf:"bytes,2,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"` // non-empty: this node is a follower — retry against this gRPC address
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Task          *Task                  `protobuf:"bytes,4,opt,name=task,proto3" json:"task,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitTaskResponse) Reset() {
+	*x = SubmitTaskResponse{}
+	mi := &file_task_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTaskResponse) ProtoMessage() {}
+
+func (x *SubmitTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTaskResponse.ProtoReflect.Descriptor instead.
+func (*SubmitTaskResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SubmitTaskResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *SubmitTaskResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *SubmitTaskResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SubmitTaskResponse) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+type GetTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTaskRequest) Reset() {
+	*x = GetTaskRequest{}
+	mi := &file_task_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTaskRequest) ProtoMessage() {}
+
+func (x *GetTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTaskRequest.ProtoReflect.Descriptor instead.
+func (*GetTaskRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetTaskRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+type GetTaskResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Task          *Task                  `protobuf:"bytes,3,opt,name=task,proto3" json:"task,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTaskResponse) Reset() {
+	*x = GetTaskResponse{}
+	mi := &file_task_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTaskResponse) ProtoMessage() {}
+
+func (x *GetTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTaskResponse.ProtoReflect.Descriptor instead.
+func (*GetTaskResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetTaskResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *GetTaskResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *GetTaskResponse) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+// ListTasksRequest filters by job and/or state; empty fields match everything.
+type ListTasksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	State         TaskState              `protobuf:"varint,2,opt,name=state,proto3,enum=task.TaskState" json:"state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTasksRequest) Reset() {
+	*x = ListTasksRequest{}
+	mi := &file_task_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTasksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTasksRequest) ProtoMessage() {}
+
+func (x *ListTasksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTasksRequest.ProtoReflect.Descriptor instead.
+func (*ListTasksRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListTasksRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *ListTasksRequest) GetState() TaskState {
+	if x != nil {
+		return x.State
+	}
+	return TaskState_TASK_STATE_UNSPECIFIED
+}
+
+type ListTasksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tasks         []*Task                `protobuf:"bytes,1,rep,name=tasks,proto3" json:"tasks,omitempty"` // ordered by task_id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTasksResponse) Reset() {
+	*x = ListTasksResponse{}
+	mi := &file_task_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTasksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTasksResponse) ProtoMessage() {}
+
+func (x *ListTasksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTasksResponse.ProtoReflect.Descriptor instead.
+func (*ListTasksResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListTasksResponse) GetTasks() []*Task {
+	if x != nil {
+		return x.Tasks
+	}
+	return nil
+}
+
+// CancelTaskRequest cancels a task that has not finished yet. The workers of
+// its running attempts are told to stop.
+type CancelTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelTaskRequest) Reset() {
+	*x = CancelTaskRequest{}
+	mi := &file_task_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelTaskRequest) ProtoMessage() {}
+
+func (x *CancelTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelTaskRequest.ProtoReflect.Descriptor instead.
+func (*CancelTaskRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CancelTaskRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *CancelTaskRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type CancelTaskResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	LeaderAddr    string                 `protobuf:"bytes,2,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Task          *Task                  `protobuf:"bytes,4,opt,name=task,proto3" json:"task,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelTaskResponse) Reset() {
+	*x = CancelTaskResponse{}
+	mi := &file_task_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelTaskResponse) ProtoMessage() {}
+
+func (x *CancelTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelTaskResponse.ProtoReflect.Descriptor instead.
+func (*CancelTaskResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CancelTaskResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *CancelTaskResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *CancelTaskResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CancelTaskResponse) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+// GetJobEgressRequest asks for the estimated cross-cloud traffic of a job's
+// tasks, from the local FSM.
+type GetJobEgressRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobEgressRequest) Reset() {
+	*x = GetJobEgressRequest{}
+	mi := &file_task_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobEgressRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobEgressRequest) ProtoMessage() {}
+
+func (x *GetJobEgressRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobEgressRequest.ProtoReflect.Descriptor instead.
+func (*GetJobEgressRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetJobEgressRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type GetJobEgressResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Tasks         uint32                 `protobuf:"varint,3,opt,name=tasks,proto3" json:"tasks,omitempty"` // tasks of the job still held by the FSM
+	EgressBytes   int64                  `protobuf:"varint,4,opt,name=egress_bytes,json=egressBytes,proto3" json:"egress_bytes,omitempty"`
+	EgressCost    float64                `protobuf:"fixed64,5,opt,name=egress_cost,json=egressCost,proto3" json:"egress_cost,omitempty"`
+	ByTask        []*Task                `protobuf:"bytes,6,rep,name=by_task,json=byTask,proto3" json:"by_task,omitempty"` // tasks with non-zero egress, ordered by task_id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobEgressResponse) Reset() {
+	*x = GetJobEgressResponse{}
+	mi := &file_task_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobEgressResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobEgressResponse) ProtoMessage() {}
+
+func (x *GetJobEgressResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobEgressResponse.ProtoReflect.Descriptor instead.
+func (*GetJobEgressResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetJobEgressResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *GetJobEgressResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *GetJobEgressResponse) GetTasks() uint32 {
+	if x != nil {
+		return x.Tasks
+	}
+	return 0
+}
+
+func (x *GetJobEgressResponse) GetEgressBytes() int64 {
+	if x != nil {
+		return x.EgressBytes
+	}
+	return 0
+}
+
+func (x *GetJobEgressResponse) GetEgressCost() float64 {
+	if x != nil {
+		return x.EgressCost
+	}
+	return 0
+}
+
+func (x *GetJobEgressResponse) GetByTask() []*Task {
+	if x != nil {
+		return x.ByTask
+	}
+	return nil
+}
+
+// StageSpec is one stage of a job. Every task in it depends on every task of
+// the stages named in depends_on, on top of its own depends_on.
+type StageSpec struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	DependsOn     []string               `protobuf:"bytes,2,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"` // stage names
+	Tasks         []*SubmitTaskRequest   `protobuf:"bytes,3,rep,name=tasks,proto3" json:"tasks,omitempty"`                          // job_id is ignored
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StageSpec) Reset() {
+	*x = StageSpec{}
+	mi := &file_task_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageSpec) ProtoMessage() {}
+
+func (x *StageSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StageSpec.ProtoReflect.Descriptor instead.
+func (*StageSpec) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *StageSpec) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *StageSpec) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *StageSpec) GetTasks() []*SubmitTaskRequest {
+	if x != nil {
+		return x.Tasks
+	}
+	return nil
+}
+
+// SubmitJobRequest creates a job and all its tasks at once. job_id is
+// generated when empty. Unknown dependencies and cycles are rejected.
+type SubmitJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	FailurePolicy JobFailurePolicy       `protobuf:"varint,2,opt,name=failure_policy,json=failurePolicy,proto3,enum=task.JobFailurePolicy" json:"failure_policy,omitempty"`
+	Stages        []*StageSpec           `protobuf:"bytes,3,rep,name=stages,proto3" json:"stages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitJobRequest) Reset() {
+	*x = SubmitJobRequest{}
+	mi := &file_task_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitJobRequest) ProtoMessage() {}
+
+func (x *SubmitJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitJobRequest.ProtoReflect.Descriptor instead.
+func (*SubmitJobRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SubmitJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetFailurePolicy() JobFailurePolicy {
+	if x != nil {
+		return x.FailurePolicy
+	}
+	return JobFailurePolicy_JOB_FAILURE_POLICY_UNSPECIFIED
+}
+
+func (x *SubmitJobRequest) GetStages() []*StageSpec {
+	if x != nil {
+		return x.Stages
+	}
+	return nil
+}
+
+type SubmitJobResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	LeaderAddr    string                 `protobuf:"bytes,2,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Job           *Job                   `protobuf:"bytes,4,opt,name=job,proto3" json:"job,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitJobResponse) Reset() {
+	*x = SubmitJobResponse{}
+	mi := &file_task_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitJobResponse) ProtoMessage() {}
+
+func (x *SubmitJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitJobResponse.ProtoReflect.Descriptor instead.
+func (*SubmitJobResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SubmitJobResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *SubmitJobResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *SubmitJobResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SubmitJobResponse) GetJob() *Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+// SubmitMapReduceRequest creates a MapReduce job: one map task per input
+// split, and partitions reduce tasks as the map outputs come in. job_id is
+// generated when empty; task IDs are "<job_id>-map-<n>" and
+// "<job_id>-reduce-<n>". The placement fields apply to every task.
+type SubmitMapReduceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	InputUris     []string               `protobuf:"bytes,2,rep,name=input_uris,json=inputUris,proto3" json:"input_uris,omitempty"`            // input splits
+	InputBytes    []int64                `protobuf:"varint,3,rep,packed,name=input_bytes,json=inputBytes,proto3" json:"input_bytes,omitempty"` // optional size of each split
+	Partitions    uint32                 `protobuf:"varint,4,opt,name=partitions,proto3" json:"partitions,omitempty"`
+	OutputPrefix  string                 `protobuf:"bytes,5,opt,name=output_prefix,json=outputPrefix,proto3" json:"output_prefix,omitempty"` // reduce n writes output_prefix + "part-<n>"
+	FailurePolicy JobFailurePolicy       `protobuf:"varint,6,opt,name=failure_policy,json=failurePolicy,proto3,enum=task.JobFailurePolicy" json:"failure_policy,omitempty"`
+	Placement     string                 `protobuf:"bytes,7,opt,name=placement,proto3" json:"placement,omitempty"`
+	CloudAffinity string                 `protobuf:"bytes,8,opt,name=cloud_affinity,json=cloudAffinity,proto3" json:"cloud_affinity,omitempty"`
+	CpuMillis     int64                  `protobuf:"varint,9,opt,name=cpu_millis,json=cpuMillis,proto3" json:"cpu_millis,omitempty"`
+	MemoryMb      int64                  `protobuf:"varint,10,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"`
+	// Splits that read several objects each, e.g. a pair of matrix blocks.
+	// Set either input_uris or splits.
+	Splits        []*MapSplitSpec `protobuf:"bytes,11,rep,name=splits,proto3" json:"splits,omitempty"`
+	Retry         *RetryPolicy    `protobuf:"bytes,12,opt,name=retry,proto3" json:"retry,omitempty"`
+	TimeoutMs     uint32          `protobuf:"varint,13,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"` // per attempt, as in SubmitTaskRequest
+	KillGraceMs   uint32          `protobuf:"varint,14,opt,name=kill_grace_ms,json=killGraceMs,proto3" json:"kill_grace_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitMapReduceRequest) Reset() {
+	*x = SubmitMapReduceRequest{}
+	mi := &file_task_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitMapReduceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitMapReduceRequest) ProtoMessage() {}
+
+func (x *SubmitMapReduceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitMapReduceRequest.ProtoReflect.Descriptor instead.
+func (*SubmitMapReduceRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SubmitMapReduceRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *SubmitMapReduceRequest) GetInputUris() []string {
+	if x != nil {
+		return x.InputUris
+	}
+	return nil
+}
+
+func (x *SubmitMapReduceRequest) GetInputBytes() []int64 {
+	if x != nil {
+		return x.InputBytes
+	}
+	return nil
+}
+
+func (x *SubmitMapReduceRequest) GetPartitions() uint32 {
+	if x != nil {
+		return x.Partitions
+	}
+	return 0
+}
+
+func (x *SubmitMapReduceRequest) GetOutputPrefix() string {
+	if x != nil {
+		return x.OutputPrefix
+	}
+	return ""
+}
+
+func (x *SubmitMapReduceRequest) GetFailurePolicy() JobFailurePolicy {
+	if x != nil {
+		return x.FailurePolicy
+	}
+	return JobFailurePolicy_JOB_FAILURE_POLICY_UNSPECIFIED
+}
+
+func (x *SubmitMapReduceRequest) GetPlacement() string {
+	if x != nil {
+		return x.Placement
+	}
+	return ""
+}
+
+func (x *SubmitMapReduceRequest) GetCloudAffinity() string {
+	if x != nil {
+		return x.CloudAffinity
+	}
+	return ""
+}
+
+func (x *SubmitMapReduceRequest) GetCpuMillis() int64 {
+	if x != nil {
+		return x.CpuMillis
+	}
+	return 0
+}
+
+func (x *SubmitMapReduceRequest) GetMemoryMb() int64 {
+	if x != nil {
+		return x.MemoryMb
+	}
+	return 0
+}
+
+func (x *SubmitMapReduceRequest) GetSplits() []*MapSplitSpec {
+	if x != nil {
+		return x.Splits
+	}
+	return nil
+}
+
+func (x *SubmitMapReduceRequest) GetRetry() *RetryPolicy {
+	if x != nil {
+		return x.Retry
+	}
+	return nil
+}
+
+func (x *SubmitMapReduceRequest) GetTimeoutMs() uint32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+func (x *SubmitMapReduceRequest) GetKillGraceMs() uint32 {
+	if x != nil {
+		return x.KillGraceMs
+	}
+	return 0
+}
+
+// MapSplitSpec is the input of one map task.
+type MapSplitSpec struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	InputUris     []string               `protobuf:"bytes,1,rep,name=input_uris,json=inputUris,proto3" json:"input_uris,omitempty"`
+	InputBytes    []int64                `protobuf:"varint,2,rep,packed,name=input_bytes,json=inputBytes,proto3" json:"input_bytes,omitempty"` // optional size of each input
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MapSplitSpec) Reset() {
+	*x = MapSplitSpec{}
+	mi := &file_task_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MapSplitSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MapSplitSpec) ProtoMessage() {}
+
+func (x *MapSplitSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MapSplitSpec.ProtoReflect.Descriptor instead.
+func (*MapSplitSpec) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *MapSplitSpec) GetInputUris() []string {
+	if x != nil {
+		return x.InputUris
+	}
+	return nil
+}
+
+func (x *MapSplitSpec) GetInputBytes() []int64 {
+	if x != nil {
+		return x.InputBytes
+	}
+	return nil
+}
+
+// Stage reports progress of one stage. The finished counts are kept by the
+// FSM; the others are counted from the stage's unfinished tasks. A MapReduce
+// job's "shuffle" stage counts partitions instead: tasks is the number of
+// partitions and succeeded those whose map outputs are all in.
+type Stage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	DependsOn     []string               `protobuf:"bytes,2,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
+	State         JobState               `protobuf:"varint,3,opt,name=state,proto3,enum=task.JobState" json:"state,omitempty"`
+	Tasks         uint32                 `protobuf:"varint,4,opt,name=tasks,proto3" json:"tasks,omitempty"`
+	Blocked       uint32                 `protobuf:"varint,5,opt,name=blocked,proto3" json:"blocked,omitempty"`
+	Pending       uint32                 `protobuf:"varint,6,opt,name=pending,proto3" json:"pending,omitempty"`
+	Running       uint32                 `protobuf:"varint,7,opt,name=running,proto3" json:"running,omitempty"`
+	Succeeded     uint32                 `protobuf:"varint,8,opt,name=succeeded,proto3" json:"succeeded,omitempty"`
+	Failed        uint32                 `protobuf:"varint,9,opt,name=failed,proto3" json:"failed,omitempty"`
+	Cancelled     uint32                 `protobuf:"varint,10,opt,name=cancelled,proto3" json:"cancelled,omitempty"`
+	TaskIds       []string               `protobuf:"bytes,11,rep,name=task_ids,json=taskIds,proto3" json:"task_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Stage) Reset() {
+	*x = Stage{}
+	mi := &file_task_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stage) ProtoMessage() {}
+
+func (x *Stage) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stage.ProtoReflect.Descriptor instead.
+func (*Stage) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Stage) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Stage) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *Stage) GetState() JobState {
+	if x != nil {
+		return x.State
+	}
+	return JobState_JOB_STATE_UNSPECIFIED
+}
+
+func (x *Stage) GetTasks() uint32 {
+	if x != nil {
+		return x.Tasks
+	}
+	return 0
+}
+
+func (x *Stage) GetBlocked() uint32 {
+	if x != nil {
+		return x.Blocked
+	}
+	return 0
+}
+
+func (x *Stage) GetPending() uint32 {
+	if x != nil {
+		return x.Pending
+	}
+	return 0
+}
+
+func (x *Stage) GetRunning() uint32 {
+	if x != nil {
+		return x.Running
+	}
+	return 0
+}
+
+func (x *Stage) GetSucceeded() uint32 {
+	if x != nil {
+		return x.Succeeded
+	}
+	return 0
+}
+
+func (x *Stage) GetFailed() uint32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *Stage) GetCancelled() uint32 {
+	if x != nil {
+		return x.Cancelled
+	}
+	return 0
+}
+
+func (x *Stage) GetTaskIds() []string {
+	if x != nil {
+		return x.TaskIds
+	}
+	return nil
+}
+
+type Job struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	FailurePolicy JobFailurePolicy       `protobuf:"varint,2,opt,name=failure_policy,json=failurePolicy,proto3,enum=task.JobFailurePolicy" json:"failure_policy,omitempty"`
+	State         JobState               `protobuf:"varint,3,opt,name=state,proto3,enum=task.JobState" json:"state,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"` // the first task failure
+	CreatedAtMs   int64                  `protobuf:"varint,5,opt,name=created_at_ms,json=createdAtMs,proto3" json:"created_at_ms,omitempty"`
+	FinishedAtMs  int64                  `protobuf:"varint,6,opt,name=finished_at_ms,json=finishedAtMs,proto3" json:"finished_at_ms,omitempty"`
+	Stages        []*Stage               `protobuf:"bytes,7,rep,name=stages,proto3" json:"stages,omitempty"`
+	Type          JobType                `protobuf:"varint,8,opt,name=type,proto3,enum=task.JobType" json:"type,omitempty"`
+	MapReduce     *MapReduceStatus       `protobuf:"bytes,9,opt,name=map_reduce,json=mapReduce,proto3" json:"map_reduce,omitempty"` // MapReduce jobs only
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_task_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *Job) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *Job) GetFailurePolicy() JobFailurePolicy {
+	if x != nil {
+		return x.FailurePolicy
+	}
+	return JobFailurePolicy_JOB_FAILURE_POLICY_UNSPECIFIED
+}
+
+func (x *Job) GetState() JobState {
+	if x != nil {
+		return x.State
+	}
+	return JobState_JOB_STATE_UNSPECIFIED
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetCreatedAtMs() int64 {
+	if x != nil {
+		return x.CreatedAtMs
+	}
+	return 0
+}
+
+func (x *Job) GetFinishedAtMs() int64 {
+	if x != nil {
+		return x.FinishedAtMs
+	}
+	return 0
+}
+
+func (x *Job) GetStages() []*Stage {
+	if x != nil {
+		return x.Stages
+	}
+	return nil
+}
+
+func (x *Job) GetType() JobType {
+	if x != nil {
+		return x.Type
+	}
+	return JobType_JOB_TYPE_UNSPECIFIED
+}
+
+func (x *Job) GetMapReduce() *MapReduceStatus {
+	if x != nil {
+		return x.MapReduce
+	}
+	return nil
+}
+
+type MapReduceStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Maps          uint32                 `protobuf:"varint,1,opt,name=maps,proto3" json:"maps,omitempty"`
+	Partitions    uint32                 `protobuf:"varint,2,opt,name=partitions,proto3" json:"partitions,omitempty"`
+	OutputPrefix  string                 `protobuf:"bytes,3,opt,name=output_prefix,json=outputPrefix,proto3" json:"output_prefix,omitempty"`
+	ByPartition   []*PartitionStatus     `protobuf:"bytes,4,rep,name=by_partition,json=byPartition,proto3" json:"by_partition,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MapReduceStatus) Reset() {
+	*x = MapReduceStatus{}
+	mi := &file_task_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MapReduceStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MapReduceStatus) ProtoMessage() {}
+
+func (x *MapReduceStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MapReduceStatus.ProtoReflect.Descriptor instead.
+func (*MapReduceStatus) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *MapReduceStatus) GetMaps() uint32 {
+	if x != nil {
+		return x.Maps
+	}
+	return 0
+}
+
+func (x *MapReduceStatus) GetPartitions() uint32 {
+	if x != nil {
+		return x.Partitions
+	}
+	return 0
+}
+
+func (x *MapReduceStatus) GetOutputPrefix() string {
+	if x != nil {
+		return x.OutputPrefix
+	}
+	return ""
+}
+
+func (x *MapReduceStatus) GetByPartition() []*PartitionStatus {
+	if x != nil {
+		return x.ByPartition
+	}
+	return nil
+}
+
+type PartitionStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Partition     uint32                 `protobuf:"varint,1,opt,name=partition,proto3" json:"partition,omitempty"`
+	MapOutputs    uint32                 `protobuf:"varint,2,opt,name=map_outputs,json=mapOutputs,proto3" json:"map_outputs,omitempty"`        // maps that reported output for it
+	Bytes         int64                  `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`                                    // intermediate bytes reported so far
+	ReduceTaskId  string                 `protobuf:"bytes,4,opt,name=reduce_task_id,json=reduceTaskId,proto3" json:"reduce_task_id,omitempty"` // empty until every map reported
+	ReduceState   TaskState              `protobuf:"varint,5,opt,name=reduce_state,json=reduceState,proto3,enum=task.TaskState" json:"reduce_state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PartitionStatus) Reset() {
+	*x = PartitionStatus{}
+	mi := &file_task_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PartitionStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PartitionStatus) ProtoMessage() {}
+
+func (x *PartitionStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PartitionStatus.ProtoReflect.Descriptor instead.
+func (*PartitionStatus) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *PartitionStatus) GetPartition() uint32 {
+	if x != nil {
+		return x.Partition
+	}
+	return 0
+}
+
+func (x *PartitionStatus) GetMapOutputs() uint32 {
+	if x != nil {
+		return x.MapOutputs
+	}
+	return 0
+}
+
+func (x *PartitionStatus) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *PartitionStatus) GetReduceTaskId() string {
+	if x != nil {
+		return x.ReduceTaskId
+	}
+	return ""
+}
+
+func (x *PartitionStatus) GetReduceState() TaskState {
+	if x != nil {
+		return x.ReduceState
+	}
+	return TaskState_TASK_STATE_UNSPECIFIED
+}
+
+// GetJobRequest reads a job from the local FSM.
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	IncludeTasks  bool                   `protobuf:"varint,2,opt,name=include_tasks,json=includeTasks,proto3" json:"include_tasks,omitempty"` // also return the job's tasks still held by the FSM
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_task_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *GetJobRequest) GetIncludeTasks() bool {
+	if x != nil {
+		return x.IncludeTasks
+	}
+	return false
+}
+
+type GetJobResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Job           *Job                   `protobuf:"bytes,3,opt,name=job,proto3" json:"job,omitempty"`
+	Tasks         []*Task                `protobuf:"bytes,4,rep,name=tasks,proto3" json:"tasks,omitempty"` // ordered by task_id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobResponse) Reset() {
+	*x = GetJobResponse{}
+	mi := &file_task_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobResponse) ProtoMessage() {}
+
+func (x *GetJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobResponse.ProtoReflect.Descriptor instead.
+func (*GetJobResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *GetJobResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *GetJobResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *GetJobResponse) GetJob() *Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+func (x *GetJobResponse) GetTasks() []*Task {
+	if x != nil {
+		return x.Tasks
+	}
+	return nil
+}
+
+// ListJobsRequest filters by state; unspecified matches everything.
+// CancelJobRequest cancels every unfinished task of a job, and the tasks it
+// has yet to create, and finishes the job as cancelled.
+type CancelJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobRequest) Reset() {
+	*x = CancelJobRequest{}
+	mi := &file_task_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobRequest) ProtoMessage() {}
+
+func (x *CancelJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobRequest.ProtoReflect.Descriptor instead.
+func (*CancelJobRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *CancelJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *CancelJobRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type CancelJobResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	LeaderAddr    string                 `protobuf:"bytes,2,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Job           *Job                   `protobuf:"bytes,4,opt,name=job,proto3" json:"job,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobResponse) Reset() {
+	*x = CancelJobResponse{}
+	mi := &file_task_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobResponse) ProtoMessage() {}
+
+func (x *CancelJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobResponse.ProtoReflect.Descriptor instead.
+func (*CancelJobResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *CancelJobResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *CancelJobResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *CancelJobResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CancelJobResponse) GetJob() *Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+type ListJobsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	State         JobState               `protobuf:"varint,1,opt,name=state,proto3,enum=task.JobState" json:"state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_task_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ListJobsRequest) GetState() JobState {
+	if x != nil {
+		return x.State
+	}
+	return JobState_JOB_STATE_UNSPECIFIED
+}
+
+type ListJobsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*Job                 `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"` // ordered by job_id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsResponse) Reset() {
+	*x = ListJobsResponse{}
+	mi := &file_task_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsResponse) ProtoMessage() {}
+
+func (x *ListJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListJobsResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ListJobsResponse) GetJobs() []*Job {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+// AcquireTaskRequest asks for a pending task this worker can run.
+type AcquireTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkerId      string                 `protobuf:"bytes,1,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Epoch         uint64                 `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`                           // from RegisterWorkerResponse
+	Types         []TaskType             `protobuf:"varint,3,rep,packed,name=types,proto3,enum=task.TaskType" json:"types,omitempty"` // task types this worker runs; empty means all
+	WaitMs        uint32                 `protobuf:"varint,4,opt,name=wait_ms,json=waitMs,proto3" json:"wait_ms,omitempty"`           // long-poll up to this long (capped by the server) when nothing is pending
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AcquireTaskRequest) Reset() {
+	*x = AcquireTaskRequest{}
+	mi := &file_task_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcquireTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcquireTaskRequest) ProtoMessage() {}
+
+func (x *AcquireTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcquireTaskRequest.ProtoReflect.Descriptor instead.
+func (*AcquireTaskRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *AcquireTaskRequest) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *AcquireTaskRequest) GetEpoch() uint64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+func (x *AcquireTaskRequest) GetTypes() []TaskType {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *AcquireTaskRequest) GetWaitMs() uint32 {
+	if x != nil {
+		return x.WaitMs
+	}
+	return 0
+}
+
+// AcquireTaskResponse carries the assigned task, or no task if the wait ran out.
+type AcquireTaskResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	LeaderAddr    string                 `protobuf:"bytes,2,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Fenced        bool                   `protobuf:"varint,4,opt,name=fenced,proto3" json:"fenced,omitempty"` // a newer registration owns this worker_id — the caller must stop
+	Task          *Task                  `protobuf:"bytes,5,opt,name=task,proto3" json:"task,omitempty"`      // unset: nothing to do, poll again
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AcquireTaskResponse) Reset() {
+	*x = AcquireTaskResponse{}
+	mi := &file_task_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcquireTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcquireTaskResponse) ProtoMessage() {}
+
+func (x *AcquireTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcquireTaskResponse.ProtoReflect.Descriptor instead.
+func (*AcquireTaskResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *AcquireTaskResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *AcquireTaskResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *AcquireTaskResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *AcquireTaskResponse) GetFenced() bool {
+	if x != nil {
+		return x.Fenced
+	}
+	return false
+}
+
+func (x *AcquireTaskResponse) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+// RenewTaskLeaseRequest extends the lease on a running task.
+type RenewTaskLeaseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkerId      string                 `protobuf:"bytes,1,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Epoch         uint64                 `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	TaskId        string                 `protobuf:"bytes,3,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Attempt       uint32                 `protobuf:"varint,4,opt,name=attempt,proto3" json:"attempt,omitempty"` // Task.attempt from AcquireTaskResponse
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenewTaskLeaseRequest) Reset() {
+	*x = RenewTaskLeaseRequest{}
+	mi := &file_task_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenewTaskLeaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewTaskLeaseRequest) ProtoMessage() {}
+
+func (x *RenewTaskLeaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewTaskLeaseRequest.ProtoReflect.Descriptor instead.
+func (*RenewTaskLeaseRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *RenewTaskLeaseRequest) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *RenewTaskLeaseRequest) GetEpoch() uint64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+func (x *RenewTaskLeaseRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *RenewTaskLeaseRequest) GetAttempt() uint32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+type RenewTaskLeaseResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Ok               bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	LeaderAddr       string                 `protobuf:"bytes,2,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"`
+	Error            string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Fenced           bool                   `protobuf:"varint,4,opt,name=fenced,proto3" json:"fenced,omitempty"`
+	LeaseLost        bool                   `protobuf:"varint,5,opt,name=lease_lost,json=leaseLost,proto3" json:"lease_lost,omitempty"` // the task was cancelled or reassigned — stop working on it
+	LeaseExpiresAtMs int64                  `protobuf:"varint,6,opt,name=lease_expires_at_ms,json=leaseExpiresAtMs,proto3" json:"lease_expires_at_ms,omitempty"`
+	Kill             *KillOrder             `protobuf:"bytes,7,opt,name=kill,proto3" json:"kill,omitempty"` // set: stop the attempt and report it failed before kill.grace_until_ms
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *RenewTaskLeaseResponse) Reset() {
+	*x = RenewTaskLeaseResponse{}
+	mi := &file_task_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenewTaskLeaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewTaskLeaseResponse) ProtoMessage() {}
+
+func (x *RenewTaskLeaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewTaskLeaseResponse.ProtoReflect.Descriptor instead.
+func (*RenewTaskLeaseResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *RenewTaskLeaseResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *RenewTaskLeaseResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *RenewTaskLeaseResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *RenewTaskLeaseResponse) GetFenced() bool {
+	if x != nil {
+		return x.Fenced
+	}
+	return false
+}
+
+func (x *RenewTaskLeaseResponse) GetLeaseLost() bool {
+	if x != nil {
+		return x.LeaseLost
+	}
+	return false
+}
+
+func (x *RenewTaskLeaseResponse) GetLeaseExpiresAtMs() int64 {
+	if x != nil {
+		return x.LeaseExpiresAtMs
+	}
+	return 0
+}
+
+func (x *RenewTaskLeaseResponse) GetKill() *KillOrder {
+	if x != nil {
+		return x.Kill
+	}
+	return nil
+}
+
+// CompleteTaskRequest reports the outcome of a running task.
+type CompleteTaskRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	WorkerId  string                 `protobuf:"bytes,1,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Epoch     uint64                 `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	TaskId    string                 `protobuf:"bytes,3,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Attempt   uint32                 `protobuf:"varint,4,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	Succeeded bool                   `protobuf:"varint,5,opt,name=succeeded,proto3" json:"succeeded,omitempty"`
+	Error     string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"` // why the task failed
+	// outputs is required when a MapReduce map task succeeds: exactly one
+	// entry per partition.
+	Outputs       []*IntermediateOutput `protobuf:"bytes,7,rep,name=outputs,proto3" json:"outputs,omitempty"`
+	ErrorClass    ErrorClass            `protobuf:"varint,8,opt,name=error_class,json=errorClass,proto3,enum=task.ErrorClass" json:"error_class,omitempty"` // failed attempts only
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompleteTaskRequest) Reset() {
+	*x = CompleteTaskRequest{}
+	mi := &file_task_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteTaskRequest) ProtoMessage() {}
+
+func (x *CompleteTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteTaskRequest.ProtoReflect.Descriptor instead.
+func (*CompleteTaskRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *CompleteTaskRequest) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *CompleteTaskRequest) GetEpoch() uint64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+func (x *CompleteTaskRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *CompleteTaskRequest) GetAttempt() uint32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *CompleteTaskRequest) GetSucceeded() bool {
+	if x != nil {
+		return x.Succeeded
+	}
+	return false
+}
+
+func (x *CompleteTaskRequest) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CompleteTaskRequest) GetOutputs() []*IntermediateOutput {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *CompleteTaskRequest) GetErrorClass() ErrorClass {
+	if x != nil {
+		return x.ErrorClass
+	}
+	return ErrorClass_ERROR_CLASS_UNSPECIFIED
+}
+
+type CompleteTaskResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	LeaderAddr    string                 `protobuf:"bytes,2,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Fenced        bool                   `protobuf:"varint,4,opt,name=fenced,proto3" json:"fenced,omitempty"`
+	LeaseLost     bool                   `protobuf:"varint,5,opt,name=lease_lost,json=leaseLost,proto3" json:"lease_lost,omitempty"` // the result was discarded: the task was cancelled or reassigned
+	Retrying      bool                   `protobuf:"varint,6,opt,name=retrying,proto3" json:"retrying,omitempty"`                    // the failure was accepted and the task will run again
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompleteTaskResponse) Reset() {
+	*x = CompleteTaskResponse{}
+	mi := &file_task_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteTaskResponse) ProtoMessage() {}
+
+func (x *CompleteTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteTaskResponse.ProtoReflect.Descriptor instead.
+func (*CompleteTaskResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *CompleteTaskResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *CompleteTaskResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *CompleteTaskResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CompleteTaskResponse) GetFenced() bool {
+	if x != nil {
+		return x.Fenced
+	}
+	return false
+}
+
+func (x *CompleteTaskResponse) GetLeaseLost() bool {
+	if x != nil {
+		return x.LeaseLost
+	}
+	return false
+}
+
+func (x *CompleteTaskResponse) GetRetrying() bool {
+	if x != nil {
+		return x.Retrying
+	}
+	return false
+}
+
+// CommitTaskOutputRequest asks to write the task's output_uri. The first
+// running attempt to ask is granted and any other attempt is superseded;
+// asking again from the granted attempt is granted again, so a worker may
+// retry the call safely.
+type CommitTaskOutputRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkerId      string                 `protobuf:"bytes,1,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Epoch         uint64                 `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	TaskId        string                 `protobuf:"bytes,3,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Attempt       uint32                 `protobuf:"varint,4,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommitTaskOutputRequest) Reset() {
+	*x = CommitTaskOutputRequest{}
+	mi := &file_task_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommitTaskOutputRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitTaskOutputRequest) ProtoMessage() {}
+
+func (x *CommitTaskOutputRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitTaskOutputRequest.ProtoReflect.Descriptor instead.
+func (*CommitTaskOutputRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *CommitTaskOutputRequest) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *CommitTaskOutputRequest) GetEpoch() uint64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+func (x *CommitTaskOutputRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *CommitTaskOutputRequest) GetAttempt() uint32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+type CommitTaskOutputResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"` // granted: write the output, then CompleteTask
+	LeaderAddr    string                 `protobuf:"bytes,2,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Fenced        bool                   `protobuf:"varint,4,opt,name=fenced,proto3" json:"fenced,omitempty"`
+	LeaseLost     bool                   `protobuf:"varint,5,opt,name=lease_lost,json=leaseLost,proto3" json:"lease_lost,omitempty"` // another attempt won; discard the output
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommitTaskOutputResponse) Reset() {
+	*x = CommitTaskOutputResponse{}
+	mi := &file_task_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommitTaskOutputResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitTaskOutputResponse) ProtoMessage() {}
+
+func (x *CommitTaskOutputResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitTaskOutputResponse.ProtoReflect.Descriptor instead.
+func (*CommitTaskOutputResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *CommitTaskOutputResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *CommitTaskOutputResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *CommitTaskOutputResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CommitTaskOutputResponse) GetFenced() bool {
+	if x != nil {
+		return x.Fenced
+	}
+	return false
+}
+
+func (x *CommitTaskOutputResponse) GetLeaseLost() bool {
+	if x != nil {
+		return x.LeaseLost
+	}
+	return false
+}
+
+// DeadLetter is a task that failed for good: with a fatal error or after
+// running out of attempts. Entries outlive the task itself in the FSM.
+type DeadLetter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	JobId         string                 `protobuf:"bytes,2,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Type          TaskType               `protobuf:"varint,3,opt,name=type,proto3,enum=task.TaskType" json:"type,omitempty"`
+	Reason        string                 `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"` // "fatal error" or "retries exhausted"
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`   // from the last attempt
+	Attempts      []*TaskAttempt         `protobuf:"bytes,6,rep,name=attempts,proto3" json:"attempts,omitempty"`
+	AtMs          int64                  `protobuf:"varint,7,opt,name=at_ms,json=atMs,proto3" json:"at_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeadLetter) Reset() {
+	*x = DeadLetter{}
+	mi := &file_task_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeadLetter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeadLetter) ProtoMessage() {}
+
+func (x *DeadLetter) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeadLetter.ProtoReflect.Descriptor instead.
+func (*DeadLetter) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *DeadLetter) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetType() TaskType {
+	if x != nil {
+		return x.Type
+	}
+	return TaskType_TASK_TYPE_UNSPECIFIED
+}
+
+func (x *DeadLetter) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetAttempts() []*TaskAttempt {
+	if x != nil {
+		return x.Attempts
+	}
+	return nil
+}
+
+func (x *DeadLetter) GetAtMs() int64 {
+	if x != nil {
+		return x.AtMs
+	}
+	return 0
+}
+
+// ListDeadLettersRequest filters by job; empty matches everything.
+type ListDeadLettersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDeadLettersRequest) Reset() {
+	*x = ListDeadLettersRequest{}
+	mi := &file_task_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDeadLettersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDeadLettersRequest) ProtoMessage() {}
+
+func (x *ListDeadLettersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDeadLettersRequest.ProtoReflect.Descriptor instead.
+func (*ListDeadLettersRequest) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ListDeadLettersRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type ListDeadLettersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeadLetters   []*DeadLetter          `protobuf:"bytes,1,rep,name=dead_letters,json=deadLetters,proto3" json:"dead_letters,omitempty"` // oldest first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDeadLettersResponse) Reset() {
+	*x = ListDeadLettersResponse{}
+	mi := &file_task_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDeadLettersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDeadLettersResponse) ProtoMessage() {}
+
+func (x *ListDeadLettersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_task_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDeadLettersResponse.ProtoReflect.Descriptor instead.
+func (*ListDeadLettersResponse) Descriptor() ([]byte, []int) {
+	return file_task_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *ListDeadLettersResponse) GetDeadLetters() []*DeadLetter {
+	if x != nil {
+		return x.DeadLetters
+	}
+	return nil
+}
+
+var File_task_proto protoreflect.FileDescriptor
+
+const file_task_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"task.proto\x12\x04task\"\x9e\t\n" +
+	"\x04Task\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x15\n" +
+	"\x06job_id\x18\x02 \x01(\tR\x05jobId\x12\"\n" +
+	"\x04type\x18\x03 \x01(\x0e2\x0e.task.TaskTypeR\x04type\x12\x1d\n" +
+	"\n" +
+	"input_uris\x18\x04 \x03(\tR\tinputUris\x12\x1d\n" +
+	"\n" +
+	"output_uri\x18\x05 \x01(\tR\toutputUri\x12\x18\n" +
+	"\aattempt\x18\x06 \x01(\rR\aattempt\x12%\n" +
+	"\x05state\x18\a \x01(\x0e2\x0f.task.TaskStateR\x05state\x12'\n" +
+	"\x0fassigned_worker\x18\b \x01(\tR\x0eassignedWorker\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\x12\"\n" +
+	"\rcreated_at_ms\x18\n" +
+	" \x01(\x03R\vcreatedAtMs\x12\"\n" +
+	"\rstarted_at_ms\x18\v \x01(\x03R\vstartedAtMs\x12$\n" +
+	"\x0efinished_at_ms\x18\f \x01(\x03R\ffinishedAtMs\x12-\n" +
+	"\x13lease_expires_at_ms\x18\r \x01(\x03R\x10leaseExpiresAtMs\x12\x1c\n" +
+	"\tplacement\x18\x0e \x01(\tR\tplacement\x12%\n" +
+	"\x0ecloud_affinity\x18\x0f \x01(\tR\rcloudAffinity\x12\x1d\n" +
+	"\n" +
+	"cpu_millis\x18\x10 \x01(\x03R\tcpuMillis\x12\x1b\n" +
+	"\tmemory_mb\x18\x11 \x01(\x03R\bmemoryMb\x12)\n" +
+	"\x10placement_reason\x18\x12 \x01(\tR\x0fplacementReason\x12\x1f\n" +
+	"\vinput_bytes\x18\x13 \x03(\x03R\n" +
+	"inputBytes\x12!\n" +
+	"\fegress_bytes\x18\x14 \x01(\x03R\vegressBytes\x12\x1f\n" +
+	"\vegress_cost\x18\x15 \x01(\x01R\n" +
+	"egressCost\x12\x14\n" +
+	"\x05stage\x18\x16 \x01(\tR\x05stage\x12\x1d\n" +
+	"\n" +
+	"depends_on\x18\x17 \x03(\tR\tdependsOn\x12\x1e\n" +
+	"\n" +
+	"partitions\x18\x18 \x01(\rR\n" +
+	"partitions\x12\x1c\n" +
+	"\tpartition\x18\x19 \x01(\rR\tpartition\x12'\n" +
+	"\x05retry\x18\x1a \x01(\v2\x11.task.RetryPolicyR\x05retry\x12-\n" +
+	"\battempts\x18\x1b \x03(\v2\x11.task.TaskAttemptR\battempts\x12\x1a\n" +
+	"\bfailures\x18\x1c \x01(\rR\bfailures\x12\"\n" +
+	"\rnot_before_ms\x18\x1d \x01(\x03R\vnotBeforeMs\x12:\n" +
+	"\vspeculative\x18\x1e \x01(\v2\x18.task.SpeculativeAttemptR\vspeculative\x12%\n" +
+	"\x0ecommit_attempt\x18\x1f \x01(\rR\rcommitAttempt\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18  \x01(\rR\ttimeoutMs\x12\"\n" +
+	"\rkill_grace_ms\x18! \x01(\rR\vkillGraceMs\x12%\n" +
+	"\x05kills\x18\" \x03(\v2\x0f.task.KillOrderR\x05kills\"\xbc\x01\n" +
+	"\tKillOrder\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x18\n" +
+	"\aattempt\x18\x02 \x01(\rR\aattempt\x12\x16\n" +
+	"\x06worker\x18\x03 \x01(\tR\x06worker\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x12&\n" +
+	"\x0frequested_at_ms\x18\x05 \x01(\x03R\rrequestedAtMs\x12$\n" +
+	"\x0egrace_until_ms\x18\x06 \x01(\x03R\fgraceUntilMs\"\xb1\x01\n" +
+	"\x12SpeculativeAttempt\x12\x18\n" +
+	"\aattempt\x18\x01 \x01(\rR\aattempt\x12\x16\n" +
+	"\x06worker\x18\x02 \x01(\tR\x06worker\x12\"\n" +
+	"\rstarted_at_ms\x18\x03 \x01(\x03R\vstartedAtMs\x12-\n" +
+	"\x13lease_expires_at_ms\x18\x04 \x01(\x03R\x10leaseExpiresAtMs\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\"\xbc\x01\n" +
+	"\vRetryPolicy\x12!\n" +
+	"\fmax_attempts\x18\x01 \x01(\rR\vmaxAttempts\x12,\n" +
+	"\x12initial_backoff_ms\x18\x02 \x01(\rR\x10initialBackoffMs\x12$\n" +
+	"\x0emax_backoff_ms\x18\x03 \x01(\rR\fmaxBackoffMs\x12\x1e\n" +
+	"\n" +
+	"multiplier\x18\x04 \x01(\x01R\n" +
+	"multiplier\x12\x16\n" +
+	"\x06jitter\x18\x05 \x01(\x01R\x06jitter\"\x82\x02\n" +
+	"\vTaskAttempt\x12\x18\n" +
+	"\aattempt\x18\x01 \x01(\rR\aattempt\x12\x16\n" +
+	"\x06worker\x18\x02 \x01(\tR\x06worker\x12\"\n" +
+	"\rstarted_at_ms\x18\x03 \x01(\x03R\vstartedAtMs\x12$\n" +
+	"\x0efinished_at_ms\x18\x04 \x01(\x03R\ffinishedAtMs\x12.\n" +
+	"\aoutcome\x18\x05 \x01(\x0e2\x14.task.AttemptOutcomeR\aoutcome\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x121\n" +
+	"\verror_class\x18\a \x01(\x0e2\x10.task.ErrorClassR\n" +
+	"errorClass\"t\n" +
+	"\x12IntermediateOutput\x12\x1c\n" +
+	"\tpartition\x18\x01 \x01(\rR\tpartition\x12\x10\n" +
+	"\x03uri\x18\x02 \x01(\tR\x03uri\x12\x14\n" +
+	"\x05bytes\x18\x03 \x01(\x03R\x05bytes\x12\x18\n" +
+	"\adurable\x18\x04 \x01(\bR\adurable\"\xd2\x03\n" +
+	"\x11SubmitTaskRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x15\n" +
+	"\x06job_id\x18\x02 \x01(\tR\x05jobId\x12\"\n" +
+	"\x04type\x18\x03 \x01(\x0e2\x0e.task.TaskTypeR\x04type\x12\x1d\n" +
+	"\n" +
+	"input_uris\x18\x04 \x03(\tR\tinputUris\x12\x1d\n" +
+	"\n" +
+	"output_uri\x18\x05 \x01(\tR\toutputUri\x12\x1c\n" +
+	"\tplacement\x18\x06 \x01(\tR\tplacement\x12%\n" +
+	"\x0ecloud_affinity\x18\a \x01(\tR\rcloudAffinity\x12\x1d\n" +
+	"\n" +
+	"cpu_millis\x18\b \x01(\x03R\tcpuMillis\x12\x1b\n" +
+	"\tmemory_mb\x18\t \x01(\x03R\bmemoryMb\x12\x1f\n" +
+	"\vinput_bytes\x18\n" +
+	" \x03(\x03R\n" +
+	"inputBytes\x12\x1d\n" +
+	"\n" +
+	"depends_on\x18\v \x03(\tR\tdependsOn\x12'\n" +
+	"\x05retry\x18\f \x01(\v2\x11.task.RetryPolicyR\x05retry\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\r \x01(\rR\ttimeoutMs\x12\"\n" +
+	"\rkill_grace_ms\x18\x0e \x01(\rR\vkillGraceMs\"{\n" +
+	"\x12SubmitTaskResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x1f\n" +
+	"\vleader_addr\x18\x02 \x01(\tR\n" +
+	"leaderAddr\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1e\n" +
+	"\x04task\x18\x04 \x01(\v2\n" +
+	".task.TaskR\x04task\")\n" +
+	"\x0eGetTaskRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\"W\n" +
+	"\x0fGetTaskResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1e\n" +
+	"\x04task\x18\x03 \x01(\v2\n" +
+	".task.TaskR\x04task\"P\n" +
+	"\x10ListTasksRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12%\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x0f.task.TaskStateR\x05state\"5\n" +
+	"\x11ListTasksResponse\x12 \n" +
+	"\x05tasks\x18\x01 \x03(\v2\n" +
+	".task.TaskR\x05tasks\"D\n" +
+	"\x11CancelTaskRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"{\n" +
+	"\x12CancelTaskResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x1f\n" +
+	"\vleader_addr\x18\x02 \x01(\tR\n" +
+	"leaderAddr\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1e\n" +
+	"\x04task\x18\x04 \x01(\v2\n" +
+	".task.TaskR\x04task\",\n" +
+	"\x13GetJobEgressRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"\xbb\x01\n" +
+	"\x14GetJobEgressResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x14\n" +
+	"\x05tasks\x18\x03 \x01(\rR\x05tasks\x12!\n" +
+	"\fegress_bytes\x18\x04 \x01(\x03R\vegressBytes\x12\x1f\n" +
+	"\vegress_cost\x18\x05 \x01(\x01R\n" +
+	"egressCost\x12#\n" +
+	"\aby_task\x18\x06 \x03(\v2\n" +
+	".task.TaskR\x06byTask\"m\n" +
+	"\tStageSpec\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"depends_on\x18\x02 \x03(\tR\tdependsOn\x12-\n" +
+	"\x05tasks\x18\x03 \x03(\v2\x17.task.SubmitTaskRequestR\x05tasks\"\x91\x01\n" +
+	"\x10SubmitJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12=\n" +
+	"\x0efailure_policy\x18\x02 \x01(\x0e2\x16.task.JobFailurePolicyR\rfailurePolicy\x12'\n" +
+	"\x06stages\x18\x03 \x03(\v2\x0f.task.StageSpecR\x06stages\"w\n" +
+	"\x11SubmitJobResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x1f\n" +
+	"\vleader_addr\x18\x02 \x01(\tR\n" +
+	"leaderAddr\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1b\n" +
+	"\x03job\x18\x04 \x01(\v2\t.task.JobR\x03job\"\x8c\x04\n" +
+	"\x16SubmitMapReduceRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x1d\n" +
+	"\n" +
+	"input_uris\x18\x02 \x03(\tR\tinputUris\x12\x1f\n" +
+	"\vinput_bytes\x18\x03 \x03(\x03R\n" +
+	"inputBytes\x12\x1e\n" +
+	"\n" +
+	"partitions\x18\x04 \x01(\rR\n" +
+	"partitions\x12#\n" +
+	"\routput_prefix\x18\x05 \x01(\tR\foutputPrefix\x12=\n" +
+	"\x0efailure_policy\x18\x06 \x01(\x0e2\x16.task.JobFailurePolicyR\rfailurePolicy\x12\x1c\n" +
+	"\tplacement\x18\a \x01(\tR\tplacement\x12%\n" +
+	"\x0ecloud_affinity\x18\b \x01(\tR\rcloudAffinity\x12\x1d\n" +
+	"\n" +
+	"cpu_millis\x18\t \x01(\x03R\tcpuMillis\x12\x1b\n" +
+	"\tmemory_mb\x18\n" +
+	" \x01(\x03R\bmemoryMb\x12*\n" +
+	"\x06splits\x18\v \x03(\v2\x12.task.MapSplitSpecR\x06splits\x12'\n" +
+	"\x05retry\x18\f \x01(\v2\x11.task.RetryPolicyR\x05retry\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\r \x01(\rR\ttimeoutMs\x12\"\n" +
+	"\rkill_grace_ms\x18\x0e \x01(\rR\vkillGraceMs\"N\n" +
+	"\fMapSplitSpec\x12\x1d\n" +
+	"\n" +
+	"input_uris\x18\x01 \x03(\tR\tinputUris\x12\x1f\n" +
+	"\vinput_bytes\x18\x02 \x03(\x03R\n" +
+	"inputBytes\"\xb3\x02\n" +
+	"\x05Stage\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"depends_on\x18\x02 \x03(\tR\tdependsOn\x12$\n" +
+	"\x05state\x18\x03 \x01(\x0e2\x0e.task.JobStateR\x05state\x12\x14\n" +
+	"\x05tasks\x18\x04 \x01(\rR\x05tasks\x12\x18\n" +
+	"\ablocked\x18\x05 \x01(\rR\ablocked\x12\x18\n" +
+	"\apending\x18\x06 \x01(\rR\apending\x12\x18\n" +
+	"\arunning\x18\a \x01(\rR\arunning\x12\x1c\n" +
+	"\tsucceeded\x18\b \x01(\rR\tsucceeded\x12\x16\n" +
+	"\x06failed\x18\t \x01(\rR\x06failed\x12\x1c\n" +
+	"\tcancelled\x18\n" +
+	" \x01(\rR\tcancelled\x12\x19\n" +
+	"\btask_ids\x18\v \x03(\tR\ataskIds\"\xdf\x02\n" +
+	"\x03Job\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12=\n" +
+	"\x0efailure_policy\x18\x02 \x01(\x0e2\x16.task.JobFailurePolicyR\rfailurePolicy\x12$\n" +
+	"\x05state\x18\x03 \x01(\x0e2\x0e.task.JobStateR\x05state\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\"\n" +
+	"\rcreated_at_ms\x18\x05 \x01(\x03R\vcreatedAtMs\x12$\n" +
+	"\x0efinished_at_ms\x18\x06 \x01(\x03R\ffinishedAtMs\x12#\n" +
+	"\x06stages\x18\a \x03(\v2\v.task.StageR\x06stages\x12!\n" +
+	"\x04type\x18\b \x01(\x0e2\r.task.JobTypeR\x04type\x124\n" +
+	"\n" +
+	"map_reduce\x18\t \x01(\v2\x15.task.MapReduceStatusR\tmapReduce\"\xa4\x01\n" +
+	"\x0fMapReduceStatus\x12\x12\n" +
+	"\x04maps\x18\x01 \x01(\rR\x04maps\x12\x1e\n" +
+	"\n" +
+	"partitions\x18\x02 \x01(\rR\n" +
+	"partitions\x12#\n" +
+	"\routput_prefix\x18\x03 \x01(\tR\foutputPrefix\x128\n" +
+	"\fby_partition\x18\x04 \x03(\v2\x15.task.PartitionStatusR\vbyPartition\"\xc0\x01\n" +
+	"\x0fPartitionStatus\x12\x1c\n" +
+	"\tpartition\x18\x01 \x01(\rR\tpartition\x12\x1f\n" +
+	"\vmap_outputs\x18\x02 \x01(\rR\n" +
+	"mapOutputs\x12\x14\n" +
+	"\x05bytes\x18\x03 \x01(\x03R\x05bytes\x12$\n" +
+	"\x0ereduce_task_id\x18\x04 \x01(\tR\freduceTaskId\x122\n" +
+	"\freduce_state\x18\x05 \x01(\x0e2\x0f.task.TaskStateR\vreduceState\"K\n" +
+	"\rGetJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12#\n" +
+	"\rinclude_tasks\x18\x02 \x01(\bR\fincludeTasks\"u\n" +
+	"\x0eGetJobResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1b\n" +
+	"\x03job\x18\x03 \x01(\v2\t.task.JobR\x03job\x12 \n" +
+	"\x05tasks\x18\x04 \x03(\v2\n" +
+	".task.TaskR\x05tasks\"A\n" +
+	"\x10CancelJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"w\n" +
+	"\x11CancelJobResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x1f\n" +
+	"\vleader_addr\x18\x02 \x01(\tR\n" +
+	"leaderAddr\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1b\n" +
+	"\x03job\x18\x04 \x01(\v2\t.task.JobR\x03job\"7\n" +
+	"\x0fListJobsRequest\x12$\n" +
+	"\x05state\x18\x01 \x01(\x0e2\x0e.task.JobStateR\x05state\"1\n" +
+	"\x10ListJobsResponse\x12\x1d\n" +
+	"\x04jobs\x18\x01 \x03(\v2\t.task.JobR\x04jobs\"\x86\x01\n" +
+	"\x12AcquireTaskRequest\x12\x1b\n" +
+	"\tworker_id\x18\x01 \x01(\tR\bworkerId\x12\x14\n" +
+	"\x05epoch\x18\x02 \x01(\x04R\x05epoch\x12$\n" +
+	"\x05types\x18\x03 \x03(\x0e2\x0e.task.TaskTypeR\x05types\x12\x17\n" +
+	"\await_ms\x18\x04 \x01(\rR\x06waitMs\"\x94\x01\n" +
+	"\x13AcquireTaskResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x1f\n" +
+	"\vleader_addr\x18\x02 \x01(\tR\n" +
+	"leaderAddr\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x16\n" +
+	"\x06fenced\x18\x04 \x01(\bR\x06fenced\x12\x1e\n" +
+	"\x04task\x18\x05 \x01(\v2\n" +
+	".task.TaskR\x04task\"}\n" +
+	"\x15RenewTaskLeaseRequest\x12\x1b\n" +
+	"\tworker_id\x18\x01 \x01(\tR\bworkerId\x12\x14\n" +
+	"\x05epoch\x18\x02 \x01(\x04R\x05epoch\x12\x17\n" +
+	"\atask_id\x18\x03 \x01(\tR\x06taskId\x12\x18\n" +
+	"\aattempt\x18\x04 \x01(\rR\aattempt\"\xea\x01\n" +
+	"\x16RenewTaskLeaseResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x1f\n" +
+	"\vleader_addr\x18\x02 \x01(\tR\n" +
+	"leaderAddr\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x16\n" +
+	"\x06fenced\x18\x04 \x01(\bR\x06fenced\x12\x1d\n" +
+	"\n" +
+	"lease_lost\x18\x05 \x01(\bR\tleaseLost\x12-\n" +
+	"\x13lease_expires_at_ms\x18\x06 \x01(\x03R\x10leaseExpiresAtMs\x12#\n" +
+	"\x04kill\x18\a \x01(\v2\x0f.task.KillOrderR\x04kill\"\x96\x02\n" +
+	"\x13CompleteTaskRequest\x12\x1b\n" +
+	"\tworker_id\x18\x01 \x01(\tR\bworkerId\x12\x14\n" +
+	"\x05epoch\x18\x02 \x01(\x04R\x05epoch\x12\x17\n" +
+	"\atask_id\x18\x03 \x01(\tR\x06taskId\x12\x18\n" +
+	"\aattempt\x18\x04 \x01(\rR\aattempt\x12\x1c\n" +
+	"\tsucceeded\x18\x05 \x01(\bR\tsucceeded\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x122\n" +
+	"\aoutputs\x18\a \x03(\v2\x18.task.IntermediateOutputR\aoutputs\x121\n" +
+	"\verror_class\x18\b \x01(\x0e2\x10.task.ErrorClassR\n" +
+	"errorClass\"\xb0\x01\n" +
+	"\x14CompleteTaskResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x1f\n" +
+	"\vleader_addr\x18\x02 \x01(\tR\n" +
+	"leaderAddr\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x16\n" +
+	"\x06fenced\x18\x04 \x01(\bR\x06fenced\x12\x1d\n" +
+	"\n" +
+	"lease_lost\x18\x05 \x01(\bR\tleaseLost\x12\x1a\n" +
+	"\bretrying\x18\x06 \x01(\bR\bretrying\"\x7f\n" +
+	"\x17CommitTaskOutputRequest\x12\x1b\n" +
+	"\tworker_id\x18\x01 \x01(\tR\bworkerId\x12\x14\n" +
+	"\x05epoch\x18\x02 \x01(\x04R\x05epoch\x12\x17\n" +
+	"\atask_id\x18\x03 \x01(\tR\x06taskId\x12\x18\n" +
+	"\aattempt\x18\x04 \x01(\rR\aattempt\"\x98\x01\n" +
+	"\x18CommitTaskOutputResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x1f\n" +
+	"\vleader_addr\x18\x02 \x01(\tR\n" +
+	"leaderAddr\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x16\n" +
+	"\x06fenced\x18\x04 \x01(\bR\x06fenced\x12\x1d\n" +
+	"\n" +
+	"lease_lost\x18\x05 \x01(\bR\tleaseLost\"\xd2\x01\n" +
+	"\n" +
+	"DeadLetter\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x15\n" +
+	"\x06job_id\x18\x02 \x01(\tR\x05jobId\x12\"\n" +
+	"\x04type\x18\x03 \x01(\x0e2\x0e.task.TaskTypeR\x04type\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12-\n" +
+	"\battempts\x18\x06 \x03(\v2\x11.task.TaskAttemptR\battempts\x12\x13\n" +
+	"\x05at_ms\x18\a \x01(\x03R\x04atMs\"/\n" +
+	"\x16ListDeadLettersRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"N\n" +
+	"\x17ListDeadLettersResponse\x123\n" +
+	"\fdead_letters\x18\x01 \x03(\v2\x10.task.DeadLetterR\vdeadLetters*e\n" +
+	"\bTaskType\x12\x19\n" +
+	"\x15TASK_TYPE_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rTASK_TYPE_MAP\x10\x01\x12\x14\n" +
+	"\x10TASK_TYPE_REDUCE\x10\x02\x12\x15\n" +
+	"\x11TASK_TYPE_GENERIC\x10\x03*\xba\x01\n" +
+	"\tTaskState\x12\x1a\n" +
+	"\x16TASK_STATE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12TASK_STATE_PENDING\x10\x01\x12\x16\n" +
+	"\x12TASK_STATE_RUNNING\x10\x02\x12\x18\n" +
+	"\x14TASK_STATE_SUCCEEDED\x10\x03\x12\x15\n" +
+	"\x11TASK_STATE_FAILED\x10\x04\x12\x18\n" +
+	"\x14TASK_STATE_CANCELLED\x10\x05\x12\x16\n" +
+	"\x12TASK_STATE_BLOCKED\x10\x06*[\n" +
+	"\n" +
+	"ErrorClass\x12\x1b\n" +
+	"\x17ERROR_CLASS_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15ERROR_CLASS_RETRYABLE\x10\x01\x12\x15\n" +
+	"\x11ERROR_CLASS_FATAL\x10\x02*\xaf\x02\n" +
+	"\x0eAttemptOutcome\x12\x1f\n" +
+	"\x1bATTEMPT_OUTCOME_UNSPECIFIED\x10\x00\x12\x1d\n" +
+	"\x19ATTEMPT_OUTCOME_SUCCEEDED\x10\x01\x12\x1a\n" +
+	"\x16ATTEMPT_OUTCOME_FAILED\x10\x02\x12!\n" +
+	"\x1dATTEMPT_OUTCOME_LEASE_EXPIRED\x10\x03\x12\x1f\n" +
+	"\x1bATTEMPT_OUTCOME_WORKER_LOST\x10\x04\x12\x1f\n" +
+	"\x1bATTEMPT_OUTCOME_INTERRUPTED\x10\x05\x12\x1d\n" +
+	"\x19ATTEMPT_OUTCOME_CANCELLED\x10\x06\x12\x1e\n" +
+	"\x1aATTEMPT_OUTCOME_SUPERSEDED\x10\a\x12\x1d\n" +
+	"\x19ATTEMPT_OUTCOME_TIMED_OUT\x10\b*\x9b\x01\n" +
+	"\bJobState\x12\x19\n" +
+	"\x15JOB_STATE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11JOB_STATE_BLOCKED\x10\x01\x12\x15\n" +
+	"\x11JOB_STATE_RUNNING\x10\x02\x12\x17\n" +
+	"\x13JOB_STATE_SUCCEEDED\x10\x03\x12\x14\n" +
+	"\x10JOB_STATE_FAILED\x10\x04\x12\x17\n" +
+	"\x13JOB_STATE_CANCELLED\x10\x05*M\n" +
+	"\aJobType\x12\x18\n" +
+	"\x14JOB_TYPE_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fJOB_TYPE_DAG\x10\x01\x12\x16\n" +
+	"\x12JOB_TYPE_MAPREDUCE\x10\x02*y\n" +
+	"\x10JobFailurePolicy\x12\"\n" +
+	"\x1eJOB_FAILURE_POLICY_UNSPECIFIED\x10\x00\x12 \n" +
+	"\x1cJOB_FAILURE_POLICY_FAIL_FAST\x10\x01\x12\x1f\n" +
+	"\x1bJOB_FAILURE_POLICY_CONTINUE\x10\x022\xfd\a\n" +
+	"\vTaskService\x12?\n" +
+	"\n" +
+	"SubmitTask\x12\x17.task.SubmitTaskRequest\x1a\x18.task.SubmitTaskResponse\x126\n" +
+	"\aGetTask\x12\x14.task.GetTaskRequest\x1a\x15.task.GetTaskResponse\x12<\n" +
+	"\tListTasks\x12\x16.task.ListTasksRequest\x1a\x17.task.ListTasksResponse\x12?\n" +
+	"\n" +
+	"CancelTask\x12\x17.task.CancelTaskRequest\x1a\x18.task.CancelTaskResponse\x12E\n" +
+	"\fGetJobEgress\x12\x19.task.GetJobEgressRequest\x1a\x1a.task.GetJobEgressResponse\x12<\n" +
+	"\tSubmitJob\x12\x16.task.SubmitJobRequest\x1a\x17.task.SubmitJobResponse\x12H\n" +
+	"\x0fSubmitMapReduce\x12\x1c.task.SubmitMapReduceRequest\x1a\x17.task.SubmitJobResponse\x123\n" +
+	"\x06GetJob\x12\x13.task.GetJobRequest\x1a\x14.task.GetJobResponse\x129\n" +
+	"\bListJobs\x12\x15.task.ListJobsRequest\x1a\x16.task.ListJobsResponse\x12<\n" +
+	"\tCancelJob\x12\x16.task.CancelJobRequest\x1a\x17.task.CancelJobResponse\x12B\n" +
+	"\vAcquireTask\x12\x18.task.AcquireTaskRequest\x1a\x19.task.AcquireTaskResponse\x12K\n" +
+	"\x0eRenewTaskLease\x12\x1b.task.RenewTaskLeaseRequest\x1a\x1c.task.RenewTaskLeaseResponse\x12E\n" +
+	"\fCompleteTask\x12\x19.task.CompleteTaskRequest\x1a\x1a.task.CompleteTaskResponse\x12N\n" +
+	"\x0fListDeadLetters\x12\x1c.task.ListDeadLettersRequest\x1a\x1d.task.ListDeadLettersResponse\x12Q\n" +
+	"\x10CommitTaskOutput\x12\x1d.task.CommitTaskOutputRequest\x1a\x1e.task.CommitTaskOutputResponseBTZRgithub.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task;taskpbb\x06proto3"
+
+var (
+	file_task_proto_rawDescOnce sync.Once
+	file_task_proto_rawDescData []byte
+)
+
+func file_task_proto_rawDescGZIP() []byte {
+	file_task_proto_rawDescOnce.Do(func() {
+		file_task_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_task_proto_rawDesc), len(file_task_proto_rawDesc)))
+	})
+	return file_task_proto_rawDescData
+}
+
+var file_task_proto_enumTypes = make([]protoimpl.EnumInfo, 7)
+var file_task_proto_msgTypes = make([]protoimpl.MessageInfo, 42)
+var file_task_proto_goTypes = []any{
+	(TaskType)(0),                    // 0: task.TaskType
+	(TaskState)(0),                   // 1: task.TaskState
+	(ErrorClass)(0),                  // 2: task.ErrorClass
+	(AttemptOutcome)(0),              // 3: task.AttemptOutcome
+	(JobState)(0),                    // 4: task.JobState
+	(JobType)(0),                     // 5: task.JobType
+	(JobFailurePolicy)(0),            // 6: task.JobFailurePolicy
+	(*Task)(nil),                     // 7: task.Task
+	(*KillOrder)(nil),                // 8: task.KillOrder
+	(*SpeculativeAttempt)(nil),       // 9: task.SpeculativeAttempt
+	(*RetryPolicy)(nil),              // 10: task.RetryPolicy
+	(*TaskAttempt)(nil),              // 11: task.TaskAttempt
+	(*IntermediateOutput)(nil),       // 12: task.IntermediateOutput
+	(*SubmitTaskRequest)(nil),        // 13: task.SubmitTaskRequest
+	(*SubmitTaskResponse)(nil),       // 14: task.SubmitTaskResponse
+	(*GetTaskRequest)(nil),           // 15: task.GetTaskRequest
+	(*GetTaskResponse)(nil),          // 16: task.GetTaskResponse
+	(*ListTasksRequest)(nil),         // 17: task.ListTasksRequest
+	(*ListTasksResponse)(nil),        // 18: task.ListTasksResponse
+	(*CancelTaskRequest)(nil),        // 19: task.CancelTaskRequest
+	(*CancelTaskResponse)(nil),       // 20: task.CancelTaskResponse
+	(*GetJobEgressRequest)(nil),      // 21: task.GetJobEgressRequest
+	(*GetJobEgressResponse)(nil),     // 22: task.GetJobEgressResponse
+	(*StageSpec)(nil),                // 23: task.StageSpec
+	(*SubmitJobRequest)(nil),         // 24: task.SubmitJobRequest
+	(*SubmitJobResponse)(nil),        // 25: task.SubmitJobResponse
+	(*SubmitMapReduceRequest)(nil),   // 26: task.SubmitMapReduceRequest
+	(*MapSplitSpec)(nil),             // 27: task.MapSplitSpec
+	(*Stage)(nil),                    // 28: task.Stage
+	(*Job)(nil),                      // 29: task.Job
+	(*MapReduceStatus)(nil),          // 30: task.MapReduceStatus
+	(*PartitionStatus)(nil),          // 31: task.PartitionStatus
+	(*GetJobRequest)(nil),            // 32: task.GetJobRequest
+	(*GetJobResponse)(nil),           // 33: task.GetJobResponse
+	(*CancelJobRequest)(nil),         // 34: task.CancelJobRequest
+	(*CancelJobResponse)(nil),        // 35: task.CancelJobResponse
+	(*ListJobsRequest)(nil),          // 36: task.ListJobsRequest
+	(*ListJobsResponse)(nil),         // 37: task.ListJobsResponse
+	(*AcquireTaskRequest)(nil),       // 38: task.AcquireTaskRequest
+	(*AcquireTaskResponse)(nil),      // 39: task.AcquireTaskResponse
+	(*RenewTaskLeaseRequest)(nil),    // 40: task.RenewTaskLeaseRequest
+	(*RenewTaskLeaseResponse)(nil),   // 41: task.RenewTaskLeaseResponse
+	(*CompleteTaskRequest)(nil),      // 42: task.CompleteTaskRequest
+	(*CompleteTaskResponse)(nil),     // 43: task.CompleteTaskResponse
+	(*CommitTaskOutputRequest)(nil),  // 44: task.CommitTaskOutputRequest
+	(*CommitTaskOutputResponse)(nil), // 45: task.CommitTaskOutputResponse
+	(*DeadLetter)(nil),               // 46: task.DeadLetter
+	(*ListDeadLettersRequest)(nil),   // 47: task.ListDeadLettersRequest
+	(*ListDeadLettersResponse)(nil),  // 48: task.ListDeadLettersResponse
+}
+var file_task_proto_depIdxs = []int32{
+	0,  // 0: task.Task.type:type_name -> task.TaskType
+	1,  // 1: task.Task.state:type_name -> task.TaskState
+	10, // 2: task.Task.retry:type_name -> task.RetryPolicy
+	11, // 3: task.Task.attempts:type_name -> task.TaskAttempt
+	9,  // 4: task.Task.speculative:type_name -> task.SpeculativeAttempt
+	8,  // 5: task.Task.kills:type_name -> task.KillOrder
+	3,  // 6: task.TaskAttempt.outcome:type_name -> task.AttemptOutcome
+	2,  // 7: task.TaskAttempt.error_class:type_name -> task.ErrorClass
+	0,  // 8: task.SubmitTaskRequest.type:type_name -> task.TaskType
+	10, // 9: task.SubmitTaskRequest.retry:type_name -> task.RetryPolicy
+	7,  // 10: task.SubmitTaskResponse.task:type_name -> task.Task
+	7,  // 11: task.GetTaskResponse.task:type_name -> task.Task
+	1,  // 12: task.ListTasksRequest.state:type_name -> task.TaskState
+	7,  // 13: task.ListTasksResponse.tasks:type_name -> task.Task
+	7,  // 14: task.CancelTaskResponse.task:type_name -> task.Task
+	7,  // 15: task.GetJobEgressResponse.by_task:type_name -> task.Task
+	13, // 16: task.StageSpec.tasks:type_name -> task.SubmitTaskRequest
+	6,  // 17: task.SubmitJobRequest.failure_policy:type_name -> task.JobFailurePolicy
+	23, // 18: task.SubmitJobRequest.stages:type_name -> task.StageSpec
+	29, // 19: task.SubmitJobResponse.job:type_name -> task.Job
+	6,  // 20: task.SubmitMapReduceRequest.failure_policy:type_name -> task.JobFailurePolicy
+	27, // 21: task.SubmitMapReduceRequest.splits:type_name -> task.MapSplitSpec
+	10, // 22: task.SubmitMapReduceRequest.retry:type_name -> task.RetryPolicy
+	4,  // 23: task.Stage.state:type_name -> task.JobState
+	6,  // 24: task.Job.failure_policy:type_name -> task.JobFailurePolicy
+	4,  // 25: task.Job.state:type_name -> task.JobState
+	28, // 26: task.Job.stages:type_name -> task.Stage
+	5,  // 27: task.Job.type:type_name -> task.JobType
+	30, // 28: task.Job.map_reduce:type_name -> task.MapReduceStatus
+	31, // 29: task.MapReduceStatus.by_partition:type_name -> task.PartitionStatus
+	1,  // 30: task.PartitionStatus.reduce_state:type_name -> task.TaskState
+	29, // 31: task.GetJobResponse.job:type_name -> task.Job
+	7,  // 32: task.GetJobResponse.tasks:type_name -> task.Task
+	29, // 33: task.CancelJobResponse.job:type_name -> task.Job
+	4,  // 34: task.ListJobsRequest.state:type_name -> task.JobState
+	29, // 35: task.ListJobsResponse.jobs:type_name -> task.Job
+	0,  // 36: task.AcquireTaskRequest.types:type_name -> task.TaskType
+	7,  // 37: task.AcquireTaskResponse.task:type_name -> task.Task
+	8,  // 38: task.RenewTaskLeaseResponse.kill:type_name -> task.KillOrder
+	12, // 39: task.CompleteTaskRequest.outputs:type_name -> task.IntermediateOutput
+	2,  // 40: task.CompleteTaskRequest.error_class:type_name -> task.ErrorClass
+	0,  // 41: task.DeadLetter.type:type_name -> task.TaskType
+	11, // 42: task.DeadLetter.attempts:type_name -> task.TaskAttempt
+	46, // 43: task.ListDeadLettersResponse.dead_letters:type_name -> task.DeadLetter
+	13, // 44: task.TaskService.SubmitTask:input_type -> task.SubmitTaskRequest
+	15, // 45: task.TaskService.GetTask:input_type -> task.GetTaskRequest
+	17, // 46: task.TaskService.ListTasks:input_type -> task.ListTasksRequest
+	19, // 47: task.TaskService.CancelTask:input_type -> task.CancelTaskRequest
+	21, // 48: task.TaskService.GetJobEgress:input_type -> task.GetJobEgressRequest
+	24, // 49: task.TaskService.SubmitJob:input_type -> task.SubmitJobRequest
+	26, // 50: task.TaskService.SubmitMapReduce:input_type -> task.SubmitMapReduceRequest
+	32, // 51: task.TaskService.GetJob:input_type -> task.GetJobRequest
+	36, // 52: task.TaskService.ListJobs:input_type -> task.ListJobsRequest
+	34, // 53: task.TaskService.CancelJob:input_type -> task.CancelJobRequest
+	38, // 54: task.TaskService.AcquireTask:input_type -> task.AcquireTaskRequest
+	40, // 55: task.TaskService.RenewTaskLease:input_type -> task.RenewTaskLeaseRequest
+	42, // 56: task.TaskService.CompleteTask:input_type -> task.CompleteTaskRequest
+	47, // 57: task.TaskService.ListDeadLetters:input_type -> task.ListDeadLettersRequest
+	44, // 58: task.TaskService.CommitTaskOutput:input_type -> task.CommitTaskOutputRequest
+	14, // 59: task.TaskService.SubmitTask:output_type -> task.SubmitTaskResponse
+	16, // 60: task.TaskService.GetTask:output_type -> task.GetTaskResponse
+	18, // 61: task.TaskService.ListTasks:output_type -> task.ListTasksResponse
+	20, // 62: task.TaskService.CancelTask:output_type -> task.CancelTaskResponse
+	22, // 63: task.TaskService.GetJobEgress:output_type -> task.GetJobEgressResponse
+	25, // 64: task.TaskService.SubmitJob:output_type -> task.SubmitJobResponse
+	25, // 65: task.TaskService.SubmitMapReduce:output_type -> task.SubmitJobResponse
+	33, // 66: task.TaskService.GetJob:output_type -> task.GetJobResponse
+	37, // 67: task.TaskService.ListJobs:output_type -> task.ListJobsResponse
+	35, // 68: task.TaskService.CancelJob:output_type -> task.CancelJobResponse
+	39, // 69: task.TaskService.AcquireTask:output_type -> task.AcquireTaskResponse
+	41, // 70: task.TaskService.RenewTaskLease:output_type -> task.RenewTaskLeaseResponse
+	43, // 71: task.TaskService.CompleteTask:output_type -> task.CompleteTaskResponse
+	48, // 72: task.TaskService.ListDeadLetters:output_type -> task.ListDeadLettersResponse
+	45, // 73: task.TaskService.CommitTaskOutput:output_type -> task.CommitTaskOutputResponse
+	59, // [59:74] is the sub-list for method output_type
+	44, // [44:59] is the sub-list for method input_type
+	44, // [44:44] is the sub-list for extension type_name
+	44, // [44:44] is the sub-list for extension extendee
+	0,  // [0:44] is the sub-list for field type_name
+}
+
+func init() { file_task_proto_init() }
+func file_task_proto_init() {
+	if File_task_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_task_proto_rawDesc), len(file_task_proto_rawDesc)),
+			NumEnums:      7,
+			NumMessages:   42,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_task_proto_goTypes,
+		DependencyIndexes: file_task_proto_depIdxs,
+		EnumInfos:         file_task_proto_enumTypes,
+		MessageInfos:      file_task_proto_msgTypes,
+	}.Build()
+	File_task_proto = out.File
+	file_task_proto_goTypes = nil
+	file_task_proto_depIdxs = nil
+}
@@ -0,0 +1,272 @@
+package linearizability
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+// Model is a sequential specification of the system under test.
+type Model struct {
+	// Partition splits a history into independent sub-histories, such as one
+	// per key, which are checked separately. Nil checks the history whole.
+	Partition func(history []Operation) [][]Operation
+	// Init returns the initial state.
+	Init func() interface{}
+	// Step reports whether applying input to state may produce output, and the
+	// resulting state. output is nil for ambiguous operations; Step should
+	// accept any legal input then. States must be treated as immutable.
+	Step func(state, input, output interface{}) (bool, interface{})
+	// Equal compares states; nil uses ==.
+	Equal func(a, b interface{}) bool
+	// Describe renders an operation for failure reports; nil uses %v.
+	Describe func(op Operation) string
+}
+
+// Result is the outcome of Check.
+type Result struct {
+	Ok bool
+	// TimedOut is set when checking gave up; Ok then only says that no
+	// violation was found in the partitions that finished.
+	TimedOut bool
+	// Failed is the first non-linearizable partition, and Longest the longest
+	// sequence of its operations that could be linearized, in order.
+	Failed  []Operation
+	Longest []Operation
+
+	longest []int // indexes of Longest in Failed
+}
+
+// Check reports whether history is linearizable with respect to m. A timeout
+// of zero means no limit.
+func Check(m Model, history []Operation, timeout time.Duration) Result {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	parts := [][]Operation{history}
+	if m.Partition != nil {
+		parts = m.Partition(history)
+	}
+	res := Result{Ok: true}
+	for _, part := range parts {
+		ok, longest, timedOut := checkPartition(m, part, deadline)
+		if timedOut {
+			res.TimedOut = true
+			continue
+		}
+		if !ok {
+			res.Ok = false
+			res.Failed = part
+			res.longest = longest
+			res.Longest = make([]Operation, len(longest))
+			for i, id := range longest {
+				res.Longest[i] = part[id]
+			}
+			return res
+		}
+	}
+	return res
+}
+
+// describe renders op with m.Describe or a default format.
+func (m Model) describe(op Operation) string {
+	if m.Describe != nil {
+		return m.Describe(op)
+	}
+	if op.Ambiguous {
+		return fmt.Sprintf("%v → ?", op.Input)
+	}
+	return fmt.Sprintf("%v → %v", op.Input, op.Output)
+}
+
+// entry is a call or return event in the doubly linked history list.
+type entry struct {
+	id       int
+	isReturn bool
+	time     int64
+	match    *entry
+	prev     *entry
+	next     *entry
+}
+
+// makeEntries returns a sentinel-headed list of call and return events in time
+// order. Calls sort before returns at equal times, treating touching
+// operations as concurrent.
+func makeEntries(ops []Operation) *entry {
+	events := make([]*entry, 0, 2*len(ops))
+	for i, op := range ops {
+		call := &entry{id: i, time: op.Call}
+		ret := &entry{id: i, isReturn: true, time: op.Return, match: call}
+		call.match = ret
+		events = append(events, call, ret)
+	}
+	sort.SliceStable(events, func(a, b int) bool {
+		if events[a].time != events[b].time {
+			return events[a].time < events[b].time
+		}
+		return !events[a].isReturn && events[b].isReturn
+	})
+	head := &entry{id: -1}
+	prev := head
+	for _, e := range events {
+		prev.next, e.prev = e, prev
+		prev = e
+	}
+	return head
+}
+
+// lift removes call e and its return from the list.
+func lift(e *entry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	r := e.match
+	r.prev.next = r.next
+	if r.next != nil {
+		r.next.prev = r.prev
+	}
+}
+
+// unlift reinserts call e and its return where they were.
+func unlift(e *entry) {
+	r := e.match
+	r.prev.next = r
+	if r.next != nil {
+		r.next.prev = r
+	}
+	e.prev.next = e
+	e.next.prev = e
+}
+
+type bitset []uint64
+
+func newBitset(n int) bitset { return make(bitset, (n+63)/64) }
+
+func (b bitset) with(i int) bitset {
+	c := append(bitset(nil), b...)
+	c[i/64] |= 1 << (uint(i) % 64)
+	return c
+}
+
+func (b bitset) without(i int) bitset {
+	c := append(bitset(nil), b...)
+	c[i/64] &^= 1 << (uint(i) % 64)
+	return c
+}
+
+func (b bitset) hash() uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, w := range b {
+		for k := range buf {
+			buf[k] = byte(w >> (8 * k))
+		}
+		_, _ = h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+func (b bitset) equal(o bitset) bool {
+	for i := range b {
+		if b[i] != o[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type cacheEntry struct {
+	linearized bitset
+	state      interface{}
+}
+
+// checkPartition runs the just-in-time linearization search with memoization
+// of (linearized set, state) pairs already explored.
+func checkPartition(m Model, ops []Operation, deadline time.Time) (ok bool, longest []int, timedOut bool) {
+	equal := m.Equal
+	if equal == nil {
+		equal = func(a, b interface{}) bool { return a == b }
+	}
+	definite := 0
+	for _, op := range ops {
+		if !op.Ambiguous {
+			definite++
+		}
+	}
+
+	head := makeEntries(ops)
+	state := m.Init()
+	linearized := newBitset(len(ops))
+	cache := make(map[uint64][]cacheEntry)
+	type frame struct {
+		call  *entry
+		state interface{}
+	}
+	var stack []frame
+
+	e := head.next
+	for steps := 0; head.next != nil; steps++ {
+		// Ambiguous operations left over may simply never have happened.
+		if definite == 0 {
+			return true, nil, false
+		}
+		if steps%1024 == 0 && !deadline.IsZero() && time.Now().After(deadline) {
+			return false, nil, true
+		}
+		if !e.isReturn {
+			op := ops[e.id]
+			output := op.Output
+			if op.Ambiguous {
+				output = nil
+			}
+			if legal, next := m.Step(state, op.Input, output); legal {
+				lin := linearized.with(e.id)
+				if !cacheContains(cache, lin, next, equal) {
+					h := lin.hash()
+					cache[h] = append(cache[h], cacheEntry{lin, next})
+					stack = append(stack, frame{e, state})
+					state, linearized = next, lin
+					lift(e)
+					if !op.Ambiguous {
+						definite--
+					}
+					if len(stack) > len(longest) {
+						longest = longest[:0]
+						for _, f := range stack {
+							longest = append(longest, f.call.id)
+						}
+					}
+					e = head.next
+					continue
+				}
+			}
+			e = e.next
+			continue
+		}
+		// Reached an operation's return without linearizing it: backtrack.
+		if len(stack) == 0 {
+			return false, longest, false
+		}
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		state = top.state
+		linearized = linearized.without(top.call.id)
+		unlift(top.call)
+		if !ops[top.call.id].Ambiguous {
+			definite++
+		}
+		e = top.call.next
+	}
+	return true, nil, false
+}
+
+func cacheContains(cache map[uint64][]cacheEntry, lin bitset, state interface{},
+	equal func(a, b interface{}) bool) bool {
+	for _, c := range cache[lin.hash()] {
+		if c.linearized.equal(lin) && equal(c.state, state) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package linearizability
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func reg(id string) RegistryInput  { return RegistryInput{Op: OpRegister, WorkerID: id} }
+func read(id string) RegistryInput { return RegistryInput{Op: OpRead, WorkerID: id} }
+func update(id, status string) RegistryInput {
+	return RegistryInput{Op: OpUpdate, WorkerID: id, Status: status}
+}
+
+var (
+	ok     = RegistryOutput{Ok: true}
+	failed = RegistryOutput{}
+)
+
+func seen(status string) RegistryOutput { return RegistryOutput{Ok: status != "", Status: status} }
+
+func TestCheckSequentialAndConcurrent(t *testing.T) {
+	history := []Operation{
+		{ClientID: 0, Input: update("w1", "offline"), Output: failed, Call: 0, Return: 10},
+		{ClientID: 0, Input: reg("w1"), Output: ok, Call: 20, Return: 30},
+		// The update and the first read overlap, so the read may see either.
+		{ClientID: 1, Input: update("w1", "offline"), Output: ok, Call: 40, Return: 80},
+		{ClientID: 2, Input: read("w1"), Output: seen("online"), Call: 50, Return: 60},
+		{ClientID: 2, Input: read("w1"), Output: seen("offline"), Call: 90, Return: 100},
+		{ClientID: 3, Input: read("w2"), Output: seen(""), Call: 0, Return: 100},
+	}
+	if res := Check(RegistryModel, history, 0); !res.Ok {
+		t.Fatalf("expected linearizable, longest=%v", res.Longest)
+	}
+}
+
+func TestCheckStaleRead(t *testing.T) {
+	history := []Operation{
+		{ClientID: 0, Input: reg("w1"), Output: ok, Call: 0, Return: 10},
+		{ClientID: 0, Input: update("w1", "offline"), Output: ok, Call: 20, Return: 30},
+		// Starts after the update completed but observes the old status.
+		{ClientID: 1, Input: read("w1"), Output: seen("online"), Call: 40, Return: 50},
+	}
+	res := Check(RegistryModel, history, 0)
+	if res.Ok {
+		t.Fatal("stale read after a completed update must not be linearizable")
+	}
+	if len(res.Longest) != 2 {
+		t.Errorf("expected the two writes to linearize, got %d ops", len(res.Longest))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, RegistryModel, res); err != nil {
+		t.Fatalf("WriteHTML: %v", err)
+	}
+	for _, want := range []string{"1. register(w1) → ok", "read(w1) → online", `class="op bad"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("timeline missing %q", want)
+		}
+	}
+}
+
+func TestCheckAmbiguousOperations(t *testing.T) {
+	// A timed-out register may explain a later read...
+	history := []Operation{
+		{ClientID: 0, Input: reg("w1"), Call: 0, Return: math.MaxInt64, Ambiguous: true},
+		{ClientID: 1, Input: read("w1"), Output: seen("online"), Call: 50, Return: 60},
+	}
+	if res := Check(RegistryModel, history, 0); !res.Ok {
+		t.Error("ambiguous register should be allowed to take effect")
+	}
+	// ...or never have happened.
+	history[1].Output = seen("")
+	if res := Check(RegistryModel, history, 0); !res.Ok {
+		t.Error("ambiguous register should be allowed to never take effect")
+	}
+	// But it cannot take effect and then be undone.
+	history = append(history[:1],
+		Operation{ClientID: 1, Input: read("w1"), Output: seen("online"), Call: 50, Return: 60},
+		Operation{ClientID: 1, Input: read("w1"), Output: seen(""), Call: 70, Return: 80},
+	)
+	if res := Check(RegistryModel, history, 0); res.Ok {
+		t.Error("a worker cannot disappear after being observed")
+	}
+}
+
+func TestRecorderAndDump(t *testing.T) {
+	r := NewRecorder()
+	r.Invoke(0, reg("w1")).Complete(ok)
+	r.Invoke(1, update("w2", "offline")).Discard()
+	r.Invoke(1, read("w1")).Ambiguous()
+	history := r.History()
+	if len(history) != 2 || !history[1].Ambiguous || history[0].Return < history[0].Call {
+		t.Fatalf("unexpected history %+v", history)
+	}
+
+	dir := t.TempDir()
+	res := Result{Ok: false, Failed: history}
+	path, err := Dump(dir, RegistryModel, history, res)
+	if err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	if filepath.Base(path) != "timeline.html" {
+		t.Errorf("failed result should dump a timeline, got %s", path)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "history.json")); err != nil {
+		t.Errorf("history.json not written: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+// Package linearizability records concurrent client operation histories and
+// checks them against a sequential model, in the style of Porcupine: a
+// history is linearizable if every operation can be placed at a single point
+// between its invocation and completion such that the sequence is legal for
+// the model.
+package linearizability
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Operation is one client call. Call and Return are nanoseconds since the
+// recorder started. An Ambiguous operation's outcome is unknown — the client
+// timed out or lost its connection — so it may or may not have taken effect;
+// its Output is nil and its Return is open-ended.
+type Operation struct {
+	ClientID  int         `json:"client_id"`
+	Input     interface{} `json:"input"`
+	Output    interface{} `json:"output"`
+	Call      int64       `json:"call"`
+	Return    int64       `json:"return"`
+	Ambiguous bool        `json:"ambiguous,omitempty"`
+}
+
+// Recorder collects operations from concurrent clients.
+type Recorder struct {
+	start time.Time
+
+	mu  sync.Mutex
+	ops []Operation
+}
+
+// NewRecorder returns an empty recorder whose clock starts now.
+func NewRecorder() *Recorder {
+	return &Recorder{start: time.Now()}
+}
+
+// Pending is an invoked operation awaiting its outcome. Exactly one of
+// Complete, Ambiguous or Discard should be called.
+type Pending struct {
+	r        *Recorder
+	clientID int
+	input    interface{}
+	call     int64
+}
+
+// Invoke records the start of an operation.
+func (r *Recorder) Invoke(clientID int, input interface{}) *Pending {
+	return &Pending{r: r, clientID: clientID, input: input, call: r.now()}
+}
+
+// Complete records a definite outcome.
+func (p *Pending) Complete(output interface{}) {
+	p.r.add(Operation{ClientID: p.clientID, Input: p.input, Output: output, Call: p.call, Return: p.r.now()})
+}
+
+// Ambiguous records an operation that may or may not have taken effect.
+func (p *Pending) Ambiguous() {
+	p.r.add(Operation{ClientID: p.clientID, Input: p.input, Call: p.call, Return: math.MaxInt64, Ambiguous: true})
+}
+
+// Discard drops an operation known not to have taken effect, such as a write
+// rejected by a follower before reaching the log.
+func (p *Pending) Discard() {}
+
+// History returns a copy of the recorded operations.
+func (r *Recorder) History() []Operation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Operation(nil), r.ops...)
+}
+
+func (r *Recorder) add(op Operation) {
+	r.mu.Lock()
+	r.ops = append(r.ops, op)
+	r.mu.Unlock()
+}
+
+func (r *Recorder) now() int64 {
+	return int64(time.Since(r.start))
+}
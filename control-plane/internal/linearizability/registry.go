@@ -0,0 +1,92 @@
+package linearizability
+
+import "fmt"
+
+// Registry operations.
+const (
+	OpRegister = "register"
+	OpUpdate   = "update"
+	OpRead     = "read"
+)
+
+// RegistryInput is one operation against the worker registry.
+type RegistryInput struct {
+	Op       string `json:"op"`
+	WorkerID string `json:"worker_id"`
+	Status   string `json:"status,omitempty"` // OpUpdate only
+}
+
+// RegistryOutput is its result. Ok reports success for writes and presence
+// for reads; Status is the status a read observed.
+type RegistryOutput struct {
+	Ok     bool   `json:"ok"`
+	Status string `json:"status,omitempty"`
+}
+
+// registeredStatus is the status PipelineFSM gives a newly registered worker.
+const registeredStatus = "online"
+
+// RegistryModel specifies PipelineFSM's worker registry, one worker at a time:
+// registering sets the worker online, updating an unknown worker fails without
+// effect, and a read returns the current status ("" and !Ok if absent). The
+// state is the worker's status, "" while unregistered.
+var RegistryModel = Model{
+	Partition: func(history []Operation) [][]Operation {
+		byWorker := make(map[string][]Operation)
+		var order []string
+		for _, op := range history {
+			id := op.Input.(RegistryInput).WorkerID
+			if _, ok := byWorker[id]; !ok {
+				order = append(order, id)
+			}
+			byWorker[id] = append(byWorker[id], op)
+		}
+		parts := make([][]Operation, len(order))
+		for i, id := range order {
+			parts[i] = byWorker[id]
+		}
+		return parts
+	},
+	Init: func() interface{} { return "" },
+	Step: func(state, input, output interface{}) (bool, interface{}) {
+		status := state.(string)
+		in := input.(RegistryInput)
+		out, known := output.(RegistryOutput)
+		switch in.Op {
+		case OpRegister:
+			return !known || out.Ok, registeredStatus
+		case OpUpdate:
+			if status == "" {
+				return !known || !out.Ok, status
+			}
+			return !known || out.Ok, in.Status
+		case OpRead:
+			return !known || (out.Ok == (status != "") && out.Status == status), status
+		}
+		return false, status
+	},
+	Describe: func(op Operation) string {
+		in := op.Input.(RegistryInput)
+		var call string
+		switch in.Op {
+		case OpUpdate:
+			call = fmt.Sprintf("update(%s, %s)", in.WorkerID, in.Status)
+		default:
+			call = fmt.Sprintf("%s(%s)", in.Op, in.WorkerID)
+		}
+		if op.Ambiguous {
+			return call + " → ?"
+		}
+		out := op.Output.(RegistryOutput)
+		switch {
+		case in.Op == OpRead && out.Ok:
+			return fmt.Sprintf("%s → %s", call, out.Status)
+		case in.Op == OpRead:
+			return call + " → absent"
+		case out.Ok:
+			return call + " → ok"
+		default:
+			return call + " → failed"
+		}
+	},
+}
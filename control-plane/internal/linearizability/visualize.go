@@ -0,0 +1,138 @@
+package linearizability
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// WriteJSON writes history as JSON, for reloading or external tools.
+func WriteJSON(w io.Writer, history []Operation) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(history)
+}
+
+// WriteHTML renders a failed Result as a self-contained timeline: one row per
+// client, one bar per operation from call to return. Bars in the longest
+// linearizable sequence are green and numbered in linearization order; the
+// rest — including the operation that could not be placed — are red, and
+// ambiguous operations are hatched to the end of the history.
+func WriteHTML(w io.Writer, m Model, res Result) error {
+	ops := res.Failed
+	if len(ops) == 0 {
+		_, err := io.WriteString(w, "<!doctype html><p>history is linearizable</p>\n")
+		return err
+	}
+
+	order := make(map[int]int, len(res.longest))
+	for i, idx := range res.longest {
+		order[idx] = i + 1
+	}
+	var start, end int64 = math.MaxInt64, 0
+	for _, op := range ops {
+		start = min(start, op.Call)
+		if !op.Ambiguous {
+			end = max(end, op.Return)
+		}
+		end = max(end, op.Call)
+	}
+	span := float64(max(end-start, 1)) * 1.05
+
+	clients := make(map[int]int)
+	var ids []int
+	for _, op := range ops {
+		if _, ok := clients[op.ClientID]; !ok {
+			clients[op.ClientID] = 0
+			ids = append(ids, op.ClientID)
+		}
+	}
+	sort.Ints(ids)
+	for row, id := range ids {
+		clients[id] = row
+	}
+
+	type bar struct {
+		Left, Width, Top float64
+		Label, Class     string
+	}
+	bars := make([]bar, len(ops))
+	for i, op := range ops {
+		ret := op.Return
+		class, label := "bad", m.describe(op)
+		if n, ok := order[i]; ok {
+			class, label = "ok", fmt.Sprintf("%d. %s", n, label)
+		}
+		if op.Ambiguous {
+			ret = start + int64(span)
+			class += " ambiguous"
+		}
+		bars[i] = bar{
+			Left:  100 * float64(op.Call-start) / span,
+			Width: max(100*float64(ret-op.Call)/span, 0.5),
+			Top:   float64(clients[op.ClientID]*36 + 8),
+			Label: label,
+			Class: class,
+		}
+	}
+	return timelineTmpl.Execute(w, struct {
+		Bars   []bar
+		Height int
+		SpanMs float64
+	}{bars, len(ids)*36 + 16, float64(end-start) / 1e6})
+}
+
+var timelineTmpl = template.Must(template.New("timeline").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>non-linearizable history</title>
+<style>
+body { font: 12px sans-serif; margin: 16px; }
+#timeline { position: relative; border: 1px solid #ccc; height: {{.Height}}px; }
+.op { position: absolute; height: 26px; overflow: hidden; white-space: nowrap;
+      border-radius: 3px; padding: 0 4px; line-height: 26px; box-sizing: border-box; }
+.ok { background: #b7e1b0; border: 1px solid #4a9a3c; }
+.bad { background: #f3b6b6; border: 1px solid #b23b3b; }
+.ambiguous { background-image: repeating-linear-gradient(45deg, transparent 0 6px, rgba(0,0,0,.08) 6px 12px); }
+</style></head><body>
+<p>Rows are clients; time runs left to right over {{printf "%.1f" .SpanMs}}ms.
+Green operations linearize in the numbered order; no legal position exists for the red ones after them.</p>
+<div id="timeline">
+{{range .Bars}}<div class="op {{.Class}}" style="left:{{.Left}}%;width:{{.Width}}%;top:{{.Top}}px" title="{{.Label}}">{{.Label}}</div>
+{{end}}</div></body></html>
+`))
+
+// Dump writes history.json and, if res failed, timeline.html into dir and
+// returns the HTML path (or the JSON path when there is no failure).
+func Dump(dir string, m Model, history []Operation, res Result) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	jsonPath := filepath.Join(dir, "history.json")
+	if err := writeFile(jsonPath, func(w io.Writer) error { return WriteJSON(w, history) }); err != nil {
+		return "", err
+	}
+	if res.Ok {
+		return jsonPath, nil
+	}
+	htmlPath := filepath.Join(dir, "timeline.html")
+	if err := writeFile(htmlPath, func(w io.Writer) error { return WriteHTML(w, m, res) }); err != nil {
+		return "", err
+	}
+	return htmlPath, nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
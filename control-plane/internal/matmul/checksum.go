@@ -0,0 +1,148 @@
+package matmul
+
+import (
+	"fmt"
+	"math"
+)
+
+// ChecksumPlan checks C without recomputing it (Freivalds' check): for each
+// block and probe vector x, C[i,k]·x must equal Σ_j A[i,j]·(B[j,k]·x), which
+// costs matrix-vector products instead of a second multiplication. The first
+// probe of every block is the all-ones vector, so it checks C's row sums; the
+// others are random ±1 vectors, which catch errors that cancel in a sum.
+type ChecksumPlan struct {
+	// Tolerance bounds |got-want| / max(1, |want|) for each element of a
+	// probe result.
+	Tolerance float64 `json:"tolerance"`
+	Checks    []Check `json:"checks"` // one per block of C, row-major
+}
+
+// Check validates one block of C.
+type Check struct {
+	I     int      `json:"i"`
+	K     int      `json:"k"`
+	C     string   `json:"c"`
+	Rows  int      `json:"rows"`
+	Cols  int      `json:"cols"`
+	Seeds []uint64 `json:"seeds"` // one probe vector each; see ProbeVector
+	Terms []Term   `json:"terms"` // the block products C[i,k] sums, in j order
+}
+
+// Term is one block product A[i,j]×B[j,k].
+type Term struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+func (m *Manifest) checksumPlan() ChecksumPlan {
+	p := ChecksumPlan{Tolerance: tolerance(m.Spec.DType, m.Spec.K)}
+	for _, r := range m.Reduces {
+		c := m.C[r.Partition]
+		chk := Check{I: r.I, K: r.K, C: c.URI, Rows: c.Rows, Cols: c.Cols, Seeds: make([]uint64, m.Spec.Probes)}
+		for n := 1; n < m.Spec.Probes; n++ {
+			chk.Seeds[n] = splitmix64(m.Spec.Seed ^ splitmix64(uint64(r.Partition)<<16|uint64(n)))
+			if chk.Seeds[n] == 0 { // reserved for the all-ones probe
+				chk.Seeds[n] = 1
+			}
+		}
+		// Plan lays out each partition's maps contiguously, in j order.
+		ib := m.Grid.InnerBlocks
+		for _, t := range m.Maps[r.Partition*ib : (r.Partition+1)*ib] {
+			chk.Terms = append(chk.Terms, Term{A: t.A, B: t.B})
+		}
+		p.Checks = append(p.Checks, chk)
+	}
+	return p
+}
+
+// ProbeVector returns the n-element probe vector for seed. Seed 0 is the
+// all-ones vector. Otherwise element t is +1 when the top bit of the t-th
+// output of a splitmix64 generator started at seed is clear and -1 when it is
+// set, so a validator in another language can reproduce it.
+func ProbeVector(seed uint64, n int) []float64 {
+	x := make([]float64, n)
+	state := seed
+	for t := range x {
+		x[t] = 1
+		if seed == 0 {
+			continue
+		}
+		state += 0x9e3779b97f4a7c15
+		if mix(state)>>63 == 1 {
+			x[t] = -1
+		}
+	}
+	return x
+}
+
+// splitmix64 returns the first output of a splitmix64 generator seeded with s.
+func splitmix64(s uint64) uint64 {
+	return mix(s + 0x9e3779b97f4a7c15)
+}
+
+func mix(z uint64) uint64 {
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// Verify runs every probe of c. load returns a block's elements row-major,
+// already decoded from storage.
+func (p *ChecksumPlan) Verify(c Check, load func(uri string) ([]float64, error)) error {
+	block, err := load(c.C)
+	if err != nil {
+		return err
+	}
+	if len(block) != c.Rows*c.Cols {
+		return fmt.Errorf("matmul: %s has %d elements, want %dx%d", c.C, len(block), c.Rows, c.Cols)
+	}
+	probes := make([][]float64, len(c.Seeds))
+	want := make([][]float64, len(c.Seeds))
+	for n, seed := range c.Seeds {
+		probes[n] = ProbeVector(seed, c.Cols)
+		want[n] = make([]float64, c.Rows)
+	}
+	for _, term := range c.Terms {
+		a, err := load(term.A)
+		if err != nil {
+			return err
+		}
+		b, err := load(term.B)
+		if err != nil {
+			return err
+		}
+		inner := len(a) / c.Rows
+		if len(a) != c.Rows*inner || len(b) != inner*c.Cols {
+			return fmt.Errorf("matmul: %s (%d elements) and %s (%d elements) do not multiply into %dx%d",
+				term.A, len(a), term.B, len(b), c.Rows, c.Cols)
+		}
+		for n, x := range probes {
+			for r, v := range mulVec(a, c.Rows, inner, mulVec(b, inner, c.Cols, x)) {
+				want[n][r] += v
+			}
+		}
+	}
+	for n, seed := range c.Seeds {
+		got := mulVec(block, c.Rows, c.Cols, probes[n])
+		for r := range got {
+			if math.Abs(got[r]-want[n][r]) > p.Tolerance*max(1, math.Abs(want[n][r])) {
+				return fmt.Errorf("matmul: C[%d,%d] failed probe %d (seed %d) at row %d: got %g, want %g",
+					c.I, c.K, n, seed, r, got[r], want[n][r])
+			}
+		}
+	}
+	return nil
+}
+
+// mulVec multiplies the row-major rows×cols matrix a by x.
+func mulVec(a []float64, rows, cols int, x []float64) []float64 {
+	out := make([]float64, rows)
+	for r := range rows {
+		var sum float64
+		for c, v := range a[r*cols : (r+1)*cols] {
+			sum += v * x[c]
+		}
+		out[r] = sum
+	}
+	return out
+}
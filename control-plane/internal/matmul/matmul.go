@@ -0,0 +1,244 @@
+// Package matmul plans the distributed matrix multiplication C = A×B that
+// validates the MapReduce core: it slices A and B into square blocks under a
+// storage prefix, lays out one map task per block product A[i,j]×B[j,k] and
+// one reduce per block of C summing the products over j, and writes down how
+// to check C without recomputing it.
+//
+// Storage layout under Spec.Prefix, with block coordinates zero-padded:
+//
+//	A/<i>-<j>        block (i, j) of A
+//	B/<j>-<k>        block (j, k) of B
+//	C/part-<r>       block (i, k) of C, written by the reduce for partition r
+//	manifest.json    the Manifest, so a worker can find its task's entry
+//
+// Blocks are raw row-major arrays of Spec.DType, little-endian. Edge blocks
+// are smaller when a dimension is not a multiple of the block size.
+//
+// Map task n reads [A[i,j], B[j,k]] and reports its product as its output for
+// partition i*ColBlocks+k; its outputs for every other partition are empty.
+// The reduce for that partition therefore reads the InnerBlocks products of
+// C[i,k], in j order, and writes their sum.
+package matmul
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// maxCBlocks keeps C within the control plane's limit of 1024 partitions per
+// MapReduce job, one per block.
+const maxCBlocks = 1024
+
+// elemBytes is the size of one element of each supported DType.
+var elemBytes = map[string]int64{"float32": 4, "float64": 8}
+
+// relTolerance is the relative error a checksum may show for each DType,
+// before scaling by √K.
+var relTolerance = map[string]float64{"float32": 1e-5, "float64": 1e-12}
+
+// Spec describes one multiplication.
+type Spec struct {
+	JobID     string `json:"job_id"`
+	M         int    `json:"m"` // A is M×K
+	K         int    `json:"k"`
+	N         int    `json:"n"` // B is K×N
+	BlockSize int    `json:"block_size"`
+	DType     string `json:"dtype"`  // "float32" or "float64"; "float64" when empty
+	Prefix    string `json:"prefix"` // e.g. "s3://bucket/matmul/run-1/"
+	Probes    int    `json:"probes"` // checksum probe vectors per C block; 2 when zero
+	Seed      uint64 `json:"seed"`   // seeds the random probe vectors
+}
+
+// Grid counts blocks along each dimension: A is RowBlocks×InnerBlocks
+// blocks, B InnerBlocks×ColBlocks and C RowBlocks×ColBlocks.
+type Grid struct {
+	RowBlocks   int `json:"row_blocks"`
+	InnerBlocks int `json:"inner_blocks"`
+	ColBlocks   int `json:"col_blocks"`
+}
+
+// Block is one block of A, B or C.
+type Block struct {
+	Row   int    `json:"row"` // block coordinates
+	Col   int    `json:"col"`
+	Rows  int    `json:"rows"` // element dimensions
+	Cols  int    `json:"cols"`
+	URI   string `json:"uri"`
+	Bytes int64  `json:"bytes"`
+}
+
+// MapTask multiplies A[I,J] by B[J,K].
+type MapTask struct {
+	TaskID    string `json:"task_id"`
+	I         int    `json:"i"`
+	J         int    `json:"j"`
+	K         int    `json:"k"`
+	A         string `json:"a"` // first input
+	B         string `json:"b"` // second input
+	Partition int    `json:"partition"`
+}
+
+// ReduceTask sums the products of C[I,K].
+type ReduceTask struct {
+	TaskID    string   `json:"task_id"`
+	I         int      `json:"i"`
+	K         int      `json:"k"`
+	Partition int      `json:"partition"`
+	Maps      []string `json:"maps"` // map task IDs whose products it sums, in j order
+	Output    string   `json:"output"`
+}
+
+// Manifest is the full plan for a Spec. A, B and C list blocks row-major.
+type Manifest struct {
+	Spec      Spec         `json:"spec"`
+	Grid      Grid         `json:"grid"`
+	A         []Block      `json:"a"`
+	B         []Block      `json:"b"`
+	C         []Block      `json:"c"`
+	Maps      []MapTask    `json:"maps"`
+	Reduces   []ReduceTask `json:"reduces"`
+	Checksums ChecksumPlan `json:"checksums"`
+}
+
+// Plan validates spec and lays out its blocks and tasks.
+func Plan(spec Spec) (*Manifest, error) {
+	if spec.DType == "" {
+		spec.DType = "float64"
+	}
+	if spec.Probes == 0 {
+		spec.Probes = 2
+	}
+	_, ok := elemBytes[spec.DType]
+	switch {
+	case spec.JobID == "":
+		return nil, fmt.Errorf("matmul: a job ID is required")
+	case spec.M <= 0 || spec.K <= 0 || spec.N <= 0:
+		return nil, fmt.Errorf("matmul: dimensions must be positive, got %dx%d × %dx%d", spec.M, spec.K, spec.K, spec.N)
+	case spec.BlockSize <= 0:
+		return nil, fmt.Errorf("matmul: block size must be positive")
+	case !ok:
+		return nil, fmt.Errorf("matmul: unsupported dtype %q (want float32 or float64)", spec.DType)
+	case spec.Probes < 0:
+		return nil, fmt.Errorf("matmul: probes must not be negative")
+	case spec.Prefix == "":
+		return nil, fmt.Errorf("matmul: a storage prefix is required")
+	}
+	if !strings.HasSuffix(spec.Prefix, "/") {
+		spec.Prefix += "/"
+	}
+
+	m := &Manifest{Spec: spec, Grid: Grid{
+		RowBlocks:   blocks(spec.M, spec.BlockSize),
+		InnerBlocks: blocks(spec.K, spec.BlockSize),
+		ColBlocks:   blocks(spec.N, spec.BlockSize),
+	}}
+	g := m.Grid
+	if n := g.RowBlocks * g.ColBlocks; n > maxCBlocks {
+		return nil, fmt.Errorf("matmul: C would have %d blocks, more than the %d partitions a job may have; use a larger block size",
+			n, maxCBlocks)
+	}
+	m.A = m.layout(spec.M, spec.K, func(i, j int) string { return fmt.Sprintf("A/%05d-%05d", i, j) })
+	m.B = m.layout(spec.K, spec.N, func(j, k int) string { return fmt.Sprintf("B/%05d-%05d", j, k) })
+	m.C = m.layout(spec.M, spec.N, func(i, k int) string {
+		return internalraft.ReduceOutputURI("C/", i*g.ColBlocks+k)
+	})
+
+	for i := range g.RowBlocks {
+		for k := range g.ColBlocks {
+			r := ReduceTask{
+				TaskID:    internalraft.ReduceTaskID(spec.JobID, i*g.ColBlocks+k),
+				I:         i,
+				K:         k,
+				Partition: i*g.ColBlocks + k,
+				Output:    m.C[i*g.ColBlocks+k].URI,
+			}
+			for j := range g.InnerBlocks {
+				t := MapTask{
+					TaskID:    internalraft.MapTaskID(spec.JobID, len(m.Maps)),
+					I:         i,
+					J:         j,
+					K:         k,
+					A:         m.A[i*g.InnerBlocks+j].URI,
+					B:         m.B[j*g.ColBlocks+k].URI,
+					Partition: r.Partition,
+				}
+				m.Maps = append(m.Maps, t)
+				r.Maps = append(r.Maps, t.TaskID)
+			}
+			m.Reduces = append(m.Reduces, r)
+		}
+	}
+	m.Checksums = m.checksumPlan()
+	return m, nil
+}
+
+// blocks is the number of size-wide blocks needed to cover n.
+func blocks(n, size int) int {
+	return (n + size - 1) / size
+}
+
+// layout slices a rows×cols matrix into blocks named by key under the prefix.
+func (m *Manifest) layout(rows, cols int, key func(r, c int) string) []Block {
+	bs := m.Spec.BlockSize
+	out := make([]Block, 0, blocks(rows, bs)*blocks(cols, bs))
+	for r := range blocks(rows, bs) {
+		for c := range blocks(cols, bs) {
+			b := Block{
+				Row:  r,
+				Col:  c,
+				Rows: min(bs, rows-r*bs),
+				Cols: min(bs, cols-c*bs),
+				URI:  m.Spec.Prefix + key(r, c),
+			}
+			b.Bytes = int64(b.Rows) * int64(b.Cols) * elemBytes[m.Spec.DType]
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// URI returns where the manifest itself is stored.
+func (m *Manifest) URI() string {
+	return m.Spec.Prefix + "manifest.json"
+}
+
+// InputBytes is the total size of A and B.
+func (m *Manifest) InputBytes() int64 {
+	var n int64
+	for _, b := range m.A {
+		n += b.Bytes
+	}
+	for _, b := range m.B {
+		n += b.Bytes
+	}
+	return n
+}
+
+// Request builds the SubmitMapReduce call for the manifest's tasks. The
+// caller may still set the failure policy and placement fields.
+func (m *Manifest) Request() *taskpb.SubmitMapReduceRequest {
+	req := &taskpb.SubmitMapReduceRequest{
+		JobId:        m.Spec.JobID,
+		Partitions:   uint32(len(m.Reduces)),
+		OutputPrefix: m.Spec.Prefix + "C/",
+	}
+	g := m.Grid
+	for _, t := range m.Maps {
+		a, b := m.A[t.I*g.InnerBlocks+t.J], m.B[t.J*g.ColBlocks+t.K]
+		req.Splits = append(req.Splits, &taskpb.MapSplitSpec{
+			InputUris:  []string{a.URI, b.URI},
+			InputBytes: []int64{a.Bytes, b.Bytes},
+		})
+	}
+	return req
+}
+
+// tolerance scales the DType's relative tolerance by √K: rounding error in a
+// K-term dot product grows roughly with its square root.
+func tolerance(dtype string, k int) float64 {
+	return relTolerance[dtype] * math.Sqrt(float64(k))
+}
@@ -0,0 +1,148 @@
+package matmul
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+func TestPlanLayout(t *testing.T) {
+	m, err := Plan(Spec{JobID: "mm", M: 5, K: 7, N: 3, BlockSize: 2, Prefix: "s3://data/mm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Grid != (Grid{RowBlocks: 3, InnerBlocks: 4, ColBlocks: 2}) {
+		t.Fatalf("grid = %+v", m.Grid)
+	}
+	if len(m.A) != 12 || len(m.B) != 8 || len(m.C) != 6 || len(m.Maps) != 24 || len(m.Reduces) != 6 {
+		t.Fatalf("got %d/%d/%d blocks, %d maps, %d reduces", len(m.A), len(m.B), len(m.C), len(m.Maps), len(m.Reduces))
+	}
+	if edge := m.A[11]; edge.Rows != 1 || edge.Cols != 1 || edge.Bytes != 8 || edge.URI != "s3://data/mm/A/00002-00003" {
+		t.Errorf("last A block = %+v", edge)
+	}
+	if m.InputBytes() != (5*7+7*3)*8 {
+		t.Errorf("input bytes = %d", m.InputBytes())
+	}
+
+	req := m.Request()
+	if req.Partitions != 6 || len(req.Splits) != 24 || req.OutputPrefix != "s3://data/mm/C/" {
+		t.Fatalf("request = %+v", req)
+	}
+	for n, mt := range m.Maps {
+		sp := req.Splits[n]
+		if mt.TaskID != internalraft.MapTaskID("mm", n) || sp.InputUris[0] != mt.A || sp.InputUris[1] != mt.B {
+			t.Fatalf("map %d = %+v, split %v", n, mt, sp.InputUris)
+		}
+	}
+	r := m.Reduces[5]
+	if r.I != 2 || r.K != 1 || r.TaskID != internalraft.ReduceTaskID("mm", 5) ||
+		r.Output != internalraft.ReduceOutputURI(req.OutputPrefix, 5) || len(r.Maps) != 4 {
+		t.Errorf("last reduce = %+v", r)
+	}
+}
+
+func TestPlanRejects(t *testing.T) {
+	ok := Spec{JobID: "mm", M: 4, K: 4, N: 4, BlockSize: 2, Prefix: "s3://data/mm/"}
+	for _, tc := range []struct {
+		edit func(*Spec)
+		want string
+	}{
+		{func(s *Spec) { s.JobID = "" }, "job ID"},
+		{func(s *Spec) { s.K = 0 }, "dimensions"},
+		{func(s *Spec) { s.DType = "int8" }, "dtype"},
+		{func(s *Spec) { s.Prefix = "" }, "prefix"},
+		{func(s *Spec) { s.M, s.N, s.BlockSize = 4096, 4096, 64 }, "larger block size"},
+	} {
+		spec := ok
+		tc.edit(&spec)
+		if _, err := Plan(spec); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Plan(%+v) = %v, want error containing %q", spec, err, tc.want)
+		}
+	}
+}
+
+// TestChecksumPlan runs the manifest's tasks in memory and checks C with the
+// plan, then corrupts one element.
+func TestChecksumPlan(t *testing.T) {
+	m, err := Plan(Spec{JobID: "mm", M: 6, K: 5, N: 7, BlockSize: 3, Prefix: "mem://", Probes: 3, Seed: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	store := map[string][]float64{}
+	for _, b := range append(append([]Block{}, m.A...), m.B...) {
+		v := make([]float64, b.Rows*b.Cols)
+		for e := range v {
+			v[e] = rng.Float64()*2 - 1
+		}
+		store[b.URI] = v
+	}
+	products := map[string][]float64{}
+	for _, mt := range m.Maps {
+		a, b := m.A[mt.I*m.Grid.InnerBlocks+mt.J], m.B[mt.J*m.Grid.ColBlocks+mt.K]
+		p := make([]float64, a.Rows*b.Cols)
+		for r := range a.Rows {
+			for c := range b.Cols {
+				for x := range a.Cols {
+					p[r*b.Cols+c] += store[a.URI][r*a.Cols+x] * store[b.URI][x*b.Cols+c]
+				}
+			}
+		}
+		products[mt.TaskID] = p
+	}
+	for _, r := range m.Reduces {
+		sum := make([]float64, len(products[r.Maps[0]]))
+		for _, id := range r.Maps {
+			for e, v := range products[id] {
+				sum[e] += v
+			}
+		}
+		store[r.Output] = sum
+	}
+	load := func(uri string) ([]float64, error) {
+		v, ok := store[uri]
+		if !ok {
+			return nil, fmt.Errorf("no object %s", uri)
+		}
+		return v, nil
+	}
+
+	if len(m.Checksums.Checks) != len(m.C) {
+		t.Fatalf("%d checks for %d blocks", len(m.Checksums.Checks), len(m.C))
+	}
+	for _, c := range m.Checksums.Checks {
+		if c.Seeds[0] != 0 || c.Seeds[1] == 0 || c.Seeds[1] == c.Seeds[2] {
+			t.Fatalf("C[%d,%d] seeds = %v", c.I, c.K, c.Seeds)
+		}
+		if err := m.Checksums.Verify(c, load); err != nil {
+			t.Errorf("correct C failed: %v", err)
+		}
+	}
+
+	c := m.Checksums.Checks[3]
+	store[c.C][1] += 1e-3
+	if err := m.Checksums.Verify(c, load); err == nil {
+		t.Error("a corrupted block passed its checksums")
+	}
+}
+
+func TestProbeVector(t *testing.T) {
+	for _, v := range ProbeVector(0, 4) {
+		if v != 1 {
+			t.Fatalf("seed 0 should be all ones, got %v", ProbeVector(0, 4))
+		}
+	}
+	x := ProbeVector(7, 64)
+	var neg int
+	for _, v := range x {
+		if v == -1 {
+			neg++
+		}
+	}
+	if neg == 0 || neg == 64 || fmt.Sprint(x) != fmt.Sprint(ProbeVector(7, 64)) {
+		t.Errorf("probe vector for seed 7 = %v", x)
+	}
+}
@@ -27,4 +27,168 @@ var (
 		Help:    "Milliseconds from raft.Apply() call to commit confirmation.",
 		Buckets: prometheus.ExponentialBuckets(1, 2, 12), // 1ms → ~4096ms
 	})
+
+	RaftTLSHandshakeFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "raft_tls_handshake_failures_total",
+		Help: "Raft transport TLS handshakes that failed or presented an untrusted peer, by direction (inbound, outbound).",
+	}, []string{"direction"})
+
+	RaftTLSReloadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "raft_tls_reloads_total",
+		Help: "Raft TLS certificate reloads triggered by changed files, by result (success, error).",
+	}, []string{"result"})
+
+	// RaftGRPCRPCDurationMs covers outbound RPCs on the gRPC Raft transport,
+	// including pipelined AppendEntries from send to response.
+	RaftGRPCRPCDurationMs = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "raft_grpc_rpc_duration_ms",
+		Help:    "Milliseconds spent in outbound gRPC Raft transport RPCs, by rpc.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 14), // 1ms → ~16s
+	}, []string{"rpc"})
+
+	RaftGRPCRPCErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "raft_grpc_rpc_errors_total",
+		Help: "Outbound gRPC Raft transport RPCs that failed, by rpc.",
+	}, []string{"rpc"})
+
+	RaftGRPCRPCsReceivedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "raft_grpc_rpcs_received_total",
+		Help: "Inbound gRPC Raft transport RPCs handed to Raft, by rpc.",
+	}, []string{"rpc"})
+
+	// WorkerPhi is the phi-accrual suspicion level last computed for each live
+	// (not offline or quarantined) worker on the leader. Capped at 100 once the
+	// probability underflows.
+	WorkerPhi = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "worker_phi",
+		Help: "Phi-accrual failure detector suspicion level per worker (leader only).",
+	}, []string{"worker_id"})
+
+	WorkerStatusTransitionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "worker_status_transitions_total",
+		Help: "Worker status changes (online/suspect/offline) committed through Raft by the agent registry.",
+	}, []string{"status"})
+
+	WorkerQuarantinesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "worker_quarantines_total",
+		Help: "Total number of times a flapping worker was quarantined.",
+	})
+
+	// WorkerQuarantined is 1 for each currently quarantined worker (leader only).
+	WorkerQuarantined = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "worker_quarantined",
+		Help: "1 while a worker is quarantined, labelled with the quarantine reason code.",
+	}, []string{"worker_id", "reason"})
+
+	WorkersReapedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "workers_reaped_total",
+		Help: "Total number of long-dead workers removed from the FSM by the leader.",
+	})
+
+	WorkerFencedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "worker_fenced_total",
+		Help: "Total number of requests rejected because they carried a stale registration epoch.",
+	})
+
+	WorkerAuthFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "worker_auth_failures_total",
+		Help: "Worker and operator calls rejected by authentication, by reason (join_token, credential, revoked, takeover, operator).",
+	}, []string{"reason"})
+
+	CertsIssuedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "certs_issued_total",
+		Help: "Certificate signing requests handled by the built-in CA, by kind (node, worker) and result (issued, rejected).",
+	}, []string{"kind", "result"})
+
+	TasksSubmittedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tasks_submitted_total",
+		Help: "Tasks accepted through TaskService.SubmitTask, by type (map, reduce, generic).",
+	}, []string{"type"})
+
+	JobsSubmittedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "jobs_submitted_total",
+		Help: "Job DAGs accepted through TaskService.SubmitJob.",
+	})
+
+	TasksCancelledTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tasks_cancelled_total",
+		Help: "Tasks cancelled through TaskService.CancelTask.",
+	})
+
+	JobsCancelledTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "jobs_cancelled_total",
+		Help: "Jobs cancelled through TaskService.CancelJob.",
+	})
+
+	TasksAssignedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tasks_assigned_total",
+		Help: "Task assignments committed by the leader in response to AcquireTask.",
+	})
+
+	TasksCompletedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tasks_completed_total",
+		Help: "Task results reported through CompleteTask, by result (succeeded, failed).",
+	}, []string{"result"})
+
+	TaskLeaseExpiriesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "task_lease_expiries_total",
+		Help: "Running task attempts ended because their worker stopped renewing the lease.",
+	})
+
+	TaskRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "task_retries_total",
+		Help: "Failed task attempts the leader put back to pending for a retry, by outcome (failed, lease_expired).",
+	}, []string{"outcome"})
+
+	TaskDeadlineKillsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "task_deadline_kills_total",
+		Help: "Running task attempts killed by the leader for passing their task's timeout.",
+	})
+
+	// TaskKillRevocationsTotal counts kill orders whose grace period ran out,
+	// by whether the attempt was still running and had its lease revoked.
+	TaskKillRevocationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "task_kill_revocations_total",
+		Help: "Kill orders whose grace period ran out, by result (revoked: the attempt still held its lease; expired).",
+	}, []string{"result"})
+
+	DeadLetterTasks = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dead_letter_tasks",
+		Help: "Tasks on the dead-letter list: failed with a fatal error or out of attempts.",
+	})
+
+	// TaskDurationSeconds covers the winning attempt of each succeeded task,
+	// from assignment to completion.
+	TaskDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "task_duration_seconds",
+		Help:    "Seconds the succeeded attempt of a task ran, by type (map, reduce, generic).",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 16), // 100ms → ~55m
+	}, []string{"type"})
+
+	SpeculativeAttemptsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "speculative_attempts_total",
+		Help: "Speculative task attempts, by result (launched; won or lost against the straggler).",
+	}, []string{"result"})
+
+	TasksPlacedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tasks_placed_total",
+		Help: "Tasks assigned by the scheduling loop, by placement policy.",
+	}, []string{"policy"})
+
+	// TaskEgressBytesTotal estimates input bytes read across clouds, from the
+	// declared input sizes of assigned tasks.
+	TaskEgressBytesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "task_egress_bytes_total",
+		Help: "Estimated task input bytes read from another cloud, by source and destination cloud.",
+	}, []string{"from", "to"})
+
+	TaskEgressCostTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "task_egress_cost_total",
+		Help: "Estimated egress cost of assigned tasks' cross-cloud input reads.",
+	})
+
+	CloudRTTSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cloud_rtt_seconds",
+		Help: "Smoothed round-trip time measured from this node's cloud to another.",
+	}, []string{"from", "to"})
 )
@@ -0,0 +1,377 @@
+// Package pki implements the control plane's built-in certificate authority.
+//
+// CA roots live in the Raft FSM with their private keys sealed by a cluster-wide
+// key, so every control plane node can take over signing after a failover
+// without a separate PKI service. Only the leader signs: each issued
+// certificate is recorded through Raft before it is returned, which makes the
+// FSM a complete audit log of issued and revoked serials.
+package pki
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// Certificate kinds. Node certificates serve and dial the Raft transport;
+// worker certificates authenticate workers to the control plane.
+const (
+	KindNode   = "node"
+	KindWorker = "worker"
+)
+
+const raftApplyTimeout = 5 * time.Second
+
+var (
+	ErrNotLeader    = errors.New("not the raft leader")
+	ErrNoActiveRoot = errors.New("certificate authority not initialised")
+	ErrInvalidCSR   = errors.New("invalid certificate signing request")
+	ErrRootInUse    = errors.New("CA root still has unexpired certificates")
+	ErrSubjectTaken = errors.New("subject belongs to a control plane node")
+)
+
+// RaftApplier is the subset of RaftNode used by the Authority.
+type RaftApplier interface {
+	ApplyCommand(cmd []byte, timeout time.Duration) (interface{}, error)
+	State() hashiraft.RaftState
+}
+
+// Store gives read access to the CA state replicated in the FSM.
+type Store interface {
+	CARoots() []internalraft.CARoot
+	IssuedCerts() []internalraft.IssuedCert
+}
+
+// Config controls certificate lifetimes.
+type Config struct {
+	CommonName    string
+	RootValidity  time.Duration
+	NodeCertTTL   time.Duration
+	WorkerCertTTL time.Duration
+}
+
+// DefaultConfig issues day-long leaf certificates from year-long roots.
+func DefaultConfig() Config {
+	return Config{
+		CommonName:    "pipeline-orchestrator CA",
+		RootValidity:  365 * 24 * time.Hour,
+		NodeCertTTL:   24 * time.Hour,
+		WorkerCertTTL: 24 * time.Hour,
+	}
+}
+
+// Issued is a freshly signed certificate together with the current trust bundle.
+type Issued struct {
+	Serial         string    `json:"serial"`
+	CertificatePEM string    `json:"certificate_pem"`
+	TrustBundlePEM string    `json:"ca_bundle_pem"`
+	NotAfter       time.Time `json:"not_after"`
+}
+
+// Authority signs certificates with the active CA root.
+type Authority struct {
+	raft   RaftApplier
+	store  Store
+	sealer *Sealer
+	cfg    Config
+}
+
+// NewAuthority returns an Authority backed by the FSM store.
+func NewAuthority(raft RaftApplier, store Store, sealer *Sealer, cfg Config) *Authority {
+	return &Authority{raft: raft, store: store, sealer: sealer, cfg: cfg}
+}
+
+// EnsureRoot creates the first CA root if none exists yet. Leader only.
+func (a *Authority) EnsureRoot() error {
+	if a.raft.State() != hashiraft.Leader {
+		return ErrNotLeader
+	}
+	if _, ok := a.activeRoot(); ok {
+		return nil
+	}
+	_, err := a.Rotate()
+	return err
+}
+
+// Rotate generates a new root and makes it the active signer. The previous
+// roots stay in the trust bundle until retired, so certificates they issued
+// keep verifying while holders renew under the new root. Leader only.
+func (a *Authority) Rotate() (string, error) {
+	if a.raft.State() != hashiraft.Leader {
+		return "", ErrNotLeader
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("generate CA key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("%s %s", a.cfg.CommonName, now.Format("2006-01-02T15:04"))},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(a.cfg.RootValidity),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		MaxPathLenZero:        true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return "", fmt.Errorf("create CA certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("marshal CA key: %w", err)
+	}
+	id := serial.Text(16)
+	sealed, err := a.sealer.Seal(keyDER, id)
+	if err != nil {
+		return "", err
+	}
+	root := internalraft.CARoot{
+		ID:        id,
+		CertPEM:   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		SealedKey: sealed,
+		NotAfter:  tmpl.NotAfter,
+		AddedAt:   now,
+	}
+	if err := a.apply(internalraft.CmdAddCARoot, internalraft.AddCARootPayload{Root: root}); err != nil {
+		return "", err
+	}
+	slog.Info("CA root rotated", "root_id", id, "not_after", root.NotAfter)
+	return id, nil
+}
+
+// Retire removes a non-active root from the trust bundle. Unless force is set it
+// refuses while unexpired, unrevoked certificates issued by that root exist.
+// Leader only.
+func (a *Authority) Retire(rootID string, force bool) error {
+	if a.raft.State() != hashiraft.Leader {
+		return ErrNotLeader
+	}
+	if !force {
+		now := time.Now()
+		outstanding := 0
+		for _, c := range a.store.IssuedCerts() {
+			if c.IssuerID == rootID && c.RevokedAt.IsZero() && c.NotAfter.After(now) {
+				outstanding++
+			}
+		}
+		if outstanding > 0 {
+			return fmt.Errorf("%w: %d outstanding", ErrRootInUse, outstanding)
+		}
+	}
+	return a.apply(internalraft.CmdRetireCARoot, internalraft.RetireCARootPayload{
+		ID: rootID, RetiredAt: time.Now().UTC(),
+	})
+}
+
+// Revoke marks an issued certificate as revoked. Leader only.
+func (a *Authority) Revoke(serial, reason string) error {
+	if a.raft.State() != hashiraft.Leader {
+		return ErrNotLeader
+	}
+	return a.apply(internalraft.CmdRevokeCert, internalraft.RevokeCertPayload{
+		Serial: serial, Reason: reason, RevokedAt: time.Now().UTC(),
+	})
+}
+
+// Sign issues a certificate of the given kind for subject from a PEM CSR. The
+// CSR must name subject as its common name or a DNS SAN; the certificate carries
+// exactly that one DNS SAN regardless of what else was requested. A worker
+// certificate is refused for a subject a node certificate was issued to, so a
+// worker registered under a node's ID cannot pass for that node. Leader only.
+func (a *Authority) Sign(kind, subject string, csrPEM []byte) (*Issued, error) {
+	if a.raft.State() != hashiraft.Leader {
+		return nil, ErrNotLeader
+	}
+	var ttl time.Duration
+	var usages []x509.ExtKeyUsage
+	switch kind {
+	case KindNode:
+		ttl = a.cfg.NodeCertTTL
+		usages = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	case KindWorker:
+		if a.nodeSubject(subject) {
+			metrics.CertsIssuedTotal.WithLabelValues(kind, "rejected").Inc()
+			return nil, fmt.Errorf("%w: %q", ErrSubjectTaken, subject)
+		}
+		ttl = a.cfg.WorkerCertTTL
+		usages = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	default:
+		return nil, fmt.Errorf("unknown certificate kind %q", kind)
+	}
+	csr, err := parseCSR(csrPEM, subject)
+	if err != nil {
+		metrics.CertsIssuedTotal.WithLabelValues(kind, "rejected").Inc()
+		return nil, err
+	}
+
+	root, ok := a.activeRoot()
+	if !ok {
+		return nil, ErrNoActiveRoot
+	}
+	caCert, caKey, err := a.openRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	notAfter := now.Add(ttl)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: subject},
+		DNSNames:     []string{subject},
+		NotBefore:    now.Add(-time.Minute), // tolerate small clock skew between clouds
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usages,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, csr.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("sign certificate: %w", err)
+	}
+
+	record := internalraft.IssuedCert{
+		Serial:    serial.Text(16),
+		Kind:      kind,
+		Subject:   subject,
+		IssuerID:  root.ID,
+		NotBefore: tmpl.NotBefore,
+		NotAfter:  notAfter,
+	}
+	// Record before handing the certificate out, so nothing valid is ever
+	// issued without an audit entry.
+	if err := a.apply(internalraft.CmdRecordCert, internalraft.RecordCertPayload{Cert: record}); err != nil {
+		return nil, err
+	}
+	metrics.CertsIssuedTotal.WithLabelValues(kind, "issued").Inc()
+	slog.Info("certificate issued", "kind", kind, "subject", subject, "serial", record.Serial,
+		"not_after", notAfter)
+	return &Issued{
+		Serial:         record.Serial,
+		CertificatePEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		TrustBundlePEM: string(a.TrustBundle()),
+		NotAfter:       notAfter,
+	}, nil
+}
+
+// TrustBundle returns the PEM certificates of every unretired, unexpired root.
+// Safe on any node.
+func (a *Authority) TrustBundle() []byte {
+	var buf bytes.Buffer
+	now := time.Now()
+	for _, r := range a.store.CARoots() {
+		if r.RetiredAt.IsZero() && r.NotAfter.After(now) {
+			buf.WriteString(r.CertPEM)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (a *Authority) activeRoot() (internalraft.CARoot, bool) {
+	for _, r := range a.store.CARoots() {
+		if r.Active && r.RetiredAt.IsZero() {
+			return r, true
+		}
+	}
+	return internalraft.CARoot{}, false
+}
+
+// openRoot parses the root certificate and unseals its private key.
+func (a *Authority) openRoot(root internalraft.CARoot) (*x509.Certificate, any, error) {
+	block, _ := pem.Decode([]byte(root.CertPEM))
+	if block == nil {
+		return nil, nil, fmt.Errorf("CA root %s: bad certificate PEM", root.ID)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CA root %s: %w", root.ID, err)
+	}
+	keyDER, err := a.sealer.Open(root.SealedKey, root.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CA root %s: %w", root.ID, err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyDER)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CA root %s: parse key: %w", root.ID, err)
+	}
+	return cert, key, nil
+}
+
+func (a *Authority) apply(t internalraft.CommandType, payload interface{}) error {
+	cmd, err := internalraft.MarshalCommand(t, payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", t, err)
+	}
+	if _, err := a.raft.ApplyCommand(cmd, raftApplyTimeout); err != nil {
+		return fmt.Errorf("raft apply %s: %w", t, err)
+	}
+	return nil
+}
+
+// nodeSubject reports whether a node certificate was ever issued to subject.
+func (a *Authority) nodeSubject(subject string) bool {
+	for _, c := range a.store.IssuedCerts() {
+		if c.Kind == KindNode && c.Subject == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCSR decodes a PEM CSR, checks its self-signature and that it names subject.
+func parseCSR(csrPEM []byte, subject string) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("%w: expected a PEM CERTIFICATE REQUEST", ErrInvalidCSR)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+	if csr.Subject.CommonName == subject {
+		return csr, nil
+	}
+	for _, name := range csr.DNSNames {
+		if name == subject {
+			return csr, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: CSR does not name %q", ErrInvalidCSR, subject)
+}
+
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial: %w", err)
+	}
+	return serial, nil
+}
@@ -0,0 +1,241 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// fsmRaft applies commands straight to a real PipelineFSM, standing in for a
+// single-node Raft cluster.
+type fsmRaft struct {
+	fsm      *internalraft.PipelineFSM
+	isLeader bool
+	index    uint64
+}
+
+func (m *fsmRaft) ApplyCommand(cmd []byte, _ time.Duration) (interface{}, error) {
+	m.index++
+	resp := m.fsm.Apply(&hashiraft.Log{Index: m.index, Type: hashiraft.LogCommand, Data: cmd})
+	if err, ok := resp.(error); ok {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *fsmRaft) State() hashiraft.RaftState {
+	if m.isLeader {
+		return hashiraft.Leader
+	}
+	return hashiraft.Follower
+}
+
+func newTestAuthority(t *testing.T) (*Authority, *fsmRaft) {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	sealer, err := NewSealer(key)
+	if err != nil {
+		t.Fatalf("NewSealer: %v", err)
+	}
+	fsm := internalraft.NewPipelineFSM()
+	r := &fsmRaft{fsm: fsm, isLeader: true}
+	return NewAuthority(r, fsm, sealer, DefaultConfig()), r
+}
+
+func makeCSR(t *testing.T, cn string, dnsNames ...string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: cn}, DNSNames: dnsNames,
+	}, key)
+	if err != nil {
+		t.Fatalf("CreateCertificateRequest: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func parseCert(t *testing.T, certPEM string) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		t.Fatal("no PEM block in certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+func verify(cert *x509.Certificate, bundle string, usage x509.ExtKeyUsage) error {
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM([]byte(bundle))
+	_, err := cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{usage}})
+	return err
+}
+
+func TestSealerRoundTrip(t *testing.T) {
+	key, err := ParseKey(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("ParseKey hex: %v", err)
+	}
+	s, _ := NewSealer(key)
+	sealed, err := s.Seal([]byte("secret"), "root-1")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if strings.Contains(string(sealed), "secret") {
+		t.Fatal("sealed value contains plaintext")
+	}
+	if got, err := s.Open(sealed, "root-1"); err != nil || string(got) != "secret" {
+		t.Fatalf("Open: %q, %v", got, err)
+	}
+	if _, err := s.Open(sealed, "root-2"); err == nil {
+		t.Error("expected Open with a different label to fail")
+	}
+	if _, err := ParseKey("too-short"); err == nil {
+		t.Error("expected ParseKey to reject a short key")
+	}
+}
+
+func TestAuthoritySignNodeAndWorker(t *testing.T) {
+	ca, r := newTestAuthority(t)
+	if _, err := ca.Sign(KindNode, "cp-aws-1", makeCSR(t, "cp-aws-1")); !errors.Is(err, ErrNoActiveRoot) {
+		t.Fatalf("expected ErrNoActiveRoot before init, got %v", err)
+	}
+	if err := ca.EnsureRoot(); err != nil {
+		t.Fatalf("EnsureRoot: %v", err)
+	}
+	roots := r.fsm.CARoots()
+	if len(roots) != 1 || !roots[0].Active {
+		t.Fatalf("expected one active root, got %+v", roots)
+	}
+	if strings.Contains(string(roots[0].SealedKey), "PRIVATE KEY") {
+		t.Fatal("CA key stored unsealed")
+	}
+
+	node, err := ca.Sign(KindNode, "cp-aws-1", makeCSR(t, "cp-aws-1", "evil.example.com"))
+	if err != nil {
+		t.Fatalf("Sign node: %v", err)
+	}
+	cert := parseCert(t, node.CertificatePEM)
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "cp-aws-1" {
+		t.Errorf("node cert must carry exactly the subject SAN, got %v", cert.DNSNames)
+	}
+	if err := verify(cert, node.TrustBundlePEM, x509.ExtKeyUsageServerAuth); err != nil {
+		t.Errorf("node cert should verify for server auth: %v", err)
+	}
+	if time.Until(cert.NotAfter) > DefaultConfig().NodeCertTTL {
+		t.Errorf("node cert outlives its TTL: %s", cert.NotAfter)
+	}
+
+	worker, err := ca.Sign(KindWorker, "worker-aws-1", makeCSR(t, "worker-aws-1"))
+	if err != nil {
+		t.Fatalf("Sign worker: %v", err)
+	}
+	wcert := parseCert(t, worker.CertificatePEM)
+	if err := verify(wcert, worker.TrustBundlePEM, x509.ExtKeyUsageServerAuth); err == nil {
+		t.Error("worker cert must not be usable for server auth (Raft)")
+	}
+	if _, err := ca.Sign(KindWorker, "cp-aws-1", makeCSR(t, "cp-aws-1")); !errors.Is(err, ErrSubjectTaken) {
+		t.Errorf("worker cert for a node's subject: expected ErrSubjectTaken, got %v", err)
+	}
+
+	certs := r.fsm.IssuedCerts()
+	if len(certs) != 2 || certs[0].Serial != node.Serial || certs[1].Kind != KindWorker ||
+		certs[0].IssuerID != roots[0].ID {
+		t.Fatalf("unexpected audit records: %+v", certs)
+	}
+}
+
+func TestAuthorityRejectsBadCSR(t *testing.T) {
+	ca, _ := newTestAuthority(t)
+	if err := ca.EnsureRoot(); err != nil {
+		t.Fatalf("EnsureRoot: %v", err)
+	}
+	if _, err := ca.Sign(KindNode, "cp-aws-1", makeCSR(t, "cp-gcp-1")); !errors.Is(err, ErrInvalidCSR) {
+		t.Errorf("CSR for another subject: expected ErrInvalidCSR, got %v", err)
+	}
+	if _, err := ca.Sign(KindNode, "cp-aws-1", []byte("garbage")); !errors.Is(err, ErrInvalidCSR) {
+		t.Errorf("garbage CSR: expected ErrInvalidCSR, got %v", err)
+	}
+}
+
+func TestAuthorityFollowerRefuses(t *testing.T) {
+	ca, r := newTestAuthority(t)
+	r.isLeader = false
+	if err := ca.EnsureRoot(); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("EnsureRoot: expected ErrNotLeader, got %v", err)
+	}
+	if _, err := ca.Sign(KindNode, "n", makeCSR(t, "n")); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("Sign: expected ErrNotLeader, got %v", err)
+	}
+}
+
+func TestAuthorityRotationOverlap(t *testing.T) {
+	ca, r := newTestAuthority(t)
+	if err := ca.EnsureRoot(); err != nil {
+		t.Fatalf("EnsureRoot: %v", err)
+	}
+	oldRoot := r.fsm.CARoots()[0].ID
+	before, err := ca.Sign(KindNode, "cp-aws-1", makeCSR(t, "cp-aws-1"))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	newRoot, err := ca.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	after, err := ca.Sign(KindNode, "cp-aws-1", makeCSR(t, "cp-aws-1"))
+	if err != nil {
+		t.Fatalf("Sign after rotate: %v", err)
+	}
+	if got := r.fsm.IssuedCerts()[1].IssuerID; got != newRoot {
+		t.Errorf("new certs must be signed by the new root, got issuer %s", got)
+	}
+
+	// During the overlap both generations verify against the bundle.
+	bundle := string(ca.TrustBundle())
+	for name, issued := range map[string]*Issued{"old": before, "new": after} {
+		if err := verify(parseCert(t, issued.CertificatePEM), bundle, x509.ExtKeyUsageClientAuth); err != nil {
+			t.Errorf("%s cert should verify during overlap: %v", name, err)
+		}
+	}
+
+	if err := ca.Retire(newRoot, true); err == nil {
+		t.Error("expected retiring the active root to fail")
+	}
+	if err := ca.Retire(oldRoot, false); !errors.Is(err, ErrRootInUse) {
+		t.Fatalf("expected ErrRootInUse with an outstanding cert, got %v", err)
+	}
+	if err := ca.Revoke(before.Serial, "superseded"); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if !r.fsm.CertRevoked(before.Serial) {
+		t.Error("revoked serial not reported by the FSM")
+	}
+	if err := ca.Retire(oldRoot, false); err != nil {
+		t.Fatalf("Retire after revoking outstanding cert: %v", err)
+	}
+	if err := verify(parseCert(t, before.CertificatePEM), string(ca.TrustBundle()), x509.ExtKeyUsageClientAuth); err == nil {
+		t.Error("old-root cert should no longer verify once its root is retired")
+	}
+}
@@ -0,0 +1,84 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// NodeTokenHeader carries the shared secret that authorises node certificate
+// requests on the /pki/sign endpoint.
+const NodeTokenHeader = "X-Node-Token"
+
+// Client requests certificates from a control plane's /pki endpoints.
+// Followers use it to reach the leader, which is the only node that signs.
+type Client struct {
+	HTTP  *http.Client
+	Token string // sent on Sign; Renew authenticates with the TLS client certificate
+}
+
+// Sign posts csrPEM to baseURL/pki/sign for the given kind and subject.
+func (c *Client) Sign(ctx context.Context, baseURL, kind, subject string, csrPEM []byte) (*Issued, error) {
+	q := url.Values{"kind": {kind}, "subject": {subject}}
+	return c.post(ctx, baseURL+"/pki/sign?"+q.Encode(), c.Token, csrPEM)
+}
+
+// Renew posts csrPEM to baseURL/pki/renew, the mutual TLS endpoint that signs
+// a node certificate for the node named by the client certificate. c.HTTP
+// must present that certificate.
+func (c *Client) Renew(ctx context.Context, baseURL string, csrPEM []byte) (*Issued, error) {
+	return c.post(ctx, baseURL+"/pki/renew", "", csrPEM)
+}
+
+func (c *Client) post(ctx context.Context, target, token string, csrPEM []byte) (*Issued, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(csrPEM))
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set(NodeTokenHeader, token)
+	}
+	req.Header.Set("Content-Type", "application/x-pem-file")
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("pki sign: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("pki sign: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var issued Issued
+	if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+		return nil, fmt.Errorf("pki sign: decode response: %w", err)
+	}
+	return &issued, nil
+}
+
+// TrustBundle fetches baseURL/pki/ca.
+func (c *Client) TrustBundle(ctx context.Context, baseURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/pki/ca", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("pki trust bundle: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pki trust bundle: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (c *Client) client() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,166 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// IssueFunc obtains a signed certificate for csrPEM, typically from the leader.
+type IssueFunc func(ctx context.Context, csrPEM []byte) (*Issued, error)
+
+// BundleFunc fetches the current trust bundle.
+type BundleFunc func(ctx context.Context) ([]byte, error)
+
+// Renewer keeps a certificate, key and CA bundle on disk fresh. It writes the
+// same files the Raft TLS transport reloads, so renewal and CA rotation take
+// effect without a restart.
+type Renewer struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+	Subject  string
+
+	Issue  IssueFunc
+	Bundle BundleFunc
+
+	// RenewBefore is the fraction of the lifetime that must remain before a new
+	// certificate is requested; 1/3 renews a 24h certificate after 16h.
+	RenewBefore   float64
+	CheckInterval time.Duration
+}
+
+// Run checks the certificate every CheckInterval until ctx is cancelled.
+func (r *Renewer) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.CheckInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RenewIfNeeded(ctx, time.Now()); err != nil {
+			slog.Warn("certificate renewal failed", "subject", r.Subject, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RenewIfNeeded refreshes the CA bundle and, if the certificate is missing or
+// inside its renewal window, replaces it with a newly issued one. It reports
+// whether a new certificate was written.
+func (r *Renewer) RenewIfNeeded(ctx context.Context, now time.Time) (bool, error) {
+	if r.Bundle != nil {
+		bundle, err := r.Bundle(ctx)
+		if err != nil {
+			return false, fmt.Errorf("fetch trust bundle: %w", err)
+		}
+		if err := writeIfChanged(r.CAFile, bundle, 0o644); err != nil {
+			return false, err
+		}
+	}
+	if !r.due(now) {
+		return false, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return false, fmt.Errorf("generate key: %w", err)
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: r.Subject},
+		DNSNames: []string{r.Subject},
+	}, key)
+	if err != nil {
+		return false, fmt.Errorf("create CSR: %w", err)
+	}
+	issued, err := r.Issue(ctx, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}))
+	if err != nil {
+		return false, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return false, fmt.Errorf("marshal key: %w", err)
+	}
+
+	// Write the bundle first so peers' new certificates already verify, and the
+	// key before the certificate so a reload never pairs a new cert with an old key.
+	if err := writeIfChanged(r.CAFile, []byte(issued.TrustBundlePEM), 0o644); err != nil {
+		return false, err
+	}
+	if err := writeAtomic(r.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return false, err
+	}
+	if err := writeAtomic(r.CertFile, []byte(issued.CertificatePEM), 0o644); err != nil {
+		return false, err
+	}
+	slog.Info("certificate renewed", "subject", r.Subject, "serial", issued.Serial,
+		"not_after", issued.NotAfter)
+	return true, nil
+}
+
+// due reports whether the certificate on disk is missing, unreadable, or has
+// less than RenewBefore of its lifetime left.
+func (r *Renewer) due(now time.Time) bool {
+	data, err := os.ReadFile(r.CertFile)
+	if err != nil {
+		return true
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	renewAt := cert.NotAfter.Add(-time.Duration(float64(lifetime) * r.RenewBefore))
+	return !now.Before(renewAt)
+}
+
+func writeIfChanged(path string, data []byte, perm os.FileMode) error {
+	if len(data) == 0 {
+		return errors.New("refusing to write empty trust bundle")
+	}
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	return writeAtomic(path, data, perm)
+}
+
+// writeAtomic replaces path via a temp file and rename so readers never see a
+// partially written file.
+func writeAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package pki
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenewerIssuesAndRenews(t *testing.T) {
+	ca, _ := newTestAuthority(t)
+	if err := ca.EnsureRoot(); err != nil {
+		t.Fatalf("EnsureRoot: %v", err)
+	}
+	dir := t.TempDir()
+	issues := 0
+	r := &Renewer{
+		CertFile:    filepath.Join(dir, "node.crt"),
+		KeyFile:     filepath.Join(dir, "node.key"),
+		CAFile:      filepath.Join(dir, "ca.crt"),
+		Subject:     "cp-aws-1",
+		RenewBefore: 1.0 / 3,
+		Issue: func(_ context.Context, csrPEM []byte) (*Issued, error) {
+			issues++
+			return ca.Sign(KindNode, "cp-aws-1", csrPEM)
+		},
+		Bundle: func(context.Context) ([]byte, error) { return ca.TrustBundle(), nil },
+	}
+	ctx := context.Background()
+	now := time.Now()
+
+	renewed, err := r.RenewIfNeeded(ctx, now)
+	if err != nil || !renewed {
+		t.Fatalf("first run should issue a certificate: renewed=%v err=%v", renewed, err)
+	}
+	if _, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile); err != nil {
+		t.Fatalf("written key pair does not load: %v", err)
+	}
+	if ca, err := os.ReadFile(r.CAFile); err != nil || len(ca) == 0 {
+		t.Fatalf("CA bundle not written: %v", err)
+	}
+
+	if renewed, _ := r.RenewIfNeeded(ctx, now.Add(time.Hour)); renewed {
+		t.Error("fresh certificate should not be renewed")
+	}
+	if renewed, err := r.RenewIfNeeded(ctx, now.Add(17*time.Hour)); err != nil || !renewed {
+		t.Errorf("certificate past 2/3 of its lifetime should renew: renewed=%v err=%v", renewed, err)
+	}
+	if issues != 2 {
+		t.Errorf("expected 2 issuances, got %d", issues)
+	}
+}
+
+func TestRenewerPicksUpRotatedBundle(t *testing.T) {
+	ca, _ := newTestAuthority(t)
+	if err := ca.EnsureRoot(); err != nil {
+		t.Fatalf("EnsureRoot: %v", err)
+	}
+	dir := t.TempDir()
+	r := &Renewer{
+		CertFile: filepath.Join(dir, "node.crt"),
+		KeyFile:  filepath.Join(dir, "node.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+		Subject:  "cp-aws-1",
+		Issue: func(_ context.Context, csrPEM []byte) (*Issued, error) {
+			return ca.Sign(KindNode, "cp-aws-1", csrPEM)
+		},
+		Bundle:      func(context.Context) ([]byte, error) { return ca.TrustBundle(), nil },
+		RenewBefore: 1.0 / 3,
+	}
+	if _, err := r.RenewIfNeeded(context.Background(), time.Now()); err != nil {
+		t.Fatalf("RenewIfNeeded: %v", err)
+	}
+	before, _ := os.ReadFile(r.CAFile)
+
+	if _, err := ca.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if _, err := r.RenewIfNeeded(context.Background(), time.Now()); err != nil {
+		t.Fatalf("RenewIfNeeded: %v", err)
+	}
+	after, _ := os.ReadFile(r.CAFile)
+	if len(after) <= len(before) {
+		t.Error("CA bundle should gain the new root while the old one is still trusted")
+	}
+}
@@ -0,0 +1,69 @@
+package pki
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sealer encrypts CA private keys with AES-256-GCM before they are written to
+// the Raft log. Every control plane node must be given the same key.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer returns a Sealer for a 32-byte key.
+func NewSealer(key []byte) (*Sealer, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("seal key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// ParseKey decodes a seal key given as 64 hex characters or standard base64.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(s); err == nil && len(b) == 32 {
+		return b, nil
+	}
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil && len(b) == 32 {
+		return b, nil
+	}
+	return nil, errors.New("seal key must be 32 bytes, hex or base64 encoded")
+}
+
+// Seal encrypts plaintext, binding it to label (the root ID) so a sealed key
+// cannot be swapped onto a different root. The nonce is prepended.
+func (s *Sealer) Seal(plaintext []byte, label string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(label)), nil
+}
+
+// Open decrypts a value produced by Seal with the same label.
+func (s *Sealer) Open(sealed []byte, label string) ([]byte, error) {
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, errors.New("sealed value too short")
+	}
+	plaintext, err := s.aead.Open(nil, sealed[:n], sealed[n:], []byte(label))
+	if err != nil {
+		return nil, fmt.Errorf("open sealed key (wrong seal key?): %w", err)
+	}
+	return plaintext, nil
+}
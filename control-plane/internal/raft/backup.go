@@ -0,0 +1,212 @@
+package raft
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+)
+
+// A backup file is one JSON header line followed by the raw FSM snapshot:
+//
+//	{"format":"pipeline-orchestrator-backup","format_version":1,...,"sha256":"…"}\n
+//	<snapshot bytes>
+//
+// The header carries the snapshot's Raft index and term, its FSM version and
+// a SHA-256 of the payload, so a restore can reject a truncated, corrupted
+// or too-new file before it touches a data dir.
+const (
+	backupFormat        = "pipeline-orchestrator-backup"
+	backupFormatVersion = 1
+
+	// maxBackupHeader bounds the header line so a wrong file fails fast.
+	maxBackupHeader = 64 << 10
+)
+
+var (
+	// ErrBackupChecksum means the payload does not match the header's size or SHA-256.
+	ErrBackupChecksum = errors.New("backup checksum mismatch")
+	// ErrIncompatibleBackup means the file is not a backup, or was written by
+	// a newer version than this binary can restore.
+	ErrIncompatibleBackup = errors.New("incompatible backup")
+	// ErrExistingState means the restore target already holds Raft state.
+	ErrExistingState = errors.New("data dir already has raft state")
+)
+
+// BackupHeader describes a backup file.
+type BackupHeader struct {
+	Format          string    `json:"format"`
+	FormatVersion   int       `json:"format_version"`
+	SnapshotVersion int       `json:"snapshot_version"` // FSM snapshot format of the payload
+	NodeID          string    `json:"node_id"`          // node the snapshot was taken on
+	Index           uint64    `json:"index"`
+	Term            uint64    `json:"term"`
+	CreatedAt       time.Time `json:"created_at"`
+	Size            int64     `json:"size"`
+	SHA256          string    `json:"sha256"`
+}
+
+// Backup takes a fresh snapshot on the leader and writes it to w as a backup
+// file. If nothing was committed since the last snapshot, the latest one is
+// used instead. Nothing is written to w unless the snapshot was read in full.
+func (n *RaftNode) Backup(w io.Writer) (BackupHeader, error) {
+	if n.raft.State() != hashiraft.Leader {
+		return BackupHeader{}, hashiraft.ErrNotLeader
+	}
+	meta, rc, err := n.openSnapshot()
+	if err != nil {
+		return BackupHeader{}, err
+	}
+	defer rc.Close()
+	payload, err := io.ReadAll(rc)
+	if err != nil {
+		return BackupHeader{}, fmt.Errorf("read snapshot %s: %w", meta.ID, err)
+	}
+	hdr := BackupHeader{NodeID: n.cfg.NodeID, Index: meta.Index, Term: meta.Term,
+		CreatedAt: time.Now().UTC()}
+	return WriteBackup(w, hdr, payload)
+}
+
+func (n *RaftNode) openSnapshot() (*hashiraft.SnapshotMeta, io.ReadCloser, error) {
+	f := n.raft.Snapshot()
+	err := f.Error()
+	if err == nil {
+		return f.Open()
+	}
+	if !errors.Is(err, hashiraft.ErrNothingNewToSnapshot) {
+		return nil, nil, fmt.Errorf("snapshot: %w", err)
+	}
+	snaps, err := n.snaps.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("list snapshots: %w", err)
+	}
+	if len(snaps) == 0 {
+		return nil, nil, errors.New("no snapshot available")
+	}
+	return n.snaps.Open(snaps[0].ID)
+}
+
+// WriteBackup fills in hdr's format, version, size and checksum from payload
+// and writes the backup file to w.
+func WriteBackup(w io.Writer, hdr BackupHeader, payload []byte) (BackupHeader, error) {
+	version, err := payloadVersion(payload)
+	if err != nil {
+		return BackupHeader{}, err
+	}
+	sum := sha256.Sum256(payload)
+	hdr.Format = backupFormat
+	hdr.FormatVersion = backupFormatVersion
+	hdr.SnapshotVersion = version
+	hdr.Size = int64(len(payload))
+	hdr.SHA256 = hex.EncodeToString(sum[:])
+
+	line, err := json.Marshal(hdr)
+	if err != nil {
+		return BackupHeader{}, fmt.Errorf("marshal backup header: %w", err)
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return BackupHeader{}, err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return BackupHeader{}, err
+	}
+	return hdr, nil
+}
+
+// ReadBackup parses and verifies a backup file, returning its header and the
+// snapshot payload. It fails with ErrIncompatibleBackup for foreign or
+// too-new files and ErrBackupChecksum for corrupted ones.
+func ReadBackup(r io.Reader) (BackupHeader, []byte, error) {
+	br := bufio.NewReader(io.LimitReader(r, maxBackupHeader))
+	line, err := br.ReadBytes('\n')
+	if err != nil {
+		return BackupHeader{}, nil, fmt.Errorf("%w: no header line", ErrIncompatibleBackup)
+	}
+	var hdr BackupHeader
+	if err := json.Unmarshal(line, &hdr); err != nil || hdr.Format != backupFormat {
+		return BackupHeader{}, nil, fmt.Errorf("%w: not a %s file", ErrIncompatibleBackup, backupFormat)
+	}
+	if hdr.FormatVersion != backupFormatVersion {
+		return hdr, nil, fmt.Errorf("%w: backup format version %d, this build reads %d",
+			ErrIncompatibleBackup, hdr.FormatVersion, backupFormatVersion)
+	}
+	if hdr.SnapshotVersion > snapshotVersion {
+		return hdr, nil, fmt.Errorf("%w: snapshot version %d is newer than supported version %d",
+			ErrIncompatibleBackup, hdr.SnapshotVersion, snapshotVersion)
+	}
+
+	// Whatever the header reader buffered past the newline is payload.
+	rest := io.MultiReader(br, r)
+	payload, err := io.ReadAll(io.LimitReader(rest, hdr.Size+1))
+	if err != nil {
+		return hdr, nil, fmt.Errorf("read backup payload: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	if int64(len(payload)) != hdr.Size || hex.EncodeToString(sum[:]) != hdr.SHA256 {
+		return hdr, nil, fmt.Errorf("%w: got %d bytes, header says %d", ErrBackupChecksum, len(payload), hdr.Size)
+	}
+	if _, err := decodeFSMState(payload); err != nil {
+		return hdr, nil, fmt.Errorf("%w: %v", ErrIncompatibleBackup, err)
+	}
+	return hdr, payload, nil
+}
+
+// payloadVersion reads the FSM snapshot version; unversioned snapshots are 0.
+func payloadVersion(payload []byte) (int, error) {
+	var probe struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return 0, fmt.Errorf("decode snapshot: %w", err)
+	}
+	return probe.Version, nil
+}
+
+// RestoreBackup seeds cfg.DataDir from a backup so that a node started on it
+// comes up with the backed-up state and a fresh cluster membership built from
+// cfg.Peers. It refuses a data dir that already holds Raft state. Run it on
+// every member of the new cluster, or on one and let the others, started
+// empty with Bootstrap, catch up by snapshot install.
+func RestoreBackup(cfg Config, r io.Reader) (BackupHeader, error) {
+	hdr, payload, err := ReadBackup(r)
+	if err != nil {
+		return hdr, err
+	}
+	store, snaps, err := openOfflineStores(cfg.DataDir)
+	if err != nil {
+		return hdr, err
+	}
+	defer store.Close()
+	hasState, err := hashiraft.HasExistingState(store, store, snaps)
+	if err != nil {
+		return hdr, fmt.Errorf("check existing state: %w", err)
+	}
+	if hasState {
+		return hdr, fmt.Errorf("%w: %s", ErrExistingState, cfg.DataDir)
+	}
+
+	servers := peersToServers(cfg.Peers, cfg.NodeID, hashiraft.ServerAddress(cfg.RaftAddr))
+	configuration := hashiraft.Configuration{Servers: servers}
+	// The transport is only consulted to encode peers for pre-v1 snapshot
+	// metadata; an in-memory one is enough.
+	_, trans := hashiraft.NewInmemTransport(hashiraft.ServerAddress(cfg.RaftAddr))
+	sink, err := snaps.Create(hashiraft.SnapshotVersionMax, hdr.Index, hdr.Term, configuration, 1, trans)
+	if err != nil {
+		return hdr, fmt.Errorf("create snapshot: %w", err)
+	}
+	if _, err := io.Copy(sink, bytes.NewReader(payload)); err != nil {
+		_ = sink.Cancel()
+		return hdr, fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := sink.Close(); err != nil {
+		return hdr, fmt.Errorf("finalize snapshot: %w", err)
+	}
+	return hdr, nil
+}
@@ -0,0 +1,125 @@
+package raft
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	hashiraft "github.com/hashicorp/raft"
+)
+
+// backupOf writes a few workers through a single-node cluster and returns a
+// backup of its state.
+func backupOf(t *testing.T) (BackupHeader, []byte) {
+	t.Helper()
+	nodes, _, _, _ := makeCluster(t, 1)
+	leader := nodes[waitForLeader(t, nodes, 10*time.Second)]
+	for _, id := range []string{"w-1", "w-2", "w-3"} {
+		cmd := mustMarshalCmd(t, CmdRegisterWorker, RegisterWorkerPayload{ID: id, CloudTag: "gcp"})
+		if err := leader.Apply(cmd, 5*time.Second); err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+	}
+	var buf bytes.Buffer
+	hdr, err := leader.Backup(&buf)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	// Nothing new since the first backup: the latest snapshot is reused.
+	var again bytes.Buffer
+	if _, err := leader.Backup(&again); err != nil {
+		t.Fatalf("second Backup: %v", err)
+	}
+	return hdr, buf.Bytes()
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	hdr, data := backupOf(t)
+	if hdr.Index == 0 || hdr.Term == 0 || hdr.SnapshotVersion != snapshotVersion {
+		t.Fatalf("unexpected header %+v", hdr)
+	}
+	got, payload, err := ReadBackup(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadBackup: %v", err)
+	}
+	if got != hdr {
+		t.Errorf("header round trip: got %+v, want %+v", got, hdr)
+	}
+	state, err := decodeFSMState(payload)
+	if err != nil || len(state.Workers) != 3 {
+		t.Fatalf("payload: %d workers, err %v", len(state.Workers), err)
+	}
+}
+
+func TestReadBackupRejectsCorruptAndIncompatible(t *testing.T) {
+	_, data := backupOf(t)
+
+	corrupt := bytes.Clone(data)
+	corrupt[len(corrupt)-3] ^= 0xff
+	if _, _, err := ReadBackup(bytes.NewReader(corrupt)); !errors.Is(err, ErrBackupChecksum) {
+		t.Errorf("flipped byte: expected ErrBackupChecksum, got %v", err)
+	}
+	if _, _, err := ReadBackup(bytes.NewReader(data[:len(data)-10])); !errors.Is(err, ErrBackupChecksum) {
+		t.Errorf("truncated: expected ErrBackupChecksum, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := WriteBackup(&buf, BackupHeader{}, []byte(`{"version":99}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadBackup(&buf); !errors.Is(err, ErrIncompatibleBackup) ||
+		!strings.Contains(err.Error(), "newer than supported") {
+		t.Errorf("future snapshot: expected ErrIncompatibleBackup, got %v", err)
+	}
+
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	bumped := bytes.Replace(header, []byte(`"format_version":1`), []byte(`"format_version":2`), 1)
+	if _, _, err := ReadBackup(bytes.NewReader(bumped)); !errors.Is(err, ErrIncompatibleBackup) {
+		t.Errorf("future format: expected ErrIncompatibleBackup, got %v", err)
+	}
+	if _, _, err := ReadBackup(strings.NewReader("raft.db contents\n")); !errors.Is(err, ErrIncompatibleBackup) {
+		t.Errorf("foreign file: expected ErrIncompatibleBackup, got %v", err)
+	}
+}
+
+func TestRestoreBackupSeedsNewCluster(t *testing.T) {
+	hdr, data := backupOf(t)
+
+	// A different cluster: new node IDs and a fresh data dir.
+	cfg := Config{NodeID: "restored-1", DataDir: t.TempDir(), Bootstrap: true,
+		Peers: []string{"restored-1"}, RaftAddr: "restored-1"}
+	if _, err := RestoreBackup(cfg, bytes.NewReader(data)); err != nil {
+		t.Fatalf("RestoreBackup: %v", err)
+	}
+	if _, err := RestoreBackup(cfg, bytes.NewReader(data)); !errors.Is(err, ErrExistingState) {
+		t.Fatalf("second restore: expected ErrExistingState, got %v", err)
+	}
+
+	fsm := NewPipelineFSM()
+	_, trans := hashiraft.NewInmemTransport("restored-1")
+	node, err := newRaftNodeWithTransport(cfg, fsm, trans, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("start restored node: %v", err)
+	}
+	t.Cleanup(func() { _ = node.Shutdown() })
+	waitForLeader(t, []*RaftNode{node}, 10*time.Second)
+
+	if got := len(fsm.Workers()); got != 3 {
+		t.Fatalf("restored FSM has %d workers, want 3", got)
+	}
+	// The restored cluster keeps committing after the snapshot's index.
+	cmd := mustMarshalCmd(t, CmdRegisterWorker, RegisterWorkerPayload{ID: "w-4"})
+	resp, err := node.ApplyCommand(cmd, 5*time.Second)
+	if err != nil {
+		t.Fatalf("apply after restore: %v", err)
+	}
+	if epoch, _ := resp.(uint64); epoch != 4 {
+		t.Errorf("epoch after restore = %v, want 4", resp)
+	}
+	if idx := node.raft.LastIndex(); idx <= hdr.Index {
+		t.Errorf("last index %d did not advance past snapshot index %d", idx, hdr.Index)
+	}
+}
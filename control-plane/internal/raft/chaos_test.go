@@ -0,0 +1,128 @@
+package raft
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestChaosMinorityPartitionedLeader(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping chaos test in short mode")
+	}
+	c := newChaosCluster(t, 3)
+	old := c.waitLeader(15 * time.Second)
+	if err := c.write(old, "before", 2*time.Second); err != nil {
+		t.Fatalf("write before partition: %v", err)
+	}
+
+	c.isolate(old)
+	if err := c.write(old, "stranded", 500*time.Millisecond); err == nil {
+		t.Fatal("isolated leader committed a write without a quorum")
+	}
+	var majority []int
+	for i := range c.ids {
+		if i != old {
+			majority = append(majority, i)
+		}
+	}
+	leader := c.waitLeader(15*time.Second, majority...)
+	if err := c.write(leader, "after", 2*time.Second); err != nil {
+		t.Fatalf("write on majority leader: %v", err)
+	}
+
+	c.checkInvariants()
+	for i, fsm := range c.fsms {
+		if fsm.GetWorker("stranded") != nil {
+			t.Errorf("%s applied the uncommitted write from the isolated leader", c.ids[i])
+		}
+	}
+}
+
+func TestChaosSplitBrainAttempt(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping chaos test in short mode")
+	}
+	c := newChaosCluster(t, 5)
+	old := c.waitLeader(15 * time.Second)
+
+	// Keep the leader on the minority side with one follower.
+	minority := []int{old, (old + 1) % 5}
+	var majority []int
+	for i := range c.ids {
+		if i != minority[0] && i != minority[1] {
+			majority = append(majority, i)
+		}
+	}
+	c.partition(minority, majority)
+
+	leader := c.waitLeader(15*time.Second, majority...)
+	for k := 0; k < 5; k++ {
+		if err := c.write(leader, fmt.Sprintf("majority-%d", k), 2*time.Second); err != nil {
+			t.Fatalf("majority write %d: %v", k, err)
+		}
+		// Both sides try; only the majority may succeed.
+		for _, i := range minority {
+			if err := c.write(i, fmt.Sprintf("minority-%d-%d", i, k), 100*time.Millisecond); err == nil {
+				t.Fatalf("%s committed on the minority side", c.ids[i])
+			}
+		}
+	}
+
+	c.checkInvariants()
+}
+
+func TestChaosAsymmetricLink(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping chaos test in short mode")
+	}
+	c := newChaosCluster(t, 3)
+	leader := c.waitLeader(15 * time.Second)
+	follower := (leader + 1) % 3
+
+	// The follower hears the leader but its replies never arrive.
+	c.netem.Block(c.addrs[follower], c.addrs[leader])
+	for k := 0; k < 5; k++ {
+		l := c.waitLeader(15 * time.Second)
+		if err := c.write(l, fmt.Sprintf("asym-%d", k), 3*time.Second); err != nil {
+			t.Fatalf("write %d with a one-way link: %v", k, err)
+		}
+	}
+
+	c.checkInvariants()
+}
+
+func TestChaosCrashRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping chaos test in short mode")
+	}
+	c := newChaosCluster(t, 3)
+	leader := c.waitLeader(15 * time.Second)
+	if err := c.write(leader, "w1", 2*time.Second); err != nil {
+		t.Fatalf("write w1: %v", err)
+	}
+
+	follower := (leader + 1) % 3
+	c.crash(follower)
+	if err := c.write(leader, "w2", 2*time.Second); err != nil {
+		t.Fatalf("write w2 with a follower down: %v", err)
+	}
+	c.start(follower)
+
+	// Losing the leader forces an election among nodes that include the
+	// restarted follower, which must have caught up from its own log.
+	c.crash(leader)
+	var rest []int
+	for i := range c.ids {
+		if i != leader {
+			rest = append(rest, i)
+		}
+	}
+	next := c.waitLeader(15*time.Second, rest...)
+	if err := c.write(next, "w3", 2*time.Second); err != nil {
+		t.Fatalf("write w3 after leader crash: %v", err)
+	}
+	c.start(leader)
+
+	c.checkInvariants()
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
@@ -18,6 +19,48 @@ type CommandType string
 const (
 	CmdRegisterWorker     CommandType = "register_worker"
 	CmdUpdateWorkerStatus CommandType = "update_worker_status"
+	CmdQuarantineWorker   CommandType = "quarantine_worker"
+	CmdRemoveWorker       CommandType = "remove_worker"
+	CmdRevokeWorker       CommandType = "revoke_worker"
+	CmdAddCARoot          CommandType = "add_ca_root"
+	CmdRetireCARoot       CommandType = "retire_ca_root"
+	CmdRecordCert         CommandType = "record_cert"
+	CmdRevokeCert         CommandType = "revoke_cert"
+	CmdSubmitTask         CommandType = "submit_task"
+	CmdCancelTask         CommandType = "cancel_task"
+	CmdAssignTask         CommandType = "assign_task"
+	CmdRenewTaskLease     CommandType = "renew_task_lease"
+	CmdCompleteTask       CommandType = "complete_task"
+	CmdRequeueTask        CommandType = "requeue_task"
+	CmdSubmitJob          CommandType = "submit_job"
+	CmdSpeculateTask      CommandType = "speculate_task"
+	CmdCommitTaskOutput   CommandType = "commit_task_output"
+	CmdKillTask           CommandType = "kill_task"
+	CmdRevokeTaskLease    CommandType = "revoke_task_lease"
+	CmdCancelJob          CommandType = "cancel_job"
+)
+
+// maxTombstones bounds the audit history of removed workers kept in the FSM.
+const maxTombstones = 256
+
+// snapshotVersion is written into every snapshot. Restore accepts every
+// version up to it and starts state an older version lacks out empty:
+//
+//	0  a bare worker map (the original unversioned format)
+//	1  workers, tombstones, credentials and certificate authority
+//	2  adds tasks
+//	3  adds jobs
+//	4  adds dead letters
+//	5  map splits keep only their non-empty outputs
+const snapshotVersion = 5
+
+// Worker status values stored in WorkerInfo.Status.
+const (
+	WorkerOnline      = "online"
+	WorkerSuspect     = "suspect" // heartbeats overdue; not yet declared dead
+	WorkerOffline     = "offline"
+	WorkerQuarantined = "quarantined" // flapping; excluded from work until QuarantinedUntil
+	WorkerRevoked     = "revoked"     // credential revoked; may not heartbeat or re-register
 )
 
 // Command is the envelope for all FSM commands. Payload is type-specific JSON.
@@ -27,10 +70,14 @@ type Command struct {
 }
 
 // RegisterWorkerPayload carries fields for a register_worker command.
+// CredentialHash is the hex SHA-256 of the per-worker credential issued by the
+// leader; empty when worker authentication is disabled.
 type RegisterWorkerPayload struct {
-	ID       string `json:"id"`
-	Address  string `json:"address"`
-	CloudTag string `json:"cloud_tag"`
+	ID             string    `json:"id"`
+	Address        string    `json:"address"`
+	CloudTag       string    `json:"cloud_tag"`
+	CredentialHash string    `json:"credential_hash,omitempty"`
+	Capacity       Resources `json:"capacity,omitzero"` // declared by the worker; zero if unknown
 }
 
 // UpdateWorkerStatusPayload carries fields for an update_worker_status command.
@@ -39,6 +86,50 @@ type UpdateWorkerStatusPayload struct {
 	Status string `json:"status"`
 }
 
+// QuarantineWorkerPayload carries fields for a quarantine_worker command.
+// Until is computed by the leader so every replica applies the same deadline.
+type QuarantineWorkerPayload struct {
+	ID     string    `json:"id"`
+	Reason string    `json:"reason"`
+	Until  time.Time `json:"until"`
+}
+
+// RemoveWorkerPayload carries fields for a remove_worker command.
+// The FSM only removes a worker that is still offline when the entry commits,
+// so a worker that re-registered in the meantime is left alone.
+type RemoveWorkerPayload struct {
+	ID        string    `json:"id"`
+	Reason    string    `json:"reason"`
+	RemovedAt time.Time `json:"removed_at"`
+}
+
+// RevokeWorkerPayload carries fields for a revoke_worker command.
+type RevokeWorkerPayload struct {
+	ID        string    `json:"id"`
+	Reason    string    `json:"reason"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// WorkerRevocation records a revoked worker credential. It outlives the worker
+// entry so the worker ID cannot simply re-register with the join token.
+type WorkerRevocation struct {
+	ID        string    `json:"id"`
+	Reason    string    `json:"reason"`
+	RevokedAt time.Time `json:"revoked_at"`
+	Index     uint64    `json:"index"`
+}
+
+// WorkerTombstone records a worker removed from the FSM, for auditing.
+type WorkerTombstone struct {
+	ID        string    `json:"id"`
+	Address   string    `json:"address"`
+	CloudTag  string    `json:"cloud_tag"`
+	LastSeen  time.Time `json:"last_seen"`
+	RemovedAt time.Time `json:"removed_at"`
+	Reason    string    `json:"reason"`
+	Index     uint64    `json:"index"` // Raft log index of the remove_worker entry
+}
+
 // WorkerInfo holds runtime state for a registered worker.
 type WorkerInfo struct {
 	ID       string    `json:"id"`
@@ -46,19 +137,73 @@ type WorkerInfo struct {
 	CloudTag string    `json:"cloud_tag"`
 	Status   string    `json:"status"`
 	LastSeen time.Time `json:"last_seen"`
+	Epoch    uint64    `json:"epoch"` // bumped on every registration; fences out older processes
+	Capacity Resources `json:"capacity,omitzero"`
+
+	// Quarantine state — set by quarantine_worker, cleared when the worker is
+	// re-admitted. QuarantineCount survives re-registration so backoff keeps growing.
+	QuarantineReason string    `json:"quarantine_reason,omitempty"`
+	QuarantinedUntil time.Time `json:"quarantined_until,omitzero"`
+	QuarantineCount  int       `json:"quarantine_count,omitempty"`
 }
 
 // PipelineFSM is the Raft finite state machine for the control plane.
 // Raft calls Apply() serially, so map mutations are safe without a lock.
 // External readers (HTTP handlers) hold mu.RLock to avoid data races.
 type PipelineFSM struct {
-	mu      sync.RWMutex
-	workers map[string]*WorkerInfo
+	mu         sync.RWMutex
+	workers    map[string]*WorkerInfo
+	tombstones []WorkerTombstone // oldest first, at most maxTombstones
+	lastEpoch  uint64            // highest registration epoch handed out
+
+	// Kept apart from WorkerInfo so hashes never appear in /cluster-state.
+	credentials map[string]string // worker ID → hex SHA-256 of its credential
+	revocations map[string]*WorkerRevocation
+
+	caRoots      []*CARoot            // oldest first; exactly one is Active once initialised
+	issuedCerts  []IssuedCert         // oldest first, at most maxIssuedCerts
+	revokedCerts map[string]time.Time // hex serial → NotAfter, pruned once expired
+
+	tasks         map[string]*Task
+	finishedTasks []string // IDs of terminal tasks, oldest first, at most maxFinishedTasks
+
+	jobs         map[string]*Job
+	finishedJobs []string // IDs of terminal jobs, oldest first, at most maxFinishedJobs
+
+	deadLetters []DeadLetter // oldest first, at most maxDeadLetters
+}
+
+// fsmState is the serialised form of PipelineFSM used for snapshots.
+type fsmState struct {
+	Version    int                    `json:"version"`
+	Workers    map[string]*WorkerInfo `json:"workers"`
+	Tombstones []WorkerTombstone      `json:"tombstones,omitempty"`
+	LastEpoch  uint64                 `json:"last_epoch"`
+
+	Credentials map[string]string            `json:"credentials,omitempty"`
+	Revocations map[string]*WorkerRevocation `json:"revocations,omitempty"`
+
+	CARoots      []*CARoot            `json:"ca_roots,omitempty"`
+	IssuedCerts  []IssuedCert         `json:"issued_certs,omitempty"`
+	RevokedCerts map[string]time.Time `json:"revoked_certs,omitempty"`
+
+	Tasks       map[string]*Task `json:"tasks,omitempty"`
+	Jobs        map[string]*Job  `json:"jobs,omitempty"`
+	DeadLetters []DeadLetter     `json:"dead_letters,omitempty"`
 }
 
 // NewPipelineFSM constructs a ready-to-use PipelineFSM.
 func NewPipelineFSM() *PipelineFSM {
-	return &PipelineFSM{workers: make(map[string]*WorkerInfo)}
+	return &PipelineFSM{
+		workers:     make(map[string]*WorkerInfo),
+		credentials: make(map[string]string),
+		revocations: make(map[string]*WorkerRevocation),
+
+		revokedCerts: make(map[string]time.Time),
+
+		tasks: make(map[string]*Task),
+		jobs:  make(map[string]*Job),
+	}
 }
 
 // Apply is called by Raft once a log entry is committed by a quorum.
@@ -77,33 +222,96 @@ func (f *PipelineFSM) Apply(log *hashiraft.Log) interface{} {
 
 	switch cmd.Type {
 	case CmdRegisterWorker:
-		return f.applyRegisterWorker(cmd.Payload, log.Index)
+		return f.applyRegisterWorker(cmd.Payload, log.Index, appendedAt(log))
 	case CmdUpdateWorkerStatus:
-		return f.applyUpdateWorkerStatus(cmd.Payload, log.Index)
+		return f.applyUpdateWorkerStatus(cmd.Payload, log.Index, appendedAt(log))
+	case CmdQuarantineWorker:
+		return f.applyQuarantineWorker(cmd.Payload, log.Index)
+	case CmdRemoveWorker:
+		return f.applyRemoveWorker(cmd.Payload, log.Index)
+	case CmdRevokeWorker:
+		return f.applyRevokeWorker(cmd.Payload, log.Index)
+	case CmdAddCARoot:
+		return f.applyAddCARoot(cmd.Payload, log.Index)
+	case CmdRetireCARoot:
+		return f.applyRetireCARoot(cmd.Payload, log.Index)
+	case CmdRecordCert:
+		return f.applyRecordCert(cmd.Payload, log.Index)
+	case CmdRevokeCert:
+		return f.applyRevokeCert(cmd.Payload, log.Index)
+	case CmdSubmitTask:
+		return f.applySubmitTask(cmd.Payload, log.Index)
+	case CmdCancelTask:
+		return f.applyCancelTask(cmd.Payload, log.Index)
+	case CmdAssignTask:
+		return f.applyAssignTask(cmd.Payload, log.Index)
+	case CmdRenewTaskLease:
+		return f.applyRenewTaskLease(cmd.Payload, log.Index)
+	case CmdCompleteTask:
+		return f.applyCompleteTask(cmd.Payload, log.Index)
+	case CmdRequeueTask:
+		return f.applyRequeueTask(cmd.Payload, log.Index)
+	case CmdSubmitJob:
+		return f.applySubmitJob(cmd.Payload, log.Index)
+	case CmdSpeculateTask:
+		return f.applySpeculateTask(cmd.Payload, log.Index)
+	case CmdCommitTaskOutput:
+		return f.applyCommitTaskOutput(cmd.Payload, log.Index)
+	case CmdKillTask:
+		return f.applyKillTask(cmd.Payload, log.Index)
+	case CmdRevokeTaskLease:
+		return f.applyRevokeTaskLease(cmd.Payload, log.Index)
+	case CmdCancelJob:
+		return f.applyCancelJob(cmd.Payload, log.Index)
 	default:
 		slog.Warn("FSM Apply: unknown command type", "type", cmd.Type, "index", log.Index)
 		return fmt.Errorf("unknown command type: %s", cmd.Type)
 	}
 }
 
-func (f *PipelineFSM) applyRegisterWorker(raw json.RawMessage, index uint64) interface{} {
+// appendedAt returns the leader's append time for log, so every replica stamps
+// the same LastSeen. Entries without one (direct FSM tests) fall back to now.
+func appendedAt(log *hashiraft.Log) time.Time {
+	if log.AppendedAt.IsZero() {
+		return time.Now().UTC()
+	}
+	return log.AppendedAt.UTC()
+}
+
+// applyRegisterWorker returns the new registration epoch (uint64) on success.
+func (f *PipelineFSM) applyRegisterWorker(raw json.RawMessage, index uint64, at time.Time) interface{} {
 	var p RegisterWorkerPayload
 	if err := json.Unmarshal(raw, &p); err != nil {
 		return fmt.Errorf("unmarshal register_worker: %w", err)
 	}
-	f.workers[p.ID] = &WorkerInfo{
+	if _, revoked := f.revocations[p.ID]; revoked {
+		return fmt.Errorf("worker %q credential revoked", p.ID)
+	}
+	f.lastEpoch++
+	w := &WorkerInfo{
 		ID:       p.ID,
 		Address:  p.Address,
 		CloudTag: p.CloudTag,
-		Status:   "online",
-		LastSeen: time.Now().UTC(),
+		Capacity: p.Capacity,
+		Status:   WorkerOnline,
+		LastSeen: at,
+		Epoch:    f.lastEpoch,
+	}
+	if prev, ok := f.workers[p.ID]; ok {
+		w.QuarantineCount = prev.QuarantineCount
+	}
+	f.workers[p.ID] = w
+	if p.CredentialHash != "" {
+		f.credentials[p.ID] = p.CredentialHash
+	} else {
+		delete(f.credentials, p.ID)
 	}
 	slog.Info("FSM: worker registered", "worker_id", p.ID, "cloud", p.CloudTag,
-		"index", index)
-	return nil
+		"epoch", w.Epoch, "index", index)
+	return w.Epoch
 }
 
-func (f *PipelineFSM) applyUpdateWorkerStatus(raw json.RawMessage, index uint64) interface{} {
+func (f *PipelineFSM) applyUpdateWorkerStatus(raw json.RawMessage, index uint64, at time.Time) interface{} {
 	var p UpdateWorkerStatusPayload
 	if err := json.Unmarshal(raw, &p); err != nil {
 		return fmt.Errorf("unmarshal update_worker_status: %w", err)
@@ -113,43 +321,232 @@ func (f *PipelineFSM) applyUpdateWorkerStatus(raw json.RawMessage, index uint64)
 		return fmt.Errorf("worker %q not found", p.ID)
 	}
 	w.Status = p.Status
-	w.LastSeen = time.Now().UTC()
+	w.LastSeen = at
+	if p.Status != WorkerQuarantined {
+		w.QuarantineReason = ""
+		w.QuarantinedUntil = time.Time{}
+	}
 	slog.Info("FSM: worker status updated", "worker_id", p.ID, "status", p.Status, "index", index)
+	if p.Status == WorkerOffline {
+		f.requeueWorkerTasksLocked(p.ID, "offline", at, index)
+		f.workerLostLocked(p.ID, "offline", at, index)
+	}
+	return nil
+}
+
+func (f *PipelineFSM) applyQuarantineWorker(raw json.RawMessage, index uint64) interface{} {
+	var p QuarantineWorkerPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal quarantine_worker: %w", err)
+	}
+	w, ok := f.workers[p.ID]
+	if !ok {
+		return fmt.Errorf("worker %q not found", p.ID)
+	}
+	w.Status = WorkerQuarantined
+	w.QuarantineReason = p.Reason
+	w.QuarantinedUntil = p.Until
+	w.QuarantineCount++
+	slog.Warn("FSM: worker quarantined", "worker_id", p.ID, "reason", p.Reason,
+		"until", p.Until, "count", w.QuarantineCount, "index", index)
+	return nil
+}
+
+func (f *PipelineFSM) applyRemoveWorker(raw json.RawMessage, index uint64) interface{} {
+	var p RemoveWorkerPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal remove_worker: %w", err)
+	}
+	w, ok := f.workers[p.ID]
+	if !ok {
+		return fmt.Errorf("worker %q not found", p.ID)
+	}
+	if w.Status != WorkerOffline && w.Status != WorkerRevoked {
+		return fmt.Errorf("worker %q is %s, not offline", p.ID, w.Status)
+	}
+	delete(f.workers, p.ID)
+	delete(f.credentials, p.ID)
+	f.tombstones = append(f.tombstones, WorkerTombstone{
+		ID:        w.ID,
+		Address:   w.Address,
+		CloudTag:  w.CloudTag,
+		LastSeen:  w.LastSeen,
+		RemovedAt: p.RemovedAt,
+		Reason:    p.Reason,
+		Index:     index,
+	})
+	if n := len(f.tombstones); n > maxTombstones {
+		f.tombstones = append([]WorkerTombstone(nil), f.tombstones[n-maxTombstones:]...)
+	}
+	slog.Info("FSM: worker removed", "worker_id", p.ID, "reason", p.Reason, "index", index)
+	return nil
+}
+
+func (f *PipelineFSM) applyRevokeWorker(raw json.RawMessage, index uint64) interface{} {
+	var p RevokeWorkerPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal revoke_worker: %w", err)
+	}
+	delete(f.credentials, p.ID)
+	f.revocations[p.ID] = &WorkerRevocation{
+		ID:        p.ID,
+		Reason:    p.Reason,
+		RevokedAt: p.RevokedAt,
+		Index:     index,
+	}
+	if w, ok := f.workers[p.ID]; ok {
+		w.Status = WorkerRevoked
+		w.LastSeen = p.RevokedAt
+	}
+	slog.Warn("FSM: worker credential revoked", "worker_id", p.ID, "reason", p.Reason,
+		"index", index)
+	f.requeueWorkerTasksLocked(p.ID, "revoked", p.RevokedAt, index)
+	f.workerLostLocked(p.ID, "revoked", p.RevokedAt, index)
 	return nil
 }
 
 // Snapshot captures a point-in-time copy of FSM state for Raft snapshotting.
 func (f *PipelineFSM) Snapshot() (hashiraft.FSMSnapshot, error) {
 	f.mu.RLock()
-	workersCopy := make(map[string]*WorkerInfo, len(f.workers))
+	state := fsmState{
+		Version:    snapshotVersion,
+		Workers:    make(map[string]*WorkerInfo, len(f.workers)),
+		Tombstones: append([]WorkerTombstone(nil), f.tombstones...),
+		LastEpoch:  f.lastEpoch,
+
+		Credentials: make(map[string]string, len(f.credentials)),
+		Revocations: make(map[string]*WorkerRevocation, len(f.revocations)),
+
+		CARoots:      make([]*CARoot, 0, len(f.caRoots)),
+		IssuedCerts:  append([]IssuedCert(nil), f.issuedCerts...),
+		RevokedCerts: make(map[string]time.Time, len(f.revokedCerts)),
+
+		Tasks: make(map[string]*Task, len(f.tasks)),
+		Jobs:  make(map[string]*Job, len(f.jobs)),
+	}
 	for k, v := range f.workers {
 		cp := *v
-		workersCopy[k] = &cp
+		state.Workers[k] = &cp
+	}
+	for k, v := range f.credentials {
+		state.Credentials[k] = v
+	}
+	for k, v := range f.revocations {
+		cp := *v
+		state.Revocations[k] = &cp
+	}
+	for _, r := range f.caRoots {
+		cp := *r
+		state.CARoots = append(state.CARoots, &cp)
+	}
+	for k, v := range f.revokedCerts {
+		state.RevokedCerts[k] = v
+	}
+	for k, v := range f.tasks {
+		state.Tasks[k] = v.clone()
+	}
+	for k, v := range f.jobs {
+		state.Jobs[k] = v.clone()
+	}
+	for _, d := range f.deadLetters {
+		d.Attempts = slices.Clone(d.Attempts)
+		state.DeadLetters = append(state.DeadLetters, d)
 	}
 	f.mu.RUnlock()
 
-	data, err := json.Marshal(workersCopy)
+	data, err := json.Marshal(state)
 	if err != nil {
 		return nil, fmt.Errorf("snapshot marshal: %w", err)
 	}
-	slog.Info("FSM Snapshot", "workers", len(workersCopy))
+	slog.Info("FSM Snapshot", "workers", len(state.Workers), "tombstones", len(state.Tombstones),
+		"tasks", len(state.Tasks), "jobs", len(state.Jobs))
 	return &pipelineFSMSnapshot{data: data}, nil
 }
 
 // Restore replaces FSM state from a snapshot reader.
 func (f *PipelineFSM) Restore(rc io.ReadCloser) error {
 	defer rc.Close()
-	var workers map[string]*WorkerInfo
-	if err := json.NewDecoder(rc).Decode(&workers); err != nil {
-		return fmt.Errorf("restore decode: %w", err)
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("restore read: %w", err)
+	}
+	state, err := decodeFSMState(data)
+	if err != nil {
+		return err
 	}
 	f.mu.Lock()
-	f.workers = workers
+	f.workers = state.Workers
+	f.tombstones = state.Tombstones
+	f.lastEpoch = state.LastEpoch
+	f.credentials = state.Credentials
+	f.revocations = state.Revocations
+	f.caRoots = state.CARoots
+	f.issuedCerts = state.IssuedCerts
+	f.revokedCerts = state.RevokedCerts
+	f.tasks = state.Tasks
+	f.finishedTasks = finishedTaskOrder(state.Tasks)
+	f.jobs = state.Jobs
+	f.finishedJobs = finishedJobOrder(state.Jobs)
+	f.deadLetters = state.DeadLetters
 	f.mu.Unlock()
-	slog.Info("FSM Restore", "workers", len(workers))
+	slog.Info("FSM Restore", "version", state.Version, "workers", len(state.Workers),
+		"tombstones", len(state.Tombstones), "tasks", len(state.Tasks), "jobs", len(state.Jobs))
 	return nil
 }
 
+// decodeFSMState parses snapshot bytes in the current or legacy format.
+func decodeFSMState(data []byte) (*fsmState, error) {
+	var probe struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("restore decode: %w", err)
+	}
+	state := &fsmState{}
+	switch {
+	case probe.Version == nil:
+		// Pre-versioned snapshots are a bare map of worker ID → WorkerInfo.
+		if err := json.Unmarshal(data, &state.Workers); err != nil {
+			return nil, fmt.Errorf("restore decode legacy: %w", err)
+		}
+	case *probe.Version > snapshotVersion:
+		return nil, fmt.Errorf("snapshot version %d is newer than supported version %d",
+			*probe.Version, snapshotVersion)
+	default:
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("restore decode: %w", err)
+		}
+		if state.Version < 5 {
+			sparseMapOutputs(state.Jobs)
+		}
+	}
+	if state.Workers == nil {
+		state.Workers = make(map[string]*WorkerInfo)
+	}
+	if state.Credentials == nil {
+		state.Credentials = make(map[string]string)
+	}
+	if state.Revocations == nil {
+		state.Revocations = make(map[string]*WorkerRevocation)
+	}
+	if state.RevokedCerts == nil {
+		state.RevokedCerts = make(map[string]time.Time)
+	}
+	if state.Tasks == nil {
+		state.Tasks = make(map[string]*Task)
+	}
+	if state.Jobs == nil {
+		state.Jobs = make(map[string]*Job)
+	}
+	// Never hand out an epoch at or below one already held by a worker.
+	for _, w := range state.Workers {
+		if w.Epoch > state.LastEpoch {
+			state.LastEpoch = w.Epoch
+		}
+	}
+	return state, nil
+}
+
 // Workers returns a copy of all workers for external readers.
 func (f *PipelineFSM) Workers() map[string]*WorkerInfo {
 	f.mu.RLock()
@@ -174,6 +571,46 @@ func (f *PipelineFSM) GetWorker(id string) *WorkerInfo {
 	return &cp
 }
 
+// Tombstones returns a copy of the removed-worker audit history, oldest first.
+func (f *PipelineFSM) Tombstones() []WorkerTombstone {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]WorkerTombstone, len(f.tombstones))
+	copy(out, f.tombstones)
+	return out
+}
+
+// CredentialHash returns the stored credential hash for a worker, or "" if none.
+func (f *PipelineFSM) CredentialHash(id string) string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.credentials[id]
+}
+
+// Revocation returns a copy of the worker's revocation record, or nil if the
+// worker has not been revoked.
+func (f *PipelineFSM) Revocation(id string) *WorkerRevocation {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	r, ok := f.revocations[id]
+	if !ok {
+		return nil
+	}
+	cp := *r
+	return &cp
+}
+
+// Revocations returns a copy of all revocation records.
+func (f *PipelineFSM) Revocations() []WorkerRevocation {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]WorkerRevocation, 0, len(f.revocations))
+	for _, r := range f.revocations {
+		out = append(out, *r)
+	}
+	return out
+}
+
 // MarshalCommand is a convenience helper to build a JSON-encoded Command.
 func MarshalCommand(t CommandType, payload interface{}) ([]byte, error) {
 	p, err := json.Marshal(payload)
@@ -0,0 +1,125 @@
+package raft
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+
+	hashiraft "github.com/hashicorp/raft"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	raftpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/raft"
+)
+
+// GRPCTLS secures a GRPCTransport with the same node certificates and checks
+// as the TCP transport's TLSConfig. The gRPC server it is registered on is
+// shared with workers, so the server presents the node certificate and asks
+// for — but does not require — a client certificate, and RaftTransport calls
+// are refused unless the caller presented a node certificate naming a known
+// server ID. Peers dial each other with their node certificates.
+type GRPCTLS struct {
+	layer *tlsStreamLayer // verifier only; never listens
+}
+
+// NewGRPCTLS loads cfg. nodeID, localAddr and peers are as in Config; fsm is
+// consulted for revoked certificates when it implements CertRevoked.
+func NewGRPCTLS(cfg TLSConfig, nodeID, localAddr string, peers []string, fsm hashiraft.FSM) (*GRPCTLS, error) {
+	layer, err := newVerifier(cfg, nodeID, localAddr, peers, fsm)
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCTLS{layer: layer}, nil
+}
+
+// newVerifier returns a stream layer that never listens, for checking node
+// certificates outside the Raft transport.
+func newVerifier(cfg TLSConfig, nodeID, localAddr string, peers []string, fsm hashiraft.FSM) (*tlsStreamLayer, error) {
+	certs, err := newCertReloader(cfg)
+	if err != nil {
+		return nil, err
+	}
+	revoked, _ := fsm.(certRevoker)
+	servers := peersToServers(peers, nodeID, hashiraft.ServerAddress(localAddr))
+	return newTLSStreamLayer(nil, nil, certs, servers, revoked), nil
+}
+
+// ServerCredentials returns the gRPC server credentials: the current node
+// certificate, verifying client certificates against the CA when given.
+func (g *GRPCTLS) ServerCredentials() credentials.TransportCredentials {
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := g.layer.certs.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   tls.VerifyClientCertIfGiven,
+				NextProtos:   []string{"h2"},
+			}, nil
+		},
+	})
+}
+
+// clientCredentials returns the credentials for dialing target, which must
+// present a certificate naming the server ID registered for it.
+func (g *GRPCTLS) clientCredentials(target hashiraft.ServerAddress) credentials.TransportCredentials {
+	_, pool := g.layer.certs.current()
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := g.layer.certs.current()
+			return cert, nil
+		},
+		RootCAs:    pool,
+		ServerName: string(g.layer.serverID(target)),
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return g.layer.checkRevoked(cs.PeerCertificates[0])
+		},
+	})
+}
+
+// UnaryInterceptor refuses unary RaftTransport calls from callers that are
+// not nodes; other services pass through.
+func (g *GRPCTLS) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if err := g.authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor is UnaryInterceptor for streaming calls, such as
+// AppendEntriesPipeline and InstallSnapshot.
+func (g *GRPCTLS) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if err := g.authorize(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+var raftMethodPrefix = "/" + raftpb.RaftTransport_ServiceDesc.ServiceName + "/"
+
+func (g *GRPCTLS) authorize(ctx context.Context, method string) error {
+	if !strings.HasPrefix(method, raftMethodPrefix) {
+		return nil
+	}
+	p, _ := peer.FromContext(ctx)
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return status.Error(codes.Unauthenticated, "raft RPCs require a node certificate")
+	}
+	if err := g.layer.verifyInbound(info.State); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package raft
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	raftpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/raft"
+)
+
+// serveGRPCTLSCluster starts n gRPC servers on loopback, each carrying a
+// TLS-secured Raft transport with a node certificate for "node-<i+1>".
+func serveGRPCTLSCluster(t *testing.T, ca *testCA, n int) ([]*GRPCTransport, []string) {
+	t.Helper()
+	listeners := make([]net.Listener, n)
+	peers := make([]string, n)
+	for i := range listeners {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		listeners[i] = ln
+		peers[i] = fmt.Sprintf("node-%d=%s", i+1, ln.Addr())
+	}
+	trans := make([]*GRPCTransport, n)
+	for i, ln := range listeners {
+		id := fmt.Sprintf("node-%d", i+1)
+		g, err := NewGRPCTLS(ca.writeNodeCert(t, t.TempDir(), id, int64(i+2)), id, ln.Addr().String(), peers, nil)
+		if err != nil {
+			t.Fatalf("grpc tls %s: %v", id, err)
+		}
+		trans[i] = NewGRPCTransportTLS(ln.Addr().String(), g)
+		srv := grpc.NewServer(grpc.Creds(g.ServerCredentials()),
+			grpc.UnaryInterceptor(g.UnaryInterceptor()), grpc.StreamInterceptor(g.StreamInterceptor()))
+		trans[i].Register(srv)
+		go func() { _ = srv.Serve(ln) }()
+		t.Cleanup(func() {
+			srv.Stop()
+			_ = trans[i].Close()
+		})
+	}
+	return trans, peers
+}
+
+func TestGRPCTransportTLSElection(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping gRPC TLS election test in short mode")
+	}
+	trans, peers := serveGRPCTLSCluster(t, newTestCA(t, "raft-ca"), 3)
+	logger := hclog.NewNullLogger()
+	nodes := make([]*RaftNode, len(trans))
+	for i := range trans {
+		var err error
+		nodes[i], err = newRaftNodeWithTransport(Config{
+			NodeID:    fmt.Sprintf("node-%d", i+1),
+			DataDir:   t.TempDir(),
+			Bootstrap: true,
+			Peers:     peers,
+			RaftAddr:  string(trans[i].LocalAddr()),
+		}, NewPipelineFSM(), trans[i], logger)
+		if err != nil {
+			t.Fatalf("create node %d: %v", i+1, err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, node := range nodes {
+			_ = node.Shutdown()
+		}
+	})
+	assertSingleLeader(t, nodes)
+}
+
+func TestGRPCTLSRefusesRaftCallsFromNonNodes(t *testing.T) {
+	ca := newTestCA(t, "raft-ca")
+	trans, _ := serveGRPCTLSCluster(t, ca, 1)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca.pem)
+	call := func(certs ...tls.Certificate) error {
+		t.Helper()
+		conn, err := grpc.NewClient(string(trans[0].LocalAddr()), grpc.WithTransportCredentials(
+			credentials.NewTLS(&tls.Config{RootCAs: pool, ServerName: "node-1", Certificates: certs})))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = raftpb.NewRaftTransportClient(conn).RequestVote(ctx, &raftpb.RaftMessage{})
+		return err
+	}
+	keyPair := func(cfg TLSConfig) tls.Certificate {
+		t.Helper()
+		c, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			t.Fatalf("load key pair: %v", err)
+		}
+		return c
+	}
+
+	// A worker connecting without a certificate, or with a worker
+	// certificate that names a node ID, gets nowhere near Raft.
+	if err := call(); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("call without a certificate: %v", err)
+	}
+	worker := keyPair(ca.writeCert(t, t.TempDir(), "node-1", 10, x509.ExtKeyUsageClientAuth))
+	if err := call(worker); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("call with a worker certificate: %v", err)
+	}
+	outsider := keyPair(ca.writeNodeCert(t, t.TempDir(), "node-9", 11))
+	if err := call(outsider); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("call with an unknown node's certificate: %v", err)
+	}
+}
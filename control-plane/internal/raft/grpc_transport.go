@@ -0,0 +1,569 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-msgpack/v2/codec"
+	hashiraft "github.com/hashicorp/raft"
+	"google.golang.org/grpc"
+
+	raftpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/raft"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+)
+
+const (
+	grpcTransportTimeout = 10 * time.Second
+	snapshotChunkSize    = 256 << 10 // also the unit InstallSnapshot timeouts scale by
+	pipelineMaxInFlight  = 128
+)
+
+// msgpackHandle matches NetworkTransport's default encoding.
+var msgpackHandle = &codec.MsgpackHandle{BasicHandle: codec.BasicHandle{TimeNotBuiltin: true}}
+
+// GRPCTransport implements hashiraft.Transport over gRPC so Raft traffic can
+// share the control plane's existing gRPC server and port instead of a
+// dedicated TCP listener. Register it on the server with Register; peers are
+// addressed by their gRPC host:port.
+type GRPCTransport struct {
+	localAddr hashiraft.ServerAddress
+	dialOpts  []grpc.DialOption
+	tls       *GRPCTLS // nil unless built by NewGRPCTransportTLS
+	consumeCh chan hashiraft.RPC
+
+	heartbeatMu sync.Mutex
+	heartbeatFn func(hashiraft.RPC)
+
+	connsMu sync.Mutex
+	conns   map[hashiraft.ServerAddress]*grpc.ClientConn
+
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+}
+
+var (
+	_ hashiraft.Transport   = (*GRPCTransport)(nil)
+	_ hashiraft.WithPreVote = (*GRPCTransport)(nil)
+	_ hashiraft.WithClose   = (*GRPCTransport)(nil)
+)
+
+// NewGRPCTransport returns a transport advertising localAddr (this node's gRPC
+// host:port). dialOpts are used for outbound connections to peers and must
+// include transport credentials.
+func NewGRPCTransport(localAddr string, dialOpts ...grpc.DialOption) *GRPCTransport {
+	return &GRPCTransport{
+		localAddr:  hashiraft.ServerAddress(localAddr),
+		dialOpts:   dialOpts,
+		consumeCh:  make(chan hashiraft.RPC),
+		conns:      make(map[hashiraft.ServerAddress]*grpc.ClientConn),
+		shutdownCh: make(chan struct{}),
+	}
+}
+
+// NewGRPCTransportTLS returns a transport that dials peers with the node
+// certificate in tls, requiring each to present the server ID registered for
+// its address. Serve it on a server using tls's credentials and interceptors.
+func NewGRPCTransportTLS(localAddr string, tls *GRPCTLS, dialOpts ...grpc.DialOption) *GRPCTransport {
+	t := NewGRPCTransport(localAddr, dialOpts...)
+	t.tls = tls
+	return t
+}
+
+// Register adds the Raft service to srv, which then carries inbound Raft RPCs
+// through its own credentials and interceptors.
+func (t *GRPCTransport) Register(srv *grpc.Server) {
+	raftpb.RegisterRaftTransportServer(srv, &grpcRaftServer{t: t})
+}
+
+// Consumer implements hashiraft.Transport.
+func (t *GRPCTransport) Consumer() <-chan hashiraft.RPC {
+	return t.consumeCh
+}
+
+// LocalAddr implements hashiraft.Transport.
+func (t *GRPCTransport) LocalAddr() hashiraft.ServerAddress {
+	return t.localAddr
+}
+
+// EncodePeer implements hashiraft.Transport.
+func (t *GRPCTransport) EncodePeer(_ hashiraft.ServerID, addr hashiraft.ServerAddress) []byte {
+	return []byte(addr)
+}
+
+// DecodePeer implements hashiraft.Transport.
+func (t *GRPCTransport) DecodePeer(buf []byte) hashiraft.ServerAddress {
+	return hashiraft.ServerAddress(buf)
+}
+
+// SetHeartbeatHandler implements hashiraft.Transport.
+func (t *GRPCTransport) SetHeartbeatHandler(cb func(rpc hashiraft.RPC)) {
+	t.heartbeatMu.Lock()
+	t.heartbeatFn = cb
+	t.heartbeatMu.Unlock()
+}
+
+// Close stops serving inbound RPCs and closes all peer connections.
+func (t *GRPCTransport) Close() error {
+	t.shutdownOnce.Do(func() { close(t.shutdownCh) })
+	t.connsMu.Lock()
+	defer t.connsMu.Unlock()
+	for addr, conn := range t.conns {
+		_ = conn.Close()
+		delete(t.conns, addr)
+	}
+	return nil
+}
+
+// ── Outbound ─────────────────────────────────────────────────────────────────
+
+// AppendEntries implements hashiraft.Transport.
+func (t *GRPCTransport) AppendEntries(_ hashiraft.ServerID, target hashiraft.ServerAddress,
+	args *hashiraft.AppendEntriesRequest, resp *hashiraft.AppendEntriesResponse) error {
+	return t.unary("AppendEntries", target, args, resp, raftpb.RaftTransportClient.AppendEntries)
+}
+
+// RequestVote implements hashiraft.Transport.
+func (t *GRPCTransport) RequestVote(_ hashiraft.ServerID, target hashiraft.ServerAddress,
+	args *hashiraft.RequestVoteRequest, resp *hashiraft.RequestVoteResponse) error {
+	return t.unary("RequestVote", target, args, resp, raftpb.RaftTransportClient.RequestVote)
+}
+
+// RequestPreVote implements hashiraft.WithPreVote.
+func (t *GRPCTransport) RequestPreVote(_ hashiraft.ServerID, target hashiraft.ServerAddress,
+	args *hashiraft.RequestPreVoteRequest, resp *hashiraft.RequestPreVoteResponse) error {
+	return t.unary("RequestPreVote", target, args, resp, raftpb.RaftTransportClient.RequestPreVote)
+}
+
+// TimeoutNow implements hashiraft.Transport.
+func (t *GRPCTransport) TimeoutNow(_ hashiraft.ServerID, target hashiraft.ServerAddress,
+	args *hashiraft.TimeoutNowRequest, resp *hashiraft.TimeoutNowResponse) error {
+	return t.unary("TimeoutNow", target, args, resp, raftpb.RaftTransportClient.TimeoutNow)
+}
+
+type unaryCall func(raftpb.RaftTransportClient, context.Context, *raftpb.RaftMessage,
+	...grpc.CallOption) (*raftpb.RaftMessage, error)
+
+func (t *GRPCTransport) unary(rpc string, target hashiraft.ServerAddress, args, resp interface{},
+	call unaryCall) (err error) {
+
+	start := time.Now()
+	defer func() { observeRPC(rpc, start, err) }()
+
+	payload, err := encodeMsg(args)
+	if err != nil {
+		return err
+	}
+	client, err := t.client(target)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), grpcTransportTimeout)
+	defer cancel()
+	out, err := call(client, ctx, &raftpb.RaftMessage{Payload: payload})
+	if err != nil {
+		return err
+	}
+	return decodeResponse(out, resp)
+}
+
+// InstallSnapshot implements hashiraft.Transport, streaming data in chunks.
+func (t *GRPCTransport) InstallSnapshot(_ hashiraft.ServerID, target hashiraft.ServerAddress,
+	args *hashiraft.InstallSnapshotRequest, resp *hashiraft.InstallSnapshotResponse, data io.Reader) (err error) {
+
+	start := time.Now()
+	defer func() { observeRPC("InstallSnapshot", start, err) }()
+
+	req, err := encodeMsg(args)
+	if err != nil {
+		return err
+	}
+	client, err := t.client(target)
+	if err != nil {
+		return err
+	}
+	// Scale the deadline with the snapshot size, as NetworkTransport does.
+	timeout := grpcTransportTimeout * time.Duration(args.Size/snapshotChunkSize+1)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stream, err := client.InstallSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&raftpb.SnapshotChunk{Request: req}); err != nil {
+		return err
+	}
+	for {
+		// A fresh buffer per chunk: gRPC may hold on to a sent message.
+		buf := make([]byte, snapshotChunkSize)
+		n, readErr := data.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&raftpb.SnapshotChunk{Data: buf[:n]}); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	out, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	return decodeResponse(out, resp)
+}
+
+// AppendEntriesPipeline implements hashiraft.Transport with a bidirectional
+// stream, so a leader can keep many AppendEntries in flight across a
+// high-latency cross-cloud link.
+func (t *GRPCTransport) AppendEntriesPipeline(_ hashiraft.ServerID,
+	target hashiraft.ServerAddress) (hashiraft.AppendPipeline, error) {
+
+	client, err := t.client(target)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.AppendEntriesPipeline(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	p := &grpcPipeline{
+		stream:     stream,
+		cancel:     cancel,
+		inflight:   make(chan *appendFuture, pipelineMaxInFlight),
+		doneCh:     make(chan hashiraft.AppendFuture, pipelineMaxInFlight),
+		shutdownCh: make(chan struct{}),
+	}
+	go p.receive()
+	return p, nil
+}
+
+// client returns a cached connection to target, creating it on first use.
+func (t *GRPCTransport) client(target hashiraft.ServerAddress) (raftpb.RaftTransportClient, error) {
+	t.connsMu.Lock()
+	defer t.connsMu.Unlock()
+	select {
+	case <-t.shutdownCh:
+		return nil, hashiraft.ErrTransportShutdown
+	default:
+	}
+	conn, ok := t.conns[target]
+	if !ok {
+		opts := t.dialOpts
+		if t.tls != nil {
+			opts = append(slices.Clip(opts), grpc.WithTransportCredentials(t.tls.clientCredentials(target)))
+		}
+		var err error
+		conn, err = grpc.NewClient(string(target), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("dial raft peer %s: %w", target, err)
+		}
+		t.conns[target] = conn
+	}
+	return raftpb.NewRaftTransportClient(conn), nil
+}
+
+// grpcPipeline implements hashiraft.AppendPipeline. Responses arrive in
+// request order on the stream and complete futures in the same order.
+type grpcPipeline struct {
+	stream grpc.BidiStreamingClient[raftpb.RaftMessage, raftpb.RaftMessage]
+	cancel context.CancelFunc
+
+	sendMu     sync.Mutex
+	inflight   chan *appendFuture
+	doneCh     chan hashiraft.AppendFuture
+	shutdownCh chan struct{}
+	closeOnce  sync.Once
+}
+
+func (p *grpcPipeline) AppendEntries(args *hashiraft.AppendEntriesRequest,
+	resp *hashiraft.AppendEntriesResponse) (hashiraft.AppendFuture, error) {
+
+	f := &appendFuture{start: time.Now(), args: args, resp: resp, done: make(chan struct{})}
+	payload, err := encodeMsg(args)
+	if err != nil {
+		return nil, err
+	}
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	if err := p.stream.Send(&raftpb.RaftMessage{Payload: payload}); err != nil {
+		return nil, err
+	}
+	select {
+	case p.inflight <- f:
+	case <-p.shutdownCh:
+		return nil, hashiraft.ErrPipelineShutdown
+	}
+	return f, nil
+}
+
+func (p *grpcPipeline) Consumer() <-chan hashiraft.AppendFuture {
+	return p.doneCh
+}
+
+func (p *grpcPipeline) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.shutdownCh)
+		p.cancel()
+	})
+	return nil
+}
+
+func (p *grpcPipeline) receive() {
+	for {
+		var f *appendFuture
+		select {
+		case f = <-p.inflight:
+		case <-p.shutdownCh:
+			return
+		}
+		msg, err := p.stream.Recv()
+		if err == nil {
+			err = decodeResponse(msg, f.resp)
+		}
+		observeRPC("AppendEntriesPipeline", f.start, err)
+		f.respond(err)
+		select {
+		case p.doneCh <- f:
+		case <-p.shutdownCh:
+			return
+		}
+	}
+}
+
+// appendFuture implements hashiraft.AppendFuture.
+type appendFuture struct {
+	start time.Time
+	args  *hashiraft.AppendEntriesRequest
+	resp  *hashiraft.AppendEntriesResponse
+	err   error
+	done  chan struct{}
+}
+
+func (f *appendFuture) Error() error {
+	<-f.done
+	return f.err
+}
+
+func (f *appendFuture) Start() time.Time                           { return f.start }
+func (f *appendFuture) Request() *hashiraft.AppendEntriesRequest   { return f.args }
+func (f *appendFuture) Response() *hashiraft.AppendEntriesResponse { return f.resp }
+
+func (f *appendFuture) respond(err error) {
+	f.err = err
+	close(f.done)
+}
+
+// ── Inbound ──────────────────────────────────────────────────────────────────
+
+// grpcRaftServer serves the RaftTransport service for a GRPCTransport. It is a
+// separate type because the service's method names collide with Transport's.
+type grpcRaftServer struct {
+	raftpb.UnimplementedRaftTransportServer
+	t *GRPCTransport
+}
+
+// AppendEntries serves a unary AppendEntries, taking the heartbeat fast path
+// when Raft has registered one.
+func (s *grpcRaftServer) AppendEntries(ctx context.Context, m *raftpb.RaftMessage) (*raftpb.RaftMessage, error) {
+	var req hashiraft.AppendEntriesRequest
+	if err := decodeMsg(m.Payload, &req); err != nil {
+		return nil, err
+	}
+	metrics.RaftGRPCRPCsReceivedTotal.WithLabelValues("AppendEntries").Inc()
+	return s.t.dispatch(ctx, &req, nil, isHeartbeat(&req))
+}
+
+func (s *grpcRaftServer) RequestVote(ctx context.Context, m *raftpb.RaftMessage) (*raftpb.RaftMessage, error) {
+	var req hashiraft.RequestVoteRequest
+	if err := decodeMsg(m.Payload, &req); err != nil {
+		return nil, err
+	}
+	metrics.RaftGRPCRPCsReceivedTotal.WithLabelValues("RequestVote").Inc()
+	return s.t.dispatch(ctx, &req, nil, false)
+}
+
+func (s *grpcRaftServer) RequestPreVote(ctx context.Context, m *raftpb.RaftMessage) (*raftpb.RaftMessage, error) {
+	var req hashiraft.RequestPreVoteRequest
+	if err := decodeMsg(m.Payload, &req); err != nil {
+		return nil, err
+	}
+	metrics.RaftGRPCRPCsReceivedTotal.WithLabelValues("RequestPreVote").Inc()
+	return s.t.dispatch(ctx, &req, nil, false)
+}
+
+func (s *grpcRaftServer) TimeoutNow(ctx context.Context, m *raftpb.RaftMessage) (*raftpb.RaftMessage, error) {
+	var req hashiraft.TimeoutNowRequest
+	if err := decodeMsg(m.Payload, &req); err != nil {
+		return nil, err
+	}
+	metrics.RaftGRPCRPCsReceivedTotal.WithLabelValues("TimeoutNow").Inc()
+	return s.t.dispatch(ctx, &req, nil, false)
+}
+
+// AppendEntriesPipeline serves pipelined AppendEntries in arrival order.
+func (s *grpcRaftServer) AppendEntriesPipeline(
+	stream grpc.BidiStreamingServer[raftpb.RaftMessage, raftpb.RaftMessage]) error {
+
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req hashiraft.AppendEntriesRequest
+		if err := decodeMsg(m.Payload, &req); err != nil {
+			return err
+		}
+		metrics.RaftGRPCRPCsReceivedTotal.WithLabelValues("AppendEntriesPipeline").Inc()
+		out, err := s.t.dispatch(stream.Context(), &req, nil, false)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(out); err != nil {
+			return err
+		}
+	}
+}
+
+// InstallSnapshot feeds streamed chunks to Raft through a pipe.
+func (s *grpcRaftServer) InstallSnapshot(
+	stream grpc.ClientStreamingServer[raftpb.SnapshotChunk, raftpb.RaftMessage]) error {
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	var req hashiraft.InstallSnapshotRequest
+	if err := decodeMsg(first.Request, &req); err != nil {
+		return err
+	}
+	metrics.RaftGRPCRPCsReceivedTotal.WithLabelValues("InstallSnapshot").Inc()
+
+	pr, pw := io.Pipe()
+	go func() {
+		if len(first.Data) > 0 {
+			if _, err := pw.Write(first.Data); err != nil {
+				return
+			}
+		}
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				_ = pw.Close()
+				return
+			}
+			if err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+			if _, err := pw.Write(chunk.Data); err != nil {
+				return
+			}
+		}
+	}()
+	out, err := s.t.dispatch(stream.Context(), &req, io.LimitReader(pr, req.Size), false)
+	_ = pr.Close()
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(out)
+}
+
+// dispatch hands an RPC to Raft and waits for its response.
+func (t *GRPCTransport) dispatch(ctx context.Context, cmd interface{}, reader io.Reader,
+	heartbeat bool) (*raftpb.RaftMessage, error) {
+
+	respCh := make(chan hashiraft.RPCResponse, 1)
+	rpc := hashiraft.RPC{Command: cmd, Reader: reader, RespChan: respCh}
+
+	handled := false
+	if heartbeat {
+		t.heartbeatMu.Lock()
+		fn := t.heartbeatFn
+		t.heartbeatMu.Unlock()
+		if fn != nil {
+			fn(rpc)
+			handled = true
+		}
+	}
+	if !handled {
+		select {
+		case t.consumeCh <- rpc:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.shutdownCh:
+			return nil, hashiraft.ErrTransportShutdown
+		}
+	}
+
+	select {
+	case resp := <-respCh:
+		payload, err := encodeMsg(resp.Response)
+		if err != nil {
+			return nil, err
+		}
+		out := &raftpb.RaftMessage{Payload: payload}
+		if resp.Error != nil {
+			out.Error = resp.Error.Error()
+		}
+		return out, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.shutdownCh:
+		return nil, hashiraft.ErrTransportShutdown
+	}
+}
+
+// isHeartbeat mirrors NetworkTransport's test for an empty heartbeat append.
+func isHeartbeat(req *hashiraft.AppendEntriesRequest) bool {
+	leaderAddr := req.RPCHeader.Addr
+	if len(leaderAddr) == 0 {
+		leaderAddr = req.Leader //nolint:staticcheck // older peers only set Leader
+	}
+	return req.Term != 0 && leaderAddr != nil &&
+		req.PrevLogEntry == 0 && req.PrevLogTerm == 0 &&
+		len(req.Entries) == 0 && req.LeaderCommitIndex == 0
+}
+
+func encodeMsg(v interface{}) ([]byte, error) {
+	var buf []byte
+	if err := codec.NewEncoderBytes(&buf, msgpackHandle).Encode(v); err != nil {
+		return nil, fmt.Errorf("encode raft message: %w", err)
+	}
+	return buf, nil
+}
+
+func decodeMsg(buf []byte, v interface{}) error {
+	if err := codec.NewDecoderBytes(buf, msgpackHandle).Decode(v); err != nil {
+		return fmt.Errorf("decode raft message: %w", err)
+	}
+	return nil
+}
+
+// decodeResponse surfaces the remote handler's error or decodes the payload.
+func decodeResponse(m *raftpb.RaftMessage, resp interface{}) error {
+	if m.Error != "" {
+		return errors.New(m.Error)
+	}
+	return decodeMsg(m.Payload, resp)
+}
+
+func observeRPC(rpc string, start time.Time, err error) {
+	metrics.RaftGRPCRPCDurationMs.WithLabelValues(rpc).Observe(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		metrics.RaftGRPCRPCErrorsTotal.WithLabelValues(rpc).Inc()
+	}
+}
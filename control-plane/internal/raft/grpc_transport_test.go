@@ -0,0 +1,192 @@
+package raft
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	hashiraft "github.com/hashicorp/raft"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// serveGRPCTransport starts a gRPC server on loopback carrying only the Raft
+// service and returns the transport bound to it.
+func serveGRPCTransport(t *testing.T) *GRPCTransport {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	trans := NewGRPCTransport(ln.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	srv := grpc.NewServer()
+	trans.Register(srv)
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() {
+		srv.Stop()
+		_ = trans.Close()
+	})
+	return trans
+}
+
+func makeGRPCCluster(t *testing.T, n int) ([]*RaftNode, []*PipelineFSM) {
+	t.Helper()
+	trans := make([]*GRPCTransport, n)
+	peers := make([]string, n)
+	for i := range trans {
+		trans[i] = serveGRPCTransport(t)
+		peers[i] = fmt.Sprintf("node-%d=%s", i+1, trans[i].LocalAddr())
+	}
+
+	logger := hclog.NewNullLogger()
+	nodes := make([]*RaftNode, n)
+	fsms := make([]*PipelineFSM, n)
+	for i := range trans {
+		fsms[i] = NewPipelineFSM()
+		var err error
+		nodes[i], err = newRaftNodeWithTransport(Config{
+			NodeID:    fmt.Sprintf("node-%d", i+1),
+			DataDir:   t.TempDir(),
+			Bootstrap: true,
+			Peers:     peers,
+			RaftAddr:  string(trans[i].LocalAddr()),
+		}, fsms[i], trans[i], logger)
+		if err != nil {
+			t.Fatalf("create node %d: %v", i+1, err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, node := range nodes {
+			_ = node.Shutdown()
+		}
+	})
+	return nodes, fsms
+}
+
+func TestGRPCTransportElection(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping gRPC transport election test in short mode")
+	}
+	nodes, _ := makeGRPCCluster(t, 3)
+	assertSingleLeader(t, nodes)
+}
+
+func TestGRPCTransportReplication(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping gRPC transport replication test in short mode")
+	}
+	nodes, fsms := makeGRPCCluster(t, 3)
+	assertReplicates(t, nodes, fsms)
+}
+
+// respondOnce answers the next RPC on trans with resp, returning what the
+// handler received.
+func respondOnce(t *testing.T, trans *GRPCTransport, resp interface{}) <-chan hashiraft.RPC {
+	t.Helper()
+	got := make(chan hashiraft.RPC, 1)
+	go func() {
+		select {
+		case rpc := <-trans.Consumer():
+			if rpc.Reader != nil {
+				data, _ := io.ReadAll(rpc.Reader)
+				rpc.Reader = bytes.NewReader(data)
+			}
+			got <- rpc
+			rpc.Respond(resp, nil)
+		case <-time.After(5 * time.Second):
+			close(got)
+		}
+	}()
+	return got
+}
+
+func TestGRPCTransportInstallSnapshot(t *testing.T) {
+	server, client := serveGRPCTransport(t), serveGRPCTransport(t)
+
+	// More than one chunk so reassembly is exercised.
+	data := bytes.Repeat([]byte("snapshot"), snapshotChunkSize/4)
+	args := &hashiraft.InstallSnapshotRequest{
+		RPCHeader:    hashiraft.RPCHeader{Addr: []byte(client.LocalAddr())},
+		Term:         3,
+		LastLogIndex: 42,
+		Size:         int64(len(data)),
+	}
+	got := respondOnce(t, server, &hashiraft.InstallSnapshotResponse{Term: 3, Success: true})
+
+	var resp hashiraft.InstallSnapshotResponse
+	if err := client.InstallSnapshot("node-1", server.LocalAddr(), args, &resp, bytes.NewReader(data)); err != nil {
+		t.Fatalf("InstallSnapshot: %v", err)
+	}
+	if !resp.Success || resp.Term != 3 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	rpc, ok := <-got
+	if !ok {
+		t.Fatal("server never received the snapshot")
+	}
+	req := rpc.Command.(*hashiraft.InstallSnapshotRequest)
+	if req.LastLogIndex != 42 {
+		t.Errorf("request not decoded: %+v", req)
+	}
+	received, _ := io.ReadAll(rpc.Reader)
+	if !bytes.Equal(received, data) {
+		t.Errorf("snapshot data mismatch: got %d bytes, want %d", len(received), len(data))
+	}
+}
+
+func TestGRPCTransportPipelineOrdering(t *testing.T) {
+	server, client := serveGRPCTransport(t), serveGRPCTransport(t)
+
+	const n = 20
+	go func() {
+		for i := 0; i < n; i++ {
+			rpc := <-server.Consumer()
+			req := rpc.Command.(*hashiraft.AppendEntriesRequest)
+			rpc.Respond(&hashiraft.AppendEntriesResponse{Term: req.Term, LastLog: req.PrevLogEntry + 1, Success: true}, nil)
+		}
+	}()
+
+	pipe, err := client.AppendEntriesPipeline("node-1", server.LocalAddr())
+	if err != nil {
+		t.Fatalf("AppendEntriesPipeline: %v", err)
+	}
+	defer pipe.Close()
+
+	for i := 0; i < n; i++ {
+		args := &hashiraft.AppendEntriesRequest{Term: 1, PrevLogEntry: uint64(i), PrevLogTerm: 1}
+		if _, err := pipe.AppendEntries(args, &hashiraft.AppendEntriesResponse{}); err != nil {
+			t.Fatalf("pipeline append %d: %v", i, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case f := <-pipe.Consumer():
+			if err := f.Error(); err != nil {
+				t.Fatalf("future %d: %v", i, err)
+			}
+			if f.Request().PrevLogEntry != uint64(i) || f.Response().LastLog != uint64(i+1) {
+				t.Fatalf("future %d out of order: req=%d resp=%d", i, f.Request().PrevLogEntry, f.Response().LastLog)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for future %d", i)
+		}
+	}
+}
+
+func TestGRPCTransportRemoteError(t *testing.T) {
+	server, client := serveGRPCTransport(t), serveGRPCTransport(t)
+	go func() {
+		rpc := <-server.Consumer()
+		rpc.Respond(nil, fmt.Errorf("vote rejected"))
+	}()
+	err := client.RequestVote("node-1", server.LocalAddr(),
+		&hashiraft.RequestVoteRequest{Term: 1}, &hashiraft.RequestVoteResponse{})
+	if err == nil || err.Error() != "vote rejected" {
+		t.Fatalf("expected remote error to surface, got %v", err)
+	}
+}
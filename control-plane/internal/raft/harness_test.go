@@ -0,0 +1,328 @@
+package raft
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	hashiraft "github.com/hashicorp/raft"
+)
+
+// chaosCluster is a fault-injection harness: an N-node cluster on in-memory
+// transports behind a shared Netem, whose nodes can be partitioned, crashed
+// and restarted from their own data dirs. A background observer records every
+// leader it sees per term, and writes that commit are remembered, so that
+// checkInvariants can assert:
+//
+//   - at most one leader per term,
+//   - no committed write is lost, and
+//   - all live FSMs converge to identical state.
+type chaosCluster struct {
+	t     *testing.T
+	ids   []string
+	addrs []hashiraft.ServerAddress
+	dirs  []string
+	netem *Netem
+
+	mu        sync.Mutex
+	nodes     []*RaftNode // nil while crashed
+	fsms      []*PipelineFSM
+	trans     []*hashiraft.InmemTransport
+	leaders   map[uint64]map[string]bool
+	committed []string
+
+	stopObserve chan struct{}
+	observeDone chan struct{}
+}
+
+func newChaosCluster(t *testing.T, n int) *chaosCluster {
+	t.Helper()
+	c := &chaosCluster{
+		t:           t,
+		ids:         make([]string, n),
+		addrs:       make([]hashiraft.ServerAddress, n),
+		dirs:        make([]string, n),
+		netem:       NewNetem(Topology{}, 1),
+		nodes:       make([]*RaftNode, n),
+		fsms:        make([]*PipelineFSM, n),
+		trans:       make([]*hashiraft.InmemTransport, n),
+		leaders:     make(map[uint64]map[string]bool),
+		stopObserve: make(chan struct{}),
+		observeDone: make(chan struct{}),
+	}
+	for i := 0; i < n; i++ {
+		c.ids[i] = fmt.Sprintf("node-%d", i+1)
+		c.addrs[i] = hashiraft.ServerAddress(c.ids[i])
+		c.dirs[i] = t.TempDir()
+	}
+	for i := 0; i < n; i++ {
+		c.start(i)
+	}
+	go c.observe()
+	t.Cleanup(func() {
+		c.stopObserver()
+		for i := range c.nodes {
+			c.crash(i)
+		}
+	})
+	return c
+}
+
+// start launches node i from its data dir. Bootstrap is skipped by
+// newRaftNodeWithTransport when the dir already holds state.
+func (c *chaosCluster) start(i int) {
+	c.t.Helper()
+	_, trans := hashiraft.NewInmemTransport(c.addrs[i])
+	fsm := NewPipelineFSM()
+
+	c.mu.Lock()
+	for j, peer := range c.trans {
+		if j != i && peer != nil {
+			trans.Connect(c.addrs[j], peer)
+			peer.Connect(c.addrs[i], trans)
+		}
+	}
+	c.trans[i], c.fsms[i] = trans, fsm
+	c.mu.Unlock()
+
+	node, err := newRaftNodeWithTransport(Config{
+		NodeID:    c.ids[i],
+		DataDir:   c.dirs[i],
+		Bootstrap: true,
+		Peers:     c.ids,
+	}, fsm, c.netem.Wrap(trans), hclog.NewNullLogger())
+	if err != nil {
+		c.t.Fatalf("start %s: %v", c.ids[i], err)
+	}
+	c.mu.Lock()
+	c.nodes[i] = node
+	c.mu.Unlock()
+}
+
+// crash stops node i abruptly from its peers' point of view; its data dir is
+// kept for restart.
+func (c *chaosCluster) crash(i int) {
+	c.mu.Lock()
+	node := c.nodes[i]
+	c.nodes[i] = nil
+	for j, peer := range c.trans {
+		if j != i && peer != nil {
+			peer.Disconnect(c.addrs[i])
+		}
+	}
+	c.trans[i] = nil
+	c.mu.Unlock()
+	if node != nil {
+		if err := node.Shutdown(); err != nil {
+			c.t.Logf("shutdown %s: %v", c.ids[i], err)
+		}
+	}
+}
+
+func (c *chaosCluster) restart(i int) {
+	c.t.Helper()
+	c.crash(i)
+	c.start(i)
+}
+
+// partition cuts every link between nodes in different groups.
+func (c *chaosCluster) partition(groups ...[]int) {
+	for gi := range groups {
+		for gj := gi + 1; gj < len(groups); gj++ {
+			c.netem.Partition(c.addrsOf(groups[gi]), c.addrsOf(groups[gj]))
+		}
+	}
+}
+
+// isolate cuts node i off from everyone else.
+func (c *chaosCluster) isolate(i int) {
+	var rest []int
+	for j := range c.ids {
+		if j != i {
+			rest = append(rest, j)
+		}
+	}
+	c.partition([]int{i}, rest)
+}
+
+func (c *chaosCluster) heal() {
+	c.netem.Heal()
+}
+
+func (c *chaosCluster) addrsOf(idx []int) []hashiraft.ServerAddress {
+	out := make([]hashiraft.ServerAddress, len(idx))
+	for k, i := range idx {
+		out[k] = c.addrs[i]
+	}
+	return out
+}
+
+func (c *chaosCluster) node(i int) *RaftNode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nodes[i]
+}
+
+// waitLeader waits for a leader among nodes in among (all nodes if empty) and
+// returns its index.
+func (c *chaosCluster) waitLeader(timeout time.Duration, among ...int) int {
+	c.t.Helper()
+	if len(among) == 0 {
+		for i := range c.ids {
+			among = append(among, i)
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		for _, i := range among {
+			if n := c.node(i); n != nil && n.State() == hashiraft.Leader {
+				return i
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	c.t.Fatalf("no leader among %v within %s", among, timeout)
+	return -1
+}
+
+// write registers a worker through node i and records it if the commit
+// succeeded.
+func (c *chaosCluster) write(i int, workerID string, timeout time.Duration) error {
+	n := c.node(i)
+	if n == nil {
+		return fmt.Errorf("%s is down", c.ids[i])
+	}
+	cmd, err := MarshalCommand(CmdRegisterWorker, RegisterWorkerPayload{ID: workerID, CloudTag: "aws"})
+	if err != nil {
+		return err
+	}
+	if err := n.Apply(cmd, timeout); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.committed = append(c.committed, workerID)
+	c.mu.Unlock()
+	return nil
+}
+
+// observe samples every node's role and term until stopped.
+func (c *chaosCluster) observe() {
+	defer close(c.observeDone)
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopObserve:
+			return
+		case <-ticker.C:
+		}
+		c.mu.Lock()
+		nodes := append([]*RaftNode(nil), c.nodes...)
+		c.mu.Unlock()
+		for i, n := range nodes {
+			if n == nil {
+				continue
+			}
+			// Only trust a sample whose term didn't move while reading the state.
+			term := n.raft.CurrentTerm()
+			leader := n.State() == hashiraft.Leader
+			if !leader || n.raft.CurrentTerm() != term {
+				continue
+			}
+			c.mu.Lock()
+			if c.leaders[term] == nil {
+				c.leaders[term] = make(map[string]bool)
+			}
+			c.leaders[term][c.ids[i]] = true
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (c *chaosCluster) stopObserver() {
+	select {
+	case <-c.stopObserve:
+	default:
+		close(c.stopObserve)
+	}
+	<-c.observeDone
+}
+
+// checkInvariants heals the network, waits for the live nodes to converge and
+// asserts the harness invariants.
+func (c *chaosCluster) checkInvariants() {
+	c.t.Helper()
+	c.heal()
+
+	c.mu.Lock()
+	for term, ids := range c.leaders {
+		if len(ids) > 1 {
+			c.t.Errorf("term %d had %d leaders: %v", term, len(ids), ids)
+		}
+	}
+	committed := append([]string(nil), c.committed...)
+	c.mu.Unlock()
+
+	var live []int
+	for i := range c.ids {
+		if c.node(i) != nil {
+			live = append(live, i)
+		}
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		missing, diverged := c.divergence(live, committed)
+		if missing == "" && diverged == "" {
+			return
+		}
+		if time.Now().After(deadline) {
+			if missing != "" {
+				c.t.Errorf("committed write lost: %s", missing)
+			}
+			if diverged != "" {
+				c.t.Errorf("FSMs diverged: %s", diverged)
+			}
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// divergence reports the first missing committed write and the first FSM that
+// differs from live[0]'s, or empty strings if there are none.
+func (c *chaosCluster) divergence(live []int, committed []string) (missing, diverged string) {
+	c.mu.Lock()
+	fsms := append([]*PipelineFSM(nil), c.fsms...)
+	c.mu.Unlock()
+	for _, i := range live {
+		for _, id := range committed {
+			if fsms[i].GetWorker(id) == nil {
+				return fmt.Sprintf("%s missing on %s", id, c.ids[i]), ""
+			}
+		}
+	}
+	want := fsmBytes(c.t, fsms[live[0]])
+	for _, i := range live[1:] {
+		if got := fsmBytes(c.t, fsms[i]); !bytes.Equal(got, want) {
+			return "", fmt.Sprintf("%s differs from %s", c.ids[i], c.ids[live[0]])
+		}
+	}
+	return "", ""
+}
+
+// fsmBytes returns f's snapshot encoding, which is canonical for equal state.
+func fsmBytes(t *testing.T, f *PipelineFSM) []byte {
+	t.Helper()
+	snap, err := f.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	sink := &testSnapshotSink{buf: &bytes.Buffer{}}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	return sink.buf.Bytes()
+}
@@ -0,0 +1,512 @@
+package raft
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxFinishedJobs bounds how many terminal jobs the FSM keeps, oldest dropped
+// first. A job's tasks are evicted separately, with the other finished tasks.
+const maxFinishedJobs = 1024
+
+// Job failure policies stored in Job.FailurePolicy.
+const (
+	// JobFailFast cancels every unfinished task of the job as soon as one
+	// task fails or is cancelled.
+	JobFailFast = "fail-fast"
+	// JobContinue cancels only the tasks that depend on the failed one,
+	// directly or transitively; independent branches run to completion.
+	JobContinue = "continue"
+)
+
+// Job and stage states stored in Job.State and Stage.State.
+const (
+	JobBlocked   = "blocked" // stages only: every unfinished task waits on another stage
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled" // jobs only: by cancel_job
+)
+
+// Job is a DAG of tasks grouped into stages. A task becomes pending only once
+// every task it depends on succeeded.
+type Job struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type,omitempty"` // JobDAG if empty
+	FailurePolicy string    `json:"failure_policy"`
+	State         string    `json:"state"`
+	Stages        []*Stage  `json:"stages"`          // in submission order
+	Error         string    `json:"error,omitempty"` // the first task failure
+	Cancelled     bool      `json:"cancelled,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	FinishedAt    time.Time `json:"finished_at,omitzero"`
+	Index         uint64    `json:"index"` // Raft log index of the last change
+
+	MapReduce *MapReduce `json:"map_reduce,omitempty"` // JobMapReduce only
+}
+
+// Stage is a named group of a job's tasks. Its counters survive the eviction
+// of the finished tasks themselves.
+type Stage struct {
+	Name      string   `json:"name"`
+	DependsOn []string `json:"depends_on,omitempty"` // stage names, for display
+	TaskIDs   []string `json:"task_ids"`
+	State     string   `json:"state"`
+	Succeeded int      `json:"succeeded,omitempty"`
+	Failed    int      `json:"failed,omitempty"`
+	Cancelled int      `json:"cancelled,omitempty"`
+	// Planned counts tasks the stage will get but that don't exist yet, such
+	// as a MapReduce job's reduces before their partitions are ready.
+	Planned int `json:"planned,omitempty"`
+}
+
+// Finished reports whether the job reached a terminal state.
+func (j *Job) Finished() bool {
+	return j.State == JobSucceeded || j.State == JobFailed || j.State == JobCancelled
+}
+
+func (s *Stage) done() int { return s.Succeeded + s.Failed + s.Cancelled }
+
+func (s *Stage) complete() bool { return s.Planned == 0 && s.done() == len(s.TaskIDs) }
+
+// ValidJobFailurePolicy reports whether p is a known failure policy.
+func ValidJobFailurePolicy(p string) bool {
+	return p == JobFailFast || p == JobContinue
+}
+
+// SubmitJobPayload carries fields for a submit_job command. Each task names
+// its stage in Task.Stage and its dependencies, by task ID within the job, in
+// Task.DependsOn. The leader fills in IDs and CreatedAt; the FSM fills in the
+// stages' task lists and every state.
+type SubmitJobPayload struct {
+	Job   Job    `json:"job"`
+	Tasks []Task `json:"tasks"`
+}
+
+// CancelJobPayload carries fields for a cancel_job command.
+type CancelJobPayload struct {
+	ID          string    `json:"id"`
+	Reason      string    `json:"reason,omitempty"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
+
+// CheckDAG reports an unknown dependency or a cycle in deps, which maps each
+// node to the nodes it depends on. noun names the nodes in errors.
+func CheckDAG(noun string, deps map[string][]string) error {
+	ids := make([]string, 0, len(deps))
+	for id := range deps {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		for _, d := range deps[id] {
+			if _, ok := deps[d]; !ok {
+				return fmt.Errorf("%s %q depends on unknown %s %q", noun, id, noun, d)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	mark := make(map[string]int, len(deps))
+	var path []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch mark[id] {
+		case visited:
+			return nil
+		case visiting:
+			start := slices.Index(path, id)
+			return fmt.Errorf("%s dependency cycle: %s", noun, strings.Join(append(path[start:], id), " -> "))
+		}
+		mark[id] = visiting
+		path = append(path, id)
+		for _, d := range deps[id] {
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		mark[id] = visited
+		return nil
+	}
+	for _, id := range ids {
+		if err := visit(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applySubmitJob stores a job and all its tasks at once. Tasks without
+// dependencies start pending, the rest blocked. Returns a copy of the job.
+func (f *PipelineFSM) applySubmitJob(raw json.RawMessage, index uint64) interface{} {
+	var p SubmitJobPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal submit_job: %w", err)
+	}
+	j := p.Job
+	if j.ID == "" {
+		return fmt.Errorf("submit_job: empty job ID")
+	}
+	if _, ok := f.jobs[j.ID]; ok {
+		return fmt.Errorf("job %q already exists", j.ID)
+	}
+	if j.FailurePolicy == "" {
+		j.FailurePolicy = JobFailFast
+	}
+	if !ValidJobFailurePolicy(j.FailurePolicy) {
+		return fmt.Errorf("submit_job: unknown failure policy %q", j.FailurePolicy)
+	}
+	if len(p.Tasks) == 0 {
+		return fmt.Errorf("submit_job: job %q has no tasks", j.ID)
+	}
+
+	stages := make(map[string]*Stage, len(j.Stages))
+	for _, s := range j.Stages {
+		if _, dup := stages[s.Name]; dup || s.Name == "" {
+			return fmt.Errorf("submit_job: invalid or duplicate stage name %q", s.Name)
+		}
+		s.TaskIDs, s.Succeeded, s.Failed, s.Cancelled, s.Planned = nil, 0, 0, 0, 0
+		stages[s.Name] = s
+	}
+	switch j.Type {
+	case "", JobDAG:
+		j.Type, j.MapReduce = JobDAG, nil
+	case JobMapReduce:
+		if err := f.checkMapReduceLocked(&j, p.Tasks); err != nil {
+			return fmt.Errorf("submit_job: %w", err)
+		}
+		stages[StageReduce].Planned = j.MapReduce.Partitions
+	default:
+		return fmt.Errorf("submit_job: unknown job type %q", j.Type)
+	}
+	deps := make(map[string][]string, len(p.Tasks))
+	sizes := make(map[string]int, len(stages))
+	for i := range p.Tasks {
+		// A dependency listed twice would be waited on twice but released
+		// once, leaving the task blocked for good.
+		p.Tasks[i].DependsOn = slices.Compact(slices.Sorted(slices.Values(p.Tasks[i].DependsOn)))
+	}
+	for _, t := range p.Tasks {
+		if t.ID == "" || !ValidTaskType(t.Type) {
+			return fmt.Errorf("submit_job: task %q has an empty ID or unknown type %q", t.ID, t.Type)
+		}
+		if _, dup := deps[t.ID]; dup {
+			return fmt.Errorf("submit_job: task %q appears twice", t.ID)
+		}
+		if _, ok := f.tasks[t.ID]; ok || f.reservedLocked(t.ID) {
+			return fmt.Errorf("task %q already exists", t.ID)
+		}
+		if stages[t.Stage] == nil {
+			return fmt.Errorf("submit_job: task %q names unknown stage %q", t.ID, t.Stage)
+		}
+		if t.Retry != nil {
+			if err := t.Retry.Validate(); err != nil {
+				return fmt.Errorf("submit_job: task %q: %w", t.ID, err)
+			}
+		}
+		if t.Timeout < 0 || t.KillGrace < 0 {
+			return fmt.Errorf("submit_job: task %q: timeout and kill grace must not be negative", t.ID)
+		}
+		deps[t.ID] = t.DependsOn
+		sizes[t.Stage]++
+	}
+	for _, s := range j.Stages {
+		if sizes[s.Name] == 0 && s.Planned == 0 {
+			return fmt.Errorf("submit_job: stage %q has no tasks", s.Name)
+		}
+	}
+	if err := CheckDAG("task", deps); err != nil {
+		return fmt.Errorf("submit_job: %w", err)
+	}
+
+	j.State = JobRunning
+	j.Error = ""
+	j.FinishedAt = time.Time{}
+	j.Index = index
+	for i := range p.Tasks {
+		t := p.Tasks[i]
+		t.JobID = j.ID
+		t.State = TaskPending
+		t.PendingSince = t.CreatedAt
+		if len(t.DependsOn) > 0 {
+			t.State = TaskBlocked
+			t.PendingSince = time.Time{}
+		}
+		t.Attempt = 1
+		t.AssignedWorker, t.PlacementReason, t.Error = "", "", ""
+		t.StartedAt, t.FinishedAt, t.LeaseExpires = time.Time{}, time.Time{}, time.Time{}
+		t.EgressBytes, t.EgressCost = 0, 0
+		t.Attempts, t.Failures, t.NotBefore = nil, 0, time.Time{}
+		t.Speculative, t.CommitAttempt = nil, 0
+		t.Kills = nil
+		t.WaitingOn = len(t.DependsOn)
+		t.Index = index
+		f.tasks[t.ID] = &t
+		s := stages[t.Stage]
+		s.TaskIDs = append(s.TaskIDs, t.ID)
+	}
+	for _, s := range j.Stages {
+		f.updateStageLocked(s)
+	}
+	f.jobs[j.ID] = &j
+	slog.Info("FSM: job submitted", "job_id", j.ID, "stages", len(j.Stages), "tasks", len(p.Tasks),
+		"failure_policy", j.FailurePolicy, "index", index)
+	return j.clone()
+}
+
+// applyCancelJob cancels every unfinished task of a job, and the tasks it
+// would still create, then finishes it as cancelled. Returns a copy of the job.
+func (f *PipelineFSM) applyCancelJob(raw json.RawMessage, index uint64) interface{} {
+	var p CancelJobPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal cancel_job: %w", err)
+	}
+	j, ok := f.jobs[p.ID]
+	if !ok {
+		return fmt.Errorf("job %q not found", p.ID)
+	}
+	if j.Finished() {
+		return fmt.Errorf("job %q already %s", p.ID, j.State)
+	}
+	reason := "job cancelled"
+	if p.Reason != "" {
+		reason += ": " + p.Reason
+	}
+	j.Cancelled = true
+	if j.Error == "" {
+		j.Error = reason
+	}
+	writeOffPlanned(j)
+	victims := f.unfinishedLocked(j)
+	for _, t := range victims {
+		f.cancelTaskLocked(t, reason, p.CancelledAt, index)
+	}
+	f.jobTasksFinishedLocked(j, victims, p.CancelledAt, index)
+	if !j.Finished() {
+		// Nothing was left to cancel, e.g. a job waiting only on planned tasks.
+		f.finishJobLocked(j, p.CancelledAt, index)
+	}
+	slog.Info("FSM: job cancelled", "job_id", j.ID, "tasks", len(victims), "reason", p.Reason, "index", index)
+	return j.clone()
+}
+
+// jobTaskFinishedLocked runs after t, a task of a job, reaches a terminal
+// state: it unblocks or cancels the tasks that depend on it and finishes the
+// job once every task has.
+func (f *PipelineFSM) jobTaskFinishedLocked(t *Task) {
+	j, ok := f.jobs[t.JobID]
+	if !ok || j.Finished() {
+		return
+	}
+	f.jobTasksFinishedLocked(j, []*Task{t}, t.FinishedAt, t.Index)
+}
+
+// jobTasksFinishedLocked does the work of jobTaskFinishedLocked for tasks of
+// j that just finished. Cancellations it causes are handled in the same pass
+// rather than recursively.
+func (f *PipelineFSM) jobTasksFinishedLocked(j *Job, queue []*Task, at time.Time, index uint64) {
+	for ; len(queue) > 0; queue = queue[1:] {
+		x := queue[0]
+		stage := j.stage(x.Stage)
+		if stage == nil {
+			continue
+		}
+		switch x.State {
+		case TaskSucceeded:
+			stage.Succeeded++
+			for _, d := range f.dependentsLocked(j, x.ID) {
+				if d.WaitingOn--; d.WaitingOn == 0 && d.State == TaskBlocked {
+					d.State = TaskPending
+					d.PendingSince = at
+					d.Index = index
+					slog.Info("FSM: task unblocked", "task_id", d.ID, "job_id", j.ID, "index", index)
+				}
+			}
+			if j.MapReduce != nil && x.Stage == StageMap {
+				f.shuffleLocked(j, at, index)
+			}
+			continue
+		case TaskFailed:
+			stage.Failed++
+		case TaskCancelled:
+			stage.Cancelled++
+		}
+		if j.Error == "" {
+			j.Error = fmt.Sprintf("task %s %s", x.ID, x.State)
+			if x.Error != "" {
+				j.Error += ": " + x.Error
+			}
+		}
+		victims := f.dependentsLocked(j, x.ID)
+		reason := fmt.Sprintf("upstream task %s %s", x.ID, x.State)
+		switch {
+		case j.FailurePolicy == JobFailFast:
+			writeOffPlanned(j)
+			victims = f.unfinishedLocked(j)
+			reason = "job failed fast: " + j.Error
+		case j.MapReduce != nil && x.Stage == StageMap:
+			victims = f.mapReduceVictimsLocked(j)
+		}
+		for _, v := range victims {
+			f.cancelTaskLocked(v, reason, at, index)
+			queue = append(queue, v)
+		}
+	}
+
+	j.Index = index
+	finished := true
+	for _, s := range j.Stages {
+		f.updateStageLocked(s)
+		finished = finished && s.complete()
+	}
+	if finished {
+		f.finishJobLocked(j, at, index)
+	}
+}
+
+// finishJobLocked moves j to its terminal state.
+func (f *PipelineFSM) finishJobLocked(j *Job, at time.Time, index uint64) {
+	switch {
+	case j.Cancelled:
+		j.State = JobCancelled
+	case j.Error != "":
+		j.State = JobFailed
+	default:
+		j.State = JobSucceeded
+	}
+	j.FinishedAt = at
+	j.Index = index
+	f.finishedJobs = append(f.finishedJobs, j.ID)
+	for len(f.finishedJobs) > maxFinishedJobs {
+		delete(f.jobs, f.finishedJobs[0])
+		f.finishedJobs = f.finishedJobs[1:]
+	}
+	slog.Info("FSM: job finished", "job_id", j.ID, "state", j.State, "error", j.Error, "index", index)
+}
+
+// dependentsLocked returns the unfinished tasks of j that depend directly on id.
+func (f *PipelineFSM) dependentsLocked(j *Job, id string) []*Task {
+	var out []*Task
+	for _, t := range f.unfinishedLocked(j) {
+		if slices.Contains(t.DependsOn, id) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// unfinishedLocked returns j's tasks that have not finished, in stage order.
+// Finished tasks may already be evicted; unfinished ones never are.
+func (f *PipelineFSM) unfinishedLocked(j *Job) []*Task {
+	var out []*Task
+	for _, s := range j.Stages {
+		for _, id := range s.TaskIDs {
+			if t, ok := f.tasks[id]; ok && !t.Finished() {
+				out = append(out, t)
+			}
+		}
+	}
+	return out
+}
+
+// updateStageLocked recomputes s.State from its counters and unfinished tasks.
+func (f *PipelineFSM) updateStageLocked(s *Stage) {
+	if s.complete() {
+		s.State = JobSucceeded
+		if s.Failed+s.Cancelled > 0 {
+			s.State = JobFailed
+		}
+		return
+	}
+	s.State = JobBlocked
+	for _, id := range s.TaskIDs {
+		if t, ok := f.tasks[id]; ok && !t.Finished() && t.State != TaskBlocked {
+			s.State = JobRunning
+			return
+		}
+	}
+}
+
+// writeOffPlanned counts the tasks j's stages would still have created as
+// cancelled, once the job can no longer create them.
+func writeOffPlanned(j *Job) {
+	for _, s := range j.Stages {
+		s.Cancelled += s.Planned
+		s.Planned = 0
+	}
+}
+
+func (j *Job) stage(name string) *Stage {
+	for _, s := range j.Stages {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// finishedJobOrder rebuilds the finished-job eviction order after a restore.
+func finishedJobOrder(jobs map[string]*Job) []string {
+	var done []*Job
+	for _, j := range jobs {
+		if j.Finished() {
+			done = append(done, j)
+		}
+	}
+	slices.SortFunc(done, func(a, b *Job) int {
+		return cmp.Or(a.FinishedAt.Compare(b.FinishedAt), cmp.Compare(a.ID, b.ID))
+	})
+	ids := make([]string, len(done))
+	for i, j := range done {
+		ids[i] = j.ID
+	}
+	return ids
+}
+
+// GetJob returns a copy of a job, or nil if not found.
+func (f *PipelineFSM) GetJob(id string) *Job {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	j, ok := f.jobs[id]
+	if !ok {
+		return nil
+	}
+	return j.clone()
+}
+
+// Jobs returns copies of all jobs, ordered by ID.
+func (f *PipelineFSM) Jobs() []*Job {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]*Job, 0, len(f.jobs))
+	for _, j := range f.jobs {
+		out = append(out, j.clone())
+	}
+	slices.SortFunc(out, func(a, b *Job) int { return cmp.Compare(a.ID, b.ID) })
+	return out
+}
+
+func (j *Job) clone() *Job {
+	cp := *j
+	cp.Stages = make([]*Stage, len(j.Stages))
+	for i, s := range j.Stages {
+		sc := *s
+		sc.DependsOn = slices.Clone(s.DependsOn)
+		sc.TaskIDs = slices.Clone(s.TaskIDs)
+		cp.Stages[i] = &sc
+	}
+	if j.MapReduce != nil {
+		cp.MapReduce = j.MapReduce.clone()
+	}
+	return &cp
+}
@@ -0,0 +1,171 @@
+package raft
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// DefaultKillGrace applies to tasks submitted without a kill grace.
+const DefaultKillGrace = 30 * time.Second
+
+// KillOrder tells the worker running one attempt of a task to stop it. The
+// order stays with the task, and is repeated to the worker, until the worker
+// reports the attempt or GraceUntil passes and the leader revokes the lease.
+type KillOrder struct {
+	TaskID      string    `json:"task_id"`
+	Attempt     int       `json:"attempt"`
+	Worker      string    `json:"worker"`
+	Reason      string    `json:"reason"`
+	RequestedAt time.Time `json:"requested_at"`
+	GraceUntil  time.Time `json:"grace_until"`
+}
+
+// KillTaskPayload carries fields for a kill_task command, written by the
+// leader when a running attempt passes its deadline. The attempt keeps its
+// lease until the grace period ends.
+type KillTaskPayload struct {
+	ID          string    `json:"id"`
+	Attempt     int       `json:"attempt"`
+	Reason      string    `json:"reason"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// RevokeTaskLeasePayload carries fields for a revoke_task_lease command,
+// written by the leader once a kill order's grace period has passed. A still
+// running attempt fails as timed out; otherwise the order is just dropped.
+type RevokeTaskLeasePayload struct {
+	ID        string    `json:"id"`
+	Attempt   int       `json:"attempt"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// applyKillTask returns a copy of the task on success.
+func (f *PipelineFSM) applyKillTask(raw json.RawMessage, index uint64) interface{} {
+	var p KillTaskPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal kill_task: %w", err)
+	}
+	t, ok := f.tasks[p.ID]
+	if !ok {
+		return fmt.Errorf("task %q not found", p.ID)
+	}
+	if !t.runningAttempt(p.Attempt) {
+		return fmt.Errorf("task %q is %s at attempt %d, not running attempt %d",
+			p.ID, t.State, t.Attempt, p.Attempt)
+	}
+	if t.killOrder(p.Attempt) != nil {
+		return fmt.Errorf("task %q: attempt %d is already being killed", p.ID, p.Attempt)
+	}
+	worker := t.AssignedWorker
+	if s := t.Speculative; s != nil && s.Attempt == p.Attempt {
+		worker = s.Worker
+	}
+	t.kill(p.Attempt, worker, p.Reason, p.RequestedAt)
+	t.Index = index
+	return t.clone()
+}
+
+// applyRevokeTaskLease returns a copy of the task on success.
+func (f *PipelineFSM) applyRevokeTaskLease(raw json.RawMessage, index uint64) interface{} {
+	var p RevokeTaskLeasePayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal revoke_task_lease: %w", err)
+	}
+	t, ok := f.tasks[p.ID]
+	if !ok {
+		return fmt.Errorf("task %q not found", p.ID)
+	}
+	k := t.killOrder(p.Attempt)
+	if k == nil {
+		return fmt.Errorf("task %q: attempt %d has no kill order", p.ID, p.Attempt)
+	}
+	order := *k
+	t.dropKill(p.Attempt)
+	t.Index = index
+	if !t.runningAttempt(p.Attempt) {
+		slog.Info("FSM: kill order expired", "task_id", p.ID, "worker_id", order.Worker,
+			"attempt", p.Attempt, "index", index)
+		return t.clone()
+	}
+	slog.Warn("FSM: task lease revoked — attempt did not stop in time", "task_id", p.ID,
+		"worker_id", order.Worker, "attempt", p.Attempt, "reason", order.Reason, "index", index)
+	f.failAttemptLocked(t, p.Attempt, AttemptTimedOut, order.Reason, ErrorRetryable, p.RevokedAt, index)
+	return t.clone()
+}
+
+// kill orders worker to stop attempt of t, allowing it t's kill grace. It
+// replaces any earlier order for the attempt, such as a timeout that a
+// cancellation overtakes.
+func (t *Task) kill(attempt int, worker, reason string, at time.Time) {
+	if worker == "" {
+		return
+	}
+	t.dropKill(attempt)
+	t.Kills = append(t.Kills, KillOrder{
+		TaskID:      t.ID,
+		Attempt:     attempt,
+		Worker:      worker,
+		Reason:      reason,
+		RequestedAt: at,
+		GraceUntil:  at.Add(t.killGrace()),
+	})
+	slog.Info("FSM: task attempt kill ordered", "task_id", t.ID, "worker_id", worker,
+		"attempt", attempt, "reason", reason)
+}
+
+// killOrder returns the kill order for attempt of t, or nil.
+func (t *Task) killOrder(attempt int) *KillOrder {
+	for i := range t.Kills {
+		if t.Kills[i].Attempt == attempt {
+			return &t.Kills[i]
+		}
+	}
+	return nil
+}
+
+// dropKill removes the kill order for attempt of t, if any.
+func (t *Task) dropKill(attempt int) {
+	t.Kills = slices.DeleteFunc(t.Kills, func(k KillOrder) bool { return k.Attempt == attempt })
+	if len(t.Kills) == 0 {
+		t.Kills = nil
+	}
+}
+
+func (t *Task) killGrace() time.Duration {
+	if t.KillGrace > 0 {
+		return t.KillGrace
+	}
+	return DefaultKillGrace
+}
+
+// Deadline returns when the attempt of t started at started passes the
+// task's timeout; zero if t has none.
+func (t *Task) Deadline(started time.Time) time.Time {
+	if t.Timeout <= 0 || started.IsZero() {
+		return time.Time{}
+	}
+	return started.Add(t.Timeout)
+}
+
+// KillOrders returns the kill orders addressed to workerID, ordered by task
+// ID and attempt.
+func (f *PipelineFSM) KillOrders(workerID string) []KillOrder {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var out []KillOrder
+	for _, t := range f.tasks {
+		for _, k := range t.Kills {
+			if k.Worker == workerID {
+				out = append(out, k)
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b KillOrder) int {
+		return cmp.Or(cmp.Compare(a.TaskID, b.TaskID), cmp.Compare(a.Attempt, b.Attempt))
+	})
+	return out
+}
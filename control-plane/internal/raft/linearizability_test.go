@@ -0,0 +1,147 @@
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/linearizability"
+)
+
+// registryClient issues registry operations against whichever node it
+// believes leads, recording each in rec.
+type registryClient struct {
+	id  int
+	c   *chaosCluster
+	rec *linearizability.Recorder
+	rng *rand.Rand
+}
+
+func (cl *registryClient) target() int {
+	for i := range cl.c.ids {
+		if n := cl.c.node(i); n != nil && n.State() == hashiraft.Leader {
+			return i
+		}
+	}
+	return cl.rng.IntN(len(cl.c.ids))
+}
+
+func (cl *registryClient) step() {
+	i := cl.target()
+	n := cl.c.node(i)
+	if n == nil {
+		return
+	}
+	workerID := fmt.Sprintf("w%d", cl.rng.IntN(3))
+	switch cl.rng.IntN(3) {
+	case 0:
+		in := linearizability.RegistryInput{Op: linearizability.OpRegister, WorkerID: workerID}
+		cl.write(n, in, CmdRegisterWorker, RegisterWorkerPayload{ID: workerID, CloudTag: "aws"})
+	case 1:
+		status := []string{WorkerOnline, WorkerSuspect, WorkerOffline}[cl.rng.IntN(3)]
+		in := linearizability.RegistryInput{Op: linearizability.OpUpdate, WorkerID: workerID, Status: status}
+		cl.write(n, in, CmdUpdateWorkerStatus, UpdateWorkerStatusPayload{ID: workerID, Status: status})
+	default:
+		// A barrier commits through the current term's quorum, so a deposed
+		// leader can't serve a stale read.
+		p := cl.rec.Invoke(cl.id, linearizability.RegistryInput{Op: linearizability.OpRead, WorkerID: workerID})
+		if err := n.raft.Barrier(500 * time.Millisecond).Error(); err != nil {
+			p.Discard()
+			return
+		}
+		out := linearizability.RegistryOutput{}
+		cl.c.mu.Lock()
+		fsm := cl.c.fsms[i]
+		cl.c.mu.Unlock()
+		if w := fsm.GetWorker(workerID); w != nil {
+			out = linearizability.RegistryOutput{Ok: true, Status: w.Status}
+		}
+		p.Complete(out)
+	}
+}
+
+func (cl *registryClient) write(n *RaftNode, in linearizability.RegistryInput, typ CommandType, payload interface{}) {
+	cmd, err := MarshalCommand(typ, payload)
+	if err != nil {
+		panic(err)
+	}
+	p := cl.rec.Invoke(cl.id, in)
+	_, err = n.ApplyCommand(cmd, 500*time.Millisecond)
+	switch {
+	case err == nil:
+		p.Complete(linearizability.RegistryOutput{Ok: true})
+	case errors.Is(err, hashiraft.ErrNotLeader):
+		p.Discard()
+	case isRaftError(err):
+		p.Ambiguous()
+	default:
+		// The FSM rejected the command, e.g. updating an unknown worker.
+		p.Complete(linearizability.RegistryOutput{})
+	}
+}
+
+// isRaftError distinguishes replication failures, whose outcome is unknown,
+// from FSM responses.
+func isRaftError(err error) bool {
+	for _, e := range []error{hashiraft.ErrLeadershipLost, hashiraft.ErrEnqueueTimeout,
+		hashiraft.ErrRaftShutdown, hashiraft.ErrAbortedByRestore, hashiraft.ErrLeadershipTransferInProgress} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegistryLinearizableUnderPartitions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping linearizability test in short mode")
+	}
+	c := newChaosCluster(t, 3)
+	c.waitLeader(15 * time.Second)
+	rec := linearizability.NewRecorder()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for id := 0; id < 4; id++ {
+		cl := &registryClient{id: id, c: c, rec: rec, rng: rand.New(rand.NewPCG(uint64(id), 7))}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					cl.step()
+				}
+			}
+		}()
+	}
+
+	// Isolate a random node, heal, repeat.
+	rng := rand.New(rand.NewPCG(1, 2))
+	for round := 0; round < 6; round++ {
+		c.isolate(rng.IntN(3))
+		time.Sleep(400 * time.Millisecond)
+		c.heal()
+		time.Sleep(300 * time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	history := rec.History()
+	res := linearizability.Check(linearizability.RegistryModel, history, 30*time.Second)
+	t.Logf("checked %d operations (timed out: %v)", len(history), res.TimedOut)
+	if !res.Ok {
+		dir, _ := os.MkdirTemp("", "linearizability-")
+		path, err := linearizability.Dump(dir, linearizability.RegistryModel, history, res)
+		t.Fatalf("history not linearizable; timeline at %s (dump error: %v)", path, err)
+	}
+	c.checkInvariants()
+}
@@ -0,0 +1,422 @@
+package raft
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Job types stored in Job.Type.
+const (
+	JobDAG       = "dag"
+	JobMapReduce = "mapreduce"
+)
+
+// Stage names of a MapReduce job. The shuffle between them has no tasks; it
+// is the per-partition tracking in MapReduce.
+const (
+	StageMap    = "map"
+	StageReduce = "reduce"
+)
+
+// MapReduce is the state of a MapReduce job. Each map task reads one input
+// split and reports one intermediate output per partition when it succeeds.
+// The reduce task for a partition is created once every map has reported its
+// output for that partition, and reads those outputs in split order.
+type MapReduce struct {
+	Partitions   int        `json:"partitions"`
+	OutputPrefix string     `json:"output_prefix,omitempty"` // reduce r writes OutputPrefix + "part-<r>"
+	Maps         []MapSplit `json:"maps"`                    // in split order
+	Reduces      []string   `json:"reduces"`                 // reduce task ID per partition; "" until created
+
+	// Copied onto every reduce task.
+	Placement     string        `json:"placement,omitempty"`
+	CloudAffinity string        `json:"cloud_affinity,omitempty"`
+	Resources     Resources     `json:"resources,omitzero"`
+	Retry         *RetryPolicy  `json:"retry,omitempty"`
+	Timeout       time.Duration `json:"timeout,omitempty"`
+	KillGrace     time.Duration `json:"kill_grace,omitempty"`
+}
+
+// MapSplit is one map task and the outputs of its last successful run.
+// Only non-empty outputs are kept, so a split costs nothing for the
+// partitions it wrote no records to.
+type MapSplit struct {
+	TaskID     string         `json:"task_id"`
+	InputURIs  []string       `json:"input_uris"`
+	InputBytes []int64        `json:"input_bytes,omitempty"`
+	Reported   bool           `json:"reported,omitempty"` // the outputs below are in
+	Outputs    []Intermediate `json:"outputs,omitempty"`  // non-empty only, by partition
+	// LastAttempt is the highest attempt number the task had reached when it
+	// reported, so a re-run of an evicted task does not reuse its numbers.
+	LastAttempt int `json:"last_attempt,omitempty"`
+}
+
+// Output returns the split's output for partition r, or ok false if it wrote
+// nothing there.
+func (m *MapSplit) Output(r int) (o Intermediate, ok bool) {
+	i, ok := slices.BinarySearchFunc(m.Outputs, r, func(o Intermediate, r int) int { return o.Partition - r })
+	if !ok {
+		return Intermediate{}, false
+	}
+	return m.Outputs[i], true
+}
+
+// Intermediate is a map task's output for one partition.
+type Intermediate struct {
+	Partition int    `json:"partition"`
+	URI       string `json:"uri,omitempty"` // empty: no records for this partition
+	Bytes     int64  `json:"bytes,omitempty"`
+	// Worker holds the output on local storage; it is lost with the worker.
+	// Durable outputs (e.g. in object storage) survive it.
+	Worker  string `json:"worker,omitempty"`
+	Durable bool   `json:"durable,omitempty"`
+}
+
+// MapTaskID returns the ID of a MapReduce job's map task for the split at
+// index split.
+func MapTaskID(jobID string, split int) string {
+	return fmt.Sprintf("%s-map-%05d", jobID, split)
+}
+
+// ReduceOutputURI returns where the reduce task for partition writes, given
+// the job's output prefix.
+func ReduceOutputURI(outputPrefix string, partition int) string {
+	return fmt.Sprintf("%spart-%05d", outputPrefix, partition)
+}
+
+// ReduceTaskID returns the ID of a MapReduce job's reduce task for partition.
+func ReduceTaskID(jobID string, partition int) string {
+	return fmt.Sprintf("%s-reduce-%05d", jobID, partition)
+}
+
+// checkMapReduce validates a submitted MapReduce job: its tasks are the maps,
+// one per split, and its stages are map then reduce.
+func (f *PipelineFSM) checkMapReduceLocked(j *Job, maps []Task) error {
+	mr := j.MapReduce
+	if mr == nil || mr.Partitions <= 0 {
+		return fmt.Errorf("mapreduce job %q needs a positive partition count", j.ID)
+	}
+	if len(j.Stages) != 2 || j.Stages[0].Name != StageMap || j.Stages[1].Name != StageReduce {
+		return fmt.Errorf("mapreduce job %q must have exactly the stages %q and %q", j.ID, StageMap, StageReduce)
+	}
+	if len(mr.Maps) != len(maps) {
+		return fmt.Errorf("mapreduce job %q has %d splits but %d tasks", j.ID, len(mr.Maps), len(maps))
+	}
+	if mr.Retry != nil {
+		if err := mr.Retry.Validate(); err != nil {
+			return fmt.Errorf("mapreduce job %q: %w", j.ID, err)
+		}
+	}
+	for i, t := range maps {
+		if t.ID != mr.Maps[i].TaskID || t.Type != TaskMap || t.Stage != StageMap ||
+			t.Partitions != mr.Partitions || len(t.DependsOn) > 0 {
+			return fmt.Errorf("mapreduce job %q: task %q is not map task %d", j.ID, t.ID, i)
+		}
+		mr.Maps[i].Reported, mr.Maps[i].Outputs = false, nil
+	}
+	for r := range mr.Partitions {
+		id := ReduceTaskID(j.ID, r)
+		if _, ok := f.tasks[id]; ok || slices.ContainsFunc(maps, func(t Task) bool { return t.ID == id }) {
+			return fmt.Errorf("reduce task ID %q is already taken", id)
+		}
+	}
+	mr.Reduces = make([]string, mr.Partitions)
+	return nil
+}
+
+// reservedLocked reports whether id is the reduce task ID of a running
+// MapReduce job, which a plain task must not take.
+func (f *PipelineFSM) reservedLocked(id string) bool {
+	for _, j := range f.jobs {
+		if j.MapReduce != nil && !j.Finished() && strings.HasPrefix(id, j.ID+"-reduce-") {
+			return true
+		}
+	}
+	return false
+}
+
+// mapSplitLocked returns the MapReduce job and split that map task t runs,
+// if it is one.
+func (f *PipelineFSM) mapSplitLocked(t *Task) (*Job, *MapSplit) {
+	j, ok := f.jobs[t.JobID]
+	if !ok || j.MapReduce == nil || t.Stage != StageMap {
+		return nil, nil
+	}
+	for i := range j.MapReduce.Maps {
+		if j.MapReduce.Maps[i].TaskID == t.ID {
+			return j, &j.MapReduce.Maps[i]
+		}
+	}
+	return nil, nil
+}
+
+// CheckMapOutputs reports whether outputs names each of partitions exactly once.
+func CheckMapOutputs(partitions int, outputs []Intermediate) error {
+	seen := make([]bool, partitions)
+	for _, o := range outputs {
+		if o.Partition < 0 || o.Partition >= partitions {
+			return fmt.Errorf("output for partition %d, want 0–%d", o.Partition, partitions-1)
+		}
+		if seen[o.Partition] {
+			return fmt.Errorf("two outputs for partition %d", o.Partition)
+		}
+		seen[o.Partition] = true
+	}
+	if len(outputs) != partitions {
+		return fmt.Errorf("got outputs for %d of %d partitions", len(outputs), partitions)
+	}
+	return nil
+}
+
+// recordMapOutputsLocked stores the non-empty outputs of a successful map
+// task, which live on the worker that ran it unless marked durable, and the
+// last attempt number t used.
+func (f *PipelineFSM) recordMapOutputsLocked(split *MapSplit, t *Task, worker string, outputs []Intermediate) {
+	split.Reported, split.Outputs = true, nil
+	split.LastAttempt = t.nextAttempt() - 1
+	for _, o := range outputs {
+		if o.URI == "" {
+			continue
+		}
+		o.Worker = worker
+		if o.Durable {
+			o.Worker = ""
+		}
+		split.Outputs = append(split.Outputs, o)
+	}
+	slices.SortFunc(split.Outputs, func(a, b Intermediate) int { return a.Partition - b.Partition })
+}
+
+// sparseMapOutputs converts the map splits of snapshots before version 5,
+// which held an output for every partition once reported, to non-empty
+// outputs only.
+func sparseMapOutputs(jobs map[string]*Job) {
+	for _, j := range jobs {
+		if j.MapReduce == nil {
+			continue
+		}
+		for i := range j.MapReduce.Maps {
+			m := &j.MapReduce.Maps[i]
+			if len(m.Outputs) == 0 {
+				continue
+			}
+			m.Reported = true
+			m.Outputs = slices.DeleteFunc(m.Outputs, func(o Intermediate) bool { return o.URI == "" })
+			if len(m.Outputs) == 0 {
+				m.Outputs = nil
+			}
+		}
+	}
+}
+
+// partitionInputs returns the intermediate outputs for partition r in split
+// order, or ok false while a map has not reported yet.
+func (mr *MapReduce) partitionInputs(r int) (uris []string, sizes []int64, ok bool) {
+	for i := range mr.Maps {
+		m := &mr.Maps[i]
+		if !m.Reported {
+			return nil, nil, false
+		}
+		if o, ok := m.Output(r); ok {
+			uris = append(uris, o.URI)
+			sizes = append(sizes, o.Bytes)
+		}
+	}
+	return uris, sizes, true
+}
+
+// shuffleLocked creates the reduce task of every partition whose map outputs
+// are all in, and unblocks reduce tasks that were waiting for lost outputs
+// to be recomputed.
+func (f *PipelineFSM) shuffleLocked(j *Job, at time.Time, index uint64) {
+	mr := j.MapReduce
+	stage := j.stage(StageReduce)
+	for r := range mr.Partitions {
+		uris, sizes, ok := mr.partitionInputs(r)
+		if !ok {
+			continue
+		}
+		if id := mr.Reduces[r]; id != "" {
+			if t := f.tasks[id]; t != nil && t.State == TaskBlocked {
+				t.InputURIs, t.InputBytes = uris, sizes
+				t.State = TaskPending
+				t.PendingSince = at
+				t.Index = index
+				slog.Info("FSM: reduce task unblocked", "task_id", id, "job_id", j.ID, "index", index)
+			}
+			continue
+		}
+		t := &Task{
+			ID:            ReduceTaskID(j.ID, r),
+			JobID:         j.ID,
+			Type:          TaskReduce,
+			InputURIs:     uris,
+			InputBytes:    sizes,
+			State:         TaskPending,
+			Attempt:       1,
+			CreatedAt:     at,
+			PendingSince:  at,
+			Index:         index,
+			Placement:     mr.Placement,
+			CloudAffinity: mr.CloudAffinity,
+			Resources:     mr.Resources,
+			Retry:         mr.Retry,
+			Timeout:       mr.Timeout,
+			KillGrace:     mr.KillGrace,
+			Stage:         StageReduce,
+			Partition:     r,
+		}
+		if mr.OutputPrefix != "" {
+			t.OutputURI = ReduceOutputURI(mr.OutputPrefix, r)
+		}
+		if _, exists := f.tasks[t.ID]; exists {
+			// Unreachable: the ID is checked at submission and reserved after.
+			slog.Error("FSM: reduce task ID already taken", "task_id", t.ID, "job_id", j.ID)
+			continue
+		}
+		f.tasks[t.ID] = t
+		mr.Reduces[r] = t.ID
+		stage.TaskIDs = append(stage.TaskIDs, t.ID)
+		stage.Planned--
+		slog.Info("FSM: reduce task created", "task_id", t.ID, "job_id", j.ID, "partition", r,
+			"inputs", len(uris), "index", index)
+	}
+}
+
+// mapReduceVictimsLocked returns what a failed map task takes down: the
+// other maps, which can no longer lead anywhere, and reduces blocked on lost
+// outputs. Reduces already running on intact inputs are left alone. Reduces
+// not created yet are written off as cancelled.
+func (f *PipelineFSM) mapReduceVictimsLocked(j *Job) []*Task {
+	writeOffPlanned(j)
+	var out []*Task
+	for _, t := range f.unfinishedLocked(j) {
+		if t.Stage == StageMap || t.State == TaskBlocked {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// workerLostLocked re-runs the map tasks whose intermediate outputs were on
+// workerID and are still needed by a reduce that has not finished. Reduce
+// tasks that read one of those outputs go back to blocked until the map has
+// run again; a running one loses its lease.
+func (f *PipelineFSM) workerLostLocked(workerID, reason string, at time.Time, index uint64) {
+	ids := make([]string, 0, len(f.jobs))
+	for id, j := range f.jobs {
+		if j.MapReduce != nil && !j.Finished() {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		j := f.jobs[id]
+		mr := j.MapReduce
+		needed := make([]bool, mr.Partitions)
+		for r, rid := range mr.Reduces {
+			t := f.tasks[rid]
+			needed[r] = rid == "" || (t != nil && !t.Finished())
+		}
+		lostParts := make([]bool, mr.Partitions)
+		var lost []int
+		for i, m := range mr.Maps {
+			hit := false
+			for _, o := range m.Outputs {
+				if o.Worker == workerID && needed[o.Partition] {
+					hit = true
+					lostParts[o.Partition] = true
+				}
+			}
+			if hit {
+				lost = append(lost, i)
+			}
+		}
+		if len(lost) == 0 {
+			continue
+		}
+
+		why := fmt.Sprintf("intermediate output lost: worker %s %s", workerID, reason)
+		for _, i := range lost {
+			mr.Maps[i].Reported, mr.Maps[i].Outputs = false, nil
+			f.rerunMapLocked(j, &mr.Maps[i], why, at, index)
+		}
+		for r, rid := range mr.Reduces {
+			t := f.tasks[rid]
+			if rid == "" || !lostParts[r] || t == nil || t.Finished() {
+				continue
+			}
+			if t.State == TaskRunning {
+				t.recordAttempt(AttemptInterrupted, why, "", at)
+				t.kill(t.Attempt, t.AssignedWorker, "interrupted: "+why, at)
+				if s := t.Speculative; s != nil {
+					t.recordSpeculative(AttemptInterrupted, why, "", at)
+					t.kill(s.Attempt, s.Worker, "interrupted: "+why, at)
+				}
+				t.Attempt = t.nextAttempt()
+				t.Speculative, t.CommitAttempt = nil, 0
+			}
+			t.State = TaskBlocked
+			t.AssignedWorker, t.PlacementReason = "", ""
+			t.Error = why
+			t.StartedAt, t.LeaseExpires, t.PendingSince, t.NotBefore = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+			t.Index = index
+			slog.Warn("FSM: reduce task blocked on lost output", "task_id", rid, "job_id", j.ID,
+				"worker_id", workerID, "index", index)
+		}
+		for _, s := range j.Stages {
+			f.updateStageLocked(s)
+		}
+		j.Index = index
+	}
+}
+
+// rerunMapLocked puts a succeeded map task back to pending for another
+// attempt. A task already evicted from the FSM is recreated from its split,
+// numbering attempts on from the split's LastAttempt.
+func (f *PipelineFSM) rerunMapLocked(j *Job, split *MapSplit, reason string, at time.Time, index uint64) {
+	t, ok := f.tasks[split.TaskID]
+	if !ok {
+		t = &Task{
+			ID: split.TaskID, JobID: j.ID, Type: TaskMap, Stage: StageMap,
+			InputURIs: slices.Clone(split.InputURIs), InputBytes: slices.Clone(split.InputBytes),
+			CreatedAt: j.CreatedAt, State: TaskSucceeded,
+			Placement: j.MapReduce.Placement, CloudAffinity: j.MapReduce.CloudAffinity,
+			Resources: j.MapReduce.Resources, Partitions: j.MapReduce.Partitions, Retry: j.MapReduce.Retry,
+			Timeout: j.MapReduce.Timeout, KillGrace: j.MapReduce.KillGrace,
+			Attempt: split.LastAttempt,
+		}
+		f.tasks[t.ID] = t
+	} else {
+		f.finishedTasks = slices.DeleteFunc(f.finishedTasks, func(id string) bool { return id == t.ID })
+	}
+	if t.State == TaskSucceeded {
+		j.stage(StageMap).Succeeded--
+	}
+	t.State = TaskPending
+	t.Attempt = t.nextAttempt()
+	t.AssignedWorker, t.PlacementReason = "", ""
+	t.CommitAttempt = 0
+	t.Error = reason
+	t.StartedAt, t.FinishedAt, t.LeaseExpires, t.NotBefore = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	t.PendingSince = at
+	t.Index = index
+	slog.Warn("FSM: map task re-run", "task_id", t.ID, "job_id", j.ID, "reason", reason,
+		"attempt", t.Attempt, "index", index)
+}
+
+func (mr *MapReduce) clone() *MapReduce {
+	cp := *mr
+	cp.Reduces = slices.Clone(mr.Reduces)
+	cp.Maps = make([]MapSplit, len(mr.Maps))
+	for i, m := range mr.Maps {
+		m.InputURIs = slices.Clone(m.InputURIs)
+		m.InputBytes = slices.Clone(m.InputBytes)
+		m.Outputs = slices.Clone(m.Outputs)
+		cp.Maps[i] = m
+	}
+	return &cp
+}
@@ -0,0 +1,249 @@
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+)
+
+// ErrLinkDown is returned for RPCs a Netem link drops, whether by partition or
+// random loss.
+var ErrLinkDown = errors.New("netem: link down")
+
+// LinkProfile describes one direction of a link between two nodes.
+type LinkProfile struct {
+	Latency time.Duration // one-way delay
+	Jitter  time.Duration // uniform ± around Latency
+	Loss    float64       // probability in [0,1] that a message is dropped
+	// BandwidthBps caps throughput in bytes per second, adding size/bandwidth
+	// of transmission delay per message. Zero means unlimited. Messages on the
+	// same link do not queue behind each other.
+	BandwidthBps int64
+}
+
+// Topology places nodes in regions and describes the links between regions.
+// Links are directional; a pair missing from Links uses the reverse
+// direction's profile if present, otherwise Default. Nodes in the same region
+// use Local.
+type Topology struct {
+	Regions map[hashiraft.ServerAddress]string
+	Links   map[[2]string]LinkProfile
+	Local   LinkProfile
+	Default LinkProfile
+}
+
+// CloudTopology returns the AWS/GCP/Azure layout the docker gateway emulates
+// with tc-netem — round trips of ~50ms AWS↔GCP, ~75ms AWS↔Azure and ~125ms
+// GCP↔Azure with ±5ms jitter — for nodes assigned to "aws", "gcp" and
+// "azure" in regions.
+func CloudTopology(regions map[hashiraft.ServerAddress]string) Topology {
+	link := func(rtt time.Duration) LinkProfile {
+		return LinkProfile{Latency: rtt / 2, Jitter: 5 * time.Millisecond}
+	}
+	return Topology{
+		Regions: regions,
+		Links: map[[2]string]LinkProfile{
+			{"aws", "gcp"}:   link(50 * time.Millisecond),
+			{"aws", "azure"}: link(75 * time.Millisecond),
+			{"gcp", "azure"}: link(125 * time.Millisecond),
+		},
+		Local: LinkProfile{Latency: 250 * time.Microsecond},
+	}
+}
+
+// Netem injects delay, jitter, loss, bandwidth limits and partitions into the
+// transports it wraps. One Netem is shared by every node of an experiment so
+// that partitions and link overrides apply cluster-wide.
+//
+// Faults are applied on the sending side: a request is delayed by the
+// sender→target link and its response by the target→sender link. Blocking
+// only the return direction therefore delivers the request but loses the
+// reply, as an asymmetric partition would.
+type Netem struct {
+	mu        sync.Mutex
+	topo      Topology
+	overrides map[[2]hashiraft.ServerAddress]LinkProfile
+	blocked   map[[2]hashiraft.ServerAddress]bool
+	rng       *rand.Rand
+}
+
+// NewNetem returns a Netem for topo. seed makes jitter and loss reproducible.
+func NewNetem(topo Topology, seed uint64) *Netem {
+	return &Netem{
+		topo:      topo,
+		overrides: make(map[[2]hashiraft.ServerAddress]LinkProfile),
+		blocked:   make(map[[2]hashiraft.ServerAddress]bool),
+		rng:       rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+	}
+}
+
+// Wrap decorates trans. Pipelining is disabled on the result so every append
+// takes the faulty path.
+func (n *Netem) Wrap(trans hashiraft.Transport) *NetemTransport {
+	return &NetemTransport{Transport: trans, netem: n}
+}
+
+// SetLink overrides the profile for the from→to direction.
+func (n *Netem) SetLink(from, to hashiraft.ServerAddress, p LinkProfile) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.overrides[[2]hashiraft.ServerAddress{from, to}] = p
+}
+
+// Block drops all traffic in the from→to direction.
+func (n *Netem) Block(from, to hashiraft.ServerAddress) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.blocked[[2]hashiraft.ServerAddress{from, to}] = true
+}
+
+// Partition blocks both directions between every node in a and every node in b.
+func (n *Netem) Partition(a, b []hashiraft.ServerAddress) {
+	for _, x := range a {
+		for _, y := range b {
+			n.Block(x, y)
+			n.Block(y, x)
+		}
+	}
+}
+
+// Heal removes all blocks. Link overrides are kept.
+func (n *Netem) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.blocked = make(map[[2]hashiraft.ServerAddress]bool)
+}
+
+// profile returns the effective profile for from→to. Caller must hold n.mu.
+func (n *Netem) profile(from, to hashiraft.ServerAddress) LinkProfile {
+	if p, ok := n.overrides[[2]hashiraft.ServerAddress{from, to}]; ok {
+		return p
+	}
+	rf, rt := n.topo.Regions[from], n.topo.Regions[to]
+	if rf != "" && rf == rt {
+		return n.topo.Local
+	}
+	if p, ok := n.topo.Links[[2]string{rf, rt}]; ok {
+		return p
+	}
+	if p, ok := n.topo.Links[[2]string{rt, rf}]; ok {
+		return p
+	}
+	return n.topo.Default
+}
+
+// transmit decides the fate of one message of size bytes on from→to: the delay
+// to apply, or ErrLinkDown if it is lost.
+func (n *Netem) transmit(from, to hashiraft.ServerAddress, size int64) (time.Duration, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.blocked[[2]hashiraft.ServerAddress{from, to}] {
+		return 0, fmt.Errorf("%w: %s → %s partitioned", ErrLinkDown, from, to)
+	}
+	p := n.profile(from, to)
+	if p.Loss > 0 && n.rng.Float64() < p.Loss {
+		return 0, fmt.Errorf("%w: %s → %s message lost", ErrLinkDown, from, to)
+	}
+	d := p.Latency
+	if p.Jitter > 0 {
+		d += time.Duration(n.rng.Int64N(int64(2*p.Jitter)+1)) - p.Jitter
+	}
+	if p.BandwidthBps > 0 && size > 0 {
+		d += time.Duration(size * int64(time.Second) / p.BandwidthBps)
+	}
+	return max(d, 0), nil
+}
+
+// NetemTransport is a hashiraft.Transport whose outbound RPCs pass through a
+// Netem. Inbound RPCs are delivered untouched; the sender's wrapper has
+// already applied the link.
+type NetemTransport struct {
+	hashiraft.Transport
+	netem *Netem
+}
+
+// rpcOverhead approximates the encoded size of an RPC without payload.
+const rpcOverhead = 64
+
+// roundTrip runs call between the request and response legs of the link.
+func (t *NetemTransport) roundTrip(target hashiraft.ServerAddress, reqSize int64, call func() error) error {
+	local := t.LocalAddr()
+	d, err := t.netem.transmit(local, target, reqSize)
+	if err != nil {
+		return err
+	}
+	time.Sleep(d)
+	if err := call(); err != nil {
+		return err
+	}
+	d, err = t.netem.transmit(target, local, rpcOverhead)
+	if err != nil {
+		return err
+	}
+	time.Sleep(d)
+	return nil
+}
+
+// AppendEntriesPipeline reports pipelining as unsupported so Raft falls back
+// to AppendEntries, which the link can delay.
+func (t *NetemTransport) AppendEntriesPipeline(hashiraft.ServerID,
+	hashiraft.ServerAddress) (hashiraft.AppendPipeline, error) {
+	return nil, hashiraft.ErrPipelineReplicationNotSupported
+}
+
+func (t *NetemTransport) AppendEntries(id hashiraft.ServerID, target hashiraft.ServerAddress,
+	args *hashiraft.AppendEntriesRequest, resp *hashiraft.AppendEntriesResponse) error {
+	size := int64(rpcOverhead)
+	for _, e := range args.Entries {
+		size += int64(len(e.Data)+len(e.Extensions)) + rpcOverhead
+	}
+	return t.roundTrip(target, size, func() error {
+		return t.Transport.AppendEntries(id, target, args, resp)
+	})
+}
+
+func (t *NetemTransport) RequestVote(id hashiraft.ServerID, target hashiraft.ServerAddress,
+	args *hashiraft.RequestVoteRequest, resp *hashiraft.RequestVoteResponse) error {
+	return t.roundTrip(target, rpcOverhead, func() error {
+		return t.Transport.RequestVote(id, target, args, resp)
+	})
+}
+
+// RequestPreVote is forwarded when the wrapped transport supports pre-vote.
+func (t *NetemTransport) RequestPreVote(id hashiraft.ServerID, target hashiraft.ServerAddress,
+	args *hashiraft.RequestPreVoteRequest, resp *hashiraft.RequestPreVoteResponse) error {
+	pv, ok := t.Transport.(hashiraft.WithPreVote)
+	if !ok {
+		return errors.New("netem: wrapped transport does not support pre-vote")
+	}
+	return t.roundTrip(target, rpcOverhead, func() error {
+		return pv.RequestPreVote(id, target, args, resp)
+	})
+}
+
+func (t *NetemTransport) InstallSnapshot(id hashiraft.ServerID, target hashiraft.ServerAddress,
+	args *hashiraft.InstallSnapshotRequest, resp *hashiraft.InstallSnapshotResponse, data io.Reader) error {
+	return t.roundTrip(target, rpcOverhead+args.Size, func() error {
+		return t.Transport.InstallSnapshot(id, target, args, resp, data)
+	})
+}
+
+func (t *NetemTransport) TimeoutNow(id hashiraft.ServerID, target hashiraft.ServerAddress,
+	args *hashiraft.TimeoutNowRequest, resp *hashiraft.TimeoutNowResponse) error {
+	return t.roundTrip(target, rpcOverhead, func() error {
+		return t.Transport.TimeoutNow(id, target, args, resp)
+	})
+}
+
+// Close closes the wrapped transport if it supports closing.
+func (t *NetemTransport) Close() error {
+	if c, ok := t.Transport.(hashiraft.WithClose); ok {
+		return c.Close()
+	}
+	return nil
+}
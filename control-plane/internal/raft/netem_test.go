@@ -0,0 +1,192 @@
+package raft
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	hashiraft "github.com/hashicorp/raft"
+)
+
+// netemPair connects two in-memory transports and wraps a's side in netem.
+// b answers every RPC successfully.
+func netemPair(t *testing.T, netem *Netem) (*NetemTransport, hashiraft.ServerAddress) {
+	t.Helper()
+	addrA, a := hashiraft.NewInmemTransport("a")
+	addrB, b := hashiraft.NewInmemTransport("b")
+	a.Connect(addrB, b)
+	b.Connect(addrA, a)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case rpc := <-b.Consumer():
+				switch rpc.Command.(type) {
+				case *hashiraft.RequestVoteRequest:
+					rpc.Respond(&hashiraft.RequestVoteResponse{Granted: true}, nil)
+				default:
+					rpc.Respond(&hashiraft.AppendEntriesResponse{Success: true}, nil)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() { close(done) })
+	return netem.Wrap(a), addrB
+}
+
+func TestNetemLatencyAndBandwidth(t *testing.T) {
+	netem := NewNetem(Topology{Default: LinkProfile{Latency: 20 * time.Millisecond}}, 1)
+	trans, target := netemPair(t, netem)
+
+	start := time.Now()
+	if err := trans.RequestVote("b", target, &hashiraft.RequestVoteRequest{}, &hashiraft.RequestVoteResponse{}); err != nil {
+		t.Fatalf("RequestVote: %v", err)
+	}
+	if rtt := time.Since(start); rtt < 40*time.Millisecond {
+		t.Errorf("round trip %s shorter than two 20ms legs", rtt)
+	}
+
+	// 100KB at 1MB/s adds ~100ms on the request leg.
+	netem.SetLink("a", "b", LinkProfile{BandwidthBps: 1 << 20})
+	args := &hashiraft.AppendEntriesRequest{Entries: []*hashiraft.Log{{Data: make([]byte, 100<<10)}}}
+	start = time.Now()
+	if err := trans.AppendEntries("b", target, args, &hashiraft.AppendEntriesResponse{}); err != nil {
+		t.Fatalf("AppendEntries: %v", err)
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("bandwidth-limited append took %s, want ≥ ~100ms", d)
+	}
+}
+
+func TestNetemLossAndPartitions(t *testing.T) {
+	netem := NewNetem(Topology{Default: LinkProfile{Loss: 1}}, 1)
+	trans, target := netemPair(t, netem)
+	err := trans.RequestVote("b", target, &hashiraft.RequestVoteRequest{}, &hashiraft.RequestVoteResponse{})
+	if !errors.Is(err, ErrLinkDown) {
+		t.Fatalf("expected total loss to drop the RPC, got %v", err)
+	}
+
+	netem.SetLink("a", "b", LinkProfile{})
+	netem.SetLink("b", "a", LinkProfile{})
+	netem.Block("b", "a")
+	var resp hashiraft.RequestVoteResponse
+	err = trans.RequestVote("b", target, &hashiraft.RequestVoteRequest{}, &resp)
+	if !errors.Is(err, ErrLinkDown) || !resp.Granted {
+		t.Fatalf("one-way block should deliver the request but lose the reply: err=%v granted=%v", err, resp.Granted)
+	}
+
+	netem.Heal()
+	if err := trans.RequestVote("b", target, &hashiraft.RequestVoteRequest{}, &hashiraft.RequestVoteResponse{}); err != nil {
+		t.Fatalf("healed link: %v", err)
+	}
+	if _, err := trans.AppendEntriesPipeline("b", target); !errors.Is(err, hashiraft.ErrPipelineReplicationNotSupported) {
+		t.Errorf("pipelining should be disabled, got %v", err)
+	}
+}
+
+func TestCloudTopologyProfiles(t *testing.T) {
+	topo := CloudTopology(map[hashiraft.ServerAddress]string{"a1": "aws", "a2": "aws", "g": "gcp", "z": "azure"})
+	netem := NewNetem(topo, 1)
+	for _, tc := range []struct {
+		from, to hashiraft.ServerAddress
+		want     time.Duration
+	}{
+		{"a1", "g", 25 * time.Millisecond},
+		{"z", "a1", 37500 * time.Microsecond},
+		{"g", "z", 62500 * time.Microsecond},
+		{"a1", "a2", 250 * time.Microsecond},
+	} {
+		if got := netem.profile(tc.from, tc.to).Latency; got != tc.want {
+			t.Errorf("%s→%s: latency %s, want %s", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+// makeCloudCluster builds one node per cloud on in-memory transports wrapped
+// in CloudTopology.
+func makeCloudCluster(tb testing.TB) ([]*RaftNode, *Netem) {
+	tb.Helper()
+	clouds := []string{"aws", "gcp", "azure"}
+	peers := make([]string, len(clouds))
+	regions := make(map[hashiraft.ServerAddress]string, len(clouds))
+	trans := make([]*hashiraft.InmemTransport, len(clouds))
+	for i, cloud := range clouds {
+		peers[i] = "cp-" + cloud
+		regions[hashiraft.ServerAddress(peers[i])] = cloud
+		_, trans[i] = hashiraft.NewInmemTransport(hashiraft.ServerAddress(peers[i]))
+	}
+	for i := range trans {
+		for j := range trans {
+			if i != j {
+				trans[i].Connect(trans[j].LocalAddr(), trans[j])
+			}
+		}
+	}
+	netem := NewNetem(CloudTopology(regions), 42)
+	nodes := make([]*RaftNode, len(clouds))
+	for i := range clouds {
+		var err error
+		nodes[i], err = newRaftNodeWithTransport(Config{
+			NodeID:    peers[i],
+			DataDir:   tb.TempDir(),
+			Bootstrap: true,
+			Peers:     peers,
+		}, NewPipelineFSM(), netem.Wrap(trans[i]), hclog.NewNullLogger())
+		if err != nil {
+			tb.Fatalf("create node %s: %v", peers[i], err)
+		}
+	}
+	tb.Cleanup(func() {
+		for _, n := range nodes {
+			_ = n.Shutdown()
+		}
+	})
+	return nodes, netem
+}
+
+func TestNetemCloudClusterElectsAndReplicates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping netem cluster test in short mode")
+	}
+	nodes, _ := makeCloudCluster(t)
+	leader := waitForLeader(t, nodes, 15*time.Second)
+
+	cmd := mustMarshalCmd(t, CmdRegisterWorker, RegisterWorkerPayload{ID: "w1", CloudTag: "aws"})
+	start := time.Now()
+	if err := nodes[leader].Apply(cmd, 5*time.Second); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	// A commit needs at least one round trip to the nearest peer.
+	if d := time.Since(start); d < 35*time.Millisecond {
+		t.Errorf("commit took %s; expected at least a cross-cloud round trip", d)
+	}
+	t.Logf("leader=%s commit latency=%s", nodes[leader].cfg.NodeID, time.Since(start))
+}
+
+// BenchmarkElectionCloudTopology measures time to first leader for a fresh
+// three-cloud cluster.
+func BenchmarkElectionCloudTopology(b *testing.B) {
+	var total time.Duration
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		nodes, _ := makeCloudCluster(b)
+		elected := false
+		for !elected && time.Since(start) < 15*time.Second {
+			for _, n := range nodes {
+				elected = elected || n.State() == hashiraft.Leader
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !elected {
+			b.Fatal("no leader elected")
+		}
+		total += time.Since(start)
+		for _, n := range nodes {
+			_ = n.Shutdown()
+		}
+	}
+	b.ReportMetric(float64(total.Milliseconds())/float64(b.N), "ms/election")
+}
@@ -5,6 +5,8 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
@@ -19,17 +21,21 @@ type Config struct {
 	Bootstrap bool
 	DataDir   string
 	NodeID    string
-	Peers     []string // "host:port" entries for all cluster members, including self
+	Peers     []string // "host:port" or "id=host:port" entries for all cluster members, including self
 	RaftAddr  string
+	TLS       TLSConfig
 }
 
 // RaftNode wraps hashicorp/raft with BoltDB persistence.
 type RaftNode struct {
-	raft *hashiraft.Raft
-	cfg  Config
+	raft  *hashiraft.Raft
+	store *raftboltdb.BoltStore
+	snaps hashiraft.SnapshotStore
+	cfg   Config
 }
 
-// NewRaftNode creates and starts a Raft node with a TCP transport.
+// NewRaftNode creates and starts a Raft node with a TCP transport, wrapped in
+// mutual TLS when cfg.TLS is enabled.
 // BoltDB files are stored in cfg.DataDir — mount /data/raft/ as a Docker volume.
 func NewRaftNode(cfg Config, fsm hashiraft.FSM) (*RaftNode, error) {
 	_, port, err := net.SplitHostPort(cfg.RaftAddr)
@@ -41,6 +47,20 @@ func NewRaftNode(cfg Config, fsm hashiraft.FSM) (*RaftNode, error) {
 		return nil, fmt.Errorf("resolve raft addr %q: %w", cfg.RaftAddr, err)
 	}
 	logger := hclog.New(&hclog.LoggerOptions{Name: "raft", Level: hclog.Info})
+	if cfg.TLS.Enabled() {
+		ln, err := net.Listen("tcp", ":"+port)
+		if err != nil {
+			return nil, fmt.Errorf("listen raft addr: %w", err)
+		}
+		servers := peersToServers(cfg.Peers, cfg.NodeID, hashiraft.ServerAddress(cfg.RaftAddr))
+		revoked, _ := fsm.(certRevoker)
+		transport, err := newTLSTransport(ln, advertise, cfg.TLS, servers, revoked, logger)
+		if err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("tls transport: %w", err)
+		}
+		return newRaftNodeWithTransport(cfg, fsm, transport, logger)
+	}
 	transport, err := hashiraft.NewTCPTransportWithLogger(
 		":"+port, advertise, 3, 10*time.Second, logger,
 	)
@@ -50,6 +70,14 @@ func NewRaftNode(cfg Config, fsm hashiraft.FSM) (*RaftNode, error) {
 	return newRaftNodeWithTransport(cfg, fsm, transport, logger)
 }
 
+// NewRaftNodeWithTransport creates and starts a Raft node over a caller-built
+// transport, such as a GRPCTransport registered on the main gRPC server.
+// cfg.RaftAddr must equal transport.LocalAddr() and cfg.TLS is ignored.
+func NewRaftNodeWithTransport(cfg Config, fsm hashiraft.FSM, transport hashiraft.Transport) (*RaftNode, error) {
+	logger := hclog.New(&hclog.LoggerOptions{Name: "raft", Level: hclog.Info})
+	return newRaftNodeWithTransport(cfg, fsm, transport, logger)
+}
+
 // newRaftNodeWithTransport is the internal constructor — used by NewRaftNode and tests.
 func newRaftNodeWithTransport(cfg Config, fsm hashiraft.FSM, transport hashiraft.Transport,
 	logger hclog.Logger) (*RaftNode, error) {
@@ -58,28 +86,17 @@ func newRaftNodeWithTransport(cfg Config, fsm hashiraft.FSM, transport hashiraft
 		return nil, fmt.Errorf("create data dir: %w", err)
 	}
 
-	boltPath := filepath.Join(cfg.DataDir, "raft.db")
-	boltStore, err := raftboltdb.NewBoltStore(boltPath)
+	boltStore, err := raftboltdb.NewBoltStore(boltPath(cfg.DataDir))
 	if err != nil {
 		return nil, fmt.Errorf("bolt store: %w", err)
 	}
 
-	snapDir := filepath.Join(cfg.DataDir, "snapshots")
-	snapStore, err := hashiraft.NewFileSnapshotStore(snapDir, 3, os.Stderr)
+	snapStore, err := hashiraft.NewFileSnapshotStore(snapshotDir(cfg.DataDir), 3, os.Stderr)
 	if err != nil {
 		return nil, fmt.Errorf("snapshot store: %w", err)
 	}
 
-	raftCfg := hashiraft.DefaultConfig()
-	raftCfg.LocalID = hashiraft.ServerID(cfg.NodeID)
-	raftCfg.HeartbeatTimeout = 500 * time.Millisecond
-	raftCfg.ElectionTimeout = 1000 * time.Millisecond
-	raftCfg.CommitTimeout = 50 * time.Millisecond
-	raftCfg.SnapshotInterval = 30 * time.Second
-	raftCfg.SnapshotThreshold = 100
-	raftCfg.Logger = logger
-
-	r, err := hashiraft.NewRaft(raftCfg, fsm, boltStore, boltStore, snapStore, transport)
+	r, err := hashiraft.NewRaft(newRaftConfig(cfg.NodeID, logger), fsm, boltStore, boltStore, snapStore, transport)
 	if err != nil {
 		return nil, fmt.Errorf("new raft: %w", err)
 	}
@@ -99,10 +116,32 @@ func newRaftNodeWithTransport(cfg Config, fsm hashiraft.FSM, transport hashiraft
 		}
 	}
 
-	return &RaftNode{raft: r, cfg: cfg}, nil
+	return &RaftNode{raft: r, store: boltStore, snaps: snapStore, cfg: cfg}, nil
+}
+
+// newRaftConfig returns the hashicorp/raft tuning shared by running nodes and
+// offline recovery.
+func newRaftConfig(nodeID string, logger hclog.Logger) *hashiraft.Config {
+	raftCfg := hashiraft.DefaultConfig()
+	raftCfg.LocalID = hashiraft.ServerID(nodeID)
+	raftCfg.HeartbeatTimeout = 500 * time.Millisecond
+	raftCfg.ElectionTimeout = 1000 * time.Millisecond
+	raftCfg.CommitTimeout = 50 * time.Millisecond
+	raftCfg.SnapshotInterval = 30 * time.Second
+	raftCfg.SnapshotThreshold = 100
+	raftCfg.Logger = logger
+	return raftCfg
 }
 
+// boltPath and snapshotDir locate a node's log, stable store and snapshots
+// under its data dir.
+func boltPath(dataDir string) string    { return filepath.Join(dataDir, "raft.db") }
+func snapshotDir(dataDir string) string { return filepath.Join(dataDir, "snapshots") }
+
 // peersToServers converts a Peers slice into a raft.Configuration server list.
+// An entry is either "host:port", whose server ID is the host, or "id=host:port"
+// for peers addressed by IP, where the ID must still match the node's NODE_ID
+// (and, with TLS, its certificate SAN).
 // Falls back to single-node if peers is empty (used in tests).
 func peersToServers(peers []string, nodeID string, localAddr hashiraft.ServerAddress) []hashiraft.Server {
 	if len(peers) == 0 {
@@ -111,7 +150,9 @@ func peersToServers(peers []string, nodeID string, localAddr hashiraft.ServerAdd
 	servers := make([]hashiraft.Server, 0, len(peers))
 	for _, peer := range peers {
 		id := peer
-		if host, _, err := net.SplitHostPort(peer); err == nil {
+		if name, addr, ok := strings.Cut(peer, "="); ok {
+			id, peer = name, addr
+		} else if host, _, err := net.SplitHostPort(peer); err == nil {
 			id = host
 		}
 		servers = append(servers, hashiraft.Server{
@@ -132,6 +173,23 @@ func (n *RaftNode) Apply(cmd []byte, timeout time.Duration) error {
 	return err
 }
 
+// ApplyCommand is like Apply but also returns the FSM's response for the entry.
+// If the FSM rejected the command (returned an error), that error is returned.
+func (n *RaftNode) ApplyCommand(cmd []byte, timeout time.Duration) (interface{}, error) {
+	start := time.Now()
+	f := n.raft.Apply(cmd, timeout)
+	err := f.Error()
+	metrics.RaftReplicationLatencyMs.Observe(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		return nil, err
+	}
+	resp := f.Response()
+	if respErr, ok := resp.(error); ok {
+		return nil, respErr
+	}
+	return resp, nil
+}
+
 // State returns the current Raft state of this node.
 func (n *RaftNode) State() hashiraft.RaftState {
 	return n.raft.State()
@@ -169,12 +227,27 @@ func (n *RaftNode) LeaderID() string {
 	return string(id)
 }
 
+// IsServer reports whether id is a server in the latest Raft configuration.
+func (n *RaftNode) IsServer(id string) bool {
+	f := n.raft.GetConfiguration()
+	if f.Error() != nil {
+		return false
+	}
+	return slices.ContainsFunc(f.Configuration().Servers, func(s hashiraft.Server) bool {
+		return string(s.ID) == id
+	})
+}
+
 // Raft returns the underlying hashicorp/raft instance.
 func (n *RaftNode) Raft() *hashiraft.Raft {
 	return n.raft
 }
 
-// Shutdown cleanly stops the Raft node.
+// Shutdown cleanly stops the Raft node and closes its BoltDB store, so the
+// data dir can be reopened by a new node in the same process.
 func (n *RaftNode) Shutdown() error {
-	return n.raft.Shutdown().Error()
+	if err := n.raft.Shutdown().Error(); err != nil {
+		return err
+	}
+	return n.store.Close()
 }
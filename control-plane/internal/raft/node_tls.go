@@ -0,0 +1,50 @@
+package raft
+
+import (
+	"crypto/tls"
+	"errors"
+
+	hashiraft "github.com/hashicorp/raft"
+)
+
+// NodeTLS authenticates control planes to each other outside the Raft
+// transport — node certificate renewal, for one — with the transport's
+// certificates and checks: both sides present node certificates, and a client
+// is identified by the SAN that names a known Raft server ID.
+type NodeTLS struct {
+	layer *tlsStreamLayer // verifier only; never listens
+}
+
+// NewNodeTLS loads cfg. Its arguments are as for NewGRPCTLS.
+func NewNodeTLS(cfg TLSConfig, nodeID, localAddr string, peers []string, fsm hashiraft.FSM) (*NodeTLS, error) {
+	layer, err := newVerifier(cfg, nodeID, localAddr, peers, fsm)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeTLS{layer: layer}, nil
+}
+
+// ServerConfig presents the current node certificate and refuses clients
+// without a node certificate naming a known server ID.
+func (n *NodeTLS) ServerConfig() *tls.Config {
+	return n.layer.serverCfg.Clone()
+}
+
+// ClientConfig presents the current node certificate to the node serverID,
+// whose certificate must name it.
+func (n *NodeTLS) ClientConfig(serverID string) *tls.Config {
+	return n.layer.clientConfig(hashiraft.ServerID(serverID))
+}
+
+// PeerID returns the server ID named by the client certificate of a
+// connection accepted with ServerConfig.
+func (n *NodeTLS) PeerID(cs *tls.ConnectionState) (string, error) {
+	if cs == nil || len(cs.PeerCertificates) == 0 {
+		return "", errors.New("no node certificate presented")
+	}
+	id, ok := n.layer.knownID(cs.PeerCertificates[0])
+	if !ok {
+		return "", errors.New("client certificate names no known raft server")
+	}
+	return id, nil
+}
@@ -0,0 +1,65 @@
+package raft
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestNodeTLSIdentifiesPeerFromCertificate(t *testing.T) {
+	ca := newTestCA(t, "raft-ca")
+	peers := []string{"node-1=127.0.0.1:7001", "node-2=127.0.0.1:7002"}
+	server, err := NewNodeTLS(ca.writeNodeCert(t, t.TempDir(), "node-1", 2), "node-1", "127.0.0.1:7001", peers, nil)
+	if err != nil {
+		t.Fatalf("server NodeTLS: %v", err)
+	}
+	dial := func(cfg TLSConfig) (string, error) {
+		t.Helper()
+		client, err := NewNodeTLS(cfg, "node-2", "127.0.0.1:7002", peers, nil)
+		if err != nil {
+			t.Fatalf("client NodeTLS: %v", err)
+		}
+		ln, err := tls.Listen("tcp", "127.0.0.1:0", server.ServerConfig())
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		defer ln.Close()
+		type result struct {
+			id  string
+			err error
+		}
+		accepted := make(chan result, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				accepted <- result{err: err}
+				return
+			}
+			defer conn.Close()
+			tc := conn.(*tls.Conn)
+			if err := tc.Handshake(); err != nil {
+				accepted <- result{err: err}
+				return
+			}
+			cs := tc.ConnectionState()
+			id, err := server.PeerID(&cs)
+			accepted <- result{id, err}
+		}()
+		if conn, err := tls.Dial("tcp", ln.Addr().String(), client.ClientConfig("node-1")); err == nil {
+			conn.Close()
+		}
+		r := <-accepted
+		return r.id, r.err
+	}
+
+	id, err := dial(ca.writeNodeCert(t, t.TempDir(), "node-2", 3))
+	if err != nil || id != "node-2" {
+		t.Errorf("PeerID = %q, %v; want node-2", id, err)
+	}
+	// Chains to the CA, but names no raft server: refused in the handshake.
+	if id, err := dial(ca.writeNodeCert(t, t.TempDir(), "worker-aws-1", 4)); err == nil {
+		t.Errorf("certificate for a non-member accepted as %q", id)
+	}
+	if _, err := server.PeerID(nil); err == nil {
+		t.Error("expected PeerID to refuse a connection without TLS")
+	}
+}
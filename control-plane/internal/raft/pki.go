@@ -0,0 +1,176 @@
+package raft
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// maxIssuedCerts bounds the certificate issuance audit history kept in the FSM.
+// Revoked serials are tracked separately until they expire, so dropping an old
+// audit record never un-revokes a certificate.
+const maxIssuedCerts = 1024
+
+// CARoot is a cluster CA certificate. The private key is sealed (AES-GCM) by the
+// leader before it enters the Raft log, so neither the log, snapshots nor
+// /cluster-state ever contain it in the clear.
+type CARoot struct {
+	ID        string    `json:"id"` // hex serial of the CA certificate
+	CertPEM   string    `json:"cert_pem"`
+	SealedKey []byte    `json:"sealed_key"`
+	NotAfter  time.Time `json:"not_after"`
+	AddedAt   time.Time `json:"added_at"`
+	Active    bool      `json:"active"` // the one root that signs new certificates
+	RetiredAt time.Time `json:"retired_at,omitzero"`
+	Index     uint64    `json:"index"`
+}
+
+// IssuedCert is the audit record of a certificate signed by the cluster CA.
+type IssuedCert struct {
+	Serial       string    `json:"serial"` // hex
+	Kind         string    `json:"kind"`   // "node" or "worker"
+	Subject      string    `json:"subject"`
+	IssuerID     string    `json:"issuer_id"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+	Index        uint64    `json:"index"`
+	RevokedAt    time.Time `json:"revoked_at,omitzero"`
+	RevokeReason string    `json:"revoke_reason,omitempty"`
+}
+
+// AddCARootPayload carries a new root for an add_ca_root command. The new root
+// becomes the active signer; earlier roots stay trusted until retired.
+type AddCARootPayload struct {
+	Root CARoot `json:"root"`
+}
+
+// RetireCARootPayload carries fields for a retire_ca_root command.
+type RetireCARootPayload struct {
+	ID        string    `json:"id"`
+	RetiredAt time.Time `json:"retired_at"`
+}
+
+// RecordCertPayload carries fields for a record_cert command.
+type RecordCertPayload struct {
+	Cert IssuedCert `json:"cert"`
+}
+
+// RevokeCertPayload carries fields for a revoke_cert command.
+type RevokeCertPayload struct {
+	Serial    string    `json:"serial"`
+	Reason    string    `json:"reason"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+func (f *PipelineFSM) applyAddCARoot(raw json.RawMessage, index uint64) interface{} {
+	var p AddCARootPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal add_ca_root: %w", err)
+	}
+	for _, r := range f.caRoots {
+		if r.ID == p.Root.ID {
+			return fmt.Errorf("CA root %s already present", p.Root.ID)
+		}
+	}
+	for _, r := range f.caRoots {
+		r.Active = false
+	}
+	root := p.Root
+	root.Active = true
+	root.RetiredAt = time.Time{}
+	root.Index = index
+	f.caRoots = append(f.caRoots, &root)
+	slog.Info("FSM: CA root added", "root_id", root.ID, "not_after", root.NotAfter, "index", index)
+	return nil
+}
+
+func (f *PipelineFSM) applyRetireCARoot(raw json.RawMessage, index uint64) interface{} {
+	var p RetireCARootPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal retire_ca_root: %w", err)
+	}
+	for _, r := range f.caRoots {
+		if r.ID != p.ID {
+			continue
+		}
+		if r.Active {
+			return fmt.Errorf("CA root %s is the active signer; rotate first", p.ID)
+		}
+		r.RetiredAt = p.RetiredAt
+		slog.Info("FSM: CA root retired", "root_id", p.ID, "index", index)
+		return nil
+	}
+	return fmt.Errorf("CA root %s not found", p.ID)
+}
+
+func (f *PipelineFSM) applyRecordCert(raw json.RawMessage, index uint64) interface{} {
+	var p RecordCertPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal record_cert: %w", err)
+	}
+	c := p.Cert
+	c.Index = index
+	f.issuedCerts = append(f.issuedCerts, c)
+	if n := len(f.issuedCerts); n > maxIssuedCerts {
+		f.issuedCerts = append([]IssuedCert(nil), f.issuedCerts[n-maxIssuedCerts:]...)
+	}
+	// Revocations only matter until the certificate would have expired anyway.
+	for serial, notAfter := range f.revokedCerts {
+		if notAfter.Before(c.NotBefore) {
+			delete(f.revokedCerts, serial)
+		}
+	}
+	slog.Info("FSM: certificate issued", "serial", c.Serial, "kind", c.Kind,
+		"subject", c.Subject, "not_after", c.NotAfter, "index", index)
+	return nil
+}
+
+func (f *PipelineFSM) applyRevokeCert(raw json.RawMessage, index uint64) interface{} {
+	var p RevokeCertPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal revoke_cert: %w", err)
+	}
+	for i := range f.issuedCerts {
+		c := &f.issuedCerts[i]
+		if c.Serial != p.Serial {
+			continue
+		}
+		c.RevokedAt = p.RevokedAt
+		c.RevokeReason = p.Reason
+		f.revokedCerts[p.Serial] = c.NotAfter
+		slog.Warn("FSM: certificate revoked", "serial", p.Serial, "subject", c.Subject,
+			"reason", p.Reason, "index", index)
+		return nil
+	}
+	return fmt.Errorf("certificate %s not found", p.Serial)
+}
+
+// CARoots returns copies of all CA roots, including retired ones, oldest first.
+func (f *PipelineFSM) CARoots() []CARoot {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]CARoot, len(f.caRoots))
+	for i, r := range f.caRoots {
+		out[i] = *r
+	}
+	return out
+}
+
+// IssuedCerts returns a copy of the certificate audit history, oldest first.
+func (f *PipelineFSM) IssuedCerts() []IssuedCert {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]IssuedCert, len(f.issuedCerts))
+	copy(out, f.issuedCerts)
+	return out
+}
+
+// CertRevoked reports whether the certificate with the given hex serial has
+// been revoked and has not yet expired.
+func (f *PipelineFSM) CertRevoked(serial string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	_, ok := f.revokedCerts[serial]
+	return ok
+}
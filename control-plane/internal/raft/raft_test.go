@@ -2,8 +2,11 @@ package raft
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -122,6 +125,13 @@ func TestBootstrapMultiNode(t *testing.T) {
 		t.Skip("skipping multi-node bootstrap test in short mode")
 	}
 	nodes, _, _, _ := makeCluster(t, 3)
+	assertSingleLeader(t, nodes)
+}
+
+// assertSingleLeader waits for an election and checks exactly one node won.
+// Shared by the in-memory and gRPC transport tests.
+func assertSingleLeader(t *testing.T, nodes []*RaftNode) {
+	t.Helper()
 	leaderIdx := waitForLeader(t, nodes, 15*time.Second)
 
 	leaders := 0
@@ -148,8 +158,8 @@ func TestFSMApply(t *testing.T) {
 	result := fsm.Apply(&hashiraft.Log{
 		Index: 1, Term: 1, Type: hashiraft.LogCommand, Data: cmd,
 	})
-	if result != nil {
-		t.Fatalf("unexpected Apply result: %v", result)
+	if epoch, ok := result.(uint64); !ok || epoch != 1 {
+		t.Fatalf("expected register_worker to return epoch 1, got %v", result)
 	}
 
 	w := fsm.GetWorker("w-1")
@@ -171,6 +181,1045 @@ func TestFSMApply(t *testing.T) {
 	}
 }
 
+func TestFSMQuarantineWorker(t *testing.T) {
+	fsm := NewPipelineFSM()
+	apply := func(i uint64, typ CommandType, payload interface{}) interface{} {
+		return fsm.Apply(&hashiraft.Log{Index: i, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+
+	until := time.Now().Add(time.Minute).UTC()
+	apply(1, CmdRegisterWorker, RegisterWorkerPayload{ID: "w-1", CloudTag: "aws"})
+	if res := apply(2, CmdQuarantineWorker, QuarantineWorkerPayload{
+		ID: "w-1", Reason: "flapping", Until: until,
+	}); res != nil {
+		t.Fatalf("unexpected Apply result: %v", res)
+	}
+	w := fsm.GetWorker("w-1")
+	if w.Status != WorkerQuarantined || w.QuarantineReason != "flapping" ||
+		!w.QuarantinedUntil.Equal(until) || w.QuarantineCount != 1 {
+		t.Fatalf("unexpected worker after quarantine: %+v", w)
+	}
+
+	// Re-admission clears the reason; re-registration keeps the count.
+	apply(3, CmdUpdateWorkerStatus, UpdateWorkerStatusPayload{ID: "w-1", Status: WorkerOnline})
+	apply(4, CmdRegisterWorker, RegisterWorkerPayload{ID: "w-1", CloudTag: "aws"})
+	w = fsm.GetWorker("w-1")
+	if w.QuarantineReason != "" || !w.QuarantinedUntil.IsZero() || w.QuarantineCount != 1 {
+		t.Errorf("unexpected worker after re-admission: %+v", w)
+	}
+
+	if res := apply(5, CmdQuarantineWorker, QuarantineWorkerPayload{ID: "ghost"}); res == nil {
+		t.Error("expected error quarantining unknown worker")
+	}
+}
+
+func TestFSMRemoveWorker(t *testing.T) {
+	fsm := NewPipelineFSM()
+	apply := func(i uint64, typ CommandType, payload interface{}) interface{} {
+		return fsm.Apply(&hashiraft.Log{Index: i, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+
+	apply(1, CmdRegisterWorker, RegisterWorkerPayload{ID: "w-1", CloudTag: "aws"})
+	if res := apply(2, CmdRemoveWorker, RemoveWorkerPayload{ID: "w-1"}); res == nil {
+		t.Fatal("expected online worker removal to be refused")
+	}
+
+	apply(3, CmdUpdateWorkerStatus, UpdateWorkerStatusPayload{ID: "w-1", Status: WorkerOffline})
+	removedAt := time.Now().UTC()
+	if res := apply(4, CmdRemoveWorker, RemoveWorkerPayload{
+		ID: "w-1", Reason: "offline too long", RemovedAt: removedAt,
+	}); res != nil {
+		t.Fatalf("unexpected Apply result: %v", res)
+	}
+	if fsm.GetWorker("w-1") != nil {
+		t.Fatal("worker still present after remove_worker")
+	}
+	ts := fsm.Tombstones()
+	if len(ts) != 1 || ts[0].ID != "w-1" || ts[0].CloudTag != "aws" || ts[0].Index != 4 ||
+		!ts[0].RemovedAt.Equal(removedAt) {
+		t.Fatalf("unexpected tombstones: %+v", ts)
+	}
+
+	// Tombstone history is bounded.
+	for i := 0; i < maxTombstones+10; i++ {
+		id := fmt.Sprintf("w-%d", i+10)
+		idx := uint64(10 + 3*i)
+		apply(idx, CmdRegisterWorker, RegisterWorkerPayload{ID: id})
+		apply(idx+1, CmdUpdateWorkerStatus, UpdateWorkerStatusPayload{ID: id, Status: WorkerOffline})
+		apply(idx+2, CmdRemoveWorker, RemoveWorkerPayload{ID: id})
+	}
+	ts = fsm.Tombstones()
+	if len(ts) != maxTombstones {
+		t.Fatalf("expected %d tombstones, got %d", maxTombstones, len(ts))
+	}
+	if ts[len(ts)-1].ID != fmt.Sprintf("w-%d", maxTombstones+19) {
+		t.Errorf("newest tombstone should be last, got %s", ts[len(ts)-1].ID)
+	}
+}
+
+func TestFSMRevokeWorker(t *testing.T) {
+	fsm := NewPipelineFSM()
+	apply := func(i uint64, typ CommandType, payload interface{}) interface{} {
+		return fsm.Apply(&hashiraft.Log{Index: i, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+
+	apply(1, CmdRegisterWorker, RegisterWorkerPayload{ID: "w-1", CredentialHash: "abc"})
+	if got := fsm.CredentialHash("w-1"); got != "abc" {
+		t.Fatalf("expected stored credential hash, got %q", got)
+	}
+	if w := fsm.GetWorker("w-1"); w == nil {
+		t.Fatal("worker missing after register")
+	}
+
+	revokedAt := time.Now().UTC()
+	if res := apply(2, CmdRevokeWorker, RevokeWorkerPayload{
+		ID: "w-1", Reason: "compromised", RevokedAt: revokedAt,
+	}); res != nil {
+		t.Fatalf("unexpected Apply result: %v", res)
+	}
+	if fsm.CredentialHash("w-1") != "" {
+		t.Error("credential hash must be dropped on revoke")
+	}
+	rev := fsm.Revocation("w-1")
+	if rev == nil || rev.Reason != "compromised" || rev.Index != 2 {
+		t.Fatalf("unexpected revocation: %+v", rev)
+	}
+	if w := fsm.GetWorker("w-1"); w.Status != WorkerRevoked || !w.LastSeen.Equal(revokedAt) {
+		t.Errorf("unexpected worker after revoke: %+v", w)
+	}
+
+	if res := apply(3, CmdRegisterWorker, RegisterWorkerPayload{ID: "w-1", CredentialHash: "def"}); res == nil {
+		t.Fatal("expected re-registration of a revoked worker to be refused")
+	} else if _, isErr := res.(error); !isErr {
+		t.Fatalf("expected error result, got %v", res)
+	}
+
+	// Revocations and credentials survive a snapshot round-trip.
+	apply(4, CmdRegisterWorker, RegisterWorkerPayload{ID: "w-2", CredentialHash: "xyz"})
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	sink := &testSnapshotSink{buf: &bytes.Buffer{}}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist: %v", err)
+	}
+	snap.Release()
+	restored := NewPipelineFSM()
+	if err := restored.Restore(io.NopCloser(bytes.NewReader(sink.buf.Bytes()))); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.Revocation("w-1") == nil || restored.CredentialHash("w-2") != "xyz" {
+		t.Error("auth state lost across snapshot/restore")
+	}
+}
+
+func TestFSMCertificateAuthorityState(t *testing.T) {
+	fsm := NewPipelineFSM()
+	apply := func(i uint64, typ CommandType, payload interface{}) interface{} {
+		return fsm.Apply(&hashiraft.Log{Index: i, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+
+	now := time.Now().UTC()
+	apply(1, CmdAddCARoot, AddCARootPayload{Root: CARoot{ID: "r1", NotAfter: now.Add(time.Hour)}})
+	apply(2, CmdAddCARoot, AddCARootPayload{Root: CARoot{ID: "r2", NotAfter: now.Add(time.Hour)}})
+	roots := fsm.CARoots()
+	if len(roots) != 2 || roots[0].Active || !roots[1].Active {
+		t.Fatalf("newest root must be the only active one: %+v", roots)
+	}
+	if res := apply(3, CmdRetireCARoot, RetireCARootPayload{ID: "r2"}); res == nil {
+		t.Error("expected retiring the active root to be refused")
+	}
+
+	apply(4, CmdRecordCert, RecordCertPayload{Cert: IssuedCert{
+		Serial: "aa", Kind: "node", Subject: "cp-aws-1", IssuerID: "r2",
+		NotBefore: now, NotAfter: now.Add(time.Hour),
+	}})
+	if res := apply(5, CmdRevokeCert, RevokeCertPayload{Serial: "aa", Reason: "lost"}); res != nil {
+		t.Fatalf("unexpected Apply result: %v", res)
+	}
+	if !fsm.CertRevoked("aa") || fsm.IssuedCerts()[0].RevokeReason != "lost" {
+		t.Fatal("revocation not recorded")
+	}
+	if res := apply(6, CmdRevokeCert, RevokeCertPayload{Serial: "zz"}); res == nil {
+		t.Error("expected revoking an unknown serial to fail")
+	}
+
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	sink := &testSnapshotSink{buf: &bytes.Buffer{}}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist: %v", err)
+	}
+	snap.Release()
+	restored := NewPipelineFSM()
+	if err := restored.Restore(io.NopCloser(bytes.NewReader(sink.buf.Bytes()))); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if len(restored.CARoots()) != 2 || !restored.CertRevoked("aa") || len(restored.IssuedCerts()) != 1 {
+		t.Error("CA state lost across snapshot/restore")
+	}
+}
+
+func TestFSMTasks(t *testing.T) {
+	fsm := NewPipelineFSM()
+	var index uint64
+	apply := func(typ CommandType, payload interface{}) interface{} {
+		index++
+		return fsm.Apply(&hashiraft.Log{Index: index, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+
+	now := time.Now().UTC()
+	res := apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "m-0", JobID: "j", Type: TaskMap,
+		InputURIs: []string{"s3://in/0"}, CreatedAt: now, State: TaskSucceeded}})
+	if task, ok := res.(*Task); !ok || task.State != TaskPending || task.Attempt != 1 {
+		t.Fatalf("submit_task result = %#v", res)
+	}
+	if _, ok := apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "m-0", Type: TaskMap}}).(error); !ok {
+		t.Error("expected a duplicate task ID to be refused")
+	}
+	if _, ok := apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "x", Type: "shuffle"}}).(error); !ok {
+		t.Error("expected an unknown task type to be refused")
+	}
+	apply(CmdCancelTask, CancelTaskPayload{ID: "m-0", Reason: "test", CancelledAt: now})
+	if task := fsm.GetTask("m-0"); task.State != TaskCancelled || task.Error != "test" {
+		t.Fatalf("task after cancel = %+v", task)
+	}
+	if _, ok := apply(CmdCancelTask, CancelTaskPayload{ID: "m-0"}).(error); !ok {
+		t.Error("expected cancelling a finished task to be refused")
+	}
+
+	// Finished tasks beyond the limit are dropped oldest first.
+	for i := 0; i < maxFinishedTasks; i++ {
+		id := fmt.Sprintf("g-%05d", i)
+		apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: id, Type: TaskGeneric}})
+		apply(CmdCancelTask, CancelTaskPayload{ID: id, CancelledAt: now.Add(time.Duration(i+1) * time.Millisecond)})
+	}
+	apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "live", Type: TaskReduce}})
+	if fsm.GetTask("m-0") != nil || fsm.GetTask("g-00000") == nil || fsm.GetTask("live") == nil {
+		t.Fatal("expected only the oldest finished task to be evicted")
+	}
+
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	sink := &testSnapshotSink{buf: &bytes.Buffer{}}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist: %v", err)
+	}
+	restored := NewPipelineFSM()
+	if err := restored.Restore(io.NopCloser(bytes.NewReader(sink.buf.Bytes()))); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got := len(restored.Tasks()); got != maxFinishedTasks+1 {
+		t.Fatalf("restored %d tasks, want %d", got, maxFinishedTasks+1)
+	}
+	// The eviction order survives the restore.
+	restored.Apply(&hashiraft.Log{Index: index + 1, Type: hashiraft.LogCommand,
+		Data: mustMarshalCmd(t, CmdCancelTask, CancelTaskPayload{ID: "live", CancelledAt: now.Add(time.Hour)})})
+	if restored.GetTask("g-00000") != nil || restored.GetTask("live") == nil {
+		t.Error("restored FSM evicted the wrong finished task")
+	}
+}
+
+func TestFSMEvictionKeepsTasksWithKillOrders(t *testing.T) {
+	fsm := NewPipelineFSM()
+	var index uint64
+	apply := func(typ CommandType, payload interface{}) interface{} {
+		index++
+		return fsm.Apply(&hashiraft.Log{Index: index, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+	now := time.Now().UTC()
+	apply(CmdRegisterWorker, RegisterWorkerPayload{ID: "w-1"})
+	apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "k", Type: TaskGeneric}})
+	apply(CmdAssignTask, AssignTaskPayload{ID: "k", WorkerID: "w-1", AssignedAt: now, LeaseExpires: now.Add(time.Minute)})
+	apply(CmdCancelTask, CancelTaskPayload{ID: "k", CancelledAt: now})
+	if k := fsm.GetTask("k"); k.State != TaskCancelled || len(k.Kills) != 1 {
+		t.Fatalf("cancelled running task = %+v", k)
+	}
+
+	finish := func(id string) {
+		apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: id, Type: TaskGeneric}})
+		apply(CmdCancelTask, CancelTaskPayload{ID: id, CancelledAt: now})
+	}
+	for i := range maxFinishedTasks {
+		finish(fmt.Sprintf("g-%05d", i))
+	}
+	if fsm.GetTask("k") == nil || len(fsm.KillOrders("w-1")) != 1 {
+		t.Fatal("a finished task whose kill order is undelivered must not be evicted")
+	}
+	if fsm.GetTask("g-00000") != nil {
+		t.Error("the oldest task without kill orders should have been evicted instead")
+	}
+
+	// Once the order is revoked the task is evicted like any other.
+	apply(CmdRevokeTaskLease, RevokeTaskLeasePayload{ID: "k", Attempt: 1, RevokedAt: now.Add(time.Minute)})
+	finish("last")
+	if fsm.GetTask("k") != nil || fsm.GetTask("g-00001") == nil {
+		t.Error("task should be evicted once its kill order is gone")
+	}
+	if got := len(fsm.Tasks()); got != maxFinishedTasks {
+		t.Errorf("kept %d tasks, want %d", got, maxFinishedTasks)
+	}
+}
+
+func TestFSMTaskLeases(t *testing.T) {
+	fsm := NewPipelineFSM()
+	var index uint64
+	apply := func(typ CommandType, payload interface{}) interface{} {
+		index++
+		return fsm.Apply(&hashiraft.Log{Index: index, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+	now := time.Now().UTC()
+	lease := now.Add(time.Minute)
+
+	retry := DefaultRetryPolicy
+	retry.MaxAttempts = 2
+	apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "t", Type: TaskGeneric, CreatedAt: now, Retry: &retry}})
+	apply(CmdAssignTask, AssignTaskPayload{ID: "t", WorkerID: "w-1", AssignedAt: now, LeaseExpires: lease,
+		EgressBytes: 100, EgressCost: 0.5})
+	if res := apply(CmdAssignTask, AssignTaskPayload{ID: "t", WorkerID: "w-2"}); res == nil {
+		t.Error("expected assigning a running task to be refused")
+	}
+	if res, _ := apply(CmdRenewTaskLease, RenewTaskLeasePayload{ID: "t", WorkerID: "w-2", Attempt: 1}).(error); !errors.Is(res, ErrLeaseLost) {
+		t.Errorf("renew by another worker: expected ErrLeaseLost, got %v", res)
+	}
+
+	// A requeue for an older attempt must not undo a newer assignment.
+	requeued := now.Add(time.Minute)
+	apply(CmdRequeueTask, RequeueTaskPayload{ID: "t", Attempt: 1, Reason: "lease expired", RequeuedAt: requeued})
+	if task := fsm.GetTask("t"); !task.PendingSince.Equal(requeued) {
+		t.Errorf("pending since %v after requeue, want %v", task.PendingSince, requeued)
+	}
+	apply(CmdAssignTask, AssignTaskPayload{ID: "t", WorkerID: "w-2", AssignedAt: now, LeaseExpires: lease,
+		EgressBytes: 100, EgressCost: 0.5})
+	if task := fsm.GetTask("t"); task.EgressBytes != 200 || task.EgressCost != 1 {
+		t.Errorf("egress should add up over attempts: %+v", task)
+	}
+	if res := apply(CmdRequeueTask, RequeueTaskPayload{ID: "t", Attempt: 1}); res == nil {
+		t.Error("expected a stale requeue to be refused")
+	}
+	if res, _ := apply(CmdCompleteTask, CompleteTaskPayload{ID: "t", WorkerID: "w-1", Attempt: 1}).(error); !errors.Is(res, ErrLeaseLost) {
+		t.Errorf("completion by the previous holder: expected ErrLeaseLost, got %v", res)
+	}
+	apply(CmdCompleteTask, CompleteTaskPayload{ID: "t", WorkerID: "w-2", Attempt: 2, Error: "exit 1", FinishedAt: now})
+	// The expired lease counted as the first of two attempts.
+	if task := fsm.GetTask("t"); task.State != TaskFailed || task.Attempt != 2 || task.Error != "exit 1" ||
+		len(task.Attempts) != 2 || task.Attempts[0].Outcome != AttemptLeaseExpired {
+		t.Errorf("task after completion = %+v", task)
+	}
+	if dl := fsm.DeadLetters(); len(dl) != 1 || dl[0].TaskID != "t" || dl[0].Reason != DeadLetterExhausted {
+		t.Errorf("dead letters = %+v", dl)
+	}
+}
+
+func TestFSMTaskRetries(t *testing.T) {
+	fsm := NewPipelineFSM()
+	var index uint64
+	apply := func(typ CommandType, payload interface{}) interface{} {
+		index++
+		return fsm.Apply(&hashiraft.Log{Index: index, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+	now := time.Now().UTC()
+	assign := func(id, worker string) {
+		t.Helper()
+		if err, _ := apply(CmdAssignTask, AssignTaskPayload{ID: id, WorkerID: worker, AssignedAt: now,
+			LeaseExpires: now.Add(time.Minute)}).(error); err != nil {
+			t.Fatalf("assign %s: %v", id, err)
+		}
+	}
+	fail := func(id, worker, class string) *Task {
+		t.Helper()
+		task := fsm.GetTask(id)
+		res := apply(CmdCompleteTask, CompleteTaskPayload{ID: id, WorkerID: worker, Attempt: task.Attempt,
+			Error: "boom", ErrorClass: class, FinishedAt: now})
+		got, ok := res.(*Task)
+		if !ok {
+			t.Fatalf("complete %s: %v", id, res)
+		}
+		return got
+	}
+
+	bad := DefaultRetryPolicy
+	bad.Multiplier = 0.5
+	if _, ok := apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "bad", Type: TaskGeneric, Retry: &bad}}).(error); !ok {
+		t.Error("expected an invalid retry policy to be refused")
+	}
+
+	// A retryable failure backs off; the delay is the same on every replica.
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second, Multiplier: 4, Jitter: 0.5}
+	apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "r", Type: TaskGeneric, CreatedAt: now, Retry: &policy}})
+	assign("r", "w-1")
+	if res, _ := apply(CmdCompleteTask, CompleteTaskPayload{ID: "r", WorkerID: "w-1", Attempt: 1,
+		ErrorClass: "flaky"}).(error); res == nil {
+		t.Error("expected an unknown error class to be refused")
+	}
+	task := fail("r", "w-1", "")
+	if task.State != TaskPending || task.Attempt != 2 || task.Failures != 1 ||
+		!task.NotBefore.Equal(now.Add(policy.Backoff("r", 1))) {
+		t.Fatalf("task after a retryable failure = %+v", task)
+	}
+	if d := policy.Backoff("r", 1); d <= 500*time.Millisecond || d > time.Second || d != policy.Backoff("r", 1) {
+		t.Errorf("first backoff = %v, want a stable value in (0.5s, 1s]", d)
+	}
+	if d := policy.Backoff("r", 5); d <= 1500*time.Millisecond || d > policy.MaxBackoff {
+		t.Errorf("late backoff = %v, want it capped at %v less jitter", d, policy.MaxBackoff)
+	}
+	assign("r", "w-2")
+	if task = fail("r", "w-2", ErrorRetryable); task.State != TaskPending || task.Attempt != 3 {
+		t.Fatalf("task after a second failure = %+v", task)
+	}
+	assign("r", "w-1")
+	if task = fail("r", "w-1", ""); task.State != TaskFailed || !task.NotBefore.IsZero() || len(task.Attempts) != 3 ||
+		task.Attempts[1].Worker != "w-2" || task.Attempts[2].Outcome != AttemptFailed {
+		t.Fatalf("task after exhausting its attempts = %+v", task)
+	}
+
+	// A fatal failure is never retried.
+	apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "f", JobID: "j", Type: TaskGeneric, CreatedAt: now}})
+	assign("f", "w-1")
+	if task = fail("f", "w-1", ErrorFatal); task.State != TaskFailed || task.Failures != 1 {
+		t.Fatalf("task after a fatal failure = %+v", task)
+	}
+
+	// A worker going offline requeues its running tasks.
+	apply(CmdRegisterWorker, RegisterWorkerPayload{ID: "w-3"})
+	apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "o", Type: TaskGeneric, CreatedAt: now}})
+	assign("o", "w-3")
+	apply(CmdUpdateWorkerStatus, UpdateWorkerStatusPayload{ID: "w-3", Status: WorkerOffline})
+	if task = fsm.GetTask("o"); task.State != TaskPending || task.AssignedWorker != "" || task.Attempt != 2 ||
+		task.Attempts[0].Outcome != AttemptWorkerLost || task.NotBefore.IsZero() {
+		t.Fatalf("task after its worker went offline = %+v", task)
+	}
+
+	want := []struct{ task, reason string }{{"r", DeadLetterExhausted}, {"f", DeadLetterFatal}}
+	check := func(fsm *PipelineFSM) {
+		t.Helper()
+		dl := fsm.DeadLetters()
+		if len(dl) != len(want) {
+			t.Fatalf("dead letters = %+v", dl)
+		}
+		for i, w := range want {
+			if dl[i].TaskID != w.task || dl[i].Reason != w.reason || dl[i].Error != "boom" || len(dl[i].Attempts) == 0 {
+				t.Errorf("dead letter %d = %+v, want %s (%s)", i, dl[i], w.task, w.reason)
+			}
+		}
+	}
+	check(fsm)
+
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	sink := &testSnapshotSink{buf: &bytes.Buffer{}}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist: %v", err)
+	}
+	restored := NewPipelineFSM()
+	if err := restored.Restore(io.NopCloser(bytes.NewReader(sink.buf.Bytes()))); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	check(restored)
+	if task = restored.GetTask("o"); task.Failures != 1 || len(task.Attempts) != 1 || !task.NotBefore.Equal(fsm.GetTask("o").NotBefore) {
+		t.Errorf("restored retry state = %+v", task)
+	}
+}
+
+func TestFSMSpeculativeAttempts(t *testing.T) {
+	fsm := NewPipelineFSM()
+	var index uint64
+	apply := func(typ CommandType, payload interface{}) interface{} {
+		index++
+		return fsm.Apply(&hashiraft.Log{Index: index, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+	now := time.Now().UTC()
+	lease := now.Add(time.Minute)
+	apply(CmdRegisterWorker, RegisterWorkerPayload{ID: "w-2"})
+	start := func(id string) {
+		t.Helper()
+		apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: id, Type: TaskReduce, CreatedAt: now}})
+		apply(CmdAssignTask, AssignTaskPayload{ID: id, WorkerID: "w-1", AssignedAt: now, LeaseExpires: lease})
+		res := apply(CmdSpeculateTask, SpeculateTaskPayload{ID: id, Attempt: 1, WorkerID: "w-2",
+			AssignedAt: now.Add(time.Second), LeaseExpires: lease})
+		if task, ok := res.(*Task); !ok || task.Speculative == nil || task.Speculative.Attempt != 2 {
+			t.Fatalf("speculate %s = %#v", id, res)
+		}
+	}
+	isLeaseLost := func(res interface{}) bool {
+		err, _ := res.(error)
+		return errors.Is(err, ErrLeaseLost)
+	}
+
+	// The duplicate wins by finishing first; the straggler is superseded.
+	apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "a", Type: TaskReduce, CreatedAt: now}})
+	apply(CmdAssignTask, AssignTaskPayload{ID: "a", WorkerID: "w-1", AssignedAt: now, LeaseExpires: lease})
+	for _, p := range []SpeculateTaskPayload{
+		{ID: "a", Attempt: 1, WorkerID: "w-1"},
+		{ID: "a", Attempt: 2, WorkerID: "w-2"},
+	} {
+		if _, ok := apply(CmdSpeculateTask, p).(error); !ok {
+			t.Errorf("expected speculation %+v to be refused", p)
+		}
+	}
+	apply(CmdSpeculateTask, SpeculateTaskPayload{ID: "a", Attempt: 1, WorkerID: "w-2", AssignedAt: now, LeaseExpires: lease})
+	if _, ok := apply(CmdSpeculateTask, SpeculateTaskPayload{ID: "a", Attempt: 1, WorkerID: "w-3"}).(error); !ok {
+		t.Error("expected a second duplicate to be refused")
+	}
+	if res := apply(CmdRenewTaskLease, RenewTaskLeasePayload{ID: "a", WorkerID: "w-2", Attempt: 2,
+		LeaseExpires: lease.Add(time.Minute)}); res != nil {
+		t.Fatalf("renew speculative lease: %v", res)
+	}
+	if task := fsm.GetTask("a"); !task.Speculative.LeaseExpires.Equal(lease.Add(time.Minute)) || !task.LeaseExpires.Equal(lease) {
+		t.Errorf("renewal landed on the wrong attempt: %+v", task)
+	}
+	apply(CmdCompleteTask, CompleteTaskPayload{ID: "a", WorkerID: "w-2", Attempt: 2, Succeeded: true, FinishedAt: now})
+	if task := fsm.GetTask("a"); task.State != TaskSucceeded || task.Attempt != 2 || task.AssignedWorker != "w-2" ||
+		task.Speculative != nil || task.CommitAttempt != 2 || len(task.Attempts) != 2 ||
+		task.Attempts[0].Outcome != AttemptSuperseded || task.Attempts[0].Worker != "w-1" {
+		t.Fatalf("task after the duplicate won = %+v", task)
+	}
+	if res := apply(CmdCompleteTask, CompleteTaskPayload{ID: "a", WorkerID: "w-1", Attempt: 1, Succeeded: true}); !isLeaseLost(res) {
+		t.Errorf("straggler completion after losing: %v", res)
+	}
+
+	// The output commit goes to the first attempt to ask, and asking again
+	// is harmless.
+	start("b")
+	commit := func(worker string, attempt int) interface{} {
+		return apply(CmdCommitTaskOutput, CommitTaskOutputPayload{ID: "b", WorkerID: worker, Attempt: attempt, CommittedAt: now})
+	}
+	if task, ok := commit("w-1", 1).(*Task); !ok || task.CommitAttempt != 1 || task.Speculative != nil ||
+		task.Attempts[0].Attempt != 2 || task.Attempts[0].Outcome != AttemptSuperseded {
+		t.Fatalf("first commit = %+v", task)
+	}
+	if _, ok := commit("w-1", 1).(*Task); !ok {
+		t.Error("a repeated commit by the granted attempt must succeed")
+	}
+	if res := commit("w-2", 2); !isLeaseLost(res) {
+		t.Errorf("commit by the superseded attempt: %v", res)
+	}
+	if _, ok := apply(CmdSpeculateTask, SpeculateTaskPayload{ID: "b", Attempt: 1, WorkerID: "w-3"}).(error); !ok {
+		t.Error("expected no duplicate once an attempt is committing")
+	}
+
+	// A failed straggler leaves the duplicate running; losing that one too
+	// retries the task with a fresh attempt number.
+	start("c")
+	apply(CmdCompleteTask, CompleteTaskPayload{ID: "c", WorkerID: "w-1", Attempt: 1, Error: "oom", FinishedAt: now})
+	if task := fsm.GetTask("c"); task.State != TaskRunning || task.Attempt != 2 || task.AssignedWorker != "w-2" ||
+		task.Speculative != nil || task.Failures != 1 {
+		t.Fatalf("task after the straggler failed = %+v", task)
+	}
+	apply(CmdUpdateWorkerStatus, UpdateWorkerStatusPayload{ID: "w-2", Status: WorkerOffline})
+	if task := fsm.GetTask("c"); task.State != TaskPending || task.Attempt != 3 || task.Failures != 2 {
+		t.Fatalf("task after both attempts failed = %+v", task)
+	}
+
+	// A duplicate whose lease runs out just ends; cancelling ends both.
+	apply(CmdUpdateWorkerStatus, UpdateWorkerStatusPayload{ID: "w-2", Status: WorkerOnline})
+	start("d")
+	apply(CmdRequeueTask, RequeueTaskPayload{ID: "d", Attempt: 2, Reason: "lease expired", RequeuedAt: now})
+	if task := fsm.GetTask("d"); task.State != TaskRunning || task.Attempt != 1 || task.Speculative != nil {
+		t.Fatalf("task after the duplicate's lease ran out = %+v", task)
+	}
+	start("e")
+	apply(CmdCancelTask, CancelTaskPayload{ID: "e", Reason: "test", CancelledAt: now})
+	if task := fsm.GetTask("e"); task.Speculative != nil || len(task.Attempts) != 2 {
+		t.Errorf("cancelled task = %+v", task)
+	}
+
+	// With an output URI, only the attempt holding the commit may succeed:
+	// one that skips the commit is refused, before and after the other wins.
+	apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "f", Type: TaskReduce, OutputURI: "s3://out/f", CreatedAt: now}})
+	apply(CmdAssignTask, AssignTaskPayload{ID: "f", WorkerID: "w-1", AssignedAt: now, LeaseExpires: lease})
+	apply(CmdSpeculateTask, SpeculateTaskPayload{ID: "f", Attempt: 1, WorkerID: "w-2", AssignedAt: now, LeaseExpires: lease})
+	complete := func(worker string, attempt int) interface{} {
+		return apply(CmdCompleteTask, CompleteTaskPayload{ID: "f", WorkerID: worker, Attempt: attempt, Succeeded: true, FinishedAt: now})
+	}
+	if err, _ := complete("w-1", 1).(error); !errors.Is(err, ErrCommitRequired) {
+		t.Fatalf("success without the commit: %v", err)
+	}
+	if task := fsm.GetTask("f"); task.State != TaskRunning || task.Speculative == nil {
+		t.Fatalf("a refused completion must leave both attempts running: %+v", task)
+	}
+	apply(CmdCommitTaskOutput, CommitTaskOutputPayload{ID: "f", WorkerID: "w-2", Attempt: 2, CommittedAt: now})
+	if res := complete("w-1", 1); !isLeaseLost(res) {
+		t.Errorf("loser completing after the commit went elsewhere: %v", res)
+	}
+	if _, ok := complete("w-2", 2).(*Task); !ok {
+		t.Fatal("the commit holder must be able to complete")
+	}
+	if task := fsm.GetTask("f"); task.State != TaskSucceeded || task.CommitAttempt != 2 {
+		t.Errorf("task at the end = %+v", task)
+	}
+}
+
+func TestFSMKillOrders(t *testing.T) {
+	fsm := NewPipelineFSM()
+	var index uint64
+	apply := func(typ CommandType, payload interface{}) interface{} {
+		index++
+		return fsm.Apply(&hashiraft.Log{Index: index, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+	now := time.Now().UTC()
+	lease := now.Add(time.Minute)
+	grace := 10 * time.Second
+	start := func(id string) {
+		t.Helper()
+		apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: id, Type: TaskGeneric, Timeout: time.Minute,
+			KillGrace: grace, CreatedAt: now}})
+		if err, _ := apply(CmdAssignTask, AssignTaskPayload{ID: id, WorkerID: "w-1", AssignedAt: now, LeaseExpires: lease}).(error); err != nil {
+			t.Fatalf("assign %s: %v", id, err)
+		}
+	}
+
+	if _, ok := apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "neg", Type: TaskGeneric, Timeout: -time.Second}}).(error); !ok {
+		t.Error("expected a negative timeout to be refused")
+	}
+
+	// A deadline kill that the worker ignores ends in a revoked lease and a
+	// retry, the attempt recorded as timed out.
+	start("a")
+	if task := fsm.GetTask("a"); !task.Deadline(task.StartedAt).Equal(now.Add(time.Minute)) {
+		t.Fatalf("deadline = %v", task.Deadline(task.StartedAt))
+	}
+	killed := now.Add(2 * time.Minute)
+	kill := KillTaskPayload{ID: "a", Attempt: 1, Reason: "deadline exceeded", RequestedAt: killed}
+	if task, ok := apply(CmdKillTask, kill).(*Task); !ok || len(task.Kills) != 1 ||
+		task.Kills[0].Worker != "w-1" || !task.Kills[0].GraceUntil.Equal(killed.Add(grace)) {
+		t.Fatalf("kill_task result = %#v", task)
+	}
+	if _, ok := apply(CmdKillTask, kill).(error); !ok {
+		t.Error("expected a second kill of the same attempt to be refused")
+	}
+	if orders := fsm.KillOrders("w-1"); len(orders) != 1 || orders[0].TaskID != "a" {
+		t.Fatalf("KillOrders(w-1) = %+v", orders)
+	}
+	if orders := fsm.KillOrders("w-2"); len(orders) != 0 {
+		t.Errorf("KillOrders(w-2) = %+v", orders)
+	}
+	if res := apply(CmdRenewTaskLease, RenewTaskLeasePayload{ID: "a", WorkerID: "w-1", Attempt: 1,
+		LeaseExpires: lease.Add(time.Minute)}); res != nil {
+		t.Errorf("a killed attempt keeps its lease until revoked: %v", res)
+	}
+	apply(CmdRevokeTaskLease, RevokeTaskLeasePayload{ID: "a", Attempt: 1, RevokedAt: killed.Add(grace)})
+	if task := fsm.GetTask("a"); task.State != TaskPending || task.Attempt != 2 || len(task.Kills) != 0 ||
+		len(task.Attempts) != 1 || task.Attempts[0].Outcome != AttemptTimedOut {
+		t.Fatalf("task after revocation = %+v", task)
+	}
+	if _, ok := apply(CmdRevokeTaskLease, RevokeTaskLeasePayload{ID: "a", Attempt: 1}).(error); !ok {
+		t.Error("expected a revocation without a kill order to be refused")
+	}
+
+	// A worker that stops in time reports the attempt failed; it still
+	// counts as timed out and the order goes away.
+	start("b")
+	apply(CmdKillTask, KillTaskPayload{ID: "b", Attempt: 1, Reason: "deadline exceeded", RequestedAt: killed})
+	apply(CmdCompleteTask, CompleteTaskPayload{ID: "b", WorkerID: "w-1", Attempt: 1, Error: "stopped", FinishedAt: killed})
+	if task := fsm.GetTask("b"); task.State != TaskPending || len(task.Kills) != 0 ||
+		task.Attempts[0].Outcome != AttemptTimedOut {
+		t.Fatalf("task after stopping = %+v", task)
+	}
+
+	// Cancelling a running task orders its worker to stop; once the grace
+	// passes the order is just dropped.
+	start("c")
+	apply(CmdCancelTask, CancelTaskPayload{ID: "c", Reason: "operator", CancelledAt: now})
+	task := fsm.GetTask("c")
+	if task.State != TaskCancelled || len(task.Kills) != 1 || task.Kills[0].Reason != "cancelled: operator" {
+		t.Fatalf("cancelled task = %+v", task)
+	}
+	apply(CmdRevokeTaskLease, RevokeTaskLeasePayload{ID: "c", Attempt: 1, RevokedAt: now.Add(grace)})
+	if task := fsm.GetTask("c"); task.State != TaskCancelled || len(task.Kills) != 0 {
+		t.Fatalf("cancelled task after the grace = %+v", task)
+	}
+
+	// Cancelling a job cancels its unfinished tasks and kills the running
+	// ones; the job ends cancelled, not failed.
+	job := SubmitJobPayload{
+		Job: Job{ID: "j", CreatedAt: now, Stages: []*Stage{{Name: "one"}, {Name: "two", DependsOn: []string{"one"}}}},
+		Tasks: []Task{
+			{ID: "j-1", Type: TaskGeneric, Stage: "one", CreatedAt: now},
+			{ID: "j-2", Type: TaskGeneric, Stage: "one", CreatedAt: now},
+			{ID: "j-3", Type: TaskGeneric, Stage: "two", DependsOn: []string{"j-1"}, CreatedAt: now},
+		},
+	}
+	if _, ok := apply(CmdSubmitJob, job).(*Job); !ok {
+		t.Fatal("submit_job failed")
+	}
+	apply(CmdAssignTask, AssignTaskPayload{ID: "j-1", WorkerID: "w-2", AssignedAt: now, LeaseExpires: lease})
+	j, ok := apply(CmdCancelJob, CancelJobPayload{ID: "j", Reason: "no longer needed", CancelledAt: now}).(*Job)
+	if !ok || j.State != JobCancelled || j.Error != "job cancelled: no longer needed" || j.FinishedAt.IsZero() {
+		t.Fatalf("cancel_job result = %#v", j)
+	}
+	for _, id := range []string{"j-1", "j-2", "j-3"} {
+		if st := fsm.GetTask(id).State; st != TaskCancelled {
+			t.Errorf("%s is %s after the job was cancelled", id, st)
+		}
+	}
+	if orders := fsm.KillOrders("w-2"); len(orders) != 1 || orders[0].TaskID != "j-1" {
+		t.Errorf("KillOrders(w-2) = %+v", orders)
+	}
+	if _, ok := apply(CmdCancelJob, CancelJobPayload{ID: "j"}).(error); !ok {
+		t.Error("expected cancelling a finished job to be refused")
+	}
+}
+
+func TestFSMJobs(t *testing.T) {
+	fsm := NewPipelineFSM()
+	var index uint64
+	apply := func(typ CommandType, payload interface{}) interface{} {
+		index++
+		return fsm.Apply(&hashiraft.Log{Index: index, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+	now := time.Now().UTC()
+	// Diamond: a → (b, c) → d, plus an independent branch x → y.
+	diamond := func(id, policy string) SubmitJobPayload {
+		task := func(tid, stage string, deps ...string) Task {
+			return Task{ID: id + "-" + tid, Type: TaskGeneric, Stage: stage, DependsOn: deps, CreatedAt: now}
+		}
+		return SubmitJobPayload{
+			Job: Job{ID: id, FailurePolicy: policy, CreatedAt: now, Stages: []*Stage{
+				{Name: "first"}, {Name: "middle", DependsOn: []string{"first"}}, {Name: "last"},
+			}},
+			Tasks: []Task{
+				task("a", "first"), task("x", "first"),
+				task("b", "middle", id+"-a"), task("c", "middle", id+"-a"),
+				task("d", "last", id+"-b", id+"-c"), task("y", "last", id+"-x"),
+			},
+		}
+	}
+	run := func(id string, ok bool) {
+		t.Helper()
+		apply(CmdAssignTask, AssignTaskPayload{ID: id, WorkerID: "w", AssignedAt: now, LeaseExpires: now.Add(time.Minute)})
+		// Failures are fatal so that they fail the task without a retry.
+		payload := CompleteTaskPayload{ID: id, WorkerID: "w", Attempt: 1, Succeeded: ok, FinishedAt: now}
+		if !ok {
+			payload.ErrorClass = ErrorFatal
+		}
+		if err, _ := apply(CmdCompleteTask, payload).(error); err != nil {
+			t.Fatalf("complete %s: %v", id, err)
+		}
+	}
+	state := func(id string) string { return fsm.GetTask(id).State }
+
+	cyclic := diamond("cyc", "")
+	cyclic.Tasks[0].DependsOn = []string{"cyc-d"}
+	if err, _ := apply(CmdSubmitJob, cyclic).(error); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected a cycle to be refused, got %v", err)
+	}
+	if fsm.GetTask("cyc-x") != nil || fsm.GetJob("cyc") != nil {
+		t.Fatal("a refused job must not leave tasks behind")
+	}
+
+	if j, ok := apply(CmdSubmitJob, diamond("ok", "")).(*Job); !ok || j.FailurePolicy != JobFailFast ||
+		j.Stages[1].State != JobBlocked || len(j.Stages[2].TaskIDs) != 2 {
+		t.Fatalf("submit_job result = %#v", j)
+	}
+	if state("ok-a") != TaskPending || state("ok-b") != TaskBlocked {
+		t.Fatal("only tasks without dependencies should start pending")
+	}
+	if _, ok := apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: "extra", JobID: "ok", Type: TaskMap}}).(error); !ok {
+		t.Error("expected a plain task in a DAG job to be refused")
+	}
+	run("ok-a", true)
+	run("ok-b", true)
+	if state("ok-c") != TaskPending || state("ok-d") != TaskBlocked {
+		t.Fatal("d must wait for both b and c")
+	}
+	for _, id := range []string{"ok-c", "ok-d", "ok-x", "ok-y"} {
+		run(id, true)
+	}
+	if j := fsm.GetJob("ok"); j.State != JobSucceeded || j.Stages[2].Succeeded != 2 {
+		t.Fatalf("job after all tasks = %+v", j)
+	}
+
+	// Fail-fast: one failure cancels everything unfinished.
+	apply(CmdSubmitJob, diamond("ff", JobFailFast))
+	run("ff-a", false)
+	if j := fsm.GetJob("ff"); j.State != JobFailed || state("ff-x") != TaskCancelled || state("ff-d") != TaskCancelled {
+		t.Fatalf("fail-fast job = %+v", j)
+	}
+
+	// Continue: the failure cancels its dependents; x → y still runs.
+	apply(CmdSubmitJob, diamond("cont", JobContinue))
+	run("cont-a", true)
+	run("cont-b", false)
+	if state("cont-d") != TaskCancelled || state("cont-c") != TaskPending || state("cont-x") != TaskPending {
+		t.Fatalf("continue: c=%s d=%s x=%s", state("cont-c"), state("cont-d"), state("cont-x"))
+	}
+	if fsm.GetTask("cont-d").Error != "upstream task cont-b failed" {
+		t.Errorf("cancel reason = %q", fsm.GetTask("cont-d").Error)
+	}
+	run("cont-x", true)
+
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	sink := &testSnapshotSink{buf: &bytes.Buffer{}}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist: %v", err)
+	}
+	fsm = NewPipelineFSM()
+	if err := fsm.Restore(io.NopCloser(bytes.NewReader(sink.buf.Bytes()))); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if state("cont-y") != TaskPending || fsm.GetJob("cont").State != JobRunning {
+		t.Fatal("job progress lost across snapshot/restore")
+	}
+	run("cont-c", true)
+	run("cont-y", true)
+	if j := fsm.GetJob("cont"); j.State != JobFailed || j.Error != "task cont-b failed" ||
+		j.Stages[1].State != JobFailed || j.Stages[2].Succeeded != 1 || j.Stages[2].Cancelled != 1 {
+		t.Errorf("continue job at the end = %+v", j)
+	}
+	if n := len(fsm.Jobs()); n != 3 {
+		t.Errorf("got %d jobs, want 3", n)
+	}
+}
+
+func TestFSMJobRepeatedDependency(t *testing.T) {
+	fsm := NewPipelineFSM()
+	var index uint64
+	apply := func(typ CommandType, payload interface{}) interface{} {
+		index++
+		return fsm.Apply(&hashiraft.Log{Index: index, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+	now := time.Now().UTC()
+	job := SubmitJobPayload{
+		Job: Job{ID: "dup", CreatedAt: now, Stages: []*Stage{{Name: "one"}, {Name: "two"}}},
+		Tasks: []Task{
+			{ID: "a", Type: TaskGeneric, Stage: "one", CreatedAt: now},
+			{ID: "b", Type: TaskGeneric, Stage: "two", DependsOn: []string{"a", "a"}, CreatedAt: now},
+		},
+	}
+	if _, ok := apply(CmdSubmitJob, job).(*Job); !ok {
+		t.Fatal("submit_job with a repeated dependency failed")
+	}
+	if b := fsm.GetTask("b"); len(b.DependsOn) != 1 || b.WaitingOn != 1 {
+		t.Fatalf("repeated dependency not collapsed: %+v", b)
+	}
+	apply(CmdAssignTask, AssignTaskPayload{ID: "a", WorkerID: "w", AssignedAt: now, LeaseExpires: now.Add(time.Minute)})
+	apply(CmdCompleteTask, CompleteTaskPayload{ID: "a", WorkerID: "w", Attempt: 1, Succeeded: true, FinishedAt: now})
+	if b := fsm.GetTask("b"); b.State != TaskPending {
+		t.Fatalf("b is %s once its only dependency succeeded", b.State)
+	}
+}
+
+func TestFSMMapReduce(t *testing.T) {
+	fsm := NewPipelineFSM()
+	var index uint64
+	apply := func(typ CommandType, payload interface{}) interface{} {
+		index++
+		return fsm.Apply(&hashiraft.Log{Index: index, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+	now := time.Now().UTC()
+	for _, id := range []string{"w-1", "w-2"} {
+		apply(CmdRegisterWorker, RegisterWorkerPayload{ID: id})
+	}
+	run := func(id, worker string, outputs ...Intermediate) interface{} {
+		t.Helper()
+		task := fsm.GetTask(id)
+		apply(CmdAssignTask, AssignTaskPayload{ID: id, WorkerID: worker, AssignedAt: now, LeaseExpires: now.Add(time.Minute)})
+		if task.OutputURI != "" {
+			apply(CmdCommitTaskOutput, CommitTaskOutputPayload{ID: id, WorkerID: worker, Attempt: task.Attempt, CommittedAt: now})
+		}
+		return apply(CmdCompleteTask, CompleteTaskPayload{ID: id, WorkerID: worker, Attempt: task.Attempt,
+			Succeeded: true, FinishedAt: now, Outputs: outputs})
+	}
+	out := func(p int, uri string) Intermediate { return Intermediate{Partition: p, URI: uri, Bytes: 10} }
+
+	maps := []Task{
+		{ID: "mr-map-0", Type: TaskMap, Stage: StageMap, Partitions: 2, InputURIs: []string{"s3://in/0"}},
+		{ID: "mr-map-1", Type: TaskMap, Stage: StageMap, Partitions: 2, InputURIs: []string{"s3://in/1"}},
+	}
+	res := apply(CmdSubmitJob, SubmitJobPayload{
+		Job: Job{ID: "mr", Type: JobMapReduce, CreatedAt: now,
+			Stages: []*Stage{{Name: StageMap}, {Name: StageReduce, DependsOn: []string{StageMap}}},
+			MapReduce: &MapReduce{Partitions: 2, OutputPrefix: "s3://out/", Maps: []MapSplit{
+				{TaskID: "mr-map-0", InputURIs: []string{"s3://in/0"}}, {TaskID: "mr-map-1", InputURIs: []string{"s3://in/1"}},
+			}}},
+		Tasks: maps,
+	})
+	if j, ok := res.(*Job); !ok || j.Stages[1].Planned != 2 || j.Stages[1].State != JobBlocked {
+		t.Fatalf("submit mapreduce = %#v", res)
+	}
+	if _, ok := apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: ReduceTaskID("mr", 0), Type: TaskReduce}}).(error); !ok {
+		t.Error("a plain task must not take a reserved reduce ID")
+	}
+
+	if res, _ := run("mr-map-0", "w-1", out(0, "w1://m0/p0")).(error); res == nil {
+		t.Fatal("a map reporting too few partitions should be refused")
+	}
+	run("mr-map-0", "w-1", out(0, "w1://m0/p0"), out(1, "w1://m0/p1"))
+	if fsm.GetTask(ReduceTaskID("mr", 0)) != nil {
+		t.Fatal("no reduce may start before every map reported")
+	}
+	run("mr-map-1", "w-2", Intermediate{Partition: 1}, out(0, "w2://m1/p0"))
+	if m := fsm.GetJob("mr").MapReduce.Maps[1]; !m.Reported || len(m.Outputs) != 1 || m.Outputs[0].Partition != 0 {
+		t.Errorf("only the non-empty output should be stored: %+v", m)
+	}
+	r0, r1 := fsm.GetTask(ReduceTaskID("mr", 0)), fsm.GetTask(ReduceTaskID("mr", 1))
+	if r0 == nil || r1 == nil || len(r0.InputURIs) != 2 || len(r1.InputURIs) != 1 || r1.OutputURI != "s3://out/part-00001" {
+		t.Fatalf("reduces = %+v, %+v", r0, r1)
+	}
+	run(r0.ID, "w-2")
+	apply(CmdAssignTask, AssignTaskPayload{ID: r1.ID, WorkerID: "w-2", AssignedAt: now, LeaseExpires: now.Add(time.Minute)})
+
+	// w-1 dies: partition 0 is reduced already, but the running reduce of
+	// partition 1 reads map 0's output there.
+	apply(CmdUpdateWorkerStatus, UpdateWorkerStatusPayload{ID: "w-1", Status: WorkerOffline})
+	if m0 := fsm.GetTask("mr-map-0"); m0.State != TaskPending || m0.Attempt != 2 {
+		t.Fatalf("lost map should be re-run: %+v", m0)
+	}
+	if m1 := fsm.GetTask("mr-map-1"); m1.State != TaskSucceeded {
+		t.Errorf("map with intact outputs must not re-run: %+v", m1)
+	}
+	if r1 = fsm.GetTask(r1.ID); r1.State != TaskBlocked || r1.Attempt != 2 {
+		t.Fatalf("reduce reading the lost output should be blocked: %+v", r1)
+	}
+
+	run("mr-map-0", "w-2", out(0, "w2://m0/p0"), out(1, "w2://m0/p1"))
+	if r1 = fsm.GetTask(r1.ID); r1.State != TaskPending || r1.InputURIs[0] != "w2://m0/p1" {
+		t.Fatalf("reduce after the re-run = %+v", r1)
+	}
+	run(r1.ID, "w-2")
+	if j := fsm.GetJob("mr"); j.State != JobSucceeded || j.Stages[0].Succeeded != 2 || j.Stages[1].Succeeded != 2 {
+		t.Errorf("job at the end = %+v", j)
+	}
+}
+
+func TestFSMMapRerunAfterEvictionContinuesAttempts(t *testing.T) {
+	fsm := NewPipelineFSM()
+	var index uint64
+	apply := func(typ CommandType, payload interface{}) interface{} {
+		index++
+		return fsm.Apply(&hashiraft.Log{Index: index, Term: 1, Type: hashiraft.LogCommand,
+			Data: mustMarshalCmd(t, typ, payload)})
+	}
+	now := time.Now().UTC()
+	apply(CmdRegisterWorker, RegisterWorkerPayload{ID: "w-1"})
+	apply(CmdSubmitJob, SubmitJobPayload{
+		Job: Job{ID: "mr", Type: JobMapReduce, CreatedAt: now,
+			Stages: []*Stage{{Name: StageMap}, {Name: StageReduce, DependsOn: []string{StageMap}}},
+			MapReduce: &MapReduce{Partitions: 1, OutputPrefix: "s3://out/",
+				Maps: []MapSplit{{TaskID: "mr-map-0", InputURIs: []string{"s3://in/0"}}}}},
+		Tasks: []Task{{ID: "mr-map-0", Type: TaskMap, Stage: StageMap, Partitions: 1, InputURIs: []string{"s3://in/0"}}},
+	})
+	apply(CmdAssignTask, AssignTaskPayload{ID: "mr-map-0", WorkerID: "w-1", AssignedAt: now, LeaseExpires: now.Add(time.Minute)})
+	apply(CmdCompleteTask, CompleteTaskPayload{ID: "mr-map-0", WorkerID: "w-1", Attempt: 1, Succeeded: true,
+		FinishedAt: now, Outputs: []Intermediate{{Partition: 0, URI: "w1://m0/p0"}}})
+	if fsm.GetTask(ReduceTaskID("mr", 0)) == nil {
+		t.Fatal("reduce should start once the map reported")
+	}
+
+	// The succeeded map is the oldest finished task, so it is evicted first.
+	for i := range maxFinishedTasks {
+		id := fmt.Sprintf("g-%05d", i)
+		apply(CmdSubmitTask, SubmitTaskPayload{Task: Task{ID: id, Type: TaskGeneric}})
+		apply(CmdCancelTask, CancelTaskPayload{ID: id, CancelledAt: now})
+	}
+	if fsm.GetTask("mr-map-0") != nil {
+		t.Fatal("expected the succeeded map to be evicted")
+	}
+
+	// Losing w-1 loses the map's output; the re-run must not be attempt 1 again.
+	apply(CmdUpdateWorkerStatus, UpdateWorkerStatusPayload{ID: "w-1", Status: WorkerOffline})
+	if m := fsm.GetTask("mr-map-0"); m == nil || m.State != TaskPending || m.Attempt != 2 {
+		t.Fatalf("re-run of the evicted map = %+v, want pending attempt 2", m)
+	}
+	apply(CmdUpdateWorkerStatus, UpdateWorkerStatusPayload{ID: "w-1", Status: WorkerOnline})
+	apply(CmdAssignTask, AssignTaskPayload{ID: "mr-map-0", WorkerID: "w-1", AssignedAt: now, LeaseExpires: now.Add(time.Minute)})
+	if _, ok := apply(CmdCompleteTask, CompleteTaskPayload{ID: "mr-map-0", WorkerID: "w-1", Attempt: 1, Succeeded: true,
+		FinishedAt: now, Outputs: []Intermediate{{Partition: 0, URI: "w1://m0/p0"}}}).(error); !ok {
+		t.Error("a stale attempt-1 report must be refused after the re-run")
+	}
+	if m := fsm.GetTask("mr-map-0"); m.State != TaskRunning || m.Attempt != 2 {
+		t.Errorf("map after the stale report = %+v", m)
+	}
+}
+
+func TestFSMRestoreLegacyAndFutureSnapshots(t *testing.T) {
+	legacy := `{"w-1":{"id":"w-1","address":"a:1","cloud_tag":"gcp","status":"online"}}`
+	fsm := NewPipelineFSM()
+	if err := fsm.Restore(io.NopCloser(bytes.NewReader([]byte(legacy)))); err != nil {
+		t.Fatalf("Restore legacy: %v", err)
+	}
+	if w := fsm.GetWorker("w-1"); w == nil || w.CloudTag != "gcp" {
+		t.Errorf("legacy snapshot not restored: %+v", w)
+	}
+
+	v1 := `{"version":1,"workers":{"w-2":{"id":"w-2","address":"a:2","cloud_tag":"aws","status":"online"}},"last_epoch":4}`
+	if err := fsm.Restore(io.NopCloser(bytes.NewReader([]byte(v1)))); err != nil {
+		t.Fatalf("Restore v1: %v", err)
+	}
+	if w := fsm.GetWorker("w-2"); w == nil || w.CloudTag != "aws" {
+		t.Errorf("v1 snapshot not restored: %+v", w)
+	}
+	if tasks, jobs, dead := fsm.Tasks(), fsm.Jobs(), fsm.DeadLetters(); len(tasks) != 0 || len(jobs) != 0 || len(dead) != 0 {
+		t.Errorf("v1 snapshot should restore empty task state, got %d tasks, %d jobs, %d dead letters",
+			len(tasks), len(jobs), len(dead))
+	}
+	// The empty task state a v1 snapshot restores to must be usable.
+	res := fsm.Apply(&hashiraft.Log{Index: 1, Term: 1, Type: hashiraft.LogCommand, Data: mustMarshalCmd(t, CmdSubmitTask,
+		SubmitTaskPayload{Task: Task{ID: "t-1", Type: TaskMap}})})
+	if err, ok := res.(error); ok {
+		t.Errorf("submit after a v1 restore: %v", err)
+	}
+	if err := fsm.Restore(io.NopCloser(bytes.NewReader([]byte(legacy)))); err != nil {
+		t.Fatalf("Restore legacy: %v", err)
+	}
+
+	future := fmt.Sprintf(`{"version":%d,"workers":{}}`, snapshotVersion+1)
+	if err := fsm.Restore(io.NopCloser(bytes.NewReader([]byte(future)))); err == nil {
+		t.Error("expected Restore to refuse a snapshot from a newer version")
+	}
+	if fsm.GetWorker("w-1") == nil {
+		t.Error("failed Restore must leave existing state untouched")
+	}
+}
+
+func TestFSMRestoreDenseMapOutputs(t *testing.T) {
+	// Before version 5 a reported split held an output for every partition.
+	v4, err := json.Marshal(fsmState{Version: 4, Jobs: map[string]*Job{"mr": {
+		ID: "mr", Type: JobMapReduce, MapReduce: &MapReduce{Partitions: 2, Maps: []MapSplit{
+			{TaskID: "mr-map-0", Outputs: []Intermediate{{Partition: 0, URI: "w1://p0"}, {Partition: 1}}},
+			{TaskID: "mr-map-1"},
+		}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsm := NewPipelineFSM()
+	if err := fsm.Restore(io.NopCloser(bytes.NewReader(v4))); err != nil {
+		t.Fatalf("Restore v4: %v", err)
+	}
+	maps := fsm.GetJob("mr").MapReduce.Maps
+	if m := maps[0]; !m.Reported || len(m.Outputs) != 1 || m.Outputs[0].URI != "w1://p0" {
+		t.Errorf("reported split = %+v", m)
+	}
+	if m := maps[1]; m.Reported || m.Outputs != nil {
+		t.Errorf("unreported split = %+v", m)
+	}
+}
+
 func TestFSMSnapshotRestore(t *testing.T) {
 	fsm := NewPipelineFSM()
 
@@ -223,6 +1272,13 @@ func TestReplicationToFollowers(t *testing.T) {
 	}
 
 	nodes, fsms, _, _ := makeCluster(t, 3)
+	assertReplicates(t, nodes, fsms)
+}
+
+// assertReplicates applies an entry on the leader and checks every FSM sees it
+// within 500ms. Shared by the in-memory and gRPC transport tests.
+func assertReplicates(t *testing.T, nodes []*RaftNode, fsms []*PipelineFSM) {
+	t.Helper()
 	leaderIdx := waitForLeader(t, nodes, 15*time.Second)
 
 	cmd := mustMarshalCmd(t, CmdRegisterWorker, RegisterWorkerPayload{
@@ -0,0 +1,170 @@
+package raft
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/hashicorp/go-hclog"
+	hashiraft "github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+)
+
+// recoveryLogName is the append-only audit trail of manual recoveries, kept
+// in the data dir next to raft.db.
+const recoveryLogName = "recovery.log"
+
+// offlineLockTimeout is how long offline tools wait for raft.db's file lock.
+// A running node holds it, so timing out means the node is still up.
+const offlineLockTimeout = 2 * time.Second
+
+// ErrNodeRunning means an offline operation found raft.db locked by a live node.
+var ErrNodeRunning = errors.New("raft.db is locked — stop the node first")
+
+// RecoveryRecord is the audit entry written for every manual recovery.
+type RecoveryRecord struct {
+	At       time.Time          `json:"at"`
+	NodeID   string             `json:"node_id"`
+	Operator string             `json:"operator"`
+	Reason   string             `json:"reason"`
+	Previous []hashiraft.Server `json:"previous_servers"`
+	New      []hashiraft.Server `json:"new_servers"`
+	Index    uint64             `json:"index"` // last index recovered from the local log and snapshots
+	Term     uint64             `json:"term"`
+}
+
+// openOfflineStores opens a stopped node's log/stable store and snapshot
+// store, failing with ErrNodeRunning instead of blocking on a live node's lock.
+func openOfflineStores(dataDir string) (*raftboltdb.BoltStore, *hashiraft.FileSnapshotStore, error) {
+	if err := os.MkdirAll(dataDir, 0o750); err != nil {
+		return nil, nil, fmt.Errorf("create data dir: %w", err)
+	}
+	store, err := raftboltdb.New(raftboltdb.Options{
+		Path:        boltPath(dataDir),
+		BoltOptions: &bolt.Options{Timeout: offlineLockTimeout},
+	})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNodeRunning, dataDir)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("bolt store: %w", err)
+	}
+	snaps, err := hashiraft.NewFileSnapshotStore(snapshotDir(dataDir), 3, io.Discard)
+	if err != nil {
+		_ = store.Close()
+		return nil, nil, fmt.Errorf("snapshot store: %w", err)
+	}
+	return store, snaps, nil
+}
+
+// RecoverCluster rewrites a stopped node's Raft membership to peers, for use
+// when quorum is permanently lost (e.g. two of three nodes destroyed). The
+// node's FSM state — its local log replayed over its latest snapshot — is
+// kept; anything only the lost nodes had is gone. peers uses the RAFT_PEERS
+// syntax and must include cfg.NodeID.
+//
+// Run it on every surviving node with the same peer list, then start them.
+// Every recovery is appended to recovery.log in the data dir.
+func RecoverCluster(cfg Config, peers []string, operator, reason string) (RecoveryRecord, error) {
+	if len(peers) == 0 {
+		return RecoveryRecord{}, errors.New("recover: the new peer list is empty")
+	}
+	servers := peersToServers(peers, cfg.NodeID, hashiraft.ServerAddress(cfg.RaftAddr))
+	if !slices.ContainsFunc(servers, func(s hashiraft.Server) bool { return s.ID == hashiraft.ServerID(cfg.NodeID) }) {
+		return RecoveryRecord{}, fmt.Errorf("recover: new peer list does not include this node %q", cfg.NodeID)
+	}
+	seen := make(map[hashiraft.ServerID]bool)
+	for _, s := range servers {
+		if seen[s.ID] {
+			return RecoveryRecord{}, fmt.Errorf("recover: duplicate server ID %q in peer list", s.ID)
+		}
+		seen[s.ID] = true
+	}
+
+	// Don't let openOfflineStores create an empty raft.db in a mistyped dir.
+	if _, err := os.Stat(boltPath(cfg.DataDir)); err != nil {
+		return RecoveryRecord{}, fmt.Errorf("recover: no raft state in %s: %w", cfg.DataDir, err)
+	}
+	store, snaps, err := openOfflineStores(cfg.DataDir)
+	if err != nil {
+		return RecoveryRecord{}, err
+	}
+	defer store.Close()
+
+	logger := hclog.New(&hclog.LoggerOptions{Name: "raft-recover", Level: hclog.Warn})
+	_, trans := hashiraft.NewInmemTransport(hashiraft.ServerAddress(cfg.RaftAddr))
+
+	previous, err := hashiraft.GetConfiguration(newRaftConfig(cfg.NodeID, logger), NewPipelineFSM(), store, store, snaps, trans)
+	if err != nil {
+		return RecoveryRecord{}, fmt.Errorf("recover: read current configuration: %w", err)
+	}
+	configuration := hashiraft.Configuration{Servers: servers}
+	if err := hashiraft.RecoverCluster(newRaftConfig(cfg.NodeID, logger), NewPipelineFSM(), store, store, snaps, trans, configuration); err != nil {
+		return RecoveryRecord{}, fmt.Errorf("recover: %w", err)
+	}
+
+	rec := RecoveryRecord{
+		At:       time.Now().UTC(),
+		NodeID:   cfg.NodeID,
+		Operator: operator,
+		Reason:   reason,
+		Previous: previous.Servers,
+		New:      servers,
+	}
+	if list, err := snaps.List(); err == nil && len(list) > 0 {
+		rec.Index, rec.Term = list[0].Index, list[0].Term
+	}
+	if err := appendRecoveryRecord(cfg.DataDir, rec); err != nil {
+		return rec, fmt.Errorf("recover: membership rewritten but audit record failed: %w", err)
+	}
+	return rec, nil
+}
+
+func appendRecoveryRecord(dataDir string, rec RecoveryRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dataDir, recoveryLogName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// RecoveryHistory returns the audit records of past recoveries of dataDir,
+// oldest first; none is not an error.
+func RecoveryHistory(dataDir string) ([]RecoveryRecord, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, recoveryLogName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []RecoveryRecord
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var rec RecoveryRecord
+		if err := dec.Decode(&rec); err != nil {
+			return out, fmt.Errorf("parse %s: %w", recoveryLogName, err)
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
@@ -0,0 +1,105 @@
+package raft
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	hashiraft "github.com/hashicorp/raft"
+)
+
+func TestRecoverClusterAfterQuorumLoss(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping quorum-loss recovery test in short mode")
+	}
+	nodes, fsms, trans, _ := makeCluster(t, 3)
+	leader := waitForLeader(t, nodes, 10*time.Second)
+	for _, id := range []string{"w-1", "w-2"} {
+		cmd := mustMarshalCmd(t, CmdRegisterWorker, RegisterWorkerPayload{ID: id, CloudTag: "aws"})
+		if err := nodes[leader].Apply(cmd, 5*time.Second); err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+	}
+	survivor := (leader + 1) % 3
+	waitFor(t, 5*time.Second, func() bool { return len(fsms[survivor].Workers()) == 2 })
+
+	// Lose the other two nodes for good. The survivor can never win an election.
+	for i := range nodes {
+		if i != survivor {
+			_ = nodes[i].Shutdown()
+			for j := range trans {
+				trans[j].Disconnect(trans[i].LocalAddr())
+			}
+		}
+	}
+	time.Sleep(3 * time.Second)
+	if nodes[survivor].State() == hashiraft.Leader {
+		t.Fatal("survivor became leader without a quorum")
+	}
+
+	cfg := nodes[survivor].cfg
+	if _, err := RecoverCluster(cfg, []string{cfg.NodeID}, "test", "two nodes lost"); !errors.Is(err, ErrNodeRunning) {
+		t.Fatalf("recovering a running node: expected ErrNodeRunning, got %v", err)
+	}
+	if err := nodes[survivor].Shutdown(); err != nil {
+		t.Fatalf("shutdown survivor: %v", err)
+	}
+	if _, err := RecoverCluster(cfg, []string{"someone-else"}, "test", ""); err == nil ||
+		!strings.Contains(err.Error(), "does not include this node") {
+		t.Fatalf("expected a peer list without this node to be refused, got %v", err)
+	}
+
+	rec, err := RecoverCluster(cfg, []string{cfg.NodeID}, "test", "two nodes lost")
+	if err != nil {
+		t.Fatalf("RecoverCluster: %v", err)
+	}
+	if len(rec.Previous) != 3 || len(rec.New) != 1 || rec.Index == 0 {
+		t.Errorf("unexpected recovery record %+v", rec)
+	}
+	history, err := RecoveryHistory(cfg.DataDir)
+	if err != nil || len(history) != 1 || history[0].Reason != "two nodes lost" {
+		t.Fatalf("audit trail = %+v, %v", history, err)
+	}
+
+	// Restart on the recovered data dir: a one-node cluster that elects itself.
+	fsm := NewPipelineFSM()
+	_, tr := hashiraft.NewInmemTransport(trans[survivor].LocalAddr())
+	node, err := newRaftNodeWithTransport(cfg, fsm, tr, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("restart survivor: %v", err)
+	}
+	t.Cleanup(func() { _ = node.Shutdown() })
+	waitForLeader(t, []*RaftNode{node}, 10*time.Second)
+	if got := len(fsm.Workers()); got != 2 {
+		t.Fatalf("recovered FSM has %d workers, want 2", got)
+	}
+	cmd := mustMarshalCmd(t, CmdRegisterWorker, RegisterWorkerPayload{ID: "w-3", CloudTag: "aws"})
+	if err := node.Apply(cmd, 5*time.Second); err != nil {
+		t.Fatalf("write after recovery: %v", err)
+	}
+	f := node.raft.GetConfiguration()
+	if err := f.Error(); err != nil || len(f.Configuration().Servers) != 1 {
+		t.Errorf("configuration after recovery: %+v, %v", f.Configuration(), err)
+	}
+}
+
+func TestRecoverClusterRefusesEmptyDataDir(t *testing.T) {
+	cfg := Config{NodeID: "n1", RaftAddr: "n1", DataDir: t.TempDir()}
+	if _, err := RecoverCluster(cfg, []string{"n1"}, "test", ""); err == nil {
+		t.Fatal("expected recovery without existing state to be refused")
+	}
+}
+
+// waitFor polls cond until it holds or timeout elapses.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within %s", timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
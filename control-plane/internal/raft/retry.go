@@ -0,0 +1,260 @@
+package raft
+
+import (
+	"cmp"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+)
+
+// maxDeadLetters bounds the dead-letter list; the oldest entries are dropped
+// first.
+const maxDeadLetters = 1024
+
+// maxAttemptHistory bounds Task.Attempts; the oldest attempts are dropped
+// first. Task.Failures keeps counting.
+const maxAttemptHistory = 32
+
+// Error classes a worker reports with a failed attempt.
+const (
+	ErrorRetryable = "retryable"
+	ErrorFatal     = "fatal"
+)
+
+// Attempt outcomes stored in Attempt.Outcome.
+const (
+	AttemptSucceeded    = "succeeded"
+	AttemptFailed       = "failed"
+	AttemptLeaseExpired = "lease_expired"
+	AttemptWorkerLost   = "worker_lost"
+	AttemptInterrupted  = "interrupted"
+	AttemptCancelled    = "cancelled"
+	AttemptSuperseded   = "superseded" // another attempt of the task won
+	AttemptTimedOut     = "timed_out"  // ran past the task's deadline
+)
+
+// Dead-letter reasons stored in DeadLetter.Reason.
+const (
+	DeadLetterFatal     = "fatal error"
+	DeadLetterExhausted = "retries exhausted"
+)
+
+// RetryPolicy bounds how often a task is retried and how long it waits
+// before each retry.
+type RetryPolicy struct {
+	MaxAttempts    int           `json:"max_attempts"` // attempts in total; 1 disables retries
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"`
+	Multiplier     float64       `json:"multiplier"`
+	Jitter         float64       `json:"jitter"` // share of each delay that is randomised, 0–1
+}
+
+// DefaultRetryPolicy applies to tasks submitted without a policy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// Validate reports a policy the FSM would refuse.
+func (p RetryPolicy) Validate() error {
+	switch {
+	case p.MaxAttempts < 1:
+		return fmt.Errorf("retry policy: max attempts must be at least 1")
+	case p.InitialBackoff < 0 || p.MaxBackoff < p.InitialBackoff:
+		return fmt.Errorf("retry policy: need 0 <= initial backoff <= max backoff")
+	case p.Multiplier < 1:
+		return fmt.Errorf("retry policy: multiplier must be at least 1")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("retry policy: jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// Backoff returns the delay before retry number n (1 for the first retry) of
+// taskID. The jitter is drawn from a hash of taskID and n rather than a random
+// source, so every replica computes the same delay.
+func (p RetryPolicy) Backoff(taskID string, n int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(n-1))
+	d = min(d, float64(p.MaxBackoff))
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s/%d", taskID, n)
+	u := float64(h.Sum64()>>11) / (1 << 53) // uniform in [0, 1)
+	return time.Duration(d * (1 - p.Jitter*u))
+}
+
+// Attempt is one finished run of a task.
+type Attempt struct {
+	Attempt    int       `json:"attempt"`
+	Worker     string    `json:"worker,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at"`
+	Outcome    string    `json:"outcome"`
+	Error      string    `json:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"`
+}
+
+// DeadLetter records a task that failed for good.
+type DeadLetter struct {
+	TaskID   string    `json:"task_id"`
+	JobID    string    `json:"job_id,omitempty"`
+	Type     string    `json:"type"`
+	Reason   string    `json:"reason"`
+	Error    string    `json:"error,omitempty"`
+	Attempts []Attempt `json:"attempts,omitempty"`
+	At       time.Time `json:"at"`
+	Index    uint64    `json:"index"`
+}
+
+// retryPolicy returns the policy that governs t.
+func (t *Task) retryPolicy() RetryPolicy {
+	if t.Retry != nil {
+		return *t.Retry
+	}
+	return DefaultRetryPolicy
+}
+
+// recordAttempt adds t's current attempt, now over, to its history.
+func (t *Task) recordAttempt(outcome, errMsg, class string, at time.Time) {
+	t.appendAttempt(Attempt{
+		Attempt:    t.Attempt,
+		Worker:     t.AssignedWorker,
+		StartedAt:  t.StartedAt,
+		FinishedAt: at,
+		Outcome:    outcome,
+		Error:      errMsg,
+		ErrorClass: class,
+	})
+}
+
+func (t *Task) appendAttempt(a Attempt) {
+	t.Attempts = append(t.Attempts, a)
+	if n := len(t.Attempts) - maxAttemptHistory; n > 0 {
+		t.Attempts = slices.Delete(t.Attempts, 0, n)
+	}
+}
+
+// failAttemptLocked ends attempt, one of t's running attempts, with a
+// failure; an attempt that was being killed for its deadline counts as timed
+// out. While t has another attempt running, a retryable failure leaves that
+// one to carry on. Otherwise a retryable failure with attempts left puts t
+// back to pending until its backoff has passed, and anything else fails t for
+// good and dead-letters it. It reports whether t will run again.
+func (f *PipelineFSM) failAttemptLocked(t *Task, attempt int, outcome, errMsg, class string, at time.Time, index uint64) bool {
+	if class == "" {
+		class = ErrorRetryable
+	}
+	if t.killOrder(attempt) != nil {
+		outcome = AttemptTimedOut
+		t.dropKill(attempt)
+	}
+	if t.CommitAttempt == attempt {
+		t.CommitAttempt = 0 // the next attempt rewrites the output
+	}
+	t.Failures++
+	t.Error = errMsg
+	t.Index = index
+	worker := t.AssignedWorker
+	if s := t.Speculative; s != nil {
+		if s.Attempt == attempt {
+			worker = s.Worker
+			t.recordSpeculative(outcome, errMsg, class, at)
+			t.Speculative = nil
+		} else {
+			t.recordAttempt(outcome, errMsg, class, at)
+			t.promoteSpeculative()
+		}
+		if class != ErrorFatal {
+			slog.Warn("FSM: task attempt failed — other attempt carries on", "task_id", t.ID,
+				"worker_id", worker, "outcome", outcome, "error", errMsg, "failed_attempt", attempt,
+				"attempt", t.Attempt, "index", index)
+			return true
+		}
+		// A fatal error dooms the surviving attempt too.
+		why := fmt.Sprintf("attempt %d failed fatally", attempt)
+		t.recordAttempt(AttemptCancelled, why, "", at)
+		t.kill(t.Attempt, t.AssignedWorker, "cancelled: "+why, at)
+	} else {
+		t.recordAttempt(outcome, errMsg, class, at)
+	}
+	policy := t.retryPolicy()
+	t.AssignedWorker, t.PlacementReason = "", ""
+	t.StartedAt, t.LeaseExpires = time.Time{}, time.Time{}
+
+	if class != ErrorFatal && t.Failures < policy.MaxAttempts {
+		t.State = TaskPending
+		t.Attempt = t.nextAttempt()
+		t.PendingSince = at
+		t.NotBefore = at.Add(policy.Backoff(t.ID, t.Failures))
+		slog.Warn("FSM: task attempt failed — retrying", "task_id", t.ID, "worker_id", worker,
+			"outcome", outcome, "error", errMsg, "attempt", t.Attempt, "not_before", t.NotBefore, "index", index)
+		return true
+	}
+
+	reason := DeadLetterExhausted
+	if class == ErrorFatal {
+		reason = DeadLetterFatal
+	}
+	t.State = TaskFailed
+	t.FinishedAt = at
+	t.NotBefore = time.Time{}
+	f.deadLetters = append(f.deadLetters, DeadLetter{
+		TaskID:   t.ID,
+		JobID:    t.JobID,
+		Type:     t.Type,
+		Reason:   reason,
+		Error:    errMsg,
+		Attempts: slices.Clone(t.Attempts),
+		At:       at,
+		Index:    index,
+	})
+	if n := len(f.deadLetters) - maxDeadLetters; n > 0 {
+		f.deadLetters = slices.Delete(f.deadLetters, 0, n)
+	}
+	f.finishTaskLocked(t)
+	slog.Warn("FSM: task dead-lettered", "task_id", t.ID, "job_id", t.JobID, "worker_id", worker,
+		"reason", reason, "error", errMsg, "failures", t.Failures, "index", index)
+	f.jobTaskFinishedLocked(t)
+	return false
+}
+
+// requeueWorkerTasksLocked fails every running attempt on workerID, which is
+// gone, speculative ones included.
+func (f *PipelineFSM) requeueWorkerTasksLocked(workerID, reason string, at time.Time, index uint64) {
+	type lost struct {
+		t       *Task
+		attempt int
+	}
+	var running []lost
+	for _, t := range f.tasks {
+		switch {
+		case t.State != TaskRunning:
+		case t.AssignedWorker == workerID:
+			running = append(running, lost{t, t.Attempt})
+		case t.Speculative != nil && t.Speculative.Worker == workerID:
+			running = append(running, lost{t, t.Speculative.Attempt})
+		}
+	}
+	slices.SortFunc(running, func(a, b lost) int { return cmp.Compare(a.t.ID, b.t.ID) })
+	for _, r := range running {
+		f.failAttemptLocked(r.t, r.attempt, AttemptWorkerLost, fmt.Sprintf("worker %s %s", workerID, reason),
+			ErrorRetryable, at, index)
+	}
+}
+
+// DeadLetters returns copies of the dead-letter entries, oldest first.
+func (f *PipelineFSM) DeadLetters() []DeadLetter {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]DeadLetter, len(f.deadLetters))
+	for i, d := range f.deadLetters {
+		d.Attempts = slices.Clone(d.Attempts)
+		out[i] = d
+	}
+	return out
+}
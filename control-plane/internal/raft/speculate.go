@@ -0,0 +1,173 @@
+package raft
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SpeculativeAttempt is a second attempt of a running task on another worker,
+// launched because the first is straggling. Whichever attempt succeeds, or is
+// granted the output commit, first wins; the other is superseded.
+type SpeculativeAttempt struct {
+	Attempt      int       `json:"attempt"`
+	Worker       string    `json:"worker"`
+	StartedAt    time.Time `json:"started_at"`
+	LeaseExpires time.Time `json:"lease_expires"`
+	Reason       string    `json:"reason,omitempty"` // why the duplicate was launched
+}
+
+// SpeculateTaskPayload carries fields for a speculate_task command. Attempt
+// is the running attempt to duplicate; the FSM refuses the command if that
+// attempt has ended, already has a duplicate or was granted the commit.
+type SpeculateTaskPayload struct {
+	ID           string    `json:"id"`
+	Attempt      int       `json:"attempt"`
+	WorkerID     string    `json:"worker_id"`
+	AssignedAt   time.Time `json:"assigned_at"`
+	LeaseExpires time.Time `json:"lease_expires"`
+	Reason       string    `json:"reason,omitempty"`
+	EgressBytes  int64     `json:"egress_bytes,omitempty"`
+	EgressCost   float64   `json:"egress_cost,omitempty"`
+}
+
+// CommitTaskOutputPayload carries fields for a commit_task_output command: an
+// attempt asking to write the task's OutputURI.
+type CommitTaskOutputPayload struct {
+	ID          string    `json:"id"`
+	WorkerID    string    `json:"worker_id"`
+	Attempt     int       `json:"attempt"`
+	CommittedAt time.Time `json:"committed_at"`
+}
+
+// applySpeculateTask returns a copy of the task on success.
+func (f *PipelineFSM) applySpeculateTask(raw json.RawMessage, index uint64) interface{} {
+	var p SpeculateTaskPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal speculate_task: %w", err)
+	}
+	t, ok := f.tasks[p.ID]
+	if !ok {
+		return fmt.Errorf("task %q not found", p.ID)
+	}
+	switch {
+	case t.State != TaskRunning || t.Attempt != p.Attempt:
+		return fmt.Errorf("task %q is %s at attempt %d, not running attempt %d",
+			p.ID, t.State, t.Attempt, p.Attempt)
+	case t.Speculative != nil:
+		return fmt.Errorf("task %q already has speculative attempt %d", p.ID, t.Speculative.Attempt)
+	case t.CommitAttempt != 0:
+		return fmt.Errorf("task %q: attempt %d is committing its output", p.ID, t.CommitAttempt)
+	case p.WorkerID == "" || p.WorkerID == t.AssignedWorker:
+		return fmt.Errorf("task %q: a speculative attempt must run on a worker other than %q",
+			p.ID, t.AssignedWorker)
+	}
+	t.Speculative = &SpeculativeAttempt{
+		Attempt:      t.nextAttempt(),
+		Worker:       p.WorkerID,
+		StartedAt:    p.AssignedAt,
+		LeaseExpires: p.LeaseExpires,
+		Reason:       p.Reason,
+	}
+	t.EgressBytes += p.EgressBytes
+	t.EgressCost += p.EgressCost
+	t.Index = index
+	slog.Info("FSM: speculative attempt launched", "task_id", p.ID, "worker_id", p.WorkerID,
+		"attempt", t.Speculative.Attempt, "straggler", t.Attempt, "index", index)
+	return t.clone()
+}
+
+// applyCommitTaskOutput grants the output commit to the first attempt that
+// asks and supersedes the other. A repeated request from the granted attempt
+// succeeds again without changing anything. It returns a copy of the task.
+func (f *PipelineFSM) applyCommitTaskOutput(raw json.RawMessage, index uint64) interface{} {
+	var p CommitTaskOutputPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal commit_task_output: %w", err)
+	}
+	t, err := f.leaseHolderLocked(p.ID, p.WorkerID, p.Attempt)
+	if err != nil {
+		return err
+	}
+	switch t.CommitAttempt {
+	case p.Attempt:
+		return t.clone()
+	case 0:
+	default:
+		return fmt.Errorf("%w: task %q: attempt %d holds the output commit", ErrLeaseLost, p.ID, t.CommitAttempt)
+	}
+	f.settleSpeculationLocked(t, p.Attempt, p.CommittedAt)
+	t.CommitAttempt = p.Attempt
+	t.Index = index
+	slog.Info("FSM: task output commit granted", "task_id", p.ID, "worker_id", p.WorkerID,
+		"attempt", p.Attempt, "index", index)
+	return t.clone()
+}
+
+// settleSpeculationLocked ends the race between t's attempts in favour of
+// winner: the other attempt, if there is one, is recorded as superseded and
+// winner becomes t's only running attempt.
+func (f *PipelineFSM) settleSpeculationLocked(t *Task, winner int, at time.Time) {
+	s := t.Speculative
+	if s == nil {
+		return
+	}
+	why := fmt.Sprintf("attempt %d won", winner)
+	loser, lost := t.AssignedWorker, t.Attempt
+	if s.Attempt == winner {
+		t.recordAttempt(AttemptSuperseded, why, "", at)
+		t.promoteSpeculative()
+	} else {
+		loser, lost = s.Worker, s.Attempt
+		t.recordSpeculative(AttemptSuperseded, why, "", at)
+		t.Speculative = nil
+	}
+	t.kill(lost, loser, "superseded: "+why, at)
+	slog.Info("FSM: speculation settled", "task_id", t.ID, "winner", winner,
+		"speculative", s.Attempt, "superseded_worker", loser)
+}
+
+// runningAttempt reports whether attempt is one of t's running attempts.
+func (t *Task) runningAttempt(attempt int) bool {
+	if t.State != TaskRunning {
+		return false
+	}
+	return t.Attempt == attempt || (t.Speculative != nil && t.Speculative.Attempt == attempt)
+}
+
+// nextAttempt returns the number for t's next attempt: one past any attempt
+// it has run or is running, speculative ones included. t must not be pending.
+func (t *Task) nextAttempt() int {
+	n := t.Attempt
+	if t.Speculative != nil {
+		n = max(n, t.Speculative.Attempt)
+	}
+	for _, a := range t.Attempts {
+		n = max(n, a.Attempt)
+	}
+	return n + 1
+}
+
+// promoteSpeculative makes t's speculative attempt its only running one.
+func (t *Task) promoteSpeculative() {
+	s := t.Speculative
+	t.Attempt, t.AssignedWorker, t.PlacementReason = s.Attempt, s.Worker, s.Reason
+	t.StartedAt, t.LeaseExpires = s.StartedAt, s.LeaseExpires
+	t.Speculative = nil
+}
+
+// recordSpeculative adds t's speculative attempt, now over, to its history.
+// The caller clears or promotes it.
+func (t *Task) recordSpeculative(outcome, errMsg, class string, at time.Time) {
+	s := t.Speculative
+	t.appendAttempt(Attempt{
+		Attempt:    s.Attempt,
+		Worker:     s.Worker,
+		StartedAt:  s.StartedAt,
+		FinishedAt: at,
+		Outcome:    outcome,
+		Error:      errMsg,
+		ErrorClass: class,
+	})
+}
@@ -0,0 +1,38 @@
+package raft
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/clock"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+)
+
+// StatsSource is the part of RaftNode the stats poller reads.
+type StatsSource interface {
+	Stats() map[string]string
+	StateFloat() float64
+}
+
+// PollStats publishes the Raft state and term gauges every interval on clk,
+// counting an election for each term increase, until ctx is done.
+func PollStats(ctx context.Context, src StatsSource, clk clock.Clock, interval time.Duration) {
+	var lastTerm uint64
+	clk.Every(ctx, interval, func() {
+		metrics.RaftState.Set(src.StateFloat())
+		termStr, ok := src.Stats()["term"]
+		if !ok {
+			return
+		}
+		term, err := strconv.ParseUint(termStr, 10, 64)
+		if err != nil {
+			return
+		}
+		metrics.RaftTerm.Set(float64(term))
+		if term > lastTerm && lastTerm > 0 {
+			metrics.RaftElectionsTotal.Inc()
+		}
+		lastTerm = term
+	})
+}
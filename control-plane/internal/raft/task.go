@@ -0,0 +1,505 @@
+package raft
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// maxFinishedTasks bounds how many terminal tasks the FSM keeps. The oldest
+// finished tasks are dropped first; pending and running tasks, and finished
+// tasks with outstanding kill orders, are never dropped.
+const maxFinishedTasks = 4096
+
+// ErrLeaseLost means a worker call named a task attempt it no longer holds:
+// the task was cancelled, finished or handed to someone else.
+var ErrLeaseLost = errors.New("task lease lost")
+
+// ErrCommitRequired means an attempt of a task with an output URI reported
+// success without first being granted the output commit.
+var ErrCommitRequired = errors.New("output commit required")
+
+// Task types stored in Task.Type.
+const (
+	TaskMap     = "map"
+	TaskReduce  = "reduce"
+	TaskGeneric = "generic"
+)
+
+// Task states stored in Task.State.
+const (
+	TaskPending   = "pending"
+	TaskRunning   = "running"
+	TaskSucceeded = "succeeded"
+	TaskFailed    = "failed"
+	TaskCancelled = "cancelled"
+	TaskBlocked   = "blocked" // job task waiting for its dependencies
+)
+
+// Task is a unit of work tracked by the control plane.
+type Task struct {
+	ID             string    `json:"id"`
+	JobID          string    `json:"job_id,omitempty"`
+	Type           string    `json:"type"`
+	InputURIs      []string  `json:"input_uris,omitempty"`
+	OutputURI      string    `json:"output_uri,omitempty"`
+	Attempt        int       `json:"attempt"` // 1 for the first run
+	State          string    `json:"state"`
+	AssignedWorker string    `json:"assigned_worker,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	StartedAt      time.Time `json:"started_at,omitzero"`
+	FinishedAt     time.Time `json:"finished_at,omitzero"`
+	LeaseExpires   time.Time `json:"lease_expires,omitzero"` // running tasks only
+	Index          uint64    `json:"index"`                  // Raft log index of the last change
+
+	// Placement names the policy the scheduling loop uses to push the task to
+	// a worker; empty leaves it for the first worker that polls. Normally the
+	// same for every task in a job.
+	Placement       string    `json:"placement,omitempty"`
+	CloudAffinity   string    `json:"cloud_affinity,omitempty"` // preferred worker CloudTag
+	Resources       Resources `json:"resources,omitzero"`       // declared requirements
+	PlacementReason string    `json:"placement_reason,omitempty"`
+
+	InputBytes   []int64   `json:"input_bytes,omitempty"`  // declared size of each InputURIs entry
+	PendingSince time.Time `json:"pending_since,omitzero"` // when the task last became pending
+	EgressBytes  int64     `json:"egress_bytes,omitempty"` // estimated cross-cloud input bytes, summed over attempts
+	EgressCost   float64   `json:"egress_cost,omitempty"`  // estimated cost of EgressBytes
+
+	// Job tasks only (see Job): the stage the task belongs to, the IDs of the
+	// tasks that must succeed before it runs, and how many of them haven't yet.
+	Stage     string   `json:"stage,omitempty"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	WaitingOn int      `json:"waiting_on,omitempty"`
+
+	// MapReduce tasks only: how many partitions a map task writes, and which
+	// partition a reduce task reads.
+	Partitions int `json:"partitions,omitempty"`
+	Partition  int `json:"partition,omitempty"`
+
+	// Retry governs what happens when an attempt fails; nil means
+	// DefaultRetryPolicy. Attempts holds the finished attempts, Failures
+	// counts those that count against Retry.MaxAttempts, and a pending task
+	// is not handed out before NotBefore while it backs off.
+	Retry     *RetryPolicy `json:"retry,omitempty"`
+	Attempts  []Attempt    `json:"attempts,omitempty"`
+	Failures  int          `json:"failures,omitempty"`
+	NotBefore time.Time    `json:"not_before,omitzero"`
+
+	// Speculative is a duplicate of the running attempt on another worker,
+	// launched because it straggles. CommitAttempt is the attempt granted the
+	// output commit or, once the task succeeded, the attempt that did; 0 means
+	// none yet. A task with an OutputURI succeeds only through its commit
+	// attempt, so exactly one attempt ever writes the output.
+	Speculative   *SpeculativeAttempt `json:"speculative,omitempty"`
+	CommitAttempt int                 `json:"commit_attempt,omitempty"`
+
+	// Timeout is the execution deadline of each attempt; 0 means none. An
+	// attempt past it is killed: its worker is told to stop, and its lease is
+	// revoked once KillGrace (0 means DefaultKillGrace) has passed. Kills holds
+	// the kill orders of attempts, of this task, not yet over or revoked.
+	Timeout   time.Duration `json:"timeout,omitempty"`
+	KillGrace time.Duration `json:"kill_grace,omitempty"`
+	Kills     []KillOrder   `json:"kills,omitempty"`
+}
+
+// Resources is a CPU and memory amount: a task's declared requirements or a
+// worker's declared capacity. Zero means undeclared.
+type Resources struct {
+	CPUMillis int64 `json:"cpu_millis,omitempty"`
+	MemoryMB  int64 `json:"memory_mb,omitempty"`
+}
+
+// Add returns r plus o.
+func (r Resources) Add(o Resources) Resources {
+	return Resources{CPUMillis: r.CPUMillis + o.CPUMillis, MemoryMB: r.MemoryMB + o.MemoryMB}
+}
+
+// Sub returns r minus o.
+func (r Resources) Sub(o Resources) Resources {
+	return Resources{CPUMillis: r.CPUMillis - o.CPUMillis, MemoryMB: r.MemoryMB - o.MemoryMB}
+}
+
+// FitsIn reports whether r fits in what capacity has left after used, in each
+// dimension capacity declares. An undeclared dimension does not limit.
+func (r Resources) FitsIn(capacity, used Resources) bool {
+	free := capacity.Sub(used)
+	return (capacity.CPUMillis == 0 || r.CPUMillis <= free.CPUMillis) &&
+		(capacity.MemoryMB == 0 || r.MemoryMB <= free.MemoryMB)
+}
+
+func (r Resources) String() string {
+	return fmt.Sprintf("%dm CPU/%dMB", r.CPUMillis, r.MemoryMB)
+}
+
+// Finished reports whether the task reached a terminal state.
+func (t *Task) Finished() bool {
+	switch t.State {
+	case TaskSucceeded, TaskFailed, TaskCancelled:
+		return true
+	}
+	return false
+}
+
+// ValidTaskType reports whether typ is a known task type.
+func ValidTaskType(typ string) bool {
+	switch typ {
+	case TaskMap, TaskReduce, TaskGeneric:
+		return true
+	}
+	return false
+}
+
+// SubmitTaskPayload carries fields for a submit_task command. The leader fills
+// in the ID and CreatedAt; the FSM sets State and Attempt.
+type SubmitTaskPayload struct {
+	Task Task `json:"task"`
+}
+
+// CancelTaskPayload carries fields for a cancel_task command.
+type CancelTaskPayload struct {
+	ID          string    `json:"id"`
+	Reason      string    `json:"reason"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
+
+// AssignTaskPayload carries fields for an assign_task command. The FSM only
+// assigns a task that is still pending when the entry commits.
+type AssignTaskPayload struct {
+	ID           string    `json:"id"`
+	WorkerID     string    `json:"worker_id"`
+	AssignedAt   time.Time `json:"assigned_at"`
+	LeaseExpires time.Time `json:"lease_expires"`
+	Reason       string    `json:"reason,omitempty"` // placement decision, for debugging
+	EgressBytes  int64     `json:"egress_bytes,omitempty"`
+	EgressCost   float64   `json:"egress_cost,omitempty"`
+}
+
+// RenewTaskLeasePayload carries fields for a renew_task_lease command.
+type RenewTaskLeasePayload struct {
+	ID           string    `json:"id"`
+	WorkerID     string    `json:"worker_id"`
+	Attempt      int       `json:"attempt"`
+	LeaseExpires time.Time `json:"lease_expires"`
+}
+
+// CompleteTaskPayload carries fields for a complete_task command.
+type CompleteTaskPayload struct {
+	ID         string    `json:"id"`
+	WorkerID   string    `json:"worker_id"`
+	Attempt    int       `json:"attempt"`
+	Succeeded  bool      `json:"succeeded"`
+	Error      string    `json:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"` // failures only; empty means retryable
+	FinishedAt time.Time `json:"finished_at"`
+	// Outputs are a MapReduce map task's intermediate outputs, one per
+	// partition; required when such a task succeeds.
+	Outputs []Intermediate `json:"outputs,omitempty"`
+}
+
+// RequeueTaskPayload carries fields for a requeue_task command, written by
+// the leader when a lease runs out. Attempt fences it like a worker call, so
+// a task renewed or completed in the meantime is left alone. The lost attempt
+// counts against the task's retry policy.
+type RequeueTaskPayload struct {
+	ID         string    `json:"id"`
+	Attempt    int       `json:"attempt"`
+	Reason     string    `json:"reason"`
+	RequeuedAt time.Time `json:"requeued_at,omitzero"`
+}
+
+// applySubmitTask returns a copy of the stored task on success.
+func (f *PipelineFSM) applySubmitTask(raw json.RawMessage, index uint64) interface{} {
+	var p SubmitTaskPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal submit_task: %w", err)
+	}
+	t := p.Task
+	if t.ID == "" {
+		return fmt.Errorf("submit_task: empty task ID")
+	}
+	if !ValidTaskType(t.Type) {
+		return fmt.Errorf("submit_task: unknown task type %q", t.Type)
+	}
+	if _, ok := f.tasks[t.ID]; ok {
+		return fmt.Errorf("task %q already exists", t.ID)
+	}
+	if _, ok := f.jobs[t.JobID]; ok {
+		return fmt.Errorf("submit_task: job %q is a DAG job; its tasks are submitted with it", t.JobID)
+	}
+	if f.reservedLocked(t.ID) {
+		return fmt.Errorf("submit_task: task ID %q is reserved for a MapReduce job", t.ID)
+	}
+	if t.Retry != nil {
+		if err := t.Retry.Validate(); err != nil {
+			return fmt.Errorf("submit_task: %w", err)
+		}
+	}
+	if t.Timeout < 0 || t.KillGrace < 0 {
+		return fmt.Errorf("submit_task: timeout and kill grace must not be negative")
+	}
+	t.Stage, t.DependsOn, t.WaitingOn = "", nil, 0
+	t.State = TaskPending
+	t.Attempt = 1
+	t.AssignedWorker, t.PlacementReason = "", ""
+	t.Error = ""
+	t.StartedAt, t.FinishedAt, t.LeaseExpires = time.Time{}, time.Time{}, time.Time{}
+	t.PendingSince = t.CreatedAt
+	t.EgressBytes, t.EgressCost = 0, 0
+	t.Attempts, t.Failures, t.NotBefore = nil, 0, time.Time{}
+	t.Speculative, t.CommitAttempt = nil, 0
+	t.Kills = nil
+	t.Index = index
+	f.tasks[t.ID] = &t
+	slog.Info("FSM: task submitted", "task_id", t.ID, "job_id", t.JobID, "type", t.Type, "index", index)
+	return t.clone()
+}
+
+// applyCancelTask returns a copy of the cancelled task on success.
+func (f *PipelineFSM) applyCancelTask(raw json.RawMessage, index uint64) interface{} {
+	var p CancelTaskPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal cancel_task: %w", err)
+	}
+	t, ok := f.tasks[p.ID]
+	if !ok {
+		return fmt.Errorf("task %q not found", p.ID)
+	}
+	if t.Finished() {
+		return fmt.Errorf("task %q already %s", p.ID, t.State)
+	}
+	f.cancelTaskLocked(t, p.Reason, p.CancelledAt, index)
+	f.jobTaskFinishedLocked(t)
+	return t.clone()
+}
+
+// cancelTaskLocked moves an unfinished task to cancelled and orders the
+// workers of its running attempts to stop. It does not touch the task's job;
+// callers outside jobTaskFinishedLocked follow it with that.
+func (f *PipelineFSM) cancelTaskLocked(t *Task, reason string, at time.Time, index uint64) {
+	if t.State == TaskRunning {
+		t.recordAttempt(AttemptCancelled, reason, "", at)
+		t.kill(t.Attempt, t.AssignedWorker, "cancelled: "+reason, at)
+		if s := t.Speculative; s != nil {
+			t.recordSpeculative(AttemptCancelled, reason, "", at)
+			t.kill(s.Attempt, s.Worker, "cancelled: "+reason, at)
+			t.Speculative = nil
+		}
+	}
+	t.State = TaskCancelled
+	t.Error = reason
+	t.FinishedAt = at
+	t.LeaseExpires, t.NotBefore = time.Time{}, time.Time{}
+	t.Index = index
+	f.finishTaskLocked(t)
+	slog.Info("FSM: task cancelled", "task_id", t.ID, "reason", reason, "index", index)
+}
+
+// applyAssignTask returns a copy of the assigned task on success.
+func (f *PipelineFSM) applyAssignTask(raw json.RawMessage, index uint64) interface{} {
+	var p AssignTaskPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal assign_task: %w", err)
+	}
+	t, ok := f.tasks[p.ID]
+	if !ok {
+		return fmt.Errorf("task %q not found", p.ID)
+	}
+	if t.State != TaskPending {
+		return fmt.Errorf("task %q is %s, not pending", p.ID, t.State)
+	}
+	t.State = TaskRunning
+	t.AssignedWorker = p.WorkerID
+	t.PlacementReason = p.Reason
+	t.EgressBytes += p.EgressBytes
+	t.EgressCost += p.EgressCost
+	t.StartedAt = p.AssignedAt
+	t.LeaseExpires = p.LeaseExpires
+	t.Index = index
+	slog.Info("FSM: task assigned", "task_id", p.ID, "worker_id", p.WorkerID,
+		"attempt", t.Attempt, "index", index)
+	return t.clone()
+}
+
+// leaseHolderLocked returns the running task if workerID holds attempt's
+// lease, speculative or not.
+func (f *PipelineFSM) leaseHolderLocked(id, workerID string, attempt int) (*Task, error) {
+	t, ok := f.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: task %q not found", ErrLeaseLost, id)
+	}
+	if s := t.Speculative; t.State == TaskRunning && s != nil && s.Worker == workerID && s.Attempt == attempt {
+		return t, nil
+	}
+	if t.State != TaskRunning || t.AssignedWorker != workerID || t.Attempt != attempt {
+		return nil, fmt.Errorf("%w: task %q is %s on %q, attempt %d", ErrLeaseLost,
+			id, t.State, t.AssignedWorker, t.Attempt)
+	}
+	return t, nil
+}
+
+func (f *PipelineFSM) applyRenewTaskLease(raw json.RawMessage, index uint64) interface{} {
+	var p RenewTaskLeasePayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal renew_task_lease: %w", err)
+	}
+	t, err := f.leaseHolderLocked(p.ID, p.WorkerID, p.Attempt)
+	if err != nil {
+		return err
+	}
+	if s := t.Speculative; s != nil && s.Attempt == p.Attempt {
+		s.LeaseExpires = p.LeaseExpires
+	} else {
+		t.LeaseExpires = p.LeaseExpires
+	}
+	t.Index = index
+	return nil
+}
+
+func (f *PipelineFSM) applyCompleteTask(raw json.RawMessage, index uint64) interface{} {
+	var p CompleteTaskPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal complete_task: %w", err)
+	}
+	t, err := f.leaseHolderLocked(p.ID, p.WorkerID, p.Attempt)
+	if err != nil {
+		return err
+	}
+	if p.ErrorClass != "" && p.ErrorClass != ErrorRetryable && p.ErrorClass != ErrorFatal {
+		return fmt.Errorf("complete_task %q: unknown error class %q", p.ID, p.ErrorClass)
+	}
+	if p.Succeeded && t.OutputURI != "" && t.CommitAttempt != p.Attempt {
+		return fmt.Errorf("%w: task %q: attempt %d reported success without the output commit",
+			ErrCommitRequired, p.ID, p.Attempt)
+	}
+	j, split := f.mapSplitLocked(t)
+	if split != nil && p.Succeeded {
+		if err := CheckMapOutputs(j.MapReduce.Partitions, p.Outputs); err != nil {
+			return fmt.Errorf("complete_task %q: %w", p.ID, err)
+		}
+		f.recordMapOutputsLocked(split, t, p.WorkerID, p.Outputs)
+	}
+	if !p.Succeeded {
+		f.failAttemptLocked(t, p.Attempt, AttemptFailed, p.Error, p.ErrorClass, p.FinishedAt, index)
+		return t.clone()
+	}
+	f.settleSpeculationLocked(t, p.Attempt, p.FinishedAt)
+	t.dropKill(p.Attempt) // finished within its grace period
+	t.CommitAttempt = p.Attempt
+	t.recordAttempt(AttemptSucceeded, "", "", p.FinishedAt)
+	t.State = TaskSucceeded
+	t.Error = ""
+	t.FinishedAt = p.FinishedAt
+	t.LeaseExpires = time.Time{}
+	t.Index = index
+	f.finishTaskLocked(t)
+	slog.Info("FSM: task completed", "task_id", p.ID, "worker_id", p.WorkerID,
+		"state", t.State, "attempt", t.Attempt, "index", index)
+	f.jobTaskFinishedLocked(t)
+	return t.clone()
+}
+
+func (f *PipelineFSM) applyRequeueTask(raw json.RawMessage, index uint64) interface{} {
+	var p RequeueTaskPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("unmarshal requeue_task: %w", err)
+	}
+	t, ok := f.tasks[p.ID]
+	if !ok {
+		return fmt.Errorf("task %q not found", p.ID)
+	}
+	if !t.runningAttempt(p.Attempt) {
+		return fmt.Errorf("task %q is %s at attempt %d, not running attempt %d",
+			p.ID, t.State, t.Attempt, p.Attempt)
+	}
+	f.failAttemptLocked(t, p.Attempt, AttemptLeaseExpired, p.Reason, ErrorRetryable, p.RequeuedAt, index)
+	return t.clone()
+}
+
+// finishTaskLocked records that t reached a terminal state and drops the
+// oldest finished tasks beyond maxFinishedTasks. Tasks with kill orders still
+// outstanding are kept — their workers have yet to hear them, and the leader
+// revokes them through the task — and are dropped on a later call once the
+// orders are gone.
+func (f *PipelineFSM) finishTaskLocked(t *Task) {
+	f.finishedTasks = append(f.finishedTasks, t.ID)
+	excess := len(f.finishedTasks) - maxFinishedTasks
+	if excess <= 0 {
+		return
+	}
+	kept := f.finishedTasks[:0]
+	i := 0
+	for ; i < len(f.finishedTasks) && excess > 0; i++ {
+		id := f.finishedTasks[i]
+		if old := f.tasks[id]; old != nil && len(old.Kills) > 0 {
+			kept = append(kept, id)
+			continue
+		}
+		delete(f.tasks, id)
+		excess--
+	}
+	f.finishedTasks = append(kept, f.finishedTasks[i:]...)
+}
+
+// finishedTaskOrder rebuilds the finished-task eviction order after a restore.
+func finishedTaskOrder(tasks map[string]*Task) []string {
+	var done []*Task
+	for _, t := range tasks {
+		if t.Finished() {
+			done = append(done, t)
+		}
+	}
+	slices.SortFunc(done, func(a, b *Task) int {
+		return cmp.Or(a.FinishedAt.Compare(b.FinishedAt), cmp.Compare(a.ID, b.ID))
+	})
+	ids := make([]string, len(done))
+	for i, t := range done {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+// GetTask returns a copy of a task, or nil if not found.
+func (f *PipelineFSM) GetTask(id string) *Task {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	t, ok := f.tasks[id]
+	if !ok {
+		return nil
+	}
+	return t.clone()
+}
+
+// Tasks returns copies of all tasks, ordered by ID.
+func (f *PipelineFSM) Tasks() []*Task {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]*Task, 0, len(f.tasks))
+	for _, t := range f.tasks {
+		out = append(out, t.clone())
+	}
+	slices.SortFunc(out, func(a, b *Task) int { return cmp.Compare(a.ID, b.ID) })
+	return out
+}
+
+func (t *Task) clone() *Task {
+	cp := *t
+	cp.InputURIs = slices.Clone(t.InputURIs)
+	cp.InputBytes = slices.Clone(t.InputBytes)
+	cp.DependsOn = slices.Clone(t.DependsOn)
+	cp.Attempts = slices.Clone(t.Attempts)
+	if t.Retry != nil {
+		r := *t.Retry
+		cp.Retry = &r
+	}
+	if t.Speculative != nil {
+		s := *t.Speculative
+		cp.Speculative = &s
+	}
+	cp.Kills = slices.Clone(t.Kills)
+	return &cp
+}
@@ -0,0 +1,314 @@
+package raft
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	hashiraft "github.com/hashicorp/raft"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+)
+
+// TLSConfig enables mutual TLS on the Raft transport. TLS is off when CertFile
+// is empty.
+//
+// Every node presents CertFile/KeyFile both when accepting and when dialing, so
+// node certificates need the serverAuth and clientAuth extended key usages. A
+// peer is accepted only if its chain verifies against CAFile and one of its DNS
+// SANs equals a Raft server ID from the peer list: the dialer checks the SAN
+// against the ID it expects at that address, the acceptor against the set of
+// known IDs. Any cert the CA issues to something else — a worker, say — is
+// therefore refused, as is any certificate whose serial the FSM has revoked.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+
+	// ReloadInterval is how often the files are checked for changes. New
+	// material applies to new connections; pooled connections keep theirs.
+	ReloadInterval time.Duration
+}
+
+// Enabled reports whether the transport should use TLS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+const defaultTLSReloadInterval = 30 * time.Second
+
+// certRevoker reports revoked certificate serials; PipelineFSM implements it.
+type certRevoker interface {
+	CertRevoked(serial string) bool
+}
+
+// newTLSTransport wraps ln in a mutually authenticated TLS stream layer.
+// servers is the cluster membership used to bind addresses to server IDs;
+// revoked may be nil.
+func newTLSTransport(ln net.Listener, advertise net.Addr, cfg TLSConfig,
+	servers []hashiraft.Server, revoked certRevoker, logger hclog.Logger) (*hashiraft.NetworkTransport, error) {
+
+	certs, err := newCertReloader(cfg)
+	if err != nil {
+		return nil, err
+	}
+	stream := newTLSStreamLayer(ln, advertise, certs, servers, revoked)
+	return hashiraft.NewNetworkTransportWithLogger(stream, 3, 10*time.Second, logger), nil
+}
+
+// certReloader holds the node certificate and CA pool, re-reading them from
+// disk when their modification times change.
+type certReloader struct {
+	cfg TLSConfig
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTimes  [3]time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(cfg TLSConfig) (*certReloader, error) {
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = defaultTLSReloadInterval
+	}
+	c := &certReloader{cfg: cfg}
+	if err := c.load(time.Now()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// current returns the certificate and CA pool to use for a new handshake.
+func (c *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.lastCheck) >= c.cfg.ReloadInterval {
+		c.lastCheck = now
+		if c.changed() {
+			if err := c.load(now); err != nil {
+				// Keep serving the old material; a half-written file is the
+				// usual cause and the next check will pick up the final one.
+				metrics.RaftTLSReloadsTotal.WithLabelValues("error").Inc()
+				slog.Warn("raft TLS reload failed, keeping previous certificate", "error", err)
+			} else {
+				metrics.RaftTLSReloadsTotal.WithLabelValues("success").Inc()
+				slog.Info("raft TLS certificate reloaded", "cert_file", c.cfg.CertFile)
+			}
+		}
+	}
+	return c.cert, c.pool
+}
+
+// changed reports whether any of the files has a different modification time.
+// Caller must hold c.mu.
+func (c *certReloader) changed() bool {
+	for i, path := range c.files() {
+		fi, err := os.Stat(path)
+		if err != nil || !fi.ModTime().Equal(c.modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads all files and swaps them in atomically. Caller must hold c.mu
+// (or be the constructor).
+func (c *certReloader) load(now time.Time) error {
+	var modTimes [3]time.Time
+	for i, path := range c.files() {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", path, err)
+		}
+		modTimes[i] = fi.ModTime()
+	}
+	cert, err := tls.LoadX509KeyPair(c.cfg.CertFile, c.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load raft key pair: %w", err)
+	}
+	caPEM, err := os.ReadFile(c.cfg.CAFile)
+	if err != nil {
+		return fmt.Errorf("read raft CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no certificates found in %s", c.cfg.CAFile)
+	}
+	c.cert, c.pool, c.modTimes, c.lastCheck = &cert, pool, modTimes, now
+	return nil
+}
+
+func (c *certReloader) files() [3]string {
+	return [3]string{c.cfg.CertFile, c.cfg.KeyFile, c.cfg.CAFile}
+}
+
+// tlsStreamLayer implements hashiraft.StreamLayer over mutually authenticated TLS.
+type tlsStreamLayer struct {
+	net.Listener
+	advertise net.Addr
+	certs     *certReloader
+	serverCfg *tls.Config
+	revoked   certRevoker
+
+	ids   map[hashiraft.ServerAddress]hashiraft.ServerID
+	known map[string]bool
+}
+
+func newTLSStreamLayer(ln net.Listener, advertise net.Addr, certs *certReloader,
+	servers []hashiraft.Server, revoked certRevoker) *tlsStreamLayer {
+
+	s := &tlsStreamLayer{
+		Listener:  ln,
+		advertise: advertise,
+		certs:     certs,
+		revoked:   revoked,
+		ids:       make(map[hashiraft.ServerAddress]hashiraft.ServerID, len(servers)),
+		known:     make(map[string]bool, len(servers)),
+	}
+	for _, srv := range servers {
+		s.ids[srv.Address] = srv.ID
+		s.known[string(srv.ID)] = true
+	}
+	// Client certs are verified by hand in verifyInbound so the CA pool can be
+	// reloaded and every rejection is counted.
+	s.serverCfg = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := s.certs.current()
+			return cert, nil
+		},
+		ClientAuth:       tls.RequireAnyClientCert,
+		VerifyConnection: s.verifyInbound,
+	}
+	return s
+}
+
+// Accept wraps the next inbound connection. The handshake runs on first read,
+// in the transport's per-connection goroutine, so a slow peer can't stall Accept.
+func (s *tlsStreamLayer) Accept() (net.Conn, error) {
+	conn, err := s.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Server(conn, s.serverCfg), nil
+}
+
+// Addr returns the advertised address rather than the bind address.
+func (s *tlsStreamLayer) Addr() net.Addr {
+	if s.advertise != nil {
+		return s.advertise
+	}
+	return s.Listener.Addr()
+}
+
+// Dial connects to address and requires the peer's certificate to name the
+// server ID registered for that address.
+func (s *tlsStreamLayer) Dial(address hashiraft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	id := s.serverID(address)
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", string(address), s.clientConfig(id))
+	if err != nil {
+		metrics.RaftTLSHandshakeFailuresTotal.WithLabelValues("outbound").Inc()
+		return nil, fmt.Errorf("raft tls dial %s (server id %s): %w", address, id, err)
+	}
+	return conn, nil
+}
+
+// clientConfig presents the current node certificate and requires the server's
+// to name id.
+func (s *tlsStreamLayer) clientConfig(id hashiraft.ServerID) *tls.Config {
+	cert, pool := s.certs.current()
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*cert},
+		RootCAs:      pool,
+		ServerName:   string(id),
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return s.checkRevoked(cs.PeerCertificates[0])
+		},
+	}
+}
+
+// serverID returns the expected server ID for address, defaulting to its host
+// part — the convention peersToServers uses when no explicit ID is given.
+func (s *tlsStreamLayer) serverID(address hashiraft.ServerAddress) hashiraft.ServerID {
+	if id, ok := s.ids[address]; ok {
+		return id
+	}
+	if host, _, err := net.SplitHostPort(string(address)); err == nil {
+		return hashiraft.ServerID(host)
+	}
+	return hashiraft.ServerID(address)
+}
+
+// verifyInbound checks a dialing peer's chain against the current CA pool and
+// requires a node certificate — one that may also serve, which worker
+// certificates from the same CA may not — with a SAN naming a known server ID.
+func (s *tlsStreamLayer) verifyInbound(cs tls.ConnectionState) error {
+	err := s.verifyPeer(cs)
+	if err != nil {
+		metrics.RaftTLSHandshakeFailuresTotal.WithLabelValues("inbound").Inc()
+		slog.Warn("raft TLS: rejected inbound peer", "error", err)
+	}
+	return err
+}
+
+func (s *tlsStreamLayer) verifyPeer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("peer presented no certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+	_, pool := s.certs.current()
+	intermediates := x509.NewCertPool()
+	for _, c := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return fmt.Errorf("verify peer certificate: %w", err)
+	}
+	if !slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageServerAuth) {
+		return fmt.Errorf("peer certificate %v is not a node certificate: no server auth usage", leaf.DNSNames)
+	}
+	if err := s.checkRevoked(leaf); err != nil {
+		return err
+	}
+	// No membership means no ID a certificate could be bound to; refuse
+	// rather than accept any node certificate the CA has issued.
+	if len(s.known) == 0 {
+		return errors.New("no raft server IDs known to check the peer certificate against")
+	}
+	if _, ok := s.knownID(leaf); !ok {
+		return fmt.Errorf("peer certificate SANs %v name no known raft server", leaf.DNSNames)
+	}
+	return nil
+}
+
+// knownID returns the first DNS SAN of leaf that is a known server ID.
+func (s *tlsStreamLayer) knownID(leaf *x509.Certificate) (string, bool) {
+	for _, name := range leaf.DNSNames {
+		if s.known[name] {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (s *tlsStreamLayer) checkRevoked(leaf *x509.Certificate) error {
+	if s.revoked != nil && s.revoked.CertRevoked(leaf.SerialNumber.Text(16)) {
+		return fmt.Errorf("peer certificate %s has been revoked", leaf.SerialNumber.Text(16))
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package raft
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	hashiraft "github.com/hashicorp/raft"
+)
+
+// testCA is a throwaway certificate authority for TLS transport tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA cert: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key,
+		pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// writeNodeCert issues a node certificate for dnsName and writes cert, key and
+// CA bundle into dir, returning the matching TLSConfig.
+func (ca *testCA) writeNodeCert(t *testing.T, dir, dnsName string, serial int64) TLSConfig {
+	t.Helper()
+	return ca.writeCert(t, dir, dnsName, serial, x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth)
+}
+
+// writeCert is writeNodeCert with explicit extended key usages.
+func (ca *testCA) writeCert(t *testing.T, dir, dnsName string, serial int64, usages ...x509.ExtKeyUsage) TLSConfig {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate node key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usages,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create node cert: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal node key: %v", err)
+	}
+	cfg := TLSConfig{
+		CertFile: filepath.Join(dir, "node.crt"),
+		KeyFile:  filepath.Join(dir, "node.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	}
+	writeFile(t, cfg.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, cfg.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	writeFile(t, cfg.CAFile, ca.pem)
+	return cfg
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// makeTLSCluster starts one node per TLSConfig on loopback listeners. Node i
+// has server ID "node-<i+1>"; certificate SANs are up to the caller.
+func makeTLSCluster(t *testing.T, tlsCfgs []TLSConfig) []*RaftNode {
+	t.Helper()
+	n := len(tlsCfgs)
+	listeners := make([]net.Listener, n)
+	peers := make([]string, n)
+	for i := range listeners {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		listeners[i] = ln
+		peers[i] = fmt.Sprintf("node-%d=%s", i+1, ln.Addr())
+	}
+	servers := peersToServers(peers, "", "")
+
+	logger := hclog.NewNullLogger()
+	nodes := make([]*RaftNode, n)
+	for i, ln := range listeners {
+		trans, err := newTLSTransport(ln, ln.Addr(), tlsCfgs[i], servers, nil, logger)
+		if err != nil {
+			t.Fatalf("tls transport %d: %v", i+1, err)
+		}
+		nodes[i], err = newRaftNodeWithTransport(Config{
+			NodeID:    string(servers[i].ID),
+			DataDir:   t.TempDir(),
+			Bootstrap: true,
+			Peers:     peers,
+		}, NewPipelineFSM(), trans, logger)
+		if err != nil {
+			t.Fatalf("create node %d: %v", i+1, err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, node := range nodes {
+			_ = node.Shutdown()
+		}
+	})
+	return nodes
+}
+
+func assertNoLeader(t *testing.T, nodes []*RaftNode, wait time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		for i, n := range nodes {
+			if n.State() == hashiraft.Leader {
+				t.Fatalf("node-%d became leader despite an untrusted peer", i+1)
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestPeersToServersExplicitIDs(t *testing.T) {
+	servers := peersToServers([]string{"cp-aws-1:7000", "cp-gcp-1=34.1.2.3:7000"}, "", "")
+	if servers[0].ID != "cp-aws-1" || servers[0].Address != "cp-aws-1:7000" {
+		t.Errorf("host:port entry: got %+v", servers[0])
+	}
+	if servers[1].ID != "cp-gcp-1" || servers[1].Address != "34.1.2.3:7000" {
+		t.Errorf("id=host:port entry: got %+v", servers[1])
+	}
+}
+
+func TestTLSTransportFormsCluster(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TLS cluster test in short mode")
+	}
+	ca := newTestCA(t, "raft-ca")
+	cfgs := make([]TLSConfig, 3)
+	for i := range cfgs {
+		cfgs[i] = ca.writeNodeCert(t, t.TempDir(), fmt.Sprintf("node-%d", i+1), int64(i+2))
+	}
+	nodes := makeTLSCluster(t, cfgs)
+	leaderIdx := waitForLeader(t, nodes, 15*time.Second)
+
+	cmd := mustMarshalCmd(t, CmdRegisterWorker, RegisterWorkerPayload{ID: "tls-worker"})
+	if err := nodes[leaderIdx].Apply(cmd, 3*time.Second); err != nil {
+		t.Fatalf("Apply over TLS transport: %v", err)
+	}
+}
+
+func TestTLSTransportRejectsUntrustedPeer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TLS cluster test in short mode")
+	}
+	trusted := newTestCA(t, "raft-ca")
+	rogue := newTestCA(t, "rogue-ca")
+
+	cfgs := []TLSConfig{
+		trusted.writeNodeCert(t, t.TempDir(), "node-1", 2),
+		rogue.writeNodeCert(t, t.TempDir(), "node-2", 3),
+	}
+	// node-2 must still trust the real CA so only its own cert is the problem.
+	writeFile(t, cfgs[1].CAFile, trusted.pem)
+
+	// Two voters need both for quorum, so no leader can ever be elected.
+	assertNoLeader(t, makeTLSCluster(t, cfgs), 5*time.Second)
+}
+
+func TestTLSTransportRejectsWrongServerID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TLS cluster test in short mode")
+	}
+	ca := newTestCA(t, "raft-ca")
+	cfgs := []TLSConfig{
+		ca.writeNodeCert(t, t.TempDir(), "node-1", 2),
+		// Trusted chain, but issued to a name that is not a cluster member —
+		// e.g. a worker certificate from the same CA.
+		ca.writeNodeCert(t, t.TempDir(), "worker-aws-1", 3),
+	}
+	assertNoLeader(t, makeTLSCluster(t, cfgs), 5*time.Second)
+}
+
+func TestTLSTransportRejectsWorkerCertForNodeID(t *testing.T) {
+	ca := newTestCA(t, "raft-ca")
+	certs, err := newCertReloader(ca.writeNodeCert(t, t.TempDir(), "node-1", 2))
+	if err != nil {
+		t.Fatalf("load certs: %v", err)
+	}
+	s := newTLSStreamLayer(nil, nil, certs, []hashiraft.Server{{ID: "node-1"}, {ID: "node-2"}}, nil)
+	peer := func(dnsName string, serial int64, usages ...x509.ExtKeyUsage) tls.ConnectionState {
+		t.Helper()
+		data, err := os.ReadFile(ca.writeCert(t, t.TempDir(), dnsName, serial, usages...).CertFile)
+		if err != nil {
+			t.Fatalf("read cert: %v", err)
+		}
+		block, _ := pem.Decode(data)
+		leaf, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("parse cert: %v", err)
+		}
+		return tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+	}
+
+	if err := s.verifyPeer(peer("node-2", 3, x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth)); err != nil {
+		t.Errorf("node certificate refused: %v", err)
+	}
+	// A worker registered as "node-2" gets a client-only certificate.
+	if err := s.verifyPeer(peer("node-2", 4, x509.ExtKeyUsageClientAuth)); err == nil {
+		t.Error("expected a worker certificate naming a node ID to be refused")
+	}
+
+	// Without a membership to bind SANs to, even a node certificate is refused.
+	empty := newTLSStreamLayer(nil, nil, certs, nil, nil)
+	if err := empty.verifyPeer(peer("node-2", 5, x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth)); err == nil {
+		t.Error("expected a layer with no known server IDs to fail closed")
+	}
+}
+
+func TestCertReloaderPicksUpNewCert(t *testing.T) {
+	ca := newTestCA(t, "raft-ca")
+	dir := t.TempDir()
+	cfg := ca.writeNodeCert(t, dir, "node-1", 10)
+	cfg.ReloadInterval = time.Millisecond
+
+	r, err := newCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+	serial := func() int64 {
+		cert, _ := r.current()
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatalf("parse leaf: %v", err)
+		}
+		return leaf.SerialNumber.Int64()
+	}
+	if got := serial(); got != 10 {
+		t.Fatalf("expected serial 10, got %d", got)
+	}
+
+	ca.writeNodeCert(t, dir, "node-1", 11)
+	future := time.Now().Add(time.Minute)
+	for _, p := range []string{cfg.CertFile, cfg.KeyFile, cfg.CAFile} {
+		if err := os.Chtimes(p, future, future); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if got := serial(); got != 11 {
+		t.Errorf("expected reloaded serial 11, got %d", got)
+	}
+
+	// A broken key file keeps the previous certificate in service.
+	writeFile(t, cfg.KeyFile, []byte("not a key"))
+	if err := os.Chtimes(cfg.KeyFile, future.Add(time.Minute), future.Add(time.Minute)); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if got := serial(); got != 11 {
+		t.Errorf("expected serial 11 after failed reload, got %d", got)
+	}
+}
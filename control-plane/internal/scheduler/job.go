@@ -0,0 +1,267 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	hashiraft "github.com/hashicorp/raft"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// SubmitJob creates a job and all its tasks in one Raft entry. Stage
+// dependencies are expanded into task dependencies here; the FSM only sees
+// the task graph.
+func (s *Service) SubmitJob(ctx context.Context, req *taskpb.SubmitJobRequest) (*taskpb.SubmitJobResponse, error) {
+	if s.raft.State() != hashiraft.Leader {
+		return &taskpb.SubmitJobResponse{Ok: false, LeaderAddr: s.leaderAddr()}, nil
+	}
+	job, tasks, err := s.buildJob(req)
+	if err != nil {
+		return &taskpb.SubmitJobResponse{Ok: false, Error: err.Error()}, nil
+	}
+
+	resp, err := s.apply(internalraft.CmdSubmitJob, internalraft.SubmitJobPayload{Job: job, Tasks: tasks})
+	if err != nil {
+		return nil, err
+	}
+	j, _ := resp.(*internalraft.Job)
+	s.notify()
+	for _, t := range tasks {
+		metrics.TasksSubmittedTotal.WithLabelValues(t.Type).Inc()
+	}
+	metrics.JobsSubmittedTotal.Inc()
+	slog.Info("job submitted", "job_id", job.ID, "stages", len(job.Stages), "tasks", len(tasks),
+		"failure_policy", job.FailurePolicy)
+	return &taskpb.SubmitJobResponse{Ok: true, Job: s.jobToProto(j)}, nil
+}
+
+// buildJob validates req and turns it into the submit_job payload.
+func (s *Service) buildJob(req *taskpb.SubmitJobRequest) (internalraft.Job, []internalraft.Task, error) {
+	policy, ok := jobPolicies[req.FailurePolicy]
+	if !ok {
+		return internalraft.Job{}, nil, fmt.Errorf("unknown failure policy %v", req.FailurePolicy)
+	}
+	job := internalraft.Job{ID: req.JobId, FailurePolicy: policy, CreatedAt: s.clock.Now().UTC()}
+	if job.ID == "" {
+		job.ID = newJobID()
+	} else if s.tasks.GetJob(job.ID) != nil {
+		return job, nil, fmt.Errorf("job %q already exists", job.ID)
+	}
+	if len(req.Stages) == 0 {
+		return job, nil, fmt.Errorf("a job needs at least one stage")
+	}
+
+	// ids[i][k] is the ID of task k of stage i, generated where empty.
+	ids := make([][]string, len(req.Stages))
+	stageDeps := make(map[string][]string, len(req.Stages))
+	stageTasks := make(map[string][]string, len(req.Stages))
+	for i, st := range req.Stages {
+		if st.Name == "" {
+			return job, nil, fmt.Errorf("stage names must not be empty")
+		}
+		if _, dup := stageDeps[st.Name]; dup {
+			return job, nil, fmt.Errorf("duplicate stage %q", st.Name)
+		}
+		if len(st.Tasks) == 0 {
+			return job, nil, fmt.Errorf("stage %q has no tasks", st.Name)
+		}
+		stageDeps[st.Name] = st.DependsOn
+		job.Stages = append(job.Stages, &internalraft.Stage{Name: st.Name, DependsOn: st.DependsOn})
+		for _, spec := range st.Tasks {
+			id := spec.TaskId
+			if id == "" {
+				id = newTaskID()
+			}
+			ids[i] = append(ids[i], id)
+		}
+		stageTasks[st.Name] = ids[i]
+	}
+	if err := internalraft.CheckDAG("stage", stageDeps); err != nil {
+		return job, nil, err
+	}
+
+	var tasks []internalraft.Task
+	taskDeps := make(map[string][]string)
+	for i, st := range req.Stages {
+		var upstream []string
+		for _, d := range st.DependsOn {
+			upstream = append(upstream, stageTasks[d]...)
+		}
+		for k, spec := range st.Tasks {
+			t, err := s.taskFromSpec(spec)
+			if err != nil {
+				return job, nil, fmt.Errorf("stage %q task %d: %w", st.Name, k, err)
+			}
+			t.ID = ids[i][k]
+			if _, dup := taskDeps[t.ID]; dup || s.tasks.GetTask(t.ID) != nil {
+				return job, nil, fmt.Errorf("task %q already exists", t.ID)
+			}
+			t.JobID = job.ID
+			t.Stage = st.Name
+			t.DependsOn = slices.Compact(slices.Sorted(slices.Values(append(slices.Clone(upstream), spec.DependsOn...))))
+			taskDeps[t.ID] = t.DependsOn
+			tasks = append(tasks, t)
+		}
+	}
+	if err := internalraft.CheckDAG("task", taskDeps); err != nil {
+		return job, nil, err
+	}
+	return job, tasks, nil
+}
+
+// GetJob returns a job and the state of its stages from the local FSM.
+func (s *Service) GetJob(ctx context.Context, req *taskpb.GetJobRequest) (*taskpb.GetJobResponse, error) {
+	j := s.tasks.GetJob(req.JobId)
+	if j == nil {
+		return &taskpb.GetJobResponse{Ok: false, Error: fmt.Sprintf("job %q not found", req.JobId)}, nil
+	}
+	resp := &taskpb.GetJobResponse{Ok: true, Job: s.jobToProto(j)}
+	if req.IncludeTasks {
+		for _, t := range s.tasks.Tasks() {
+			if t.JobID == j.ID {
+				resp.Tasks = append(resp.Tasks, taskToProto(t))
+			}
+		}
+	}
+	return resp, nil
+}
+
+// ListJobs returns the jobs in the local FSM, optionally filtered by state.
+func (s *Service) ListJobs(ctx context.Context, req *taskpb.ListJobsRequest) (*taskpb.ListJobsResponse, error) {
+	resp := &taskpb.ListJobsResponse{}
+	if req.State != taskpb.JobState_JOB_STATE_UNSPECIFIED {
+		if _, ok := jobStates[req.State]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown job state %v", req.State)
+		}
+	}
+	for _, j := range s.tasks.Jobs() {
+		p := s.jobToProto(j)
+		if req.State == taskpb.JobState_JOB_STATE_UNSPECIFIED || p.State == req.State {
+			resp.Jobs = append(resp.Jobs, p)
+		}
+	}
+	return resp, nil
+}
+
+// CancelJob cancels a job that has not finished yet: every unfinished task,
+// with the workers of running ones told to stop, and the tasks it has yet to
+// create.
+func (s *Service) CancelJob(ctx context.Context, req *taskpb.CancelJobRequest) (*taskpb.CancelJobResponse, error) {
+	if s.raft.State() != hashiraft.Leader {
+		return &taskpb.CancelJobResponse{Ok: false, LeaderAddr: s.leaderAddr()}, nil
+	}
+	switch j := s.tasks.GetJob(req.JobId); {
+	case j == nil:
+		return &taskpb.CancelJobResponse{Ok: false, Error: fmt.Sprintf("job %q not found", req.JobId)}, nil
+	case j.Finished():
+		return &taskpb.CancelJobResponse{Ok: false, Error: fmt.Sprintf("job %q already %s", req.JobId, j.State),
+			Job: s.jobToProto(j)}, nil
+	}
+
+	resp, err := s.apply(internalraft.CmdCancelJob, internalraft.CancelJobPayload{
+		ID:          req.JobId,
+		Reason:      req.Reason,
+		CancelledAt: s.clock.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	j, _ := resp.(*internalraft.Job)
+	metrics.JobsCancelledTotal.Inc()
+	slog.Info("job cancelled", "job_id", req.JobId, "reason", req.Reason)
+	return &taskpb.CancelJobResponse{Ok: true, Job: s.jobToProto(j)}, nil
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "job-" + hex.EncodeToString(b)
+}
+
+// ── proto conversion ───────────────────────────────────────────────────────
+
+var jobPolicies = map[taskpb.JobFailurePolicy]string{
+	taskpb.JobFailurePolicy_JOB_FAILURE_POLICY_UNSPECIFIED: internalraft.JobFailFast,
+	taskpb.JobFailurePolicy_JOB_FAILURE_POLICY_FAIL_FAST:   internalraft.JobFailFast,
+	taskpb.JobFailurePolicy_JOB_FAILURE_POLICY_CONTINUE:    internalraft.JobContinue,
+}
+
+var jobStates = map[taskpb.JobState]string{
+	taskpb.JobState_JOB_STATE_BLOCKED:   internalraft.JobBlocked,
+	taskpb.JobState_JOB_STATE_RUNNING:   internalraft.JobRunning,
+	taskpb.JobState_JOB_STATE_SUCCEEDED: internalraft.JobSucceeded,
+	taskpb.JobState_JOB_STATE_FAILED:    internalraft.JobFailed,
+	taskpb.JobState_JOB_STATE_CANCELLED: internalraft.JobCancelled,
+}
+
+func jobStateToProto(st string) taskpb.JobState {
+	for k, v := range jobStates {
+		if v == st {
+			return k
+		}
+	}
+	return taskpb.JobState_JOB_STATE_UNSPECIFIED
+}
+
+// jobToProto converts j, counting the live states of its unfinished tasks.
+func (s *Service) jobToProto(j *internalraft.Job) *taskpb.Job {
+	if j == nil {
+		return nil
+	}
+	out := &taskpb.Job{
+		JobId:        j.ID,
+		State:        jobStateToProto(j.State),
+		Error:        j.Error,
+		CreatedAtMs:  unixMilli(j.CreatedAt),
+		FinishedAtMs: unixMilli(j.FinishedAt),
+		Type:         taskpb.JobType_JOB_TYPE_DAG,
+	}
+	if j.FailurePolicy == internalraft.JobContinue {
+		out.FailurePolicy = taskpb.JobFailurePolicy_JOB_FAILURE_POLICY_CONTINUE
+	} else {
+		out.FailurePolicy = taskpb.JobFailurePolicy_JOB_FAILURE_POLICY_FAIL_FAST
+	}
+	for _, st := range j.Stages {
+		ps := &taskpb.Stage{
+			Name:      st.Name,
+			DependsOn: st.DependsOn,
+			State:     jobStateToProto(st.State),
+			Tasks:     uint32(len(st.TaskIDs)),
+			Succeeded: uint32(st.Succeeded),
+			Failed:    uint32(st.Failed),
+			Cancelled: uint32(st.Cancelled),
+			TaskIds:   st.TaskIDs,
+		}
+		if !j.Finished() {
+			for _, id := range st.TaskIDs {
+				t := s.tasks.GetTask(id)
+				if t == nil {
+					continue
+				}
+				switch t.State {
+				case internalraft.TaskBlocked:
+					ps.Blocked++
+				case internalraft.TaskPending:
+					ps.Pending++
+				case internalraft.TaskRunning:
+					ps.Running++
+				}
+			}
+		}
+		out.Stages = append(out.Stages, ps)
+	}
+	if j.MapReduce != nil {
+		out.Type = taskpb.JobType_JOB_TYPE_MAPREDUCE
+		s.mapReduceToProto(j, out)
+	}
+	return out
+}
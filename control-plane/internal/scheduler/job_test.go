@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+)
+
+func mapReduceJob(id string) *taskpb.SubmitJobRequest {
+	spec := func(id string, typ taskpb.TaskType) *taskpb.SubmitTaskRequest {
+		return &taskpb.SubmitTaskRequest{TaskId: id, Type: typ}
+	}
+	return &taskpb.SubmitJobRequest{
+		JobId: id,
+		Stages: []*taskpb.StageSpec{
+			{Name: "map", Tasks: []*taskpb.SubmitTaskRequest{
+				spec("m-0", taskpb.TaskType_TASK_TYPE_MAP), spec("m-1", taskpb.TaskType_TASK_TYPE_MAP),
+			}},
+			{Name: "reduce", DependsOn: []string{"map"}, Tasks: []*taskpb.SubmitTaskRequest{
+				spec("r-0", taskpb.TaskType_TASK_TYPE_REDUCE),
+			}},
+		},
+	}
+}
+
+func TestSubmitJobRunsStagesInOrder(t *testing.T) {
+	svc, mr := newLeaderService()
+	ctx := context.Background()
+	registerWorker(t, mr, "w-1")
+
+	sub, err := svc.SubmitJob(ctx, mapReduceJob("job-1"))
+	if err != nil || !sub.Ok {
+		t.Fatalf("SubmitJob = %+v, %v", sub, err)
+	}
+	if st := sub.Job.Stages[1]; st.State != taskpb.JobState_JOB_STATE_BLOCKED || st.Blocked != 1 {
+		t.Fatalf("reduce stage at submit = %+v", st)
+	}
+	if got, _ := svc.GetTask(ctx, &taskpb.GetTaskRequest{TaskId: "r-0"}); len(got.Task.DependsOn) != 2 {
+		t.Errorf("stage dependency not expanded: %v", got.Task.DependsOn)
+	}
+
+	for range 2 {
+		acq, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+		if acq.Task == nil || acq.Task.Stage != "map" {
+			t.Fatalf("expected a map task first, got %+v", acq.Task)
+		}
+		if _, err := svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1",
+			TaskId: acq.Task.TaskId, Attempt: acq.Task.Attempt, Succeeded: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	acq, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+	if acq.Task == nil || acq.Task.TaskId != "r-0" {
+		t.Fatalf("reduce should run once the maps succeeded, got %+v", acq.Task)
+	}
+	svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1", TaskId: "r-0", Attempt: 1, Succeeded: true})
+
+	got, _ := svc.GetJob(ctx, &taskpb.GetJobRequest{JobId: "job-1", IncludeTasks: true})
+	if !got.Ok || got.Job.State != taskpb.JobState_JOB_STATE_SUCCEEDED || len(got.Tasks) != 3 ||
+		got.Job.Stages[0].Succeeded != 2 {
+		t.Errorf("GetJob = %+v", got)
+	}
+	list, _ := svc.ListJobs(ctx, &taskpb.ListJobsRequest{State: taskpb.JobState_JOB_STATE_RUNNING})
+	if len(list.Jobs) != 0 {
+		t.Errorf("no job should be running, got %d", len(list.Jobs))
+	}
+}
+
+func TestSubmitJobValidation(t *testing.T) {
+	svc, _ := newLeaderService()
+	ctx := context.Background()
+
+	cyclic := mapReduceJob("cyc")
+	cyclic.Stages[0].DependsOn = []string{"reduce"}
+	taskCycle := mapReduceJob("cyc2")
+	taskCycle.Stages[0].Tasks[0].DependsOn = []string{"r-0"}
+	unknown := mapReduceJob("unk")
+	unknown.Stages[1].DependsOn = []string{"shuffle"}
+
+	for _, tc := range []struct {
+		req  *taskpb.SubmitJobRequest
+		want string
+	}{
+		{cyclic, "stage dependency cycle: map -> reduce -> map"},
+		{taskCycle, "task dependency cycle"},
+		{unknown, `unknown stage "shuffle"`},
+		{&taskpb.SubmitJobRequest{JobId: "empty"}, "at least one stage"},
+	} {
+		resp, err := svc.SubmitJob(ctx, tc.req)
+		if err != nil || resp.Ok || !strings.Contains(resp.Error, tc.want) {
+			t.Errorf("SubmitJob(%s) = %+v, %v; want error containing %q", tc.req.JobId, resp, err, tc.want)
+		}
+	}
+	if list, _ := svc.ListTasks(ctx, &taskpb.ListTasksRequest{}); len(list.Tasks) != 0 {
+		t.Errorf("rejected jobs left %d tasks behind", len(list.Tasks))
+	}
+	if resp, _ := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{
+		Type: taskpb.TaskType_TASK_TYPE_MAP, DependsOn: []string{"x"},
+	}); resp.Ok {
+		t.Error("SubmitTask must reject depends_on")
+	}
+}
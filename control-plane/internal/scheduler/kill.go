@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// killOverdue kills t's running attempts, speculative ones included, that
+// are past the task's deadline and not being killed already.
+func (s *Service) killOverdue(t *internalraft.Task, now time.Time) {
+	type attempt struct {
+		n       int
+		worker  string
+		started time.Time
+	}
+	running := []attempt{{t.Attempt, t.AssignedWorker, t.StartedAt}}
+	if sp := t.Speculative; sp != nil {
+		running = append(running, attempt{sp.Attempt, sp.Worker, sp.StartedAt})
+	}
+	for _, a := range running {
+		deadline := t.Deadline(a.started)
+		if deadline.IsZero() || !now.After(deadline) || killFor(t, a.n) != nil {
+			continue
+		}
+		reason := fmt.Sprintf("deadline exceeded: ran %s, timeout %s", now.Sub(a.started).Round(time.Millisecond), t.Timeout)
+		_, err := s.apply(internalraft.CmdKillTask, internalraft.KillTaskPayload{
+			ID: t.ID, Attempt: a.n, Reason: reason, RequestedAt: now.UTC(),
+		})
+		if err != nil {
+			slog.Warn("kill task failed", "task_id", t.ID, "attempt", a.n, "error", err)
+			continue
+		}
+		metrics.TaskDeadlineKillsTotal.Inc()
+		slog.Warn("task attempt past its deadline — killing", "task_id", t.ID, "worker_id", a.worker,
+			"attempt", a.n, "reason", reason)
+	}
+}
+
+// revokeKills ends the kill orders of t whose grace period has passed. An
+// attempt that still runs has its lease revoked and fails as timed out; the
+// FSM retries or fails the task by its retry policy. It reports whether it
+// changed t, and whether t went back to pending.
+func (s *Service) revokeKills(t *internalraft.Task, now time.Time) (changed, requeued bool) {
+	for _, k := range t.Kills {
+		if !k.GraceUntil.Before(now) {
+			continue
+		}
+		running := t.State == internalraft.TaskRunning &&
+			(t.Attempt == k.Attempt || (t.Speculative != nil && t.Speculative.Attempt == k.Attempt))
+		resp, err := s.apply(internalraft.CmdRevokeTaskLease, internalraft.RevokeTaskLeasePayload{
+			ID: t.ID, Attempt: k.Attempt, RevokedAt: now.UTC(),
+		})
+		if err != nil {
+			slog.Warn("revoke task lease failed", "task_id", t.ID, "attempt", k.Attempt, "error", err)
+			continue
+		}
+		changed = true
+		if !running {
+			metrics.TaskKillRevocationsTotal.WithLabelValues("expired").Inc()
+			slog.Info("kill order expired", "task_id", t.ID, "worker_id", k.Worker, "attempt", k.Attempt)
+			continue
+		}
+		metrics.TaskKillRevocationsTotal.WithLabelValues("revoked").Inc()
+		after, _ := resp.(*internalraft.Task)
+		if after != nil && after.State == internalraft.TaskPending {
+			requeued = true
+			metrics.TaskRetriesTotal.WithLabelValues(internalraft.AttemptTimedOut).Inc()
+		}
+		slog.Warn("killed task attempt did not stop — lease revoked", "task_id", t.ID, "worker_id", k.Worker,
+			"attempt", k.Attempt, "reason", k.Reason, "requeued", requeued)
+	}
+	return changed, requeued
+}
+
+// killFor returns the kill order for attempt of t, or nil.
+func killFor(t *internalraft.Task, attempt int) *internalraft.KillOrder {
+	for i := range t.Kills {
+		if t.Kills[i].Attempt == attempt {
+			return &t.Kills[i]
+		}
+	}
+	return nil
+}
+
+func killToProto(k *internalraft.KillOrder) *taskpb.KillOrder {
+	if k == nil {
+		return nil
+	}
+	return &taskpb.KillOrder{
+		TaskId:        k.TaskID,
+		Attempt:       uint32(k.Attempt),
+		Worker:        k.Worker,
+		Reason:        k.Reason,
+		RequestedAtMs: unixMilli(k.RequestedAt),
+		GraceUntilMs:  unixMilli(k.GraceUntil),
+	}
+}
+
+func killsToProto(kills []internalraft.KillOrder) []*taskpb.KillOrder {
+	var out []*taskpb.KillOrder
+	for i := range kills {
+		out = append(out, killToProto(&kills[i]))
+	}
+	return out
+}
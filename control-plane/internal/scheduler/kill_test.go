@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/clock"
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+func TestDeadlineKillAndRevocation(t *testing.T) {
+	svc, mr := newLeaderService()
+	svc.cfg.TaskTimeout = 20 * time.Second
+	sim := clock.NewSim(1, time.Unix(1_700_000_000, 0))
+	svc.SetClock(sim)
+	registerWorker(t, mr, "w-1")
+	ctx := context.Background()
+	renew := func(task string, attempt uint32) *taskpb.RenewTaskLeaseResponse {
+		t.Helper()
+		r, err := svc.RenewTaskLease(ctx, &taskpb.RenewTaskLeaseRequest{WorkerId: "w-1", TaskId: task, Attempt: attempt})
+		if err != nil || !r.Ok {
+			t.Fatalf("renew %s: %+v, %v", task, r, err)
+		}
+		return r
+	}
+
+	// t-1 takes the service default; t-2 brings its own grace.
+	submit(t, svc, "t-1", taskpb.TaskType_TASK_TYPE_GENERIC)
+	if r, err := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{TaskId: "t-2", Type: taskpb.TaskType_TASK_TYPE_GENERIC,
+		TimeoutMs: 20_000, KillGraceMs: 5_000}); err != nil || !r.Ok {
+		t.Fatalf("submit t-2: %+v, %v", r, err)
+	}
+	if task := mr.fsm.GetTask("t-1"); task.Timeout != 20*time.Second || task.KillGrace != internalraft.DefaultKillGrace {
+		t.Fatalf("t-1 deadline settings = %v, %v", task.Timeout, task.KillGrace)
+	}
+
+	// The worker ignores the kill order on t-2: once the grace is over the
+	// lease is revoked and the task retried.
+	acq, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+	if acq.Task.GetTaskId() != "t-1" {
+		t.Fatalf("acquired %+v", acq.Task)
+	}
+	acq, _ = svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+	if acq.Task.GetTaskId() != "t-2" || acq.Task.TimeoutMs != 20_000 {
+		t.Fatalf("acquired %+v", acq.Task)
+	}
+	sim.RunFor(15 * time.Second)
+	renew("t-1", 1)
+	renew("t-2", 1)
+	svc.expireLeases()
+	if task := mr.fsm.GetTask("t-2"); len(task.Kills) != 0 {
+		t.Fatalf("killed before the deadline: %+v", task.Kills)
+	}
+	sim.RunFor(6 * time.Second)
+	svc.expireLeases()
+	r := renew("t-2", 1)
+	if r.Kill == nil || !strings.HasPrefix(r.Kill.Reason, "deadline exceeded") || r.Kill.Worker != "w-1" {
+		t.Fatalf("renewal after the deadline = %+v", r)
+	}
+	svc.expireLeases()
+	if task := mr.fsm.GetTask("t-2"); task.State != internalraft.TaskRunning || len(task.Kills) != 1 {
+		t.Fatalf("a second scan must not kill twice: %+v", task)
+	}
+	sim.RunFor(6 * time.Second)
+	svc.expireLeases()
+	task := mr.fsm.GetTask("t-2")
+	if task.State != internalraft.TaskPending || task.Attempt != 2 || len(task.Kills) != 0 ||
+		task.Attempts[0].Outcome != internalraft.AttemptTimedOut {
+		t.Fatalf("task after revocation = %+v", task)
+	}
+	if r, _ := svc.RenewTaskLease(ctx, &taskpb.RenewTaskLeaseRequest{WorkerId: "w-1", TaskId: "t-2", Attempt: 1}); !r.LeaseLost {
+		t.Errorf("renewal after revocation = %+v", r)
+	}
+
+	// The worker stops t-1 within its grace and reports it.
+	if r := renew("t-1", 1); r.Kill == nil {
+		t.Fatalf("expected a kill order for t-1, got %+v", r)
+	}
+	if r, err := svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1", TaskId: "t-1", Attempt: 1,
+		Error: "stopped"}); err != nil || !r.Ok {
+		t.Fatalf("complete t-1: %+v, %v", r, err)
+	}
+	if task := mr.fsm.GetTask("t-1"); task.State != internalraft.TaskPending || len(task.Kills) != 0 ||
+		task.Attempts[0].Outcome != internalraft.AttemptTimedOut {
+		t.Fatalf("t-1 after stopping = %+v", task)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	svc, mr := newLeaderService()
+	ctx := context.Background()
+	registerWorker(t, mr, "w-1")
+
+	if r, _ := svc.CancelJob(ctx, &taskpb.CancelJobRequest{JobId: "nope"}); r.Ok {
+		t.Error("expected cancelling an unknown job to fail")
+	}
+	if sub, err := svc.SubmitJob(ctx, mapReduceJob("job-1")); err != nil || !sub.Ok {
+		t.Fatalf("SubmitJob = %+v, %v", sub, err)
+	}
+	acq, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+	running := acq.Task.GetTaskId()
+
+	r, err := svc.CancelJob(ctx, &taskpb.CancelJobRequest{JobId: "job-1", Reason: "superseded by job-2"})
+	if err != nil || !r.Ok || r.Job.State != taskpb.JobState_JOB_STATE_CANCELLED {
+		t.Fatalf("CancelJob = %+v, %v", r, err)
+	}
+	for _, id := range []string{"m-0", "m-1", "r-0"} {
+		if st := mr.fsm.GetTask(id).State; st != internalraft.TaskCancelled {
+			t.Errorf("%s is %s", id, st)
+		}
+	}
+	renew, _ := svc.RenewTaskLease(ctx, &taskpb.RenewTaskLeaseRequest{WorkerId: "w-1", TaskId: running, Attempt: 1})
+	if !renew.LeaseLost {
+		t.Errorf("renewal of a cancelled task = %+v", renew)
+	}
+	if orders := mr.fsm.KillOrders("w-1"); len(orders) != 1 || orders[0].TaskID != running ||
+		orders[0].Reason != "cancelled: job cancelled: superseded by job-2" {
+		t.Errorf("kill orders = %+v", orders)
+	}
+	if r, _ := svc.CancelJob(ctx, &taskpb.CancelJobRequest{JobId: "job-1"}); r.Ok {
+		t.Error("expected cancelling a finished job to fail")
+	}
+}
@@ -0,0 +1,346 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/agent"
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// AcquireTask returns a task the scheduling loop pushed to the worker or else
+// assigns it the oldest pending unplaced task it can run, waiting up to the
+// request's wait_ms (capped at MaxPollWait) for one to appear.
+func (s *Service) AcquireTask(ctx context.Context, req *taskpb.AcquireTaskRequest) (*taskpb.AcquireTaskResponse, error) {
+	if s.raft.State() != hashiraft.Leader {
+		return &taskpb.AcquireTaskResponse{Ok: false, LeaderAddr: s.leaderAddr()}, nil
+	}
+	if fenced, err := s.checkWorker(req.WorkerId, req.Epoch); err != nil {
+		return &taskpb.AcquireTaskResponse{Ok: false, Fenced: fenced, Error: err.Error()}, nil
+	}
+	types := make(map[string]bool, len(req.Types))
+	for _, pt := range req.Types {
+		typ, ok := taskTypeFromProto(pt)
+		if !ok {
+			return &taskpb.AcquireTaskResponse{Ok: false, Error: fmt.Sprintf("unknown task type %v", pt)}, nil
+		}
+		types[typ] = true
+	}
+	s.mu.Lock()
+	s.capabilities[req.WorkerId] = types
+	s.mu.Unlock()
+
+	wait := min(time.Duration(req.WaitMs)*time.Millisecond, s.cfg.MaxPollWait)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		p := s.addPoller(req.WorkerId, types)
+		t := s.takePushed(req.WorkerId, types)
+		if t == nil {
+			var err error
+			if t, err = s.tryAssign(req.WorkerId, types); err != nil {
+				s.removePoller(p)
+				return nil, err
+			}
+		}
+		if t != nil {
+			s.removePoller(p)
+			return &taskpb.AcquireTaskResponse{Ok: true, Task: taskToProto(t)}, nil
+		}
+		select {
+		case <-p.wake:
+		case <-timer.C:
+			s.removePoller(p)
+			return &taskpb.AcquireTaskResponse{Ok: true}, nil
+		case <-ctx.Done():
+			s.removePoller(p)
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		if s.raft.State() != hashiraft.Leader {
+			return &taskpb.AcquireTaskResponse{Ok: false, LeaderAddr: s.leaderAddr()}, nil
+		}
+	}
+}
+
+// RenewTaskLease extends the caller's lease on a running task.
+func (s *Service) RenewTaskLease(ctx context.Context, req *taskpb.RenewTaskLeaseRequest) (*taskpb.RenewTaskLeaseResponse, error) {
+	if s.raft.State() != hashiraft.Leader {
+		return &taskpb.RenewTaskLeaseResponse{Ok: false, LeaderAddr: s.leaderAddr()}, nil
+	}
+	if fenced, err := s.checkWorker(req.WorkerId, req.Epoch); err != nil {
+		return &taskpb.RenewTaskLeaseResponse{Ok: false, Fenced: fenced, Error: err.Error()}, nil
+	}
+	expires := s.clock.Now().UTC().Add(s.cfg.LeaseDuration)
+	_, err := s.apply(internalraft.CmdRenewTaskLease, internalraft.RenewTaskLeasePayload{
+		ID:           req.TaskId,
+		WorkerID:     req.WorkerId,
+		Attempt:      int(req.Attempt),
+		LeaseExpires: expires,
+	})
+	if errors.Is(err, internalraft.ErrLeaseLost) {
+		return &taskpb.RenewTaskLeaseResponse{Ok: false, LeaseLost: true, Error: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp := &taskpb.RenewTaskLeaseResponse{Ok: true, LeaseExpiresAtMs: expires.UnixMilli()}
+	if t := s.tasks.GetTask(req.TaskId); t != nil {
+		resp.Kill = killToProto(killFor(t, int(req.Attempt)))
+	}
+	return resp, nil
+}
+
+// CompleteTask records the outcome of the caller's running task.
+func (s *Service) CompleteTask(ctx context.Context, req *taskpb.CompleteTaskRequest) (*taskpb.CompleteTaskResponse, error) {
+	if s.raft.State() != hashiraft.Leader {
+		return &taskpb.CompleteTaskResponse{Ok: false, LeaderAddr: s.leaderAddr()}, nil
+	}
+	if fenced, err := s.checkWorker(req.WorkerId, req.Epoch); err != nil {
+		return &taskpb.CompleteTaskResponse{Ok: false, Fenced: fenced, Error: err.Error()}, nil
+	}
+	outputs, err := s.mapOutputs(req)
+	if err != nil {
+		return &taskpb.CompleteTaskResponse{Ok: false, Error: err.Error()}, nil
+	}
+	class, err := errorClassFromProto(req.ErrorClass)
+	if err != nil {
+		return &taskpb.CompleteTaskResponse{Ok: false, Error: err.Error()}, nil
+	}
+	if req.Succeeded {
+		class = ""
+	}
+	before := s.tasks.GetTask(req.TaskId)
+	resp, err := s.apply(internalraft.CmdCompleteTask, internalraft.CompleteTaskPayload{
+		ID:         req.TaskId,
+		WorkerID:   req.WorkerId,
+		Attempt:    int(req.Attempt),
+		Succeeded:  req.Succeeded,
+		Error:      req.Error,
+		ErrorClass: class,
+		FinishedAt: s.clock.Now().UTC(),
+		Outputs:    outputs,
+	})
+	if errors.Is(err, internalraft.ErrLeaseLost) {
+		slog.Info("task result discarded", "task_id", req.TaskId, "worker_id", req.WorkerId, "error", err)
+		return &taskpb.CompleteTaskResponse{Ok: false, LeaseLost: true, Error: err.Error()}, nil
+	}
+	if errors.Is(err, internalraft.ErrCommitRequired) {
+		return &taskpb.CompleteTaskResponse{Ok: false, Error: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A job task's success may have unblocked others.
+	s.notify()
+	t, _ := resp.(*internalraft.Task)
+	if t != nil && t.State == internalraft.TaskRunning {
+		slog.Info("task attempt failed — other attempt carries on", "task_id", req.TaskId,
+			"worker_id", req.WorkerId, "attempt", req.Attempt, "error", req.Error)
+		return &taskpb.CompleteTaskResponse{Ok: true, Retrying: true}, nil
+	}
+	if t != nil && t.State == internalraft.TaskPending {
+		outcome := internalraft.AttemptFailed
+		if before != nil && killFor(before, int(req.Attempt)) != nil {
+			outcome = internalraft.AttemptTimedOut // stopped on its deadline kill
+		}
+		metrics.TaskRetriesTotal.WithLabelValues(outcome).Inc()
+		slog.Info("task attempt failed — retrying", "task_id", req.TaskId, "worker_id", req.WorkerId,
+			"attempt", req.Attempt, "error", req.Error, "not_before", t.NotBefore)
+		return &taskpb.CompleteTaskResponse{Ok: true, Retrying: true}, nil
+	}
+	result := internalraft.TaskFailed
+	if req.Succeeded {
+		result = internalraft.TaskSucceeded
+		speculationSettled(before, req.Attempt)
+		if t != nil {
+			metrics.TaskDurationSeconds.WithLabelValues(t.Type).Observe(t.FinishedAt.Sub(t.StartedAt).Seconds())
+		}
+	}
+	metrics.TasksCompletedTotal.WithLabelValues(result).Inc()
+	slog.Info("task completed", "task_id", req.TaskId, "worker_id", req.WorkerId,
+		"attempt", req.Attempt, "result", result)
+	return &taskpb.CompleteTaskResponse{Ok: true}, nil
+}
+
+// checkWorker verifies the caller's epoch and that it may take work. fenced
+// reports a stale epoch: a newer process owns the worker ID.
+func (s *Service) checkWorker(workerID string, epoch uint64) (fenced bool, err error) {
+	if s.epochs != nil {
+		if err := s.epochs.ValidateEpoch(workerID, epoch); err != nil {
+			fenced = errors.Is(err, agent.ErrStaleEpoch)
+			if fenced {
+				metrics.WorkerFencedTotal.Inc()
+			}
+			return fenced, err
+		}
+	}
+	w := s.tasks.GetWorker(workerID)
+	if w == nil {
+		return false, agent.ErrNotRegistered
+	}
+	switch w.Status {
+	case internalraft.WorkerOffline, internalraft.WorkerQuarantined, internalraft.WorkerRevoked:
+		return false, fmt.Errorf("worker %q is %s", workerID, w.Status)
+	}
+	return false, nil
+}
+
+// tryAssign commits an assignment of the oldest matching pending task to
+// workerID, taken from the ready index. Tasks with a placement policy are left
+// to the scheduling loop. It returns nil when there is nothing to assign.
+func (s *Service) tryAssign(workerID string, types map[string]bool) (*internalraft.Task, error) {
+	s.assignMu.Lock()
+	defer s.assignMu.Unlock()
+
+	now := s.clock.Now()
+	for {
+		s.mu.Lock()
+		r, ok := s.oldestReadyLocked(types, now)
+		s.mu.Unlock()
+		if !ok {
+			return nil, nil
+		}
+		// The index is rebuilt only by notify; skip entries that have moved on.
+		pick := s.tasks.GetTask(r.id)
+		if pick == nil || pick.State != internalraft.TaskPending || pick.Placement != "" || pick.NotBefore.After(now) {
+			s.dropReady(r)
+			continue
+		}
+		t, err := s.assign(pick, workerID, "")
+		if err != nil {
+			return nil, err
+		}
+		s.dropReady(r)
+		metrics.TasksAssignedTotal.Inc()
+		slog.Info("task assigned", "task_id", pick.ID, "worker_id", workerID, "attempt", pick.Attempt)
+		return t, nil
+	}
+}
+
+// assign commits the assignment of t to workerID with a fresh lease, along
+// with the estimated egress of running it in that worker's cloud.
+func (s *Service) assign(t *internalraft.Task, workerID, reason string) (*internalraft.Task, error) {
+	var egress Egress
+	if w := s.tasks.GetWorker(workerID); w != nil {
+		egress = s.locality.Estimate(t, w.CloudTag)
+	}
+	now := s.clock.Now().UTC()
+	resp, err := s.apply(internalraft.CmdAssignTask, internalraft.AssignTaskPayload{
+		ID:           t.ID,
+		WorkerID:     workerID,
+		AssignedAt:   now,
+		LeaseExpires: now.Add(s.cfg.LeaseDuration),
+		Reason:       reason,
+		EgressBytes:  egress.Bytes,
+		EgressCost:   egress.Cost,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for pair, n := range egress.Flows {
+		metrics.TaskEgressBytesTotal.WithLabelValues(pair.From, pair.To).Add(float64(n))
+	}
+	metrics.TaskEgressCostTotal.Add(egress.Cost)
+	assigned, _ := resp.(*internalraft.Task)
+	return assigned, nil
+}
+
+// expireLeases ends the running attempts whose lease ran out, speculative
+// ones included; the FSM retries or fails each task by its retry policy. It
+// likewise kills attempts past their deadline and revokes the leases of
+// killed attempts whose grace has passed. It also wakes AcquireTask callers
+// for tasks whose retry backoff has passed since the last check.
+func (s *Service) expireLeases() {
+	if s.raft.State() != hashiraft.Leader {
+		return
+	}
+	now := s.clock.Now()
+	s.mu.Lock()
+	since := s.lastLeaseCheck
+	s.lastLeaseCheck = now
+	s.mu.Unlock()
+
+	wake := false
+	for _, t := range s.tasks.Tasks() {
+		if t.State == internalraft.TaskPending && t.NotBefore.After(since) && !t.NotBefore.After(now) {
+			wake = true
+		}
+		// Each step works from t as read; once one changed the task, the
+		// rest wait for the next check.
+		changed, requeued := s.revokeKills(t, now)
+		wake = wake || requeued
+		if changed || t.State != internalraft.TaskRunning {
+			continue
+		}
+		expired := false
+		if t.LeaseExpires.Before(now) {
+			expired = true
+			wake = s.expireAttempt(t, t.Attempt, t.AssignedWorker, now) || wake
+		}
+		if sp := t.Speculative; sp != nil && sp.LeaseExpires.Before(now) {
+			expired = true
+			wake = s.expireAttempt(t, sp.Attempt, sp.Worker, now) || wake
+		}
+		if !expired {
+			s.killOverdue(t, now)
+		}
+	}
+	metrics.DeadLetterTasks.Set(float64(len(s.tasks.DeadLetters())))
+	if wake {
+		s.notify()
+	}
+}
+
+// expireAttempt ends attempt of t, held by workerID, for its lease running
+// out. It reports whether t went back to pending.
+func (s *Service) expireAttempt(t *internalraft.Task, attempt int, workerID string, now time.Time) bool {
+	reason := fmt.Sprintf("lease expired on %s", workerID)
+	resp, err := s.apply(internalraft.CmdRequeueTask, internalraft.RequeueTaskPayload{
+		ID: t.ID, Attempt: attempt, Reason: reason, RequeuedAt: now.UTC(),
+	})
+	if err != nil {
+		slog.Warn("requeue task failed", "task_id", t.ID, "attempt", attempt, "error", err)
+		return false
+	}
+	metrics.TaskLeaseExpiriesTotal.Inc()
+	after, _ := resp.(*internalraft.Task)
+	switch {
+	case after == nil:
+	case after.State == internalraft.TaskPending:
+		metrics.TaskRetriesTotal.WithLabelValues(internalraft.AttemptLeaseExpired).Inc()
+		slog.Warn("task lease expired — requeued", "task_id", t.ID, "worker_id", workerID,
+			"attempt", attempt, "not_before", after.NotBefore)
+		return true
+	case after.State == internalraft.TaskRunning:
+		slog.Warn("task lease expired — other attempt carries on", "task_id", t.ID, "worker_id", workerID,
+			"attempt", attempt)
+	default:
+		slog.Warn("task lease expired — no attempts left", "task_id", t.ID, "worker_id", workerID,
+			"attempt", attempt)
+	}
+	return false
+}
+
+// apply commits a task command. ErrLeaseLost and ErrCommitRequired are
+// returned as-is for the caller to report; any other failure becomes a gRPC
+// Internal error.
+func (s *Service) apply(typ internalraft.CommandType, payload interface{}) (interface{}, error) {
+	cmd, err := internalraft.MarshalCommand(typ, payload)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "marshal command: %v", err)
+	}
+	resp, err := s.raft.ApplyCommand(cmd, raftApplyTimeout)
+	if err != nil && !errors.Is(err, internalraft.ErrLeaseLost) && !errors.Is(err, internalraft.ErrCommitRequired) {
+		return nil, status.Errorf(codes.Internal, "raft apply: %v", err)
+	}
+	return resp, err
+}
@@ -0,0 +1,297 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/agent"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/clock"
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+func registerWorker(t *testing.T, mr *mockRaft, id string) {
+	t.Helper()
+	cmd, err := internalraft.MarshalCommand(internalraft.CmdRegisterWorker, internalraft.RegisterWorkerPayload{ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mr.ApplyCommand(cmd, time.Second); err != nil {
+		t.Fatalf("register %s: %v", id, err)
+	}
+}
+
+func submit(t *testing.T, svc *Service, id string, typ taskpb.TaskType) {
+	t.Helper()
+	resp, err := svc.SubmitTask(context.Background(), &taskpb.SubmitTaskRequest{TaskId: id, Type: typ})
+	if err != nil || !resp.Ok {
+		t.Fatalf("submit %s: %+v, %v", id, resp, err)
+	}
+}
+
+func TestAcquireTaskMatchesTypesOldestFirst(t *testing.T) {
+	svc, mr := newLeaderService()
+	sim := clock.NewSim(1, time.Unix(1_700_000_000, 0))
+	svc.SetClock(sim)
+	registerWorker(t, mr, "w-1")
+	ctx := context.Background()
+
+	submit(t, svc, "r-0", taskpb.TaskType_TASK_TYPE_REDUCE)
+	sim.RunFor(time.Second)
+	submit(t, svc, "m-1", taskpb.TaskType_TASK_TYPE_MAP)
+	sim.RunFor(time.Second)
+	submit(t, svc, "m-0", taskpb.TaskType_TASK_TYPE_MAP)
+
+	maps := []taskpb.TaskType{taskpb.TaskType_TASK_TYPE_MAP}
+	for _, want := range []string{"m-1", "m-0", ""} {
+		resp, err := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1", Types: maps})
+		if err != nil || !resp.Ok {
+			t.Fatalf("AcquireTask: %+v, %v", resp, err)
+		}
+		if got := resp.Task.GetTaskId(); got != want {
+			t.Fatalf("acquired %q, want %q", got, want)
+		}
+		if want != "" && (resp.Task.State != taskpb.TaskState_TASK_STATE_RUNNING ||
+			resp.Task.AssignedWorker != "w-1" || resp.Task.LeaseExpiresAtMs == 0) {
+			t.Errorf("unexpected assignment %+v", resp.Task)
+		}
+	}
+	resp, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+	if resp.Task.GetTaskId() != "r-0" {
+		t.Errorf("a worker without type filter should get the reduce task, got %+v", resp.Task)
+	}
+}
+
+func TestAcquireTaskLongPolls(t *testing.T) {
+	svc, mr := newLeaderService()
+	registerWorker(t, mr, "w-1")
+	ctx := context.Background()
+
+	start := time.Now()
+	resp, err := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1", WaitMs: 50})
+	if err != nil || !resp.Ok || resp.Task != nil || time.Since(start) < 50*time.Millisecond {
+		t.Fatalf("empty poll = %+v, %v after %s", resp, err, time.Since(start))
+	}
+
+	got := make(chan *taskpb.AcquireTaskResponse)
+	go func() {
+		resp, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1", WaitMs: 10_000})
+		got <- resp
+	}()
+	time.Sleep(20 * time.Millisecond)
+	submit(t, svc, "t-1", taskpb.TaskType_TASK_TYPE_GENERIC)
+	select {
+	case resp := <-got:
+		if resp.Task.GetTaskId() != "t-1" {
+			t.Errorf("woken poll returned %+v", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a waiting AcquireTask was not woken by a new task")
+	}
+}
+
+func TestNotifyWakesOnlyPollersThatCanTakeTheTask(t *testing.T) {
+	woken := func(p *poller) bool {
+		select {
+		case <-p.wake:
+			return true
+		default:
+			return false
+		}
+	}
+
+	svc, mr := newLeaderService()
+	registerWorker(t, mr, "w-1")
+	registerWorker(t, mr, "w-2")
+	mapper := svc.addPoller("w-1", map[string]bool{internalraft.TaskMap: true})
+	reducer := svc.addPoller("w-2", map[string]bool{internalraft.TaskReduce: true})
+	submit(t, svc, "m-1", taskpb.TaskType_TASK_TYPE_MAP)
+	if !woken(mapper) || woken(reducer) {
+		t.Errorf("a map task woke mapper %v, reducer %v", woken(mapper), woken(reducer))
+	}
+	svc.removePoller(reducer)
+
+	// A pushed task wakes only the worker it was placed on.
+	svc, mr = newLeaderService()
+	registerCapacity(t, mr, "w-aws", "aws", 2000)
+	registerCapacity(t, mr, "w-gcp", "gcp", 2000)
+	aws, gcp := svc.addPoller("w-aws", nil), svc.addPoller("w-gcp", nil)
+	resp, err := svc.SubmitTask(context.Background(), &taskpb.SubmitTaskRequest{TaskId: "near",
+		Type: taskpb.TaskType_TASK_TYPE_GENERIC, Placement: PolicyCloudAffinity, CloudAffinity: "gcp"})
+	if err != nil || !resp.Ok {
+		t.Fatalf("submit: %+v, %v", resp, err)
+	}
+	if woken(aws) || woken(gcp) {
+		t.Fatal("a task left to the scheduling loop must not wake pollers before it is placed")
+	}
+	svc.schedule()
+	if woken(aws) || !woken(gcp) {
+		t.Errorf("push to w-gcp woke w-aws %v, w-gcp %v", woken(aws), woken(gcp))
+	}
+	acq, _ := svc.AcquireTask(context.Background(), &taskpb.AcquireTaskRequest{WorkerId: "w-gcp"})
+	if acq.Task.GetTaskId() != "near" {
+		t.Errorf("w-gcp acquired %+v", acq.Task)
+	}
+}
+
+func TestLeaseRenewCompleteAndExpiry(t *testing.T) {
+	svc, mr := newLeaderService()
+	sim := clock.NewSim(1, time.Unix(1_700_000_000, 0))
+	svc.SetClock(sim)
+	registerWorker(t, mr, "w-1")
+	registerWorker(t, mr, "w-2")
+	ctx := context.Background()
+	lease := svc.cfg.LeaseDuration
+
+	submit(t, svc, "t-1", taskpb.TaskType_TASK_TYPE_GENERIC)
+	a, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+	if a.Task.GetAttempt() != 1 {
+		t.Fatalf("first assignment = %+v", a.Task)
+	}
+
+	// Renewing keeps the task past its original lease.
+	sim.RunFor(lease * 2 / 3)
+	renew, err := svc.RenewTaskLease(ctx, &taskpb.RenewTaskLeaseRequest{WorkerId: "w-1", TaskId: "t-1", Attempt: 1})
+	if err != nil || !renew.Ok {
+		t.Fatalf("renew: %+v, %v", renew, err)
+	}
+	sim.RunFor(lease * 2 / 3)
+	svc.expireLeases()
+	if task := mr.fsm.GetTask("t-1"); task.State != internalraft.TaskRunning {
+		t.Fatalf("renewed task was requeued: %+v", task)
+	}
+
+	// Without renewals the lease runs out and the task goes back to pending,
+	// after a retry backoff.
+	sim.RunFor(lease)
+	svc.expireLeases()
+	task := mr.fsm.GetTask("t-1")
+	if task.State != internalraft.TaskPending || task.Attempt != 2 || task.AssignedWorker != "" ||
+		!task.NotBefore.After(sim.Now()) || len(task.Attempts) != 1 ||
+		task.Attempts[0].Outcome != internalraft.AttemptLeaseExpired {
+		t.Fatalf("expired task = %+v", task)
+	}
+	if r, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-2"}); r.Task != nil {
+		t.Fatalf("task handed out during its backoff: %+v", r.Task)
+	}
+	sim.RunFor(svc.cfg.Retry.InitialBackoff)
+
+	// The old holder's calls are refused; the new holder completes it.
+	if r, _ := svc.RenewTaskLease(ctx, &taskpb.RenewTaskLeaseRequest{WorkerId: "w-1", TaskId: "t-1", Attempt: 1}); !r.LeaseLost {
+		t.Errorf("stale renew = %+v", r)
+	}
+	b, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-2"})
+	if b.Task.GetAttempt() != 2 {
+		t.Fatalf("second assignment = %+v", b.Task)
+	}
+	if r, _ := svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1", TaskId: "t-1", Attempt: 1, Succeeded: true}); !r.LeaseLost {
+		t.Errorf("stale completion = %+v", r)
+	}
+	done, err := svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-2", TaskId: "t-1", Attempt: 2, Succeeded: true})
+	if err != nil || !done.Ok {
+		t.Fatalf("complete: %+v, %v", done, err)
+	}
+	if task := mr.fsm.GetTask("t-1"); task.State != internalraft.TaskSucceeded || !task.LeaseExpires.IsZero() {
+		t.Errorf("completed task = %+v", task)
+	}
+}
+
+func TestCompleteTaskRetriesAndDeadLetters(t *testing.T) {
+	svc, mr := newLeaderService()
+	sim := clock.NewSim(1, time.Unix(1_700_000_000, 0))
+	svc.SetClock(sim)
+	registerWorker(t, mr, "w-1")
+	ctx := context.Background()
+
+	if r, _ := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{TaskId: "bad", Type: taskpb.TaskType_TASK_TYPE_GENERIC,
+		Retry: &taskpb.RetryPolicy{InitialBackoffMs: 5000, MaxBackoffMs: 100}}); r.Ok {
+		t.Error("expected a retry policy with max backoff below initial backoff to be refused")
+	}
+	r, err := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{TaskId: "t-1", Type: taskpb.TaskType_TASK_TYPE_GENERIC,
+		Retry: &taskpb.RetryPolicy{MaxAttempts: 2, InitialBackoffMs: 100}})
+	if err != nil || !r.Ok || r.Task.Retry.MaxAttempts != 2 || r.Task.Retry.MaxBackoffMs != uint32(svc.cfg.Retry.MaxBackoff.Milliseconds()) {
+		t.Fatalf("submit with a partial retry policy: %+v, %v", r, err)
+	}
+	fail := func(id string, attempt uint32, class taskpb.ErrorClass) *taskpb.CompleteTaskResponse {
+		t.Helper()
+		a, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+		if a.Task.GetTaskId() != id || a.Task.GetAttempt() != attempt {
+			t.Fatalf("acquired %+v, want %s attempt %d", a.Task, id, attempt)
+		}
+		done, err := svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1", TaskId: id,
+			Attempt: attempt, Error: "boom", ErrorClass: class})
+		if err != nil || !done.Ok {
+			t.Fatalf("complete %s: %+v, %v", id, done, err)
+		}
+		return done
+	}
+
+	if done := fail("t-1", 1, taskpb.ErrorClass_ERROR_CLASS_RETRYABLE); !done.Retrying {
+		t.Fatalf("first failure = %+v, want a retry", done)
+	}
+	got, _ := svc.GetTask(ctx, &taskpb.GetTaskRequest{TaskId: "t-1"})
+	if got.Task.State != taskpb.TaskState_TASK_STATE_PENDING || got.Task.Failures != 1 || len(got.Task.Attempts) != 1 ||
+		got.Task.Attempts[0].Outcome != taskpb.AttemptOutcome_ATTEMPT_OUTCOME_FAILED || got.Task.NotBeforeMs == 0 {
+		t.Fatalf("task after a retryable failure = %+v", got.Task)
+	}
+	sim.RunFor(100 * time.Millisecond)
+	if done := fail("t-1", 2, taskpb.ErrorClass_ERROR_CLASS_UNSPECIFIED); done.Retrying {
+		t.Fatalf("last failure = %+v, want no retry", done)
+	}
+
+	submit(t, svc, "t-2", taskpb.TaskType_TASK_TYPE_GENERIC)
+	if done := fail("t-2", 1, taskpb.ErrorClass_ERROR_CLASS_FATAL); done.Retrying {
+		t.Fatalf("fatal failure = %+v, want no retry", done)
+	}
+
+	list, err := svc.ListDeadLetters(ctx, &taskpb.ListDeadLettersRequest{})
+	if err != nil || len(list.DeadLetters) != 2 {
+		t.Fatalf("dead letters: %+v, %v", list, err)
+	}
+	if d := list.DeadLetters[0]; d.TaskId != "t-1" || d.Reason != internalraft.DeadLetterExhausted ||
+		d.Type != taskpb.TaskType_TASK_TYPE_GENERIC || len(d.Attempts) != 2 {
+		t.Errorf("exhausted dead letter = %+v", d)
+	}
+	if d := list.DeadLetters[1]; d.TaskId != "t-2" || d.Reason != internalraft.DeadLetterFatal ||
+		d.Attempts[0].ErrorClass != taskpb.ErrorClass_ERROR_CLASS_FATAL {
+		t.Errorf("fatal dead letter = %+v", d)
+	}
+	if list, _ := svc.ListDeadLetters(ctx, &taskpb.ListDeadLettersRequest{JobId: "other"}); len(list.DeadLetters) != 0 {
+		t.Errorf("dead letters of another job = %+v", list.DeadLetters)
+	}
+}
+
+type stubEpochs struct{ err error }
+
+func (s stubEpochs) ValidateEpoch(string, uint64) error { return s.err }
+
+func TestWorkerCallsCheckRegistration(t *testing.T) {
+	svc, mr := newLeaderService()
+	ctx := context.Background()
+	submit(t, svc, "t-1", taskpb.TaskType_TASK_TYPE_GENERIC)
+
+	if r, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "ghost"}); r.Ok {
+		t.Error("an unregistered worker must not get work")
+	}
+
+	registerWorker(t, mr, "w-1")
+	svc.SetEpochValidator(stubEpochs{fmt.Errorf("%w: got 1, current 2", agent.ErrStaleEpoch)})
+	if r, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1", Epoch: 1}); r.Ok || !r.Fenced {
+		t.Errorf("stale epoch = %+v, want fenced", r)
+	}
+
+	svc.SetEpochValidator(stubEpochs{})
+	cmd, _ := internalraft.MarshalCommand(internalraft.CmdQuarantineWorker,
+		internalraft.QuarantineWorkerPayload{ID: "w-1", Reason: "flapping", Until: time.Now().Add(time.Hour)})
+	if _, err := mr.ApplyCommand(cmd, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"}); r.Ok {
+		t.Error("a quarantined worker must not get work")
+	}
+	if task := mr.fsm.GetTask("t-1"); task.State != internalraft.TaskPending {
+		t.Errorf("refused polls must leave the task pending: %+v", task)
+	}
+}
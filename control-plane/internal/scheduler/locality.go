@@ -0,0 +1,265 @@
+package scheduler
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// PolicyLocality is the name of the data-locality placement policy.
+const PolicyLocality = "locality"
+
+const bytesPerGB = 1 << 30
+
+// CloudPair is a direction between two clouds, e.g. {"aws", "gcp"} for data
+// read in GCP from an object stored in AWS.
+type CloudPair struct{ From, To string }
+
+// DataLocation says that objects whose URI starts with Prefix live in Cloud.
+type DataLocation struct {
+	Prefix string
+	Cloud  string
+}
+
+// LocalityConfig describes where task inputs live and what moving them
+// between clouds costs.
+type LocalityConfig struct {
+	// Locations maps input URIs to clouds; the longest matching prefix wins.
+	// Inputs matching none are treated as free to read from anywhere.
+	Locations []DataLocation
+	// EgressPerGB is the cost of moving one GB in a direction. A missing
+	// pair uses the reverse direction if present, otherwise DefaultEgressPerGB.
+	// Traffic within a cloud is free.
+	EgressPerGB        map[CloudPair]float64
+	DefaultEgressPerGB float64
+	// Wait is how long the locality policy holds a task for a worker in its
+	// data's cloud before placing it remotely.
+	Wait time.Duration
+}
+
+// Locality estimates the cross-cloud traffic of running a task in a cloud.
+type Locality struct {
+	cfg LocalityConfig
+	rtt *RTTTable
+}
+
+// NewLocality returns a Locality for cfg. rtt supplies inter-cloud round
+// trips for placement decisions; it may be nil.
+func NewLocality(cfg LocalityConfig, rtt *RTTTable) *Locality {
+	if rtt == nil {
+		rtt = NewRTTTable(nil)
+	}
+	slices.SortFunc(cfg.Locations, func(a, b DataLocation) int { return cmp.Compare(len(b.Prefix), len(a.Prefix)) })
+	return &Locality{cfg: cfg, rtt: rtt}
+}
+
+// CloudOf returns the cloud holding uri, or "" if unknown.
+func (l *Locality) CloudOf(uri string) string {
+	for _, loc := range l.cfg.Locations {
+		if strings.HasPrefix(uri, loc.Prefix) {
+			return loc.Cloud
+		}
+	}
+	return ""
+}
+
+// EgressPerGB returns the cost of moving one GB from one cloud to another.
+func (l *Locality) EgressPerGB(from, to string) float64 {
+	if from == to || from == "" || to == "" {
+		return 0
+	}
+	if c, ok := l.cfg.EgressPerGB[CloudPair{from, to}]; ok {
+		return c
+	}
+	if c, ok := l.cfg.EgressPerGB[CloudPair{to, from}]; ok {
+		return c
+	}
+	return l.cfg.DefaultEgressPerGB
+}
+
+// Egress is the estimated cost of running a task in one cloud.
+type Egress struct {
+	Bytes int64         // input bytes read from other clouds
+	Cost  float64       // their egress cost
+	RTT   time.Duration // slowest round trip to a remote input; 0 if all local
+	Flows map[CloudPair]int64
+}
+
+// Estimate returns the traffic t causes when run in cloud. Inputs without a
+// declared size count as zero bytes but still add their round trip.
+func (l *Locality) Estimate(t *internalraft.Task, cloud string) Egress {
+	var e Egress
+	for i, uri := range t.InputURIs {
+		from := l.CloudOf(uri)
+		if from == "" || from == cloud {
+			continue
+		}
+		if rtt, ok := l.rtt.Get(from, cloud); ok {
+			e.RTT = max(e.RTT, rtt)
+		}
+		if i >= len(t.InputBytes) || t.InputBytes[i] == 0 {
+			continue
+		}
+		n := t.InputBytes[i]
+		e.Bytes += n
+		e.Cost += float64(n) / bytesPerGB * l.EgressPerGB(from, cloud)
+		if e.Flows == nil {
+			e.Flows = make(map[CloudPair]int64)
+		}
+		e.Flows[CloudPair{from, cloud}] += n
+	}
+	return e
+}
+
+// home returns the cloud holding most of t's input, weighting inputs by
+// declared size (or 1 when undeclared), or "" if no input has a known cloud.
+func (l *Locality) home(t *internalraft.Task) string {
+	weight := make(map[string]int64)
+	for i, uri := range t.InputURIs {
+		cloud := l.CloudOf(uri)
+		if cloud == "" {
+			continue
+		}
+		w := int64(1)
+		if i < len(t.InputBytes) && t.InputBytes[i] > 0 {
+			w = t.InputBytes[i]
+		}
+		weight[cloud] += w
+	}
+	var best string
+	for cloud, w := range weight {
+		if best == "" || w > weight[best] || (w == weight[best] && cloud < best) {
+			best = cloud
+		}
+	}
+	return best
+}
+
+// LocalityPolicy places a task on the least-loaded worker in the cloud that
+// holds most of its input. If there is none, it waits up to the configured
+// Wait for one before choosing the cheapest remote worker by estimated egress
+// cost, then round-trip time.
+type LocalityPolicy struct {
+	loc *Locality
+	now func() time.Time
+}
+
+func (*LocalityPolicy) Name() string { return PolicyLocality }
+
+func (p *LocalityPolicy) Place(t *internalraft.Task, candidates []Candidate) (string, string) {
+	if len(candidates) == 0 {
+		return "", "no eligible worker"
+	}
+	home := p.loc.home(t)
+	if home == "" {
+		c, _ := leastLoaded(candidates)
+		return c.Worker.ID, fmt.Sprintf("input location unknown; fewest running tasks (%d)", c.Running)
+	}
+	var local []Candidate
+	for _, c := range candidates {
+		if c.Worker.CloudTag == home {
+			local = append(local, c)
+		}
+	}
+	if c, ok := leastLoaded(local); ok {
+		e := p.loc.Estimate(t, home)
+		return c.Worker.ID, fmt.Sprintf("data in %s; local with %d running tasks; est. egress %s",
+			home, c.Running, formatEgress(e))
+	}
+
+	since := t.PendingSince
+	if since.IsZero() {
+		since = t.CreatedAt
+	}
+	if waited := p.now().Sub(since); waited < p.loc.cfg.Wait {
+		return "", fmt.Sprintf("data in %s; waiting for a worker there (%s of %s)",
+			home, waited.Round(time.Second), p.loc.cfg.Wait)
+	}
+
+	type option struct {
+		c Candidate
+		e Egress
+	}
+	opts := make([]option, len(candidates))
+	for i, c := range candidates {
+		opts[i] = option{c, p.loc.Estimate(t, c.Worker.CloudTag)}
+	}
+	best := slices.MinFunc(opts, func(a, b option) int {
+		return cmp.Or(cmp.Compare(a.e.Cost, b.e.Cost), cmp.Compare(a.e.RTT, b.e.RTT),
+			cmp.Compare(a.c.Running, b.c.Running), cmp.Compare(a.c.Worker.ID, b.c.Worker.ID))
+	})
+	return best.c.Worker.ID, fmt.Sprintf("data in %s; no eligible worker there after %s; cheapest remote in %s, est. egress %s",
+		home, p.loc.cfg.Wait, best.c.Worker.CloudTag, formatEgress(best.e))
+}
+
+func formatEgress(e Egress) string {
+	return fmt.Sprintf("%s, $%.4f, rtt %s", formatBytes(e.Bytes), e.Cost, e.RTT.Round(time.Millisecond))
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= bytesPerGB:
+		return fmt.Sprintf("%.2fGB", float64(n)/bytesPerGB)
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
+
+// ParseDataLocations parses "prefix=cloud,prefix=cloud", e.g.
+// "s3://pipeline-data/aws/=aws,s3://pipeline-data/gcp/=gcp".
+func ParseDataLocations(s string) ([]DataLocation, error) {
+	var out []DataLocation
+	for _, entry := range splitList(s) {
+		prefix, cloud, ok := strings.Cut(entry, "=")
+		if !ok || prefix == "" || cloud == "" {
+			return nil, fmt.Errorf("invalid data location %q, want prefix=cloud", entry)
+		}
+		out = append(out, DataLocation{Prefix: prefix, Cloud: cloud})
+	}
+	return out, nil
+}
+
+// ParseCloudPairs parses "from:to=value,..." with parse applied to each value,
+// e.g. "aws:gcp=0.09" or "aws:gcp=50ms".
+func ParseCloudPairs[V any](s string, parse func(string) (V, error)) (map[CloudPair]V, error) {
+	out := make(map[CloudPair]V)
+	for _, entry := range splitList(s) {
+		pair, value, ok := strings.Cut(entry, "=")
+		from, to, ok2 := strings.Cut(pair, ":")
+		if !ok || !ok2 || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid cloud pair entry %q, want from:to=value", entry)
+		}
+		v, err := parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("cloud pair %s: %w", pair, err)
+		}
+		out[CloudPair{from, to}] = v
+	}
+	return out, nil
+}
+
+// ParseCost parses a non-negative cost per GB.
+func ParseCost(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err == nil && v < 0 {
+		err = fmt.Errorf("negative cost %g", v)
+	}
+	return v, err
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"context"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/clock"
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+const gb = 1 << 30
+
+func testLocality(t *testing.T) *Locality {
+	t.Helper()
+	locs, err := ParseDataLocations("s3://data/=aws, s3://data/gcp/=gcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	costs, err := ParseCloudPairs("aws:gcp=0.10,gcp:azure=0.20", ParseCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rtts, err := ParseCloudPairs("aws:gcp=50ms", time.ParseDuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewLocality(LocalityConfig{
+		Locations:          locs,
+		EgressPerGB:        costs,
+		DefaultEgressPerGB: 0.05,
+		Wait:               time.Minute,
+	}, NewRTTTable(rtts))
+}
+
+func TestLocalityEstimate(t *testing.T) {
+	loc := testLocality(t)
+	if got := loc.CloudOf("s3://data/gcp/part-0"); got != "gcp" {
+		t.Errorf("longest prefix should win, got %q", got)
+	}
+	for _, tc := range []struct {
+		from, to string
+		want     float64
+	}{
+		{"aws", "aws", 0}, {"aws", "gcp", 0.10}, {"gcp", "aws", 0.10}, {"aws", "azure", 0.05},
+	} {
+		if got := loc.EgressPerGB(tc.from, tc.to); got != tc.want {
+			t.Errorf("EgressPerGB(%s, %s) = %g, want %g", tc.from, tc.to, got, tc.want)
+		}
+	}
+
+	task := &internalraft.Task{
+		InputURIs:  []string{"s3://data/a", "s3://data/gcp/b", "file:///tmp/c"},
+		InputBytes: []int64{2 * gb, gb, gb},
+	}
+	e := loc.Estimate(task, "gcp")
+	if e.Bytes != 2*gb || math.Abs(e.Cost-0.20) > 1e-9 || e.RTT != 50*time.Millisecond {
+		t.Errorf("estimate in gcp = %+v", e)
+	}
+	if e := loc.Estimate(task, "azure"); e.Bytes != 3*gb || math.Abs(e.Cost-(2*0.05+0.20)) > 1e-9 {
+		t.Errorf("estimate in azure = %+v", e)
+	}
+	if home := loc.home(task); home != "aws" {
+		t.Errorf("home = %q, want the cloud holding most bytes", home)
+	}
+
+	for _, bad := range []string{"aws:gcp", "aws=0.1", "aws:gcp=-1", "aws:gcp=x"} {
+		if _, err := ParseCloudPairs(bad, ParseCost); err == nil {
+			t.Errorf("ParseCloudPairs(%q) should fail", bad)
+		}
+	}
+}
+
+func TestLocalityPolicyWaitsThenFallsBack(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	p := &LocalityPolicy{loc: testLocality(t), now: func() time.Time { return now }}
+	task := &internalraft.Task{
+		ID: "t", InputURIs: []string{"s3://data/a"}, InputBytes: []int64{gb}, PendingSince: now,
+	}
+	cands := []Candidate{
+		candidate("w-azure", "azure", 0, internalraft.Resources{}, internalraft.Resources{}),
+		candidate("w-gcp", "gcp", 3, internalraft.Resources{}, internalraft.Resources{}),
+	}
+
+	if id, reason := p.Place(task, cands); id != "" || !strings.Contains(reason, "waiting") {
+		t.Fatalf("with no aws worker the task should wait, got %q (%s)", id, reason)
+	}
+	now = now.Add(2 * time.Minute)
+	// Both remote clouds read the same GB; azure is cheaper (default 0.05 vs 0.10).
+	if id, reason := p.Place(task, cands); id != "w-azure" || !strings.Contains(reason, "cheapest remote") {
+		t.Errorf("fallback placed on %q (%s), want w-azure", id, reason)
+	}
+
+	cands = append(cands, candidate("w-aws", "aws", 5, internalraft.Resources{}, internalraft.Resources{}))
+	if id, _ := p.Place(task, cands); id != "w-aws" {
+		t.Errorf("a local worker should win however busy, got %q", id)
+	}
+}
+
+func TestJobEgressReport(t *testing.T) {
+	svc, mr := newLeaderService()
+	ctx := context.Background()
+	svc.SetLocality(testLocality(t))
+	registerCapacity(t, mr, "w-gcp", "gcp", 0)
+
+	for _, id := range []string{"m-0", "m-1"} {
+		if resp, _ := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{
+			TaskId: id, JobId: "job-1", Type: taskpb.TaskType_TASK_TYPE_MAP,
+			InputUris: []string{"s3://data/" + id}, InputBytes: []int64{gb},
+		}); !resp.Ok {
+			t.Fatalf("submit %s: %+v", id, resp)
+		}
+	}
+	if resp, _ := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{
+		Type: taskpb.TaskType_TASK_TYPE_MAP, InputUris: []string{"s3://data/x"}, InputBytes: []int64{1, 2},
+	}); resp.Ok {
+		t.Error("input sizes must match inputs")
+	}
+	// Pulled by a GCP worker: each task reads 1GB from AWS.
+	for range 2 {
+		if resp, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-gcp"}); resp.Task == nil {
+			t.Fatal("expected a task")
+		}
+	}
+
+	rep, err := svc.GetJobEgress(ctx, &taskpb.GetJobEgressRequest{JobId: "job-1"})
+	if err != nil || !rep.Ok {
+		t.Fatalf("GetJobEgress = %+v, %v", rep, err)
+	}
+	if rep.Tasks != 2 || rep.EgressBytes != 2*gb || math.Abs(rep.EgressCost-0.20) > 1e-9 || len(rep.ByTask) != 2 {
+		t.Errorf("job egress = %+v", rep)
+	}
+	if rep, _ := svc.GetJobEgress(ctx, &taskpb.GetJobEgressRequest{JobId: "nope"}); rep.Ok {
+		t.Error("unknown job should not be ok")
+	}
+}
+
+func TestRTTProbeReplacesConfigured(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	table := NewRTTTable(map[CloudPair]time.Duration{{"aws", "gcp"}: time.Hour})
+	sim := clock.NewSim(1, time.Unix(1_700_000_000, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ProbeRTT(ctx, sim, table, "aws", map[string]string{"aws": "unused", "gcp": lis.Addr().String()}, time.Second)
+	sim.RunFor(3 * time.Second)
+
+	if d, ok := table.Get("gcp", "aws"); !ok || d >= time.Second {
+		t.Errorf("measured RTT should replace the configured hour, got %s", d)
+	}
+	if d, ok := table.Get("aws", "aws"); !ok || d != 0 {
+		t.Errorf("same-cloud RTT = %s, %v", d, ok)
+	}
+	if _, ok := table.Get("gcp", "azure"); ok {
+		t.Error("unknown pair should not be reported")
+	}
+}
@@ -0,0 +1,181 @@
+package scheduler
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// schedule is one pass of the push scheduling loop: every pending task with a
+// placement policy is offered to that policy and, if it picks a worker,
+// assigned there through Raft. The worker receives the task from its next
+// AcquireTask; if it never asks, the lease runs out and the task is placed
+// again. The pass then launches duplicates of stragglers (see speculate).
+func (s *Service) schedule() {
+	if s.raft.State() != hashiraft.Leader {
+		return
+	}
+	s.assignMu.Lock()
+	defer s.assignMu.Unlock()
+
+	all := s.tasks.Workers()
+	workers := make(map[string]*Candidate)
+	for id, w := range all {
+		if w.Status == internalraft.WorkerOnline {
+			workers[id] = &Candidate{Worker: w}
+		}
+	}
+	var pending, running []*internalraft.Task
+	live := make(map[string]bool) // pending or running tasks, for pruning
+	stats := make(stageDurations)
+	now := s.clock.Now()
+	for _, t := range s.tasks.Tasks() {
+		switch t.State {
+		case internalraft.TaskRunning:
+			live[t.ID] = true
+			running = append(running, t)
+			if c := workers[t.AssignedWorker]; c != nil {
+				c.Running++
+				c.Used = c.Used.Add(t.Resources)
+			}
+			if sp := t.Speculative; sp != nil && workers[sp.Worker] != nil {
+				c := workers[sp.Worker]
+				c.Running++
+				c.Used = c.Used.Add(t.Resources)
+			}
+		case internalraft.TaskSucceeded:
+			stats.add(t)
+		case internalraft.TaskPending:
+			live[t.ID] = true
+			if t.Placement != "" && !t.NotBefore.After(now) {
+				pending = append(pending, t)
+			}
+		}
+	}
+	slices.SortFunc(pending, func(a, b *internalraft.Task) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	stats.sort()
+	s.prune(live, all)
+
+	placed := 0
+	for _, t := range pending {
+		policy := s.policies[t.Placement]
+		if policy == nil {
+			s.unplaced(t, "unknown placement policy")
+			continue
+		}
+		id, reason := policy.Place(t, s.candidates(workers, t.Type))
+		if id == "" {
+			s.unplaced(t, reason)
+			continue
+		}
+		if _, err := s.assign(t, id, policy.Name()+": "+reason); err != nil {
+			// Most likely leadership was lost; the next pass (or leader) retries.
+			slog.Warn("task placement failed", "task_id", t.ID, "worker_id", id, "error", err)
+			break
+		}
+		c := workers[id]
+		c.Running++
+		c.Used = c.Used.Add(t.Resources)
+		placed++
+		s.mu.Lock()
+		delete(s.unplacedReasons, t.ID)
+		s.mu.Unlock()
+		metrics.TasksPlacedTotal.WithLabelValues(policy.Name()).Inc()
+		slog.Info("task placed", "task_id", t.ID, "job_id", t.JobID, "worker_id", id,
+			"policy", policy.Name(), "reason", reason, "attempt", t.Attempt)
+	}
+	s.speculate(running, workers, stats)
+	s.notify()
+}
+
+// candidates returns the workers able to run typ, sorted by ID. A worker that
+// has polled with a type filter is limited to those types; one that has not
+// polled yet is assumed to run anything.
+func (s *Service) candidates(workers map[string]*Candidate, typ string) []Candidate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Candidate, 0, len(workers))
+	for id, c := range workers {
+		if types := s.capabilities[id]; len(types) > 0 && !types[typ] {
+			continue
+		}
+		out = append(out, *c)
+	}
+	slices.SortFunc(out, func(a, b Candidate) int { return cmp.Compare(a.Worker.ID, b.Worker.ID) })
+	return out
+}
+
+// unplaced logs why t was left pending, once per change of reason so a task
+// waiting for capacity doesn't log every pass.
+func (s *Service) unplaced(t *internalraft.Task, reason string) {
+	s.mu.Lock()
+	changed := s.unplacedReasons[t.ID] != reason
+	s.unplacedReasons[t.ID] = reason
+	s.mu.Unlock()
+	if changed {
+		slog.Info("task not placed", "task_id", t.ID, "job_id", t.JobID, "policy", t.Placement,
+			"reason", reason)
+	}
+}
+
+// takePushed returns a task the scheduling loop assigned to workerID, or a
+// speculative attempt it launched there, that the worker has not been given
+// yet. Delivery is tracked only in memory, so after a leader change a worker
+// may be handed a task it is already running; the attempt number lets it
+// notice.
+func (s *Service) takePushed(workerID string, types map[string]bool) *internalraft.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.pushed[workerID]
+	defer func() { s.pushed[workerID] = q }()
+	for i := 0; i < len(q); {
+		p := q[i]
+		if len(types) > 0 && !types[p.typ] {
+			i++
+			continue
+		}
+		q = slices.Delete(q, i, i+1)
+		t := s.tasks.GetTask(p.id)
+		if t == nil || t.State != internalraft.TaskRunning || s.delivered[p.id] >= p.attempt {
+			continue
+		}
+		if sp := t.Speculative; sp != nil && sp.Worker == workerID && sp.Attempt == p.attempt {
+			s.delivered[p.id] = p.attempt
+			return speculativeView(t)
+		}
+		if t.AssignedWorker == workerID && t.Attempt == p.attempt {
+			s.delivered[p.id] = p.attempt
+			return t
+		}
+	}
+	return nil
+}
+
+// prune forgets delivery and placement state for tasks no longer live, and
+// the capabilities of workers that are gone.
+func (s *Service) prune(live map[string]bool, workers map[string]*internalraft.WorkerInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.capabilities {
+		if workers[id] == nil {
+			delete(s.capabilities, id)
+		}
+	}
+	for id := range s.delivered {
+		if !live[id] {
+			delete(s.delivered, id)
+		}
+	}
+	for id := range s.unplacedReasons {
+		if !live[id] {
+			delete(s.unplacedReasons, id)
+		}
+	}
+}
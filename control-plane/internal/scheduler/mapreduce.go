@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// maxPartitions caps a MapReduce job's reduce fan-out.
+const maxPartitions = 1024
+
+// SubmitMapReduce creates a MapReduce job with one map task per input split:
+// each entry of input_uris, or each of splits for maps reading several objects.
+// Its reduce tasks are created by the FSM as map outputs are reported.
+func (s *Service) SubmitMapReduce(ctx context.Context, req *taskpb.SubmitMapReduceRequest) (*taskpb.SubmitJobResponse, error) {
+	if s.raft.State() != hashiraft.Leader {
+		return &taskpb.SubmitJobResponse{Ok: false, LeaderAddr: s.leaderAddr()}, nil
+	}
+	job, maps, err := s.buildMapReduce(req)
+	if err != nil {
+		return &taskpb.SubmitJobResponse{Ok: false, Error: err.Error()}, nil
+	}
+
+	resp, err := s.apply(internalraft.CmdSubmitJob, internalraft.SubmitJobPayload{Job: job, Tasks: maps})
+	if err != nil {
+		return nil, err
+	}
+	j, _ := resp.(*internalraft.Job)
+	s.notify()
+	metrics.TasksSubmittedTotal.WithLabelValues(internalraft.TaskMap).Add(float64(len(maps)))
+	metrics.JobsSubmittedTotal.Inc()
+	slog.Info("mapreduce job submitted", "job_id", job.ID, "maps", len(maps), "partitions", req.Partitions,
+		"failure_policy", job.FailurePolicy)
+	return &taskpb.SubmitJobResponse{Ok: true, Job: s.jobToProto(j)}, nil
+}
+
+// buildMapReduce validates req and turns it into the submit_job payload.
+func (s *Service) buildMapReduce(req *taskpb.SubmitMapReduceRequest) (internalraft.Job, []internalraft.Task, error) {
+	policy, ok := jobPolicies[req.FailurePolicy]
+	if !ok {
+		return internalraft.Job{}, nil, fmt.Errorf("unknown failure policy %v", req.FailurePolicy)
+	}
+	job := internalraft.Job{
+		ID:            req.JobId,
+		Type:          internalraft.JobMapReduce,
+		FailurePolicy: policy,
+		CreatedAt:     s.clock.Now().UTC(),
+		Stages: []*internalraft.Stage{
+			{Name: internalraft.StageMap},
+			{Name: internalraft.StageReduce, DependsOn: []string{internalraft.StageMap}},
+		},
+	}
+	if job.ID == "" {
+		job.ID = newJobID()
+	} else if s.tasks.GetJob(job.ID) != nil {
+		return job, nil, fmt.Errorf("job %q already exists", job.ID)
+	}
+	splits := req.Splits
+	switch {
+	case len(splits) > 0 && len(req.InputUris) > 0:
+		return job, nil, fmt.Errorf("set either input_uris or splits, not both")
+	case len(req.InputBytes) > 0 && len(req.InputBytes) != len(req.InputUris):
+		return job, nil, fmt.Errorf("got %d input sizes for %d inputs", len(req.InputBytes), len(req.InputUris))
+	case len(splits) == 0:
+		for i, uri := range req.InputUris {
+			sp := &taskpb.MapSplitSpec{InputUris: []string{uri}}
+			if len(req.InputBytes) > 0 {
+				sp.InputBytes = []int64{req.InputBytes[i]}
+			}
+			splits = append(splits, sp)
+		}
+	}
+	switch {
+	case len(splits) == 0:
+		return job, nil, fmt.Errorf("a MapReduce job needs at least one input split")
+	case req.Partitions == 0 || req.Partitions > maxPartitions:
+		return job, nil, fmt.Errorf("partitions must be between 1 and %d", maxPartitions)
+	}
+	job.MapReduce = &internalraft.MapReduce{Partitions: int(req.Partitions), OutputPrefix: req.OutputPrefix}
+
+	maps := make([]internalraft.Task, len(splits))
+	for i, sp := range splits {
+		if len(sp.InputUris) == 0 {
+			return job, nil, fmt.Errorf("split %d has no inputs", i)
+		}
+		t, err := s.taskFromSpec(&taskpb.SubmitTaskRequest{
+			Type: taskpb.TaskType_TASK_TYPE_MAP, InputUris: sp.InputUris, InputBytes: sp.InputBytes,
+			Placement: req.Placement, CloudAffinity: req.CloudAffinity, CpuMillis: req.CpuMillis, MemoryMb: req.MemoryMb,
+			Retry: req.Retry, TimeoutMs: req.TimeoutMs, KillGraceMs: req.KillGraceMs,
+		})
+		if err != nil {
+			return job, nil, fmt.Errorf("split %d: %w", i, err)
+		}
+		t.ID = internalraft.MapTaskID(job.ID, i)
+		t.JobID = job.ID
+		t.Stage = internalraft.StageMap
+		t.Partitions = int(req.Partitions)
+		if s.tasks.GetTask(t.ID) != nil {
+			return job, nil, fmt.Errorf("task %q already exists", t.ID)
+		}
+		maps[i] = t
+		job.MapReduce.Maps = append(job.MapReduce.Maps, internalraft.MapSplit{
+			TaskID: t.ID, InputURIs: t.InputURIs, InputBytes: t.InputBytes,
+		})
+	}
+	// The reduces inherit the maps' placement fields, validated above.
+	job.MapReduce.Placement = maps[0].Placement
+	job.MapReduce.CloudAffinity = maps[0].CloudAffinity
+	job.MapReduce.Resources = maps[0].Resources
+	job.MapReduce.Retry = maps[0].Retry
+	job.MapReduce.Timeout, job.MapReduce.KillGrace = maps[0].Timeout, maps[0].KillGrace
+	return job, maps, nil
+}
+
+// mapOutputs checks the outputs reported for a task and converts them. Only
+// a MapReduce map task that succeeded may report outputs, and it must.
+func (s *Service) mapOutputs(req *taskpb.CompleteTaskRequest) ([]internalraft.Intermediate, error) {
+	t := s.tasks.GetTask(req.TaskId)
+	isMap := t != nil && t.Stage == internalraft.StageMap && t.Partitions > 0
+	if !isMap || !req.Succeeded {
+		if len(req.Outputs) > 0 {
+			return nil, fmt.Errorf("outputs are only accepted from a successful MapReduce map task")
+		}
+		return nil, nil
+	}
+	out := make([]internalraft.Intermediate, len(req.Outputs))
+	for i, o := range req.Outputs {
+		if o.Bytes < 0 {
+			return nil, fmt.Errorf("partition %d: negative size", o.Partition)
+		}
+		out[i] = internalraft.Intermediate{Partition: int(o.Partition), URI: o.Uri, Bytes: o.Bytes, Durable: o.Durable}
+	}
+	return out, internalraft.CheckMapOutputs(t.Partitions, out)
+}
+
+// mapReduceToProto reports per-partition shuffle progress and adds the
+// shuffle as a stage between map and reduce.
+func (s *Service) mapReduceToProto(j *internalraft.Job, out *taskpb.Job) {
+	mr := j.MapReduce
+	status := &taskpb.MapReduceStatus{
+		Maps:         uint32(len(mr.Maps)),
+		Partitions:   uint32(mr.Partitions),
+		OutputPrefix: mr.OutputPrefix,
+	}
+	shuffle := &taskpb.Stage{
+		Name:      "shuffle",
+		DependsOn: []string{internalraft.StageMap},
+		Tasks:     uint32(mr.Partitions),
+	}
+	for r := range mr.Partitions {
+		ps := &taskpb.PartitionStatus{Partition: uint32(r)}
+		for i := range mr.Maps {
+			if m := &mr.Maps[i]; m.Reported {
+				ps.MapOutputs++
+				if o, ok := m.Output(r); ok {
+					ps.Bytes += o.Bytes
+				}
+			}
+		}
+		if r < len(mr.Reduces) && mr.Reduces[r] != "" {
+			ps.ReduceTaskId = mr.Reduces[r]
+			if t := s.tasks.GetTask(ps.ReduceTaskId); t != nil {
+				ps.ReduceState = taskStateToProto(t.State)
+			}
+		}
+		if ps.MapOutputs == status.Maps {
+			shuffle.Succeeded++
+		}
+		status.ByPartition = append(status.ByPartition, ps)
+	}
+	switch {
+	case shuffle.Succeeded == shuffle.Tasks:
+		shuffle.State = taskpb.JobState_JOB_STATE_SUCCEEDED
+	case j.Finished():
+		shuffle.State = taskpb.JobState_JOB_STATE_FAILED
+	case slices.ContainsFunc(status.ByPartition, func(p *taskpb.PartitionStatus) bool { return p.MapOutputs > 0 }):
+		shuffle.State = taskpb.JobState_JOB_STATE_RUNNING
+	default:
+		shuffle.State = taskpb.JobState_JOB_STATE_BLOCKED
+	}
+	out.MapReduce = status
+	out.Stages = slices.Insert(out.Stages, 1, shuffle)
+}
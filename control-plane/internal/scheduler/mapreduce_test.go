@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// runMap acquires the next task on workerID, which must be a map, and
+// completes it with one output per partition.
+func runMap(t *testing.T, svc *Service, workerID string) *taskpb.Task {
+	t.Helper()
+	ctx := context.Background()
+	acq, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: workerID})
+	if acq.Task == nil || acq.Task.Type != taskpb.TaskType_TASK_TYPE_MAP {
+		t.Fatalf("expected a map task, got %+v", acq.Task)
+	}
+	req := &taskpb.CompleteTaskRequest{WorkerId: workerID, TaskId: acq.Task.TaskId, Attempt: acq.Task.Attempt, Succeeded: true}
+	for r := range acq.Task.Partitions {
+		req.Outputs = append(req.Outputs, &taskpb.IntermediateOutput{
+			Partition: r, Uri: fmt.Sprintf("%s://%s/%d", workerID, acq.Task.TaskId, r), Bytes: 5,
+		})
+	}
+	if resp, err := svc.CompleteTask(ctx, req); err != nil || !resp.Ok {
+		t.Fatalf("complete %s: %+v, %v", acq.Task.TaskId, resp, err)
+	}
+	return acq.Task
+}
+
+func TestMapReduceJob(t *testing.T) {
+	svc, mr := newLeaderService()
+	ctx := context.Background()
+	registerWorker(t, mr, "w-1")
+	registerWorker(t, mr, "w-2")
+
+	sub, err := svc.SubmitMapReduce(ctx, &taskpb.SubmitMapReduceRequest{
+		JobId: "wc", InputUris: []string{"s3://in/a", "s3://in/b", "s3://in/c"}, Partitions: 2,
+		OutputPrefix: "s3://out/wc/",
+	})
+	if err != nil || !sub.Ok {
+		t.Fatalf("SubmitMapReduce = %+v, %v", sub, err)
+	}
+	if j := sub.Job; j.Type != taskpb.JobType_JOB_TYPE_MAPREDUCE || len(j.Stages) != 3 || j.Stages[1].Name != "shuffle" ||
+		j.MapReduce.Maps != 3 {
+		t.Fatalf("submitted job = %+v", j)
+	}
+
+	// A map may not succeed without its outputs.
+	acq, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+	if resp, _ := svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1", TaskId: acq.Task.TaskId,
+		Attempt: acq.Task.Attempt, Succeeded: true}); resp.Ok {
+		t.Fatal("a map without outputs should be refused")
+	}
+	svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1", TaskId: acq.Task.TaskId, Attempt: acq.Task.Attempt,
+		Succeeded: true, Outputs: []*taskpb.IntermediateOutput{{Partition: 0, Uri: "w-1://a/0"}, {Partition: 1}}})
+	runMap(t, svc, "w-1")
+
+	got, _ := svc.GetJob(ctx, &taskpb.GetJobRequest{JobId: "wc"})
+	if p := got.Job.MapReduce.ByPartition[0]; p.MapOutputs != 2 || p.ReduceTaskId != "" {
+		t.Fatalf("partition 0 before the last map = %+v", p)
+	}
+	runMap(t, svc, "w-2")
+
+	got, _ = svc.GetJob(ctx, &taskpb.GetJobRequest{JobId: "wc"})
+	shuffle, p1 := got.Job.Stages[1], got.Job.MapReduce.ByPartition[1]
+	if shuffle.State != taskpb.JobState_JOB_STATE_SUCCEEDED || p1.ReduceTaskId != internalraft.ReduceTaskID("wc", 1) ||
+		p1.ReduceState != taskpb.TaskState_TASK_STATE_PENDING {
+		t.Fatalf("after all maps: shuffle %+v, partition 1 %+v", shuffle, p1)
+	}
+	r1, _ := svc.GetTask(ctx, &taskpb.GetTaskRequest{TaskId: p1.ReduceTaskId})
+	if len(r1.Task.InputUris) != 2 || r1.Task.OutputUri != "s3://out/wc/part-00001" {
+		t.Errorf("reduce 1 = %+v (the first map wrote nothing for partition 1)", r1.Task)
+	}
+
+	// w-2 goes offline: only its map runs again, and the reduces wait for it.
+	cmd, _ := internalraft.MarshalCommand(internalraft.CmdUpdateWorkerStatus,
+		internalraft.UpdateWorkerStatusPayload{ID: "w-2", Status: internalraft.WorkerOffline})
+	if _, err := mr.ApplyCommand(cmd, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// The status change came from outside the task service; the next
+	// scheduling pass indexes the re-run map.
+	svc.schedule()
+	list, _ := svc.ListTasks(ctx, &taskpb.ListTasksRequest{JobId: "wc", State: taskpb.TaskState_TASK_STATE_PENDING})
+	if len(list.Tasks) != 1 || list.Tasks[0].Attempt != 2 || list.Tasks[0].Type != taskpb.TaskType_TASK_TYPE_MAP {
+		t.Fatalf("pending after losing w-2 = %+v", list.Tasks)
+	}
+	runMap(t, svc, "w-1")
+	for range 2 {
+		acq, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+		if acq.Task == nil || acq.Task.Type != taskpb.TaskType_TASK_TYPE_REDUCE {
+			t.Fatalf("expected a reduce, got %+v", acq.Task)
+		}
+		svc.CommitTaskOutput(ctx, &taskpb.CommitTaskOutputRequest{WorkerId: "w-1", TaskId: acq.Task.TaskId,
+			Attempt: acq.Task.Attempt})
+		svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1", TaskId: acq.Task.TaskId,
+			Attempt: acq.Task.Attempt, Succeeded: true})
+	}
+	got, _ = svc.GetJob(ctx, &taskpb.GetJobRequest{JobId: "wc"})
+	if got.Job.State != taskpb.JobState_JOB_STATE_SUCCEEDED {
+		t.Errorf("job at the end = %+v", got.Job)
+	}
+
+	if resp, _ := svc.SubmitMapReduce(ctx, &taskpb.SubmitMapReduceRequest{InputUris: []string{"x"}}); resp.Ok {
+		t.Error("zero partitions should be refused")
+	}
+}
+
+func TestMapReduceMultiInputSplits(t *testing.T) {
+	svc, _ := newLeaderService()
+	ctx := context.Background()
+
+	sub, err := svc.SubmitMapReduce(ctx, &taskpb.SubmitMapReduceRequest{
+		JobId: "mm", Partitions: 1,
+		Splits: []*taskpb.MapSplitSpec{
+			{InputUris: []string{"s3://m/A/0-0", "s3://m/B/0-0"}, InputBytes: []int64{8, 8}},
+			{InputUris: []string{"s3://m/A/0-1", "s3://m/B/1-0"}},
+		},
+	})
+	if err != nil || !sub.Ok {
+		t.Fatalf("SubmitMapReduce = %+v, %v", sub, err)
+	}
+	got, _ := svc.GetTask(ctx, &taskpb.GetTaskRequest{TaskId: "mm-map-00000"})
+	if len(got.Task.InputUris) != 2 || len(got.Task.InputBytes) != 2 {
+		t.Errorf("map 0 = %+v", got.Task)
+	}
+
+	for _, req := range []*taskpb.SubmitMapReduceRequest{
+		{Partitions: 1, InputUris: []string{"x"}, Splits: []*taskpb.MapSplitSpec{{InputUris: []string{"y"}}}},
+		{Partitions: 1, Splits: []*taskpb.MapSplitSpec{{}}},
+		{Partitions: 1, Splits: []*taskpb.MapSplitSpec{{InputUris: []string{"y"}, InputBytes: []int64{1, 2}}}},
+	} {
+		if resp, _ := svc.SubmitMapReduce(ctx, req); resp.Ok {
+			t.Errorf("SubmitMapReduce(%v) should be refused", req.Splits)
+		}
+	}
+}
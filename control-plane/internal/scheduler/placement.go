@@ -0,0 +1,182 @@
+package scheduler
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// Built-in placement policy names, as given in SubmitTaskRequest.placement.
+const (
+	PolicyRoundRobin    = "round-robin"
+	PolicyLeastLoaded   = "least-loaded"
+	PolicyBinPack       = "bin-pack"
+	PolicyCloudAffinity = "cloud-affinity"
+)
+
+// PlacementPolicy chooses a worker for a pending task. The scheduling loop
+// calls Place on the leader with every online worker able to run the task's
+// type, sorted by ID. It returns the chosen worker's ID and a short reason, or
+// an empty ID and the reason nothing was chosen. Place is only called from the
+// scheduling loop, never concurrently.
+type PlacementPolicy interface {
+	Name() string
+	Place(t *internalraft.Task, candidates []Candidate) (workerID, reason string)
+}
+
+// Candidate is an online worker as seen by a placement policy.
+type Candidate struct {
+	Worker  *internalraft.WorkerInfo
+	Running int                    // tasks currently assigned to it
+	Used    internalraft.Resources // sum of its running tasks' declared resources
+}
+
+// Free is the worker's declared capacity not used by running tasks.
+func (c Candidate) Free() internalraft.Resources {
+	return c.Worker.Capacity.Sub(c.Used)
+}
+
+// DefaultPolicies returns the built-in placement policies.
+func DefaultPolicies() []PlacementPolicy {
+	return []PlacementPolicy{&RoundRobin{}, LeastLoaded{}, BinPack{}, CloudAffinity{}}
+}
+
+// RoundRobin hands tasks to workers in worker-ID order, carrying on after the
+// last worker it chose.
+type RoundRobin struct {
+	mu   sync.Mutex
+	last string
+}
+
+func (*RoundRobin) Name() string { return PolicyRoundRobin }
+
+func (p *RoundRobin) Place(_ *internalraft.Task, candidates []Candidate) (string, string) {
+	if len(candidates) == 0 {
+		return "", "no eligible worker"
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i, _ := slices.BinarySearchFunc(candidates, p.last, func(c Candidate, id string) int {
+		return cmp.Compare(c.Worker.ID, id)
+	})
+	if i < len(candidates) && candidates[i].Worker.ID == p.last {
+		i++
+	}
+	pick := candidates[i%len(candidates)]
+	reason := fmt.Sprintf("next in turn after %q", p.last)
+	if p.last == "" {
+		reason = "first in turn"
+	}
+	p.last = pick.Worker.ID
+	return pick.Worker.ID, reason
+}
+
+// LeastLoaded picks the worker running the fewest tasks.
+type LeastLoaded struct{}
+
+func (LeastLoaded) Name() string { return PolicyLeastLoaded }
+
+func (LeastLoaded) Place(_ *internalraft.Task, candidates []Candidate) (string, string) {
+	c, ok := leastLoaded(candidates)
+	if !ok {
+		return "", "no eligible worker"
+	}
+	return c.Worker.ID, fmt.Sprintf("fewest running tasks (%d) of %d workers", c.Running, len(candidates))
+}
+
+// leastLoaded returns the candidate with the fewest running tasks, the first
+// by ID on a tie.
+func leastLoaded(candidates []Candidate) (Candidate, bool) {
+	if len(candidates) == 0 {
+		return Candidate{}, false
+	}
+	return slices.MinFunc(candidates, func(a, b Candidate) int {
+		return cmp.Or(cmp.Compare(a.Running, b.Running), cmp.Compare(a.Worker.ID, b.Worker.ID))
+	}), true
+}
+
+// BinPack places a task on the worker whose free capacity it fills most
+// tightly, keeping other workers empty for large tasks. Workers that have not
+// declared a capacity are never chosen; a dimension a worker leaves undeclared
+// neither limits nor scores it.
+type BinPack struct{}
+
+func (BinPack) Name() string { return PolicyBinPack }
+
+func (BinPack) Place(t *internalraft.Task, candidates []Candidate) (string, string) {
+	var (
+		pick     Candidate
+		best     float64
+		declared int
+	)
+	for _, c := range candidates {
+		if c.Worker.Capacity == (internalraft.Resources{}) {
+			continue
+		}
+		declared++
+		if !t.Resources.FitsIn(c.Worker.Capacity, c.Used) {
+			continue
+		}
+		if score := leftover(c.Worker.Capacity, c.Free().Sub(t.Resources)); pick.Worker == nil || score < best {
+			pick, best = c, score
+		}
+	}
+	switch {
+	case pick.Worker != nil:
+		return pick.Worker.ID, fmt.Sprintf("tightest fit for %s: %s free of %s",
+			t.Resources, pick.Free(), pick.Worker.Capacity)
+	case declared == 0:
+		return "", "no worker has declared its capacity"
+	default:
+		return "", fmt.Sprintf("%s fits none of %d workers with declared capacity", t.Resources, declared)
+	}
+}
+
+// leftover is the share of capacity that stays free, averaged over the
+// declared dimensions so workers declaring one or both compare fairly.
+func leftover(capacity, free internalraft.Resources) float64 {
+	var score float64
+	var dims int
+	if capacity.CPUMillis > 0 {
+		score += float64(free.CPUMillis) / float64(capacity.CPUMillis)
+		dims++
+	}
+	if capacity.MemoryMB > 0 {
+		score += float64(free.MemoryMB) / float64(capacity.MemoryMB)
+		dims++
+	}
+	return score / float64(dims)
+}
+
+// CloudAffinity prefers the least-loaded worker whose CloudTag matches the
+// task's CloudAffinity, falling back to the least-loaded worker anywhere so a
+// cloud without workers does not stall the job.
+type CloudAffinity struct{}
+
+func (CloudAffinity) Name() string { return PolicyCloudAffinity }
+
+func (CloudAffinity) Place(t *internalraft.Task, candidates []Candidate) (string, string) {
+	if t.CloudAffinity != "" {
+		var local []Candidate
+		for _, c := range candidates {
+			if c.Worker.CloudTag == t.CloudAffinity {
+				local = append(local, c)
+			}
+		}
+		if c, ok := leastLoaded(local); ok {
+			return c.Worker.ID, fmt.Sprintf("in cloud %q with %d running tasks", t.CloudAffinity, c.Running)
+		}
+	}
+	c, ok := leastLoaded(candidates)
+	if !ok {
+		return "", "no eligible worker"
+	}
+	if t.CloudAffinity == "" {
+		return c.Worker.ID, fmt.Sprintf("no affinity; fewest running tasks (%d)", c.Running)
+	}
+	return c.Worker.ID, fmt.Sprintf("no eligible worker in cloud %q; fell back to %q in %q",
+		t.CloudAffinity, c.Worker.ID, c.Worker.CloudTag)
+}
@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+func candidate(id, cloud string, running int, capacity, used internalraft.Resources) Candidate {
+	return Candidate{
+		Worker:  &internalraft.WorkerInfo{ID: id, CloudTag: cloud, Capacity: capacity},
+		Running: running,
+		Used:    used,
+	}
+}
+
+func TestPlacementPolicies(t *testing.T) {
+	res := func(cpu, mem int64) internalraft.Resources {
+		return internalraft.Resources{CPUMillis: cpu, MemoryMB: mem}
+	}
+	cands := []Candidate{
+		candidate("w-a", "aws", 3, res(4000, 8192), res(3000, 2048)),
+		candidate("w-b", "gcp", 1, res(2000, 4096), res(0, 0)),
+		candidate("w-c", "gcp", 2, internalraft.Resources{}, internalraft.Resources{}),
+	}
+	task := func(cloud string, r internalraft.Resources) *internalraft.Task {
+		return &internalraft.Task{ID: "t", CloudAffinity: cloud, Resources: r}
+	}
+
+	rr := &RoundRobin{}
+	var got []string
+	for range 4 {
+		id, _ := rr.Place(task("", res(0, 0)), cands)
+		got = append(got, id)
+	}
+	if strings.Join(got, ",") != "w-a,w-b,w-c,w-a" {
+		t.Errorf("round-robin order = %v", got)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		policy PlacementPolicy
+		task   *internalraft.Task
+		want   string
+	}{
+		{"least loaded", LeastLoaded{}, task("", res(0, 0)), "w-b"},
+		// w-a has 1000m/6144MB free, w-b 2000m/4096MB: w-a is the tighter fit.
+		{"bin-pack tightest", BinPack{}, task("", res(1000, 1024)), "w-a"},
+		{"bin-pack only fit", BinPack{}, task("", res(1500, 1024)), "w-b"},
+		{"bin-pack nothing fits", BinPack{}, task("", res(8000, 0)), ""},
+		{"affinity local", CloudAffinity{}, task("aws", res(0, 0)), "w-a"},
+		{"affinity least loaded in cloud", CloudAffinity{}, task("gcp", res(0, 0)), "w-b"},
+		{"affinity fallback", CloudAffinity{}, task("azure", res(0, 0)), "w-b"},
+	} {
+		id, reason := tc.policy.Place(tc.task, cands)
+		if id != tc.want || reason == "" {
+			t.Errorf("%s: placed on %q (%s), want %q", tc.name, id, reason, tc.want)
+		}
+	}
+	if id, _ := (LeastLoaded{}).Place(task("", res(0, 0)), nil); id != "" {
+		t.Errorf("placed on %q with no candidates", id)
+	}
+
+	// A worker declaring only CPU is not limited by memory it never declared.
+	cpuOnly := []Candidate{
+		candidate("w-d", "aws", 0, res(2000, 0), res(500, 0)),
+		candidate("w-e", "aws", 0, res(4000, 4096), res(0, 0)),
+	}
+	if id, reason := (BinPack{}).Place(task("", res(1500, 1024)), cpuOnly); id != "w-d" {
+		t.Errorf("bin-pack with an undeclared dimension: placed on %q (%s), want w-d", id, reason)
+	}
+	if id, _ := (BinPack{}).Place(task("", res(1600, 1024)), cpuOnly); id != "w-e" {
+		t.Errorf("bin-pack must still respect the declared dimension: placed on %q, want w-e", id)
+	}
+}
+
+func registerCapacity(t *testing.T, mr *mockRaft, id, cloud string, cpu int64) {
+	t.Helper()
+	cmd, err := internalraft.MarshalCommand(internalraft.CmdRegisterWorker, internalraft.RegisterWorkerPayload{
+		ID: id, CloudTag: cloud, Capacity: internalraft.Resources{CPUMillis: cpu},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mr.ApplyCommand(cmd, time.Second); err != nil {
+		t.Fatalf("register %s: %v", id, err)
+	}
+}
+
+func TestScheduleLoopPushesTasks(t *testing.T) {
+	svc, mr := newLeaderService()
+	ctx := context.Background()
+	registerCapacity(t, mr, "w-aws", "aws", 2000)
+	registerCapacity(t, mr, "w-gcp", "gcp", 2000)
+
+	for _, req := range []*taskpb.SubmitTaskRequest{
+		{TaskId: "big-1", Placement: PolicyBinPack, CpuMillis: 1500},
+		{TaskId: "big-2", Placement: PolicyBinPack, CpuMillis: 1500},
+		{TaskId: "big-3", Placement: PolicyBinPack, CpuMillis: 1500},
+		{TaskId: "near", Placement: PolicyCloudAffinity, CloudAffinity: "gcp"},
+		{TaskId: "pull"},
+	} {
+		req.Type = taskpb.TaskType_TASK_TYPE_GENERIC
+		if resp, err := svc.SubmitTask(ctx, req); err != nil || !resp.Ok {
+			t.Fatalf("submit %s: %+v, %v", req.TaskId, resp, err)
+		}
+	}
+	svc.schedule()
+
+	placed := map[string]string{}
+	for _, task := range mr.fsm.Tasks() {
+		placed[task.ID] = task.AssignedWorker
+		if task.AssignedWorker != "" && !strings.HasPrefix(task.PlacementReason, task.Placement+": ") {
+			t.Errorf("%s: placement reason %q", task.ID, task.PlacementReason)
+		}
+	}
+	if placed["big-1"] == placed["big-2"] || placed["big-1"] == "" || placed["big-2"] == "" {
+		t.Errorf("two 1500m tasks must land on different 2000m workers: %v", placed)
+	}
+	if placed["big-3"] != "" {
+		t.Errorf("a third 1500m task fits nowhere and must stay pending: %v", placed)
+	}
+	if placed["near"] != "w-gcp" || placed["pull"] != "" {
+		t.Errorf("unexpected placement %v", placed)
+	}
+
+	// The pushed task goes to its worker ahead of unplaced work, once.
+	resp, err := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-gcp"})
+	if err != nil || resp.Task.GetTaskId() == "pull" || resp.Task.GetPlacementReason() == "" {
+		t.Fatalf("first poll = %+v, %v", resp, err)
+	}
+	first := resp.Task.TaskId
+	resp, _ = svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-gcp"})
+	if id := resp.Task.GetTaskId(); id == first || id == "big-3" {
+		t.Errorf("second poll returned %q after %q", id, first)
+	}
+
+	if resp, _ := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{Type: taskpb.TaskType_TASK_TYPE_MAP, Placement: "random"}); resp.Ok {
+		t.Error("an unknown placement policy must be rejected")
+	}
+}
+
+func TestScheduleLoopRespectsPolledTypes(t *testing.T) {
+	svc, mr := newLeaderService()
+	ctx := context.Background()
+	registerWorker(t, mr, "w-1")
+	if resp, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{
+		WorkerId: "w-1", Types: []taskpb.TaskType{taskpb.TaskType_TASK_TYPE_MAP},
+	}); resp.Task != nil {
+		t.Fatalf("unexpected task %+v", resp.Task)
+	}
+	if resp, _ := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{
+		TaskId: "r-0", Type: taskpb.TaskType_TASK_TYPE_REDUCE, Placement: PolicyLeastLoaded,
+	}); !resp.Ok {
+		t.Fatalf("submit: %+v", resp)
+	}
+	svc.schedule()
+	if task := mr.fsm.GetTask("r-0"); task.State != internalraft.TaskPending {
+		t.Errorf("a reduce task was placed on a map-only worker: %+v", task)
+	}
+}
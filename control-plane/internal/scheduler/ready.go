@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// readyTask is a pending unplaced task AcquireTask may assign.
+type readyTask struct {
+	id        string
+	typ       string
+	createdAt time.Time
+	notBefore time.Time
+}
+
+// pushedAttempt is an attempt assigned to a worker by the scheduling loop, or
+// a speculative one launched there, that the worker has not been handed yet.
+type pushedAttempt struct {
+	id      string
+	typ     string
+	attempt int
+}
+
+// poller is an AcquireTask call waiting for work.
+type poller struct {
+	workerID string
+	types    map[string]bool // empty: any
+	wake     chan struct{}   // closed once there is work it can take
+}
+
+// notify rebuilds the ready and pushed indexes from the FSM and wakes the
+// waiting AcquireTask calls that can now take a task. Call it wherever tasks
+// may have become pending or been pushed; the scheduling loop also calls it
+// every pass, which picks up changes made outside this service (a worker
+// going offline, a new leader's first pass).
+func (s *Service) notify() {
+	s.notifyMu.Lock()
+	defer s.notifyMu.Unlock()
+
+	ready := make(map[string][]readyTask)
+	pushed := make(map[string][]pushedAttempt)
+	for _, t := range s.tasks.Tasks() {
+		switch t.State {
+		case internalraft.TaskPending:
+			if t.Placement == "" {
+				ready[t.Type] = append(ready[t.Type], readyTask{t.ID, t.Type, t.CreatedAt, t.NotBefore})
+			}
+		case internalraft.TaskRunning:
+			if t.Placement != "" {
+				pushed[t.AssignedWorker] = append(pushed[t.AssignedWorker], pushedAttempt{t.ID, t.Type, t.Attempt})
+			}
+			if sp := t.Speculative; sp != nil {
+				pushed[sp.Worker] = append(pushed[sp.Worker], pushedAttempt{t.ID, t.Type, sp.Attempt})
+			}
+		}
+	}
+	for _, q := range ready {
+		slices.SortFunc(q, func(a, b readyTask) int {
+			return cmp.Or(a.createdAt.Compare(b.createdAt), cmp.Compare(a.id, b.id))
+		})
+	}
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, q := range pushed {
+		// Attempt numbers only grow, so delivered holds the latest attempt
+		// handed out, speculative or not.
+		pushed[id] = slices.DeleteFunc(q, func(p pushedAttempt) bool { return s.delivered[p.id] >= p.attempt })
+	}
+	s.ready, s.pushed = ready, pushed
+	for p := range s.pollers {
+		if s.hasWorkLocked(p.workerID, p.types, now) {
+			close(p.wake)
+			delete(s.pollers, p)
+		}
+	}
+}
+
+// addPoller registers an AcquireTask call as waiting; notify closes its wake
+// channel once it has work. Register before looking, so work published
+// between the look and the wait still wakes the caller.
+func (s *Service) addPoller(workerID string, types map[string]bool) *poller {
+	p := &poller{workerID: workerID, types: types, wake: make(chan struct{})}
+	s.mu.Lock()
+	s.pollers[p] = struct{}{}
+	s.mu.Unlock()
+	return p
+}
+
+// removePoller unregisters p if notify has not already.
+func (s *Service) removePoller(p *poller) {
+	s.mu.Lock()
+	delete(s.pollers, p)
+	s.mu.Unlock()
+}
+
+// hasWorkLocked reports whether the indexes hold a task workerID can take.
+// Caller must hold s.mu.
+func (s *Service) hasWorkLocked(workerID string, types map[string]bool, now time.Time) bool {
+	for _, p := range s.pushed[workerID] {
+		if len(types) == 0 || types[p.typ] {
+			return true
+		}
+	}
+	_, ok := s.oldestReadyLocked(types, now)
+	return ok
+}
+
+// oldestReadyLocked returns the oldest indexed ready task of one of types
+// whose retry backoff has passed. Caller must hold s.mu.
+func (s *Service) oldestReadyLocked(types map[string]bool, now time.Time) (readyTask, bool) {
+	var pick readyTask
+	found := false
+	for typ, q := range s.ready {
+		if len(types) > 0 && !types[typ] {
+			continue
+		}
+		i := slices.IndexFunc(q, func(r readyTask) bool { return !r.notBefore.After(now) })
+		if i < 0 {
+			continue
+		}
+		if r := q[i]; !found || cmp.Or(r.createdAt.Compare(pick.createdAt), cmp.Compare(r.id, pick.id)) < 0 {
+			pick, found = r, true
+		}
+	}
+	return pick, found
+}
+
+// dropReady removes r from the ready index.
+func (s *Service) dropReady(r readyTask) {
+	s.mu.Lock()
+	s.ready[r.typ] = slices.DeleteFunc(s.ready[r.typ], func(q readyTask) bool { return q.id == r.id })
+	s.mu.Unlock()
+}
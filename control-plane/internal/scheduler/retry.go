@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// ListDeadLetters returns the tasks that failed for good, from the local FSM.
+func (s *Service) ListDeadLetters(ctx context.Context, req *taskpb.ListDeadLettersRequest) (*taskpb.ListDeadLettersResponse, error) {
+	resp := &taskpb.ListDeadLettersResponse{}
+	for _, d := range s.tasks.DeadLetters() {
+		if req.JobId != "" && d.JobID != req.JobId {
+			continue
+		}
+		out := &taskpb.DeadLetter{
+			TaskId:   d.TaskID,
+			JobId:    d.JobID,
+			Reason:   d.Reason,
+			Error:    d.Error,
+			Attempts: attemptsToProto(d.Attempts),
+			AtMs:     unixMilli(d.At),
+		}
+		for k, v := range taskTypes {
+			if v == d.Type {
+				out.Type = k
+			}
+		}
+		resp.DeadLetters = append(resp.DeadLetters, out)
+	}
+	return resp, nil
+}
+
+// retryPolicy resolves a submitted policy against the cluster default: zero
+// fields take the default's value.
+func (s *Service) retryPolicy(req *taskpb.RetryPolicy) (*internalraft.RetryPolicy, error) {
+	p := s.cfg.Retry
+	if req != nil {
+		if req.MaxAttempts > 0 {
+			p.MaxAttempts = int(req.MaxAttempts)
+		}
+		if req.InitialBackoffMs > 0 {
+			p.InitialBackoff = time.Duration(req.InitialBackoffMs) * time.Millisecond
+		}
+		if req.MaxBackoffMs > 0 {
+			p.MaxBackoff = time.Duration(req.MaxBackoffMs) * time.Millisecond
+		}
+		if req.Multiplier != 0 {
+			p.Multiplier = req.Multiplier
+		}
+		if req.Jitter != 0 {
+			p.Jitter = req.Jitter
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ── proto conversion ───────────────────────────────────────────────────────
+
+var errorClasses = map[taskpb.ErrorClass]string{
+	taskpb.ErrorClass_ERROR_CLASS_UNSPECIFIED: internalraft.ErrorRetryable,
+	taskpb.ErrorClass_ERROR_CLASS_RETRYABLE:   internalraft.ErrorRetryable,
+	taskpb.ErrorClass_ERROR_CLASS_FATAL:       internalraft.ErrorFatal,
+}
+
+var attemptOutcomes = map[string]taskpb.AttemptOutcome{
+	internalraft.AttemptSucceeded:    taskpb.AttemptOutcome_ATTEMPT_OUTCOME_SUCCEEDED,
+	internalraft.AttemptFailed:       taskpb.AttemptOutcome_ATTEMPT_OUTCOME_FAILED,
+	internalraft.AttemptLeaseExpired: taskpb.AttemptOutcome_ATTEMPT_OUTCOME_LEASE_EXPIRED,
+	internalraft.AttemptWorkerLost:   taskpb.AttemptOutcome_ATTEMPT_OUTCOME_WORKER_LOST,
+	internalraft.AttemptInterrupted:  taskpb.AttemptOutcome_ATTEMPT_OUTCOME_INTERRUPTED,
+	internalraft.AttemptCancelled:    taskpb.AttemptOutcome_ATTEMPT_OUTCOME_CANCELLED,
+	internalraft.AttemptSuperseded:   taskpb.AttemptOutcome_ATTEMPT_OUTCOME_SUPERSEDED,
+	internalraft.AttemptTimedOut:     taskpb.AttemptOutcome_ATTEMPT_OUTCOME_TIMED_OUT,
+}
+
+func errorClassFromProto(c taskpb.ErrorClass) (string, error) {
+	class, ok := errorClasses[c]
+	if !ok {
+		return "", fmt.Errorf("unknown error class %v", c)
+	}
+	return class, nil
+}
+
+func retryToProto(p *internalraft.RetryPolicy) *taskpb.RetryPolicy {
+	if p == nil {
+		return nil
+	}
+	return &taskpb.RetryPolicy{
+		MaxAttempts:      uint32(p.MaxAttempts),
+		InitialBackoffMs: uint32(p.InitialBackoff.Milliseconds()),
+		MaxBackoffMs:     uint32(p.MaxBackoff.Milliseconds()),
+		Multiplier:       p.Multiplier,
+		Jitter:           p.Jitter,
+	}
+}
+
+func attemptsToProto(attempts []internalraft.Attempt) []*taskpb.TaskAttempt {
+	var out []*taskpb.TaskAttempt
+	for _, a := range attempts {
+		pa := &taskpb.TaskAttempt{
+			Attempt:      uint32(a.Attempt),
+			Worker:       a.Worker,
+			StartedAtMs:  unixMilli(a.StartedAt),
+			FinishedAtMs: unixMilli(a.FinishedAt),
+			Outcome:      attemptOutcomes[a.Outcome],
+			Error:        a.Error,
+		}
+		switch a.ErrorClass {
+		case internalraft.ErrorRetryable:
+			pa.ErrorClass = taskpb.ErrorClass_ERROR_CLASS_RETRYABLE
+		case internalraft.ErrorFatal:
+			pa.ErrorClass = taskpb.ErrorClass_ERROR_CLASS_FATAL
+		}
+		out = append(out, pa)
+	}
+	return out
+}
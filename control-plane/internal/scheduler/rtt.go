@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/clock"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+)
+
+// rttSmoothing is the weight of a new sample in the moving average, so one
+// slow dial doesn't swing placement.
+const rttSmoothing = 0.2
+
+// RTTTable holds round-trip times between clouds. Measured values replace the
+// configured ones for the pairs a node can probe; the rest keep their
+// configured value. Round trips are symmetric.
+type RTTTable struct {
+	mu         sync.Mutex
+	configured map[CloudPair]time.Duration
+	measured   map[CloudPair]time.Duration
+}
+
+// NewRTTTable returns a table seeded with configured round trips.
+func NewRTTTable(configured map[CloudPair]time.Duration) *RTTTable {
+	return &RTTTable{configured: configured, measured: make(map[CloudPair]time.Duration)}
+}
+
+// Get returns the round trip between two clouds. Within a cloud it is zero.
+func (t *RTTTable) Get(a, b string) (time.Duration, bool) {
+	if a == b {
+		return 0, true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, p := range []CloudPair{{a, b}, {b, a}} {
+		if d, ok := t.measured[p]; ok {
+			return d, true
+		}
+	}
+	for _, p := range []CloudPair{{a, b}, {b, a}} {
+		if d, ok := t.configured[p]; ok {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// Observe folds a measured round trip into the moving average for the pair.
+func (t *RTTTable) Observe(a, b string, rtt time.Duration) {
+	t.mu.Lock()
+	p := CloudPair{a, b}
+	if prev, ok := t.measured[p]; ok {
+		rtt = prev + time.Duration(rttSmoothing*float64(rtt-prev))
+	}
+	t.measured[p] = rtt
+	t.mu.Unlock()
+	metrics.CloudRTTSeconds.WithLabelValues(a, b).Set(rtt.Seconds())
+}
+
+// ProbeRTT measures the round trip from this node's cloud to each target
+// cloud every interval on clk until ctx is done. targets maps a cloud to an
+// address there, normally another control-plane node's gRPC port; a TCP
+// connect takes one round trip.
+func ProbeRTT(ctx context.Context, clk clock.Clock, table *RTTTable, self string,
+	targets map[string]string, interval time.Duration) {
+	clk.Every(ctx, interval, func() {
+		for cloud, addr := range targets {
+			if cloud == self {
+				continue
+			}
+			d := net.Dialer{Timeout: 2 * time.Second}
+			start := time.Now()
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				slog.Debug("rtt probe failed", "cloud", cloud, "addr", addr, "error", err)
+				continue
+			}
+			rtt := time.Since(start)
+			_ = conn.Close()
+			table.Observe(self, cloud, rtt)
+		}
+	})
+}
@@ -1 +1,92 @@
+// Package scheduler tracks tasks in the replicated FSM and serves TaskService.
+//
+// Tasks reach workers two ways. By default a worker pulls: AcquireTask hands
+// it the oldest pending task it can run. A task submitted with a placement
+// policy is pushed instead: the leader's scheduling loop asks the named
+// PlacementPolicy for a worker, commits the assignment, and delivers it on
+// that worker's next AcquireTask.
+//
+// The same loop speculates on stragglers: a task running past a percentile of
+// its stage's run times gets a duplicate attempt on another worker, delivered
+// the same way. The first attempt to succeed or commit its output wins.
+//
+// The lease monitor enforces task deadlines: an attempt running past its
+// task's timeout is killed, which tells its worker to stop, and once the kill
+// grace has passed its lease is revoked and the task retried. Cancelled and
+// superseded attempts are killed the same way.
 package scheduler
+
+import (
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+const raftApplyTimeout = 2 * time.Second
+
+// Config holds the tunables for a Service.
+type Config struct {
+	// LeaseDuration is how long an assignment stays valid without a renewal.
+	LeaseDuration time.Duration
+	// LeaseCheckInterval is how often the leader looks for expired leases.
+	LeaseCheckInterval time.Duration
+	// MaxPollWait caps how long AcquireTask long-polls for a pending task.
+	MaxPollWait time.Duration
+	// ScheduleInterval is how often the leader places pushed tasks.
+	ScheduleInterval time.Duration
+	// Retry is the retry policy of tasks submitted without one, and fills in
+	// the fields a submitted policy leaves zero.
+	Retry internalraft.RetryPolicy
+	// SpeculationPercentile (0–100) is how far into its stage's run times a
+	// running task may get before a duplicate is launched; 0 disables
+	// speculation. SpeculationMinSamples is how many succeeded attempts a
+	// stage needs first.
+	SpeculationPercentile float64
+	SpeculationMinSamples int
+	// TaskTimeout is the execution deadline of each attempt of tasks
+	// submitted without one; 0 means none. KillGrace is how long a killed
+	// attempt has to stop before its lease is revoked, likewise.
+	TaskTimeout time.Duration
+	KillGrace   time.Duration
+}
+
+// DefaultConfig returns the configuration used by NewService.
+func DefaultConfig() Config {
+	return Config{
+		LeaseDuration:      30 * time.Second,
+		LeaseCheckInterval: time.Second,
+		MaxPollWait:        20 * time.Second,
+		ScheduleInterval:   time.Second,
+		Retry:              internalraft.DefaultRetryPolicy,
+
+		SpeculationPercentile: 90,
+		SpeculationMinSamples: 5,
+
+		KillGrace: internalraft.DefaultKillGrace,
+	}
+}
+
+// RaftApplier is the subset of RaftNode that the scheduler needs.
+type RaftApplier interface {
+	ApplyCommand(cmd []byte, timeout time.Duration) (interface{}, error)
+	State() hashiraft.RaftState
+}
+
+// TaskReader is the read side of PipelineFSM that the scheduler needs.
+type TaskReader interface {
+	GetTask(id string) *internalraft.Task
+	Tasks() []*internalraft.Task
+	GetWorker(id string) *internalraft.WorkerInfo
+	Workers() map[string]*internalraft.WorkerInfo
+	GetJob(id string) *internalraft.Job
+	Jobs() []*internalraft.Job
+	DeadLetters() []internalraft.DeadLetter
+}
+
+// EpochValidator checks a worker's registration epoch; AgentRegistry
+// implements it.
+type EpochValidator interface {
+	ValidateEpoch(workerID string, epoch uint64) error
+}
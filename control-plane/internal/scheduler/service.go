@@ -0,0 +1,355 @@
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/clock"
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// Service implements taskpb.TaskServiceServer. Writes go through Raft on the
+// leader; reads are answered from the local FSM.
+type Service struct {
+	taskpb.UnimplementedTaskServiceServer
+
+	cfg        Config
+	raft       RaftApplier
+	tasks      TaskReader
+	epochs     EpochValidator // nil: every epoch is accepted
+	leaderAddr func() string  // gRPC address of the current leader, for redirects
+	clock      clock.Clock
+	policies   map[string]PlacementPolicy
+	locality   *Locality
+
+	assignMu sync.Mutex // serialises picking and assigning a pending task
+	notifyMu sync.Mutex // serialises rebuilding the ready and pushed indexes
+
+	mu              sync.Mutex
+	ready           map[string][]readyTask     // task type → pending unplaced tasks, oldest first
+	pushed          map[string][]pushedAttempt // worker ID → attempts placed there, not yet handed out
+	pollers         map[*poller]struct{}       // AcquireTask calls waiting for work
+	capabilities    map[string]map[string]bool // worker ID → task types from its last AcquireTask; empty: any
+	delivered       map[string]int             // pushed task ID → latest attempt handed to a worker
+	unplacedReasons map[string]string          // task ID → why the last pass left it pending
+	lastLeaseCheck  time.Time                  // when expireLeases last looked for elapsed backoffs
+}
+
+// NewService returns a TaskService with DefaultConfig. leaderAddr is
+// consulted when a follower has to redirect a write.
+func NewService(raft RaftApplier, tasks TaskReader, leaderAddr func() string) *Service {
+	return NewServiceWithConfig(raft, tasks, leaderAddr, DefaultConfig())
+}
+
+// NewServiceWithConfig returns a TaskService with explicit tunables.
+func NewServiceWithConfig(raft RaftApplier, tasks TaskReader, leaderAddr func() string, cfg Config) *Service {
+	s := &Service{
+		cfg:             cfg,
+		raft:            raft,
+		tasks:           tasks,
+		leaderAddr:      leaderAddr,
+		clock:           clock.Real{},
+		policies:        make(map[string]PlacementPolicy),
+		ready:           make(map[string][]readyTask),
+		pushed:          make(map[string][]pushedAttempt),
+		pollers:         make(map[*poller]struct{}),
+		capabilities:    make(map[string]map[string]bool),
+		delivered:       make(map[string]int),
+		unplacedReasons: make(map[string]string),
+	}
+	for _, p := range DefaultPolicies() {
+		s.RegisterPolicy(p)
+	}
+	s.SetLocality(NewLocality(LocalityConfig{}, nil))
+	return s
+}
+
+// SetClock replaces the clock used to stamp tasks and leases; call before Start.
+func (s *Service) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetEpochValidator makes worker calls check the caller's registration epoch.
+func (s *Service) SetEpochValidator(v EpochValidator) {
+	s.epochs = v
+}
+
+// RegisterPolicy makes p selectable by name at submission, replacing any
+// policy of the same name; call before Start.
+func (s *Service) RegisterPolicy(p PlacementPolicy) {
+	s.policies[p.Name()] = p
+}
+
+// SetLocality sets where task inputs live and what cross-cloud reads cost,
+// for the locality policy and egress estimates; call before Start.
+func (s *Service) SetLocality(l *Locality) {
+	s.locality = l
+	s.RegisterPolicy(&LocalityPolicy{loc: l, now: func() time.Time { return s.clock.Now() }})
+}
+
+// Start runs the lease monitor and the scheduling loop until ctx is
+// cancelled. Both only act while this node is the leader.
+func (s *Service) Start(ctx context.Context) {
+	s.clock.Every(ctx, s.cfg.LeaseCheckInterval, s.expireLeases)
+	s.clock.Every(ctx, s.cfg.ScheduleInterval, s.schedule)
+}
+
+// SubmitTask creates a pending task.
+func (s *Service) SubmitTask(ctx context.Context, req *taskpb.SubmitTaskRequest) (*taskpb.SubmitTaskResponse, error) {
+	if s.raft.State() != hashiraft.Leader {
+		return &taskpb.SubmitTaskResponse{Ok: false, LeaderAddr: s.leaderAddr()}, nil
+	}
+	if len(req.DependsOn) > 0 {
+		return &taskpb.SubmitTaskResponse{Ok: false, Error: "depends_on is only valid in SubmitJob"}, nil
+	}
+	task, err := s.taskFromSpec(req)
+	if err != nil {
+		return &taskpb.SubmitTaskResponse{Ok: false, Error: err.Error()}, nil
+	}
+	if task.ID == "" {
+		task.ID = newTaskID()
+	} else if s.tasks.GetTask(task.ID) != nil {
+		return &taskpb.SubmitTaskResponse{Ok: false, Error: fmt.Sprintf("task %q already exists", task.ID)}, nil
+	}
+	id, typ := task.ID, task.Type
+
+	resp, err := s.apply(internalraft.CmdSubmitTask, internalraft.SubmitTaskPayload{Task: task})
+	if err != nil {
+		return nil, err
+	}
+	t, _ := resp.(*internalraft.Task)
+	s.notify()
+	metrics.TasksSubmittedTotal.WithLabelValues(typ).Inc()
+	slog.Info("task submitted", "task_id", id, "job_id", req.JobId, "type", typ, "placement", req.Placement)
+	return &taskpb.SubmitTaskResponse{Ok: true, Task: taskToProto(t)}, nil
+}
+
+// taskFromSpec validates a task submission and converts it, leaving the ID
+// as given.
+func (s *Service) taskFromSpec(req *taskpb.SubmitTaskRequest) (internalraft.Task, error) {
+	typ, ok := taskTypeFromProto(req.Type)
+	if !ok {
+		return internalraft.Task{}, fmt.Errorf("unknown task type %v", req.Type)
+	}
+	if req.Placement != "" && s.policies[req.Placement] == nil {
+		return internalraft.Task{}, fmt.Errorf("unknown placement policy %q", req.Placement)
+	}
+	if req.CpuMillis < 0 || req.MemoryMb < 0 {
+		return internalraft.Task{}, fmt.Errorf("declared resources must not be negative")
+	}
+	if len(req.InputBytes) > 0 && len(req.InputBytes) != len(req.InputUris) {
+		return internalraft.Task{}, fmt.Errorf("got %d input sizes for %d inputs", len(req.InputBytes), len(req.InputUris))
+	}
+	if slices.ContainsFunc(req.InputBytes, func(n int64) bool { return n < 0 }) {
+		return internalraft.Task{}, fmt.Errorf("input sizes must not be negative")
+	}
+	retry, err := s.retryPolicy(req.Retry)
+	if err != nil {
+		return internalraft.Task{}, err
+	}
+	return internalraft.Task{
+		ID:         req.TaskId,
+		JobID:      req.JobId,
+		Type:       typ,
+		InputURIs:  req.InputUris,
+		InputBytes: req.InputBytes,
+		OutputURI:  req.OutputUri,
+		CreatedAt:  s.clock.Now().UTC(),
+
+		Placement:     req.Placement,
+		CloudAffinity: req.CloudAffinity,
+		Resources:     internalraft.Resources{CPUMillis: req.CpuMillis, MemoryMB: req.MemoryMb},
+		Retry:         retry,
+		Timeout:       cmp.Or(time.Duration(req.TimeoutMs)*time.Millisecond, s.cfg.TaskTimeout),
+		KillGrace:     cmp.Or(time.Duration(req.KillGraceMs)*time.Millisecond, s.cfg.KillGrace),
+	}, nil
+}
+
+// GetTask returns one task from the local FSM.
+func (s *Service) GetTask(ctx context.Context, req *taskpb.GetTaskRequest) (*taskpb.GetTaskResponse, error) {
+	t := s.tasks.GetTask(req.TaskId)
+	if t == nil {
+		return &taskpb.GetTaskResponse{Ok: false, Error: fmt.Sprintf("task %q not found", req.TaskId)}, nil
+	}
+	return &taskpb.GetTaskResponse{Ok: true, Task: taskToProto(t)}, nil
+}
+
+// ListTasks returns the tasks in the local FSM matching the request's filters.
+func (s *Service) ListTasks(ctx context.Context, req *taskpb.ListTasksRequest) (*taskpb.ListTasksResponse, error) {
+	state, filterState := taskStateFromProto(req.State)
+	if req.State != taskpb.TaskState_TASK_STATE_UNSPECIFIED && !filterState {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown task state %v", req.State)
+	}
+	resp := &taskpb.ListTasksResponse{}
+	for _, t := range s.tasks.Tasks() {
+		if req.JobId != "" && t.JobID != req.JobId {
+			continue
+		}
+		if filterState && t.State != state {
+			continue
+		}
+		resp.Tasks = append(resp.Tasks, taskToProto(t))
+	}
+	return resp, nil
+}
+
+// GetJobEgress sums the estimated cross-cloud traffic of a job's tasks in the
+// local FSM.
+func (s *Service) GetJobEgress(ctx context.Context, req *taskpb.GetJobEgressRequest) (*taskpb.GetJobEgressResponse, error) {
+	if req.JobId == "" {
+		return &taskpb.GetJobEgressResponse{Ok: false, Error: "job_id is required"}, nil
+	}
+	resp := &taskpb.GetJobEgressResponse{Ok: true}
+	for _, t := range s.tasks.Tasks() {
+		if t.JobID != req.JobId {
+			continue
+		}
+		resp.Tasks++
+		resp.EgressBytes += t.EgressBytes
+		resp.EgressCost += t.EgressCost
+		if t.EgressBytes > 0 {
+			resp.ByTask = append(resp.ByTask, taskToProto(t))
+		}
+	}
+	if resp.Tasks == 0 {
+		return &taskpb.GetJobEgressResponse{Ok: false, Error: fmt.Sprintf("no tasks for job %q", req.JobId)}, nil
+	}
+	return resp, nil
+}
+
+// CancelTask cancels a task that has not finished yet. The workers of its
+// running attempts are told to stop.
+func (s *Service) CancelTask(ctx context.Context, req *taskpb.CancelTaskRequest) (*taskpb.CancelTaskResponse, error) {
+	if s.raft.State() != hashiraft.Leader {
+		return &taskpb.CancelTaskResponse{Ok: false, LeaderAddr: s.leaderAddr()}, nil
+	}
+	switch t := s.tasks.GetTask(req.TaskId); {
+	case t == nil:
+		return &taskpb.CancelTaskResponse{Ok: false, Error: fmt.Sprintf("task %q not found", req.TaskId)}, nil
+	case t.Finished():
+		return &taskpb.CancelTaskResponse{Ok: false, Error: fmt.Sprintf("task %q already %s", req.TaskId, t.State),
+			Task: taskToProto(t)}, nil
+	}
+
+	resp, err := s.apply(internalraft.CmdCancelTask, internalraft.CancelTaskPayload{
+		ID:          req.TaskId,
+		Reason:      req.Reason,
+		CancelledAt: s.clock.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	t, _ := resp.(*internalraft.Task)
+	metrics.TasksCancelledTotal.Inc()
+	slog.Info("task cancelled", "task_id", req.TaskId, "reason", req.Reason)
+	return &taskpb.CancelTaskResponse{Ok: true, Task: taskToProto(t)}, nil
+}
+
+func newTaskID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "task-" + hex.EncodeToString(b)
+}
+
+// ── proto conversion ───────────────────────────────────────────────────────
+
+var taskTypes = map[taskpb.TaskType]string{
+	taskpb.TaskType_TASK_TYPE_MAP:     internalraft.TaskMap,
+	taskpb.TaskType_TASK_TYPE_REDUCE:  internalraft.TaskReduce,
+	taskpb.TaskType_TASK_TYPE_GENERIC: internalraft.TaskGeneric,
+}
+
+var taskStates = map[taskpb.TaskState]string{
+	taskpb.TaskState_TASK_STATE_PENDING:   internalraft.TaskPending,
+	taskpb.TaskState_TASK_STATE_RUNNING:   internalraft.TaskRunning,
+	taskpb.TaskState_TASK_STATE_SUCCEEDED: internalraft.TaskSucceeded,
+	taskpb.TaskState_TASK_STATE_FAILED:    internalraft.TaskFailed,
+	taskpb.TaskState_TASK_STATE_CANCELLED: internalraft.TaskCancelled,
+	taskpb.TaskState_TASK_STATE_BLOCKED:   internalraft.TaskBlocked,
+}
+
+func taskTypeFromProto(t taskpb.TaskType) (string, bool) {
+	s, ok := taskTypes[t]
+	return s, ok
+}
+
+func taskStateFromProto(st taskpb.TaskState) (string, bool) {
+	s, ok := taskStates[st]
+	return s, ok
+}
+
+func taskToProto(t *internalraft.Task) *taskpb.Task {
+	if t == nil {
+		return nil
+	}
+	out := &taskpb.Task{
+		TaskId:           t.ID,
+		JobId:            t.JobID,
+		InputUris:        t.InputURIs,
+		OutputUri:        t.OutputURI,
+		Attempt:          uint32(t.Attempt),
+		AssignedWorker:   t.AssignedWorker,
+		Error:            t.Error,
+		CreatedAtMs:      unixMilli(t.CreatedAt),
+		StartedAtMs:      unixMilli(t.StartedAt),
+		FinishedAtMs:     unixMilli(t.FinishedAt),
+		LeaseExpiresAtMs: unixMilli(t.LeaseExpires),
+		Placement:        t.Placement,
+		CloudAffinity:    t.CloudAffinity,
+		CpuMillis:        t.Resources.CPUMillis,
+		MemoryMb:         t.Resources.MemoryMB,
+		PlacementReason:  t.PlacementReason,
+		InputBytes:       t.InputBytes,
+		EgressBytes:      t.EgressBytes,
+		EgressCost:       t.EgressCost,
+		Stage:            t.Stage,
+		DependsOn:        t.DependsOn,
+		Partitions:       uint32(t.Partitions),
+		Partition:        uint32(t.Partition),
+		State:            taskStateToProto(t.State),
+		Retry:            retryToProto(t.Retry),
+		Attempts:         attemptsToProto(t.Attempts),
+		Failures:         uint32(t.Failures),
+		NotBeforeMs:      unixMilli(t.NotBefore),
+		Speculative:      speculativeToProto(t.Speculative),
+		CommitAttempt:    uint32(t.CommitAttempt),
+		TimeoutMs:        uint32(t.Timeout.Milliseconds()),
+		KillGraceMs:      uint32(t.KillGrace.Milliseconds()),
+		Kills:            killsToProto(t.Kills),
+	}
+	for k, v := range taskTypes {
+		if v == t.Type {
+			out.Type = k
+		}
+	}
+	return out
+}
+
+func taskStateToProto(st string) taskpb.TaskState {
+	for k, v := range taskStates {
+		if v == st {
+			return k
+		}
+	}
+	return taskpb.TaskState_TASK_STATE_UNSPECIFIED
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
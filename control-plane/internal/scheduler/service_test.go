@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// mockRaft applies commands straight to an in-process FSM.
+type mockRaft struct {
+	isLeader bool
+	fsm      *internalraft.PipelineFSM
+	index    uint64
+}
+
+func (m *mockRaft) ApplyCommand(cmd []byte, _ time.Duration) (interface{}, error) {
+	m.index++
+	resp := m.fsm.Apply(&hashiraft.Log{Index: m.index, Type: hashiraft.LogCommand, Data: cmd})
+	if err, ok := resp.(error); ok {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *mockRaft) State() hashiraft.RaftState {
+	if m.isLeader {
+		return hashiraft.Leader
+	}
+	return hashiraft.Follower
+}
+
+func newLeaderService() (*Service, *mockRaft) {
+	mr := &mockRaft{isLeader: true, fsm: internalraft.NewPipelineFSM()}
+	return NewService(mr, mr.fsm, func() string { return "cp-aws-1:50051" }), mr
+}
+
+func TestSubmitGetListCancel(t *testing.T) {
+	svc, _ := newLeaderService()
+	ctx := context.Background()
+
+	sub, err := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{
+		JobId: "job-1", Type: taskpb.TaskType_TASK_TYPE_MAP,
+		InputUris: []string{"s3://in/part-0"}, OutputUri: "s3://out/map-0",
+	})
+	if err != nil || !sub.Ok {
+		t.Fatalf("SubmitTask = %+v, %v", sub, err)
+	}
+	task := sub.Task
+	if task.TaskId == "" || task.State != taskpb.TaskState_TASK_STATE_PENDING || task.Attempt != 1 ||
+		task.CreatedAtMs == 0 || task.Type != taskpb.TaskType_TASK_TYPE_MAP {
+		t.Fatalf("unexpected submitted task %+v", task)
+	}
+	if _, err := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{
+		TaskId: "r-0", JobId: "job-1", Type: taskpb.TaskType_TASK_TYPE_REDUCE,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{
+		TaskId: "g-0", JobId: "job-2", Type: taskpb.TaskType_TASK_TYPE_GENERIC,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := svc.GetTask(ctx, &taskpb.GetTaskRequest{TaskId: task.TaskId})
+	if !got.Ok || got.Task.InputUris[0] != "s3://in/part-0" {
+		t.Errorf("GetTask = %+v", got)
+	}
+	if got, _ := svc.GetTask(ctx, &taskpb.GetTaskRequest{TaskId: "nope"}); got.Ok {
+		t.Error("GetTask of an unknown task should fail")
+	}
+
+	list, _ := svc.ListTasks(ctx, &taskpb.ListTasksRequest{JobId: "job-1"})
+	if len(list.Tasks) != 2 {
+		t.Fatalf("ListTasks(job-1) returned %d tasks", len(list.Tasks))
+	}
+
+	cancel, err := svc.CancelTask(ctx, &taskpb.CancelTaskRequest{TaskId: "r-0", Reason: "operator"})
+	if err != nil || !cancel.Ok || cancel.Task.State != taskpb.TaskState_TASK_STATE_CANCELLED ||
+		cancel.Task.Error != "operator" || cancel.Task.FinishedAtMs == 0 {
+		t.Fatalf("CancelTask = %+v, %v", cancel, err)
+	}
+	if again, _ := svc.CancelTask(ctx, &taskpb.CancelTaskRequest{TaskId: "r-0"}); again.Ok {
+		t.Error("cancelling a finished task should fail")
+	}
+	list, _ = svc.ListTasks(ctx, &taskpb.ListTasksRequest{State: taskpb.TaskState_TASK_STATE_PENDING})
+	if len(list.Tasks) != 2 {
+		t.Errorf("ListTasks(pending) returned %d tasks, want 2", len(list.Tasks))
+	}
+}
+
+func TestSubmitTaskValidation(t *testing.T) {
+	svc, mr := newLeaderService()
+	ctx := context.Background()
+
+	if resp, _ := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{TaskId: "t-1"}); resp.Ok {
+		t.Error("a task without a type should be rejected")
+	}
+	req := &taskpb.SubmitTaskRequest{TaskId: "t-1", Type: taskpb.TaskType_TASK_TYPE_GENERIC}
+	if resp, _ := svc.SubmitTask(ctx, req); !resp.Ok {
+		t.Fatalf("SubmitTask: %s", resp.Error)
+	}
+	if resp, _ := svc.SubmitTask(ctx, req); resp.Ok {
+		t.Error("a duplicate task ID should be rejected")
+	}
+	if mr.index != 1 {
+		t.Errorf("rejected submissions must not reach Raft; %d commands applied", mr.index)
+	}
+}
+
+func TestFollowerRedirectsWrites(t *testing.T) {
+	svc, mr := newLeaderService()
+	mr.isLeader = false
+	ctx := context.Background()
+
+	sub, _ := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{Type: taskpb.TaskType_TASK_TYPE_MAP})
+	if sub.Ok || sub.LeaderAddr != "cp-aws-1:50051" {
+		t.Errorf("SubmitTask on follower = %+v", sub)
+	}
+	cancel, _ := svc.CancelTask(ctx, &taskpb.CancelTaskRequest{TaskId: "t-1"})
+	if cancel.Ok || cancel.LeaderAddr != "cp-aws-1:50051" {
+		t.Errorf("CancelTask on follower = %+v", cancel)
+	}
+	if mr.index != 0 {
+		t.Error("a follower must not apply writes")
+	}
+}
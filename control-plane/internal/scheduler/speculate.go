@@ -0,0 +1,223 @@
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/metrics"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// stageDurations holds how long the succeeded attempts of each stage ran,
+// keyed by stageKey. Build it with add, then sort before reading.
+type stageDurations map[string][]time.Duration
+
+// stageKey groups tasks whose run times are comparable: a job's stage, or
+// tasks of one type outside any stage.
+func stageKey(t *internalraft.Task) string {
+	return t.JobID + "/" + cmp.Or(t.Stage, t.Type)
+}
+
+// add records the run time of t's succeeded attempt, if t has one.
+func (d stageDurations) add(t *internalraft.Task) {
+	if t.State != internalraft.TaskSucceeded || len(t.Attempts) == 0 {
+		return
+	}
+	a := t.Attempts[len(t.Attempts)-1]
+	if a.Outcome != internalraft.AttemptSucceeded || a.StartedAt.IsZero() {
+		return
+	}
+	key := stageKey(t)
+	d[key] = append(d[key], a.FinishedAt.Sub(a.StartedAt))
+}
+
+func (d stageDurations) sort() {
+	for _, ds := range d {
+		slices.Sort(ds)
+	}
+}
+
+// percentile returns the p-th percentile (0–100, nearest rank) of key's run
+// times and how many there are.
+func (d stageDurations) percentile(key string, p float64) (time.Duration, int) {
+	ds := d[key]
+	if len(ds) == 0 {
+		return 0, 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(ds))))
+	return ds[min(max(rank, 1), len(ds))-1], len(ds)
+}
+
+// speculate launches a duplicate of each running task that has run past
+// SpeculationPercentile of its stage's succeeded attempts, on a worker other
+// than the straggler's. A task gets at most one duplicate per attempt, and
+// none once an attempt is committing its output. The duplicate is placed by
+// the task's placement policy, or the least-loaded worker for pulled tasks,
+// and delivered like a pushed task. It runs during the scheduling pass and
+// returns how many duplicates it launched.
+func (s *Service) speculate(running []*internalraft.Task, workers map[string]*Candidate, stats stageDurations) int {
+	if s.cfg.SpeculationPercentile <= 0 {
+		return 0
+	}
+	slices.SortFunc(running, func(a, b *internalraft.Task) int {
+		return cmp.Or(a.StartedAt.Compare(b.StartedAt), cmp.Compare(a.ID, b.ID))
+	})
+	now := s.clock.Now()
+	launched := 0
+	for _, t := range running {
+		if t.Speculative != nil || t.CommitAttempt != 0 || speculatedBefore(t) || !s.started(t) {
+			continue
+		}
+		threshold, n := stats.percentile(stageKey(t), s.cfg.SpeculationPercentile)
+		ran := now.Sub(t.StartedAt)
+		if n < max(s.cfg.SpeculationMinSamples, 1) || ran <= threshold {
+			continue
+		}
+		candidates := slices.DeleteFunc(s.candidates(workers, t.Type), func(c Candidate) bool {
+			return c.Worker.ID == t.AssignedWorker
+		})
+		policy := s.policies[t.Placement]
+		if policy == nil {
+			policy = LeastLoaded{}
+		}
+		id, reason := policy.Place(t, candidates)
+		if id == "" {
+			slog.Debug("straggler not speculated", "task_id", t.ID, "worker_id", t.AssignedWorker, "reason", reason)
+			continue
+		}
+		reason = fmt.Sprintf("speculative: ran %s, past p%g of %d (%s); %s: %s", ran.Round(time.Millisecond),
+			s.cfg.SpeculationPercentile, n, threshold.Round(time.Millisecond), policy.Name(), reason)
+		if err := s.launchSpeculative(t, id, reason); err != nil {
+			// Leadership was lost or the straggler finished meanwhile; the
+			// next pass looks again.
+			slog.Warn("speculative attempt failed to launch", "task_id", t.ID, "worker_id", id, "error", err)
+			break
+		}
+		c := workers[id]
+		c.Running++
+		c.Used = c.Used.Add(t.Resources)
+		launched++
+		metrics.SpeculativeAttemptsTotal.WithLabelValues("launched").Inc()
+		slog.Info("speculative attempt launched", "task_id", t.ID, "job_id", t.JobID, "straggler", t.AssignedWorker,
+			"worker_id", id, "reason", reason)
+	}
+	return launched
+}
+
+// launchSpeculative commits a duplicate of t's running attempt on workerID.
+func (s *Service) launchSpeculative(t *internalraft.Task, workerID, reason string) error {
+	var egress Egress
+	if w := s.tasks.GetWorker(workerID); w != nil {
+		egress = s.locality.Estimate(t, w.CloudTag)
+	}
+	now := s.clock.Now().UTC()
+	_, err := s.apply(internalraft.CmdSpeculateTask, internalraft.SpeculateTaskPayload{
+		ID:           t.ID,
+		Attempt:      t.Attempt,
+		WorkerID:     workerID,
+		AssignedAt:   now,
+		LeaseExpires: now.Add(s.cfg.LeaseDuration),
+		Reason:       reason,
+		EgressBytes:  egress.Bytes,
+		EgressCost:   egress.Cost,
+	})
+	if err != nil {
+		return err
+	}
+	for pair, n := range egress.Flows {
+		metrics.TaskEgressBytesTotal.WithLabelValues(pair.From, pair.To).Add(float64(n))
+	}
+	metrics.TaskEgressCostTotal.Add(egress.Cost)
+	return nil
+}
+
+// started reports whether t's running attempt reached its worker: a pushed
+// task not yet delivered is left to its lease.
+func (s *Service) started(t *internalraft.Task) bool {
+	if t.Placement == "" {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delivered[t.ID] >= t.Attempt
+}
+
+// speculatedBefore reports whether t's running attempt already had a
+// duplicate. Duplicates take the next attempt number, so any recorded
+// attempt past the running one was one.
+func speculatedBefore(t *internalraft.Task) bool {
+	return slices.ContainsFunc(t.Attempts, func(a internalraft.Attempt) bool { return a.Attempt > t.Attempt })
+}
+
+// speculativeView returns t as the worker running its speculative attempt
+// sees it.
+func speculativeView(t *internalraft.Task) *internalraft.Task {
+	v := *t
+	sp := t.Speculative
+	v.Attempt, v.AssignedWorker, v.PlacementReason = sp.Attempt, sp.Worker, sp.Reason
+	v.StartedAt, v.LeaseExpires = sp.StartedAt, sp.LeaseExpires
+	return &v
+}
+
+// speculationSettled counts the outcome of a race that winner ended, given
+// the task as it was before.
+func speculationSettled(before *internalraft.Task, winner uint32) {
+	if before == nil || before.Speculative == nil {
+		return
+	}
+	result := "lost"
+	if before.Speculative.Attempt == int(winner) {
+		result = "won"
+	}
+	metrics.SpeculativeAttemptsTotal.WithLabelValues(result).Inc()
+	slog.Info("speculation settled", "task_id", before.ID, "winner", winner, "speculative", result)
+}
+
+// CommitTaskOutput grants the caller's attempt the task's output commit and
+// supersedes any other attempt; see CommitTaskOutputRequest.
+func (s *Service) CommitTaskOutput(ctx context.Context, req *taskpb.CommitTaskOutputRequest) (*taskpb.CommitTaskOutputResponse, error) {
+	if s.raft.State() != hashiraft.Leader {
+		return &taskpb.CommitTaskOutputResponse{Ok: false, LeaderAddr: s.leaderAddr()}, nil
+	}
+	if fenced, err := s.checkWorker(req.WorkerId, req.Epoch); err != nil {
+		return &taskpb.CommitTaskOutputResponse{Ok: false, Fenced: fenced, Error: err.Error()}, nil
+	}
+	before := s.tasks.GetTask(req.TaskId)
+	_, err := s.apply(internalraft.CmdCommitTaskOutput, internalraft.CommitTaskOutputPayload{
+		ID:          req.TaskId,
+		WorkerID:    req.WorkerId,
+		Attempt:     int(req.Attempt),
+		CommittedAt: s.clock.Now().UTC(),
+	})
+	if errors.Is(err, internalraft.ErrLeaseLost) {
+		slog.Info("task output commit refused", "task_id", req.TaskId, "worker_id", req.WorkerId, "error", err)
+		return &taskpb.CommitTaskOutputResponse{Ok: false, LeaseLost: true, Error: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	speculationSettled(before, req.Attempt)
+	return &taskpb.CommitTaskOutputResponse{Ok: true}, nil
+}
+
+func speculativeToProto(sp *internalraft.SpeculativeAttempt) *taskpb.SpeculativeAttempt {
+	if sp == nil {
+		return nil
+	}
+	return &taskpb.SpeculativeAttempt{
+		Attempt:          uint32(sp.Attempt),
+		Worker:           sp.Worker,
+		StartedAtMs:      unixMilli(sp.StartedAt),
+		LeaseExpiresAtMs: unixMilli(sp.LeaseExpires),
+		Reason:           sp.Reason,
+	}
+}
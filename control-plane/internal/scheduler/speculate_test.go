@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/clock"
+	taskpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/task"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+func TestStageDurationsPercentile(t *testing.T) {
+	d := stageDurations{"j/map": {4 * time.Second, time.Second, 3 * time.Second, 2 * time.Second}}
+	d.sort()
+	for _, tc := range []struct {
+		p    float64
+		want time.Duration
+	}{{50, 2 * time.Second}, {75, 3 * time.Second}, {90, 4 * time.Second}, {1, time.Second}} {
+		if got, n := d.percentile("j/map", tc.p); got != tc.want || n != 4 {
+			t.Errorf("p%g = %v (%d samples), want %v", tc.p, got, n, tc.want)
+		}
+	}
+	if _, n := d.percentile("j/reduce", 90); n != 0 {
+		t.Errorf("unknown stage has %d samples", n)
+	}
+}
+
+func TestSpeculativeExecution(t *testing.T) {
+	svc, mr := newLeaderService()
+	svc.cfg.SpeculationMinSamples = 2
+	sim := clock.NewSim(1, time.Unix(1_700_000_000, 0))
+	svc.SetClock(sim)
+	registerWorker(t, mr, "w-1")
+	registerWorker(t, mr, "w-2")
+	ctx := context.Background()
+	submitReduce := func(id string) {
+		t.Helper()
+		r, err := svc.SubmitTask(ctx, &taskpb.SubmitTaskRequest{TaskId: id, JobId: "j", Type: taskpb.TaskType_TASK_TYPE_REDUCE,
+			OutputUri: "s3://out/" + id})
+		if err != nil || !r.Ok {
+			t.Fatalf("submit %s: %+v, %v", id, r, err)
+		}
+	}
+	acquire := func(worker string) *taskpb.Task {
+		t.Helper()
+		r, err := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: worker})
+		if err != nil || !r.Ok {
+			t.Fatalf("acquire by %s: %+v, %v", worker, r, err)
+		}
+		return r.Task
+	}
+
+	// Two tasks of the stage take 10s each.
+	for _, id := range []string{"t-1", "t-2"} {
+		submitReduce(id)
+		a := acquire("w-1")
+		sim.RunFor(10 * time.Second)
+		if r, _ := svc.CommitTaskOutput(ctx, &taskpb.CommitTaskOutputRequest{WorkerId: "w-1", TaskId: id,
+			Attempt: a.Attempt}); !r.Ok {
+			t.Fatalf("commit %s: %+v", id, r)
+		}
+		if r, _ := svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1", TaskId: id, Attempt: a.Attempt,
+			Succeeded: true}); !r.Ok {
+			t.Fatalf("complete %s: %+v", id, r)
+		}
+	}
+
+	// The third is no straggler yet at 10s, but is at 11s.
+	submitReduce("t-3")
+	straggler := acquire("w-1")
+	sim.RunFor(10 * time.Second)
+	svc.schedule()
+	if task := mr.fsm.GetTask("t-3"); task.Speculative != nil {
+		t.Fatalf("speculated before passing p90: %+v", task.Speculative)
+	}
+	sim.RunFor(time.Second)
+	svc.schedule()
+	task := mr.fsm.GetTask("t-3")
+	if task.Speculative == nil || task.Speculative.Worker != "w-2" || task.Speculative.Attempt != 2 {
+		t.Fatalf("expected a duplicate on w-2, got %+v", task.Speculative)
+	}
+	svc.schedule()
+	if again := mr.fsm.GetTask("t-3"); again.Speculative.Attempt != 2 || len(again.Attempts) != 0 {
+		t.Fatalf("a second pass must not launch another duplicate: %+v", again)
+	}
+
+	// The straggler cannot succeed without asking for the commit first.
+	if r, err := svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1", TaskId: "t-3",
+		Attempt: straggler.Attempt, Succeeded: true}); err != nil || r.Ok || r.LeaseLost {
+		t.Fatalf("complete without the commit = %+v, %v", r, err)
+	}
+
+	// w-2 receives the duplicate as its own attempt and wins the commit.
+	dup := acquire("w-2")
+	if dup.GetTaskId() != "t-3" || dup.Attempt != 2 || dup.AssignedWorker != "w-2" {
+		t.Fatalf("w-2 got %+v, want attempt 2 of t-3", dup)
+	}
+	if got := acquire("w-2"); got != nil {
+		t.Fatalf("duplicate delivered twice: %+v", got)
+	}
+	for range 2 {
+		if r, _ := svc.CommitTaskOutput(ctx, &taskpb.CommitTaskOutputRequest{WorkerId: "w-2", TaskId: "t-3", Attempt: 2}); !r.Ok {
+			t.Fatalf("commit by the duplicate: %+v", r)
+		}
+	}
+	if r, _ := svc.CommitTaskOutput(ctx, &taskpb.CommitTaskOutputRequest{WorkerId: "w-1", TaskId: "t-3",
+		Attempt: straggler.Attempt}); !r.LeaseLost {
+		t.Errorf("commit by the straggler = %+v, want lease_lost", r)
+	}
+	if r, _ := svc.RenewTaskLease(ctx, &taskpb.RenewTaskLeaseRequest{WorkerId: "w-1", TaskId: "t-3",
+		Attempt: straggler.Attempt}); !r.LeaseLost {
+		t.Errorf("renew by the straggler = %+v, want lease_lost", r)
+	}
+	if r, _ := svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-2", TaskId: "t-3", Attempt: 2,
+		Succeeded: true}); !r.Ok {
+		t.Fatalf("complete by the duplicate: %+v", r)
+	}
+	got, _ := svc.GetTask(ctx, &taskpb.GetTaskRequest{TaskId: "t-3"})
+	if got.Task.State != taskpb.TaskState_TASK_STATE_SUCCEEDED || got.Task.CommitAttempt != 2 ||
+		got.Task.Attempts[0].Outcome != taskpb.AttemptOutcome_ATTEMPT_OUTCOME_SUPERSEDED {
+		t.Errorf("task at the end = %+v", got.Task)
+	}
+}
+
+func TestSpeculationSkipsStragglerWithoutSpareWorker(t *testing.T) {
+	svc, mr := newLeaderService()
+	svc.cfg.SpeculationMinSamples = 1
+	sim := clock.NewSim(1, time.Unix(1_700_000_000, 0))
+	svc.SetClock(sim)
+	registerWorker(t, mr, "w-1")
+	ctx := context.Background()
+
+	submit(t, svc, "t-1", taskpb.TaskType_TASK_TYPE_GENERIC)
+	a, _ := svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+	sim.RunFor(time.Second)
+	svc.CompleteTask(ctx, &taskpb.CompleteTaskRequest{WorkerId: "w-1", TaskId: "t-1", Attempt: a.Task.Attempt, Succeeded: true})
+	submit(t, svc, "t-2", taskpb.TaskType_TASK_TYPE_GENERIC)
+	svc.AcquireTask(ctx, &taskpb.AcquireTaskRequest{WorkerId: "w-1"})
+	sim.RunFor(time.Minute)
+	svc.schedule()
+	if task := mr.fsm.GetTask("t-2"); task.Speculative != nil || task.State != internalraft.TaskRunning {
+		t.Errorf("a duplicate needs another worker: %+v", task)
+	}
+}
@@ -0,0 +1,87 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+// Faults schedules random failures for the next d, about one every mean:
+// a control-plane node crash (restarted 2–10s later) or a worker crash
+// (restarted 5–60s later, or left down for good one time in four).
+func (c *Cluster) Faults(d, mean time.Duration) {
+	end := c.Sim.Now().Add(d)
+	var next func()
+	next = func() {
+		if !c.Sim.Now().Before(end) {
+			return
+		}
+		c.fault()
+		c.Sim.After(mean/2+time.Duration(c.Sim.Rand().Int64N(int64(mean))), next)
+	}
+	c.Sim.After(mean/2+time.Duration(c.Sim.Rand().Int64N(int64(mean))), next)
+}
+
+func (c *Cluster) fault() {
+	rng := c.Sim.Rand()
+	if rng.IntN(3) == 0 {
+		i := rng.IntN(len(c.nodes))
+		if c.nodes[i].down {
+			return
+		}
+		c.CrashNode(i)
+		c.Sim.After(2*time.Second+time.Duration(rng.Int64N(int64(8*time.Second))), func() { c.RestartNode(i) })
+		return
+	}
+	i := rng.IntN(len(c.workers))
+	if c.workers[i].down {
+		return
+	}
+	c.CrashWorker(i)
+	if rng.IntN(4) != 0 {
+		c.Sim.After(5*time.Second+time.Duration(rng.Int64N(int64(55*time.Second))), func() { c.RestartWorker(i) })
+	}
+}
+
+// Check reports every invariant violated at the current instant. It expects
+// the cluster to have run fault-free for at least settle:
+//
+//   - a leader is in office and has been for settle,
+//   - all live FSMs hold identical worker state,
+//   - every running worker is online (or quarantined), and
+//   - every worker down for settle, whose last heartbeat the current leader
+//     accepted, is offline (or quarantined, or reaped).
+//
+// The last condition is scoped to the current leader because a new leader
+// only tracks workers it has heard from.
+func (c *Cluster) Check(settle time.Duration) error {
+	now := c.Sim.Now()
+	if c.leader == -1 || now.Sub(c.elected) < settle {
+		return fmt.Errorf("no leader stable for %s (leader=%d, elected %s ago)", settle, c.leader, now.Sub(c.elected))
+	}
+	var errs []error
+	want := c.nodes[c.leader].fsm.Workers()
+	for _, n := range c.nodes {
+		if !n.down && !reflect.DeepEqual(n.fsm.Workers(), want) {
+			errs = append(errs, fmt.Errorf("node %s diverged from leader", n.id))
+		}
+	}
+	for _, w := range c.workers {
+		info := want[w.ID]
+		status := ""
+		if info != nil {
+			status = info.Status
+		}
+		switch {
+		case !w.down && status != internalraft.WorkerOnline && status != internalraft.WorkerQuarantined:
+			errs = append(errs, fmt.Errorf("running worker %s is %q", w.ID, status))
+		case w.down && w.ackTerm == c.term && now.Sub(w.downSince) >= settle &&
+			status != internalraft.WorkerOffline && status != internalraft.WorkerQuarantined && status != "":
+			errs = append(errs, fmt.Errorf("worker %s down for %s is %q", w.ID, now.Sub(w.downSince), status))
+		}
+	}
+	return errors.Join(errs...)
+}
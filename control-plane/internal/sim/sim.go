@@ -0,0 +1,309 @@
+// Package sim runs control-plane nodes and fake workers in one process under
+// a seeded virtual clock.
+//
+// Consensus is modelled rather than run: the cluster keeps a single log and
+// commits each entry to every live node's FSM synchronously, and the
+// simulation itself decides when a leader fails and who wins the next
+// election. Everything else — AgentRegistry, the heartbeat monitor, the
+// reaper, the stats poller — is the production code, driven by a clock.Sim.
+// All randomness comes from the seed, so a failing seed replays identically
+// and its Trace can be diffed against a fixed build.
+package sim
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	hashiraft "github.com/hashicorp/raft"
+
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/agent"
+	"github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/clock"
+	internalraft "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/raft"
+)
+
+const (
+	raftPort = "7000"
+	grpcPort = "50051"
+)
+
+// Epoch is the virtual start time of every simulation.
+var Epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Config describes a simulated deployment.
+type Config struct {
+	Seed      uint64
+	Nodes     int           // control-plane nodes; default 3
+	Workers   int           // fake workers; default 5
+	Heartbeat time.Duration // worker heartbeat interval; default 1s, jittered ±10%
+	Election  time.Duration // leaderless gap after a leader fails; default 1s, jittered up to 2x
+	Agent     agent.Config  // registry tunables; zero means agent.DefaultConfig()
+}
+
+func (c *Config) setDefaults() {
+	if c.Nodes == 0 {
+		c.Nodes = 3
+	}
+	if c.Workers == 0 {
+		c.Workers = 5
+	}
+	if c.Heartbeat == 0 {
+		c.Heartbeat = time.Second
+	}
+	if c.Election == 0 {
+		c.Election = time.Second
+	}
+	if c.Agent.Detector.CheckInterval == 0 {
+		c.Agent = agent.DefaultConfig()
+	}
+}
+
+// Cluster is a simulated control plane plus its workers. It is not safe for
+// concurrent use; everything runs on the Sim's event loop.
+type Cluster struct {
+	Sim *clock.Sim
+	cfg Config
+
+	nodes   []*node
+	workers []*Worker
+	log     []*hashiraft.Log
+	leader  int // index into nodes, -1 while an election is in progress
+	term    uint64
+	elected time.Time // when the current leader took office
+
+	trace []string
+}
+
+// New builds a cluster, elects a first leader and starts every worker.
+func New(cfg Config) *Cluster {
+	cfg.setDefaults()
+	c := &Cluster{Sim: clock.NewSim(cfg.Seed, Epoch), cfg: cfg, leader: -1}
+	for i := 0; i < cfg.Nodes; i++ {
+		n := &node{c: c, idx: i, id: fmt.Sprintf("cp-%d", i)}
+		c.nodes = append(c.nodes, n)
+		n.start()
+	}
+	c.elect()
+	for i := 0; i < cfg.Workers; i++ {
+		w := &Worker{ID: fmt.Sprintf("w-%d", i), Cloud: clouds[i%len(clouds)], c: c}
+		c.workers = append(c.workers, w)
+		w.start()
+	}
+	return c
+}
+
+// RunFor advances the simulation by d.
+func (c *Cluster) RunFor(d time.Duration) { c.Sim.RunFor(d) }
+
+// Trace returns the event log so far: one line per leader change, crash,
+// restart, registration and committed command, stamped with virtual time.
+func (c *Cluster) Trace() []string { return append([]string(nil), c.trace...) }
+
+// Digest is a hash of the trace, for comparing runs.
+func (c *Cluster) Digest() string {
+	sum := sha256.Sum256([]byte(strings.Join(c.trace, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Leader returns the index of the current leader, or -1.
+func (c *Cluster) Leader() int { return c.leader }
+
+// FSM returns node i's state machine.
+func (c *Cluster) FSM(i int) *internalraft.PipelineFSM { return c.nodes[i].fsm }
+
+// Workers returns the fake workers.
+func (c *Cluster) Workers() []*Worker { return c.workers }
+
+func (c *Cluster) tracef(format string, args ...any) {
+	c.trace = append(c.trace, fmt.Sprintf("%12s ", c.Sim.Now().Sub(Epoch))+fmt.Sprintf(format, args...))
+}
+
+// CrashNode stops node i. If it led, a new leader is elected after the
+// election gap — provided a majority of nodes is still up.
+func (c *Cluster) CrashNode(i int) {
+	n := c.nodes[i]
+	if n.down {
+		return
+	}
+	n.stop()
+	c.tracef("node %s crashed", n.id)
+	if c.leader == i {
+		c.leader = -1
+		gap := c.cfg.Election + time.Duration(c.Sim.Rand().Int64N(int64(c.cfg.Election)))
+		c.Sim.After(gap, c.elect)
+	}
+}
+
+// RestartNode brings node i back with a fresh registry and an FSM rebuilt
+// from the committed log.
+func (c *Cluster) RestartNode(i int) {
+	n := c.nodes[i]
+	if !n.down {
+		return
+	}
+	n.start()
+	c.tracef("node %s restarted at index %d", n.id, len(c.log))
+	if c.leader == -1 {
+		c.elect()
+	}
+}
+
+// elect picks a random live node as leader if a majority is up.
+func (c *Cluster) elect() {
+	if c.leader != -1 {
+		return
+	}
+	var live []int
+	for i, n := range c.nodes {
+		if !n.down {
+			live = append(live, i)
+		}
+	}
+	if len(live) <= len(c.nodes)/2 {
+		c.tracef("no quorum (%d/%d up)", len(live), len(c.nodes))
+		return
+	}
+	c.leader = live[c.Sim.Rand().IntN(len(live))]
+	c.term++
+	c.elected = c.Sim.Now()
+	c.tracef("node %s elected leader for term %d", c.nodes[c.leader].id, c.term)
+}
+
+// commit appends cmd to the log and applies it on every live node, returning
+// the leader's FSM response.
+func (c *Cluster) commit(cmd []byte) interface{} {
+	entry := &hashiraft.Log{
+		Index:      uint64(len(c.log) + 1),
+		Term:       c.term,
+		Type:       hashiraft.LogCommand,
+		Data:       cmd,
+		AppendedAt: c.Sim.Now(),
+	}
+	c.log = append(c.log, entry)
+	var resp interface{}
+	for i, n := range c.nodes {
+		if n.down {
+			continue
+		}
+		r := n.fsm.Apply(entry)
+		if i == c.leader {
+			resp = r
+		}
+	}
+	var decoded internalraft.Command
+	if err := json.Unmarshal(cmd, &decoded); err == nil {
+		c.tracef("commit %d %s %s", entry.Index, decoded.Type, decoded.Payload)
+	}
+	return resp
+}
+
+// nodeByAddr maps a redirect address ("cp-1:50051") to a node index.
+func (c *Cluster) nodeByAddr(addr string) (int, bool) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0, false
+	}
+	i, err := strconv.Atoi(strings.TrimPrefix(host, "cp-"))
+	if err != nil || i < 0 || i >= len(c.nodes) {
+		return 0, false
+	}
+	return i, true
+}
+
+// node is one control-plane process. It implements agent.RaftApplier and
+// internalraft.StatsSource against the cluster's modelled log.
+type node struct {
+	c    *Cluster
+	idx  int
+	id   string
+	down bool
+
+	fsm    *internalraft.PipelineFSM
+	reg    *agent.AgentRegistry
+	cancel context.CancelFunc
+}
+
+func (n *node) start() {
+	n.down = false
+	n.fsm = internalraft.NewPipelineFSM()
+	for _, entry := range n.c.log {
+		n.fsm.Apply(entry)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+	n.reg = agent.NewAgentRegistryWithConfig(n, n.fsm, grpcPort, n.c.cfg.Agent)
+	n.reg.SetClock(n.c.Sim)
+	n.reg.Start(ctx)
+	internalraft.PollStats(ctx, n, n.c.Sim, 5*time.Second)
+}
+
+func (n *node) stop() {
+	n.down = true
+	n.cancel()
+}
+
+func (n *node) Apply(cmd []byte, timeout time.Duration) error {
+	_, err := n.ApplyCommand(cmd, timeout)
+	return err
+}
+
+func (n *node) ApplyCommand(cmd []byte, _ time.Duration) (interface{}, error) {
+	if n.down || n.c.leader != n.idx {
+		return nil, hashiraft.ErrNotLeader
+	}
+	resp := n.c.commit(cmd)
+	if err, ok := resp.(error); ok {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (n *node) Leader() string {
+	if n.down || n.c.leader == -1 {
+		return ""
+	}
+	return n.c.nodes[n.c.leader].id + ":" + raftPort
+}
+
+func (n *node) LeaderID() string {
+	if n.down || n.c.leader == -1 {
+		return ""
+	}
+	return n.c.nodes[n.c.leader].id
+}
+
+func (n *node) State() hashiraft.RaftState {
+	switch {
+	case n.down:
+		return hashiraft.Shutdown
+	case n.c.leader == n.idx:
+		return hashiraft.Leader
+	case n.c.leader == -1:
+		return hashiraft.Candidate
+	default:
+		return hashiraft.Follower
+	}
+}
+
+func (n *node) StateFloat() float64 {
+	switch n.State() {
+	case hashiraft.Follower:
+		return 0
+	case hashiraft.Candidate:
+		return 1
+	case hashiraft.Leader:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func (n *node) Stats() map[string]string {
+	return map[string]string{"term": strconv.FormatUint(n.c.term, 10)}
+}
@@ -0,0 +1,110 @@
+package sim
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Every registry logs each transition; thousands of simulated minutes
+	// would drown the test output.
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	os.Exit(m.Run())
+}
+
+const settle = time.Minute
+
+// run drives one seeded scenario: ten minutes of faults, then a quiet period
+// long enough for the detector and any quarantine to settle.
+func run(seed uint64) *Cluster {
+	c := New(Config{Seed: seed})
+	c.Faults(10*time.Minute, 15*time.Second)
+	c.RunFor(10 * time.Minute)
+	for i := range c.nodes {
+		c.RestartNode(i)
+	}
+	c.RunFor(settle + 2*time.Minute)
+	return c
+}
+
+// seeds returns SIM_SEED alone when set, so a failure can be replayed.
+func seeds(t *testing.T, n uint64) []uint64 {
+	if s := os.Getenv("SIM_SEED"); s != "" {
+		seed, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			t.Fatalf("SIM_SEED: %v", err)
+		}
+		return []uint64{seed}
+	}
+	var out []uint64
+	for s := uint64(1); s <= n; s++ {
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestSameSeedReplaysIdentically(t *testing.T) {
+	a, b := run(42), run(42)
+	if !slices.Equal(a.Trace(), b.Trace()) {
+		for i := range min(len(a.trace), len(b.trace)) {
+			if a.trace[i] != b.trace[i] {
+				t.Fatalf("traces diverge at line %d:\n  %s\n  %s", i, a.trace[i], b.trace[i])
+			}
+		}
+		t.Fatalf("traces differ in length: %d vs %d", len(a.trace), len(b.trace))
+	}
+	if len(a.trace) < 100 {
+		t.Errorf("suspiciously short trace (%d lines)", len(a.trace))
+	}
+	if run(43).Digest() == a.Digest() {
+		t.Error("different seeds produced the same trace")
+	}
+}
+
+func TestInvariantsHoldUnderFaults(t *testing.T) {
+	n := uint64(20)
+	if testing.Short() {
+		n = 3
+	}
+	for _, seed := range seeds(t, n) {
+		c := run(seed)
+		if err := c.Check(settle); err != nil {
+			tail := c.Trace()
+			tail = tail[max(0, len(tail)-40):]
+			t.Errorf("seed %d: %v\nreplay with SIM_SEED=%d; trace tail:\n%s",
+				seed, err, seed, strings.Join(tail, "\n"))
+		}
+	}
+}
+
+func TestLeaderFailoverRedirectsWorkers(t *testing.T) {
+	c := New(Config{Seed: 7})
+	c.RunFor(10 * time.Second)
+	old := c.Leader()
+	c.CrashNode(old)
+	c.RunFor(settle)
+	if c.Leader() == -1 || c.Leader() == old {
+		t.Fatalf("expected a new leader, got %d", c.Leader())
+	}
+	if err := c.Check(settle / 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Losing a second node costs quorum; nothing can be committed until it returns.
+	c.CrashNode(c.Leader())
+	committed := len(c.log)
+	c.RunFor(30 * time.Second)
+	if c.Leader() != -1 || len(c.log) != committed {
+		t.Fatalf("minority elected a leader or committed entries")
+	}
+	c.RestartNode(old)
+	c.RunFor(settle)
+	if err := c.Check(settle / 2); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,130 @@
+package sim
+
+import (
+	"context"
+	"time"
+
+	workerpb "github.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker"
+)
+
+var clouds = []string{"aws", "gcp", "azure"}
+
+// Worker is a fake worker that speaks the WorkerService protocol by calling
+// the registries directly: it registers, heartbeats at a jittered interval,
+// follows leader redirects and re-registers when its epoch is rejected.
+type Worker struct {
+	ID    string
+	Cloud string
+	c     *Cluster
+
+	target     int // node the worker currently talks to
+	epoch      uint64
+	registered bool
+	down       bool
+	gen        int    // bumped on crash so ticks scheduled before it are dropped
+	ackTerm    uint64 // term of the last leader that accepted a heartbeat
+	downSince  time.Time
+}
+
+// Down reports whether the worker is crashed.
+func (w *Worker) Down() bool { return w.down }
+
+func (w *Worker) start() {
+	w.down = false
+	w.registered = false
+	w.target = w.c.Sim.Rand().IntN(len(w.c.nodes))
+	w.schedule()
+}
+
+// schedule queues the next step one jittered heartbeat interval from now.
+func (w *Worker) schedule() {
+	hb := w.c.cfg.Heartbeat
+	d := hb - hb/10 + time.Duration(w.c.Sim.Rand().Int64N(int64(hb/5)+1))
+	gen := w.gen
+	w.c.Sim.After(d, func() {
+		if w.gen == gen && !w.down {
+			w.step()
+			w.schedule()
+		}
+	})
+}
+
+func (w *Worker) step() {
+	n := w.c.nodes[w.target]
+	if n.down {
+		w.retarget("")
+		return
+	}
+	ctx := context.Background()
+	if !w.registered {
+		resp, err := n.reg.RegisterWorker(ctx, &workerpb.RegisterWorkerRequest{
+			WorkerId: w.ID,
+			Address:  w.ID + ":8080",
+			CloudTag: w.Cloud,
+		})
+		switch {
+		case err != nil:
+			w.retarget("")
+		case !resp.Ok && resp.LeaderAddr != "":
+			w.retarget(resp.LeaderAddr)
+		case resp.Ok:
+			w.registered = true
+			w.epoch = resp.Epoch
+			w.c.tracef("worker %s registered with %s at epoch %d", w.ID, n.id, w.epoch)
+		}
+		return
+	}
+
+	resp, err := n.reg.Heartbeat(ctx, &workerpb.HeartbeatRequest{WorkerId: w.ID, Epoch: w.epoch})
+	switch {
+	case err != nil:
+		w.retarget("")
+	case resp.Ok:
+		w.ackTerm = w.c.term
+	case resp.LeaderAddr != "":
+		w.retarget(resp.LeaderAddr)
+	case resp.Fenced:
+		w.c.tracef("worker %s fenced: %s", w.ID, resp.Error)
+		w.crash()
+	case resp.Error != "":
+		// Not registered or epoch unknown to this leader — start over.
+		w.c.tracef("worker %s heartbeat rejected: %s", w.ID, resp.Error)
+		w.registered = false
+	}
+}
+
+// retarget switches to the redirected node, or a random one when there is no
+// usable redirect (no leader yet, or the node is unreachable).
+func (w *Worker) retarget(addr string) {
+	if i, ok := w.c.nodeByAddr(addr); ok {
+		w.target = i
+		return
+	}
+	w.target = w.c.Sim.Rand().IntN(len(w.c.nodes))
+}
+
+func (w *Worker) crash() {
+	w.down = true
+	w.gen++
+	w.downSince = w.c.Sim.Now()
+}
+
+// CrashWorker kills worker i; its heartbeats stop until RestartWorker.
+func (c *Cluster) CrashWorker(i int) {
+	w := c.workers[i]
+	if w.down {
+		return
+	}
+	w.crash()
+	c.tracef("worker %s crashed", w.ID)
+}
+
+// RestartWorker starts worker i again as a new process, which re-registers.
+func (c *Cluster) RestartWorker(i int) {
+	w := c.workers[i]
+	if !w.down {
+		return
+	}
+	w.start()
+	c.tracef("worker %s restarted", w.ID)
+}
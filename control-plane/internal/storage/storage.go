@@ -1 +1,83 @@
+// Package storage wraps the S3-compatible object store (MinIO in the local
+// simulation) used for pipeline data and cluster backups.
 package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Config locates and authenticates against the object store.
+type Config struct {
+	Endpoint  string // "http://minio:9000" or "https://s3.amazonaws.com"; the scheme selects TLS
+	AccessKey string
+	SecretKey string
+	Region    string // optional
+}
+
+// Client reads and writes whole objects.
+type Client struct {
+	mc *minio.Client
+}
+
+// New builds a client for cfg.Endpoint. No request is made until first use.
+func New(cfg Config) (*Client, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("storage: invalid endpoint %q, want http(s)://host:port", cfg.Endpoint)
+	}
+	mc, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: u.Scheme == "https",
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	return &Client{mc: mc}, nil
+}
+
+// Put uploads r to bucket/key. size may be -1 when unknown, at the cost of a
+// multipart upload buffered in memory.
+func (c *Client) Put(ctx context.Context, bucket, key string, r io.Reader, size int64) error {
+	_, err := c.mc.PutObject(ctx, bucket, key, r, size,
+		minio.PutObjectOptions{ContentType: "application/octet-stream"})
+	if err != nil {
+		return fmt.Errorf("storage: put s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// Get opens bucket/key for reading. The caller closes the reader.
+func (c *Client) Get(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	obj, err := c.mc.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("storage: get s3://%s/%s: %w", bucket, key, err)
+	}
+	// GetObject is lazy; Stat surfaces a missing object here instead of on first Read.
+	if _, err := obj.Stat(); err != nil {
+		_ = obj.Close()
+		return nil, fmt.Errorf("storage: get s3://%s/%s: %w", bucket, key, err)
+	}
+	return obj, nil
+}
+
+// ParseURL splits "s3://bucket/key/path" into bucket and key. ok is false for
+// anything that is not an s3:// URL, so callers can treat it as a local path.
+func ParseURL(s string) (bucket, key string, ok bool, err error) {
+	rest, found := strings.CutPrefix(s, "s3://")
+	if !found {
+		return "", "", false, nil
+	}
+	bucket, key, _ = strings.Cut(rest, "/")
+	if bucket == "" || key == "" {
+		return "", "", true, fmt.Errorf("storage: %q must be s3://bucket/key", s)
+	}
+	return bucket, key, true, nil
+}
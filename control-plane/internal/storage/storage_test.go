@@ -0,0 +1,31 @@
+package storage
+
+import "testing"
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		in          string
+		bucket, key string
+		ok, wantErr bool
+	}{
+		{"s3://backups/cluster/2025-01-01.snap", "backups", "cluster/2025-01-01.snap", true, false},
+		{"/var/backups/cluster.snap", "", "", false, false},
+		{"s3://backups", "", "", true, true},
+		{"s3:///key", "", "", true, true},
+	}
+	for _, tt := range tests {
+		bucket, key, ok, err := ParseURL(tt.in)
+		if bucket != tt.bucket || key != tt.key || ok != tt.ok || (err != nil) != tt.wantErr {
+			t.Errorf("ParseURL(%q) = %q, %q, %v, %v", tt.in, bucket, key, ok, err)
+		}
+	}
+}
+
+func TestNewRejectsBareHost(t *testing.T) {
+	if _, err := New(Config{Endpoint: "minio:9000"}); err == nil {
+		t.Error("expected an endpoint without scheme to be rejected")
+	}
+	if _, err := New(Config{Endpoint: "http://minio:9000"}); err != nil {
+		t.Errorf("New: %v", err)
+	}
+}
@@ -1,5 +1,6 @@
 #!/usr/bin/env bash
-# proto-gen.sh — generates Go and Python stubs from proto/worker.proto
+# proto-gen.sh — generates Go stubs for every proto/*.proto and Python stubs
+# for proto/worker.proto
 # Usage: bash scripts/proto-gen.sh
 set -eu
 
@@ -26,19 +27,23 @@ for tool in protoc-gen-go protoc-gen-go-grpc; do
 done
 
 # ── Output directories ────────────────────────────────────────────────────────
-GO_OUT="$REPO_ROOT/control-plane/internal/gen/worker"
+GO_GEN="$REPO_ROOT/control-plane/internal/gen"
 PY_OUT="$REPO_ROOT/worker/worker/gen"
 PROTO="$REPO_ROOT/proto/worker.proto"
 
-mkdir -p "$GO_OUT" "$PY_OUT"
+mkdir -p "$PY_OUT"
 
 # ── Go stubs ──────────────────────────────────────────────────────────────────
-echo "Generating Go stubs → $GO_OUT"
-protoc \
-  --proto_path="$REPO_ROOT/proto" \
-  --go_out="$GO_OUT"      --go_opt=paths=source_relative \
-  --go-grpc_out="$GO_OUT" --go-grpc_opt=paths=source_relative \
-  "$PROTO"
+for name in worker raft task; do
+  GO_OUT="$GO_GEN/$name"
+  mkdir -p "$GO_OUT"
+  echo "Generating Go stubs → $GO_OUT"
+  protoc \
+    --proto_path="$REPO_ROOT/proto" \
+    --go_out="$GO_OUT"      --go_opt=paths=source_relative \
+    --go-grpc_out="$GO_OUT" --go-grpc_opt=paths=source_relative \
+    "$REPO_ROOT/proto/$name.proto"
+done
 
 # ── Python stubs ──────────────────────────────────────────────────────────────
 echo "Generating Python stubs → $PY_OUT"
@@ -37,6 +37,44 @@ def test_register_success():
     assert client._orchestrator_addr == "localhost:50051"
 
 
+def test_register_stores_epoch():
+    """_register keeps the epoch returned by the leader for later heartbeats."""
+    client = make_client()
+    mock_resp = MagicMock()
+    mock_resp.ok = True
+    mock_resp.epoch = 7
+
+    with patch("worker.heartbeat.grpc.insecure_channel"):
+        with patch("worker.heartbeat.worker_pb2_grpc.WorkerServiceStub") as MockStub:
+            MockStub.return_value.RegisterWorker.return_value = mock_resp
+            client._register()
+
+    assert client._epoch == 7
+
+
+def test_register_sends_join_token_and_stores_credential():
+    """_register presents the join token and keeps the issued credential."""
+    client = HeartbeatClient(
+        worker_id="test-worker",
+        cloud_tag="aws",
+        orchestrator_addr="localhost:50051",
+        join_token="join-secret",
+    )
+    mock_resp = MagicMock()
+    mock_resp.ok = True
+    mock_resp.epoch = 1
+    mock_resp.credential = "cred-secret"
+
+    with patch("worker.heartbeat.grpc.insecure_channel"):
+        with patch("worker.heartbeat.worker_pb2_grpc.WorkerServiceStub") as MockStub:
+            MockStub.return_value.RegisterWorker.return_value = mock_resp
+            client._register()
+            kwargs = MockStub.return_value.RegisterWorker.call_args.kwargs
+
+    assert kwargs["metadata"] == [("x-join-token", "join-secret")]
+    assert client._auth_metadata() == [("authorization", "Bearer cred-secret")]
+
+
 def test_register_redirect():
     """_register returns False and updates _orchestrator_addr on follower redirect."""
     client = make_client()
@@ -98,6 +136,60 @@ def test_send_heartbeat_redirect_updates_addr():
     assert client._orchestrator_addr == "cp-gcp-1:50051"
 
 
+def test_send_heartbeat_sends_epoch():
+    """_send_heartbeat includes the registration epoch."""
+    client = make_client()
+    client._epoch = 7
+    mock_resp = MagicMock()
+    mock_resp.ok = True
+
+    with patch("worker.heartbeat.grpc.insecure_channel"):
+        with patch("worker.heartbeat.worker_pb2_grpc.WorkerServiceStub") as MockStub:
+            MockStub.return_value.Heartbeat.return_value = mock_resp
+            client._send_heartbeat()
+            req = MockStub.return_value.Heartbeat.call_args[0][0]
+
+    assert req.epoch == 7
+
+
+def test_send_heartbeat_fenced_stops_client():
+    """A fenced heartbeat stops the client instead of re-registering."""
+    client = make_client()
+    mock_resp = MagicMock()
+    mock_resp.ok = False
+    mock_resp.leader_addr = ""
+    mock_resp.fenced = True
+    mock_resp.error = "stale registration epoch"
+
+    with patch("worker.heartbeat.grpc.insecure_channel"):
+        with patch("worker.heartbeat.worker_pb2_grpc.WorkerServiceStub") as MockStub:
+            MockStub.return_value.Heartbeat.return_value = mock_resp
+            with patch.object(client, "_register_with_retry") as reregister:
+                client._send_heartbeat()
+
+    assert client._stop_event.is_set()
+    reregister.assert_not_called()
+
+
+def test_send_heartbeat_not_registered_reregisters():
+    """A rejected, unfenced heartbeat triggers re-registration."""
+    client = make_client()
+    mock_resp = MagicMock()
+    mock_resp.ok = False
+    mock_resp.leader_addr = ""
+    mock_resp.fenced = False
+    mock_resp.error = "worker not registered"
+
+    with patch("worker.heartbeat.grpc.insecure_channel"):
+        with patch("worker.heartbeat.worker_pb2_grpc.WorkerServiceStub") as MockStub:
+            MockStub.return_value.Heartbeat.return_value = mock_resp
+            with patch.object(client, "_register_with_retry") as reregister:
+                client._send_heartbeat()
+
+    reregister.assert_called_once()
+    assert not client._stop_event.is_set()
+
+
 def test_send_heartbeat_grpc_error_does_not_raise():
     """_send_heartbeat swallows gRPC errors — workers must not crash on transient failures."""
     client = make_client()
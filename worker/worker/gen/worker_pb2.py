@@ -24,7 +24,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0cworker.proto\x12\x06worker\"N\n\x15RegisterWorkerRequest\x12\x11\n\tworker_id\x18\x01 \x01(\t\x12\x0f\n\x07\x61\x64\x64ress\x18\x02 \x01(\t\x12\x11\n\tcloud_tag\x18\x03 \x01(\t\"H\n\x16RegisterWorkerResponse\x12\n\n\x02ok\x18\x01 \x01(\x08\x12\x13\n\x0bleader_addr\x18\x02 \x01(\t\x12\r\n\x05\x65rror\x18\x03 \x01(\t\"%\n\x10HeartbeatRequest\x12\x11\n\tworker_id\x18\x01 \x01(\t\"C\n\x11HeartbeatResponse\x12\n\n\x02ok\x18\x01 \x01(\x08\x12\x13\n\x0bleader_addr\x18\x02 \x01(\t\x12\r\n\x05\x65rror\x18\x03 \x01(\t2\xa2\x01\n\rWorkerService\x12O\n\x0eRegisterWorker\x12\x1d.worker.RegisterWorkerRequest\x1a\x1e.worker.RegisterWorkerResponse\x12@\n\tHeartbeat\x12\x18.worker.HeartbeatRequest\x1a\x19.worker.HeartbeatResponseBXZVgithub.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker;workerpbb\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0cworker.proto\x12\x06worker\"u\n\x15RegisterWorkerRequest\x12\x11\n\tworker_id\x18\x01 \x01(\t\x12\x0f\n\x07\x61\x64\x64ress\x18\x02 \x01(\t\x12\x11\n\tcloud_tag\x18\x03 \x01(\t\x12\x12\n\ncpu_millis\x18\x04 \x01(\x03\x12\x11\n\tmemory_mb\x18\x05 \x01(\x03\"k\n\x16RegisterWorkerResponse\x12\n\n\x02ok\x18\x01 \x01(\x08\x12\x13\n\x0bleader_addr\x18\x02 \x01(\t\x12\r\n\x05\x65rror\x18\x03 \x01(\t\x12\r\n\x05\x65poch\x18\x04 \x01(\x04\x12\x12\n\ncredential\x18\x05 \x01(\t\"4\n\x10HeartbeatRequest\x12\x11\n\tworker_id\x18\x01 \x01(\t\x12\r\n\x05\x65poch\x18\x02 \x01(\x04\"t\n\x11HeartbeatResponse\x12\n\n\x02ok\x18\x01 \x01(\x08\x12\x13\n\x0bleader_addr\x18\x02 \x01(\t\x12\r\n\x05\x65rror\x18\x03 \x01(\t\x12\x0e\n\x06\x66\x65nced\x18\x04 \x01(\x08\x12\x1f\n\x05kills\x18\x05 \x03(\x0b\x32\x10.worker.TaskKill\"T\n\x08TaskKill\x12\x0f\n\x07task_id\x18\x01 \x01(\t\x12\x0f\n\x07\x61ttempt\x18\x02 \x01(\r\x12\x0e\n\x06reason\x18\x03 \x01(\t\x12\x16\n\x0egrace_until_ms\x18\x04 \x01(\x03\"L\n\x17IssueCertificateRequest\x12\x11\n\tworker_id\x18\x01 \x01(\t\x12\r\n\x05\x65poch\x18\x02 \x01(\x04\x12\x0f\n\x07\x63sr_pem\x18\x03 \x01(\t\"\x92\x01\n\x18IssueCertificateResponse\x12\n\n\x02ok\x18\x01 \x01(\x08\x12\x13\n\x0bleader_addr\x18\x02 \x01(\t\x12\r\n\x05\x65rror\x18\x03 \x01(\t\x12\x17\n\x0f\x63\x65rtificate_pem\x18\x04 \x01(\t\x12\x15\n\rca_bundle_pem\x18\x05 \x01(\t\x12\x16\n\x0enot_after_unix\x18\x06 \x01(\x03\x32\xf9\x01\n\rWorkerService\x12O\n\x0eRegisterWorker\x12\x1d.worker.RegisterWorkerRequest\x1a\x1e.worker.RegisterWorkerResponse\x12@\n\tHeartbeat\x12\x18.worker.HeartbeatRequest\x1a\x19.worker.HeartbeatResponse\x12U\n\x10IssueCertificate\x12\x1f.worker.IssueCertificateRequest\x1a .worker.IssueCertificateResponseBXZVgithub.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker;workerpbb\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -33,13 +33,19 @@ if not _descriptor._USE_C_DESCRIPTORS:
   _globals['DESCRIPTOR']._loaded_options = None
   _globals['DESCRIPTOR']._serialized_options = b'ZVgithub.com/joelcrouch/pipeline-orchestrator/control-plane/internal/gen/worker;workerpb'
   _globals['_REGISTERWORKERREQUEST']._serialized_start=24
-  _globals['_REGISTERWORKERREQUEST']._serialized_end=102
-  _globals['_REGISTERWORKERRESPONSE']._serialized_start=104
-  _globals['_REGISTERWORKERRESPONSE']._serialized_end=176
-  _globals['_HEARTBEATREQUEST']._serialized_start=178
-  _globals['_HEARTBEATREQUEST']._serialized_end=215
-  _globals['_HEARTBEATRESPONSE']._serialized_start=217
-  _globals['_HEARTBEATRESPONSE']._serialized_end=284
-  _globals['_WORKERSERVICE']._serialized_start=287
-  _globals['_WORKERSERVICE']._serialized_end=449
+  _globals['_REGISTERWORKERREQUEST']._serialized_end=141
+  _globals['_REGISTERWORKERRESPONSE']._serialized_start=143
+  _globals['_REGISTERWORKERRESPONSE']._serialized_end=250
+  _globals['_HEARTBEATREQUEST']._serialized_start=252
+  _globals['_HEARTBEATREQUEST']._serialized_end=304
+  _globals['_HEARTBEATRESPONSE']._serialized_start=306
+  _globals['_HEARTBEATRESPONSE']._serialized_end=422
+  _globals['_TASKKILL']._serialized_start=424
+  _globals['_TASKKILL']._serialized_end=508
+  _globals['_ISSUECERTIFICATEREQUEST']._serialized_start=510
+  _globals['_ISSUECERTIFICATEREQUEST']._serialized_end=586
+  _globals['_ISSUECERTIFICATERESPONSE']._serialized_start=589
+  _globals['_ISSUECERTIFICATERESPONSE']._serialized_end=735
+  _globals['_WORKERSERVICE']._serialized_start=738
+  _globals['_WORKERSERVICE']._serialized_end=987
 # @@protoc_insertion_point(module_scope)
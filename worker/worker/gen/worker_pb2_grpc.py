@@ -45,6 +45,11 @@ class WorkerServiceStub(object):
                 request_serializer=worker__pb2.HeartbeatRequest.SerializeToString,
                 response_deserializer=worker__pb2.HeartbeatResponse.FromString,
                 _registered_method=True)
+        self.IssueCertificate = channel.unary_unary(
+                '/worker.WorkerService/IssueCertificate',
+                request_serializer=worker__pb2.IssueCertificateRequest.SerializeToString,
+                response_deserializer=worker__pb2.IssueCertificateResponse.FromString,
+                _registered_method=True)
 
 
 class WorkerServiceServicer(object):
@@ -63,6 +68,12 @@ class WorkerServiceServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def IssueCertificate(self, request, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_WorkerServiceServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -76,6 +87,11 @@ def add_WorkerServiceServicer_to_server(servicer, server):
                     request_deserializer=worker__pb2.HeartbeatRequest.FromString,
                     response_serializer=worker__pb2.HeartbeatResponse.SerializeToString,
             ),
+            'IssueCertificate': grpc.unary_unary_rpc_method_handler(
+                    servicer.IssueCertificate,
+                    request_deserializer=worker__pb2.IssueCertificateRequest.FromString,
+                    response_serializer=worker__pb2.IssueCertificateResponse.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'worker.WorkerService', rpc_method_handlers)
@@ -141,3 +157,30 @@ class WorkerService(object):
             timeout,
             metadata,
             _registered_method=True)
+
+    @staticmethod
+    def IssueCertificate(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(
+            request,
+            target,
+            '/worker.WorkerService/IssueCertificate',
+            worker__pb2.IssueCertificateRequest.SerializeToString,
+            worker__pb2.IssueCertificateResponse.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True)
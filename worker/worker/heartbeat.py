@@ -23,11 +23,19 @@ class HeartbeatClient:
         cloud_tag: str,
         orchestrator_addr: str,
         worker_addr: str = "",
+        join_token: str = "",
+        cpu_millis: int = 0,
+        memory_mb: int = 0,
     ):
         self.worker_id = worker_id
         self.cloud_tag = cloud_tag
         self.worker_addr = worker_addr or worker_id  # fallback: use worker_id as addr
         self._orchestrator_addr = orchestrator_addr   # mutable — updated on redirect
+        self._join_token = join_token                 # bootstrap secret, exchanged for a credential
+        self.cpu_millis = cpu_millis                  # declared capacity for placement; 0 = unknown
+        self.memory_mb = memory_mb
+        self._credential = ""                         # per-worker secret issued at registration
+        self._epoch = 0                               # registration epoch from the leader
         self._stop_event = threading.Event()
 
     def run(self):
@@ -71,17 +79,25 @@ class HeartbeatClient:
                 worker_id=self.worker_id,
                 address=self.worker_addr,
                 cloud_tag=self.cloud_tag,
+                cpu_millis=self.cpu_millis,
+                memory_mb=self.memory_mb,
             )
+            metadata = [("x-join-token", self._join_token)] if self._join_token else []
+            # A live registration is only replaced by the worker holding it.
+            metadata += self._auth_metadata() or []
             try:
-                resp = stub.RegisterWorker(req, timeout=5.0)
+                resp = stub.RegisterWorker(req, timeout=5.0, metadata=metadata)
             except grpc.RpcError as e:
                 raise RuntimeError(
                     f"gRPC RegisterWorker: {e.code()} {e.details()}"
                 )
 
         if resp.ok:
+            self._epoch = resp.epoch
+            self._credential = resp.credential
             logger.info(
                 f"Registered: worker_id={self.worker_id} "
+                f"epoch={self._epoch} "
                 f"orchestrator={self._orchestrator_addr}"
             )
             return True
@@ -99,12 +115,19 @@ class HeartbeatClient:
     # ── Heartbeat ─────────────────────────────────────────────────────────────
 
     def _send_heartbeat(self):
-        """Sends one Heartbeat RPC. Logs errors but never raises."""
+        """
+        Sends one Heartbeat RPC. Logs errors but never raises.
+        Stops the client if fenced by a newer registration of the same worker_id;
+        re-registers if the leader does not recognise this registration.
+        """
         try:
             with grpc.insecure_channel(self._orchestrator_addr) as channel:
                 stub = worker_pb2_grpc.WorkerServiceStub(channel)
-                req = worker_pb2.HeartbeatRequest(worker_id=self.worker_id)
-                resp = stub.Heartbeat(req, timeout=3.0)
+                req = worker_pb2.HeartbeatRequest(
+                    worker_id=self.worker_id,
+                    epoch=self._epoch,
+                )
+                resp = stub.Heartbeat(req, timeout=3.0, metadata=self._auth_metadata())
 
             if resp.ok:
                 logger.debug(f"Heartbeat ok: worker_id={self.worker_id}")
@@ -118,10 +141,20 @@ class HeartbeatClient:
                 self._orchestrator_addr = resp.leader_addr
                 return
 
+            if resp.fenced:
+                logger.error(
+                    f"Fenced by a newer registration — stopping: "
+                    f"worker_id={self.worker_id} epoch={self._epoch} "
+                    f"error={resp.error}"
+                )
+                self.stop()
+                return
+
             logger.warning(
-                f"Heartbeat rejected: worker_id={self.worker_id} "
+                f"Heartbeat rejected, re-registering: worker_id={self.worker_id} "
                 f"error={resp.error}"
             )
+            self._register_with_retry()
 
         except grpc.RpcError as e:
             logger.warning(
@@ -133,6 +166,12 @@ class HeartbeatClient:
                 f"Heartbeat error: worker_id={self.worker_id} error={e}"
             )
 
+    def _auth_metadata(self):
+        """Bearer credential metadata for calls after registration, if one was issued."""
+        if not self._credential:
+            return None
+        return [("authorization", f"Bearer {self._credential}")]
+
     def stop(self):
         self._stop_event.set()
         logger.info(f"Heartbeat client stopped: worker_id={self.worker_id}")
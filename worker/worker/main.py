@@ -20,6 +20,9 @@ CLOUD_TAG = os.environ.get("WORKER_CLOUD_TAG", "unknown")
 ORCHESTRATOR_ADDR = os.environ.get("ORCHESTRATOR_ADDR", "")
 HTTP_PORT = int(os.environ.get("HTTP_PORT", "8081"))
 WORKER_ADDR = os.environ.get("WORKER_ADDR", "") or f"{WORKER_ID}:{HTTP_PORT}"
+JOIN_TOKEN = os.environ.get("WORKER_JOIN_TOKEN", "")
+CPU_MILLIS = int(os.environ.get("WORKER_CPU_MILLIS") or (os.cpu_count() or 1) * 1000)
+MEMORY_MB = int(os.environ.get("WORKER_MEMORY_MB") or 0)  # 0: undeclared
 
 # ── App ───────────────────────────────────────────────────────────
 app = FastAPI(title="Pipeline Worker", version="0.1.0")
@@ -39,6 +42,9 @@ async def startup():
             cloud_tag=CLOUD_TAG,
             orchestrator_addr=ORCHESTRATOR_ADDR,
             worker_addr=WORKER_ADDR,
+            join_token=JOIN_TOKEN,
+            cpu_millis=CPU_MILLIS,
+            memory_mb=MEMORY_MB,
         )
         heartbeat_thread = threading.Thread(
             target=heartbeat_client.run, daemon=True